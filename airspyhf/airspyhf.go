@@ -28,6 +28,7 @@ import "C"
 import (
 	"bytes"
 	"fmt"
+	"strconv"
 	"unsafe"
 
 	"hz.tools/rf"
@@ -191,6 +192,20 @@ func (s *Sdr) GetSampleRates() ([]uint, error) {
 	return ret, nil
 }
 
+// SampleRateRanges implements the sdr.SampleRateRanger interface, with
+// one fixed-rate range for each of the GetSampleRates values.
+func (s *Sdr) SampleRateRanges() ([]sdr.SampleRateRange, error) {
+	rates, err := s.GetSampleRates()
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]sdr.SampleRateRange, len(rates))
+	for i, rate := range rates {
+		ret[i] = sdr.SampleRateRange{Min: rate, Max: rate}
+	}
+	return ret, nil
+}
+
 // HardwareInfo implements the sdr.Sdr interface.
 func (s *Sdr) HardwareInfo() sdr.HardwareInfo {
 	return s.info
@@ -223,6 +238,28 @@ func (s *Sdr) SampleFormat() sdr.SampleFormat {
 
 func init() {
 	debug.RegisterRadioDriver("hz.tools/sdr/airspyhf.Sdr")
+	sdr.RegisterSerialOpener("airspyhf", func(serial string) (sdr.Sdr, error) {
+		// HardwareInfo formats the serial as hex, so let's parse it
+		// back the same way.
+		sn, err := strconv.ParseUint(serial, 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("airspyhf: invalid serial %q: %s", serial, err)
+		}
+		return OpenBySerial(sn)
+	})
+	sdr.RegisterEnumerator("airspyhf", func() ([]sdr.DeviceDescription, error) {
+		// The Product is only known once the device is opened, so only
+		// the Manufacturer and Serial are filled in here.
+		serials := ListSerials()
+		ret := make([]sdr.DeviceDescription, len(serials))
+		for i, sn := range serials {
+			ret[i] = sdr.DeviceDescription{HardwareInfo: sdr.HardwareInfo{
+				Manufacturer: "Airspy",
+				Serial:       fmt.Sprintf("%X", sn),
+			}}
+		}
+		return ret, nil
+	})
 }
 
 // vim: foldmethod=marker
@@ -64,12 +64,21 @@ func (rx rx) Close() error {
 // typedef int (*airspyhf_sample_block_cb_fn) (airspyhf_transfer_t* transfer_fn);
 
 //export airspyhfRxCallback
-func airspyhfRxCallback(transfer *C.airspyhf_transfer_t) C.int {
+func airspyhfRxCallback(transfer *C.airspyhf_transfer_t) (rv C.int) {
 	context, ok := pointer.Restore(transfer.ctx).(*callbackContext)
 	if !ok {
 		return -1
 	}
 
+	// A panic here would unwind through libairspyhf and take down the whole
+	// process, so we'll close the pipe and tell libairspyhf to stop instead.
+	defer func() {
+		if v := recover(); v != nil {
+			context.pipeWriter.CloseWithError(sdr.DriverPanic(v))
+			rv = -1
+		}
+	}()
+
 	// First, check to see if we need to stop.
 	if err := context.ctx.Err(); err != nil {
 		context.pipeWriter.CloseWithError(err)
@@ -0,0 +1,181 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package audio
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// Reader is the interface that wraps the basic Read method for mono audio,
+// where each sample is between -1 and 1.
+type Reader interface {
+	// Read will read audio samples into the provided buffer, returning the
+	// number of samples read, in the same way as an io.Reader.
+	Read([]float32) (int, error)
+
+	// SampleRate will return the number of audio samples per second.
+	SampleRate() uint
+}
+
+// ReadCloser is the interface that groups the basic Read and Close methods.
+type ReadCloser interface {
+	Reader
+	io.Closer
+}
+
+// Writer is the interface that wraps the basic Write method for mono audio.
+type Writer interface {
+	// Write will write audio samples from the provided buffer, returning
+	// the number of samples written, in the same way as an io.Writer.
+	Write([]float32) (int, error)
+
+	// SampleRate will return the number of audio samples per second.
+	SampleRate() uint
+}
+
+// ReadFull will read exactly len(buf) samples from r, unless an error is
+// hit first.
+func ReadFull(r Reader, buf []float32) (int, error) {
+	var n int
+	for n < len(buf) {
+		i, err := r.Read(buf[n:])
+		n += i
+		if err != nil {
+			if err == io.EOF && n > 0 && n < len(buf) {
+				return n, io.ErrUnexpectedEOF
+			}
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+type byteReader struct {
+	r          io.Reader
+	byteOrder  binary.ByteOrder
+	sampleRate uint
+	buf        []byte
+}
+
+func (br *byteReader) SampleRate() uint {
+	return br.sampleRate
+}
+
+func (br *byteReader) Read(samples []float32) (int, error) {
+	if len(samples) == 0 {
+		return 0, nil
+	}
+	if cap(br.buf) < len(samples)*4 {
+		br.buf = make([]byte, len(samples)*4)
+	}
+	buf := br.buf[:len(samples)*4]
+
+	n, err := io.ReadAtLeast(br.r, buf, 4)
+	if rem := n % 4; rem != 0 && err == nil {
+		// Finish off the partial sample, rather than dropping it.
+		var i int
+		i, err = io.ReadFull(br.r, buf[n:n+4-rem])
+		n += i
+	}
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+
+	for i := range samples[:n/4] {
+		samples[i] = math.Float32frombits(br.byteOrder.Uint32(buf[i*4:]))
+	}
+	return n / 4, err
+}
+
+// ByteReader will wrap an io.Reader of raw float32 audio samples (such as
+// the output of `sox -t f32`), and return an audio Reader.
+func ByteReader(r io.Reader, byteOrder binary.ByteOrder, sampleRate uint) Reader {
+	return &byteReader{r: r, byteOrder: byteOrder, sampleRate: sampleRate}
+}
+
+type bytesReader struct {
+	r       Reader
+	size    int
+	encode  func([]byte, float32)
+	samples []float32
+	buf     []byte
+	pending []byte
+}
+
+func (br *bytesReader) Read(p []byte) (int, error) {
+	if len(br.pending) == 0 {
+		n := len(p) / br.size
+		if n == 0 {
+			n = 1
+		}
+		if cap(br.samples) < n {
+			br.samples = make([]float32, n)
+			br.buf = make([]byte, n*br.size)
+		}
+		i, err := br.r.Read(br.samples[:n])
+		for j, sample := range br.samples[:i] {
+			br.encode(br.buf[j*br.size:], sample)
+		}
+		br.pending = br.buf[:i*br.size]
+		if i == 0 {
+			return 0, err
+		}
+	}
+	n := copy(p, br.pending)
+	br.pending = br.pending[n:]
+	return n, nil
+}
+
+// Bytes will return an io.Reader which encodes the audio read from r as
+// raw float32 samples, such as to pipe into `aplay -f FLOAT_LE`.
+func Bytes(r Reader, byteOrder binary.ByteOrder) io.Reader {
+	return &bytesReader{
+		r:    r,
+		size: 4,
+		encode: func(buf []byte, sample float32) {
+			byteOrder.PutUint32(buf, math.Float32bits(sample))
+		},
+	}
+}
+
+// Int16Bytes will return an io.Reader which encodes the audio read from r as
+// raw signed 16 bit samples, clipping anything outside of -1 to 1. This is
+// the format most external decoders (such as multimon-ng or direwolf)
+// expect.
+func Int16Bytes(r Reader, byteOrder binary.ByteOrder) io.Reader {
+	return &bytesReader{
+		r:    r,
+		size: 2,
+		encode: func(buf []byte, sample float32) {
+			switch {
+			case sample > 1:
+				sample = 1
+			case sample < -1:
+				sample = -1
+			}
+			byteOrder.PutUint16(buf, uint16(int16(sample*math.MaxInt16)))
+		},
+	}
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,81 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package audio_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr/audio"
+)
+
+func TestBytesRoundTrip(t *testing.T) {
+	samples := make([]float32, 1001)
+	for i := range samples {
+		samples[i] = float32(i)/1001 - 0.5
+	}
+
+	for _, byteOrder := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		buf := &bytes.Buffer{}
+		assert.NoError(t, binary.Write(buf, byteOrder, samples))
+		encoded := buf.Bytes()
+
+		r := audio.ByteReader(bytes.NewReader(encoded), byteOrder, 8000)
+		assert.Equal(t, uint(8000), r.SampleRate())
+
+		out := make([]float32, len(samples))
+		n, err := audio.ReadFull(r, out)
+		assert.NoError(t, err)
+		assert.Equal(t, len(samples), n)
+		assert.Equal(t, samples, out)
+
+		_, err = r.Read(out)
+		assert.Equal(t, io.EOF, err)
+
+		// And back to bytes again, read a byte at a time.
+		r = audio.ByteReader(bytes.NewReader(encoded), byteOrder, 8000)
+		reencoded, err := ioutil.ReadAll(iotest.OneByteReader(audio.Bytes(r, byteOrder)))
+		assert.NoError(t, err)
+		assert.Equal(t, encoded, reencoded)
+	}
+}
+
+func TestInt16Bytes(t *testing.T) {
+	samples := []float32{0, 0.5, -0.5, 1, -1, 2, -2}
+	buf := &bytes.Buffer{}
+	assert.NoError(t, binary.Write(buf, binary.LittleEndian, samples))
+
+	r := audio.ByteReader(buf, binary.LittleEndian, 8000)
+	out, err := ioutil.ReadAll(audio.Int16Bytes(r, binary.LittleEndian))
+	assert.NoError(t, err)
+
+	decoded := make([]int16, len(samples))
+	assert.NoError(t, binary.Read(bytes.NewReader(out), binary.LittleEndian, decoded))
+	assert.Equal(t, []int16{0, 16383, -16383, 32767, -32767, 32767, -32767}, decoded)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,67 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+//go:build darwin
+// +build darwin
+
+package coreaudio
+
+// #cgo LDFLAGS: -framework AudioToolbox -framework CoreFoundation
+//
+// #include <stdint.h>
+//
+// #include <AudioToolbox/AudioToolbox.h>
+//
+// extern void coreaudioOutputCallback(void *user_data, int index);
+//
+// static void coreaudio_output_callback(void *user_data, AudioQueueRef queue,
+//                                       AudioQueueBufferRef buf) {
+//   coreaudioOutputCallback(user_data, (int)(intptr_t)buf->mUserData);
+// }
+//
+// OSStatus coreaudio_new_output(double sample_rate, void *user_data,
+//                               AudioQueueRef *queue) {
+//   AudioStreamBasicDescription format = {0};
+//   format.mSampleRate = sample_rate;
+//   format.mFormatID = kAudioFormatLinearPCM;
+//   format.mFormatFlags = kAudioFormatFlagsNativeFloatPacked;
+//   format.mBytesPerPacket = sizeof(float);
+//   format.mFramesPerPacket = 1;
+//   format.mBytesPerFrame = sizeof(float);
+//   format.mChannelsPerFrame = 1;
+//   format.mBitsPerChannel = 8 * sizeof(float);
+//
+//   // With no run loop, the callback is invoked on one of the AudioQueue's
+//   // own threads.
+//   return AudioQueueNewOutput(&format, coreaudio_output_callback, user_data,
+//                              NULL, NULL, 0, queue);
+// }
+//
+// OSStatus coreaudio_alloc_buffer(AudioQueueRef queue, int index,
+//                                 UInt32 size, AudioQueueBufferRef *buf) {
+//   OSStatus status = AudioQueueAllocateBuffer(queue, size, buf);
+//   if (status == noErr) {
+//     (*buf)->mUserData = (void *)(intptr_t)index;
+//   }
+//   return status;
+// }
+import "C"
+
+// vim: foldmethod=marker
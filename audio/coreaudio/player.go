@@ -0,0 +1,213 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+//go:build darwin
+// +build darwin
+
+package coreaudio
+
+// #cgo LDFLAGS: -framework AudioToolbox -framework CoreFoundation
+//
+// #include <AudioToolbox/AudioToolbox.h>
+//
+// OSStatus coreaudio_new_output(double sample_rate, void *user_data,
+//                               AudioQueueRef *queue);
+// OSStatus coreaudio_alloc_buffer(AudioQueueRef queue, int index,
+//                                 UInt32 size, AudioQueueBufferRef *buf);
+import "C"
+
+import (
+	"fmt"
+	"sync"
+	"unsafe"
+
+	"github.com/mattn/go-pointer"
+)
+
+var (
+	// ErrClosed will be returned when writing to a Player that has been
+	// Closed.
+	ErrClosed = fmt.Errorf("coreaudio: player is closed")
+)
+
+// statusToErr will take the CoreAudio OSStatus and turn that into a go
+// error.
+func statusToErr(status C.OSStatus) error {
+	if status != C.noErr {
+		return fmt.Errorf("coreaudio: OSStatus %d", int(status))
+	}
+	return nil
+}
+
+// PlayerOptions will configure how much audio a Player will buffer.
+type PlayerOptions struct {
+	// Buffers is the number of AudioQueue buffers to cycle through. If 0,
+	// this will default to 3.
+	Buffers int
+
+	// BufferLength is the number of samples in each buffer. If 0, this will
+	// default to 50ms of audio.
+	BufferLength int
+}
+
+func (o PlayerOptions) getBuffers() int {
+	if o.Buffers == 0 {
+		return 3
+	}
+	return o.Buffers
+}
+
+func (o PlayerOptions) getBufferLength(sampleRate uint) int {
+	if o.BufferLength == 0 {
+		return int(sampleRate / 20)
+	}
+	return o.BufferLength
+}
+
+// Player implements the audio.Writer interface, by playing the audio out of
+// the default output device.
+//
+// Writes will block once all the buffers are queued, until one of them has
+// finished playing, so the writer is paced by the audio device.
+type Player struct {
+	lock       *sync.Mutex
+	sampleRate uint
+	length     int
+	queue      C.AudioQueueRef
+	buffers    []C.AudioQueueBufferRef
+	free       chan int
+	state      unsafe.Pointer
+	started    bool
+	closed     bool
+}
+
+// NewPlayer will create a Player at the provided sample rate, with the
+// default PlayerOptions.
+func NewPlayer(sampleRate uint) (*Player, error) {
+	return NewPlayerWithOptions(sampleRate, PlayerOptions{})
+}
+
+// NewPlayerWithOptions will create a Player at the provided sample rate.
+func NewPlayerWithOptions(sampleRate uint, opts PlayerOptions) (*Player, error) {
+	p := &Player{
+		lock:       &sync.Mutex{},
+		sampleRate: sampleRate,
+		length:     opts.getBufferLength(sampleRate),
+		buffers:    make([]C.AudioQueueBufferRef, opts.getBuffers()),
+		free:       make(chan int, opts.getBuffers()),
+	}
+	p.state = pointer.Save(p)
+
+	if err := statusToErr(C.coreaudio_new_output(
+		C.double(sampleRate),
+		p.state,
+		&p.queue,
+	)); err != nil {
+		pointer.Unref(p.state)
+		return nil, err
+	}
+
+	for i := range p.buffers {
+		if err := statusToErr(C.coreaudio_alloc_buffer(
+			p.queue,
+			C.int(i),
+			C.UInt32(p.length*4),
+			&p.buffers[i],
+		)); err != nil {
+			C.AudioQueueDispose(p.queue, C.Boolean(1))
+			pointer.Unref(p.state)
+			return nil, err
+		}
+		p.free <- i
+	}
+	return p, nil
+}
+
+//export coreaudioOutputCallback
+func coreaudioOutputCallback(ptr unsafe.Pointer, index C.int) {
+	p := pointer.Restore(ptr).(*Player)
+	// There are only ever as many buffers as free has room for, so this
+	// won't block.
+	p.free <- int(index)
+}
+
+// SampleRate implements the audio.Writer interface.
+func (p *Player) SampleRate() uint {
+	return p.sampleRate
+}
+
+// Write implements the audio.Writer interface.
+func (p *Player) Write(samples []float32) (int, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.closed {
+		return 0, ErrClosed
+	}
+
+	var n int
+	for n < len(samples) {
+		i := <-p.free
+		buf := p.buffers[i]
+
+		data := (*[1 << 28]float32)(buf.mAudioData)[:p.length:p.length]
+		copied := copy(data, samples[n:])
+		buf.mAudioDataByteSize = C.UInt32(copied * 4)
+
+		if err := statusToErr(C.AudioQueueEnqueueBuffer(p.queue, buf, 0, nil)); err != nil {
+			p.free <- i
+			return n, err
+		}
+		n += copied
+
+		if !p.started {
+			if err := statusToErr(C.AudioQueueStart(p.queue, nil)); err != nil {
+				return n, err
+			}
+			p.started = true
+		}
+	}
+	return n, nil
+}
+
+// Close will wait for the audio already written to finish playing, and then
+// release the AudioQueue.
+func (p *Player) Close() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+
+	if p.started {
+		for range p.buffers {
+			<-p.free
+		}
+	}
+
+	err := statusToErr(C.AudioQueueStop(p.queue, C.Boolean(1)))
+	C.AudioQueueDispose(p.queue, C.Boolean(1))
+	pointer.Unref(p.state)
+	return err
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,42 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sdr
+
+import (
+	"hz.tools/rf"
+)
+
+// BandwidthSetter is implemented by Sdrs that are able to set the bandwidth
+// of their analog filters separately from the sample rate. This is usually
+// used to narrow the filters to keep a strong adjacent signal out of the
+// ADC, or to widen them up to the full sample rate.
+//
+// Drivers which set the bandwidth along with the sample rate will reset it
+// when SetSampleRate is called, so SetBandwidth should be called after.
+type BandwidthSetter interface {
+	// SetBandwidth will set the analog filter bandwidth.
+	SetBandwidth(rf.Hz) error
+
+	// GetBandwidth will return the current analog filter bandwidth.
+	GetBandwidth() (rf.Hz, error)
+}
+
+// vim: foldmethod=marker
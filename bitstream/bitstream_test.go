@@ -0,0 +1,116 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package bitstream_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr/bitstream"
+)
+
+func TestSearch(t *testing.T) {
+	word := bitstream.Unpack([]byte{0xD3, 0x91})
+
+	bits := make([]byte, 10)
+	bits = append(bits, word...)
+	bits = append(bits, 0, 1, 0)
+	corrupt := bitstream.Unpack([]byte{0xD3, 0x93})
+	bits = append(bits, corrupt...)
+	inverted := bitstream.Unpack([]byte{^byte(0xD3), ^byte(0x91)})
+	bits = append(bits, inverted...)
+
+	assert.Equal(t, []bitstream.Match{
+		{Offset: 10, Errors: 0},
+	}, bitstream.Search(bits, word, 0, false))
+
+	assert.Equal(t, []bitstream.Match{
+		{Offset: 10, Errors: 0},
+		{Offset: 29, Errors: 1},
+	}, bitstream.Search(bits, word, 1, false))
+
+	assert.Equal(t, []bitstream.Match{
+		{Offset: 10, Errors: 0},
+		{Offset: 29, Errors: 1},
+		{Offset: 45, Errors: 0, Inverted: true},
+	}, bitstream.Search(bits, word, 1, true))
+}
+
+func TestPack(t *testing.T) {
+	assert.Equal(t, []byte{0xA5, 0x80}, bitstream.Pack([]byte{1, 0, 1, 0, 0, 1, 0, 1, 1}))
+	assert.Equal(t, []byte{1, 0, 1, 0, 0, 1, 0, 1}, bitstream.Unpack([]byte{0xA5}))
+}
+
+func TestPN9(t *testing.T) {
+	pn9 := bitstream.NewPN9()
+
+	// The well known PN9 whitening sequence, with each byte sent LSB first.
+	for _, expected := range []byte{0xFF, 0xE1, 0x1D, 0x9A, 0xED, 0x85} {
+		var b byte
+		for i := uint(0); i < 8; i++ {
+			b |= pn9.Next() << i
+		}
+		assert.Equal(t, expected, b)
+	}
+}
+
+func TestWhiten(t *testing.T) {
+	data := bitstream.Unpack([]byte("hello, world"))
+	bits := append([]byte{}, data...)
+
+	bitstream.NewPN9().Whiten(bits)
+	assert.NotEqual(t, data, bits)
+	bitstream.NewPN9().Whiten(bits)
+	assert.Equal(t, data, bits)
+}
+
+func TestManchester(t *testing.T) {
+	src := []byte{1, 0, 0, 1, 1, 0, 1, 0}
+	dst := make([]byte, 4)
+
+	n, err := bitstream.ManchesterThomas.Decode(dst, src)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, n)
+	assert.Equal(t, []byte{1, 0, 1, 1}, dst)
+
+	n, err = bitstream.ManchesterIEEE.Decode(dst, src)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, n)
+	assert.Equal(t, []byte{0, 1, 0, 0}, dst)
+
+	n, err = bitstream.ManchesterThomas.Decode(dst, []byte{1, 0, 1, 1, 0, 1})
+	assert.Equal(t, bitstream.ErrManchester, err)
+	assert.Equal(t, 1, n)
+}
+
+func TestDifferential(t *testing.T) {
+	src := []byte{0, 1, 1, 0, 0, 0, 1}
+	dst := make([]byte, len(src))
+
+	assert.Equal(t, 7, bitstream.DifferentialDecode(dst, src, 0))
+	assert.Equal(t, []byte{0, 1, 0, 1, 0, 0, 1}, dst)
+
+	assert.Equal(t, 7, bitstream.NRZIDecode(dst, src, 0))
+	assert.Equal(t, []byte{1, 0, 1, 0, 1, 1, 0}, dst)
+}
+
+// vim: foldmethod=marker
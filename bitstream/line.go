@@ -0,0 +1,91 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package bitstream
+
+import (
+	"fmt"
+)
+
+var (
+	// ErrManchester will be returned if a pair of bits is not a valid
+	// Manchester symbol (that is, the two bits are the same).
+	ErrManchester = fmt.Errorf("bitstream: invalid manchester symbol")
+)
+
+// Manchester conventions define which transition is a 1.
+type Manchester bool
+
+var (
+	// ManchesterThomas is the G.E. Thomas convention, where a 1 is sent as
+	// a high then low (10), and a 0 as a low then high (01).
+	ManchesterThomas Manchester = false
+
+	// ManchesterIEEE is the IEEE 802.3 convention, where a 1 is sent as a
+	// low then high (01), and a 0 as a high then low (10).
+	ManchesterIEEE Manchester = true
+)
+
+// Decode will decode pairs of bits from src into dst, returning the number
+// of bits written. If an invalid symbol is encountered, the bits decoded up
+// to that point are returned, along with ErrManchester.
+//
+// dst may be the same slice as src.
+func (m Manchester) Decode(dst, src []byte) (int, error) {
+	var i int
+	for i = 0; i+1 < len(src) && i/2 < len(dst); i += 2 {
+		if src[i] == src[i+1] {
+			return i / 2, ErrManchester
+		}
+		bit := src[i]
+		if m == ManchesterIEEE {
+			bit = src[i+1]
+		}
+		dst[i/2] = bit
+	}
+	return i / 2, nil
+}
+
+// DifferentialDecode will decode differentially encoded bits, where a change
+// between consecutive bits is a 1, and no change is a 0. The `prev` argument
+// is the bit before src[0]. dst may be the same slice as src.
+func DifferentialDecode(dst, src []byte, prev byte) int {
+	n := len(src)
+	if len(dst) < n {
+		n = len(dst)
+	}
+	for i := 0; i < n; i++ {
+		bit := src[i]
+		dst[i] = bit ^ prev
+		prev = bit
+	}
+	return n
+}
+
+// NRZIDecode will decode NRZI bits (as used by HDLC, AX.25, and AIS), where
+// no change between consecutive bits is a 1, and a change is a 0. The `prev`
+// argument is the bit before src[0]. dst may be the same slice as src.
+func NRZIDecode(dst, src []byte, prev byte) int {
+	n := DifferentialDecode(dst, src, prev)
+	Invert(dst[:n])
+	return n
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,92 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package bitstream
+
+// Match is a location in a bitstream where a sync word was found.
+type Match struct {
+	// Offset is the index of the first bit of the sync word.
+	Offset int
+
+	// Errors is the number of bits that differed from the sync word.
+	Errors int
+
+	// Inverted is set if the sync word was found with every bit flipped,
+	// as happens with the phase ambiguity of BPSK, or swapped FSK tones.
+	// This is only set if inverted matches were requested.
+	Inverted bool
+}
+
+// Search will return every offset in bits where the sync word appears with
+// at most maxErrors bit errors.
+//
+// If inverted is set, the inverse of the sync word is also searched for,
+// and any matches will have Inverted set.
+func Search(bits, word []byte, maxErrors int, inverted bool) []Match {
+	ret := []Match{}
+	if len(word) == 0 {
+		return ret
+	}
+
+	for i := 0; i+len(word) <= len(bits); i++ {
+		var errs int
+		for j, b := range word {
+			if bits[i+j] != b {
+				errs++
+			}
+		}
+
+		switch {
+		case errs <= maxErrors:
+			ret = append(ret, Match{Offset: i, Errors: errs})
+		case inverted && len(word)-errs <= maxErrors:
+			ret = append(ret, Match{Offset: i, Errors: len(word) - errs, Inverted: true})
+		}
+	}
+	return ret
+}
+
+// Pack will pack bits into bytes, most significant bit first. If the number
+// of bits is not a multiple of 8, the last byte is padded with zeros.
+func Pack(bits []byte) []byte {
+	ret := make([]byte, (len(bits)+7)/8)
+	for i, b := range bits {
+		ret[i/8] |= (b & 1) << uint(7-i%8)
+	}
+	return ret
+}
+
+// Unpack will unpack bytes into bits, most significant bit first.
+func Unpack(data []byte) []byte {
+	ret := make([]byte, len(data)*8)
+	for i := range ret {
+		ret[i] = (data[i/8] >> uint(7-i%8)) & 1
+	}
+	return ret
+}
+
+// Invert will flip every bit, in place.
+func Invert(bits []byte) {
+	for i := range bits {
+		bits[i] ^= 1
+	}
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,73 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package bitstream
+
+// LFSR is a Fibonacci linear feedback shift register, used to generate the
+// pseudo-random sequence that data is XOR'd against to whiten it.
+type LFSR struct {
+	taps  uint32
+	state uint32
+	bits  uint
+}
+
+// NewLFSR will create a new LFSR of the provided length (in bits, up to 32),
+// where taps has a bit set for each register that is fed back (bit 0 being
+// the output), and seed is the initial state of the register.
+func NewLFSR(length uint, taps, seed uint32) *LFSR {
+	return &LFSR{
+		taps:  taps,
+		state: seed,
+		bits:  length,
+	}
+}
+
+// NewPN9 will create the common x^9 + x^5 + 1 LFSR with a seed of all ones,
+// which is used to whiten data by many ISM-band transceivers (such as the
+// CC1101, and the Si446x family).
+func NewPN9() *LFSR {
+	return NewLFSR(9, 0x021, 0x1FF)
+}
+
+// Next will return the next bit of the sequence.
+func (l *LFSR) Next() byte {
+	out := byte(l.state & 1)
+
+	var feedback uint32
+	taps := l.state & l.taps
+	for taps != 0 {
+		feedback ^= taps & 1
+		taps >>= 1
+	}
+
+	l.state = (l.state >> 1) | (feedback << (l.bits - 1))
+	return out
+}
+
+// Whiten will XOR the bits in place with the sequence from the LFSR. Since
+// this is an XOR, this is also how to dewhiten bits, given an LFSR in the
+// same state as the one used to whiten them.
+func (l *LFSR) Whiten(bits []byte) {
+	for i := range bits {
+		bits[i] ^= l.Next()
+	}
+}
+
+// vim: foldmethod=marker
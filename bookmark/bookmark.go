@@ -0,0 +1,223 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package bookmark
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/freq"
+)
+
+var (
+	// ErrNotFound will be returned if a Bookmark isn't in the Store.
+	ErrNotFound = fmt.Errorf("bookmark: not found")
+
+	// ErrDuplicate will be returned if a Bookmark is added with the same
+	// name as a Bookmark already in the Store.
+	ErrDuplicate = fmt.Errorf("bookmark: a bookmark with that name already exists")
+
+	// ErrNoName will be returned if a Bookmark without a name is added.
+	ErrNoName = fmt.Errorf("bookmark: bookmark has no name")
+)
+
+// Bookmark is a single channel memory.
+type Bookmark struct {
+	// Name is the unique name of the Bookmark.
+	Name string `json:"name"`
+
+	// Frequency is the frequency of the signal.
+	Frequency rf.Hz `json:"frequency"`
+
+	// Mode is how to demodulate the signal, if known.
+	Mode freq.Mode `json:"mode,omitempty"`
+
+	// Bandwidth is the bandwidth of the signal, if known.
+	Bandwidth rf.Hz `json:"bandwidth,omitempty"`
+
+	// Gains are the gain stages to set (by name) when tuning to this
+	// Bookmark, as passed to sdr.SetGainStages.
+	Gains map[string]float32 `json:"gains,omitempty"`
+
+	// Tags are free-form labels, such as "marine" or "repeater".
+	Tags []string `json:"tags,omitempty"`
+}
+
+// HasTag will check to see if the Bookmark has the provided tag.
+func (b Bookmark) HasTag(tag string) bool {
+	for _, t := range b.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Tune will tune the provided Sdr to the Bookmark, setting the center
+// frequency, and gain stages from the gain profile, if any.
+//
+// The device is tuned directly to the Frequency; callers which want to
+// tune off-center (to avoid the DC spike, or to scan a few Bookmarks at
+// once) should use the Frequency themselves.
+func (b Bookmark) Tune(dev sdr.Sdr) error {
+	if err := dev.SetCenterFrequency(b.Frequency); err != nil {
+		return err
+	}
+	if len(b.Gains) == 0 {
+		return nil
+	}
+	return sdr.SetGainStages(dev, b.Gains)
+}
+
+// Store is a set of Bookmarks, keyed by name. A Store is safe to use from
+// multiple goroutines.
+type Store struct {
+	lock      *sync.Mutex
+	bookmarks map[string]Bookmark
+}
+
+// NewStore will create a new, empty, Store.
+func NewStore() *Store {
+	return &Store{
+		lock:      &sync.Mutex{},
+		bookmarks: map[string]Bookmark{},
+	}
+}
+
+// Add will add Bookmarks to the Store. If any of the Bookmarks has the same
+// name as one already in the Store, ErrDuplicate is returned, and none of
+// the Bookmarks are added.
+func (s *Store) Add(bookmarks ...Bookmark) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	seen := map[string]bool{}
+	for _, b := range bookmarks {
+		if b.Name == "" {
+			return ErrNoName
+		}
+		if _, ok := s.bookmarks[b.Name]; ok || seen[b.Name] {
+			return ErrDuplicate
+		}
+		seen[b.Name] = true
+	}
+	for _, b := range bookmarks {
+		s.bookmarks[b.Name] = b
+	}
+	return nil
+}
+
+// Put will add the Bookmark, replacing any Bookmark with the same name.
+func (s *Store) Put(b Bookmark) error {
+	if b.Name == "" {
+		return ErrNoName
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.bookmarks[b.Name] = b
+	return nil
+}
+
+// Get will return the Bookmark with the provided name.
+func (s *Store) Get(name string) (Bookmark, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	b, ok := s.bookmarks[name]
+	if !ok {
+		return Bookmark{}, ErrNotFound
+	}
+	return b, nil
+}
+
+// Remove will remove the Bookmark with the provided name.
+func (s *Store) Remove(name string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.bookmarks[name]; !ok {
+		return ErrNotFound
+	}
+	delete(s.bookmarks, name)
+	return nil
+}
+
+// List will return every Bookmark in the Store, sorted by frequency (and
+// then by name).
+func (s *Store) List() []Bookmark {
+	return s.Filter(func(Bookmark) bool { return true })
+}
+
+// Filter will return every Bookmark in the Store that the provided function
+// returns true for, sorted by frequency (and then by name).
+func (s *Store) Filter(fn func(Bookmark) bool) []Bookmark {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	ret := []Bookmark{}
+	for _, b := range s.bookmarks {
+		if fn(b) {
+			ret = append(ret, b)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Frequency != ret[j].Frequency {
+			return ret[i].Frequency < ret[j].Frequency
+		}
+		return ret[i].Name < ret[j].Name
+	})
+	return ret
+}
+
+// Tagged will return every Bookmark with the provided tag, sorted by
+// frequency.
+func (s *Store) Tagged(tag string) []Bookmark {
+	return s.Filter(func(b Bookmark) bool { return b.HasTag(tag) })
+}
+
+// InRange will return every Bookmark within the provided range, such as the
+// span of spectrum a device is currently tuned to, sorted by frequency.
+func (s *Store) InRange(r rf.Range) []Bookmark {
+	return s.Filter(func(b Bookmark) bool { return r.ContainsFrequency(b.Frequency) })
+}
+
+// Save will write every Bookmark in the Store as JSON to the provided
+// io.Writer.
+func (s *Store) Save(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s.List())
+}
+
+// Load will read Bookmarks saved by Save from the provided io.Reader, and
+// Add them to the Store.
+func (s *Store) Load(r io.Reader) error {
+	bookmarks := []Bookmark{}
+	if err := json.NewDecoder(r).Decode(&bookmarks); err != nil {
+		return err
+	}
+	return s.Add(bookmarks...)
+}
+
+// vim: foldmethod=marker
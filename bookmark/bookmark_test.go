@@ -0,0 +1,93 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package bookmark_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/rf"
+	"hz.tools/sdr/bookmark"
+	"hz.tools/sdr/freq"
+	"hz.tools/sdr/mock"
+)
+
+var (
+	calling = bookmark.Bookmark{
+		Name:      "2m calling",
+		Frequency: 146.52 * rf.MHz,
+		Mode:      freq.ModeFM,
+		Bandwidth: 12.5 * rf.KHz,
+		Gains:     map[string]float32{"LNA": 20, "VGA": 12.5},
+		Tags:      []string{"ham", "simplex"},
+	}
+	ft8 = bookmark.Bookmark{
+		Name:      "40m FT8",
+		Frequency: 7.074 * rf.MHz,
+		Mode:      freq.ModeUSB,
+		Bandwidth: 3 * rf.KHz,
+		Tags:      []string{"ham"},
+	}
+	noaa = bookmark.Bookmark{
+		Name:      "NOAA 1",
+		Frequency: 162.55 * rf.MHz,
+		Mode:      freq.ModeFM,
+	}
+)
+
+func TestStore(t *testing.T) {
+	s := bookmark.NewStore()
+	assert.NoError(t, s.Add(calling, noaa, ft8))
+	assert.Equal(t, bookmark.ErrDuplicate, s.Add(calling))
+	assert.Equal(t, bookmark.ErrNoName, s.Add(bookmark.Bookmark{}))
+
+	assert.Equal(t, []bookmark.Bookmark{ft8, calling, noaa}, s.List())
+	assert.Equal(t, []bookmark.Bookmark{ft8, calling}, s.Tagged("ham"))
+	assert.Equal(t, []bookmark.Bookmark{calling, noaa}, s.InRange(rf.Range{144 * rf.MHz, 174 * rf.MHz}))
+
+	b, err := s.Get("NOAA 1")
+	assert.NoError(t, err)
+	assert.Equal(t, noaa, b)
+
+	assert.NoError(t, s.Remove("NOAA 1"))
+	_, err = s.Get("NOAA 1")
+	assert.Equal(t, bookmark.ErrNotFound, err)
+	assert.Equal(t, bookmark.ErrNotFound, s.Remove("NOAA 1"))
+
+	buf := bytes.Buffer{}
+	assert.NoError(t, s.Save(&buf))
+	loaded := bookmark.NewStore()
+	assert.NoError(t, loaded.Load(&buf))
+	assert.Equal(t, s.List(), loaded.List())
+}
+
+func TestTune(t *testing.T) {
+	dev := mock.New(mock.Config{})
+	assert.NoError(t, noaa.Tune(dev))
+
+	center, err := dev.GetCenterFrequency()
+	assert.NoError(t, err)
+	assert.Equal(t, noaa.Frequency, center)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,158 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package bookmark
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"hz.tools/rf"
+	"hz.tools/sdr/freq"
+)
+
+var csvHeader = []string{"name", "frequency", "mode", "bandwidth", "gains", "tags"}
+
+// WriteCSV will write the provided Bookmarks as CSV, with a header row. The
+// columns are name, frequency (in Hz), mode, bandwidth (in Hz), gains (as
+// "name=dB" pairs, separated by ";") and tags (separated by ";").
+func WriteCSV(w io.Writer, bookmarks []Bookmark) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, b := range bookmarks {
+		bandwidth := ""
+		if b.Bandwidth != 0 {
+			bandwidth = formatHz(b.Bandwidth)
+		}
+		if err := cw.Write([]string{
+			b.Name,
+			formatHz(b.Frequency),
+			string(b.Mode),
+			bandwidth,
+			formatGains(b.Gains),
+			strings.Join(b.Tags, ";"),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ReadCSV will read Bookmarks from CSV in the format written by WriteCSV.
+// The header row is optional, and trailing columns may be left off.
+// Frequencies may be given with units (such as "146.52 MHz").
+func ReadCSV(r io.Reader) ([]Bookmark, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := []Bookmark{}
+	for i, record := range records {
+		if i == 0 && len(record) > 0 && strings.EqualFold(record[0], csvHeader[0]) {
+			continue
+		}
+		for len(record) < len(csvHeader) {
+			record = append(record, "")
+		}
+
+		b := Bookmark{Name: record[0]}
+		if b.Frequency, err = freq.ParseHz(record[1]); err != nil {
+			return nil, fmt.Errorf("bookmark: line %d: %s", i+1, err)
+		}
+		if b.Mode, err = freq.ParseMode(record[2]); err != nil {
+			return nil, fmt.Errorf("bookmark: line %d: %s", i+1, err)
+		}
+		if record[3] != "" {
+			if b.Bandwidth, err = freq.ParseHz(record[3]); err != nil {
+				return nil, fmt.Errorf("bookmark: line %d: %s", i+1, err)
+			}
+		}
+		if b.Gains, err = parseGains(record[4]); err != nil {
+			return nil, fmt.Errorf("bookmark: line %d: %s", i+1, err)
+		}
+		b.Tags = splitList(record[5], ";")
+		ret = append(ret, b)
+	}
+	return ret, nil
+}
+
+func formatHz(hz rf.Hz) string {
+	return strconv.FormatFloat(float64(hz), 'f', -1, 64)
+}
+
+func formatGains(gains map[string]float32) string {
+	names := make([]string, 0, len(gains))
+	for name := range gains {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + "=" + strconv.FormatFloat(float64(gains[name]), 'f', -1, 32)
+	}
+	return strings.Join(pairs, ";")
+}
+
+func parseGains(gains string) (map[string]float32, error) {
+	pairs := splitList(gains, ";")
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	ret := map[string]float32{}
+	for _, pair := range pairs {
+		i := strings.LastIndex(pair, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid gain: %s", pair)
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(pair[i+1:]), 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gain: %s", pair)
+		}
+		ret[strings.TrimSpace(pair[:i])] = float32(value)
+	}
+	return ret, nil
+}
+
+// splitList will split a list, trimming whitespace, and dropping empty
+// entries.
+func splitList(list, sep string) []string {
+	var ret []string
+	for _, el := range strings.Split(list, sep) {
+		if el = strings.TrimSpace(el); el != "" {
+			ret = append(ret, el)
+		}
+	}
+	return ret
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,63 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package bookmark_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/rf"
+	"hz.tools/sdr/bookmark"
+	"hz.tools/sdr/freq"
+)
+
+func TestCSV(t *testing.T) {
+	buf := bytes.Buffer{}
+	assert.NoError(t, bookmark.WriteCSV(&buf, []bookmark.Bookmark{calling, ft8, noaa}))
+	assert.Equal(t, `name,frequency,mode,bandwidth,gains,tags
+2m calling,146520000,FM,12500,LNA=20;VGA=12.5,ham;simplex
+40m FT8,7074000,USB,3000,,ham
+NOAA 1,162550000,FM,,,
+`, buf.String())
+
+	bookmarks, err := bookmark.ReadCSV(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, []bookmark.Bookmark{calling, ft8, noaa}, bookmarks)
+}
+
+func TestCSVHandWritten(t *testing.T) {
+	bookmarks, err := bookmark.ReadCSV(strings.NewReader(
+		"Airport tower, 118.3 MHz, am\nBeacon,10.368GHz\n",
+	))
+	assert.NoError(t, err)
+	assert.Equal(t, []bookmark.Bookmark{
+		{Name: "Airport tower", Frequency: 118.3 * rf.MHz, Mode: freq.ModeAM},
+		{Name: "Beacon", Frequency: 10.368 * rf.GHz},
+	}, bookmarks)
+
+	_, err = bookmark.ReadCSV(strings.NewReader("Nope,lots\n"))
+	assert.Error(t, err)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,168 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package bookmark
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"hz.tools/rf"
+	"hz.tools/sdr/freq"
+)
+
+// gqrx uses its own names for modes; the first name for each Mode is the
+// one written.
+var gqrxModes = []struct {
+	name string
+	mode freq.Mode
+}{
+	{"Raw I/Q", freq.ModeRaw},
+	{"AM", freq.ModeAM},
+	{"AM-Sync", freq.ModeAM},
+	{"Narrow FM", freq.ModeFM},
+	{"WFM (mono)", freq.ModeWFM},
+	{"WFM (stereo)", freq.ModeWFM},
+	{"WFM (oirt)", freq.ModeWFM},
+	{"LSB", freq.ModeLSB},
+	{"USB", freq.ModeUSB},
+	{"CW-U", freq.ModeCW},
+	{"CW-L", freq.ModeCW},
+}
+
+func gqrxMode(name string) freq.Mode {
+	for _, m := range gqrxModes {
+		if strings.EqualFold(m.name, name) {
+			return m.mode
+		}
+	}
+	return freq.ModeNone
+}
+
+func gqrxModeName(mode freq.Mode) string {
+	for _, m := range gqrxModes {
+		if m.mode == mode {
+			return m.name
+		}
+	}
+	return ""
+}
+
+// ReadGqrx will read Bookmarks from a gqrx bookmarks.csv file. Modes which
+// don't map to a freq.Mode are dropped, and the tag colors are ignored.
+func ReadGqrx(r io.Reader) ([]Bookmark, error) {
+	var (
+		ret     = []Bookmark{}
+		scanner = bufio.NewScanner(r)
+		line    int
+	)
+
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Split(text, ";")
+		if len(fields) == 2 {
+			// This is the tag table ("name ; color"), which is only
+			// used for colors.
+			continue
+		}
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("bookmark: gqrx line %d: not enough fields", line)
+		}
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+
+		hz, err := freq.ParseHz(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("bookmark: gqrx line %d: %s", line, err)
+		}
+		b := Bookmark{
+			Frequency: hz,
+			Name:      fields[1],
+			Mode:      gqrxMode(fields[2]),
+		}
+		if bandwidth, err := strconv.ParseFloat(fields[3], 64); err == nil && bandwidth > 0 {
+			b.Bandwidth = rf.Hz(bandwidth)
+		}
+		if len(fields) > 4 {
+			for _, tag := range splitList(fields[4], ",") {
+				if tag != "Untagged" {
+					b.Tags = append(b.Tags, tag)
+				}
+			}
+		}
+		ret = append(ret, b)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// WriteGqrx will write the provided Bookmarks as a gqrx bookmarks.csv file.
+// Gain profiles aren't supported by gqrx, and are dropped. Every tag is
+// given the same (grey) color.
+func WriteGqrx(w io.Writer, bookmarks []Bookmark) error {
+	tags := map[string]bool{}
+	for _, b := range bookmarks {
+		for _, tag := range b.Tags {
+			tags[tag] = true
+		}
+	}
+	tagNames := []string{"Untagged"}
+	for tag := range tags {
+		if tag != "Untagged" {
+			tagNames = append(tagNames, tag)
+		}
+	}
+	sort.Strings(tagNames[1:])
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# Tag name          ;  color\n")
+	for _, tag := range tagNames {
+		fmt.Fprintf(bw, "%-20s; #c0c0c0\n", tag)
+	}
+	fmt.Fprintf(bw, "\n# Frequency ; Name                     ; Modulation          ;  Bandwidth; Tags\n")
+	for _, b := range bookmarks {
+		bookmarkTags := strings.Join(b.Tags, ",")
+		if bookmarkTags == "" {
+			bookmarkTags = "Untagged"
+		}
+		fmt.Fprintf(bw, "%12s; %-25s; %-20s; %10s; %s\n",
+			formatHz(b.Frequency),
+			b.Name,
+			gqrxModeName(b.Mode),
+			formatHz(b.Bandwidth),
+			bookmarkTags,
+		)
+	}
+	return bw.Flush()
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,85 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package bookmark_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/rf"
+	"hz.tools/sdr/bookmark"
+	"hz.tools/sdr/freq"
+)
+
+const gqrxBookmarks = `# Tag name          ;  color
+Untagged            ; #c0c0c0
+Marine              ; #00ff00
+
+# Frequency ; Name                     ; Modulation          ;  Bandwidth; Tags
+   156800000; Marine Ch 16             ; Narrow FM           ;      10000; Marine
+    14074000; 20m FT8                  ; USB                 ;       2800; Untagged
+    98100000; Local FM                 ; WFM (stereo)        ;     160000; Untagged
+`
+
+func TestReadGqrx(t *testing.T) {
+	bookmarks, err := bookmark.ReadGqrx(strings.NewReader(gqrxBookmarks))
+	assert.NoError(t, err)
+	assert.Equal(t, []bookmark.Bookmark{
+		{
+			Name:      "Marine Ch 16",
+			Frequency: 156.8 * rf.MHz,
+			Mode:      freq.ModeFM,
+			Bandwidth: 10 * rf.KHz,
+			Tags:      []string{"Marine"},
+		},
+		{
+			Name:      "20m FT8",
+			Frequency: 14.074 * rf.MHz,
+			Mode:      freq.ModeUSB,
+			Bandwidth: 2.8 * rf.KHz,
+		},
+		{
+			Name:      "Local FM",
+			Frequency: 98.1 * rf.MHz,
+			Mode:      freq.ModeWFM,
+			Bandwidth: 160 * rf.KHz,
+		},
+	}, bookmarks)
+}
+
+func TestWriteGqrx(t *testing.T) {
+	buf := bytes.Buffer{}
+	assert.NoError(t, bookmark.WriteGqrx(&buf, []bookmark.Bookmark{ft8, calling}))
+	assert.Contains(t, buf.String(), "simplex             ; #c0c0c0\n")
+
+	bookmarks, err := bookmark.ReadGqrx(&buf)
+	assert.NoError(t, err)
+
+	// gqrx doesn't have gain profiles.
+	want := calling
+	want.Gains = nil
+	assert.Equal(t, []bookmark.Bookmark{ft8, want}, bookmarks)
+}
+
+// vim: foldmethod=marker
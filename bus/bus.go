@@ -0,0 +1,168 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package bus
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"hz.tools/sdr/decoder"
+)
+
+// Message is a payload published to the Bus.
+type Message struct {
+	// Topic is the topic the Message was published to, such as "adsb".
+	Topic string
+
+	// Time is when the Message was published.
+	Time time.Time
+
+	// Payload is the data being carried.
+	Payload interface{}
+}
+
+// Bus will deliver published Messages to all matching Subscriptions.
+//
+// Publishing never blocks; if a Subscription's buffer is full, the Message
+// is dropped for that Subscription (and counted), so one slow consumer can't
+// stall a decoder.
+type Bus struct {
+	lock *sync.Mutex
+	subs map[*Subscription]bool
+}
+
+// New will create a new Bus.
+func New() *Bus {
+	return &Bus{
+		lock: &sync.Mutex{},
+		subs: map[*Subscription]bool{},
+	}
+}
+
+// Publish will send the payload to every Subscription matching the topic.
+func (b *Bus) Publish(topic string, payload interface{}) {
+	b.PublishMessage(Message{
+		Topic:   topic,
+		Time:    time.Now(),
+		Payload: payload,
+	})
+}
+
+// PublishMessage will send the Message to every Subscription matching its
+// Topic.
+func (b *Bus) PublishMessage(msg Message) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for sub := range b.subs {
+		if !sub.matches(msg.Topic) {
+			continue
+		}
+		select {
+		case sub.ch <- msg:
+		default:
+			sub.dropped++
+		}
+	}
+}
+
+// DecoderCallback will return a function suitable for passing to
+// decoder.Run, which publishes each decoder.Event to the provided topic,
+// with the Event's Payload and Time.
+func (b *Bus) DecoderCallback(topic string) func(decoder.Event) {
+	return func(event decoder.Event) {
+		b.PublishMessage(Message{
+			Topic:   topic,
+			Time:    event.Time,
+			Payload: event.Payload,
+		})
+	}
+}
+
+// Subscribe will return a new Subscription which will receive Messages
+// published to any of the provided topics, with room for `buffer` Messages
+// to be queued. A topic ending in "/*" matches every topic under it (so
+// "adsb/*" matches "adsb/1090"), and no topics at all matches everything.
+func (b *Bus) Subscribe(buffer int, topics ...string) *Subscription {
+	sub := &Subscription{
+		bus:    b,
+		topics: topics,
+		ch:     make(chan Message, buffer),
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.subs[sub] = true
+	return sub
+}
+
+// Subscription is a stream of Messages from the Bus.
+type Subscription struct {
+	bus     *Bus
+	topics  []string
+	ch      chan Message
+	dropped int
+}
+
+// C will return the channel Messages are delivered on. It will be closed
+// when the Subscription is Closed.
+func (s *Subscription) C() <-chan Message {
+	return s.ch
+}
+
+// Dropped will return the number of Messages that have been dropped because
+// the Subscription's buffer was full.
+func (s *Subscription) Dropped() int {
+	s.bus.lock.Lock()
+	defer s.bus.lock.Unlock()
+	return s.dropped
+}
+
+// Close will stop delivery of Messages to the Subscription, and close the
+// channel.
+func (s *Subscription) Close() {
+	s.bus.lock.Lock()
+	defer s.bus.lock.Unlock()
+
+	if !s.bus.subs[s] {
+		return
+	}
+	delete(s.bus.subs, s)
+	close(s.ch)
+}
+
+func (s *Subscription) matches(topic string) bool {
+	if len(s.topics) == 0 {
+		return true
+	}
+	for _, t := range s.topics {
+		if t == topic {
+			return true
+		}
+		if strings.HasSuffix(t, "/*") && strings.HasPrefix(topic, t[:len(t)-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// vim: foldmethod=marker
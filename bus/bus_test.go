@@ -0,0 +1,154 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package bus_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr/bus"
+	"hz.tools/sdr/decoder"
+)
+
+type frame struct {
+	ICAO string
+	Alt  int
+}
+
+type page struct {
+	Capcode int
+	Text    string
+}
+
+func init() {
+	if err := bus.RegisterType("test.frame", frame{}); err != nil {
+		panic(err)
+	}
+	if err := bus.RegisterType("test.page", &page{}); err != nil {
+		panic(err)
+	}
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	b := bus.New()
+
+	all := b.Subscribe(10)
+	defer all.Close()
+	adsb := b.Subscribe(10, "adsb/*")
+	defer adsb.Close()
+	pager := b.Subscribe(10, "pager")
+	defer pager.Close()
+
+	b.Publish("adsb/1090", frame{ICAO: "A1B2C3", Alt: 3000})
+	b.Publish("pager", &page{Capcode: 1234, Text: "hi"})
+
+	msg := <-all.C()
+	assert.Equal(t, "adsb/1090", msg.Topic)
+	msg = <-all.C()
+	assert.Equal(t, "pager", msg.Topic)
+
+	msg = <-adsb.C()
+	assert.Equal(t, frame{ICAO: "A1B2C3", Alt: 3000}, msg.Payload)
+
+	msg = <-pager.C()
+	assert.Equal(t, &page{Capcode: 1234, Text: "hi"}, msg.Payload)
+
+	select {
+	case msg := <-adsb.C():
+		t.Fatalf("unexpected message: %v", msg)
+	default:
+	}
+}
+
+func TestDropped(t *testing.T) {
+	b := bus.New()
+	sub := b.Subscribe(1)
+
+	b.Publish("a", 1)
+	b.Publish("a", 2)
+	b.Publish("a", 3)
+	assert.Equal(t, 2, sub.Dropped())
+
+	sub.Close()
+	sub.Close()
+	msg, ok := <-sub.C()
+	assert.True(t, ok)
+	assert.Equal(t, 1, msg.Payload)
+	_, ok = <-sub.C()
+	assert.False(t, ok)
+
+	// Publishing after Close must not panic.
+	b.Publish("a", 4)
+}
+
+func TestDecoderCallback(t *testing.T) {
+	b := bus.New()
+	sub := b.Subscribe(1, "decoded")
+	defer sub.Close()
+
+	now := time.Now()
+	b.DecoderCallback("decoded")(decoder.Event{
+		Decoder: "test",
+		Time:    now,
+		Payload: "hello",
+	})
+
+	msg := <-sub.C()
+	assert.Equal(t, now, msg.Time)
+	assert.Equal(t, "hello", msg.Payload)
+}
+
+func TestJSON(t *testing.T) {
+	now := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	for _, msg := range []bus.Message{
+		{Topic: "adsb", Time: now, Payload: frame{ICAO: "ABCDEF", Alt: 10}},
+		{Topic: "pager", Time: now, Payload: &page{Capcode: 1, Text: "ok"}},
+	} {
+		data, err := json.Marshal(msg)
+		assert.NoError(t, err)
+
+		var out bus.Message
+		assert.NoError(t, json.Unmarshal(data, &out))
+		assert.Equal(t, msg.Topic, out.Topic)
+		assert.True(t, msg.Time.Equal(out.Time))
+		assert.Equal(t, msg.Payload, out.Payload)
+	}
+
+	data, err := json.Marshal(bus.Message{Topic: "raw", Time: now, Payload: map[string]int{"a": 1}})
+	assert.NoError(t, err)
+	assert.Equal(t,
+		`{"topic":"raw","time":"2023-01-02T03:04:05Z","payload":{"a":1}}`,
+		string(data),
+	)
+
+	var out bus.Message
+	assert.NoError(t, json.Unmarshal(data, &out))
+	assert.Equal(t, json.RawMessage(`{"a":1}`), out.Payload)
+
+	assert.Equal(t, bus.ErrDuplicateType, bus.RegisterType("test.frame", 1))
+	assert.Equal(t, bus.ErrDuplicateType, bus.RegisterType("test.other", frame{}))
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,129 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package bus
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrDuplicateType will be returned if a payload type or name is
+	// registered twice.
+	ErrDuplicateType = fmt.Errorf("bus: payload type is already registered")
+)
+
+var (
+	typesLock   = &sync.Mutex{}
+	typesByName = map[string]reflect.Type{}
+	namesByType = map[reflect.Type]string{}
+)
+
+// RegisterType will register the type of the provided example payload
+// under the provided name, so that it can be restored when a Message is
+// unmarshaled from JSON. Payloads which are pointers are registered as, and
+// restored to, pointers.
+func RegisterType(name string, example interface{}) error {
+	typesLock.Lock()
+	defer typesLock.Unlock()
+
+	t := reflect.TypeOf(example)
+	if _, ok := typesByName[name]; ok {
+		return ErrDuplicateType
+	}
+	if _, ok := namesByType[t]; ok {
+		return ErrDuplicateType
+	}
+	typesByName[name] = t
+	namesByType[t] = name
+	return nil
+}
+
+type jsonMessage struct {
+	Topic   string          `json:"topic"`
+	Time    time.Time       `json:"time"`
+	Type    string          `json:"type,omitempty"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// MarshalJSON will encode the Message as JSON, including the registered
+// name of the Payload's type (if any), so that it can be decoded back into
+// the right Go type.
+func (m Message) MarshalJSON() ([]byte, error) {
+	payload, err := json.Marshal(m.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	typesLock.Lock()
+	name := namesByType[reflect.TypeOf(m.Payload)]
+	typesLock.Unlock()
+
+	return json.Marshal(jsonMessage{
+		Topic:   m.Topic,
+		Time:    m.Time,
+		Type:    name,
+		Payload: payload,
+	})
+}
+
+// UnmarshalJSON will decode the Message from JSON. If the payload's type was
+// registered with RegisterType, the Payload will be of that type, otherwise
+// it will be a json.RawMessage.
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var msg jsonMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return err
+	}
+
+	m.Topic = msg.Topic
+	m.Time = msg.Time
+	m.Payload = msg.Payload
+
+	typesLock.Lock()
+	t, ok := typesByName[msg.Type]
+	typesLock.Unlock()
+	if !ok {
+		return nil
+	}
+
+	var target reflect.Value
+	if t.Kind() == reflect.Ptr {
+		target = reflect.New(t.Elem())
+		if err := json.Unmarshal(msg.Payload, target.Interface()); err != nil {
+			return err
+		}
+		m.Payload = target.Interface()
+		return nil
+	}
+
+	target = reflect.New(t)
+	if err := json.Unmarshal(msg.Payload, target.Interface()); err != nil {
+		return err
+	}
+	m.Payload = target.Elem().Interface()
+	return nil
+}
+
+// vim: foldmethod=marker
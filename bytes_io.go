@@ -47,6 +47,15 @@ func (bw byteWriterForeign) Write(samples Samples) (int, error) {
 		}
 		i, err := bw.w.Write(bufBytes)
 		return i / 2, err
+	case SamplesI12Packed:
+		// The packed layout is defined in bytes, so there's nothing to
+		// swap.
+		bufBytes, err := UnsafeSamplesAsBytes(buf)
+		if err != nil {
+			return 0, err
+		}
+		i, err := bw.w.Write(bufBytes)
+		return i / SampleFormatI12Packed.Size(), err
 	case SamplesI16:
 		if err := binary.Write(bw.w, bw.byteOrder, buf); err != nil {
 			return 0, err
@@ -57,6 +66,11 @@ func (bw byteWriterForeign) Write(samples Samples) (int, error) {
 			return 0, err
 		}
 		return len(buf), nil
+	case SamplesC128:
+		if err := binary.Write(bw.w, bw.byteOrder, buf); err != nil {
+			return 0, err
+		}
+		return len(buf), nil
 	default:
 		return 0, ErrSampleFormatUnknown
 	}
@@ -147,6 +161,13 @@ func (br byteReaderForeign) Read(samples Samples) (int, error) {
 		}
 		i, err := br.r.Read(bufBytes)
 		return i / SampleFormatI8.Size(), err
+	case SamplesI12Packed:
+		bufBytes, err := UnsafeSamplesAsBytes(buf)
+		if err != nil {
+			return 0, err
+		}
+		i, err := br.r.Read(bufBytes)
+		return i / SampleFormatI12Packed.Size(), err
 	case SamplesI16:
 		// TODO(paultag): binary.Read here forces a ReadFull which isn't
 		// ideal.
@@ -155,6 +176,9 @@ func (br byteReaderForeign) Read(samples Samples) (int, error) {
 	case SamplesC64:
 		err := binary.Read(br.r, br.byteOrder, buf)
 		return buf.Length(), err
+	case SamplesC128:
+		err := binary.Read(br.r, br.byteOrder, buf)
+		return buf.Length(), err
 	default:
 		return 0, ErrSampleFormatUnknown
 	}
@@ -186,6 +210,40 @@ func (br byteReaderNative) Read(samples Samples) (int, error) {
 	return i / samples.Format().Size(), err
 }
 
+// ReadAtLeast implements the sdr.AtLeastReader interface.
+//
+// This will hand the whole buffer to io.ReadAtLeast, which avoids a call
+// to Read per short read, and also correctly handles an io.Reader that
+// returns a partial sample.
+func (br byteReaderNative) ReadAtLeast(samples Samples, min int) (int, error) {
+	if samples.Format() != br.sampleFormat {
+		return 0, ErrSampleFormatMismatch
+	}
+	if samples.Length() == 0 {
+		return 0, nil
+	}
+	bufBytes, err := UnsafeSamplesAsBytes(samples)
+	if err != nil {
+		return 0, err
+	}
+	size := samples.Format().Size()
+	i, err := io.ReadAtLeast(br.r, bufBytes, min*size)
+	if rem := i % size; rem != 0 && err == nil {
+		// We've got a partial sample at the end of the buffer; let's finish
+		// reading it rather than dropping it on the floor.
+		var n int
+		n, err = io.ReadFull(br.r, bufBytes[i:i+size-rem])
+		i += n
+	}
+	switch err {
+	case io.ErrUnexpectedEOF:
+		err = ErrUnexpectedEOF
+	case io.ErrShortBuffer:
+		err = ErrShortBuffer
+	}
+	return i / size, err
+}
+
 func (br byteReaderNative) SampleFormat() SampleFormat {
 	return br.sampleFormat
 }
@@ -218,4 +276,71 @@ func ByteReader(
 	}
 }
 
+type ioReader struct {
+	r       Reader
+	buf     Samples
+	pending []byte
+}
+
+func (ior *ioReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if len(ior.pending) == 0 {
+		n := len(p) / ior.r.SampleFormat().Size()
+		if n == 0 {
+			// Less than one sample was asked for; read a whole one, and
+			// hand it out over a few calls.
+			n = 1
+		}
+		if ior.buf == nil || ior.buf.Length() < n {
+			buf, err := MakeSamples(ior.r.SampleFormat(), n)
+			if err != nil {
+				return 0, err
+			}
+			ior.buf = buf
+		}
+		i, err := ior.r.Read(ior.buf.Slice(0, n))
+		if i == 0 {
+			return 0, err
+		}
+		bufBytes, berr := UnsafeSamplesAsBytes(ior.buf.Slice(0, i))
+		if berr != nil {
+			return 0, berr
+		}
+		ior.pending = bufBytes
+		if len(p) >= len(bufBytes) {
+			// Everything fits, so we can pass the error along too.
+			ior.pending = nil
+			return copy(p, bufBytes), err
+		}
+	}
+	n := copy(p, ior.pending)
+	ior.pending = ior.pending[n:]
+	return n, nil
+}
+
+type ioReadCloser struct {
+	*ioReader
+	c Closer
+}
+
+func (iorc ioReadCloser) Close() error {
+	return iorc.c.Close()
+}
+
+// IOReader will wrap an sdr.Reader, and return an io.Reader of the raw
+// native-endian bytes of the IQ samples (the inverse of ByteReader), to
+// hand a stream to code (or a program) that only knows how to deal with
+// bytes, such as piping to an external demodulator.
+func IOReader(r Reader) io.Reader {
+	return &ioReader{r: r}
+}
+
+// IOReadCloser is the same as IOReader, but will also Close the underlying
+// sdr.ReadCloser when closed.
+func IOReadCloser(rc ReadCloser) io.ReadCloser {
+	return ioReadCloser{ioReader: &ioReader{r: rc}, c: rc}
+}
+
 // vim: foldmethod=marker
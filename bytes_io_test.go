@@ -21,16 +21,19 @@
 package sdr_test
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"sync"
-
 	"testing"
+	"testing/iotest"
 
 	"github.com/stretchr/testify/assert"
 
 	"hz.tools/sdr"
+	"hz.tools/sdr/internal"
 	"hz.tools/sdr/testutils"
 )
 
@@ -38,9 +41,10 @@ func TestBytesIOLEStd(t *testing.T) {
 	ioReader, ioWriter := io.Pipe()
 
 	for n, sf := range map[string]sdr.SampleFormat{
-		"C64": sdr.SampleFormatC64,
-		"U8":  sdr.SampleFormatU8,
-		"I16": sdr.SampleFormatI16,
+		"C128": sdr.SampleFormatC128,
+		"C64":  sdr.SampleFormatC64,
+		"U8":   sdr.SampleFormatU8,
+		"I16":  sdr.SampleFormatI16,
 	} {
 		pipeReader := sdr.ByteReader(ioReader, binary.LittleEndian, 0, sf)
 		pipeWriter := sdr.ByteWriter(ioWriter, binary.LittleEndian, 0, sf)
@@ -54,9 +58,10 @@ func TestBytesIOBEStd(t *testing.T) {
 	ioReader, ioWriter := io.Pipe()
 
 	for n, sf := range map[string]sdr.SampleFormat{
-		"C64": sdr.SampleFormatC64,
-		"U8":  sdr.SampleFormatU8,
-		"I16": sdr.SampleFormatI16,
+		"C128": sdr.SampleFormatC128,
+		"C64":  sdr.SampleFormatC64,
+		"U8":   sdr.SampleFormatU8,
+		"I16":  sdr.SampleFormatI16,
 	} {
 		pipeReader := sdr.ByteReader(ioReader, binary.BigEndian, 0, sf)
 		pipeWriter := sdr.ByteWriter(ioWriter, binary.BigEndian, 0, sf)
@@ -124,4 +129,79 @@ func TestBytesIOBE(t *testing.T) {
 	}
 }
 
+func TestBytesIOReadFullOneByte(t *testing.T) {
+	raw := make([]byte, 8*16)
+	for i := range raw {
+		raw[i] = byte(i)
+	}
+
+	reader := sdr.ByteReader(
+		iotest.OneByteReader(bytes.NewReader(raw)),
+		internal.NativeEndian, 0, sdr.SampleFormatC64,
+	)
+
+	buf := make(sdr.SamplesC64, 16)
+	n, err := sdr.ReadFull(reader, buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 16, n)
+	assert.Equal(t, raw, sdr.MustUnsafeSamplesAsBytes(buf))
+
+	_, err = sdr.ReadFull(reader, buf)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestBytesIOReadFullShort(t *testing.T) {
+	reader := sdr.ByteReader(
+		bytes.NewReader(make([]byte, 8*10+3)),
+		internal.NativeEndian, 0, sdr.SampleFormatC64,
+	)
+
+	buf := make(sdr.SamplesC64, 16)
+	n, err := sdr.ReadFull(reader, buf)
+	assert.Equal(t, sdr.ErrUnexpectedEOF, err)
+	assert.Equal(t, 10, n)
+}
+
+func TestIOReader(t *testing.T) {
+	raw := make([]byte, 8*16)
+	for i := range raw {
+		raw[i] = byte(i)
+	}
+
+	for _, sf := range []sdr.SampleFormat{
+		sdr.SampleFormatC64,
+		sdr.SampleFormatI16,
+		sdr.SampleFormatU8,
+	} {
+		reader := sdr.ByteReader(bytes.NewReader(raw), internal.NativeEndian, 0, sf)
+
+		// One byte at a time, to split samples across calls.
+		out, err := ioutil.ReadAll(iotest.OneByteReader(sdr.IOReader(reader)))
+		assert.NoError(t, err)
+		assert.Equal(t, raw, out)
+
+		reader = sdr.ByteReader(bytes.NewReader(raw), internal.NativeEndian, 0, sf)
+		out, err = ioutil.ReadAll(sdr.IOReader(reader))
+		assert.NoError(t, err)
+		assert.Equal(t, raw, out)
+	}
+}
+
+func TestIOReadCloser(t *testing.T) {
+	pipeReader, pipeWriter := sdr.Pipe(0, sdr.SampleFormatU8)
+	go func() {
+		pipeWriter.Write(sdr.SamplesU8{{1, 2}, {3, 4}})
+	}()
+
+	rc := sdr.IOReadCloser(pipeReader)
+	buf := make([]byte, 4)
+	_, err := io.ReadFull(rc, buf)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{1, 2, 3, 4}, buf)
+
+	assert.NoError(t, rc.Close())
+	_, err = rc.Read(buf)
+	assert.Error(t, err)
+}
+
 // vim: foldmethod=marker
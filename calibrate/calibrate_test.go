@@ -0,0 +1,152 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package calibrate_test
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/calibrate"
+)
+
+// noiseSource is a fake receiver with a noise source in front of it, which
+// takes lag samples to react after being toggled.
+type noiseSource struct {
+	rand *rand.Rand
+
+	on    bool
+	lag   int
+	since int
+
+	cold float64
+	hot  float64
+
+	toggles []bool
+}
+
+func (ns *noiseSource) Set(on bool) error {
+	ns.toggles = append(ns.toggles, on)
+	if on != ns.on {
+		ns.since = 0
+	}
+	ns.on = on
+	return nil
+}
+
+func (ns *noiseSource) SampleFormat() sdr.SampleFormat { return sdr.SampleFormatC64 }
+func (ns *noiseSource) SampleRate() uint               { return 100000 }
+
+func (ns *noiseSource) Read(s sdr.Samples) (int, error) {
+	buf := s.(sdr.SamplesC64)
+	for i := range buf {
+		hot := ns.on
+		if ns.since < ns.lag {
+			hot = !hot
+		}
+		ns.since++
+
+		// Power is split between I and Q.
+		stdDev := math.Sqrt(ns.cold / 2)
+		if hot {
+			stdDev = math.Sqrt(ns.hot / 2)
+		}
+		buf[i] = complex(
+			float32(ns.rand.NormFloat64()*stdDev),
+			float32(ns.rand.NormFloat64()*stdDev),
+		)
+	}
+	return len(buf), nil
+}
+
+func TestYFactor(t *testing.T) {
+	ns := &noiseSource{
+		rand: rand.New(rand.NewSource(0)),
+		lag:  5000,
+		cold: 0.0001,
+		hot:  0.0004,
+	}
+
+	result, err := calibrate.YFactor(ns, ns, calibrate.YFactorConfig{ENR: 15})
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{false, true, false}, ns.toggles)
+	assert.False(t, ns.on)
+
+	assert.InDelta(t, 4, result.Y(), 0.1)
+	assert.InDelta(t, 15-10*math.Log10(3), result.NoiseFigure(15), 0.1)
+	assert.InDelta(t, 290*math.Pow(10, 1.5)/3, result.NoiseTemperature(15), 150)
+}
+
+func TestYFactorNoSource(t *testing.T) {
+	ns := &noiseSource{
+		rand: rand.New(rand.NewSource(0)),
+		cold: 0.0001,
+		hot:  0.00005,
+	}
+	_, err := calibrate.YFactor(ns, ns, calibrate.YFactorConfig{
+		ENR:      15,
+		Duration: 10 * time.Millisecond,
+	})
+	assert.Equal(t, calibrate.ErrNoYFactor, err)
+}
+
+func TestWith(t *testing.T) {
+	var toggles []bool
+	sw := calibrate.SwitchFunc(func(on bool) error {
+		toggles = append(toggles, on)
+		return nil
+	})
+
+	assert.NoError(t, calibrate.With(sw, func() error {
+		assert.Equal(t, []bool{true}, toggles)
+		return nil
+	}))
+	assert.Equal(t, []bool{true, false}, toggles)
+
+	toggles = nil
+	fnErr := fmt.Errorf("test")
+	assert.Equal(t, fnErr, calibrate.With(sw, func() error { return fnErr }))
+	assert.Equal(t, []bool{true, false}, toggles)
+}
+
+type gpio map[int]bool
+
+func (g gpio) SetBiasTGPIO(pin int, on bool) error {
+	g[pin] = on
+	return nil
+}
+
+func TestGPIOInvert(t *testing.T) {
+	pins := gpio{}
+	sw := calibrate.Invert(calibrate.GPIO(pins, 4))
+
+	assert.NoError(t, sw.Set(true))
+	assert.Equal(t, gpio{4: false}, pins)
+	assert.NoError(t, sw.Set(false))
+	assert.Equal(t, gpio{4: true}, pins)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,111 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package calibrate
+
+import (
+	"time"
+
+	"hz.tools/sdr"
+)
+
+// Switch is a piece of calibration hardware that can be turned on or off,
+// such as a noise source, or a relay that switches the receiver from the
+// antenna to a reference load.
+type Switch interface {
+	// Set will turn the calibration hardware on (or off).
+	Set(on bool) error
+}
+
+// SwitchFunc is a function which implements the Switch interface.
+type SwitchFunc func(on bool) error
+
+// Set implements the Switch interface.
+func (sf SwitchFunc) Set(on bool) error {
+	return sf(on)
+}
+
+// BiasTSetter is implemented by devices which can power external hardware
+// from a bias tee, such as the rtl-sdr.
+type BiasTSetter interface {
+	SetBiasT(bool) error
+}
+
+// BiasT will return a Switch that powers calibration hardware (such as a
+// noise source) from the device's bias tee.
+func BiasT(dev BiasTSetter) Switch {
+	return SwitchFunc(dev.SetBiasT)
+}
+
+// GPIOSetter is implemented by devices which expose GPIO pins, such as the
+// rtl-sdr.
+type GPIOSetter interface {
+	SetBiasTGPIO(pin int, on bool) error
+}
+
+// GPIO will return a Switch that drives calibration hardware (such as a
+// relay) from a GPIO pin on the device.
+func GPIO(dev GPIOSetter, pin int) Switch {
+	return SwitchFunc(func(on bool) error {
+		return dev.SetBiasTGPIO(pin, on)
+	})
+}
+
+// Invert will return a Switch which is on when the provided Switch is off,
+// for hardware which is active low, such as a relay that switches to the
+// calibration source when it's not powered.
+func Invert(sw Switch) Switch {
+	return SwitchFunc(func(on bool) error {
+		return sw.Set(!on)
+	})
+}
+
+// With will turn the Switch on, call fn, and then turn the Switch off again,
+// even if fn fails. If fn fails, that error is returned, otherwise any error
+// turning the Switch off is.
+func With(sw Switch, fn func() error) error {
+	if err := sw.Set(true); err != nil {
+		sw.Set(false)
+		return err
+	}
+	err := fn()
+	if offErr := sw.Set(false); err == nil {
+		err = offErr
+	}
+	return err
+}
+
+// Settle will read and discard the provided duration of samples from the
+// Reader, to let the hardware settle after a Switch has been toggled, and
+// to flush out any samples buffered from before the Switch was toggled.
+func Settle(r sdr.Reader, d time.Duration) error {
+	n := int(d.Seconds() * float64(r.SampleRate()))
+	if n <= 0 {
+		return nil
+	}
+	buf, err := sdr.MakeSamples(r.SampleFormat(), n)
+	if err != nil {
+		return err
+	}
+	_, err = sdr.ReadFull(r, buf)
+	return err
+}
+
+// vim: foldmethod=marker
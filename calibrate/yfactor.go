@@ -0,0 +1,160 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package calibrate
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"hz.tools/sdr"
+)
+
+var (
+	// ErrNoYFactor will be returned if the power with the noise source on
+	// is not above the power with it off, which usually means the noise
+	// source isn't connected, or the receiver is saturated.
+	ErrNoYFactor = fmt.Errorf("calibrate: no increase in power with the noise source on")
+)
+
+// referenceTemperature is the standard noise temperature (T0) in Kelvin,
+// which ENR is defined relative to.
+const referenceTemperature = 290
+
+// YFactorConfig configures a Y-factor noise figure measurement.
+type YFactorConfig struct {
+	// ENR is the excess noise ratio of the noise source, in dB, at the
+	// frequency being measured. This is required, and is usually printed
+	// on the noise source, or its calibration sheet.
+	ENR float64
+
+	// Settle is how long to discard samples for after toggling the noise
+	// source. If 0, this will default to 100ms.
+	Settle time.Duration
+
+	// Duration is how long to average the power over for each of the hot
+	// and cold measurements. If 0, this will default to 250ms.
+	Duration time.Duration
+}
+
+func (c YFactorConfig) getSettle() time.Duration {
+	if c.Settle == 0 {
+		return 100 * time.Millisecond
+	}
+	return c.Settle
+}
+
+func (c YFactorConfig) getDuration() time.Duration {
+	if c.Duration == 0 {
+		return 250 * time.Millisecond
+	}
+	return c.Duration
+}
+
+// YFactorResult is the result of a Y-factor measurement.
+type YFactorResult struct {
+	// Hot is the power (relative to full scale) with the noise source on.
+	Hot float64
+
+	// Cold is the power (relative to full scale) with the noise source off.
+	Cold float64
+}
+
+// Y returns the ratio of the Hot to Cold power.
+func (y YFactorResult) Y() float64 {
+	return y.Hot / y.Cold
+}
+
+// NoiseTemperature returns the equivalent noise temperature of the
+// receiver in Kelvin, given the ENR of the noise source in dB.
+func (y YFactorResult) NoiseTemperature(enr float64) float64 {
+	return referenceTemperature * math.Pow(10, enr/10) / (y.Y() - 1)
+}
+
+// NoiseFigure returns the noise figure of the receiver in dB, given the ENR
+// of the noise source in dB.
+func (y YFactorResult) NoiseFigure(enr float64) float64 {
+	return enr - 10*math.Log10(y.Y()-1)
+}
+
+// measurePower will read samples for the provided Duration, and return the
+// average power.
+func measurePower(r sdr.Reader, d time.Duration) (float64, error) {
+	n := int(d.Seconds() * float64(r.SampleRate()))
+	if n <= 0 {
+		return 0, fmt.Errorf("calibrate: measurement duration is too short")
+	}
+	buf, err := sdr.MakeSamples(r.SampleFormat(), n)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := sdr.ReadFull(r, buf); err != nil {
+		return 0, err
+	}
+	stats, err := sdr.Statistics(buf)
+	if err != nil {
+		return 0, err
+	}
+	return float64(stats.Power), nil
+}
+
+// YFactor will measure the noise figure of a receiver by reading from r with
+// the noise source (controlled by sw) off and then on, waiting for the
+// hardware to settle after each toggle. The noise source is left off.
+//
+// The gain of the receiver must not change during the measurement, so any
+// AGC must be turned off before calling this.
+func YFactor(r sdr.Reader, sw Switch, cfg YFactorConfig) (*YFactorResult, error) {
+	var (
+		settle   = cfg.getSettle()
+		duration = cfg.getDuration()
+		result   = YFactorResult{}
+		err      error
+	)
+
+	if err := sw.Set(false); err != nil {
+		return nil, err
+	}
+	if err := Settle(r, settle); err != nil {
+		return nil, err
+	}
+	result.Cold, err = measurePower(r, duration)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := With(sw, func() error {
+		if err := Settle(r, settle); err != nil {
+			return err
+		}
+		result.Hot, err = measurePower(r, duration)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	if result.Hot <= result.Cold {
+		return nil, ErrNoYFactor
+	}
+	return &result, nil
+}
+
+// vim: foldmethod=marker
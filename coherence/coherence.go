@@ -0,0 +1,255 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package coherence
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/fft"
+)
+
+var (
+	// ErrNoFrames will be returned if a result is requested from an
+	// Analyzer before any frames have been added.
+	ErrNoFrames = fmt.Errorf("coherence: no frames have been added")
+
+	// ErrSampleRateMismatch will be returned if the two Readers passed to
+	// Read are not at the same sample rate.
+	ErrSampleRateMismatch = fmt.Errorf("coherence: readers are not the same sample rate")
+)
+
+// windowPower is the coherent power gain of the Hann window (0.5 squared),
+// which is what a carrier centered on a bin is attenuated by.
+const windowPower = 0.25
+
+// Analyzer will accumulate the auto- and cross-spectral densities of pairs
+// of frames of IQ data from two receivers (using Welch's method, with a Hann
+// window), from which the cross spectrum, coherence, and phase difference
+// are computed.
+//
+// Results are in the fft.ZeroFirst order.
+type Analyzer struct {
+	a, b       sdr.SamplesC64
+	freqA      []complex64
+	freqB      []complex64
+	planA      fft.Plan
+	planB      fft.Plan
+	window     []float32
+	sampleRate uint
+
+	sxx    []float64
+	syy    []float64
+	sxy    []complex128
+	frames int
+}
+
+// NewAnalyzer will create a new Analyzer which computes FFTs of `length`
+// samples using the provided planner.
+func NewAnalyzer(planner fft.Planner, sampleRate uint, length int) (*Analyzer, error) {
+	an := &Analyzer{
+		a:          make(sdr.SamplesC64, length),
+		b:          make(sdr.SamplesC64, length),
+		freqA:      make([]complex64, length),
+		freqB:      make([]complex64, length),
+		window:     make([]float32, length),
+		sampleRate: sampleRate,
+		sxx:        make([]float64, length),
+		syy:        make([]float64, length),
+		sxy:        make([]complex128, length),
+	}
+
+	for i := range an.window {
+		an.window[i] = float32(0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(length)))
+	}
+
+	var err error
+	an.planA, err = planner(an.a, an.freqA, fft.Forward)
+	if err != nil {
+		return nil, err
+	}
+	an.planB, err = planner(an.b, an.freqB, fft.Forward)
+	if err != nil {
+		an.planA.Close()
+		return nil, err
+	}
+	return an, nil
+}
+
+// Length is the number of samples in each frame, and the number of bins in
+// each result.
+func (an *Analyzer) Length() int {
+	return len(an.a)
+}
+
+// Frames returns the number of frames averaged so far.
+func (an *Analyzer) Frames() int {
+	return an.frames
+}
+
+// Add will add one frame from each receiver to the running average. Both
+// buffers must be exactly Length samples long, and have been captured at the
+// same time.
+func (an *Analyzer) Add(a, b sdr.SamplesC64) error {
+	if len(a) != len(an.a) || len(b) != len(an.b) {
+		return sdr.ErrDstTooSmall
+	}
+
+	for i, w := range an.window {
+		wc := complex(w, 0)
+		an.a[i] = a[i] * wc
+		an.b[i] = b[i] * wc
+	}
+
+	if err := an.planA.Transform(); err != nil {
+		return err
+	}
+	if err := an.planB.Transform(); err != nil {
+		return err
+	}
+
+	for i := range an.freqA {
+		x := complex128(an.freqA[i])
+		y := complex128(an.freqB[i])
+		an.sxx[i] += real(x)*real(x) + imag(x)*imag(x)
+		an.syy[i] += real(y)*real(y) + imag(y)*imag(y)
+		an.sxy[i] += x * cmplx.Conj(y)
+	}
+	an.frames++
+	return nil
+}
+
+// CrossSpectrum will return the averaged cross-spectral density between the
+// two receivers, scaled the same way as fft.FrequencySlice.Power (so the
+// magnitude of a full scale carrier seen by both receivers is 1).
+func (an *Analyzer) CrossSpectrum() (fft.FrequencySlice, error) {
+	if an.frames == 0 {
+		return fft.FrequencySlice{}, ErrNoFrames
+	}
+
+	var (
+		n     = float64(len(an.sxy))
+		ret   = make([]complex64, len(an.sxy))
+		scale = 1 / (float64(an.frames) * n * n * windowPower)
+	)
+	for i, sxy := range an.sxy {
+		ret[i] = complex64(sxy * complex(scale, 0))
+	}
+	return fft.NewFrequencySlice(ret, an.sampleRate, fft.ZeroFirst), nil
+}
+
+// Coherence will write the magnitude-squared coherence of each bin to dst,
+// which is 1 when the two receivers see a perfectly consistent phase and
+// amplitude relationship in that bin, and tends to 0 for unrelated signals
+// as more frames are averaged.
+func (an *Analyzer) Coherence(dst []float32) error {
+	if an.frames == 0 {
+		return ErrNoFrames
+	}
+	if len(dst) < len(an.sxy) {
+		return sdr.ErrDstTooSmall
+	}
+
+	for i, sxy := range an.sxy {
+		denom := an.sxx[i] * an.syy[i]
+		if denom == 0 {
+			dst[i] = 0
+			continue
+		}
+		mag := cmplx.Abs(sxy)
+		dst[i] = float32(mag * mag / denom)
+	}
+	return nil
+}
+
+// Phase will write the phase difference (in radians) between the two
+// receivers in each bin to dst. This is the phase of the first receiver
+// relative to the second, so a positive phase means the signal in that bin
+// arrived at the first receiver "ahead" of the second.
+func (an *Analyzer) Phase(dst []float32) error {
+	if an.frames == 0 {
+		return ErrNoFrames
+	}
+	if len(dst) < len(an.sxy) {
+		return sdr.ErrDstTooSmall
+	}
+
+	for i, sxy := range an.sxy {
+		dst[i] = float32(cmplx.Phase(sxy))
+	}
+	return nil
+}
+
+// Reset will clear the running average, without releasing the FFT plans.
+func (an *Analyzer) Reset() {
+	for i := range an.sxy {
+		an.sxx[i] = 0
+		an.syy[i] = 0
+		an.sxy[i] = 0
+	}
+	an.frames = 0
+}
+
+// Read will read `frames` frames from each of the Readers in lockstep,
+// adding each pair to the Analyzer.
+//
+// The Readers are expected to be coherent and time-aligned already; this
+// will not attempt to line the two streams up.
+func (an *Analyzer) Read(a, b sdr.Reader, frames int) error {
+	if a.SampleRate() != b.SampleRate() || a.SampleRate() != an.sampleRate {
+		return ErrSampleRateMismatch
+	}
+	if a.SampleFormat() != sdr.SampleFormatC64 || b.SampleFormat() != sdr.SampleFormatC64 {
+		return sdr.ErrSampleFormatUnknown
+	}
+
+	var (
+		bufA = make(sdr.SamplesC64, an.Length())
+		bufB = make(sdr.SamplesC64, an.Length())
+	)
+
+	for i := 0; i < frames; i++ {
+		if _, err := sdr.ReadFull(a, bufA); err != nil {
+			return err
+		}
+		if _, err := sdr.ReadFull(b, bufB); err != nil {
+			return err
+		}
+		if err := an.Add(bufA, bufB); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close will release the FFT plans.
+func (an *Analyzer) Close() error {
+	errA := an.planA.Close()
+	errB := an.planB.Close()
+	if errA != nil {
+		return errA
+	}
+	return errB
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,118 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package coherence_test
+
+import (
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/coherence"
+	"hz.tools/sdr/testutils"
+)
+
+func frames(r *rand.Rand, n int, phase float64) (sdr.SamplesC64, sdr.SamplesC64) {
+	a := make(sdr.SamplesC64, n)
+	b := make(sdr.SamplesC64, n)
+	for i := range a {
+		tone := cmplx.Exp(complex(0, 2*math.Pi*8*float64(i)/float64(n)))
+		a[i] = complex64(tone*cmplx.Exp(complex(0, phase)) + complex(r.NormFloat64()*0.1, r.NormFloat64()*0.1))
+		b[i] = complex64(tone + complex(r.NormFloat64()*0.1, r.NormFloat64()*0.1))
+	}
+	return a, b
+}
+
+func TestAnalyzer(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	an, err := coherence.NewAnalyzer(testutils.DFTPlanner, 64, 64)
+	assert.NoError(t, err)
+	defer an.Close()
+
+	_, err = an.CrossSpectrum()
+	assert.Equal(t, coherence.ErrNoFrames, err)
+
+	for i := 0; i < 32; i++ {
+		a, b := frames(r, 64, 0.5)
+		assert.NoError(t, an.Add(a, b))
+	}
+	assert.Equal(t, 32, an.Frames())
+
+	cs, err := an.CrossSpectrum()
+	assert.NoError(t, err)
+	bin, err := cs.BinByFreq(8)
+	assert.NoError(t, err)
+	assert.Equal(t, 8, bin)
+	assert.InDelta(t, 1, cmplx.Abs(complex128(cs.Frequency[bin])), 0.05)
+
+	coh := make([]float32, 64)
+	assert.NoError(t, an.Coherence(coh))
+	assert.InDelta(t, 1, coh[8], 0.01)
+	// Noise alone is uncorrelated, so should trend down towards zero.
+	assert.True(t, coh[32] < 0.3, "coherence %f", coh[32])
+
+	phase := make([]float32, 64)
+	assert.NoError(t, an.Phase(phase))
+	assert.InDelta(t, 0.5, phase[8], 0.02)
+
+	an.Reset()
+	assert.Equal(t, 0, an.Frames())
+	assert.Equal(t, coherence.ErrNoFrames, an.Coherence(coh))
+	assert.Equal(t, sdr.ErrDstTooSmall, an.Add(make(sdr.SamplesC64, 32), make(sdr.SamplesC64, 64)))
+}
+
+func TestAnalyzerRead(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	an, err := coherence.NewAnalyzer(testutils.DFTPlanner, 64, 64)
+	assert.NoError(t, err)
+	defer an.Close()
+
+	pipeA, writerA := sdr.Pipe(64, sdr.SampleFormatC64)
+	pipeB, writerB := sdr.Pipe(64, sdr.SampleFormatC64)
+
+	go func() {
+		for i := 0; i < 8; i++ {
+			a, b := frames(r, 64, -1)
+			if _, err := writerA.Write(a); err != nil {
+				return
+			}
+			if _, err := writerB.Write(b); err != nil {
+				return
+			}
+		}
+	}()
+
+	assert.NoError(t, an.Read(pipeA, pipeB, 8))
+
+	phase := make([]float32, 64)
+	assert.NoError(t, an.Phase(phase))
+	assert.InDelta(t, -1, phase[8], 0.05)
+
+	pipeC, _ := sdr.Pipe(128, sdr.SampleFormatC64)
+	assert.Equal(t, coherence.ErrSampleRateMismatch, an.Read(pipeA, pipeC, 1))
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,343 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package commonview
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"sort"
+	"time"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/fft"
+)
+
+var (
+	// ErrCarrierOutOfBand will be returned if the carrier (plus or minus the
+	// search width) isn't inside the captured bandwidth.
+	ErrCarrierOutOfBand = fmt.Errorf("commonview: carrier is outside the captured bandwidth")
+
+	// ErrNoCarrier will be returned if the strongest signal in the search
+	// window isn't at least MinSNR above the noise around it.
+	ErrNoCarrier = fmt.Errorf("commonview: carrier not found in the search window")
+
+	// ErrTooShort will be returned if there aren't enough samples to
+	// estimate the frequency of the carrier.
+	ErrTooShort = fmt.Errorf("commonview: not enough samples to measure the carrier")
+)
+
+// Config configures the measurement of a common carrier.
+type Config struct {
+	// Carrier is the nominal frequency of the common carrier. This is
+	// required.
+	Carrier rf.Hz
+
+	// CenterFrequency is the frequency the receiver is tuned to. This is
+	// required.
+	CenterFrequency rf.Hz
+
+	// SearchWidth is how far either side of the nominal Carrier frequency
+	// to look for the carrier. This needs to cover the worst-case error of
+	// both the receiver and the transmitter. If 0, this will default to
+	// 2 kHz.
+	SearchWidth rf.Hz
+
+	// Duration is how many seconds of samples Measure will read. Longer
+	// measurements are more precise, as long as the oscillators are stable
+	// over the measurement. If 0, this will default to 1 second.
+	Duration time.Duration
+
+	// Size is the FFT size used to find the carrier, which must be a power
+	// of two. If 0, this will default to 16384.
+	Size int
+
+	// MinSNR is how far above the noise floor (in dB) the carrier has to be
+	// before it's used. If 0, this will default to 10 dB.
+	MinSNR float64
+
+	// Planner is the FFT planner used to find the carrier. If nil, this will
+	// default to fft.DefaultPlanner.
+	Planner fft.Planner
+}
+
+func (c Config) getSearchWidth() rf.Hz {
+	if c.SearchWidth == 0 {
+		return 2 * rf.KHz
+	}
+	return c.SearchWidth
+}
+
+func (c Config) getDuration() time.Duration {
+	if c.Duration == 0 {
+		return time.Second
+	}
+	return c.Duration
+}
+
+func (c Config) getSize() int {
+	if c.Size == 0 {
+		return 16384
+	}
+	return c.Size
+}
+
+func (c Config) getMinSNR() float64 {
+	if c.MinSNR == 0 {
+		return 10
+	}
+	return c.MinSNR
+}
+
+func (c Config) getPlanner() fft.Planner {
+	if c.Planner == nil {
+		return fft.DefaultPlanner
+	}
+	return c.Planner
+}
+
+// Measurement is one station's observation of the common carrier. It's
+// tagged for encoding/json, so that stations can send their Measurements
+// to each other (or to a central point) to be compared.
+type Measurement struct {
+	// Time is when the samples the Measurement was made from started, from
+	// the station's clock.
+	Time time.Time `json:"time"`
+
+	// Carrier is the nominal frequency of the common carrier.
+	Carrier rf.Hz `json:"carrier"`
+
+	// Offset is how far (in Hz) the carrier was observed from its nominal
+	// frequency.
+	Offset float64 `json:"offset"`
+
+	// SNR is how far the carrier was above the noise floor, in dB.
+	SNR float64 `json:"snr"`
+}
+
+// FractionalOffset returns the Offset as a fraction of the Carrier
+// frequency.
+//
+// An oscillator running fast by some fraction will tune (and sample) a bit
+// high, so the carrier is observed low by the same fraction; this returns
+// the error of the oscillator (fast is positive), with any error of the
+// transmitter mixed in.
+func (m Measurement) FractionalOffset() float64 {
+	return -m.Offset / float64(m.Carrier)
+}
+
+// PPM returns the FractionalOffset in parts per million.
+func (m Measurement) PPM() float64 {
+	return m.FractionalOffset() * 1e6
+}
+
+// Measure will read Config.Duration worth of samples from r, and measure the
+// frequency of the common carrier. The Reader is converted to
+// SampleFormatC64 if needed.
+func Measure(r sdr.Reader, cfg Config) (*Measurement, error) {
+	n := int(cfg.getDuration().Seconds() * float64(r.SampleRate()))
+	if n < cfg.getSize() {
+		return nil, ErrTooShort
+	}
+
+	buf, err := sdr.MakeSamples(r.SampleFormat(), n)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	if _, err := sdr.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+
+	iq, ok := buf.(sdr.SamplesC64)
+	if !ok {
+		iq = make(sdr.SamplesC64, n)
+		if _, err := sdr.ConvertBuffer(iq, buf); err != nil {
+			return nil, err
+		}
+	}
+
+	m, err := Estimate(iq, r.SampleRate(), cfg)
+	if err != nil {
+		return nil, err
+	}
+	m.Time = start
+	return m, nil
+}
+
+// Estimate will measure the frequency of the common carrier in the provided
+// buffer of samples. The Time of the returned Measurement is left for the
+// caller to fill in.
+//
+// The carrier is found to within an FFT bin by averaging the spectrum over
+// the whole buffer, and then refined by mixing it to DC, integrating it down
+// to a narrow bandwidth, and measuring how quickly its phase turns.
+func Estimate(iq sdr.SamplesC64, sampleRate uint, cfg Config) (*Measurement, error) {
+	var (
+		size     = cfg.getSize()
+		fs       = float64(sampleRate)
+		expected = float64(cfg.Carrier - cfg.CenterFrequency)
+		width    = float64(cfg.getSearchWidth())
+		binWidth = fs / float64(size)
+	)
+
+	if size <= 0 || size&(size-1) != 0 {
+		return nil, fmt.Errorf("commonview: FFT size must be a power of two")
+	}
+	if cfg.Carrier <= 0 {
+		return nil, fmt.Errorf("commonview: no carrier frequency configured")
+	}
+	if math.Abs(expected)+width >= fs/2 {
+		return nil, ErrCarrierOutOfBand
+	}
+	if len(iq) < size {
+		return nil, ErrTooShort
+	}
+
+	power, err := averagePower(cfg.getPlanner(), iq, size)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		lo    = int(math.Floor((expected - width) / binWidth))
+		hi    = int(math.Ceil((expected + width) / binWidth))
+		peak  = lo
+		noise = make([]float64, 0, hi-lo+1)
+	)
+	for bin := lo; bin <= hi; bin++ {
+		p := power[(bin+size)%size]
+		noise = append(noise, p)
+		if p > power[(peak+size)%size] {
+			peak = bin
+		}
+	}
+	sort.Float64s(noise)
+
+	peakPower := power[(peak+size)%size]
+	if peakPower == 0 {
+		return nil, ErrNoCarrier
+	}
+	// The median bin is used as the noise floor, clamped to keep the SNR
+	// finite (and encodable as JSON) for a perfectly clean carrier.
+	floor := math.Max(noise[len(noise)/2], peakPower*1e-30)
+	snr := 10 * math.Log10(peakPower/floor)
+	if snr < cfg.getMinSNR() {
+		return nil, ErrNoCarrier
+	}
+
+	coarse := float64(peak) * binWidth
+	fine, err := refine(iq, fs, coarse, size/8)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Measurement{
+		Carrier: cfg.Carrier,
+		Offset:  coarse + fine - expected,
+		SNR:     snr,
+	}, nil
+}
+
+// averagePower returns the power in each bin (in the natural FFT order) of
+// the Hann windowed FFT of each size-sample block of iq, summed together.
+func averagePower(planner fft.Planner, iq sdr.SamplesC64, size int) ([]float64, error) {
+	var (
+		block  = make(sdr.SamplesC64, size)
+		freq   = make([]complex64, size)
+		power  = make([]float64, size)
+		window = make([]float32, size)
+	)
+	for i := range window {
+		window[i] = float32(0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(size)))
+	}
+
+	plan, err := planner(block, freq, fft.Forward)
+	if err != nil {
+		return nil, err
+	}
+	defer plan.Close()
+
+	for start := 0; start+size <= len(iq); start += size {
+		for i := range block {
+			block[i] = iq[start+i] * complex(window[i], 0)
+		}
+		if err := plan.Transform(); err != nil {
+			return nil, err
+		}
+		for i, v := range freq {
+			power[i] += float64(real(v)*real(v) + imag(v)*imag(v))
+		}
+	}
+	return power, nil
+}
+
+// refine will mix the carrier at 'coarse' Hz down to DC, sum each block of
+// 'dump' samples together (a narrow low-pass filter, leaving a sample rate
+// of a few FFT bins), and return the remaining frequency of the carrier from
+// the phase turned between the sums.
+//
+// The phase difference between consecutive sums gives an unambiguous but
+// noisy estimate, which is then refined by looking at sums further and
+// further apart, doubling the lag each time. Each step is precise enough
+// that the next one can't wrap around.
+func refine(iq sdr.SamplesC64, fs, coarse float64, dump int) (float64, error) {
+	if dump < 1 {
+		dump = 1
+	}
+	if len(iq) < dump*2 {
+		return 0, ErrTooShort
+	}
+
+	var (
+		step  = -2 * math.Pi * coarse / fs
+		phase float64
+		acc   complex128
+		sums  = make([]complex128, 0, len(iq)/dump)
+	)
+	for i, v := range iq {
+		im, rl := math.Sincos(phase)
+		acc += complex128(v) * complex(rl, im)
+		phase = math.Mod(phase+step, 2*math.Pi)
+
+		if (i+1)%dump == 0 {
+			sums = append(sums, acc)
+			acc = 0
+		}
+	}
+
+	var (
+		period = float64(dump) / fs
+		freq   float64
+	)
+	for lag := 1; lag <= len(sums)/2 || lag == 1; lag *= 2 {
+		var corr complex128
+		for k := 0; k+lag < len(sums); k++ {
+			corr += sums[k+lag] * cmplx.Conj(sums[k])
+		}
+		corr *= cmplx.Rect(1, -2*math.Pi*freq*float64(lag)*period)
+		freq += cmplx.Phase(corr) / (2 * math.Pi * float64(lag) * period)
+	}
+	return freq, nil
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,143 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package commonview_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/commonview"
+	"hz.tools/sdr/expr"
+)
+
+const sampleRate = 256000
+
+var (
+	carrier = 100 * rf.MHz
+	center  = carrier - 50*rf.KHz
+)
+
+// station returns a second of samples of the carrier, as seen by a station
+// whose oscillator is off by 'station' (as a fraction), with the
+// transmitter off by 'transmitter'.
+func station(seed int64, transmitter, station float64) sdr.SamplesC64 {
+	var (
+		rng  = rand.New(rand.NewSource(seed))
+		freq = float64(carrier)*(1+transmitter)*(1-station) - float64(center)
+		buf  = make(sdr.SamplesC64, sampleRate)
+		ph   = rng.Float64() * 2 * math.Pi
+	)
+	for i := range buf {
+		im, rl := math.Sincos(ph + 2*math.Pi*freq*float64(i)/sampleRate)
+		buf[i] = complex64(complex(0.1*rl+0.2*rng.NormFloat64(), 0.1*im+0.2*rng.NormFloat64()))
+	}
+	return buf
+}
+
+var cfg = commonview.Config{
+	Carrier:         carrier,
+	CenterFrequency: center,
+}
+
+func TestEstimate(t *testing.T) {
+	m, err := commonview.Estimate(station(1, 0, 1e-6), sampleRate, cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, carrier, m.Carrier)
+	assert.InDelta(t, -100, m.Offset, 0.05)
+	assert.InDelta(t, 1, m.PPM(), 0.001)
+	assert.True(t, m.SNR > 10)
+}
+
+func TestCompare(t *testing.T) {
+	var transmitter = 3e-7
+
+	a, err := commonview.Estimate(station(1, transmitter, 1e-6), sampleRate, cfg)
+	assert.NoError(t, err)
+	b, err := commonview.Estimate(station(2, transmitter, -2e-6), sampleRate, cfg)
+	assert.NoError(t, err)
+
+	// Each station sees the error of the transmitter...
+	assert.InDelta(t, 0.7, a.PPM(), 0.001)
+	assert.InDelta(t, -2.3, b.PPM(), 0.001)
+
+	// ...but not in the Difference.
+	d, err := commonview.Compare(*a, *b)
+	assert.NoError(t, err)
+	assert.InDelta(t, -3, d.PPM(), 0.001)
+	assert.InDelta(t, -300, float64(d.At(carrier)), 0.1)
+
+	other := *b
+	other.Carrier = carrier + rf.MHz
+	_, err = commonview.Compare(*a, other)
+	assert.Equal(t, commonview.ErrCarrierMismatch, err)
+}
+
+func TestEstimateErrors(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	noise := make(sdr.SamplesC64, sampleRate)
+	for i := range noise {
+		noise[i] = complex64(complex(rng.NormFloat64(), rng.NormFloat64()))
+	}
+	_, err := commonview.Estimate(noise, sampleRate, cfg)
+	assert.Equal(t, commonview.ErrNoCarrier, err)
+
+	_, err = commonview.Estimate(noise, sampleRate, commonview.Config{
+		Carrier:         carrier,
+		CenterFrequency: carrier - 200*rf.KHz,
+	})
+	assert.Equal(t, commonview.ErrCarrierOutOfBand, err)
+
+	_, err = commonview.Estimate(noise[:100], sampleRate, cfg)
+	assert.Equal(t, commonview.ErrTooShort, err)
+}
+
+func TestMeasureCorrect(t *testing.T) {
+	// The Remote station is 2 ppm fast, so sees the carrier 200 Hz low.
+	r, err := expr.NewReader("0.1*exp(2i*pi*49800*t) + n(0.1)", expr.Config{
+		SampleRate: sampleRate,
+		Seed:       1,
+	})
+	assert.NoError(t, err)
+
+	remote, err := commonview.Measure(r, cfg)
+	assert.NoError(t, err)
+	assert.False(t, remote.Time.IsZero())
+	assert.InDelta(t, 2, remote.PPM(), 0.001)
+
+	d, err := commonview.Compare(commonview.Measurement{Carrier: carrier}, *remote)
+	assert.NoError(t, err)
+
+	corrected, err := commonview.Correct(r, *d, center)
+	assert.NoError(t, err)
+	m, err := commonview.Measure(corrected, cfg)
+	assert.NoError(t, err)
+
+	// The correction is right at the center frequency; the carrier is 50 kHz
+	// off center, where the 2 ppm sample rate error leaves 0.1 Hz.
+	assert.InDelta(t, -0.1, m.Offset, 0.01)
+}
+
+// vim: foldmethod=marker
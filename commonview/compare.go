@@ -0,0 +1,109 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package commonview
+
+import (
+	"fmt"
+	"time"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/stream"
+)
+
+var (
+	// ErrCarrierMismatch will be returned if two Measurements being compared
+	// weren't made of the same carrier.
+	ErrCarrierMismatch = fmt.Errorf("commonview: measurements are of different carriers")
+)
+
+// Difference is the relative frequency error between a Remote station and
+// the Reference station, computed from their Measurements of the same
+// carrier. Any error of the transmitter cancels out.
+type Difference struct {
+	// Reference is the Measurement from the station everyone else is being
+	// corrected to match.
+	Reference Measurement
+
+	// Remote is the Measurement from the station being corrected.
+	Remote Measurement
+}
+
+// Compare will compute the Difference between two Measurements of the same
+// carrier.
+//
+// The carrier's error only cancels if it hasn't drifted between the two
+// Measurements, so they should be made at about the same time. Skew will
+// return how far apart they were, according to the station clocks.
+func Compare(reference, remote Measurement) (*Difference, error) {
+	if reference.Carrier != remote.Carrier {
+		return nil, ErrCarrierMismatch
+	}
+	return &Difference{
+		Reference: reference,
+		Remote:    remote,
+	}, nil
+}
+
+// Skew returns how long after the Reference Measurement the Remote
+// Measurement was made.
+func (d Difference) Skew() time.Duration {
+	return d.Remote.Time.Sub(d.Reference.Time)
+}
+
+// FractionalOffset returns how fast the Remote station's oscillator is
+// running relative to the Reference station's, as a fraction.
+func (d Difference) FractionalOffset() float64 {
+	return d.Remote.FractionalOffset() - d.Reference.FractionalOffset()
+}
+
+// PPM returns the FractionalOffset in parts per million.
+func (d Difference) PPM() float64 {
+	return d.FractionalOffset() * 1e6
+}
+
+// At returns the frequency error of the Remote station relative to the
+// Reference station when tuned to the provided frequency. A signal will be
+// observed this far lower by the Remote station than by the Reference
+// station.
+func (d Difference) At(freq rf.Hz) rf.Hz {
+	return rf.Hz(float64(freq) * d.FractionalOffset())
+}
+
+// Correct will return a Reader that shifts the samples from the Remote
+// station (tuned to centerFrequency) so that signals line up in frequency
+// with the Reference station.
+//
+// Only the frequency is corrected; the sample rate error is a
+// FractionalOffset of the sample rate, which is usually small enough to
+// ignore, but will still cause the two stations to slowly slip in time.
+func Correct(r sdr.Reader, d Difference, centerFrequency rf.Hz) (sdr.Reader, error) {
+	if r.SampleFormat() != sdr.SampleFormatC64 {
+		var err error
+		r, err = stream.ConvertReader(r, sdr.SampleFormatC64)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return stream.ShiftReader(r, d.At(centerFrequency))
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,36 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+// Package commonview contains tools to measure and correct the relative
+// frequency error between the reference oscillators of two (or more) SDRs
+// at different stations, by having each of them observe the same ambient
+// carrier (such as a broadcast station or a beacon), in the same way GPS
+// common-view time transfer works.
+//
+// Each station measures where the common carrier shows up relative to where
+// it should be, which is the sum of the station's own oscillator error and
+// the error of the transmitter. Since the transmitter's error is common to
+// every station, subtracting two Measurements leaves only the difference
+// between the two stations, which can then be used to correct one to match
+// the other. This is good enough for loosely-coherent experiments (such as
+// TDOA or distributed spectrum monitoring) without a GPSDO at each site.
+package commonview
+
+// vim: foldmethod=marker
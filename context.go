@@ -0,0 +1,249 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sdr
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ContextReceiver is a Receiver which can tie the lifetime of an rx stream
+// to a context.Context itself, rather than having StartRxWithContext watch
+// the context and Close the stream.
+type ContextReceiver interface {
+	Receiver
+
+	// StartRxWithContext is StartRx, but the returned stream will be torn
+	// down when the context is done. Samples the driver had already
+	// buffered may still be read before the context's error is returned.
+	StartRxWithContext(context.Context) (ReadCloser, error)
+}
+
+// ContextTransmitter is a Transmitter which can tie the lifetime of a tx
+// stream to a context.Context itself, rather than having StartTxWithContext
+// watch the context and Close the stream.
+type ContextTransmitter interface {
+	Transmitter
+
+	// StartTxWithContext is StartTx, but the returned stream will be torn
+	// down when the context is done.
+	StartTxWithContext(context.Context) (WriteCloser, error)
+}
+
+// StartRxWithContext will start an rx stream on the Receiver, which will
+// be Closed when the context is done. Closing a stream tears down any
+// goroutines and C-side streams the driver has running for it, so this
+// saves having to remember to Close exactly the right object on every
+// path out of a function.
+//
+// Once the context is done, Read will return the context's error. The
+// stream may still be Closed by hand before then, and only the first Close
+// goes through to the driver.
+func StartRxWithContext(ctx context.Context, r Receiver) (ReadCloser, error) {
+	if cr, ok := r.(ContextReceiver); ok {
+		return cr.StartRxWithContext(ctx)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	rc, err := r.StartRx()
+	if err != nil {
+		return nil, err
+	}
+	return ReadCloserWithContext(ctx, rc), nil
+}
+
+// StartTxWithContext will start a tx stream on the Transmitter, which will
+// be Closed when the context is done, the same way as StartRxWithContext.
+func StartTxWithContext(ctx context.Context, t Transmitter) (WriteCloser, error) {
+	if ct, ok := t.(ContextTransmitter); ok {
+		return ct.StartTxWithContext(ctx)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	wc, err := t.StartTx()
+	if err != nil {
+		return nil, err
+	}
+	return WriteCloserWithContext(ctx, wc), nil
+}
+
+// contextCloser will Close the wrapped Closer (once) when the context is
+// done, or when close is called, whichever happens first.
+type contextCloser struct {
+	ctx    context.Context
+	closer Closer
+	once   *sync.Once
+	done   chan struct{}
+	err    error
+}
+
+func newContextCloser(ctx context.Context, closer Closer) *contextCloser {
+	cc := &contextCloser{
+		ctx:    ctx,
+		closer: closer,
+		once:   &sync.Once{},
+		done:   make(chan struct{}),
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			cc.close()
+		case <-cc.done:
+		}
+	}()
+	return cc
+}
+
+func (cc *contextCloser) close() error {
+	cc.once.Do(func() {
+		close(cc.done)
+		cc.err = cc.closer.Close()
+	})
+	return cc.err
+}
+
+// check will return the context's error if it's done, so that a stream
+// which is closed because the context was cancelled returns why.
+func (cc *contextCloser) check(err error) error {
+	if ctxErr := cc.ctx.Err(); ctxErr != nil && err != nil {
+		return ctxErr
+	}
+	return err
+}
+
+// writeTo will call WriteTo on the wrapped stream. Since WriteTo returns a
+// nil error when the stream is closed, the context's error is returned if
+// it's done, even if WriteTo didn't return an error.
+func (cc *contextCloser) writeTo(wt WriterTo, w Writer) (int64, error) {
+	if err := cc.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := wt.WriteTo(w)
+	if ctxErr := cc.ctx.Err(); ctxErr != nil {
+		return n, ctxErr
+	}
+	return n, err
+}
+
+type contextReadCloser struct {
+	Reader
+	cc *contextCloser
+}
+
+func (crc contextReadCloser) Read(s Samples) (int, error) {
+	if err := crc.cc.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := crc.Reader.Read(s)
+	return n, crc.cc.check(err)
+}
+
+func (crc contextReadCloser) Close() error {
+	return crc.cc.close()
+}
+
+type contextTimedReadCloser struct {
+	contextReadCloser
+	tr TimedReader
+}
+
+func (ctrc contextTimedReadCloser) ReadTimed(s Samples) (int, time.Time, error) {
+	if err := ctrc.cc.ctx.Err(); err != nil {
+		return 0, time.Time{}, err
+	}
+	n, ts, err := ctrc.tr.ReadTimed(s)
+	return n, ts, ctrc.cc.check(err)
+}
+
+type contextWriterToReadCloser struct {
+	contextReadCloser
+	wt WriterTo
+}
+
+func (cwrc contextWriterToReadCloser) WriteTo(w Writer) (int64, error) {
+	return cwrc.cc.writeTo(cwrc.wt, w)
+}
+
+type contextTimedWriterToReadCloser struct {
+	contextTimedReadCloser
+	wt WriterTo
+}
+
+func (ctwrc contextTimedWriterToReadCloser) WriteTo(w Writer) (int64, error) {
+	return ctwrc.cc.writeTo(ctwrc.wt, w)
+}
+
+// ReadCloserWithContext will wrap the ReadCloser, and Close it when the
+// context is done. If the ReadCloser is a TimedReadCloser, or a WriterTo,
+// so is the returned ReadCloser.
+func ReadCloserWithContext(ctx context.Context, rc ReadCloser) ReadCloser {
+	crc := contextReadCloser{
+		Reader: rc,
+		cc:     newContextCloser(ctx, rc),
+	}
+	tr, timed := rc.(TimedReader)
+	wt, writerTo := rc.(WriterTo)
+
+	switch {
+	case timed && writerTo:
+		return contextTimedWriterToReadCloser{
+			contextTimedReadCloser: contextTimedReadCloser{contextReadCloser: crc, tr: tr},
+			wt:                     wt,
+		}
+	case timed:
+		return contextTimedReadCloser{contextReadCloser: crc, tr: tr}
+	case writerTo:
+		return contextWriterToReadCloser{contextReadCloser: crc, wt: wt}
+	default:
+		return crc
+	}
+}
+
+type contextWriteCloser struct {
+	Writer
+	cc *contextCloser
+}
+
+func (cwc contextWriteCloser) Write(s Samples) (int, error) {
+	if err := cwc.cc.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := cwc.Writer.Write(s)
+	return n, cwc.cc.check(err)
+}
+
+func (cwc contextWriteCloser) Close() error {
+	return cwc.cc.close()
+}
+
+// WriteCloserWithContext will wrap the WriteCloser, and Close it when the
+// context is done.
+func WriteCloserWithContext(ctx context.Context, wc WriteCloser) WriteCloser {
+	return contextWriteCloser{
+		Writer: wc,
+		cc:     newContextCloser(ctx, wc),
+	}
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,121 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sdr_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+)
+
+func TestReadCloserWithContextCancel(t *testing.T) {
+	pipeReader, pipeWriter := sdr.Pipe(0, sdr.SampleFormatC64)
+	defer pipeWriter.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rc := sdr.ReadCloserWithContext(ctx, pipeReader)
+
+	go func() {
+		pipeWriter.Write(make(sdr.SamplesC64, 1024))
+	}()
+	n, err := sdr.ReadFull(rc, make(sdr.SamplesC64, 1024))
+	assert.NoError(t, err)
+	assert.Equal(t, 1024, n)
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := rc.Read(make(sdr.SamplesC64, 1024))
+		errs <- err
+	}()
+	cancel()
+	assert.Equal(t, context.Canceled, <-errs)
+
+	// The underlying pipe should now be closed as well.
+	_, err = pipeWriter.Write(make(sdr.SamplesC64, 1024))
+	assert.Error(t, err)
+	assert.NoError(t, rc.Close())
+}
+
+func TestReadCloserWithContextWriteTo(t *testing.T) {
+	pipeReader, pipeWriter := sdr.Pipe(0, sdr.SampleFormatC64)
+	defer pipeWriter.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rc := sdr.ReadCloserWithContext(ctx, pipeReader)
+
+	// The Pipe's WriteTo should still be reachable through the wrapper.
+	wt, ok := rc.(sdr.WriterTo)
+	assert.True(t, ok)
+
+	sinkReader, sinkWriter := sdr.Pipe(0, sdr.SampleFormatC64)
+	defer sinkReader.Close()
+
+	type result struct {
+		n   int64
+		err error
+	}
+	results := make(chan result, 1)
+	go func() {
+		n, err := wt.WriteTo(sinkWriter)
+		results <- result{n, err}
+	}()
+
+	go func() {
+		pipeWriter.Write(make(sdr.SamplesC64, 1024))
+	}()
+	n, err := sdr.ReadFull(sinkReader, make(sdr.SamplesC64, 1024))
+	assert.NoError(t, err)
+	assert.Equal(t, 1024, n)
+
+	cancel()
+	r := <-results
+	assert.Equal(t, int64(1024), r.n)
+	assert.Equal(t, context.Canceled, r.err)
+}
+
+func TestWriteCloserWithContextClose(t *testing.T) {
+	pipeReader, pipeWriter := sdr.Pipe(0, sdr.SampleFormatC64)
+	defer pipeReader.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	wc := sdr.WriteCloserWithContext(ctx, pipeWriter)
+
+	assert.NoError(t, wc.Close())
+	_, err := pipeReader.Read(make(sdr.SamplesC64, 1024))
+	assert.Error(t, err)
+
+	// Cancelling after a Close must not Close the pipe a second time.
+	cancel()
+	assert.NoError(t, wc.Close())
+}
+
+func TestStartRxWithContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := sdr.StartRxWithContext(ctx, nil)
+	assert.Equal(t, context.Canceled, err)
+}
+
+// vim: foldmethod=marker
@@ -33,13 +33,15 @@ var (
 //         ===   Table of Conversions, what's implemented?  ===
 //
 //
-//      | u8| i8|i16|c64|
-//      +---+---+---+---+   Currently, all conversions are supported, but this
-//  u8  | o | ✓ | ✓ | ✓ |   may change as new (or exotic) formats are added.
-//  i8  | ✓ | o | ✓ | ✓ |   There may come a time where some format only supports
-//  i16 | ✓ | ✓ | o | ✓ |   converting into, say, complex64, since most code
-//  c64 | ✓ | ✓ | ✓ | o |   works in complex64.
-//      +---+---+---+---+
+//       | u8| i8|i12|i16|c64|c128|
+//       +---+---+---+---+---+----+  Currently, all conversions are supported,
+//  u8   | o | ✓ | ✓ | ✓ | ✓ |  ✓ |  but this may change as new (or exotic)
+//  i8   | ✓ | o | ✓ | ✓ | ✓ |  ✓ |  formats are added. There may come a time
+//  i12  | ✓ | ✓ | o | ✓ | ✓ |  ✓ |  where some format only supports
+//  i16  | ✓ | ✓ | ✓ | o | ✓ |  ✓ |  converting into, say, complex64, since
+//  c64  | ✓ | ✓ | ✓ | ✓ | o |  ✓ |  most code works in complex64.
+//  c128 | ✓ | ✓ | ✓ | ✓ | ✓ |  o |
+//       +---+---+---+---+---+----+
 //
 //
 //
@@ -86,6 +88,22 @@ func ConvertBuffer(dst, src Samples) (int, error) {
 			return 0, ErrConversionNotImplemented
 		}
 		return convertible.ToC64(dst.(SamplesC64))
+	case SampleFormatC128:
+		convertible, ok := src.(interface {
+			ToC128(SamplesC128) (int, error)
+		})
+		if !ok {
+			return 0, ErrConversionNotImplemented
+		}
+		return convertible.ToC128(dst.(SamplesC128))
+	case SampleFormatI12Packed:
+		convertible, ok := src.(interface {
+			ToI12Packed(SamplesI12Packed) (int, error)
+		})
+		if !ok {
+			return 0, ErrConversionNotImplemented
+		}
+		return convertible.ToI12Packed(dst.(SamplesI12Packed))
 	default:
 		// Someone added a new type on us
 		return 0, ErrSampleFormatUnknown
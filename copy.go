@@ -46,16 +46,48 @@ func CopySamples(dst, src Samples) (int, error) {
 	case SamplesC64:
 		src := src.(SamplesC64)
 		return copy(dst, src), nil
+	case SamplesC128:
+		src := src.(SamplesC128)
+		return copy(dst, src), nil
+	case SamplesI12Packed:
+		src := src.(SamplesI12Packed)
+		return copy(dst, src), nil
 	default:
 		return 0, ErrSampleFormatUnknown
 	}
 }
 
+// WriterTo is the interface that wraps the WriteTo method.
+//
+// This mirrors io.WriterTo. If the src passed to Copy implements WriterTo,
+// Copy will call WriteTo rather than Reading into an intermediate buffer,
+// which allows a Reader to hand its internal buffers directly to the Writer.
+type WriterTo interface {
+	// WriteTo will write samples to w until there's no more data to write, or
+	// an error is encountered. The number of samples written is returned.
+	WriteTo(w Writer) (int64, error)
+}
+
+// ReaderFrom is the interface that wraps the ReadFrom method.
+//
+// This mirrors io.ReaderFrom. If the dst passed to Copy implements
+// ReaderFrom (and the src does not implement WriterTo), Copy will call
+// ReadFrom rather than Reading into an intermediate buffer.
+type ReaderFrom interface {
+	// ReadFrom will read samples from r until EOF or an error. The number of
+	// samples read is returned.
+	ReadFrom(r Reader) (int64, error)
+}
+
 // Copy will copy samples from the src sdr.Reader to the dst sdr.Writer.
 //
 // The Reader and Writer must be of the same SampleFormat. If not, that will
 // return an error, and the caller should explicitly define how and where to
 // convert the two formats.
+//
+// If src implements WriterTo, the copy is done by calling src.WriteTo(dst).
+// Otherwise, if dst implements ReaderFrom, the copy is done by calling
+// dst.ReadFrom(src).
 func Copy(dst Writer, src Reader) (int64, error) {
 	if dst.SampleFormat() != src.SampleFormat() {
 		return 0, ErrSampleFormatMismatch
@@ -65,6 +97,9 @@ func Copy(dst Writer, src Reader) (int64, error) {
 
 // CopyBuffer will copy samples from the src sdr.Reader to the dst sdr.Writer
 // using the provided Buffer.
+//
+// Like Copy, if either src implements WriterTo, or dst implements ReaderFrom,
+// buf will not be used.
 func CopyBuffer(dst Writer, src Reader, buf Samples) (int64, error) {
 	if dst.SampleFormat() != src.SampleFormat() {
 		return 0, ErrSampleFormatMismatch
@@ -78,6 +113,13 @@ func CopyBuffer(dst Writer, src Reader, buf Samples) (int64, error) {
 // copyBuffer will copy data from the src into the dst, using the buffer `buf`
 // to move the data. If buf is nil, the size will be 1024*32.
 func copyBuffer(dst Writer, src Reader, buf Samples) (int64, error) {
+	if wt, ok := src.(WriterTo); ok {
+		return wt.WriteTo(dst)
+	}
+	if rf, ok := dst.(ReaderFrom); ok {
+		return rf.ReadFrom(src)
+	}
+
 	var (
 		err     error
 		written int64
@@ -21,8 +21,10 @@
 package sdr_test
 
 import (
+	"bytes"
+	"encoding/binary"
+	"io"
 	"sync"
-
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -150,4 +152,56 @@ func TestCopyBufferU8(t *testing.T) {
 	wg.Wait()
 }
 
+type countingWriter struct {
+	sdr.Writer
+	writes int
+}
+
+func (cw *countingWriter) Write(s sdr.Samples) (int, error) {
+	cw.writes++
+	return cw.Writer.Write(s)
+}
+
+func TestCopyPipeWriteTo(t *testing.T) {
+	pipeReader, pipeWriter := sdr.Pipe(0, sdr.SampleFormatC64)
+	_, ok := pipeReader.(sdr.WriterTo)
+	assert.True(t, ok)
+
+	go func() {
+		buf := make(sdr.SamplesC64, 1024*64)
+		buf[1024*40] = 1
+		_, err := pipeWriter.Write(buf)
+		assert.NoError(t, err)
+		pipeWriter.CloseWithError(io.EOF)
+	}()
+
+	dst := &countingWriter{Writer: sdr.Discard(0, sdr.SampleFormatC64)}
+	n, err := sdr.Copy(dst, pipeReader)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1024*64), n)
+
+	// Without the WriteTo fast path, this would have been split into two
+	// Writes of 32*1024.
+	assert.Equal(t, 1, dst.writes)
+}
+
+type readerFrom struct {
+	sdr.Writer
+	called bool
+}
+
+func (rf *readerFrom) ReadFrom(r sdr.Reader) (int64, error) {
+	rf.called = true
+	return 42, nil
+}
+
+func TestCopyReaderFrom(t *testing.T) {
+	src := sdr.ByteReader(bytes.NewReader(nil), binary.LittleEndian, 0, sdr.SampleFormatU8)
+	dst := &readerFrom{Writer: sdr.Discard(0, sdr.SampleFormatU8)}
+	n, err := sdr.Copy(dst, src)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), n)
+	assert.True(t, dst.called)
+}
+
 // vim: foldmethod=marker
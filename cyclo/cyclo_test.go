@@ -0,0 +1,112 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package cyclo_test
+
+import (
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/cyclo"
+	"hz.tools/sdr/testutils"
+)
+
+func noise(r *rand.Rand, buf sdr.SamplesC64, sigma float64) {
+	for i := range buf {
+		buf[i] += complex64(complex(r.NormFloat64()*sigma, r.NormFloat64()*sigma))
+	}
+}
+
+func ofdm(r *rand.Rand, symbols, fftLength, cpLength int) sdr.SamplesC64 {
+	ret := sdr.SamplesC64{}
+	symbol := make(sdr.SamplesC64, fftLength)
+	for s := 0; s < symbols; s++ {
+		carriers := make([]complex128, fftLength)
+		for k := range carriers {
+			carriers[k] = complex(float64(r.Intn(2)*2-1), float64(r.Intn(2)*2-1))
+		}
+		for i := range symbol {
+			var acc complex128
+			for k, c := range carriers {
+				acc += c * cmplx.Exp(complex(0, 2*math.Pi*float64(k*i)/float64(fftLength)))
+			}
+			symbol[i] = complex64(acc / complex(float64(fftLength), 0))
+		}
+		ret = append(ret, symbol[fftLength-cpLength:]...)
+		ret = append(ret, symbol...)
+	}
+	return ret
+}
+
+func TestCyclicPrefix(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	iq := ofdm(r, 64, 64, 16)
+	assert.InDelta(t, 0.2, cyclo.CyclicPrefix(iq, 64), 0.05)
+	assert.True(t, cyclo.CyclicPrefix(iq, 128) < 0.05)
+
+	n := make(sdr.SamplesC64, len(iq))
+	noise(r, n, 1)
+	assert.True(t, cyclo.CyclicPrefix(n, 64) < 0.05)
+
+	assert.Equal(t, float64(0), cyclo.CyclicPrefix(iq[:10], 64))
+}
+
+func TestSpectralCoherence(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	// BPSK with rectangular pulses 8 samples long; at a frame length of 64
+	// the symbol rate is 8 bins.
+	bpsk := make(sdr.SamplesC64, 64*128)
+	for i := 0; i < len(bpsk); i += 8 {
+		bit := complex64(complex(float64(r.Intn(2)*2-1), 0))
+		for j := 0; j < 8; j++ {
+			bpsk[i+j] = bit
+		}
+	}
+	noise(r, bpsk, 0.5)
+
+	alphas := []int{3, 8, 13}
+	grid, err := cyclo.SpectralCoherence(testutils.DFTPlanner, bpsk, 1e6, 64, alphas)
+	assert.NoError(t, err)
+	assert.Equal(t, alphas, grid.Alphas)
+	profile := grid.Profile()
+	assert.True(t, profile[1] > 0.5, "symbol rate feature %f", profile[1])
+	assert.True(t, profile[0] < 0.15, "no feature %f", profile[0])
+	assert.True(t, profile[2] < 0.15, "no feature %f", profile[2])
+
+	n := make(sdr.SamplesC64, 64*128)
+	noise(r, n, 1)
+	grid, err = cyclo.SpectralCoherence(testutils.DFTPlanner, n, 1e6, 64, alphas)
+	assert.NoError(t, err)
+	for _, c := range grid.Profile() {
+		assert.True(t, c < 0.15, "noise feature %f", c)
+	}
+
+	_, err = cyclo.SpectralCoherence(testutils.DFTPlanner, n[:32], 1e6, 64, alphas)
+	assert.Equal(t, cyclo.ErrShortCapture, err)
+}
+
+// vim: foldmethod=marker
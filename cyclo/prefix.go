@@ -0,0 +1,66 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package cyclo
+
+import (
+	"math"
+	"math/cmplx"
+
+	"hz.tools/sdr"
+)
+
+// CyclicPrefix will return a score of how likely the iq buffer contains an
+// OFDM signal with a cyclic prefix, given the length of the OFDM FFT.
+//
+// The cyclic prefix of each OFDM symbol is a copy of the tail of the symbol,
+// so the signal correlates with itself `fftLength` samples later during the
+// prefix. This returns the normalized autocorrelation magnitude at that lag,
+// which is near 0 for noise, and tends towards cpLength/(fftLength+cpLength)
+// for OFDM with a matching FFT length.
+//
+// If the iq buffer is shorter than fftLength, this returns 0.
+func CyclicPrefix(iq sdr.SamplesC64, fftLength int) float64 {
+	if fftLength <= 0 || len(iq) <= fftLength {
+		return 0
+	}
+
+	var (
+		corr          complex128
+		powerA        float64
+		powerB        float64
+		head, delayed = iq[:len(iq)-fftLength], iq[fftLength:]
+	)
+
+	for i := range head {
+		a := complex128(head[i])
+		b := complex128(delayed[i])
+		corr += b * cmplx.Conj(a)
+		powerA += real(a)*real(a) + imag(a)*imag(a)
+		powerB += real(b)*real(b) + imag(b)*imag(b)
+	}
+
+	if powerA == 0 || powerB == 0 {
+		return 0
+	}
+	return cmplx.Abs(corr) / math.Sqrt(powerA*powerB)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,155 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package cyclo
+
+import (
+	"fmt"
+	"math/cmplx"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/fft"
+)
+
+var (
+	// ErrShortCapture will be returned if the capture is too short to
+	// contain a single FFT frame.
+	ErrShortCapture = fmt.Errorf("cyclo: capture is shorter than the fft length")
+)
+
+// Grid is the spectral coherence of a capture, computed over a coarse grid
+// of cycle frequencies.
+type Grid struct {
+	// SampleRate of the capture.
+	SampleRate uint
+
+	// Alphas are the cycle frequencies of each row of the Grid, in FFT
+	// bins. Multiply by the BinBandwidth to get the cycle frequency in Hz.
+	Alphas []int
+
+	// Coherence holds one row per cycle frequency in Alphas, each with
+	// one value per FFT bin, in fft.ZeroFirst order. The value is the
+	// magnitude-squared spectral coherence between the bin, and the bin
+	// alpha bins above it; near 1 for a signal with a feature at that
+	// cycle frequency, and near 0 for noise.
+	Coherence [][]float32
+}
+
+// Profile will return the strongest coherence of each row of the Grid, which
+// is a coarse view of the cycle frequencies present in the capture.
+func (g Grid) Profile() []float32 {
+	ret := make([]float32, len(g.Coherence))
+	for i, row := range g.Coherence {
+		for _, c := range row {
+			if c > ret[i] {
+				ret[i] = c
+			}
+		}
+	}
+	return ret
+}
+
+// SpectralCoherence will estimate the spectral coherence of the iq buffer at
+// each of the cycle frequencies in alphas (in FFT bins), using the time
+// smoothing method with non-overlapping frames of `length` samples.
+//
+// Since the frames don't overlap, and each alpha is a whole number of bins,
+// no per-frame phase correction is required. The coarse grid this creates
+// is enough to tag the presence of a symbol clock or other periodic
+// feature, but not to precisely measure it.
+//
+// Estimates of coherence are biased up by roughly 1 divided by the number of
+// frames, so longer captures give a lower noise floor.
+func SpectralCoherence(
+	planner fft.Planner,
+	iq sdr.SamplesC64,
+	sampleRate uint,
+	length int,
+	alphas []int,
+) (*Grid, error) {
+	frames := len(iq) / length
+	if length <= 0 || frames == 0 {
+		return nil, ErrShortCapture
+	}
+
+	var (
+		frame     = make(sdr.SamplesC64, length)
+		frequency = make([]complex64, length)
+		psd       = make([]float64, length)
+		scf       = make([][]complex128, len(alphas))
+	)
+	for i := range scf {
+		scf[i] = make([]complex128, length)
+	}
+
+	plan, err := planner(frame, frequency, fft.Forward)
+	if err != nil {
+		return nil, err
+	}
+	defer plan.Close()
+
+	for f := 0; f < frames; f++ {
+		copy(frame, iq[f*length:(f+1)*length])
+		if err := plan.Transform(); err != nil {
+			return nil, err
+		}
+
+		for k, x := range frequency {
+			psd[k] += float64(real(x)*real(x) + imag(x)*imag(x))
+		}
+
+		for i, alpha := range alphas {
+			row := scf[i]
+			for k := range frequency {
+				upper := complex128(frequency[wrap(k+alpha, length)])
+				row[k] += upper * cmplx.Conj(complex128(frequency[k]))
+			}
+		}
+	}
+
+	grid := &Grid{
+		SampleRate: sampleRate,
+		Alphas:     alphas,
+		Coherence:  make([][]float32, len(alphas)),
+	}
+	for i, alpha := range alphas {
+		row := make([]float32, length)
+		for k, s := range scf[i] {
+			denom := psd[wrap(k+alpha, length)] * psd[k]
+			if denom == 0 {
+				continue
+			}
+			mag := cmplx.Abs(s)
+			row[k] = float32(mag * mag / denom)
+		}
+		grid.Coherence[i] = row
+	}
+	return grid, nil
+}
+
+func wrap(i, n int) int {
+	i %= n
+	if i < 0 {
+		i += n
+	}
+	return i
+}
+
+// vim: foldmethod=marker
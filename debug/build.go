@@ -60,8 +60,10 @@ type BuildInfo struct {
 func ReadBuildInfo() BuildInfo {
 	return BuildInfo{
 		SampleFormats: []sdr.SampleFormat{
+			sdr.SampleFormatC128,
 			sdr.SampleFormatC64,
 			sdr.SampleFormatI16,
+			sdr.SampleFormatI12Packed,
 			sdr.SampleFormatU8,
 			sdr.SampleFormatI8,
 		},
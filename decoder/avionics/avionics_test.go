@@ -0,0 +1,153 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package avionics_test
+
+import (
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/decoder"
+	"hz.tools/sdr/decoder/avionics"
+)
+
+const sampleRate = 48000
+
+// am will generate a second of an AM carrier (with a random phase, and a
+// bit of noise), modulated by the provided function of time.
+func am(seed int64, fn func(t float64) float64) sdr.SamplesC64 {
+	var (
+		rng   = rand.New(rand.NewSource(seed))
+		phase = cmplx.Rect(0.5, rng.Float64()*2*math.Pi)
+		buf   = make(sdr.SamplesC64, sampleRate)
+	)
+	for i := range buf {
+		t := float64(i) / sampleRate
+		noise := complex(rng.NormFloat64(), rng.NormFloat64()) * 0.01
+		buf[i] = complex64(phase*complex(fn(t), 0) + noise)
+	}
+	return buf
+}
+
+// vor will generate a second of a VOR signal on the provided radial.
+func vor(seed int64, bearing float64) sdr.SamplesC64 {
+	theta := bearing * math.Pi / 180
+	return am(seed, func(t float64) float64 {
+		w := 2 * math.Pi * 30 * t
+		return 1 +
+			0.3*math.Cos(w-theta) +
+			0.3*math.Cos(2*math.Pi*9960*t+16*math.Sin(w)) +
+			0.1*math.Cos(2*math.Pi*1020*t)
+	})
+}
+
+func process(t *testing.T, d decoder.Decoder, iq sdr.SamplesC64) []decoder.Event {
+	var events []decoder.Event
+	// Feed in awkwardly sized chunks, to check state is carried over.
+	for len(iq) > 0 {
+		n := 1000
+		if n > len(iq) {
+			n = len(iq)
+		}
+		e, err := d.Process(iq[:n])
+		assert.NoError(t, err)
+		events = append(events, e...)
+		iq = iq[n:]
+	}
+	return events
+}
+
+func TestVOR(t *testing.T) {
+	for _, bearing := range []float64{0, 45, 123.4, 180, 270, 359} {
+		d, err := avionics.NewVOR(avionics.Config{})
+		assert.NoError(t, err)
+
+		events := process(t, d, vor(1, bearing))
+		assert.Len(t, events, 2)
+		for _, event := range events {
+			payload := event.Payload.(avionics.VORBearing)
+			diff := math.Mod(payload.Bearing-bearing+540, 360) - 180
+			assert.InDelta(t, 0, diff, 0.25, "bearing %f got %f", bearing, payload.Bearing)
+			assert.InDelta(t, 0.3, payload.Variable, 0.01)
+			assert.InDelta(t, 480, payload.Deviation, 10)
+			assert.InDelta(t, 0.5, payload.Level, 0.01)
+			assert.Equal(t, "vor", event.Decoder)
+		}
+		assert.Equal(t, uint64(24000), events[1].Start)
+	}
+}
+
+func TestILS(t *testing.T) {
+	for _, kind := range []avionics.ILSKind{avionics.Localizer, avionics.Glideslope} {
+		sdm := 0.4
+		if kind == avionics.Glideslope {
+			sdm = 0.8
+		}
+		ddm := 0.05
+
+		iq := am(2, func(t float64) float64 {
+			return 1 +
+				(sdm+ddm)/2*math.Sin(2*math.Pi*90*t) +
+				(sdm-ddm)/2*math.Sin(2*math.Pi*150*t)
+		})
+
+		d, err := avionics.NewLocalizer(avionics.Config{})
+		if kind == avionics.Glideslope {
+			d, err = avionics.NewGlideslope(avionics.Config{})
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, kind, d.Kind())
+
+		events := process(t, d, iq)
+		assert.Len(t, events, 2)
+		for _, event := range events {
+			payload := event.Payload.(avionics.ILSDeviation)
+			assert.Equal(t, kind, payload.Kind)
+			assert.InDelta(t, ddm, payload.DDM, 0.001)
+			assert.InDelta(t, sdm, payload.SDM, 0.001)
+			assert.InDelta(t, ddm/kind.FullScale(), payload.Deflection(), 0.01)
+			assert.Equal(t, "ils-"+kind.String(), event.Decoder)
+		}
+	}
+}
+
+func TestRegistered(t *testing.T) {
+	for _, name := range []string{"vor", "ils-localizer", "ils-glideslope"} {
+		d, err := decoder.New(name)
+		assert.NoError(t, err)
+		assert.Equal(t, name, d.Name())
+		assert.Equal(t, uint(sampleRate), d.SampleRate())
+	}
+}
+
+func TestBadSampleRate(t *testing.T) {
+	_, err := avionics.NewVOR(avionics.Config{SampleRate: 48001})
+	assert.Equal(t, avionics.ErrBadSampleRate, err)
+
+	_, err = avionics.NewVOR(avionics.Config{SampleRate: 12000})
+	assert.Error(t, err)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,235 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package avionics
+
+import (
+	"fmt"
+	"math/cmplx"
+	"time"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/decoder"
+)
+
+const (
+	// LocalizerFullScale is the DDM of a full scale deflection of a
+	// localizer course deviation indicator.
+	LocalizerFullScale = 0.155
+
+	// GlideslopeFullScale is the DDM of a full scale deflection of a
+	// glideslope deviation indicator.
+	GlideslopeFullScale = 0.175
+)
+
+// ILSKind is the kind of ILS signal being decoded.
+type ILSKind int
+
+const (
+	// Localizer is the VHF part of an ILS, which gives the deviation left
+	// or right of the approach course.
+	Localizer ILSKind = iota
+
+	// Glideslope is the UHF part of an ILS, which gives the deviation above
+	// or below the glide path.
+	Glideslope
+)
+
+// String returns the name of the ILSKind.
+func (k ILSKind) String() string {
+	switch k {
+	case Localizer:
+		return "localizer"
+	case Glideslope:
+		return "glideslope"
+	default:
+		return "unknown"
+	}
+}
+
+// FullScale returns the DDM of a full scale deflection for the ILSKind.
+func (k ILSKind) FullScale() float64 {
+	if k == Glideslope {
+		return GlideslopeFullScale
+	}
+	return LocalizerFullScale
+}
+
+// ILSDeviation is the Payload of the Events emitted by the ILS decoders.
+type ILSDeviation struct {
+	// Kind is the kind of ILS signal this was measured from.
+	Kind ILSKind
+
+	// Depth90 and Depth150 are the AM modulation depths of the 90 Hz and
+	// 150 Hz tones.
+	Depth90  float64
+	Depth150 float64
+
+	// DDM is the difference in depth of modulation, Depth90 less Depth150.
+	// On a localizer, this is positive when the 90 Hz tone dominates, to
+	// the left of the course (so the aircraft needs to fly right). On a
+	// glideslope, it's positive above the glide path.
+	DDM float64
+
+	// SDM is the sum of the depth of modulation, Depth90 plus Depth150,
+	// which is nominally 0.4 for a localizer, and 0.8 for a glideslope.
+	SDM float64
+
+	// Level is the average amplitude of the carrier.
+	Level float64
+}
+
+// Deflection returns the DDM as a fraction of a full scale deflection of
+// the deviation indicator, clamped to +/-1.
+func (d ILSDeviation) Deflection() float64 {
+	v := d.DDM / d.Kind.FullScale()
+	if v > 1 {
+		return 1
+	}
+	if v < -1 {
+		return -1
+	}
+	return v
+}
+
+// ILS is a decoder.Decoder, which measures the difference in depth of
+// modulation of the 90 Hz and 150 Hz tones of an ILS localizer or
+// glideslope.
+type ILS struct {
+	kind       ILSKind
+	sampleRate uint
+	length     int
+
+	n     uint64
+	count int
+
+	level   float64
+	tone90  tone
+	tone150 tone
+}
+
+// NewLocalizer will create a new ILS localizer decoder.
+func NewLocalizer(cfg Config) (*ILS, error) {
+	return newILS(Localizer, cfg)
+}
+
+// NewGlideslope will create a new ILS glideslope decoder.
+func NewGlideslope(cfg Config) (*ILS, error) {
+	return newILS(Glideslope, cfg)
+}
+
+func newILS(kind ILSKind, cfg Config) (*ILS, error) {
+	length, err := cfg.frameLength()
+	if err != nil {
+		return nil, err
+	}
+	sampleRate := cfg.getSampleRate()
+	return &ILS{
+		kind:       kind,
+		sampleRate: sampleRate,
+		length:     length,
+		tone90:     tone{freq: 90, sampleRate: float64(sampleRate)},
+		tone150:    tone{freq: 150, sampleRate: float64(sampleRate)},
+	}, nil
+}
+
+// Kind returns the kind of ILS signal being decoded.
+func (d *ILS) Kind() ILSKind {
+	return d.kind
+}
+
+// Name implements the decoder.Decoder interface.
+func (d *ILS) Name() string {
+	return "ils-" + d.kind.String()
+}
+
+// SampleRate implements the decoder.Decoder interface.
+func (d *ILS) SampleRate() uint {
+	return d.sampleRate
+}
+
+// Bandwidth implements the decoder.Decoder interface. This leaves room for
+// the 1020 Hz ident tone of the localizer, even though it's not decoded.
+func (d *ILS) Bandwidth() rf.Hz {
+	return 3 * rf.KHz
+}
+
+// Close implements the decoder.Decoder interface.
+func (d *ILS) Close() error {
+	return nil
+}
+
+// Process implements the decoder.Decoder interface. An Event is emitted
+// every Config.Integration, with an ILSDeviation as the Payload.
+func (d *ILS) Process(iq sdr.SamplesC64) ([]decoder.Event, error) {
+	var events []decoder.Event
+	for _, s := range iq {
+		env := cmplx.Abs(complex128(s))
+		d.level += env
+		d.tone90.add(d.n, env)
+		d.tone150.add(d.n, env)
+
+		d.n++
+		d.count++
+		if d.count == d.length {
+			events = append(events, d.event())
+		}
+	}
+	return events, nil
+}
+
+// event will build the Event for the frame that just ended, and reset for
+// the next one.
+func (d *ILS) event() decoder.Event {
+	var (
+		level       = d.level / float64(d.length)
+		depth90, _  = d.tone90.result(d.length)
+		depth150, _ = d.tone150.result(d.length)
+	)
+	if level > 0 {
+		depth90 /= level
+		depth150 /= level
+	}
+
+	payload := ILSDeviation{
+		Kind:     d.kind,
+		Depth90:  depth90,
+		Depth150: depth150,
+		DDM:      depth90 - depth150,
+		SDM:      depth90 + depth150,
+		Level:    level,
+	}
+
+	event := decoder.Event{
+		Decoder: d.Name(),
+		Time:    time.Now(),
+		Payload: payload,
+		Label:   fmt.Sprintf("%s DDM %+.4f", d.kind, payload.DDM),
+		Start:   d.n - uint64(d.length),
+		Length:  uint64(d.length),
+	}
+
+	d.level = 0
+	d.count = 0
+	return event
+}
+
+// vim: foldmethod=marker
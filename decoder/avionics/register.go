@@ -0,0 +1,64 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package avionics
+
+import (
+	"hz.tools/rf"
+	"hz.tools/sdr/decoder"
+)
+
+func init() {
+	for _, info := range []decoder.Info{
+		{
+			Name:        "vor",
+			Description: "VOR bearing",
+			SampleRate:  Config{}.getSampleRate(),
+			Bandwidth:   2 * (vorSubcarrier + vorSubcarrierCutoff),
+			New: func() (decoder.Decoder, error) {
+				return NewVOR(Config{})
+			},
+		},
+		{
+			Name:        "ils-localizer",
+			Description: "ILS localizer course deviation",
+			SampleRate:  Config{}.getSampleRate(),
+			Bandwidth:   3 * rf.KHz,
+			New: func() (decoder.Decoder, error) {
+				return NewLocalizer(Config{})
+			},
+		},
+		{
+			Name:        "ils-glideslope",
+			Description: "ILS glideslope deviation",
+			SampleRate:  Config{}.getSampleRate(),
+			Bandwidth:   3 * rf.KHz,
+			New: func() (decoder.Decoder, error) {
+				return NewGlideslope(Config{})
+			},
+		},
+	} {
+		if err := decoder.Register(info); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// vim: foldmethod=marker
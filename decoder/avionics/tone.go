@@ -0,0 +1,106 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package avionics
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"time"
+)
+
+var (
+	// ErrBadSampleRate will be returned if the sample rate isn't a multiple
+	// of 30 Hz, which is needed to measure the tones over a whole number of
+	// cycles.
+	ErrBadSampleRate = fmt.Errorf("avionics: sample rate must be a multiple of 30 Hz")
+)
+
+// Config configures a VOR or ILS decoder.
+type Config struct {
+	// SampleRate is the sample rate of the IQ samples, which are centered
+	// on the carrier. This must be a multiple of 30 Hz. If 0, this will
+	// default to 48000.
+	SampleRate uint
+
+	// Integration is how long the tones are measured over for each Event.
+	// Longer is less noisy, but slower to follow a moving aircraft. This is
+	// rounded up to a whole number of 30 Hz cycles. If 0, this will default
+	// to 500ms.
+	Integration time.Duration
+}
+
+func (c Config) getSampleRate() uint {
+	if c.SampleRate == 0 {
+		return 48000
+	}
+	return c.SampleRate
+}
+
+func (c Config) getIntegration() time.Duration {
+	if c.Integration == 0 {
+		return 500 * time.Millisecond
+	}
+	return c.Integration
+}
+
+// frameLength returns the number of samples in each Event, which is a whole
+// number of 30 Hz cycles (and so a whole number of 90 and 150 Hz cycles
+// too).
+func (c Config) frameLength() (int, error) {
+	sampleRate := c.getSampleRate()
+	if sampleRate%30 != 0 {
+		return 0, ErrBadSampleRate
+	}
+	cycles := int(math.Ceil(c.getIntegration().Seconds() * 30))
+	if cycles < 1 {
+		cycles = 1
+	}
+	return cycles * int(sampleRate/30), nil
+}
+
+// tone measures the amplitude and phase of a tone at a fixed frequency,
+// by correlating against it. Over a whole number of cycles, this rejects
+// every other tone that also fits a whole number of cycles.
+type tone struct {
+	freq       float64
+	sampleRate float64
+	acc        complex128
+}
+
+// add will add the sample at index n (counted from the start of the
+// stream, so the phase is the same from frame to frame) to the correlation.
+func (t *tone) add(n uint64, v float64) {
+	period := uint64(t.sampleRate)
+	phase := -2 * math.Pi * t.freq * float64(n%period) / t.sampleRate
+	t.acc += complex(v, 0) * cmplx.Rect(1, phase)
+}
+
+// result returns the amplitude and phase (in radians) of the tone over the
+// provided number of samples, and resets the correlation.
+func (t *tone) result(samples int) (float64, float64) {
+	amplitude := 2 * cmplx.Abs(t.acc) / float64(samples)
+	phase := cmplx.Phase(t.acc)
+	t.acc = 0
+	return amplitude, phase
+}
+
+// vim: foldmethod=marker
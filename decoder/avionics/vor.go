@@ -0,0 +1,233 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package avionics
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"time"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/decoder"
+	"hz.tools/sdr/filter"
+)
+
+const (
+	// vorTone is the frequency of both the variable (AM) and reference (FM)
+	// signals of a VOR.
+	vorTone = 30
+
+	// vorSubcarrier is the frequency of the subcarrier carrying the FM
+	// reference signal.
+	vorSubcarrier = 9960
+
+	// vorSubcarrierCutoff is the cutoff of the filter picking out the
+	// subcarrier, which is FM modulated by the 30 Hz reference with a
+	// deviation of 480 Hz.
+	vorSubcarrierCutoff = 1000
+)
+
+// VORBearing is the Payload of the Events emitted by the VOR decoder.
+type VORBearing struct {
+	// Bearing is the radial the receiver is on, in degrees from magnetic
+	// north (at the station), from 0 up to 360.
+	Bearing float64
+
+	// Variable is the AM modulation depth of the 30 Hz variable signal,
+	// which is nominally 0.3.
+	Variable float64
+
+	// Deviation is the FM deviation (in Hz) of the 30 Hz reference signal
+	// on the subcarrier, which is nominally 480 Hz.
+	Deviation float64
+
+	// Level is the average amplitude of the carrier.
+	Level float64
+}
+
+// VOR is a decoder.Decoder, which measures the bearing from a VOR station,
+// by comparing the phase of the 30 Hz AM variable signal against the 30 Hz
+// FM reference signal on the 9960 Hz subcarrier.
+type VOR struct {
+	sampleRate uint
+	length     int
+
+	// n is the index of the next sample, from the start of the stream.
+	n     uint64
+	count int
+
+	level     float64
+	variable  tone
+	reference tone
+
+	// The subcarrier is mixed down to DC, filtered, and FM demodulated.
+	mixer    *filter.NCO
+	fir      *filter.FIR
+	delay    float64
+	envelope []float64
+	buf      sdr.SamplesC64
+	last     complex64
+}
+
+// NewVOR will create a new VOR decoder.
+func NewVOR(cfg Config) (*VOR, error) {
+	length, err := cfg.frameLength()
+	if err != nil {
+		return nil, err
+	}
+	sampleRate := cfg.getSampleRate()
+	if sampleRate < 2*(vorSubcarrier+vorSubcarrierCutoff) {
+		return nil, fmt.Errorf("avionics: sample rate is too low for the VOR subcarrier")
+	}
+
+	mixer, err := filter.NewNCO(-vorSubcarrier, sampleRate)
+	if err != nil {
+		return nil, err
+	}
+
+	taps := int(sampleRate/500) | 1
+	lowpass, err := filter.LowPassTaps(taps, vorSubcarrierCutoff/float64(sampleRate))
+	if err != nil {
+		return nil, err
+	}
+
+	return &VOR{
+		sampleRate: sampleRate,
+		length:     length,
+		variable:   tone{freq: vorTone, sampleRate: float64(sampleRate)},
+		reference:  tone{freq: vorTone, sampleRate: float64(sampleRate)},
+		mixer:      mixer,
+		fir:        filter.NewFIR(lowpass),
+		// The FIR delays the subcarrier by half its length, and the FM
+		// demodulator by another half a sample.
+		delay: float64(taps-1)/2 + 0.5,
+	}, nil
+}
+
+// Name implements the decoder.Decoder interface.
+func (v *VOR) Name() string {
+	return "vor"
+}
+
+// SampleRate implements the decoder.Decoder interface.
+func (v *VOR) SampleRate() uint {
+	return v.sampleRate
+}
+
+// Bandwidth implements the decoder.Decoder interface.
+func (v *VOR) Bandwidth() rf.Hz {
+	return 2 * (vorSubcarrier + vorSubcarrierCutoff)
+}
+
+// Close implements the decoder.Decoder interface.
+func (v *VOR) Close() error {
+	return nil
+}
+
+// Process implements the decoder.Decoder interface. An Event is emitted
+// every Config.Integration, with a VORBearing as the Payload.
+func (v *VOR) Process(iq sdr.SamplesC64) ([]decoder.Event, error) {
+	var events []decoder.Event
+
+	if cap(v.buf) < len(iq) {
+		v.buf = make(sdr.SamplesC64, len(iq))
+		v.envelope = make([]float64, len(iq))
+	}
+	var (
+		buf      = v.buf[:len(iq)]
+		envelope = v.envelope[:len(iq)]
+	)
+
+	// Envelope (AM) detect the carrier, and pull out the subcarrier.
+	for i, s := range iq {
+		envelope[i] = cmplx.Abs(complex128(s))
+		buf[i] = complex(float32(envelope[i]), 0)
+	}
+	if _, err := v.mixer.MixC64(buf, buf); err != nil {
+		return nil, err
+	}
+	if _, err := v.fir.ProcessC64(buf, buf); err != nil {
+		return nil, err
+	}
+
+	for i, env := range envelope {
+		v.level += env
+		v.variable.add(v.n, env)
+
+		// FM demodulate the subcarrier, in Hz.
+		freq := cmplx.Phase(complex128(buf[i]*complex(real(v.last), -imag(v.last)))) *
+			float64(v.sampleRate) / (2 * math.Pi)
+		v.last = buf[i]
+		v.reference.add(v.n, freq)
+
+		v.n++
+		v.count++
+		if v.count == v.length {
+			events = append(events, v.event())
+		}
+	}
+	return events, nil
+}
+
+// event will build the Event for the frame that just ended, and reset for
+// the next one.
+func (v *VOR) event() decoder.Event {
+	var (
+		level                     = v.level / float64(v.length)
+		variable, variablePhase   = v.variable.result(v.length)
+		deviation, referencePhase = v.reference.result(v.length)
+	)
+
+	// Correct the reference for the delay through the subcarrier filter.
+	referencePhase += 2 * math.Pi * vorTone * v.delay / float64(v.sampleRate)
+
+	bearing := math.Mod((referencePhase-variablePhase)*180/math.Pi, 360)
+	if bearing < 0 {
+		bearing += 360
+	}
+	if level > 0 {
+		variable /= level
+	}
+
+	payload := VORBearing{
+		Bearing:   bearing,
+		Variable:  variable,
+		Deviation: deviation,
+		Level:     level,
+	}
+
+	event := decoder.Event{
+		Decoder: v.Name(),
+		Time:    time.Now(),
+		Payload: payload,
+		Label:   fmt.Sprintf("radial %05.1f", bearing),
+		Start:   v.n - uint64(v.length),
+		Length:  uint64(v.length),
+	}
+
+	v.level = 0
+	v.count = 0
+	return event
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,111 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package decoder
+
+import (
+	"time"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+)
+
+// Event is something a Decoder found in the IQ stream, such as a decoded
+// message.
+type Event struct {
+	// Decoder is the Name of the Decoder that produced the Event.
+	Decoder string
+
+	// Time is when the Event was decoded.
+	Time time.Time
+
+	// Payload is the decoded data. The type is up to the Decoder, and
+	// should be documented by it.
+	Payload interface{}
+
+	// Label is a short human readable description of the Event, such as
+	// a callsign, or a message type. This is optional.
+	Label string
+
+	// Start and Length are where in the stream the Event was decoded from,
+	// counted in samples from the first sample passed to Process. If Length
+	// is 0, the extent isn't known.
+	Start  uint64
+	Length uint64
+
+	// Offset and Bandwidth are where in the spectrum the Event was decoded
+	// from, relative to the center of the stream. If Bandwidth is 0, the
+	// extent isn't known, and is assumed to be the Bandwidth of the
+	// Decoder.
+	Offset    rf.Hz
+	Bandwidth rf.Hz
+}
+
+// Decoder will process IQ samples, and emit Events as it finds them.
+type Decoder interface {
+	// Name is the name the Decoder was registered under.
+	Name() string
+
+	// SampleRate is the sample rate the Decoder expects the IQ samples
+	// passed to Process to be at.
+	SampleRate() uint
+
+	// Bandwidth is the amount of spectrum (centered on the frequency the
+	// stream is tuned to) that the Decoder needs to see.
+	Bandwidth() rf.Hz
+
+	// Process will decode the provided IQ samples, which follow directly
+	// on from the previous call to Process, and return any Events found.
+	Process(sdr.SamplesC64) ([]Event, error)
+
+	// Close will release any resources held by the Decoder.
+	Close() error
+}
+
+// Run will read IQ samples from the Reader, pass them to the Decoder, and
+// invoke the provided callback with every Event emitted, until the Reader or
+// Decoder returns an error.
+func Run(r sdr.Reader, d Decoder, fn func(Event)) error {
+	if r.SampleFormat() != sdr.SampleFormatC64 {
+		return sdr.ErrSampleFormatUnknown
+	}
+	if r.SampleRate() != d.SampleRate() {
+		return ErrSampleRateMismatch
+	}
+
+	buf := make(sdr.SamplesC64, d.SampleRate()/10+1)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			events, perr := d.Process(buf[:n])
+			for _, event := range events {
+				fn(event)
+			}
+			if perr != nil {
+				return perr
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// vim: foldmethod=marker
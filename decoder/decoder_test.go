@@ -0,0 +1,123 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package decoder_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/decoder"
+)
+
+// pulse is a Decoder that emits an Event with the sample index every time a
+// sample's real component goes over 0.5.
+type pulse struct {
+	offset int
+}
+
+func (p *pulse) Name() string     { return "test-pulse" }
+func (p *pulse) SampleRate() uint { return 1000 }
+func (p *pulse) Bandwidth() rf.Hz { return rf.KHz }
+func (p *pulse) Close() error     { return nil }
+
+func (p *pulse) Process(iq sdr.SamplesC64) ([]decoder.Event, error) {
+	events := []decoder.Event{}
+	for i, s := range iq {
+		if real(s) > 0.5 {
+			events = append(events, decoder.Event{
+				Decoder: p.Name(),
+				Time:    time.Now(),
+				Payload: p.offset + i,
+			})
+		}
+	}
+	p.offset += len(iq)
+	return events, nil
+}
+
+func init() {
+	if err := decoder.Register(decoder.Info{
+		Name:        "test-pulse",
+		Description: "pulses over 0.5",
+		SampleRate:  1000,
+		Bandwidth:   rf.KHz,
+		New: func() (decoder.Decoder, error) {
+			return &pulse{}, nil
+		},
+	}); err != nil {
+		panic(err)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	info, err := decoder.Lookup("test-pulse")
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1000), info.SampleRate)
+
+	_, err = decoder.Lookup("test-nope")
+	assert.Equal(t, decoder.ErrUnknownDecoder, err)
+
+	_, err = decoder.New("test-nope")
+	assert.Equal(t, decoder.ErrUnknownDecoder, err)
+
+	assert.Equal(t, decoder.ErrDuplicateDecoder, decoder.Register(decoder.Info{
+		Name: "test-pulse",
+	}))
+
+	assert.NoError(t, decoder.Register(decoder.Info{Name: "test-a"}))
+	names := []string{}
+	for _, info := range decoder.List() {
+		names = append(names, info.Name)
+	}
+	assert.Equal(t, []string{"test-a", "test-pulse"}, names)
+}
+
+func TestRun(t *testing.T) {
+	d, err := decoder.New("test-pulse")
+	assert.NoError(t, err)
+	defer d.Close()
+
+	pipeReader, pipeWriter := sdr.Pipe(1000, sdr.SampleFormatC64)
+	go func() {
+		buf := make(sdr.SamplesC64, 300)
+		buf[10] = 1
+		buf[250] = 1
+		pipeWriter.Write(buf)
+		pipeWriter.Close()
+	}()
+
+	offsets := []int{}
+	err = decoder.Run(pipeReader, d, func(event decoder.Event) {
+		assert.Equal(t, "test-pulse", event.Decoder)
+		offsets = append(offsets, event.Payload.(int))
+	})
+	assert.Equal(t, sdr.ErrPipeClosed, err)
+	assert.Equal(t, []int{10, 250}, offsets)
+
+	pipeReader, _ = sdr.Pipe(2000, sdr.SampleFormatC64)
+	assert.Equal(t, decoder.ErrSampleRateMismatch, decoder.Run(pipeReader, d, nil))
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,120 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package decoder
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"hz.tools/rf"
+)
+
+var (
+	// ErrUnknownDecoder will be returned if a Decoder is requested by a name
+	// that hasn't been registered.
+	ErrUnknownDecoder = fmt.Errorf("decoder: unknown decoder")
+
+	// ErrDuplicateDecoder will be returned if a Decoder is registered with
+	// the same name as one already registered.
+	ErrDuplicateDecoder = fmt.Errorf("decoder: a decoder with that name is already registered")
+
+	// ErrSampleRateMismatch will be returned if a Reader isn't at the
+	// SampleRate the Decoder needs.
+	ErrSampleRateMismatch = fmt.Errorf("decoder: reader sample rate does not match the decoder")
+)
+
+// Info describes a registered Decoder.
+type Info struct {
+	// Name is the unique name of the Decoder, such as "adsb".
+	Name string
+
+	// Description is a human readable description of what the Decoder
+	// decodes.
+	Description string
+
+	// SampleRate is the sample rate the Decoder expects.
+	SampleRate uint
+
+	// Bandwidth is the amount of spectrum the Decoder needs to see.
+	Bandwidth rf.Hz
+
+	// New will create a new instance of the Decoder.
+	New func() (Decoder, error)
+}
+
+var (
+	registryLock = &sync.Mutex{}
+	registry     = map[string]Info{}
+)
+
+// Register will make a Decoder available by name. This is expected to be
+// called from an init function of the package implementing the Decoder.
+func Register(info Info) error {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	if _, ok := registry[info.Name]; ok {
+		return ErrDuplicateDecoder
+	}
+	registry[info.Name] = info
+	return nil
+}
+
+// Lookup will return the Info of the Decoder registered with the provided
+// name.
+func Lookup(name string) (Info, error) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	info, ok := registry[name]
+	if !ok {
+		return Info{}, ErrUnknownDecoder
+	}
+	return info, nil
+}
+
+// List will return the Info of every registered Decoder, sorted by name.
+func List() []Info {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	ret := make([]Info, 0, len(registry))
+	for _, info := range registry {
+		ret = append(ret, info)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Name < ret[j].Name
+	})
+	return ret
+}
+
+// New will create a new instance of the Decoder registered with the provided
+// name.
+func New(name string) (Decoder, error) {
+	info, err := Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	return info.New()
+}
+
+// vim: foldmethod=marker
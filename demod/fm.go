@@ -0,0 +1,318 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package demod
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/audio"
+	"hz.tools/sdr/filter"
+	"hz.tools/sdr/stream"
+)
+
+var (
+	// ErrSampleRateTooLow will be returned if the sample rate of the IQ
+	// stream is too low to contain the FM signal, or is lower than the
+	// requested audio sample rate.
+	ErrSampleRateTooLow = fmt.Errorf("demod: sample rate is too low")
+)
+
+// FMConfig controls the behavior of an FM demodulator. Anything left unset
+// will default to a value sensible for the mode (WBFM or NBFM).
+type FMConfig struct {
+	// Deviation is the peak frequency deviation of the signal, which will be
+	// demodulated to full scale audio. If 0, this will default to 75 kHz
+	// for WBFM, and 5 kHz for NBFM.
+	Deviation rf.Hz
+
+	// AudioBandwidth is the highest audio frequency that will be passed
+	// through. If 0, this will default to 15 kHz for WBFM (to remove the
+	// stereo pilot, and anything above it), and 3 kHz for NBFM.
+	AudioBandwidth rf.Hz
+
+	// Deemphasis is the time constant of the de-emphasis filter. If 0,
+	// this will default to 75µs for WBFM (as used in the Americas; 50µs
+	// is used most other places), and no de-emphasis for NBFM. If
+	// negative, no de-emphasis will be applied.
+	Deemphasis time.Duration
+
+	// AudioSampleRate is the sample rate of the audio. If 0, this will
+	// default to 48 kHz.
+	AudioSampleRate uint
+
+	// SquelchLevel is the power of the IQ stream (in dBFS) below which the
+	// audio will be muted. If 0, the squelch is disabled.
+	SquelchLevel float32
+
+	// SquelchHang is how long the squelch stays open after the signal
+	// drops below the SquelchLevel. If 0, this will default to 250ms.
+	SquelchHang time.Duration
+}
+
+// fmMode contains the defaults for a specific flavor of FM.
+type fmMode struct {
+	deviation      rf.Hz
+	audioBandwidth rf.Hz
+	deemphasis     time.Duration
+}
+
+var (
+	wbfmMode = fmMode{
+		deviation:      75 * rf.KHz,
+		audioBandwidth: 15 * rf.KHz,
+		deemphasis:     75 * time.Microsecond,
+	}
+
+	nbfmMode = fmMode{
+		deviation:      5 * rf.KHz,
+		audioBandwidth: 3 * rf.KHz,
+		deemphasis:     -1,
+	}
+)
+
+func (c FMConfig) getDeviation(mode fmMode) rf.Hz {
+	if c.Deviation == 0 {
+		return mode.deviation
+	}
+	return c.Deviation
+}
+
+func (c FMConfig) getAudioBandwidth(mode fmMode) rf.Hz {
+	if c.AudioBandwidth == 0 {
+		return mode.audioBandwidth
+	}
+	return c.AudioBandwidth
+}
+
+func (c FMConfig) getDeemphasis(mode fmMode) time.Duration {
+	if c.Deemphasis == 0 {
+		return mode.deemphasis
+	}
+	return c.Deemphasis
+}
+
+func (c FMConfig) getAudioSampleRate() uint {
+	if c.AudioSampleRate == 0 {
+		return 48000
+	}
+	return c.AudioSampleRate
+}
+
+func (c FMConfig) getSquelchHang() time.Duration {
+	if c.SquelchHang == 0 {
+		return 250 * time.Millisecond
+	}
+	return c.SquelchHang
+}
+
+type fmReader struct {
+	r sdr.Reader
+
+	sampleRate uint
+
+	// prev is the last IQ sample of the previous read, and gain will scale
+	// the phase change between samples to the audio level.
+	prev complex64
+	gain float32
+
+	resampler *filter.Resampler
+	lowpass   *filter.FIR
+
+	// deemphasis is the one-pole smoothing coefficient, or 0 if there is
+	// no de-emphasis, and deemphasisState is the last output.
+	deemphasis      float32
+	deemphasisState float32
+
+	squelch      bool
+	squelchLevel float32
+	squelchHang  int
+	squelchSince int
+
+	iq      sdr.SamplesC64
+	work    sdr.SamplesC64
+	out     sdr.SamplesC64
+	audio   []float32
+	pending []float32
+	err     error
+}
+
+// WBFM will demodulate wideband FM (such as broadcast FM radio stations)
+// from the provided Reader. The IQ stream should be centered on the
+// station, and have a sample rate of at least 180 kHz (with the default
+// Deviation and AudioBandwidth). Only the mono (L+R) audio is
+// demodulated.
+func WBFM(r sdr.Reader, cfg FMConfig) (audio.Reader, error) {
+	return newFM(r, cfg, wbfmMode)
+}
+
+// NBFM will demodulate narrowband FM (such as amateur or land mobile radio
+// voice channels) from the provided Reader. The IQ stream should be
+// centered on the channel, and have a sample rate of at least 16 kHz (with
+// the default Deviation and AudioBandwidth).
+func NBFM(r sdr.Reader, cfg FMConfig) (audio.Reader, error) {
+	return newFM(r, cfg, nbfmMode)
+}
+
+func newFM(r sdr.Reader, cfg FMConfig, mode fmMode) (audio.Reader, error) {
+	var (
+		inRate         = r.SampleRate()
+		audioRate      = cfg.getAudioSampleRate()
+		deviation      = cfg.getDeviation(mode)
+		audioBandwidth = cfg.getAudioBandwidth(mode)
+		err            error
+	)
+
+	// Carson's rule.
+	if rf.Hz(inRate) < 2*(deviation+audioBandwidth) || inRate < audioRate {
+		return nil, ErrSampleRateTooLow
+	}
+	if audioBandwidth >= rf.Hz(audioRate)/2 {
+		return nil, fmt.Errorf("demod: audio bandwidth is above the audio nyquist rate")
+	}
+
+	if r.SampleFormat() != sdr.SampleFormatC64 {
+		r, err = stream.ConvertReader(r, sdr.SampleFormatC64)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	decim := int(inRate/audioRate) + 1
+	resampler, err := filter.NewResampler(inRate, audioRate, 24*decim)
+	if err != nil {
+		return nil, err
+	}
+
+	taps, err := filter.LowPassTaps(97, float64(audioBandwidth)/float64(audioRate))
+	if err != nil {
+		return nil, err
+	}
+
+	fm := &fmReader{
+		r:            r,
+		sampleRate:   audioRate,
+		gain:         float32(float64(inRate) / (2 * math.Pi * float64(deviation))),
+		resampler:    resampler,
+		lowpass:      filter.NewFIR(taps),
+		squelch:      cfg.SquelchLevel != 0,
+		squelchLevel: cfg.SquelchLevel,
+		squelchHang:  int(cfg.getSquelchHang().Seconds() * float64(audioRate)),
+		iq:           make(sdr.SamplesC64, 16*1024),
+	}
+	fm.squelchSince = fm.squelchHang + 1
+	fm.work = make(sdr.SamplesC64, len(fm.iq))
+	fm.out = make(sdr.SamplesC64, resampler.OutputLength(len(fm.iq)))
+	fm.audio = make([]float32, len(fm.out))
+
+	if tau := cfg.getDeemphasis(mode); tau > 0 {
+		fm.deemphasis = float32(1 - math.Exp(-1/(tau.Seconds()*float64(audioRate))))
+	}
+	return fm, nil
+}
+
+func (fm *fmReader) SampleRate() uint {
+	return fm.sampleRate
+}
+
+// discriminate will write the phase change between each sample in iq, as
+// a real sample in work.
+func (fm *fmReader) discriminate(iq sdr.SamplesC64) {
+	prev := fm.prev
+	for i, s := range iq {
+		d := complex128(s) * complex(float64(real(prev)), -float64(imag(prev)))
+		fm.work[i] = complex(float32(math.Atan2(imag(d), real(d)))*fm.gain, 0)
+		prev = s
+	}
+	fm.prev = prev
+}
+
+// open will update the squelch state with the power of the IQ block, and
+// return true if audio should be passed through.
+func (fm *fmReader) open(iq sdr.SamplesC64, audioSamples int) bool {
+	if !fm.squelch {
+		return true
+	}
+	if iq.Stats().DBFS() >= fm.squelchLevel {
+		fm.squelchSince = 0
+	} else {
+		fm.squelchSince += audioSamples
+	}
+	return fm.squelchSince <= fm.squelchHang
+}
+
+func (fm *fmReader) process(iq sdr.SamplesC64) error {
+	fm.discriminate(iq)
+
+	n, err := fm.resampler.ProcessC64(fm.out, fm.work[:len(iq)])
+	if err != nil {
+		return err
+	}
+	out := fm.out[:n]
+	if _, err := fm.lowpass.ProcessC64(out, out); err != nil {
+		return err
+	}
+
+	var (
+		samples = fm.audio[:n]
+		state   = fm.deemphasisState
+		open    = fm.open(iq, n)
+	)
+	for i, s := range out {
+		sample := real(s)
+		if fm.deemphasis != 0 {
+			state += (sample - state) * fm.deemphasis
+			sample = state
+		}
+		if !open {
+			sample = 0
+		}
+		samples[i] = sample
+	}
+	fm.deemphasisState = state
+	fm.pending = samples
+	return nil
+}
+
+func (fm *fmReader) Read(buf []float32) (int, error) {
+	for len(fm.pending) == 0 {
+		if fm.err != nil {
+			return 0, fm.err
+		}
+		n, err := fm.r.Read(fm.iq)
+		fm.err = err
+		if n == 0 {
+			continue
+		}
+		if perr := fm.process(fm.iq[:n]); perr != nil {
+			fm.err = perr
+			return 0, perr
+		}
+	}
+	n := copy(buf, fm.pending)
+	fm.pending = fm.pending[n:]
+	return n, nil
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,185 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package demod_test
+
+import (
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/audio"
+	"hz.tools/sdr/demod"
+)
+
+// fmReader will produce n samples of a carrier frequency modulated by a
+// tone, with the provided peak deviation, scaled to amplitude.
+type fmReader struct {
+	sampleRate uint
+	tone       float64
+	deviation  float64
+	amplitude  float32
+	remaining  int
+
+	t     int
+	phase float64
+}
+
+func (fr *fmReader) SampleFormat() sdr.SampleFormat { return sdr.SampleFormatC64 }
+func (fr *fmReader) SampleRate() uint               { return fr.sampleRate }
+
+func (fr *fmReader) Read(s sdr.Samples) (int, error) {
+	if fr.remaining == 0 {
+		return 0, io.EOF
+	}
+	buf := s.(sdr.SamplesC64)
+	if len(buf) > fr.remaining {
+		buf = buf[:fr.remaining]
+	}
+	rate := float64(fr.sampleRate)
+	for i := range buf {
+		freq := fr.deviation * math.Sin(2*math.Pi*fr.tone*float64(fr.t)/rate)
+		fr.phase += 2 * math.Pi * freq / rate
+		fr.t++
+		buf[i] = complex(
+			fr.amplitude*float32(math.Cos(fr.phase)),
+			fr.amplitude*float32(math.Sin(fr.phase)),
+		)
+	}
+	fr.remaining -= len(buf)
+	return len(buf), nil
+}
+
+// readAudio reads all the audio, and returns the RMS of the second half.
+func readAudio(t *testing.T, r audio.Reader) (float64, []float32) {
+	out := []float32{}
+	buf := make([]float32, 1000)
+	for {
+		n, err := r.Read(buf)
+		out = append(out, buf[:n]...)
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+	}
+
+	var sum float64
+	tail := out[len(out)/2:]
+	for _, s := range tail {
+		sum += float64(s) * float64(s)
+	}
+	return math.Sqrt(sum / float64(len(tail))), out
+}
+
+func TestWBFM(t *testing.T) {
+	in := &fmReader{
+		sampleRate: 240000,
+		tone:       1000,
+		deviation:  37500,
+		amplitude:  0.5,
+		remaining:  240000,
+	}
+	r, err := demod.WBFM(in, demod.FMConfig{Deemphasis: -1})
+	assert.NoError(t, err)
+	assert.Equal(t, uint(48000), r.SampleRate())
+
+	rms, out := readAudio(t, r)
+	assert.InDelta(t, 48000, len(out), 10)
+
+	// Half of full deviation, so a peak of 0.5.
+	assert.InDelta(t, 0.5/math.Sqrt2, rms, 0.01)
+
+	// 1 kHz tone, so 1000 zero crossings in the last half second.
+	var crossings int
+	for i := len(out)/2 + 1; i < len(out); i++ {
+		if (out[i-1] < 0) != (out[i] < 0) {
+			crossings++
+		}
+	}
+	assert.InDelta(t, 1000, crossings, 2)
+}
+
+func TestWBFMDeemphasis(t *testing.T) {
+	for _, tone := range []float64{1000, 5000} {
+		in := &fmReader{
+			sampleRate: 240000,
+			tone:       tone,
+			deviation:  37500,
+			amplitude:  0.5,
+			remaining:  240000,
+		}
+		r, err := demod.WBFM(in, demod.FMConfig{})
+		assert.NoError(t, err)
+
+		rms, _ := readAudio(t, r)
+		wt := 2 * math.Pi * tone * 75e-6
+		expected := 0.5 / math.Sqrt2 / math.Sqrt(1+wt*wt)
+		assert.InDelta(t, expected, rms, 0.02, "tone %f", tone)
+	}
+}
+
+func TestNBFM(t *testing.T) {
+	in := &fmReader{
+		sampleRate: 48000,
+		tone:       500,
+		deviation:  5000,
+		amplitude:  0.5,
+		remaining:  48000,
+	}
+	r, err := demod.NBFM(in, demod.FMConfig{
+		AudioSampleRate: 8000,
+		Deviation:       5 * rf.KHz,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, uint(8000), r.SampleRate())
+
+	rms, _ := readAudio(t, r)
+	assert.InDelta(t, 1/math.Sqrt2, rms, 0.02)
+}
+
+func TestFMSquelch(t *testing.T) {
+	in := &fmReader{
+		sampleRate: 48000,
+		tone:       500,
+		deviation:  2500,
+		amplitude:  0.01,
+		remaining:  48000,
+	}
+	r, err := demod.NBFM(in, demod.FMConfig{
+		SquelchLevel: -20,
+		SquelchHang:  time.Millisecond,
+	})
+	assert.NoError(t, err)
+
+	rms, _ := readAudio(t, r)
+	assert.Equal(t, float64(0), rms)
+}
+
+func TestFMSampleRateTooLow(t *testing.T) {
+	_, err := demod.WBFM(&fmReader{sampleRate: 48000}, demod.FMConfig{})
+	assert.Equal(t, demod.ErrSampleRateTooLow, err)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,187 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package detect
+
+import (
+	"fmt"
+	"math"
+
+	"hz.tools/rf"
+	"hz.tools/sdr/fft"
+)
+
+var (
+	// ErrCFARConfig will be returned if the CFARConfig is invalid.
+	ErrCFARConfig = fmt.Errorf("detect: invalid cfar configuration")
+
+	// ErrCFARShortInput will be returned if the input is too short to hold
+	// the CFAR window (training and guard cells on either side of the cell
+	// under test).
+	ErrCFARShortInput = fmt.Errorf("detect: input is shorter than the cfar window")
+)
+
+// CFARConfig configures a cell-averaging CFAR detector.
+type CFARConfig struct {
+	// GuardCells is the number of cells on either side of the cell under
+	// test that are excluded from the noise estimate, so that a signal
+	// wider than one cell doesn't raise its own threshold.
+	GuardCells int
+
+	// TrainingCells is the number of cells on either side of the guard
+	// cells that are averaged to estimate the noise. If unset, this will
+	// default to 16.
+	TrainingCells int
+
+	// FalseAlarmRate is the desired probability that a cell containing
+	// only noise is detected. If unset, this will default to 1e-3.
+	FalseAlarmRate float64
+}
+
+func (c CFARConfig) getTrainingCells() int {
+	if c.TrainingCells == 0 {
+		return 16
+	}
+	return c.TrainingCells
+}
+
+func (c CFARConfig) getFalseAlarmRate() float64 {
+	if c.FalseAlarmRate == 0 {
+		return 1e-3
+	}
+	return c.FalseAlarmRate
+}
+
+// Detection is a cell which was over the CFAR threshold.
+type Detection struct {
+	// Bin is the index of the cell in the input.
+	Bin int
+
+	// Frequency is the center frequency of the Bin. This is only set by
+	// CFAR.DetectSlice.
+	Frequency rf.Hz
+
+	// Power of the cell, in the same units as the input.
+	Power float32
+
+	// Noise is the estimate of the noise power around the cell, in the
+	// same units as the input.
+	Noise float32
+}
+
+// SNR is the ratio of the Power of the detection to the Noise around it,
+// in dB.
+func (d Detection) SNR() float64 {
+	return 10 * math.Log10(float64(d.Power)/float64(d.Noise))
+}
+
+// CFAR is a cell-averaging constant false alarm rate detector. For each cell
+// of the input, the noise is estimated from the average of the training cells
+// on either side, and the cell is detected if it's over that estimate scaled
+// to hit the configured false alarm rate.
+//
+// The input is expected to be linear power (such as from
+// fft.FrequencySlice.Power), of a signal with complex Gaussian noise, which
+// is to say the noise power is exponentially distributed. The input is
+// treated as circular, since FFT frames wrap around at the Nyquest frequency.
+type CFAR struct {
+	guard int
+	train int
+	scale float32
+}
+
+// NewCFAR will create a new CFAR detector.
+func NewCFAR(cfg CFARConfig) (*CFAR, error) {
+	var (
+		guard = cfg.GuardCells
+		train = cfg.getTrainingCells()
+		pfa   = cfg.getFalseAlarmRate()
+	)
+
+	if guard < 0 || train < 0 || pfa <= 0 || pfa >= 1 {
+		return nil, ErrCFARConfig
+	}
+
+	n := float64(train * 2)
+	return &CFAR{
+		guard: guard,
+		train: train,
+		scale: float32(n * (math.Pow(pfa, -1/n) - 1)),
+	}, nil
+}
+
+// Detect will return all the cells of the input which are over the CFAR
+// threshold.
+func (c *CFAR) Detect(power []float32) ([]Detection, error) {
+	n := len(power)
+	if n < 2*(c.guard+c.train)+1 {
+		return nil, ErrCFARShortInput
+	}
+
+	var (
+		ret     = []Detection{}
+		cells   = float32(2 * c.train)
+		wrapped = func(i int) float32 {
+			return power[((i%n)+n)%n]
+		}
+	)
+
+	for i := range power {
+		var noise float32
+		for j := c.guard + 1; j <= c.guard+c.train; j++ {
+			noise += wrapped(i-j) + wrapped(i+j)
+		}
+		noise /= cells
+
+		if power[i] > noise*c.scale {
+			ret = append(ret, Detection{
+				Bin:   i,
+				Power: power[i],
+				Noise: noise,
+			})
+		}
+	}
+	return ret, nil
+}
+
+// DetectSlice will compute the power of the FrequencySlice, and return
+// all the bins which are over the CFAR threshold, with the Frequency of each
+// Detection set.
+func (c *CFAR) DetectSlice(slice fft.FrequencySlice) ([]Detection, error) {
+	power := make([]float32, len(slice.Frequency))
+	if err := slice.Power(power); err != nil {
+		return nil, err
+	}
+
+	dets, err := c.Detect(power)
+	if err != nil {
+		return nil, err
+	}
+	for i := range dets {
+		freq, err := slice.FreqByBin(dets[i].Bin)
+		if err != nil {
+			return nil, err
+		}
+		dets[i].Frequency = freq
+	}
+	return dets, nil
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,141 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package detect_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/rf"
+	"hz.tools/sdr/detect"
+	"hz.tools/sdr/fft"
+)
+
+// frame fills power with exponentially distributed noise (the power of
+// complex Gaussian noise) with a mean of 1, and a signal at bin 100 at the
+// provided SNR.
+func frame(r *rand.Rand, power []float32, snr float64) {
+	amplitude := math.Sqrt(math.Pow(10, snr/10))
+	for i := range power {
+		re := r.NormFloat64() / math.Sqrt2
+		im := r.NormFloat64() / math.Sqrt2
+		if i == 100 {
+			re += amplitude
+		}
+		power[i] = float32(re*re + im*im)
+	}
+}
+
+func TestCFARFalseAlarmRate(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	cfar, err := detect.NewCFAR(detect.CFARConfig{GuardCells: 2})
+	assert.NoError(t, err)
+
+	var (
+		power  = make([]float32, 256)
+		frames = 400
+		alarms = 0
+	)
+	for i := 0; i < frames; i++ {
+		frame(r, power, math.Inf(-1))
+		dets, err := cfar.Detect(power)
+		assert.NoError(t, err)
+		alarms += len(dets)
+	}
+
+	rate := float64(alarms) / float64(frames*len(power))
+	assert.True(t, rate > 0.5e-3 && rate < 2e-3, "false alarm rate %f", rate)
+}
+
+func TestCFARSNRSweep(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	cfar, err := detect.NewCFAR(detect.CFARConfig{
+		GuardCells:     2,
+		TrainingCells:  16,
+		FalseAlarmRate: 1e-4,
+	})
+	assert.NoError(t, err)
+
+	var (
+		power  = make([]float32, 256)
+		trials = 500
+		last   = -1.0
+	)
+
+	for _, snr := range []float64{0, 5, 10, 13, 16, 20} {
+		hits := 0
+		for i := 0; i < trials; i++ {
+			frame(r, power, snr)
+			dets, err := cfar.Detect(power)
+			assert.NoError(t, err)
+			for _, det := range dets {
+				if det.Bin == 100 {
+					hits++
+				}
+			}
+		}
+		pd := float64(hits) / float64(trials)
+		assert.True(t, pd >= last, "pd at %f dB went down (%f)", snr, pd)
+		last = pd
+
+		switch snr {
+		case 0:
+			assert.True(t, pd < 0.05, "pd at 0 dB %f", pd)
+		case 20:
+			assert.True(t, pd > 0.99, "pd at 20 dB %f", pd)
+		}
+	}
+}
+
+func TestCFARSlice(t *testing.T) {
+	frequency := make([]complex64, 64)
+	for i := range frequency {
+		frequency[i] = complex(float32(i%3), float32(i%2))
+	}
+	frequency[60] = 500
+
+	cfar, err := detect.NewCFAR(detect.CFARConfig{TrainingCells: 8})
+	assert.NoError(t, err)
+
+	dets, err := cfar.DetectSlice(fft.NewFrequencySlice(frequency, 64, fft.ZeroFirst))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(dets))
+	assert.Equal(t, 60, dets[0].Bin)
+	assert.Equal(t, rf.Hz(-4), dets[0].Frequency)
+	assert.True(t, dets[0].SNR() > 40)
+}
+
+func TestCFARErrors(t *testing.T) {
+	_, err := detect.NewCFAR(detect.CFARConfig{FalseAlarmRate: 2})
+	assert.Equal(t, detect.ErrCFARConfig, err)
+	_, err = detect.NewCFAR(detect.CFARConfig{GuardCells: -1})
+	assert.Equal(t, detect.ErrCFARConfig, err)
+
+	cfar, err := detect.NewCFAR(detect.CFARConfig{GuardCells: 2})
+	assert.NoError(t, err)
+	_, err = cfar.Detect(make([]float32, 16))
+	assert.Equal(t, detect.ErrCFARShortInput, err)
+}
+
+// vim: foldmethod=marker
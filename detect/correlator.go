@@ -0,0 +1,310 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package detect
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/fft"
+)
+
+var (
+	// ErrNoTemplates will be returned if a Correlator is created without
+	// any templates to search for.
+	ErrNoTemplates = fmt.Errorf("detect: no correlator templates provided")
+
+	// ErrFFTLengthTooShort will be returned if the configured FFTLength is
+	// not longer than the longest template.
+	ErrFFTLengthTooShort = fmt.Errorf("detect: fft length must be longer than the longest template")
+)
+
+// CorrelatorConfig configures a bank of matched filters.
+type CorrelatorConfig struct {
+	// Templates are the complex waveforms to search for, such as the
+	// modulated preamble of a burst protocol.
+	Templates []sdr.SamplesC64
+
+	// Planner is the FFT implementation used to accelerate the correlation.
+	Planner fft.Planner
+
+	// Threshold is the normalized correlation (between 0 and 1) that must
+	// be exceeded to emit a Match. If unset, this will default to 0.7.
+	Threshold float32
+
+	// FFTLength is the size of the FFT used to do the correlation. Each
+	// FFT will produce FFTLength minus the longest template plus one new
+	// outputs. If unset, this will default to the first power of two at
+	// least four times as long as the longest template.
+	FFTLength int
+}
+
+func (c CorrelatorConfig) getThreshold() float32 {
+	if c.Threshold == 0 {
+		return 0.7
+	}
+	return c.Threshold
+}
+
+func (c CorrelatorConfig) getFFTLength(templateLength int) int {
+	if c.FFTLength != 0 {
+		return c.FFTLength
+	}
+	n := 1
+	for n < templateLength*4 {
+		n <<= 1
+	}
+	return n
+}
+
+// Match is a template found in the stream by a Correlator.
+type Match struct {
+	// Template is the index of the matched template in the
+	// CorrelatorConfig.
+	Template int
+
+	// Offset is the index of the sample in the stream (counting from the
+	// first sample passed to Process) where the template starts.
+	Offset int64
+
+	// Correlation is the normalized correlation between the template and
+	// the stream at Offset, between 0 and 1.
+	Correlation float32
+
+	// Phase is the phase of the stream relative to the template, in
+	// radians.
+	Phase float64
+}
+
+type correlatorTemplate struct {
+	freq   []complex64
+	length int
+	energy float64
+
+	// the two most recently computed points, used to find peaks across
+	// block boundaries.
+	last [2]Match
+}
+
+// Correlator slides a bank of complex templates over a stream of IQ samples,
+// emitting a Match at each peak in the normalized correlation that is over
+// the configured threshold.
+//
+// The correlation is done in the frequency domain using overlap-save, so the
+// cost per sample grows with the log of the template length, rather than the
+// template length, as a direct convolution would.
+type Correlator struct {
+	threshold float32
+	templates []correlatorTemplate
+	fftLength int
+	overlap   int
+	scale     float32
+
+	pending sdr.SamplesC64
+	offset  int64
+
+	block   sdr.SamplesC64
+	blockF  []complex64
+	corr    sdr.SamplesC64
+	corrF   []complex64
+	energy  []float64
+	forward fft.Plan
+	reverse fft.Plan
+}
+
+// NewCorrelator will create a new matched filter bank.
+func NewCorrelator(cfg CorrelatorConfig) (*Correlator, error) {
+	if len(cfg.Templates) == 0 {
+		return nil, ErrNoTemplates
+	}
+
+	longest := 0
+	for _, template := range cfg.Templates {
+		if len(template) > longest {
+			longest = len(template)
+		}
+	}
+
+	fftLength := cfg.getFFTLength(longest)
+	if fftLength <= longest {
+		return nil, ErrFFTLengthTooShort
+	}
+
+	c := &Correlator{
+		threshold: cfg.getThreshold(),
+		templates: make([]correlatorTemplate, len(cfg.Templates)),
+		fftLength: fftLength,
+		overlap:   longest - 1,
+		block:     make(sdr.SamplesC64, fftLength),
+		blockF:    make([]complex64, fftLength),
+		corr:      make(sdr.SamplesC64, fftLength),
+		corrF:     make([]complex64, fftLength),
+		energy:    make([]float64, fftLength+1),
+	}
+
+	var err error
+	if c.forward, err = cfg.Planner(c.block, c.blockF, fft.Forward); err != nil {
+		return nil, err
+	}
+	if c.reverse, err = cfg.Planner(c.corr, c.corrF, fft.Backward); err != nil {
+		c.forward.Close()
+		return nil, err
+	}
+
+	// FFT backends don't agree on how (or if) to normalize, so figure out
+	// what a round trip does to an impulse, and undo it.
+	c.block[0] = 1
+	if err := c.forward.Transform(); err != nil {
+		c.Close()
+		return nil, err
+	}
+	copy(c.corrF, c.blockF)
+	if err := c.reverse.Transform(); err != nil {
+		c.Close()
+		return nil, err
+	}
+	c.scale = 1 / real(c.corr[0])
+
+	for i, template := range cfg.Templates {
+		for j := range c.block {
+			c.block[j] = 0
+		}
+		copy(c.block, template)
+
+		var energy float64
+		for _, s := range template {
+			energy += float64(real(s)*real(s) + imag(s)*imag(s))
+		}
+
+		if err := c.forward.Transform(); err != nil {
+			c.Close()
+			return nil, err
+		}
+		freq := make([]complex64, fftLength)
+		for j, f := range c.blockF {
+			freq[j] = complex(real(f), -imag(f))
+		}
+		c.templates[i] = correlatorTemplate{
+			freq:   freq,
+			length: len(template),
+			energy: energy,
+		}
+	}
+
+	// Start off with a history of zeros so the first sample passed to
+	// Process is correlated against every template, and offsets line up.
+	c.pending = make(sdr.SamplesC64, c.overlap)
+	c.offset = -int64(c.overlap)
+	return c, nil
+}
+
+// Process will correlate the provided samples (and the tail of the samples
+// previously passed to Process) against each template, and return any
+// Matches found.
+//
+// Samples are buffered until there are enough to perform a full FFT, so
+// Matches may be returned from a later call than the one that contained
+// the samples.
+func (c *Correlator) Process(iq sdr.SamplesC64) ([]Match, error) {
+	var (
+		ret   = []Match{}
+		valid = c.fftLength - c.overlap
+	)
+
+	c.pending = append(c.pending, iq...)
+	for len(c.pending) >= c.fftLength {
+		matches, err := c.processBlock(valid)
+		if err != nil {
+			return ret, err
+		}
+		ret = append(ret, matches...)
+
+		c.pending = append(c.pending[:0], c.pending[valid:]...)
+		c.offset += int64(valid)
+	}
+	return ret, nil
+}
+
+func (c *Correlator) processBlock(valid int) ([]Match, error) {
+	ret := []Match{}
+
+	copy(c.block, c.pending[:c.fftLength])
+	if err := c.forward.Transform(); err != nil {
+		return nil, err
+	}
+
+	// Prefix sum of the power of the block, so the energy of the stream
+	// under each template can be found quickly.
+	for i, s := range c.block {
+		c.energy[i+1] = c.energy[i] + float64(real(s)*real(s)+imag(s)*imag(s))
+	}
+
+	for ti := range c.templates {
+		template := &c.templates[ti]
+		for i, f := range c.blockF {
+			c.corrF[i] = f * template.freq[i]
+		}
+		if err := c.reverse.Transform(); err != nil {
+			return nil, err
+		}
+
+		for i := 0; i < valid; i++ {
+			var (
+				r      = complex128(c.corr[i] * complex(c.scale, 0))
+				energy = c.energy[i+template.length] - c.energy[i]
+				corr   float32
+			)
+			if energy > 0 && template.energy > 0 {
+				corr = float32(cmplx.Abs(r) / math.Sqrt(energy*template.energy))
+			}
+
+			point := Match{
+				Template:    ti,
+				Offset:      c.offset + int64(i),
+				Correlation: corr,
+				Phase:       cmplx.Phase(r),
+			}
+
+			prev, prevprev := template.last[1], template.last[0]
+			if prev.Correlation >= c.threshold &&
+				prev.Correlation > prevprev.Correlation &&
+				prev.Correlation >= point.Correlation {
+				ret = append(ret, prev)
+			}
+			template.last[0], template.last[1] = prev, point
+		}
+	}
+	return ret, nil
+}
+
+// Close will release the FFT plans.
+func (c *Correlator) Close() error {
+	errF := c.forward.Close()
+	errR := c.reverse.Close()
+	if errF != nil {
+		return errF
+	}
+	return errR
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,107 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package detect_test
+
+import (
+	"math/cmplx"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/detect"
+	"hz.tools/sdr/testutils"
+)
+
+func qpsk(r *rand.Rand, n int) sdr.SamplesC64 {
+	ret := make(sdr.SamplesC64, n)
+	for i := range ret {
+		ret[i] = complex(float32(r.Intn(2)*2-1), float32(r.Intn(2)*2-1))
+	}
+	return ret
+}
+
+func TestCorrelator(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	templates := []sdr.SamplesC64{qpsk(r, 32), qpsk(r, 20)}
+
+	stream := make(sdr.SamplesC64, 4000)
+	for i := range stream {
+		stream[i] = complex(float32(r.NormFloat64()*0.3), float32(r.NormFloat64()*0.3))
+	}
+	rotate := complex64(cmplx.Exp(complex(0, 0.7)))
+	for i, s := range templates[0] {
+		stream[1000+i] += s * rotate
+	}
+	for i, s := range templates[1] {
+		stream[2500+i] += s * 0.5
+	}
+
+	c, err := detect.NewCorrelator(detect.CorrelatorConfig{
+		Templates: templates,
+		Planner:   testutils.DFTPlanner,
+		FFTLength: 128,
+	})
+	assert.NoError(t, err)
+	defer c.Close()
+
+	matches := []detect.Match{}
+	for i := 0; i < len(stream); i += 300 {
+		end := i + 300
+		if end > len(stream) {
+			end = len(stream)
+		}
+		m, err := c.Process(stream[i:end])
+		assert.NoError(t, err)
+		matches = append(matches, m...)
+	}
+
+	assert.Equal(t, 2, len(matches))
+	if len(matches) != 2 {
+		return
+	}
+
+	assert.Equal(t, 0, matches[0].Template)
+	assert.Equal(t, int64(1000), matches[0].Offset)
+	assert.InDelta(t, 0.7, matches[0].Phase, 0.1)
+	assert.True(t, matches[0].Correlation > 0.9)
+
+	assert.Equal(t, 1, matches[1].Template)
+	assert.Equal(t, int64(2500), matches[1].Offset)
+	assert.InDelta(t, 0, matches[1].Phase, 0.1)
+	assert.True(t, matches[1].Correlation > 0.8)
+}
+
+func TestCorrelatorErrors(t *testing.T) {
+	_, err := detect.NewCorrelator(detect.CorrelatorConfig{Planner: testutils.DFTPlanner})
+	assert.Equal(t, detect.ErrNoTemplates, err)
+
+	_, err = detect.NewCorrelator(detect.CorrelatorConfig{
+		Templates: []sdr.SamplesC64{make(sdr.SamplesC64, 64)},
+		Planner:   testutils.DFTPlanner,
+		FFTLength: 64,
+	})
+	assert.Equal(t, detect.ErrFFTLengthTooShort, err)
+}
+
+// vim: foldmethod=marker
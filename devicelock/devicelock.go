@@ -0,0 +1,147 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package devicelock
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvDir is the environment variable which, if set, overrides the directory
+// lock files are kept in. All programs sharing devices must agree on it.
+const EnvDir = "HZ_TOOLS_SDR_LOCK_DIR"
+
+// ErrLocked will be returned if the device is locked by another process.
+type ErrLocked struct {
+	// Key is the key of the device that is locked.
+	Key string
+
+	// PID is the process ID of the holder of the lock, as written into
+	// the lock file, or 0 if unknown.
+	PID int
+}
+
+// Error implements the error interface.
+func (e ErrLocked) Error() string {
+	if e.PID == 0 {
+		return fmt.Sprintf("devicelock: device %q is locked by another process", e.Key)
+	}
+	return fmt.Sprintf("devicelock: device %q is locked by pid %d", e.Key, e.PID)
+}
+
+// Dir returns the directory lock files are kept in.
+func Dir() string {
+	if dir := os.Getenv(EnvDir); dir != "" {
+		return dir
+	}
+	return filepath.Join(os.TempDir(), "hz.tools-sdr-locks")
+}
+
+// Lock is a held lock on a device.
+type Lock struct {
+	key  string
+	file *os.File
+}
+
+// Key returns the key of the locked device.
+func (l *Lock) Key() string {
+	return l.key
+}
+
+func path(key string) string {
+	// Keys are things like serial numbers, but let's not let a weird one
+	// escape the lock directory.
+	clean := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9',
+			r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+	return filepath.Join(Dir(), clean+".lock")
+}
+
+// TryLock will take the lock on the device with the provided key, returning
+// an ErrLocked right away if another process holds it.
+func TryLock(key string) (*Lock, error) {
+	if err := os.MkdirAll(Dir(), 0777); err != nil {
+		return nil, err
+	}
+
+	fd, err := os.OpenFile(path(key), os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := flock(fd); err != nil {
+		pid := readPID(fd)
+		fd.Close()
+		if err == errWouldBlock {
+			return nil, ErrLocked{Key: key, PID: pid}
+		}
+		return nil, err
+	}
+
+	// Write our pid in, so that the next process to try knows who to go
+	// bother.
+	fd.Truncate(0)
+	fd.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+
+	return &Lock{key: key, file: fd}, nil
+}
+
+// LockTimeout will take the lock on the device with the provided key,
+// waiting up to the provided timeout for another process to release it, in
+// which case the last ErrLocked is returned.
+func LockTimeout(key string, timeout time.Duration) (*Lock, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		l, err := TryLock(key)
+		if _, ok := err.(ErrLocked); !ok || !time.Now().Before(deadline) {
+			return l, err
+		}
+		time.Sleep(time.Millisecond * 50)
+	}
+}
+
+// Unlock will release the lock.
+func (l *Lock) Unlock() error {
+	l.file.Truncate(0)
+	return l.file.Close()
+}
+
+func readPID(fd *os.File) int {
+	buf := make([]byte, 32)
+	n, _ := fd.ReadAt(buf, 0)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(buf[:n])))
+	if err != nil {
+		return 0
+	}
+	return pid
+}
+
+// vim: foldmethod=marker
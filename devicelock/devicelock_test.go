@@ -0,0 +1,80 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package devicelock_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr/devicelock"
+)
+
+func TestLock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "devicelock")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	os.Setenv(devicelock.EnvDir, dir)
+
+	l, err := devicelock.TryLock("00000001")
+	assert.NoError(t, err)
+
+	_, err = devicelock.TryLock("00000001")
+	assert.Equal(t, devicelock.ErrLocked{Key: "00000001", PID: os.Getpid()}, err)
+
+	other, err := devicelock.TryLock("00000002")
+	assert.NoError(t, err)
+	assert.NoError(t, other.Unlock())
+
+	assert.NoError(t, l.Unlock())
+
+	l, err = devicelock.TryLock("00000001")
+	assert.NoError(t, err)
+	assert.NoError(t, l.Unlock())
+}
+
+func TestLockTimeout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "devicelock")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	os.Setenv(devicelock.EnvDir, dir)
+
+	l, err := devicelock.TryLock("serial/with/../slashes")
+	assert.NoError(t, err)
+
+	start := time.Now()
+	_, err = devicelock.LockTimeout("serial/with/../slashes", time.Millisecond*100)
+	assert.IsType(t, devicelock.ErrLocked{}, err)
+	assert.True(t, time.Since(start) >= time.Millisecond*100)
+
+	go func() {
+		time.Sleep(time.Millisecond * 100)
+		l.Unlock()
+	}()
+	l2, err := devicelock.LockTimeout("serial/with/../slashes", time.Second*5)
+	assert.NoError(t, err)
+	assert.NoError(t, l2.Unlock())
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,96 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package df
+
+import (
+	"math"
+	"math/cmplx"
+
+	"hz.tools/rf"
+)
+
+// Element is the position of one antenna in an Array, in meters, relative
+// to the center of the array. The Y axis points towards a bearing of 0, and
+// the X axis towards a bearing of π/2.
+type Element struct {
+	X float64
+	Y float64
+}
+
+// Array is the layout of the antennas connected to each of the receivers,
+// in the same order as the receivers.
+//
+// Bearings are in radians, clockwise from the Y axis, so if the Y axis of
+// the array is pointed north, bearings are the same as a compass heading.
+//
+// An array with all its elements in a line can't tell which side of the
+// line a signal is coming from, so every bearing from a linear array will
+// have a mirror image on the other side of the line, with the same score.
+type Array []Element
+
+// UniformLinearArray will return an Array of n elements along the X axis,
+// spacing meters apart. Half a wavelength is the usual spacing; any wider
+// and there will be more than one bearing that fits the same phase
+// differences.
+func UniformLinearArray(n int, spacing float64) Array {
+	ret := make(Array, n)
+	for i := range ret {
+		ret[i] = Element{X: spacing * (float64(i) - float64(n-1)/2)}
+	}
+	return ret
+}
+
+// UniformCircularArray will return an Array of n elements spaced evenly
+// around a circle, radius meters from the center. The 0th element is on the
+// Y axis, and the rest are clockwise from it (the same way as bearings).
+func UniformCircularArray(n int, radius float64) Array {
+	ret := make(Array, n)
+	for i := range ret {
+		angle := 2 * math.Pi * float64(i) / float64(n)
+		ret[i] = Element{
+			X: radius * math.Sin(angle),
+			Y: radius * math.Cos(angle),
+		}
+	}
+	return ret
+}
+
+// SteeringVector will write the phase (as a unit complex number) that a
+// signal at the provided frequency, arriving from the provided bearing,
+// would have at each element, relative to the center of the array.
+func (a Array) SteeringVector(freq rf.Hz, bearing float64, dst []complex128) error {
+	if len(dst) < len(a) {
+		return ErrChannelMismatch
+	}
+	var (
+		k = 2 * math.Pi / freq.Wavelength()
+		x = math.Sin(bearing)
+		y = math.Cos(bearing)
+	)
+	for i, el := range a {
+		// Elements closer to the signal see it first, which is a phase
+		// lead of however much closer they are, in radians.
+		dst[i] = cmplx.Rect(1, k*(el.X*x+el.Y*y))
+	}
+	return nil
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,164 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package df
+
+import (
+	"fmt"
+
+	"hz.tools/sdr"
+)
+
+var (
+	// ErrNoSamples will be returned if a bearing is requested from a
+	// Covariance before any samples have been added.
+	ErrNoSamples = fmt.Errorf("df: no samples have been added")
+
+	// ErrChannelMismatch will be returned if the number of buffers or
+	// readers doesn't match the number of channels (or Array elements).
+	ErrChannelMismatch = fmt.Errorf("df: wrong number of channels")
+
+	// ErrSampleRateMismatch will be returned if the Readers passed to Read
+	// are not all at the same sample rate.
+	ErrSampleRateMismatch = fmt.Errorf("df: readers are not the same sample rate")
+)
+
+// Covariance will accumulate the spatial covariance matrix of a set of
+// coherent receivers, which is what all the bearing estimators work from.
+// Element (i, j) of the matrix is the average of x_i * conj(x_j).
+type Covariance struct {
+	channels int
+	sum      []complex128
+	samples  int
+}
+
+// NewCovariance will create a new, empty, Covariance for the provided
+// number of channels.
+func NewCovariance(channels int) *Covariance {
+	return &Covariance{
+		channels: channels,
+		sum:      make([]complex128, channels*channels),
+	}
+}
+
+// Channels returns the number of channels in the Covariance.
+func (c *Covariance) Channels() int {
+	return c.channels
+}
+
+// Samples returns the number of samples averaged so far.
+func (c *Covariance) Samples() int {
+	return c.samples
+}
+
+// At will return element (i, j) of the covariance matrix.
+func (c *Covariance) At(i, j int) complex128 {
+	if c.samples == 0 {
+		return 0
+	}
+	return c.sum[i*c.channels+j] / complex(float64(c.samples), 0)
+}
+
+// matrix will return the average covariance matrix, row major.
+func (c *Covariance) matrix() ([]complex128, error) {
+	if c.samples == 0 {
+		return nil, ErrNoSamples
+	}
+	var (
+		scale = complex(1/float64(c.samples), 0)
+		ret   = make([]complex128, len(c.sum))
+	)
+	for i, el := range c.sum {
+		ret[i] = el * scale
+	}
+	return ret, nil
+}
+
+// Add will add one buffer from each channel to the running average. The
+// buffers must all be the same length, and have been captured at the same
+// time.
+func (c *Covariance) Add(bufs []sdr.SamplesC64) error {
+	if len(bufs) != c.channels {
+		return ErrChannelMismatch
+	}
+	n := len(bufs[0])
+	for _, buf := range bufs {
+		if len(buf) != n {
+			return sdr.ErrDstTooSmall
+		}
+	}
+
+	for i := 0; i < c.channels; i++ {
+		for j := i; j < c.channels; j++ {
+			var acc complex128
+			for k := 0; k < n; k++ {
+				x := complex128(bufs[i][k])
+				y := complex128(bufs[j][k])
+				acc += x * complex(real(y), -imag(y))
+			}
+			c.sum[i*c.channels+j] += acc
+			if i != j {
+				c.sum[j*c.channels+i] += complex(real(acc), -imag(acc))
+			}
+		}
+	}
+	c.samples += n
+	return nil
+}
+
+// Reset will clear the running average.
+func (c *Covariance) Reset() {
+	for i := range c.sum {
+		c.sum[i] = 0
+	}
+	c.samples = 0
+}
+
+// Read will read `samples` samples from each of the Readers in lockstep,
+// and Add them.
+//
+// The Readers are expected to be coherent and time-aligned already; this
+// will not attempt to line the streams up.
+func (c *Covariance) Read(readers []sdr.Reader, samples int) error {
+	if len(readers) != c.channels {
+		return ErrChannelMismatch
+	}
+	for _, r := range readers {
+		if r.SampleRate() != readers[0].SampleRate() {
+			return ErrSampleRateMismatch
+		}
+		if r.SampleFormat() != sdr.SampleFormatC64 {
+			return sdr.ErrSampleFormatUnknown
+		}
+	}
+
+	bufs := make([]sdr.SamplesC64, len(readers))
+	for i := range bufs {
+		bufs[i] = make(sdr.SamplesC64, samples)
+	}
+	for i, r := range readers {
+		if _, err := sdr.ReadFull(r, bufs[i]); err != nil {
+			return err
+		}
+	}
+	return c.Add(bufs)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,192 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package df_test
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/df"
+)
+
+var freq = rf.MHz * 433
+
+// capture will simulate each element of the array receiving an independent
+// random signal from each of the bearings, plus some noise.
+func capture(r *rand.Rand, array df.Array, n int, bearings ...float64) []sdr.SamplesC64 {
+	ret := make([]sdr.SamplesC64, len(array))
+	for i := range ret {
+		ret[i] = make(sdr.SamplesC64, n)
+		for j := range ret[i] {
+			ret[i][j] = complex64(complex(r.NormFloat64()*0.1, r.NormFloat64()*0.1))
+		}
+	}
+
+	steering := make([]complex128, len(array))
+	for _, bearing := range bearings {
+		if err := array.SteeringVector(freq, bearing, steering); err != nil {
+			panic(err)
+		}
+		for j := 0; j < n; j++ {
+			s := complex(r.NormFloat64(), r.NormFloat64())
+			for i := range ret {
+				ret[i][j] += complex64(steering[i] * s)
+			}
+		}
+	}
+	return ret
+}
+
+func TestArray(t *testing.T) {
+	ula := df.UniformLinearArray(3, 0.5)
+	assert.Equal(t, df.Array{{X: -0.5}, {X: 0}, {X: 0.5}}, ula)
+
+	uca := df.UniformCircularArray(4, 1)
+	assert.InDelta(t, 1, uca[0].Y, 1e-9)
+	assert.InDelta(t, 1, uca[1].X, 1e-9)
+	assert.InDelta(t, -1, uca[2].Y, 1e-9)
+
+	// A signal from straight up the Y axis reaches the 0th element a
+	// quarter wavelength early.
+	steering := make([]complex128, 4)
+	assert.NoError(t, df.UniformCircularArray(4, freq.Wavelength()/4).SteeringVector(freq, 0, steering))
+	assert.InDelta(t, 0, real(steering[0]), 1e-9)
+	assert.InDelta(t, 1, imag(steering[0]), 1e-9)
+	assert.InDelta(t, 1, real(steering[1]), 1e-9)
+
+	assert.Equal(t, df.ErrChannelMismatch, uca.SteeringVector(freq, 0, make([]complex128, 3)))
+}
+
+func TestCovariance(t *testing.T) {
+	cov := df.NewCovariance(2)
+	assert.Equal(t, complex128(0), cov.At(0, 1))
+
+	assert.NoError(t, cov.Add([]sdr.SamplesC64{{1, 1i}, {1i, -1}}))
+	assert.Equal(t, 2, cov.Samples())
+	assert.Equal(t, complex128(1), cov.At(0, 0))
+	assert.Equal(t, complex128(-1i), cov.At(0, 1))
+	assert.Equal(t, complex128(1i), cov.At(1, 0))
+
+	assert.Equal(t, df.ErrChannelMismatch, cov.Add([]sdr.SamplesC64{{1}}))
+	assert.Equal(t, sdr.ErrDstTooSmall, cov.Add([]sdr.SamplesC64{{1}, {1, 2}}))
+
+	cov.Reset()
+	assert.Equal(t, 0, cov.Samples())
+	_, err := df.Interferometry(df.UniformLinearArray(2, 0.1), freq, cov, 360)
+	assert.Equal(t, df.ErrNoSamples, err)
+}
+
+func TestCovarianceRead(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	array := df.UniformCircularArray(3, freq.Wavelength()/3)
+	bufs := capture(r, array, 1024, 2)
+
+	var (
+		readers = make([]sdr.Reader, len(bufs))
+		writers = make([]sdr.PipeWriter, len(bufs))
+	)
+	for i := range readers {
+		readers[i], writers[i] = sdr.Pipe(1024, sdr.SampleFormatC64)
+	}
+	go func() {
+		for i := range writers {
+			writers[i].Write(bufs[i])
+		}
+	}()
+
+	cov := df.NewCovariance(3)
+	assert.NoError(t, cov.Read(readers, 1024))
+	assert.Equal(t, 1024, cov.Samples())
+
+	spectrum, err := df.Interferometry(array, freq, cov, 360)
+	assert.NoError(t, err)
+	assert.InDelta(t, 2, spectrum.Peaks(1)[0], 0.02)
+
+	assert.Equal(t, df.ErrChannelMismatch, cov.Read(readers[:2], 1))
+}
+
+func TestInterferometry(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	array := df.UniformCircularArray(5, freq.Wavelength()/3)
+
+	cov := df.NewCovariance(5)
+	assert.NoError(t, cov.Add(capture(r, array, 4096, 1)))
+
+	spectrum, err := df.Interferometry(array, freq, cov, 360)
+	assert.NoError(t, err)
+	assert.Len(t, spectrum.Bearings, 360)
+
+	peaks := spectrum.Peaks(1)
+	assert.Len(t, peaks, 1)
+	assert.InDelta(t, 1, peaks[0], 0.02)
+
+	i := sort.SearchFloat64s(spectrum.Bearings, peaks[0])
+	assert.InDelta(t, 1, spectrum.Power[i], 0.05)
+
+	_, err = df.Interferometry(df.UniformCircularArray(4, 1), freq, cov, 360)
+	assert.Equal(t, df.ErrChannelMismatch, err)
+}
+
+func TestMUSIC(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	array := df.UniformCircularArray(5, freq.Wavelength()/3)
+
+	cov := df.NewCovariance(5)
+	assert.NoError(t, cov.Add(capture(r, array, 4096, 1, 4)))
+
+	spectrum, err := df.MUSIC(array, freq, cov, 2, 720)
+	assert.NoError(t, err)
+
+	peaks := spectrum.Peaks(2)
+	assert.Len(t, peaks, 2)
+	sort.Float64s(peaks)
+	assert.InDelta(t, 1, peaks[0], 0.01)
+	assert.InDelta(t, 4, peaks[1], 0.01)
+
+	_, err = df.MUSIC(array, freq, cov, 5, 720)
+	assert.Equal(t, df.ErrTooManySources, err)
+}
+
+func TestMUSICLinear(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	array := df.UniformLinearArray(4, freq.Wavelength()/2)
+
+	cov := df.NewCovariance(4)
+	assert.NoError(t, cov.Add(capture(r, array, 4096, 0.5)))
+
+	spectrum, err := df.MUSIC(array, freq, cov, 1, 720)
+	assert.NoError(t, err)
+
+	// A linear array can't tell which side of the line the signal is on.
+	peaks := spectrum.Peaks(2)
+	assert.Len(t, peaks, 2)
+	sort.Float64s(peaks)
+	assert.InDelta(t, 0.5, peaks[0], 0.01)
+	assert.InDelta(t, math.Pi-0.5, peaks[1], 0.01)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,206 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package df
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"sort"
+
+	"hz.tools/rf"
+)
+
+var (
+	// ErrTooManySources will be returned if MUSIC is asked to find as many
+	// (or more) signals as there are elements in the Array.
+	ErrTooManySources = fmt.Errorf("df: there must be fewer sources than array elements")
+)
+
+// Spectrum is the score of each bearing scanned by an estimator, which is
+// higher the better the bearing fits the measured covariance. Bearings
+// are evenly spaced, starting at 0.
+type Spectrum struct {
+	Bearings []float64
+	Power    []float64
+}
+
+// Peaks will return the bearings of the n highest peaks in the Spectrum,
+// highest first. Since bearings wrap around, a peak can be at the first
+// or last bearing.
+func (s Spectrum) Peaks(n int) []float64 {
+	var (
+		l     = len(s.Power)
+		peaks = []int{}
+	)
+	for i, p := range s.Power {
+		if p > s.Power[(i+l-1)%l] && p >= s.Power[(i+1)%l] {
+			peaks = append(peaks, i)
+		}
+	}
+	sort.Slice(peaks, func(i, j int) bool {
+		return s.Power[peaks[i]] > s.Power[peaks[j]]
+	})
+	if len(peaks) > n {
+		peaks = peaks[:n]
+	}
+
+	ret := make([]float64, len(peaks))
+	for i, peak := range peaks {
+		ret[i] = s.Bearings[peak]
+	}
+	return ret
+}
+
+// scan will call score for `steps` bearings evenly spaced around the circle,
+// with the steering vector for each.
+func scan(
+	array Array,
+	freq rf.Hz,
+	steps int,
+	score func([]complex128) float64,
+) (Spectrum, error) {
+	var (
+		steering = make([]complex128, len(array))
+		ret      = Spectrum{
+			Bearings: make([]float64, steps),
+			Power:    make([]float64, steps),
+		}
+	)
+	for i := range ret.Bearings {
+		bearing := 2 * math.Pi * float64(i) / float64(steps)
+		if err := array.SteeringVector(freq, bearing, steering); err != nil {
+			return Spectrum{}, err
+		}
+		ret.Bearings[i] = bearing
+		ret.Power[i] = score(steering)
+	}
+	return ret, nil
+}
+
+// Interferometry will estimate the bearing of a single signal by
+// correlative interferometry, comparing the phase and amplitude each
+// element sees relative to the 0th element with the steering vector at
+// `steps` bearings. The score is the correlation between the two, from 0
+// to 1.
+func Interferometry(array Array, freq rf.Hz, cov *Covariance, steps int) (Spectrum, error) {
+	if len(array) != cov.Channels() {
+		return Spectrum{}, ErrChannelMismatch
+	}
+	r, err := cov.matrix()
+	if err != nil {
+		return Spectrum{}, err
+	}
+
+	var (
+		n        = len(array)
+		measured = make([]complex128, n)
+		power    float64
+	)
+	for i := range measured {
+		measured[i] = r[i*n]
+		power += real(measured[i])*real(measured[i]) + imag(measured[i])*imag(measured[i])
+	}
+	if power == 0 {
+		return Spectrum{}, ErrNoSamples
+	}
+
+	return scan(array, freq, steps, func(steering []complex128) float64 {
+		var dot complex128
+		for i := range steering {
+			dot += cmplx.Conj(steering[i]) * measured[i]
+		}
+		mag := cmplx.Abs(dot)
+		return mag * mag / (float64(n) * power)
+	})
+}
+
+// MUSIC will estimate the bearings of `sources` signals with the MUSIC
+// (MUltiple SIgnal Classification) algorithm. The covariance matrix is
+// split into the signal subspace (the eigenvectors with the `sources`
+// largest eigenvalues) and the noise subspace (the rest); the score of a
+// bearing is how close to orthogonal its steering vector is to the noise
+// subspace, which is very large at the bearing of each signal.
+//
+// There must be fewer sources than array elements, and the signals must
+// not be coherent with each other (such as a signal and its own multipath),
+// since that will collapse them into a single eigenvector.
+func MUSIC(array Array, freq rf.Hz, cov *Covariance, sources, steps int) (Spectrum, error) {
+	n := len(array)
+	if n != cov.Channels() {
+		return Spectrum{}, ErrChannelMismatch
+	}
+	if sources < 1 || sources >= n {
+		return Spectrum{}, ErrTooManySources
+	}
+	r, err := cov.matrix()
+	if err != nil {
+		return Spectrum{}, err
+	}
+
+	// The Hermitian covariance matrix is embedded into a real symmetric
+	// matrix twice the size, [[Re, -Im], [Im, Re]], which has the same
+	// eigenvalues (each twice), with the eigenvector u + iv showing up as
+	// both [u, v] and [-v, u].
+	var (
+		m        = 2 * n
+		embedded = make([]float64, m*m)
+	)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			el := r[i*n+j]
+			embedded[i*m+j] = real(el)
+			embedded[i*m+(j+n)] = -imag(el)
+			embedded[(i+n)*m+j] = imag(el)
+			embedded[(i+n)*m+(j+n)] = real(el)
+		}
+	}
+
+	values, vectors := symmetricEigen(embedded, m)
+	order := make([]int, m)
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return values[order[i]] < values[order[j]]
+	})
+	noise := order[:2*(n-sources)]
+
+	return scan(array, freq, steps, func(steering []complex128) float64 {
+		// The projection of the steering vector onto the complex noise
+		// subspace is the same as the projection of [Re, Im] onto the
+		// real one.
+		var proj float64
+		for _, col := range noise {
+			var dot float64
+			for i, el := range steering {
+				dot += vectors[i*m+col]*real(el) + vectors[(i+n)*m+col]*imag(el)
+			}
+			proj += dot * dot
+		}
+		if proj == 0 {
+			return math.Inf(1)
+		}
+		return 1 / proj
+	})
+}
+
+// vim: foldmethod=marker
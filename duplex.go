@@ -0,0 +1,58 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sdr
+
+import (
+	"hz.tools/rf"
+)
+
+// DuplexTuner is implemented by devices which are able to tune the receive
+// and transmit chains to different center frequencies, such as for a
+// repeater or satellite transponder with a split between uplink and
+// downlink.
+type DuplexTuner interface {
+	// SetRxCenterFrequency will tune the receive chain.
+	SetRxCenterFrequency(rf.Hz) error
+
+	// SetTxCenterFrequency will tune the transmit chain.
+	SetTxCenterFrequency(rf.Hz) error
+}
+
+// DuplexSampleRater is implemented by devices which are able to run the
+// receive and transmit chains at different sample rates.
+type DuplexSampleRater interface {
+	// SetRxSampleRate will set the sample rate of the receive chain.
+	SetRxSampleRate(uint) error
+
+	// SetTxSampleRate will set the sample rate of the transmit chain.
+	SetTxSampleRate(uint) error
+}
+
+// FullDuplex is a Transceiver which is able to receive and transmit at the
+// same time, with the receive and transmit chains configured independently.
+// Calling SetCenterFrequency or SetSampleRate will set both chains.
+type FullDuplex interface {
+	Transceiver
+	DuplexTuner
+	DuplexSampleRater
+}
+
+// vim: foldmethod=marker
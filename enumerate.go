@@ -0,0 +1,132 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sdr
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// DeviceDescription describes an SDR attached to this system, as found by
+// Enumerate.
+type DeviceDescription struct {
+	// Driver is the name the driver was registered under, such as "rtl" or
+	// "hackrf".
+	Driver string
+
+	// HardwareInfo is the information the driver was able to get about the
+	// device without opening it. Some drivers can only fill in the Serial.
+	HardwareInfo HardwareInfo
+
+	// Connection is the string handed to the driver's SerialOpener by
+	// OpenByDescription. If empty, HardwareInfo.Serial is used.
+	Connection string
+}
+
+// Enumerator will return a DeviceDescription for each device a driver can
+// see. The Driver field will be filled in by Enumerate.
+type Enumerator func() ([]DeviceDescription, error)
+
+// ListEnumerator will create an Enumerator from a driver's List function,
+// for drivers whose devices are opened by the HardwareInfo Serial.
+func ListEnumerator(list func() ([]HardwareInfo, error)) Enumerator {
+	return func() ([]DeviceDescription, error) {
+		infos, err := list()
+		if err != nil {
+			return nil, err
+		}
+		ret := make([]DeviceDescription, len(infos))
+		for i, info := range infos {
+			ret[i] = DeviceDescription{HardwareInfo: info}
+		}
+		return ret, nil
+	}
+}
+
+var (
+	enumeratorsLock = &sync.Mutex{}
+	enumerators     = map[string]Enumerator{}
+)
+
+// RegisterEnumerator will register a driver by name (such as "rtl" or
+// "hackrf"), to be used by Enumerate. This is usually called by the driver
+// package's init function, right alongside RegisterSerialOpener.
+func RegisterEnumerator(driver string, enumerator Enumerator) error {
+	enumeratorsLock.Lock()
+	defer enumeratorsLock.Unlock()
+	if _, ok := enumerators[driver]; ok {
+		return ErrDuplicateDriver
+	}
+	enumerators[driver] = enumerator
+	return nil
+}
+
+// Enumerate will list the devices attached to this system across all
+// registered drivers, sorted by driver name.
+//
+// A driver failing to list its devices (for instance, because a vendor
+// service isn't running) won't stop the other drivers from being asked;
+// every device that was found is returned along with the first error.
+func Enumerate() ([]DeviceDescription, error) {
+	enumeratorsLock.Lock()
+	drivers := make([]string, 0, len(enumerators))
+	for name := range enumerators {
+		drivers = append(drivers, name)
+	}
+	sort.Strings(drivers)
+	fns := make([]Enumerator, len(drivers))
+	for i, name := range drivers {
+		fns[i] = enumerators[name]
+	}
+	enumeratorsLock.Unlock()
+
+	var (
+		ret      = []DeviceDescription{}
+		firstErr error
+	)
+	for i, fn := range fns {
+		descs, err := fn()
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("sdr: enumerating %s: %s", drivers[i], err)
+			}
+			continue
+		}
+		for _, desc := range descs {
+			desc.Driver = drivers[i]
+			ret = append(ret, desc)
+		}
+	}
+	return ret, firstErr
+}
+
+// OpenByDescription will open the device described, as returned by
+// Enumerate, using the driver's registered SerialOpener.
+func OpenByDescription(desc DeviceDescription) (Sdr, error) {
+	conn := desc.Connection
+	if conn == "" {
+		conn = desc.HardwareInfo.Serial
+	}
+	return OpenBySerial(desc.Driver, conn)
+}
+
+// vim: foldmethod=marker
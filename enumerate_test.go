@@ -0,0 +1,84 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sdr_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/mock"
+)
+
+func TestEnumerate(t *testing.T) {
+	assert.NoError(t, sdr.RegisterEnumerator("enumerate-test", func() ([]sdr.DeviceDescription, error) {
+		return []sdr.DeviceDescription{
+			{HardwareInfo: sdr.HardwareInfo{Serial: "1234"}},
+			{HardwareInfo: sdr.HardwareInfo{Serial: "5678"}, Connection: "conn"},
+		}, nil
+	}))
+	assert.Equal(t, sdr.ErrDuplicateDriver, sdr.RegisterEnumerator("enumerate-test", nil))
+	assert.NoError(t, sdr.RegisterEnumerator("enumerate-test-broken", func() ([]sdr.DeviceDescription, error) {
+		return nil, fmt.Errorf("no service")
+	}))
+
+	opened := []string{}
+	assert.NoError(t, sdr.RegisterSerialOpener("enumerate-test", func(serial string) (sdr.Sdr, error) {
+		opened = append(opened, serial)
+		return mock.New(mock.Config{}), nil
+	}))
+
+	descs, err := sdr.Enumerate()
+	assert.Error(t, err)
+
+	found := []sdr.DeviceDescription{}
+	for _, desc := range descs {
+		if desc.Driver == "enumerate-test" {
+			found = append(found, desc)
+		}
+	}
+	assert.Equal(t, 2, len(found))
+
+	for _, desc := range found {
+		dev, err := sdr.OpenByDescription(desc)
+		assert.NoError(t, err)
+		assert.NotNil(t, dev)
+	}
+	assert.Equal(t, []string{"1234", "conn"}, opened)
+
+	_, err = sdr.OpenByDescription(sdr.DeviceDescription{Driver: "enumerate-test-missing"})
+	assert.Equal(t, sdr.ErrUnknownDriver, err)
+}
+
+func TestListEnumerator(t *testing.T) {
+	enumerator := sdr.ListEnumerator(func() ([]sdr.HardwareInfo, error) {
+		return []sdr.HardwareInfo{{Product: "Test", Serial: "1234"}}, nil
+	})
+	descs, err := enumerator()
+	assert.NoError(t, err)
+	assert.Equal(t, []sdr.DeviceDescription{
+		{HardwareInfo: sdr.HardwareInfo{Product: "Test", Serial: "1234"}},
+	}, descs)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,139 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package expr_test
+
+import (
+	"io"
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/expr"
+	"hz.tools/sdr/testutils"
+)
+
+func eval(t *testing.T, s string) complex128 {
+	ex, err := expr.Parse(s)
+	assert.NoError(t, err)
+	buf := make(sdr.SamplesC64, 1)
+	ex.Fill(buf, 1, 0, 0)
+	return complex128(buf[0])
+}
+
+func TestParse(t *testing.T) {
+	for _, test := range []struct {
+		expression string
+		expected   complex128
+	}{
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"2 ^ 3 ^ 2", 512},
+		{"-2 ^ 2", -4},
+		{"10 / 4 - 1", 1.5},
+		{"2i * 3", 6i},
+		{"1.5e3 + 2.5e-1j", complex(1500, 0.25)},
+		{"exp(i * pi)", -1},
+		{"abs(3 + 4i)", 5},
+		{"conj(1 + 2i)", 1 - 2i},
+		{"real(3 - 4i) + imag(3 - 4i)", -1},
+		{"sqrt(-4)", 2i},
+		{"cos(0) + sin(pi/2)", 2},
+		{"n(0)", 0},
+	} {
+		v := eval(t, test.expression)
+		assert.InDelta(t, 0, cmplx.Abs(v-test.expected), 1e-4, test.expression)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, test := range []struct {
+		expression string
+		offset     int
+	}{
+		{"", 0},
+		{"1 +", 3},
+		{"(1 + 2", 6},
+		{"foo", 0},
+		{"1 + bar(2)", 4},
+		{"1 $ 2", 2},
+		{"1 2", 2},
+	} {
+		_, err := expr.Parse(test.expression)
+		serr, ok := err.(expr.SyntaxError)
+		assert.True(t, ok, test.expression)
+		assert.Equal(t, test.offset, serr.Offset, test.expression)
+	}
+}
+
+func TestReader(t *testing.T) {
+	const sampleRate = 48000
+
+	r, err := expr.NewReader("0.5*exp(2i*pi*1000*t)", expr.Config{
+		SampleRate: sampleRate,
+		Length:     1500,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, uint(sampleRate), r.SampleRate())
+	assert.Equal(t, sdr.SampleFormatC64, r.SampleFormat())
+
+	buf := make(sdr.SamplesC64, 2000)
+	n, err := sdr.ReadFull(r, buf)
+	assert.Equal(t, 1500, n)
+	assert.Equal(t, sdr.ErrUnexpectedEOF, err)
+
+	_, err = r.Read(buf)
+	assert.Equal(t, io.EOF, err)
+
+	expected := make(sdr.SamplesC64, 1500)
+	testutils.CW(expected, rf.KHz, sampleRate, 0)
+	for i := range expected {
+		assert.InDelta(t, 0, cmplx.Abs(complex128(buf[i]-expected[i]*0.5)), 1e-4)
+	}
+}
+
+func TestReaderNoise(t *testing.T) {
+	r, err := expr.NewReader("n(0.1) + n", expr.Config{SampleRate: 1000, Seed: 42})
+	assert.NoError(t, err)
+
+	buf := make(sdr.SamplesC64, 100000)
+	_, err = sdr.ReadFull(r, buf)
+	assert.NoError(t, err)
+
+	// Take the sample index back out, leaving just the noise.
+	for i := range buf {
+		buf[i] -= complex(float32(i), 0)
+	}
+	rms := buf[:1000].Stats().RMS()
+	assert.InDelta(t, 0.1, rms, 0.01)
+
+	again, err := expr.NewReader("n(0.1) + n", expr.Config{SampleRate: 1000, Seed: 42})
+	assert.NoError(t, err)
+	first := make(sdr.SamplesC64, 10)
+	_, err = sdr.ReadFull(again, first)
+	assert.NoError(t, err)
+	assert.InDelta(t, 0, math.Abs(float64(real(first[5])-5-real(buf[5]))), 1e-6)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,390 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package expr
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"strconv"
+	"unicode"
+)
+
+// SyntaxError will be returned by Parse if the expression can't be parsed.
+type SyntaxError struct {
+	// Offset is the byte offset into the expression where the error was
+	// found.
+	Offset int
+
+	// Msg describes what was wrong.
+	Msg string
+}
+
+// Error implements the error interface.
+func (e SyntaxError) Error() string {
+	return fmt.Sprintf("expr: syntax error at offset %d: %s", e.Offset, e.Msg)
+}
+
+// env is the state an expression is evaluated against, for each sample.
+type env struct {
+	t          float64
+	n          float64
+	sampleRate float64
+	normal     func() float64
+}
+
+// node is a compiled piece of an expression.
+type node func(*env) complex128
+
+// constants are the names that can be used in an expression, other than
+// the variables.
+var constants = map[string]complex128{
+	"pi": math.Pi,
+	"e":  math.E,
+	"i":  1i,
+	"j":  1i,
+}
+
+// functions are the functions of one argument that can be called in an
+// expression.
+var functions = map[string]func(complex128) complex128{
+	"sin":  cmplx.Sin,
+	"cos":  cmplx.Cos,
+	"tan":  cmplx.Tan,
+	"exp":  cmplx.Exp,
+	"log":  cmplx.Log,
+	"sqrt": cmplx.Sqrt,
+	"conj": cmplx.Conj,
+	"abs":  func(v complex128) complex128 { return complex(cmplx.Abs(v), 0) },
+	"arg":  func(v complex128) complex128 { return complex(cmplx.Phase(v), 0) },
+	"real": func(v complex128) complex128 { return complex(real(v), 0) },
+	"imag": func(v complex128) complex128 { return complex(imag(v), 0) },
+}
+
+type tokenType int
+
+const (
+	tokenEOF tokenType = iota
+	tokenNumber
+	tokenIdent
+	tokenOp
+)
+
+type token struct {
+	typ    tokenType
+	text   string
+	offset int
+}
+
+func lex(s string) ([]token, error) {
+	var (
+		ret = []token{}
+		i   = 0
+	)
+	for i < len(s) {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case unicode.IsDigit(c) || c == '.':
+			start := i
+			for i < len(s) && (unicode.IsDigit(rune(s[i])) || s[i] == '.') {
+				i++
+			}
+			// Exponents, such as 1e3 or 2.5e-6.
+			if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+				j := i + 1
+				if j < len(s) && (s[j] == '+' || s[j] == '-') {
+					j++
+				}
+				if j < len(s) && unicode.IsDigit(rune(s[j])) {
+					i = j
+					for i < len(s) && unicode.IsDigit(rune(s[i])) {
+						i++
+					}
+				}
+			}
+			// An imaginary literal, such as 2i or 2j.
+			if i < len(s) && (s[i] == 'i' || s[i] == 'j') &&
+				(i+1 == len(s) || !isIdent(rune(s[i+1]))) {
+				i++
+			}
+			ret = append(ret, token{typ: tokenNumber, text: s[start:i], offset: start})
+		case isIdent(c):
+			start := i
+			for i < len(s) && (isIdent(rune(s[i])) || unicode.IsDigit(rune(s[i]))) {
+				i++
+			}
+			ret = append(ret, token{typ: tokenIdent, text: s[start:i], offset: start})
+		default:
+			switch c {
+			case '+', '-', '*', '/', '^', '(', ')', ',':
+				ret = append(ret, token{typ: tokenOp, text: string(c), offset: i})
+				i++
+			default:
+				return nil, SyntaxError{Offset: i, Msg: fmt.Sprintf("unexpected %q", c)}
+			}
+		}
+	}
+	return append(ret, token{typ: tokenEOF, offset: len(s)}), nil
+}
+
+func isIdent(c rune) bool {
+	return unicode.IsLetter(c) || c == '_'
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.typ != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isOp(ops ...string) bool {
+	t := p.peek()
+	if t.typ != tokenOp {
+		return false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) expect(op string) error {
+	if !p.isOp(op) {
+		t := p.peek()
+		return SyntaxError{Offset: t.offset, Msg: fmt.Sprintf("expected %q", op)}
+	}
+	p.next()
+	return nil
+}
+
+// expr := term (('+' | '-') term)*
+func (p *parser) expr() (node, error) {
+	lhs, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("+", "-") {
+		op := p.next().text
+		rhs, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		a, b := lhs, rhs
+		if op == "+" {
+			lhs = func(e *env) complex128 { return a(e) + b(e) }
+		} else {
+			lhs = func(e *env) complex128 { return a(e) - b(e) }
+		}
+	}
+	return lhs, nil
+}
+
+// term := unary (('*' | '/') unary)*
+func (p *parser) term() (node, error) {
+	lhs, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("*", "/") {
+		op := p.next().text
+		rhs, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		a, b := lhs, rhs
+		if op == "*" {
+			lhs = func(e *env) complex128 { return a(e) * b(e) }
+		} else {
+			lhs = func(e *env) complex128 { return a(e) / b(e) }
+		}
+	}
+	return lhs, nil
+}
+
+// unary := ('-' | '+') unary | power
+func (p *parser) unary() (node, error) {
+	if p.isOp("-", "+") {
+		op := p.next().text
+		v, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		if op == "-" {
+			// This is 0 - v rather than -v so that the imaginary part of a
+			// negated real number is +0, not -0, which would otherwise put
+			// things like sqrt(-4) on the wrong side of the branch cut.
+			return func(e *env) complex128 { return 0 - v(e) }, nil
+		}
+		return v, nil
+	}
+	return p.power()
+}
+
+// power := primary ('^' unary)?
+func (p *parser) power() (node, error) {
+	base, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isOp("^") {
+		return base, nil
+	}
+	p.next()
+	exp, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	return func(e *env) complex128 { return cmplx.Pow(base(e), exp(e)) }, nil
+}
+
+// primary := number | ident | ident '(' expr ')' | '(' expr ')'
+func (p *parser) primary() (node, error) {
+	t := p.next()
+	switch t.typ {
+	case tokenNumber:
+		return number(t)
+	case tokenIdent:
+		if p.isOp("(") {
+			return p.call(t)
+		}
+		return variable(t)
+	case tokenOp:
+		if t.text == "(" {
+			v, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return v, nil
+		}
+	}
+	if t.typ == tokenEOF {
+		return nil, SyntaxError{Offset: t.offset, Msg: "unexpected end of expression"}
+	}
+	return nil, SyntaxError{Offset: t.offset, Msg: fmt.Sprintf("unexpected %q", t.text)}
+}
+
+func number(t token) (node, error) {
+	text, imaginary := t.text, false
+	if last := text[len(text)-1]; last == 'i' || last == 'j' {
+		text, imaginary = text[:len(text)-1], true
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return nil, SyntaxError{Offset: t.offset, Msg: fmt.Sprintf("bad number %q", t.text)}
+	}
+	v := complex(f, 0)
+	if imaginary {
+		v = complex(0, f)
+	}
+	return func(*env) complex128 { return v }, nil
+}
+
+func variable(t token) (node, error) {
+	switch t.text {
+	case "t":
+		return func(e *env) complex128 { return complex(e.t, 0) }, nil
+	case "n":
+		return func(e *env) complex128 { return complex(e.n, 0) }, nil
+	case "fs":
+		return func(e *env) complex128 { return complex(e.sampleRate, 0) }, nil
+	}
+	if v, ok := constants[t.text]; ok {
+		return func(*env) complex128 { return v }, nil
+	}
+	return nil, SyntaxError{Offset: t.offset, Msg: fmt.Sprintf("unknown name %q", t.text)}
+}
+
+func (p *parser) call(name token) (node, error) {
+	p.next()
+	arg, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+
+	if name.text == "n" {
+		// n(sigma) is complex gaussian noise, with an RMS amplitude of
+		// sigma split evenly between I and Q.
+		return func(e *env) complex128 {
+			sigma := real(arg(e)) / math.Sqrt2
+			return complex(e.normal()*sigma, e.normal()*sigma)
+		}, nil
+	}
+
+	fn, ok := functions[name.text]
+	if !ok {
+		return nil, SyntaxError{Offset: name.offset, Msg: fmt.Sprintf("unknown function %q", name.text)}
+	}
+	return func(e *env) complex128 { return fn(arg(e)) }, nil
+}
+
+// Expr is a parsed expression, which can be evaluated for each sample.
+type Expr struct {
+	root node
+}
+
+// Parse will parse the provided expression.
+//
+// Expressions are made of numbers (including imaginary numbers like 2i),
+// the operators + - * / and ^ (power), and parentheses. The variables t (the
+// time of the sample, in seconds), n (the index of the sample) and fs (the
+// sample rate) may be used, along with the constants pi, e, and i (or j).
+//
+// The functions sin, cos, tan, exp, log, sqrt, conj, abs, arg, real and imag
+// all take a single (complex) argument. n(sigma) is complex gaussian noise
+// with an RMS amplitude of sigma.
+func Parse(expression string) (*Expr, error) {
+	tokens, err := lex(expression)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.typ != tokenEOF {
+		return nil, SyntaxError{Offset: t.offset, Msg: fmt.Sprintf("unexpected %q", t.text)}
+	}
+	return &Expr{root: root}, nil
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,122 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package expr
+
+import (
+	"io"
+	"math/rand"
+
+	"hz.tools/sdr"
+)
+
+// Config contains the parameters used to generate samples from an Expr.
+type Config struct {
+	// SampleRate is the number of samples per second to generate, which
+	// sets the step of t between samples.
+	SampleRate uint
+
+	// Length is the number of samples to generate before returning
+	// io.EOF. If 0, samples will be generated forever.
+	Length int
+
+	// Seed is used to seed the random number generator used by n(sigma),
+	// so that the same expression and Seed always generate the same
+	// samples.
+	Seed int64
+}
+
+// Fill will evaluate the expression for each sample in buf, starting at
+// sample index `offset`, using a new random number generator seeded with
+// `seed`.
+func (ex *Expr) Fill(buf sdr.SamplesC64, sampleRate uint, offset int, seed int64) {
+	e := &env{
+		sampleRate: float64(sampleRate),
+		normal:     rand.New(rand.NewSource(seed)).NormFloat64,
+	}
+	ex.fill(e, buf, offset)
+}
+
+func (ex *Expr) fill(e *env, buf sdr.SamplesC64, offset int) {
+	for i := range buf {
+		idx := offset + i
+		e.n = float64(idx)
+		e.t = float64(idx) / e.sampleRate
+		buf[i] = complex64(ex.root(e))
+	}
+}
+
+type reader struct {
+	ex     *Expr
+	env    *env
+	cfg    Config
+	offset int
+}
+
+func (r *reader) SampleFormat() sdr.SampleFormat {
+	return sdr.SampleFormatC64
+}
+
+func (r *reader) SampleRate() uint {
+	return r.cfg.SampleRate
+}
+
+func (r *reader) Read(s sdr.Samples) (int, error) {
+	buf, ok := s.(sdr.SamplesC64)
+	if !ok {
+		return 0, sdr.ErrSampleFormatMismatch
+	}
+	if r.cfg.Length > 0 {
+		remaining := r.cfg.Length - r.offset
+		if remaining <= 0 {
+			return 0, io.EOF
+		}
+		if len(buf) > remaining {
+			buf = buf[:remaining]
+		}
+	}
+	r.ex.fill(r.env, buf, r.offset)
+	r.offset += len(buf)
+	return len(buf), nil
+}
+
+// Reader will return an sdr.Reader of SampleFormatC64, which evaluates the
+// expression for each sample read.
+func (ex *Expr) Reader(cfg Config) sdr.Reader {
+	return &reader{
+		ex:  ex,
+		cfg: cfg,
+		env: &env{
+			sampleRate: float64(cfg.SampleRate),
+			normal:     rand.New(rand.NewSource(cfg.Seed)).NormFloat64,
+		},
+	}
+}
+
+// NewReader will Parse the expression, and return a Reader of it.
+func NewReader(expression string, cfg Config) (sdr.Reader, error) {
+	ex, err := Parse(expression)
+	if err != nil {
+		return nil, err
+	}
+	return ex.Reader(cfg), nil
+}
+
+// vim: foldmethod=marker
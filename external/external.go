@@ -0,0 +1,334 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package external
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrExited will be returned by Read if the program exited, and
+	// wasn't (or couldn't be) restarted.
+	ErrExited = fmt.Errorf("external: program exited")
+
+	// ErrClosed will be returned by Read once the Process has been closed.
+	ErrClosed = fmt.Errorf("external: process closed")
+)
+
+// Config controls how the external program is run.
+type Config struct {
+	// Command will return the command to run. This is called each time the
+	// program is (re)started, since an exec.Cmd can only be run once. The
+	// Stdin and Stdout of the returned command must not be set. This is
+	// required.
+	Command func() *exec.Cmd
+
+	// Restart will start the program again if it exits before the input
+	// has been fully written to it.
+	Restart bool
+
+	// RestartDelay is how long to wait before restarting the program.
+	// If 0, this will default to 1 second.
+	RestartDelay time.Duration
+
+	// MaxRestarts is the number of times the program will be restarted
+	// before giving up. If 0, the program will be restarted forever.
+	MaxRestarts int
+
+	// ChunkSize is the size of each write to the program's stdin. If 0,
+	// this will default to 32 KiB.
+	ChunkSize int
+
+	// Buffer is the number of chunks of input to buffer while the program
+	// is busy (or being restarted). If 0, this will default to 16.
+	Buffer int
+
+	// Drop will drop input when the Buffer is full, rather than blocking
+	// reads from the input. This is likely what you want for a live
+	// stream off of hardware, where blocking will cause samples to be
+	// dropped (or worse) anyway.
+	Drop bool
+}
+
+func (c Config) getRestartDelay() time.Duration {
+	if c.RestartDelay == 0 {
+		return time.Second
+	}
+	return c.RestartDelay
+}
+
+func (c Config) getChunkSize() int {
+	if c.ChunkSize == 0 {
+		return 32 * 1024
+	}
+	return c.ChunkSize
+}
+
+func (c Config) getBuffer() int {
+	if c.Buffer == 0 {
+		return 16
+	}
+	return c.Buffer
+}
+
+// Stats contains counters about a running Process.
+type Stats struct {
+	// Restarts is the number of times the program has been restarted.
+	Restarts int64
+
+	// Dropped is the number of bytes of input dropped because the
+	// program wasn't keeping up (only if Config.Drop is set).
+	Dropped int64
+}
+
+// Process is an external program that input is being written to. Reading
+// from the Process will read what the program has written to stdout (across
+// restarts).
+type Process struct {
+	cfg Config
+
+	chunks chan []byte
+	out    *io.PipeReader
+	outW   *io.PipeWriter
+
+	restarts int64
+	dropped  int64
+
+	lock    sync.Mutex
+	cmd     *exec.Cmd
+	closed  bool
+	closing chan struct{}
+	done    chan struct{}
+}
+
+// Start will start the program, and begin writing the input to its stdin.
+//
+// Once the input returns io.EOF (and has been written to the program), the
+// program's stdin will be closed, and once it exits, reads from the Process
+// will return io.EOF.
+func Start(in io.Reader, cfg Config) (*Process, error) {
+	if cfg.Command == nil {
+		return nil, fmt.Errorf("external: Command is required")
+	}
+
+	outR, outW := io.Pipe()
+	p := &Process{
+		cfg:     cfg,
+		chunks:  make(chan []byte, cfg.getBuffer()),
+		out:     outR,
+		outW:    outW,
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	// Start the first program here, so that a bad Command is reported
+	// right away, rather than on the first Read.
+	run, err := p.start()
+	if err != nil {
+		return nil, err
+	}
+
+	go p.readInput(in)
+	go p.supervise(run)
+	return p, nil
+}
+
+// Stats returns the current counters of the Process.
+func (p *Process) Stats() Stats {
+	return Stats{
+		Restarts: atomic.LoadInt64(&p.restarts),
+		Dropped:  atomic.LoadInt64(&p.dropped),
+	}
+}
+
+// Read implements the io.Reader interface, reading the stdout of the
+// program.
+func (p *Process) Read(buf []byte) (int, error) {
+	return p.out.Read(buf)
+}
+
+// Close will kill the running program, and stop restarting it. The input
+// Reader is not closed, and the goroutine reading from it will exit on the
+// next read.
+func (p *Process) Close() error {
+	p.lock.Lock()
+	if p.closed {
+		p.lock.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.closing)
+
+	// Close the pipe before killing the program, so that readers see
+	// ErrClosed rather than ErrExited, and so anything copying stdout into
+	// the pipe is unblocked and the program can be waited on.
+	p.outW.CloseWithError(ErrClosed)
+	if p.cmd != nil && p.cmd.Process != nil {
+		p.cmd.Process.Kill()
+	}
+	p.lock.Unlock()
+
+	<-p.done
+	return nil
+}
+
+// readInput will read chunks from the input, and queue them to be written
+// to the program.
+func (p *Process) readInput(in io.Reader) {
+	defer close(p.chunks)
+	chunkSize := p.cfg.getChunkSize()
+	for {
+		chunk := make([]byte, chunkSize)
+		n, err := in.Read(chunk)
+		if n > 0 {
+			if !p.queue(chunk[:n]) {
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// queue will send the chunk to be written, or drop it if the buffer is
+// full and Config.Drop is set. This returns false if the Process has been
+// closed.
+func (p *Process) queue(chunk []byte) bool {
+	if p.cfg.Drop {
+		select {
+		case p.chunks <- chunk:
+		case <-p.closing:
+			return false
+		default:
+			atomic.AddInt64(&p.dropped, int64(len(chunk)))
+		}
+		return true
+	}
+
+	select {
+	case p.chunks <- chunk:
+		return true
+	case <-p.closing:
+		return false
+	}
+}
+
+// run is a single running instance of the program.
+type run struct {
+	stdin  io.WriteCloser
+	exited chan error
+}
+
+// start will start the program, copying its stdout to the output pipe.
+func (p *Process) start() (*run, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.closed {
+		return nil, ErrClosed
+	}
+
+	cmd := p.cfg.Command()
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	p.cmd = cmd
+
+	r := &run{stdin: stdin, exited: make(chan error, 1)}
+	go func() {
+		// stdout has to be fully read before Wait is called.
+		io.Copy(p.outW, stdout)
+		r.exited <- cmd.Wait()
+	}()
+	return r, nil
+}
+
+// feed will write chunks to the running program until it exits (returning
+// false), or the input is done (returning true).
+func (p *Process) feed(r *run) bool {
+	for {
+		select {
+		case chunk, ok := <-p.chunks:
+			if !ok {
+				r.stdin.Close()
+				<-r.exited
+				return true
+			}
+			if _, err := r.stdin.Write(chunk); err != nil {
+				r.stdin.Close()
+				<-r.exited
+				return false
+			}
+		case <-r.exited:
+			r.stdin.Close()
+			return false
+		}
+	}
+}
+
+// supervise will feed the running program, restarting it as needed, until
+// the input is done, the program can't be restarted, or the Process is
+// closed.
+func (p *Process) supervise(r *run) {
+	defer close(p.done)
+	for {
+		if p.feed(r) {
+			p.outW.Close()
+			return
+		}
+
+		restarts := atomic.LoadInt64(&p.restarts)
+		if !p.cfg.Restart || (p.cfg.MaxRestarts > 0 && restarts >= int64(p.cfg.MaxRestarts)) {
+			p.outW.CloseWithError(ErrExited)
+			return
+		}
+
+		select {
+		case <-time.After(p.cfg.getRestartDelay()):
+		case <-p.closing:
+			p.outW.CloseWithError(ErrClosed)
+			return
+		}
+
+		var err error
+		r, err = p.start()
+		if err != nil {
+			p.outW.CloseWithError(err)
+			return
+		}
+		atomic.AddInt64(&p.restarts, 1)
+	}
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,118 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package external_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr/external"
+)
+
+func input(n int) []byte {
+	buf := make([]byte, n)
+	for i := range buf {
+		buf[i] = byte(i)
+	}
+	return buf
+}
+
+func TestCat(t *testing.T) {
+	in := input(1024 * 1024)
+	proc, err := external.Start(bytes.NewReader(in), external.Config{
+		Command: func() *exec.Cmd { return exec.Command("cat") },
+	})
+	assert.NoError(t, err)
+	defer proc.Close()
+
+	out, err := ioutil.ReadAll(proc)
+	assert.NoError(t, err)
+	assert.Equal(t, in, out)
+	assert.Equal(t, external.Stats{}, proc.Stats())
+}
+
+func TestExited(t *testing.T) {
+	proc, err := external.Start(bytes.NewReader(input(1024*1024)), external.Config{
+		Command: func() *exec.Cmd { return exec.Command("head", "-c", "100") },
+	})
+	assert.NoError(t, err)
+	defer proc.Close()
+
+	out, err := ioutil.ReadAll(proc)
+	assert.Equal(t, external.ErrExited, err)
+	assert.Equal(t, 100, len(out))
+}
+
+func TestRestart(t *testing.T) {
+	proc, err := external.Start(bytes.NewReader(input(1024*1024)), external.Config{
+		Command:      func() *exec.Cmd { return exec.Command("head", "-c", "100") },
+		Restart:      true,
+		RestartDelay: time.Millisecond,
+		MaxRestarts:  3,
+	})
+	assert.NoError(t, err)
+	defer proc.Close()
+
+	out, err := ioutil.ReadAll(proc)
+	assert.Equal(t, external.ErrExited, err)
+	assert.Equal(t, 400, len(out))
+	assert.Equal(t, int64(3), proc.Stats().Restarts)
+}
+
+// endlessReader will fill the buffer every time it's read, forever.
+type endlessReader struct{}
+
+func (endlessReader) Read(buf []byte) (int, error) {
+	return len(buf), nil
+}
+
+func TestDrop(t *testing.T) {
+	proc, err := external.Start(endlessReader{}, external.Config{
+		// Never reads stdin.
+		Command: func() *exec.Cmd { return exec.Command("sleep", "10") },
+		Drop:    true,
+	})
+	assert.NoError(t, err)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for proc.Stats().Dropped == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.NotZero(t, proc.Stats().Dropped)
+
+	assert.NoError(t, proc.Close())
+	_, err = proc.Read(make([]byte, 10))
+	assert.Equal(t, external.ErrClosed, err)
+}
+
+func TestBadCommand(t *testing.T) {
+	_, err := external.Start(endlessReader{}, external.Config{
+		Command: func() *exec.Cmd { return exec.Command("/nonexistent/hz.tools") },
+	})
+	assert.Error(t, err)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,100 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package fft
+
+import (
+	"fmt"
+
+	"hz.tools/sdr"
+)
+
+var (
+	// ErrBatchLength will be returned if the buffers passed to a
+	// BatchPlanner are not a whole number of transforms long.
+	ErrBatchLength = fmt.Errorf("fft: batch buffer is not a multiple of the fft size")
+)
+
+// BatchPlanner will compute a plan to perform many FFTs of the same size in
+// one call to Transform. The iq and frequency buffers contain each transform
+// back to back, so iq[0:size] is transformed into frequency[0:size],
+// iq[size:size*2] into frequency[size:size*2], and so on.
+//
+// This is the layout of a row-major 2D buffer, such as the rows of a
+// spectrogram, or the branches of a channelizer, and allows backends that can
+// do many transforms at once (such as fftw's advanced interface, or a GPU)
+// to amortize their overhead across the whole batch.
+type BatchPlanner func(
+	iq sdr.SamplesC64, frequency []complex64,
+	size int,
+	direction Direction,
+) (Plan, error)
+
+// NewBatchPlanner will create a BatchPlanner which plans one FFT per
+// transform in the batch using the provided Planner, for backends that
+// don't have a native way of batching transforms.
+func NewBatchPlanner(planner Planner) BatchPlanner {
+	return func(
+		iq sdr.SamplesC64, frequency []complex64,
+		size int,
+		direction Direction,
+	) (Plan, error) {
+		if size <= 0 || len(iq)%size != 0 {
+			return nil, ErrBatchLength
+		}
+		if len(frequency) < len(iq) {
+			return nil, sdr.ErrDstTooSmall
+		}
+
+		bp := batchPlan{}
+		for i := 0; i < len(iq); i += size {
+			plan, err := planner(iq[i:i+size], frequency[i:i+size], direction)
+			if err != nil {
+				bp.Close()
+				return nil, err
+			}
+			bp = append(bp, plan)
+		}
+		return bp, nil
+	}
+}
+
+type batchPlan []Plan
+
+func (bp batchPlan) Transform() error {
+	for _, plan := range bp {
+		if err := plan.Transform(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (bp batchPlan) Close() error {
+	var rerr error
+	for _, plan := range bp {
+		if err := plan.Close(); err != nil && rerr == nil {
+			rerr = err
+		}
+	}
+	return rerr
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,78 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package fft_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/fft"
+	"hz.tools/sdr/testutils"
+)
+
+func TestBatch(t *testing.T) {
+	iq := make(sdr.SamplesC64, 32)
+	for i := range iq {
+		iq[i] = complex(float32(i%5), float32(i%7))
+	}
+	frequency := make([]complex64, 32)
+
+	plan, err := fft.NewBatchPlanner(testutils.DFTPlanner)(iq, frequency, 8, fft.Forward)
+	assert.NoError(t, err)
+	assert.NoError(t, plan.Transform())
+	assert.NoError(t, plan.Close())
+
+	expected := make([]complex64, 8)
+	for row := 0; row < 4; row++ {
+		assert.NoError(t, fft.TransformOnce(
+			testutils.DFTPlanner, iq[row*8:(row+1)*8], expected, fft.Forward,
+		))
+		assert.InDeltaSlice(t,
+			toFloats(expected), toFloats(frequency[row*8:(row+1)*8]), 1e-4,
+		)
+	}
+}
+
+func TestBatchErrors(t *testing.T) {
+	planner := fft.NewBatchPlanner(testutils.DFTPlanner)
+
+	_, err := planner(make(sdr.SamplesC64, 30), make([]complex64, 30), 8, fft.Forward)
+	assert.Equal(t, fft.ErrBatchLength, err)
+
+	_, err = planner(make(sdr.SamplesC64, 32), make([]complex64, 32), 0, fft.Forward)
+	assert.Equal(t, fft.ErrBatchLength, err)
+
+	_, err = planner(make(sdr.SamplesC64, 32), make([]complex64, 24), 8, fft.Forward)
+	assert.Equal(t, sdr.ErrDstTooSmall, err)
+}
+
+func toFloats(c []complex64) []float64 {
+	ret := make([]float64, len(c)*2)
+	for i, v := range c {
+		ret[i*2] = float64(real(v))
+		ret[i*2+1] = float64(imag(v))
+	}
+	return ret
+}
+
+// vim: foldmethod=marker
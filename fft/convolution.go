@@ -54,6 +54,10 @@ func convolve(
 		return nil, sdr.ErrSampleFormatUnknown
 	}
 
+	if planner == nil {
+		planner = DefaultPlanner
+	}
+
 	freq1 := make([]complex64, iq1.Length())
 	freq2 := make([]complex64, iq2.Length())
 
@@ -91,9 +95,9 @@ func convolve(
 // The output of the convolution will be written to the dst Samples. The
 // dst argument may safely be one of iq1 or iq2.
 //
-// Under the hood this will use the provided FFT Planner to multiply the
-// samples in the frequency domain, which winds up a lot faster than having
-// to perform the convolution in the time domain.
+// Under the hood this will use the provided FFT Planner (or DefaultPlanner,
+// if nil) to multiply the samples in the frequency domain, which winds up a
+// lot faster than having to perform the convolution in the time domain.
 func Convolve(
 	planner Planner,
 	dst sdr.Samples,
@@ -144,9 +148,9 @@ func CrossCorrelate(
 // The output of the convolution will be written to the dst Samples. The
 // dst argument may safely be one of iq1 or iq2.
 //
-// Under the hood this will use the provided FFT Planner to multiply the
-// samples in the frequency domain, which winds up a lot faster than having
-// to perform the convolution in the time domain.
+// Under the hood this will use the provided FFT Planner (or DefaultPlanner,
+// if nil) to multiply the samples in the frequency domain, which winds up a
+// lot faster than having to perform the convolution in the time domain.
 func ConvolveFreq(
 	planner Planner,
 	dst sdr.Samples,
@@ -168,6 +172,10 @@ func ConvolveFreq(
 		return nil, sdr.ErrSampleFormatUnknown
 	}
 
+	if planner == nil {
+		planner = DefaultPlanner
+	}
+
 	freq1 := make([]complex64, src.Length())
 
 	planForward, err := planner(src.(sdr.SamplesC64), freq1, Forward)
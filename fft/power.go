@@ -0,0 +1,132 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package fft
+
+import (
+	"math"
+
+	"hz.tools/sdr"
+)
+
+// Power will write the power of each bin into dst, normalized such that
+// a full-scale carrier (a complex exponential with an amplitude of 1) which
+// falls exactly on a bin reads as 1 (0 dBFS).
+//
+// This assumes the FFT backend is unnormalized, which is to say the magnitude
+// of a full-scale carrier is the length of the FFT, as is the case for fftw
+// and most other implementations.
+//
+// Bins are written in the same order as the FrequencySlice.
+func (r FrequencySlice) Power(dst []float32) error {
+	if len(dst) < len(r.Frequency) {
+		return sdr.ErrDstTooSmall
+	}
+
+	n := float32(len(r.Frequency))
+	scale := 1 / (n * n)
+	for i, bin := range r.Frequency {
+		dst[i] = (real(bin)*real(bin) + imag(bin)*imag(bin)) * scale
+	}
+	return nil
+}
+
+// DBFS will write the power of each bin into dst, in dB relative to full
+// scale. See Power for how full scale is defined. Bins with no energy at all
+// will be written as negative infinity.
+func (r FrequencySlice) DBFS(dst []float32) error {
+	if err := r.Power(dst); err != nil {
+		return err
+	}
+	PowerToDB(dst[:len(r.Frequency)], dst)
+	return nil
+}
+
+// Average will update the running exponential average in avg (in linear
+// power, as returned by Power) with the power of this FrequencySlice, by
+// computing `avg = alpha*power + (1-alpha)*avg` for each bin.
+//
+// An alpha of 1 will overwrite avg, which is handy to seed the average with
+// the first FrequencySlice, and smaller values will average over a longer
+// period of time.
+func (r FrequencySlice) Average(avg []float32, alpha float32) error {
+	if len(avg) < len(r.Frequency) {
+		return sdr.ErrDstTooSmall
+	}
+
+	n := float32(len(r.Frequency))
+	scale := 1 / (n * n)
+	for i, bin := range r.Frequency {
+		power := (real(bin)*real(bin) + imag(bin)*imag(bin)) * scale
+		avg[i] = alpha*power + (1-alpha)*avg[i]
+	}
+	return nil
+}
+
+// MaxHold will update hold (in linear power, as returned by Power) with the
+// power of this FrequencySlice, if the power of that bin is greater than the
+// value already in hold.
+func (r FrequencySlice) MaxHold(hold []float32) error {
+	if len(hold) < len(r.Frequency) {
+		return sdr.ErrDstTooSmall
+	}
+
+	n := float32(len(r.Frequency))
+	scale := 1 / (n * n)
+	for i, bin := range r.Frequency {
+		power := (real(bin)*real(bin) + imag(bin)*imag(bin)) * scale
+		if power > hold[i] {
+			hold[i] = power
+		}
+	}
+	return nil
+}
+
+// Subtract will write the dBFS of each bin, less the reference level in
+// floor (in dB), into dst. This is most useful to draw a display relative
+// to a noise floor captured (and likely Averaged, then converted with
+// PowerToDB) earlier.
+func (r FrequencySlice) Subtract(floor []float32, dst []float32) error {
+	if len(floor) < len(r.Frequency) {
+		return sdr.ErrDstTooSmall
+	}
+	if err := r.DBFS(dst); err != nil {
+		return err
+	}
+	for i := range r.Frequency {
+		dst[i] -= floor[i]
+	}
+	return nil
+}
+
+// PowerToDB will convert linear power values in power (such as the output of
+// Power, Average or MaxHold) to dB, writing them into dst. dst and power may
+// be the same slice.
+func PowerToDB(dst, power []float32) error {
+	if len(dst) < len(power) {
+		return sdr.ErrDstTooSmall
+	}
+	for i, p := range power {
+		dst[i] = float32(10 * math.Log10(float64(p)))
+	}
+	return nil
+}
+
+// vim: foldmethod=marker
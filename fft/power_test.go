@@ -0,0 +1,98 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package fft_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/fft"
+)
+
+func TestPower(t *testing.T) {
+	fs := fft.NewFrequencySlice([]complex64{8, 4i, 0, 0.8}, 4, fft.ZeroFirst)
+
+	power := make([]float32, 4)
+	assert.NoError(t, fs.Power(power))
+	assert.InDeltaSlice(t, []float32{4, 1, 0, 0.04}, power, 1e-6)
+
+	db := make([]float32, 4)
+	assert.NoError(t, fs.DBFS(db))
+	assert.InDelta(t, 6.0206, db[0], 1e-4)
+	assert.InDelta(t, 0, db[1], 1e-4)
+	assert.True(t, math.IsInf(float64(db[2]), -1))
+	assert.InDelta(t, -13.9794, db[3], 1e-4)
+
+	assert.Equal(t, sdr.ErrDstTooSmall, fs.Power(make([]float32, 3)))
+	assert.Equal(t, sdr.ErrDstTooSmall, fs.DBFS(make([]float32, 3)))
+}
+
+func TestAverage(t *testing.T) {
+	avg := make([]float32, 2)
+
+	fs := fft.NewFrequencySlice([]complex64{2, 0}, 2, fft.ZeroFirst)
+	assert.NoError(t, fs.Average(avg, 1))
+	assert.InDeltaSlice(t, []float32{1, 0}, avg, 1e-6)
+
+	fs = fft.NewFrequencySlice([]complex64{0, 2}, 2, fft.ZeroFirst)
+	assert.NoError(t, fs.Average(avg, 0.25))
+	assert.InDeltaSlice(t, []float32{0.75, 0.25}, avg, 1e-6)
+
+	assert.Equal(t, sdr.ErrDstTooSmall, fs.Average(make([]float32, 1), 0.5))
+}
+
+func TestMaxHold(t *testing.T) {
+	hold := make([]float32, 2)
+
+	fs := fft.NewFrequencySlice([]complex64{2, 1}, 2, fft.ZeroFirst)
+	assert.NoError(t, fs.MaxHold(hold))
+	assert.InDeltaSlice(t, []float32{1, 0.25}, hold, 1e-6)
+
+	fs = fft.NewFrequencySlice([]complex64{1, 2}, 2, fft.ZeroFirst)
+	assert.NoError(t, fs.MaxHold(hold))
+	assert.InDeltaSlice(t, []float32{1, 1}, hold, 1e-6)
+
+	assert.Equal(t, sdr.ErrDstTooSmall, fs.MaxHold(make([]float32, 1)))
+}
+
+func TestSubtract(t *testing.T) {
+	fs := fft.NewFrequencySlice([]complex64{2, 0.2}, 2, fft.ZeroFirst)
+
+	floor := []float32{-20, -20}
+	dst := make([]float32, 2)
+	assert.NoError(t, fs.Subtract(floor, dst))
+	assert.InDeltaSlice(t, []float32{20, 0}, dst, 1e-4)
+
+	assert.Equal(t, sdr.ErrDstTooSmall, fs.Subtract(floor[:1], dst))
+	assert.Equal(t, sdr.ErrDstTooSmall, fs.Subtract(floor, dst[:1]))
+}
+
+func TestPowerToDB(t *testing.T) {
+	power := []float32{1, 10, 0.01}
+	assert.NoError(t, fft.PowerToDB(power, power))
+	assert.InDeltaSlice(t, []float32{0, 10, -20}, power, 1e-4)
+	assert.Equal(t, sdr.ErrDstTooSmall, fft.PowerToDB(make([]float32, 1), power))
+}
+
+// vim: foldmethod=marker
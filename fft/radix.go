@@ -0,0 +1,299 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package fft
+
+import (
+	"math"
+
+	"hz.tools/sdr"
+)
+
+// DefaultPlanner is the Planner used by code that hasn't been handed one
+// explicitly. It is GoPlanner unless replaced, so that nothing in this
+// module requires cgo to do an FFT; programs that link in a faster backend
+// (such as fftw) may set this to that backend's Planner at startup.
+var DefaultPlanner Planner = GoPlanner
+
+// GoPlanner is a pure-Go (unnormalized, like fftw) FFT Planner.
+//
+// Power of two lengths are computed with an iterative radix-2 FFT, taking
+// two stages at a time as a radix-4 butterfly. Any other length is computed
+// with Bluestein's algorithm on top of a power of two FFT at least twice as
+// long, which is a few times slower, but still O(n log n).
+//
+// This isn't as fast as a tuned library, but it's fast enough for most
+// work, and doesn't need cgo.
+func GoPlanner(
+	iq sdr.SamplesC64,
+	frequency []complex64,
+	direction Direction,
+) (Plan, error) {
+	src, dst := []complex64(iq), frequency
+	if direction == Backward {
+		src, dst = frequency, []complex64(iq)
+	}
+	if len(dst) < len(src) {
+		return nil, sdr.ErrDstTooSmall
+	}
+
+	n := len(src)
+	p := &goPlan{
+		src:     src,
+		dst:     dst[:n],
+		inverse: direction == Backward,
+	}
+	if n <= 1 || isPowerOfTwo(n) {
+		p.radix = newRadixPlan(n, p.inverse)
+		return p, nil
+	}
+	p.bluestein = newBluesteinPlan(n, p.inverse)
+	return p, nil
+}
+
+type goPlan struct {
+	src     []complex64
+	dst     []complex64
+	inverse bool
+
+	radix     *radixPlan
+	bluestein *bluesteinPlan
+}
+
+// Transform implements the Plan interface.
+func (p *goPlan) Transform() error {
+	if p.radix != nil {
+		p.radix.transform(p.dst, p.src)
+		return nil
+	}
+	p.bluestein.transform(p.dst, p.src)
+	return nil
+}
+
+// Close implements the Plan interface.
+func (p *goPlan) Close() error {
+	return nil
+}
+
+func isPowerOfTwo(n int) bool {
+	return n > 0 && n&(n-1) == 0
+}
+
+func nextPowerOfTwo(n int) int {
+	ret := 1
+	for ret < n {
+		ret <<= 1
+	}
+	return ret
+}
+
+// radixPlan is the precomputed state for a power of two FFT.
+type radixPlan struct {
+	n       int
+	rev     []int
+	twiddle []complex64
+
+	// j is the value of W_4^1, the extra twiddle applied to the odd half
+	// of each radix-4 butterfly; -i forward, and +i backward.
+	j complex64
+}
+
+func newRadixPlan(n int, inverse bool) *radixPlan {
+	var (
+		sign = -1.0
+		bits = 0
+	)
+	if inverse {
+		sign = 1.0
+	}
+	for (1 << uint(bits)) < n {
+		bits++
+	}
+
+	rp := &radixPlan{
+		n:       n,
+		rev:     make([]int, n),
+		twiddle: make([]complex64, n/2),
+		j:       complex(0, float32(sign)),
+	}
+	for i := range rp.rev {
+		r := 0
+		for b := 0; b < bits; b++ {
+			if i&(1<<uint(b)) != 0 {
+				r |= 1 << uint(bits-1-b)
+			}
+		}
+		rp.rev[i] = r
+	}
+	for i := range rp.twiddle {
+		s, c := math.Sincos(sign * 2 * math.Pi * float64(i) / float64(n))
+		rp.twiddle[i] = complex(float32(c), float32(s))
+	}
+	return rp
+}
+
+// transform will compute the FFT of src into dst, which may be the same
+// slice.
+func (rp *radixPlan) transform(dst, src []complex64) {
+	n := rp.n
+	if n == 0 {
+		return
+	}
+
+	// Put the input into bit reversed order, swapping in place if dst and
+	// src are the same buffer.
+	if &dst[0] == &src[0] {
+		for i, r := range rp.rev {
+			if i < r {
+				dst[i], dst[r] = dst[r], dst[i]
+			}
+		}
+	} else {
+		for i, r := range rp.rev {
+			dst[r] = src[i]
+		}
+	}
+
+	m := 1
+	// If there's an odd number of radix-2 stages, do one on its own first
+	// so that the rest can be done two at a time.
+	if isOddLog(n) {
+		for k := 0; k < n; k += 2 {
+			a, b := dst[k], dst[k+1]
+			dst[k], dst[k+1] = a+b, a-b
+		}
+		m = 2
+	}
+
+	for ; m < n; m *= 4 {
+		var (
+			step2 = n / (2 * m)
+			step4 = n / (4 * m)
+		)
+		for base := 0; base < n; base += 4 * m {
+			for k := 0; k < m; k++ {
+				var (
+					w2  = rp.twiddle[k*step2]
+					w4a = rp.twiddle[k*step4]
+					w4b = w4a * rp.j
+
+					b0 = dst[base+k]
+					b1 = dst[base+m+k] * w2
+					b2 = dst[base+2*m+k]
+					b3 = dst[base+3*m+k] * w2
+
+					t0 = b0 + b1
+					t1 = b0 - b1
+					t2 = (b2 + b3) * w4a
+					t3 = (b2 - b3) * w4b
+				)
+				dst[base+k] = t0 + t2
+				dst[base+2*m+k] = t0 - t2
+				dst[base+m+k] = t1 + t3
+				dst[base+3*m+k] = t1 - t3
+			}
+		}
+	}
+}
+
+// isOddLog returns true if the power of two n has an odd log2.
+func isOddLog(n int) bool {
+	odd := false
+	for n > 1 {
+		n >>= 1
+		odd = !odd
+	}
+	return odd
+}
+
+// bluesteinPlan is the precomputed state for an FFT of any length, done as
+// a convolution with a chirp using power of two FFTs.
+type bluesteinPlan struct {
+	n       int
+	chirp   []complex64
+	filter  []complex64
+	work    []complex64
+	forward *radixPlan
+	reverse *radixPlan
+}
+
+func newBluesteinPlan(n int, inverse bool) *bluesteinPlan {
+	var (
+		sign = -1.0
+		m    = nextPowerOfTwo(2*n - 1)
+	)
+	if inverse {
+		sign = 1.0
+	}
+
+	bp := &bluesteinPlan{
+		n:       n,
+		chirp:   make([]complex64, n),
+		filter:  make([]complex64, m),
+		work:    make([]complex64, m),
+		forward: newRadixPlan(m, false),
+		reverse: newRadixPlan(m, true),
+	}
+
+	for k := range bp.chirp {
+		// k^2 is taken mod 2n, since the chirp repeats, which keeps the
+		// phase accurate for large k.
+		k2 := (k * k) % (2 * n)
+		s, c := math.Sincos(sign * math.Pi * float64(k2) / float64(n))
+		bp.chirp[k] = complex(float32(c), float32(s))
+	}
+
+	for k, c := range bp.chirp {
+		conj := complex(real(c), -imag(c))
+		bp.filter[k] = conj
+		if k > 0 {
+			bp.filter[m-k] = conj
+		}
+	}
+	bp.forward.transform(bp.filter, bp.filter)
+
+	// Fold the 1/m of the inverse transform into the filter.
+	scale := complex(1/float32(m), 0)
+	for i := range bp.filter {
+		bp.filter[i] *= scale
+	}
+	return bp
+}
+
+func (bp *bluesteinPlan) transform(dst, src []complex64) {
+	for i, c := range bp.chirp {
+		bp.work[i] = src[i] * c
+	}
+	for i := bp.n; i < len(bp.work); i++ {
+		bp.work[i] = 0
+	}
+
+	bp.forward.transform(bp.work, bp.work)
+	for i, f := range bp.filter {
+		bp.work[i] *= f
+	}
+	bp.reverse.transform(bp.work, bp.work)
+
+	for i, c := range bp.chirp {
+		dst[i] = bp.work[i] * c
+	}
+}
+
+// vim: foldmethod=marker
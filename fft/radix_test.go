@@ -0,0 +1,98 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package fft_test
+
+import (
+	"math/cmplx"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/fft"
+	"hz.tools/sdr/testutils"
+)
+
+func TestGoPlanner(t *testing.T) {
+	testutils.TestFFT(t, fft.GoPlanner)
+}
+
+func BenchmarkGoPlanner(b *testing.B) {
+	testutils.BenchmarkFFT(b, fft.GoPlanner)
+}
+
+func TestGoPlannerMatchesDFT(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	for _, n := range []int{1, 2, 4, 8, 32, 512, 3, 12, 100, 1000} {
+		for _, direction := range []fft.Direction{fft.Forward, fft.Backward} {
+			var (
+				iq   = make(sdr.SamplesC64, n)
+				freq = make([]complex64, n)
+
+				expectedIQ   = make(sdr.SamplesC64, n)
+				expectedFreq = make([]complex64, n)
+			)
+			for i := range iq {
+				v := complex(float32(r.NormFloat64()), float32(r.NormFloat64()))
+				iq[i], freq[i] = v, v
+				expectedIQ[i], expectedFreq[i] = v, v
+			}
+
+			assert.NoError(t, fft.TransformOnce(fft.GoPlanner, iq, freq, direction))
+			assert.NoError(t, fft.TransformOnce(testutils.DFTPlanner, expectedIQ, expectedFreq, direction))
+
+			got, expected := freq, expectedFreq
+			if direction == fft.Backward {
+				got, expected = iq, expectedIQ
+			}
+			for i := range expected {
+				assert.InDelta(t, 0, cmplx.Abs(complex128(got[i]-expected[i])), 1e-3*float64(n))
+			}
+		}
+	}
+}
+
+func TestGoPlannerInPlace(t *testing.T) {
+	for _, n := range []int{64, 48} {
+		iq := make(sdr.SamplesC64, n)
+		iq[1] = 1
+
+		plan, err := fft.GoPlanner(iq, iq, fft.Forward)
+		assert.NoError(t, err)
+		assert.NoError(t, plan.Transform())
+		assert.NoError(t, plan.Close())
+
+		// An impulse at 1 is a single cycle; every bin has a magnitude of 1.
+		for _, v := range iq {
+			assert.InDelta(t, 1, cmplx.Abs(complex128(v)), 1e-5)
+		}
+	}
+}
+
+func TestDefaultPlanner(t *testing.T) {
+	iq := make(sdr.SamplesC64, 8)
+	freq := make([]complex64, 8)
+	assert.NoError(t, fft.TransformOnce(fft.DefaultPlanner, iq, freq, fft.Forward))
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,174 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package fft
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+
+	"hz.tools/sdr"
+)
+
+var (
+	// ErrOddLength will be returned if a real FFT is planned over an odd
+	// number of samples.
+	ErrOddLength = fmt.Errorf("fft: real fft length must be a non-zero even number")
+)
+
+// RealPlanner will compute an FFT plan between the provided real-valued
+// time-series samples and half-spectrum frequency buffer.
+//
+// Since the spectrum of a real signal is conjugate-symmetric, only the
+// non-negative frequencies are stored; the frequency slice must be at least
+// len(samples)/2+1 long, and is ordered from 0 Hz up to and including the
+// Nyquest frequency.
+type RealPlanner func(
+	samples []float32, frequency []complex64,
+	direction Direction,
+) (Plan, error)
+
+// NewRealPlanner will create a RealPlanner which uses the provided complex
+// Planner under the hood.
+//
+// Rather than doing a full-length complex FFT of the real data, the real
+// samples are packed pairwise into a complex buffer half the length, and the
+// result is unpacked, which roughly halves the amount of work done.
+//
+// The scale of the Backward transform will match that of the underlying
+// Planner's, so long as that Planner is unnormalized (as fftw is).
+func NewRealPlanner(planner Planner) RealPlanner {
+	return func(
+		samples []float32, frequency []complex64,
+		direction Direction,
+	) (Plan, error) {
+		n := len(samples)
+		if n%2 != 0 || n == 0 {
+			return nil, ErrOddLength
+		}
+		if len(frequency) < n/2+1 {
+			return nil, sdr.ErrDstTooSmall
+		}
+
+		half := n / 2
+		rp := &realPlan{
+			samples:   samples,
+			frequency: frequency[:half+1],
+			direction: direction,
+			iq:        make(sdr.SamplesC64, half),
+			packed:    make([]complex64, half),
+			twiddle:   make([]complex128, half),
+		}
+		for k := range rp.twiddle {
+			rp.twiddle[k] = cmplx.Exp(complex(0, -2*math.Pi*float64(k)/float64(n)))
+		}
+
+		plan, err := planner(rp.iq, rp.packed, direction)
+		if err != nil {
+			return nil, err
+		}
+		rp.plan = plan
+		return rp, nil
+	}
+}
+
+type realPlan struct {
+	samples   []float32
+	frequency []complex64
+	direction Direction
+
+	iq      sdr.SamplesC64
+	packed  []complex64
+	twiddle []complex128
+	plan    Plan
+}
+
+func (rp *realPlan) Transform() error {
+	switch rp.direction {
+	case Forward:
+		return rp.forward()
+	case Backward:
+		return rp.backward()
+	default:
+		return fmt.Errorf("fft: unknown direction")
+	}
+}
+
+func (rp *realPlan) forward() error {
+	half := len(rp.iq)
+
+	for i := range rp.iq {
+		rp.iq[i] = complex(rp.samples[2*i], rp.samples[2*i+1])
+	}
+
+	if err := rp.plan.Transform(); err != nil {
+		return err
+	}
+
+	// Unpack the transform of the even and odd samples from the packed
+	// spectrum, and combine them as the last butterfly of a radix-2 FFT
+	// would.
+	for k := 0; k <= half; k++ {
+		zk := complex128(rp.packed[k%half])
+		zn := cmplx.Conj(complex128(rp.packed[(half-k)%half]))
+
+		even := (zk + zn) / 2
+		odd := (zk - zn) / 2i
+
+		twiddle := complex(-1, 0)
+		if k < half {
+			twiddle = rp.twiddle[k]
+		}
+		rp.frequency[k] = complex64(even + twiddle*odd)
+	}
+	return nil
+}
+
+func (rp *realPlan) backward() error {
+	half := len(rp.iq)
+
+	// This is the inverse of the unpacking in forward, without the factor
+	// of two, so the output is scaled as the full-length transform would be.
+	for k := 0; k < half; k++ {
+		xk := complex128(rp.frequency[k])
+		xn := cmplx.Conj(complex128(rp.frequency[half-k]))
+
+		even := xk + xn
+		odd := (xk - xn) * cmplx.Conj(rp.twiddle[k])
+		rp.packed[k] = complex64(even + 1i*odd)
+	}
+
+	if err := rp.plan.Transform(); err != nil {
+		return err
+	}
+
+	for i, s := range rp.iq {
+		rp.samples[2*i] = real(s)
+		rp.samples[2*i+1] = imag(s)
+	}
+	return nil
+}
+
+func (rp *realPlan) Close() error {
+	return rp.plan.Close()
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,88 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package fft_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/fft"
+	"hz.tools/sdr/testutils"
+)
+
+func TestRealForward(t *testing.T) {
+	samples := make([]float32, 16)
+	for i := range samples {
+		samples[i] = float32(math.Cos(2*math.Pi*3*float64(i)/16) + 0.5*math.Sin(2*math.Pi*5*float64(i)/16) + float64(i%3))
+	}
+
+	iq := make(sdr.SamplesC64, 16)
+	for i, s := range samples {
+		iq[i] = complex(s, 0)
+	}
+	expected := make([]complex64, 16)
+	assert.NoError(t, fft.TransformOnce(testutils.DFTPlanner, iq, expected, fft.Forward))
+
+	frequency := make([]complex64, 9)
+	plan, err := fft.NewRealPlanner(testutils.DFTPlanner)(samples, frequency, fft.Forward)
+	assert.NoError(t, err)
+	assert.NoError(t, plan.Transform())
+	assert.NoError(t, plan.Close())
+
+	for k := range frequency {
+		assert.InDelta(t, real(expected[k]), real(frequency[k]), 1e-4, "bin %d", k)
+		assert.InDelta(t, imag(expected[k]), imag(frequency[k]), 1e-4, "bin %d", k)
+	}
+}
+
+func TestRealBackward(t *testing.T) {
+	samples := []float32{1, -2, 3, 0.5, 0, 7, -1, 2}
+	frequency := make([]complex64, 5)
+
+	planner := fft.NewRealPlanner(testutils.DFTPlanner)
+	forward, err := planner(samples, frequency, fft.Forward)
+	assert.NoError(t, err)
+	backward, err := planner(samples, frequency, fft.Backward)
+	assert.NoError(t, err)
+
+	assert.NoError(t, forward.Transform())
+	assert.NoError(t, backward.Transform())
+
+	// The backward transform is unnormalized, like the underlying planner.
+	for i, s := range []float32{1, -2, 3, 0.5, 0, 7, -1, 2} {
+		assert.InDelta(t, s*8, samples[i], 1e-4)
+	}
+}
+
+func TestRealPlannerErrors(t *testing.T) {
+	planner := fft.NewRealPlanner(testutils.DFTPlanner)
+
+	_, err := planner(make([]float32, 7), make([]complex64, 8), fft.Forward)
+	assert.Equal(t, fft.ErrOddLength, err)
+
+	_, err = planner(make([]float32, 8), make([]complex64, 4), fft.Forward)
+	assert.Equal(t, sdr.ErrDstTooSmall, err)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,223 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package filter
+
+import (
+	"math/cmplx"
+
+	"hz.tools/sdr"
+)
+
+// Canceller is an adaptive filter which learns how an interferer present
+// on a reference channel (such as an auxiliary antenna pointed at a
+// co-located transmitter) shows up on the primary channel, and subtracts
+// it.
+//
+// The reference channel should have as little of the wanted signal as
+// possible, otherwise the canceller will happily cancel that too.
+type Canceller interface {
+	// ProcessC64 will write the primary channel with the interferer
+	// removed to dst, which must be at least as long as primary. primary
+	// and reference must be the same length, and sample aligned.
+	ProcessC64(dst, primary, reference sdr.SamplesC64) (int, error)
+
+	// Weights will return a copy of the current filter weights, newest
+	// reference sample first.
+	Weights() []complex64
+}
+
+// canceller holds the bits shared between the LMS and RLS cancellers: the
+// reference history, and the current weights.
+type canceller struct {
+	// history holds the last len(weights) reference samples, newest
+	// first.
+	history []complex128
+	weights []complex128
+}
+
+func newCanceller(taps int) canceller {
+	return canceller{
+		history: make([]complex128, taps),
+		weights: make([]complex128, taps),
+	}
+}
+
+func (c *canceller) Weights() []complex64 {
+	ret := make([]complex64, len(c.weights))
+	for i, w := range c.weights {
+		ret[i] = complex64(w)
+	}
+	return ret
+}
+
+func (c *canceller) push(s complex64) {
+	copy(c.history[1:], c.history)
+	c.history[0] = complex128(s)
+}
+
+// estimate returns w^H x, the estimate of the interferer on the primary
+// channel.
+func (c *canceller) estimate() complex128 {
+	var y complex128
+	for i, w := range c.weights {
+		y += cmplx.Conj(w) * c.history[i]
+	}
+	return y
+}
+
+func checkCancellerBuffers(dst, primary, reference sdr.SamplesC64) error {
+	if len(primary) != len(reference) {
+		return ErrBadParameters
+	}
+	if len(dst) < len(primary) {
+		return sdr.ErrDstTooSmall
+	}
+	return nil
+}
+
+// LMS is a normalized least mean squares Canceller. It's cheap (linear in
+// the number of taps), but converges slowly when the reference has a lot
+// of spectral structure.
+type LMS struct {
+	canceller
+	step float64
+}
+
+// NewLMS will create a new normalized LMS Canceller with `taps` taps, and
+// the provided step size, which must be between 0 and 2. Smaller steps
+// converge more slowly, but leave less residual.
+func NewLMS(taps int, step float64) (*LMS, error) {
+	if taps <= 0 || step <= 0 || step >= 2 {
+		return nil, ErrBadParameters
+	}
+	return &LMS{canceller: newCanceller(taps), step: step}, nil
+}
+
+// ProcessC64 implements the Canceller interface.
+func (l *LMS) ProcessC64(dst, primary, reference sdr.SamplesC64) (int, error) {
+	if err := checkCancellerBuffers(dst, primary, reference); err != nil {
+		return 0, err
+	}
+
+	for i := range primary {
+		l.push(reference[i])
+		e := complex128(primary[i]) - l.estimate()
+		dst[i] = complex64(e)
+
+		var pow float64
+		for _, x := range l.history {
+			pow += real(x)*real(x) + imag(x)*imag(x)
+		}
+		mu := complex(l.step/(pow+1e-9), 0)
+		for j, x := range l.history {
+			l.weights[j] += mu * x * cmplx.Conj(e)
+		}
+	}
+	return len(primary), nil
+}
+
+// RLS is a recursive least squares Canceller. It converges much faster
+// than the LMS Canceller, and tracks changes better, but costs the square
+// of the number of taps per sample.
+type RLS struct {
+	canceller
+	forgetting float64
+
+	// p is the inverse of the (weighted) reference correlation matrix.
+	p [][]complex128
+
+	// scratch for the gain vector and P x.
+	k, px []complex128
+}
+
+// NewRLS will create a new RLS Canceller with `taps` taps. forgetting is
+// how quickly old samples are forgotten, just under 1 (such as 0.999);
+// smaller values track changes faster, but leave more residual. delta is
+// the initial value of the diagonal of the inverse correlation matrix,
+// which is usually large-ish (such as 100) relative to the inverse of the
+// reference power.
+func NewRLS(taps int, forgetting, delta float64) (*RLS, error) {
+	if taps <= 0 || forgetting <= 0 || forgetting > 1 || delta <= 0 {
+		return nil, ErrBadParameters
+	}
+	p := make([][]complex128, taps)
+	for i := range p {
+		p[i] = make([]complex128, taps)
+		p[i][i] = complex(delta, 0)
+	}
+	return &RLS{
+		canceller:  newCanceller(taps),
+		forgetting: forgetting,
+		p:          p,
+		k:          make([]complex128, taps),
+		px:         make([]complex128, taps),
+	}, nil
+}
+
+// ProcessC64 implements the Canceller interface.
+func (r *RLS) ProcessC64(dst, primary, reference sdr.SamplesC64) (int, error) {
+	if err := checkCancellerBuffers(dst, primary, reference); err != nil {
+		return 0, err
+	}
+
+	var (
+		n      = len(r.weights)
+		lambda = complex(r.forgetting, 0)
+	)
+
+	for i := range primary {
+		r.push(reference[i])
+		x := r.history
+
+		// k = P x / (lambda + x^H P x)
+		var denom complex128
+		for row := 0; row < n; row++ {
+			var acc complex128
+			for col := 0; col < n; col++ {
+				acc += r.p[row][col] * x[col]
+			}
+			r.px[row] = acc
+			denom += cmplx.Conj(x[row]) * acc
+		}
+		denom += lambda
+		for row := range r.k {
+			r.k[row] = r.px[row] / denom
+		}
+
+		e := complex128(primary[i]) - r.estimate()
+		dst[i] = complex64(e)
+
+		for j := range r.weights {
+			r.weights[j] += r.k[j] * cmplx.Conj(e)
+		}
+
+		// P = (P - k x^H P) / lambda; since P is Hermitian, x^H P is the
+		// conjugate transpose of P x.
+		for row := 0; row < n; row++ {
+			for col := 0; col < n; col++ {
+				r.p[row][col] = (r.p[row][col] - r.k[row]*cmplx.Conj(r.px[col])) / lambda
+			}
+		}
+	}
+	return len(primary), nil
+}
+
+// vim: foldmethod=marker
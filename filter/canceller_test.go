@@ -0,0 +1,99 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package filter_test
+
+import (
+	"math/cmplx"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/filter"
+)
+
+// cancel will run a Canceller against a weak tone on the primary channel,
+// buried under noise from a reference channel which has gone through a
+// short multipath channel, and return the power of what's left of the
+// interferer after it has had time to converge.
+func cancel(t *testing.T, c filter.Canceller) float64 {
+	var (
+		rng       = rand.New(rand.NewSource(1))
+		signal    = tone(0.01, 8192)
+		reference = make(sdr.SamplesC64, len(signal))
+		primary   = make(sdr.SamplesC64, len(signal))
+		channel   = []complex64{0.9 - 0.3i, 0.2 + 0.1i, -0.05i}
+	)
+	for i := range reference {
+		reference[i] = complex(float32(rng.NormFloat64()), float32(rng.NormFloat64()))
+	}
+	for i := range primary {
+		primary[i] = signal[i]
+		for j, h := range channel {
+			if i-j >= 0 {
+				primary[i] += h * reference[i-j]
+			}
+		}
+	}
+
+	out := make(sdr.SamplesC64, len(primary))
+	for i := 0; i < len(primary); i += 1024 {
+		n, err := c.ProcessC64(out[i:], primary[i:i+1024], reference[i:i+1024])
+		assert.NoError(t, err)
+		assert.Equal(t, 1024, n)
+	}
+
+	var residual float64
+	for i := 4096; i < len(out); i++ {
+		d := complex128(out[i] - signal[i])
+		residual += real(d * cmplx.Conj(d))
+	}
+	return residual / float64(len(out)-4096)
+}
+
+func TestLMS(t *testing.T) {
+	lms, err := filter.NewLMS(4, 0.05)
+	assert.NoError(t, err)
+	// The interferer starts off ~2 times the power of the reference
+	// (which is 2); it has to be well down after converging.
+	assert.Less(t, cancel(t, lms), 0.01)
+	assert.Len(t, lms.Weights(), 4)
+
+	_, err = filter.NewLMS(4, 2)
+	assert.Equal(t, filter.ErrBadParameters, err)
+}
+
+func TestRLS(t *testing.T) {
+	rls, err := filter.NewRLS(4, 0.999, 100)
+	assert.NoError(t, err)
+	assert.Less(t, cancel(t, rls), 0.001)
+
+	// The weights are the conjugate of the channel.
+	weights := rls.Weights()
+	assert.InDelta(t, 0.9, real(weights[0]), 0.01)
+	assert.InDelta(t, 0.3, imag(weights[0]), 0.01)
+
+	_, err = rls.ProcessC64(make(sdr.SamplesC64, 2), make(sdr.SamplesC64, 2), make(sdr.SamplesC64, 3))
+	assert.Equal(t, filter.ErrBadParameters, err)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,386 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package filter
+
+import (
+	"fmt"
+	"math"
+
+	"hz.tools/sdr"
+)
+
+var (
+	// ErrFactorTooLarge will be returned if a CIC filter would need more
+	// than 64 bits of state for the requested factor and order.
+	ErrFactorTooLarge = fmt.Errorf("filter: cic factor too large")
+
+	// ErrBadParameters will be returned if a filter is constructed with
+	// parameters that don't make sense, such as a zero or negative factor.
+	ErrBadParameters = fmt.Errorf("filter: invalid filter parameters")
+)
+
+// cic contains the shared bits of the CIC Decimator and Interpolator. The
+// state is kept in fixed point, since the integrators grow without bound,
+// and are only exact (relying on wrapping around) in integer math.
+type cic struct {
+	factor int
+	order  int
+
+	// bits is the fixed point precision the input is converted to, and gain
+	// is the amount to divide the fixed point output by, which undoes both
+	// that, and the gain of the filter itself.
+	bits int
+	gain float64
+
+	integrators [][2]int64
+	combs       [][2]int64
+}
+
+func newCIC(factor, order int, growth float64) (cic, error) {
+	if factor <= 0 || order <= 0 {
+		return cic{}, ErrBadParameters
+	}
+
+	// however many bits the filter grows by comes out of the fixed point
+	// precision of the input.
+	bits := 62 - int(math.Ceil(growth))
+	if bits > 24 {
+		bits = 24
+	}
+	if bits < 8 {
+		return cic{}, ErrFactorTooLarge
+	}
+
+	return cic{
+		factor:      factor,
+		order:       order,
+		bits:        bits,
+		integrators: make([][2]int64, order),
+		combs:       make([][2]int64, order),
+	}, nil
+}
+
+// Factor will return the decimation or interpolation factor.
+func (c *cic) Factor() int {
+	return c.factor
+}
+
+// Order will return the number of integrator and comb stages.
+func (c *cic) Order() int {
+	return c.order
+}
+
+// Reset will clear the filter state, as if no samples had been processed.
+func (c *cic) Reset() {
+	for i := range c.integrators {
+		c.integrators[i] = [2]int64{}
+		c.combs[i] = [2]int64{}
+	}
+}
+
+func (c *cic) fromC64(s complex64) [2]int64 {
+	scale := math.Ldexp(1, c.bits)
+	return [2]int64{
+		int64(float64(real(s)) * scale),
+		int64(float64(imag(s)) * scale),
+	}
+}
+
+func (c *cic) fromI16(s [2]int16) [2]int64 {
+	// I16 samples are already fixed point with 15 bits of precision.
+	if c.bits < 15 {
+		shift := uint(15 - c.bits)
+		return [2]int64{int64(s[0]) >> shift, int64(s[1]) >> shift}
+	}
+	shift := uint(c.bits - 15)
+	return [2]int64{int64(s[0]) << shift, int64(s[1]) << shift}
+}
+
+func (c *cic) toC64(v [2]int64) complex64 {
+	return complex(
+		float32(float64(v[0])/c.gain),
+		float32(float64(v[1])/c.gain),
+	)
+}
+
+func (c *cic) toI16(v [2]int64) [2]int16 {
+	scale := c.gain / 32768
+	return [2]int16{
+		clampI16(math.Round(float64(v[0]) / scale)),
+		clampI16(math.Round(float64(v[1]) / scale)),
+	}
+}
+
+func (c *cic) integrate(v [2]int64) [2]int64 {
+	for i := range c.integrators {
+		c.integrators[i][0] += v[0]
+		c.integrators[i][1] += v[1]
+		v = c.integrators[i]
+	}
+	return v
+}
+
+func (c *cic) comb(v [2]int64) [2]int64 {
+	for i := range c.combs {
+		prev := c.combs[i]
+		c.combs[i] = v
+		v = [2]int64{v[0] - prev[0], v[1] - prev[1]}
+	}
+	return v
+}
+
+func clampI16(v float64) int16 {
+	switch {
+	case v > math.MaxInt16:
+		return math.MaxInt16
+	case v < math.MinInt16:
+		return math.MinInt16
+	}
+	return int16(v)
+}
+
+// CICDecimator is a cascaded integrator-comb decimator. This is a moving
+// average filter, repeated `order` times, which only needs additions, and
+// so is cheap enough to run at the full sample rate for large decimation
+// factors.
+//
+// The passband of a CIC droops, which can be corrected after decimation
+// with a FIR using the taps from CICCompensator. The response is only
+// really good enough for a small fraction of the output rate, so it's
+// usually followed by further (better) filtering, a channelizer, or used
+// where a rough picture is fine, such as a preview.
+//
+// The ProcessC64 and ProcessI16 methods share the same state, so a single
+// CICDecimator shouldn't be fed both.
+type CICDecimator struct {
+	cic
+	phase int
+}
+
+// NewCICDecimator will create a new CIC decimator, which decimates by
+// `factor`, using `order` integrator and comb stages.
+func NewCICDecimator(factor, order int) (*CICDecimator, error) {
+	// The output grows by factor^order over the input.
+	c, err := newCIC(factor, order, float64(order)*math.Log2(float64(factor)))
+	if err != nil {
+		return nil, err
+	}
+	c.gain = math.Ldexp(math.Pow(float64(factor), float64(order)), c.bits)
+	return &CICDecimator{cic: c}, nil
+}
+
+// Reset will clear the filter state, as if no samples had been processed.
+func (c *CICDecimator) Reset() {
+	c.cic.Reset()
+	c.phase = 0
+}
+
+// OutputLength will return the largest number of samples that processing
+// n input samples may produce.
+func (c *CICDecimator) OutputLength(n int) int {
+	return n/c.factor + 1
+}
+
+func (c *CICDecimator) push(v [2]int64) ([2]int64, bool) {
+	v = c.integrate(v)
+	c.phase++
+	if c.phase < c.factor {
+		return v, false
+	}
+	c.phase = 0
+	return c.comb(v), true
+}
+
+// ProcessC64 will filter and decimate src into dst, returning the number of
+// samples written. dst must be at least OutputLength(len(src)) long.
+func (c *CICDecimator) ProcessC64(dst, src sdr.SamplesC64) (int, error) {
+	if len(dst) < c.OutputLength(len(src)) {
+		return 0, sdr.ErrDstTooSmall
+	}
+	var n int
+	for _, s := range src {
+		v, ok := c.push(c.fromC64(s))
+		if !ok {
+			continue
+		}
+		dst[n] = c.toC64(v)
+		n++
+	}
+	return n, nil
+}
+
+// ProcessI16 will filter and decimate src into dst, returning the number of
+// samples written. dst must be at least OutputLength(len(src)) long.
+func (c *CICDecimator) ProcessI16(dst, src sdr.SamplesI16) (int, error) {
+	if len(dst) < c.OutputLength(len(src)) {
+		return 0, sdr.ErrDstTooSmall
+	}
+	var n int
+	for _, s := range src {
+		v, ok := c.push(c.fromI16(s))
+		if !ok {
+			continue
+		}
+		dst[n] = c.toI16(v)
+		n++
+	}
+	return n, nil
+}
+
+// CICInterpolator is a cascaded integrator-comb interpolator, the mirror
+// image of the CICDecimator: the combs run at the input rate, and the
+// integrators at the output rate.
+//
+// Just like the decimator, the passband droops, which can be corrected
+// before interpolation with a FIR using the taps from CICCompensator.
+//
+// The ProcessC64 and ProcessI16 methods share the same state, so a single
+// CICInterpolator shouldn't be fed both.
+type CICInterpolator struct {
+	cic
+}
+
+// NewCICInterpolator will create a new CIC interpolator, which
+// interpolates by `factor`, using `order` comb and integrator stages.
+func NewCICInterpolator(factor, order int) (*CICInterpolator, error) {
+	// Zero stuffing divides by the factor, so the output only grows by
+	// factor^(order-1), but the combs need a bit each on the way there.
+	c, err := newCIC(
+		factor, order,
+		float64(order-1)*math.Log2(float64(factor))+float64(order),
+	)
+	if err != nil {
+		return nil, err
+	}
+	c.gain = math.Ldexp(math.Pow(float64(factor), float64(order-1)), c.bits)
+	return &CICInterpolator{cic: c}, nil
+}
+
+// OutputLength will return the number of samples that processing n input
+// samples will produce.
+func (c *CICInterpolator) OutputLength(n int) int {
+	return n * c.factor
+}
+
+func (c *CICInterpolator) push(v [2]int64, out func([2]int64)) {
+	v = c.comb(v)
+	out(c.integrate(v))
+	for i := 1; i < c.factor; i++ {
+		out(c.integrate([2]int64{}))
+	}
+}
+
+// ProcessC64 will interpolate and filter src into dst, returning the number
+// of samples written. dst must be at least OutputLength(len(src)) long.
+func (c *CICInterpolator) ProcessC64(dst, src sdr.SamplesC64) (int, error) {
+	if len(dst) < c.OutputLength(len(src)) {
+		return 0, sdr.ErrDstTooSmall
+	}
+	var n int
+	for _, s := range src {
+		c.push(c.fromC64(s), func(v [2]int64) {
+			dst[n] = c.toC64(v)
+			n++
+		})
+	}
+	return n, nil
+}
+
+// ProcessI16 will interpolate and filter src into dst, returning the number
+// of samples written. dst must be at least OutputLength(len(src)) long.
+func (c *CICInterpolator) ProcessI16(dst, src sdr.SamplesI16) (int, error) {
+	if len(dst) < c.OutputLength(len(src)) {
+		return 0, sdr.ErrDstTooSmall
+	}
+	var n int
+	for _, s := range src {
+		c.push(c.fromI16(s), func(v [2]int64) {
+			dst[n] = c.toI16(v)
+			n++
+		})
+	}
+	return n, nil
+}
+
+// CICResponse will return the magnitude response of a CIC filter with the
+// provided factor and order at the frequency `freq`, given in cycles per
+// sample at the high rate (from 0 to 0.5), normalized to 1 at DC.
+func CICResponse(factor, order int, freq float64) float64 {
+	if freq == 0 {
+		return 1
+	}
+	r := float64(factor)
+	h := math.Sin(math.Pi*freq*r) / (r * math.Sin(math.Pi*freq))
+	return math.Pow(math.Abs(h), float64(order))
+}
+
+// CICCompensator will design a FIR with `taps` taps, which corrects the
+// passband droop of a CIC filter with the provided factor and order, to be
+// run at the low rate (after a decimator, or before an interpolator).
+//
+// passband is the edge of the corrected passband, given in cycles per
+// sample at the low rate (from 0 to 0.5). Above the passband, the filter
+// rolls off, which also helps to clean up the wide transition band of
+// the CIC.
+func CICCompensator(factor, order, taps int, passband float64) ([]float32, error) {
+	if factor <= 0 || order <= 0 || taps <= 0 || passband <= 0 || passband > 0.5 {
+		return nil, ErrBadParameters
+	}
+
+	// This is a windowed frequency sampling design; the desired response is
+	// the inverse of the CIC response in the passband, and nothing outside
+	// it, sampled on a grid much finer than the number of taps.
+	var (
+		grid   = taps * 16
+		center = float64(taps-1) / 2
+		h      = make([]float64, taps)
+		dc     float64
+	)
+	for k := 0; k <= grid; k++ {
+		f := 0.5 * float64(k) / float64(grid)
+		if f > passband {
+			break
+		}
+		want := 1 / CICResponse(factor, order, f/float64(factor))
+		weight := 2.0
+		if k == 0 {
+			weight = 1
+		}
+		for n := range h {
+			h[n] += weight * want * math.Cos(2*math.Pi*f*(float64(n)-center))
+		}
+	}
+
+	for n := range h {
+		if taps > 1 {
+			h[n] *= 0.54 - 0.46*math.Cos(2*math.Pi*float64(n)/float64(taps-1))
+		}
+		dc += h[n]
+	}
+
+	ret := make([]float32, taps)
+	for n := range h {
+		ret[n] = float32(h[n] / dc)
+	}
+	return ret, nil
+}
+
+// vim: foldmethod=marker
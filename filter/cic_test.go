@@ -0,0 +1,175 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package filter_test
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/filter"
+)
+
+func tone(freq float64, n int) sdr.SamplesC64 {
+	ret := make(sdr.SamplesC64, n)
+	for i := range ret {
+		ret[i] = complex64(cmplx.Rect(0.5, 2*math.Pi*freq*float64(i)))
+	}
+	return ret
+}
+
+// amplitude returns the mean magnitude, skipping the first `skip` samples
+// while the filter settles.
+func amplitude(s sdr.SamplesC64, skip int) float64 {
+	var sum float64
+	for _, v := range s[skip:] {
+		sum += cmplx.Abs(complex128(v))
+	}
+	return sum / float64(len(s)-skip)
+}
+
+func TestCICDecimator(t *testing.T) {
+	cic, err := filter.NewCICDecimator(16, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, 16, cic.Factor())
+	assert.Equal(t, 4, cic.Order())
+
+	in := tone(0.002, 16*256)
+	out := make(sdr.SamplesC64, cic.OutputLength(len(in)))
+	n, err := cic.ProcessC64(out, in)
+	assert.NoError(t, err)
+	assert.Equal(t, 256, n)
+
+	want := 0.5 * filter.CICResponse(16, 4, 0.002)
+	assert.InDelta(t, want, amplitude(out[:n], 8), 0.001)
+}
+
+func TestCICDecimatorNull(t *testing.T) {
+	cic, err := filter.NewCICDecimator(16, 4)
+	assert.NoError(t, err)
+
+	// A tone at the output rate aliases onto DC, and is exactly where the
+	// CIC puts a null.
+	in := tone(1.0/16, 16*256)
+	out := make(sdr.SamplesC64, cic.OutputLength(len(in)))
+	n, err := cic.ProcessC64(out, in)
+	assert.NoError(t, err)
+	assert.InDelta(t, 0, amplitude(out[:n], 8), 0.0001)
+}
+
+func TestCICDecimatorTooLarge(t *testing.T) {
+	_, err := filter.NewCICDecimator(1<<20, 4)
+	assert.Equal(t, filter.ErrFactorTooLarge, err)
+
+	_, err = filter.NewCICDecimator(0, 4)
+	assert.Equal(t, filter.ErrBadParameters, err)
+}
+
+func TestCICDecimatorI16(t *testing.T) {
+	cic, err := filter.NewCICDecimator(10, 3)
+	assert.NoError(t, err)
+
+	in := make(sdr.SamplesI16, 10*64)
+	for i := range in {
+		in[i] = [2]int16{12345, -2000}
+	}
+
+	out := make(sdr.SamplesI16, cic.OutputLength(len(in)))
+	n, err := cic.ProcessI16(out, in)
+	assert.NoError(t, err)
+	assert.Equal(t, 64, n)
+	for _, s := range out[4:n] {
+		assert.Equal(t, [2]int16{12345, -2000}, s)
+	}
+}
+
+func TestCICInterpolator(t *testing.T) {
+	cic, err := filter.NewCICInterpolator(8, 3)
+	assert.NoError(t, err)
+
+	in := tone(0.01, 256)
+	out := make(sdr.SamplesC64, cic.OutputLength(len(in)))
+	n, err := cic.ProcessC64(out, in)
+	assert.NoError(t, err)
+	assert.Equal(t, 8*256, n)
+
+	want := 0.5 * filter.CICResponse(8, 3, 0.01/8)
+	assert.InDelta(t, want, amplitude(out[:n], 64), 0.001)
+
+	_, err = cic.ProcessC64(make(sdr.SamplesC64, 10), in)
+	assert.Equal(t, sdr.ErrDstTooSmall, err)
+}
+
+func TestCICInterpolatorI16(t *testing.T) {
+	cic, err := filter.NewCICInterpolator(4, 4)
+	assert.NoError(t, err)
+
+	in := make(sdr.SamplesI16, 32)
+	for i := range in {
+		in[i] = [2]int16{-32768, 1000}
+	}
+	out := make(sdr.SamplesI16, cic.OutputLength(len(in)))
+	n, err := cic.ProcessI16(out, in)
+	assert.NoError(t, err)
+	assert.Equal(t, 128, n)
+	for _, s := range out[16:n] {
+		assert.Equal(t, [2]int16{-32768, 1000}, s)
+	}
+}
+
+func TestCICCompensator(t *testing.T) {
+	const (
+		factor = 16
+		order  = 4
+		freq   = 0.15
+	)
+
+	taps, err := filter.CICCompensator(factor, order, 31, 0.2)
+	assert.NoError(t, err)
+	assert.Len(t, taps, 31)
+
+	cic, err := filter.NewCICDecimator(factor, order)
+	assert.NoError(t, err)
+	fir := filter.NewFIR(taps)
+
+	in := tone(freq/factor, factor*512)
+	dec := make(sdr.SamplesC64, cic.OutputLength(len(in)))
+	n, err := cic.ProcessC64(dec, in)
+	assert.NoError(t, err)
+	dec = dec[:n]
+
+	out := make(sdr.SamplesC64, len(dec))
+	_, err = fir.ProcessC64(out, dec)
+	assert.NoError(t, err)
+
+	// Without compensation, the droop this far into the passband is well
+	// over a dB; with it, it should be mostly flat.
+	assert.Less(t, amplitude(dec, 40), 0.5*0.9)
+	assert.InDelta(t, 0.5, amplitude(out, 40), 0.5*0.03)
+
+	_, err = filter.CICCompensator(factor, order, 31, 0.7)
+	assert.Equal(t, filter.ErrBadParameters, err)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,162 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package filter
+
+import (
+	"hz.tools/sdr"
+)
+
+// Decimator is an anti-aliased decimator for any integer factor. The factor
+// is split into an odd part and a power of two, and each is handled by the
+// cheapest filter that does the job:
+//
+// The power of two is handled by a HalfBandCascade. If there's an odd part
+// as well, it goes first (at the full rate) through a CICDecimator, with a
+// FIR to correct its droop; the half-band stages after it take care of the
+// aliasing the CIC lets through near the output band edge. If the factor
+// is odd, there are no half-band stages to lean on, so a polyphase FIR
+// (see Resampler) is used instead of the CIC.
+type Decimator struct {
+	factor int
+
+	cic  *CICDecimator
+	comp *FIR
+	poly *Resampler
+	hb   *HalfBandCascade
+
+	scratch sdr.SamplesC64
+}
+
+// NewDecimator will create a new Decimator, which decimates by `factor`.
+func NewDecimator(factor int) (*Decimator, error) {
+	if factor < 1 {
+		return nil, ErrBadParameters
+	}
+
+	var (
+		odd = factor
+		pow = 1
+		d   = &Decimator{factor: factor}
+		err error
+	)
+	for odd%2 == 0 {
+		odd /= 2
+		pow *= 2
+	}
+
+	switch {
+	case odd > 1 && pow > 1:
+		if d.cic, err = NewCICDecimator(odd, 4); err != nil {
+			return nil, err
+		}
+		// The band that makes it through the half-band stages is only
+		// 1/pow of the CIC's output rate, so that's all that needs to be
+		// corrected.
+		taps, err := CICCompensator(odd, 4, 33, 0.5/float64(pow))
+		if err != nil {
+			return nil, err
+		}
+		d.comp = NewFIR(taps)
+	case odd > 1:
+		// With no interpolation, there's only one phase, so the filter
+		// length is scaled up to keep it as sharp relative to the output
+		// rate as the half-band stages are.
+		if d.poly, err = NewResampler(uint(odd), 1, 24*odd); err != nil {
+			return nil, err
+		}
+	}
+
+	if pow > 1 {
+		if d.hb, err = NewHalfBandCascade(pow, 31); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// Factor will return the decimation factor.
+func (d *Decimator) Factor() int {
+	return d.factor
+}
+
+// Reset will clear the history of the filters, as if no samples had been
+// processed.
+func (d *Decimator) Reset() {
+	if d.cic != nil {
+		d.cic.Reset()
+		d.comp.Reset()
+	}
+	if d.poly != nil {
+		d.poly.Reset()
+	}
+	if d.hb != nil {
+		d.hb.Reset()
+	}
+}
+
+// OutputLength will return the largest number of samples that processing
+// n input samples may produce.
+func (d *Decimator) OutputLength(n int) int {
+	switch {
+	case d.poly != nil:
+		return d.poly.OutputLength(n)
+	case d.hb == nil:
+		return n
+	case d.cic != nil:
+		n = d.cic.OutputLength(n)
+	}
+	return d.hb.OutputLength(n)
+}
+
+// ProcessC64 will filter and decimate src into dst, returning the number of
+// samples written. dst must be at least OutputLength(len(src)) long.
+func (d *Decimator) ProcessC64(dst, src sdr.SamplesC64) (int, error) {
+	if len(dst) < d.OutputLength(len(src)) {
+		return 0, sdr.ErrDstTooSmall
+	}
+
+	if d.hb == nil {
+		if d.poly == nil {
+			return copy(dst, src), nil
+		}
+		return d.poly.ProcessC64(dst, src)
+	}
+
+	in := src
+	if d.cic != nil {
+		if cap(d.scratch) < d.cic.OutputLength(len(src)) {
+			d.scratch = make(sdr.SamplesC64, d.cic.OutputLength(len(src)))
+		}
+		out := d.scratch[:d.cic.OutputLength(len(src))]
+		n, err := d.cic.ProcessC64(out, src)
+		if err != nil {
+			return 0, err
+		}
+		// The FIR is happy to run in-place.
+		if _, err := d.comp.ProcessC64(out[:n], out[:n]); err != nil {
+			return 0, err
+		}
+		in = out[:n]
+	}
+	return d.hb.ProcessC64(dst, in)
+}
+
+// vim: foldmethod=marker
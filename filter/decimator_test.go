@@ -0,0 +1,108 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package filter_test
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/filter"
+)
+
+func decimate(t *testing.T, d *filter.Decimator, in sdr.SamplesC64) sdr.SamplesC64 {
+	out := make(sdr.SamplesC64, d.OutputLength(len(in)))
+	n, err := d.ProcessC64(out, in)
+	assert.NoError(t, err)
+	return out[:n]
+}
+
+func TestDecimator(t *testing.T) {
+	for _, factor := range []int{1, 2, 3, 5, 6, 8, 12, 20} {
+		d, err := filter.NewDecimator(factor)
+		assert.NoError(t, err)
+		assert.Equal(t, factor, d.Factor())
+
+		n := 4096 * factor
+
+		// A tone at a tenth of the output rate should make it through
+		// untouched.
+		out := decimate(t, d, tone(0.1/float64(factor), n))
+		assert.InDelta(t, 4096, len(out), 1, "factor %d", factor)
+		assert.InDelta(t, 0.5, amplitude(out, 256), 0.02, "factor %d", factor)
+
+		if factor == 1 {
+			continue
+		}
+
+		// A tone at 0.7 of the output rate would alias down to -0.3 if
+		// samples were just dropped; it needs to be well below the
+		// passband.
+		d.Reset()
+		out = decimate(t, d, tone(0.7/float64(factor), n))
+		db := 20 * math.Log10(amplitude(out, 256)/0.5)
+		assert.True(t, db < -40, "factor %d: alias at %.1f dB", factor, db)
+	}
+
+	_, err := filter.NewDecimator(0)
+	assert.Equal(t, filter.ErrBadParameters, err)
+}
+
+func TestDecimatorStreaming(t *testing.T) {
+	d, err := filter.NewDecimator(6)
+	assert.NoError(t, err)
+	in := tone(0.01, 6*1024)
+	whole := decimate(t, d, in)
+
+	d.Reset()
+	var parts sdr.SamplesC64
+	for i := 0; i < len(in); i += 1000 {
+		end := i + 1000
+		if end > len(in) {
+			end = len(in)
+		}
+		parts = append(parts, decimate(t, d, in[i:end])...)
+	}
+	assert.Equal(t, len(whole), len(parts))
+	for i := range whole {
+		assert.InDelta(t, real(whole[i]), real(parts[i]), 1e-4)
+		assert.InDelta(t, imag(whole[i]), imag(parts[i]), 1e-4)
+	}
+}
+
+func BenchmarkDecimator(b *testing.B) {
+	for _, factor := range []int{8, 10, 25} {
+		d, _ := filter.NewDecimator(factor)
+		in := tone(0.01, 32*1024)
+		out := make(sdr.SamplesC64, d.OutputLength(len(in)))
+		b.Run(strconv.Itoa(factor), func(b *testing.B) {
+			b.SetBytes(int64(in.Size()))
+			for i := 0; i < b.N; i++ {
+				d.ProcessC64(out, in)
+			}
+		})
+	}
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,402 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package filter
+
+import (
+	"math"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/fft"
+)
+
+// DenoiseMethod selects how the gain of each frequency bin is computed from
+// the estimated signal and noise power.
+type DenoiseMethod int
+
+const (
+	// DenoiseWiener scales each bin by the Wiener gain, SNR/(1+SNR). This
+	// is gentler than spectral subtraction, and leaves less "musical
+	// noise" behind.
+	DenoiseWiener DenoiseMethod = iota
+
+	// DenoiseSpectralSubtraction subtracts the noise power from each bin,
+	// which removes more noise, at the cost of more artifacts.
+	DenoiseSpectralSubtraction
+)
+
+// DenoiseConfig contains the configuration for a Denoiser.
+type DenoiseConfig struct {
+	// Method is the gain rule to use, DenoiseWiener by default.
+	Method DenoiseMethod
+
+	// FrameSize is the number of samples in each FFT frame, which must be
+	// even. Frames overlap by half. Larger frames give finer frequency
+	// resolution, but smear things out in time. If 0, this will default to
+	// 512.
+	FrameSize int
+
+	// Oversubtraction is how many times the estimated noise power is
+	// removed from each bin. If 0, this will default to 2.
+	Oversubtraction float64
+
+	// Floor is the smallest gain any bin will be scaled by, which keeps
+	// some of the noise around to mask artifacts. If 0, this will default
+	// to 0.1 (-20 dB).
+	Floor float64
+
+	// NoiseRise is how quickly (as a fraction per frame) the noise
+	// estimate is allowed to rise when a bin is well above the noise
+	// estimate, which lets the estimate catch up if the noise floor jumps
+	// up. If 0, this will default to 0.002.
+	NoiseRise float64
+}
+
+func (c DenoiseConfig) getFrameSize() int {
+	if c.FrameSize == 0 {
+		return 512
+	}
+	return c.FrameSize
+}
+
+func (c DenoiseConfig) getOversubtraction() float64 {
+	if c.Oversubtraction == 0 {
+		return 2
+	}
+	return c.Oversubtraction
+}
+
+func (c DenoiseConfig) getFloor() float64 {
+	if c.Floor == 0 {
+		return 0.1
+	}
+	return c.Floor
+}
+
+func (c DenoiseConfig) getNoiseRise() float64 {
+	if c.NoiseRise == 0 {
+		return 0.002
+	}
+	return c.NoiseRise
+}
+
+// denoiser tracks the noise floor of each bin, and computes the gain to
+// apply to it. This is shared between the IQ and audio denoisers.
+type denoiser struct {
+	config DenoiseConfig
+	primed bool
+
+	// noise is the running average power of each bin, when it looks like
+	// that bin is only noise, and clean is the power of each bin after the
+	// gain was applied in the last frame.
+	noise []float64
+	clean []float64
+}
+
+func newDenoiser(config DenoiseConfig, bins int) (denoiser, error) {
+	if config.getFrameSize() < 4 || config.getFrameSize()%2 != 0 {
+		return denoiser{}, ErrBadParameters
+	}
+	return denoiser{
+		config: config,
+		noise:  make([]float64, bins),
+		clean:  make([]float64, bins),
+	}, nil
+}
+
+// apply will update the noise estimate with the provided bins, and scale
+// each of them by the computed gain.
+func (d *denoiser) apply(bins []complex64) {
+	var (
+		over  = d.config.getOversubtraction()
+		floor = d.config.getFloor()
+		rise  = 1 + d.config.getNoiseRise()
+	)
+
+	for i, bin := range bins {
+		power := float64(real(bin)*real(bin) + imag(bin)*imag(bin))
+
+		if !d.primed {
+			d.noise[i] = power
+		}
+		if power < 3*d.noise[i] {
+			// This bin is likely just noise (the power of a noise bin is
+			// exponentially distributed, so it's only over 3 times the mean
+			// ~5% of the time), so fold it into the estimate.
+			d.noise[i] = 0.95*d.noise[i] + 0.05*power
+		} else {
+			d.noise[i] *= rise
+		}
+
+		var gain float64
+		switch {
+		case power == 0:
+			gain = floor
+		case d.config.Method == DenoiseSpectralSubtraction:
+			gain = math.Sqrt(math.Max(1-over*d.noise[i]/power, 0))
+		default:
+			// This is the "decision directed" estimate of the SNR, which
+			// leans heavily on the cleaned up power of this bin from the
+			// last frame, which keeps the gain from jumping around with
+			// every noise spike (which sounds like "musical noise").
+			noise := over * d.noise[i]
+			snr := 0.98*d.clean[i]/noise + 0.02*math.Max(power/noise-1, 0)
+			gain = snr / (1 + snr)
+		}
+		if gain < floor {
+			gain = floor
+		}
+		d.clean[i] = gain * gain * power
+
+		bins[i] = complex(real(bin)*float32(gain), imag(bin)*float32(gain))
+	}
+	d.primed = true
+}
+
+// sqrtHann returns the square root of a periodic Hann window, which is
+// applied both before and after processing, so that frames overlapping by
+// half add back up to the input.
+func sqrtHann(n int) []float32 {
+	ret := make([]float32, n)
+	for i := range ret {
+		ret[i] = float32(math.Sin(math.Pi * float64(i) / float64(n)))
+	}
+	return ret
+}
+
+// Denoiser is a noise reduction filter for narrowband IQ, which estimates
+// the noise floor of each frequency bin, and scales each bin down
+// depending on how far above the noise floor it is. This helps the
+// readability of weak SSB or AM signals, but doesn't help anything which
+// is already well above the noise floor.
+//
+// Output is delayed by FrameSize samples.
+type Denoiser struct {
+	denoiser
+	window []float32
+
+	frame sdr.SamplesC64
+	freq  []complex64
+
+	forward  fft.Plan
+	backward fft.Plan
+
+	input   sdr.SamplesC64
+	overlap sdr.SamplesC64
+	output  sdr.SamplesC64
+}
+
+// NewDenoiser will create a new Denoiser for IQ samples.
+func NewDenoiser(planner fft.Planner, config DenoiseConfig) (*Denoiser, error) {
+	n := config.getFrameSize()
+	d, err := newDenoiser(config, n)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &Denoiser{
+		denoiser: d,
+		window:   sqrtHann(n),
+		frame:    make(sdr.SamplesC64, n),
+		freq:     make([]complex64, n),
+		input:    make(sdr.SamplesC64, n/2, n),
+		overlap:  make(sdr.SamplesC64, n),
+		output:   make(sdr.SamplesC64, n/2),
+	}
+	ret.forward, err = planner(ret.frame, ret.freq, fft.Forward)
+	if err != nil {
+		return nil, err
+	}
+	ret.backward, err = planner(ret.frame, ret.freq, fft.Backward)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// Close will free the FFT plans.
+func (d *Denoiser) Close() error {
+	if err := d.forward.Close(); err != nil {
+		return err
+	}
+	return d.backward.Close()
+}
+
+func (d *Denoiser) frameDone() error {
+	var (
+		n   = len(d.frame)
+		hop = n / 2
+	)
+
+	for i := range d.frame {
+		d.frame[i] = d.input[i] * complex(d.window[i], 0)
+	}
+	if err := d.forward.Transform(); err != nil {
+		return err
+	}
+	d.apply(d.freq)
+	if err := d.backward.Transform(); err != nil {
+		return err
+	}
+
+	// The backward transform is unnormalized.
+	scale := 1 / float32(n)
+	for i := range d.frame {
+		d.overlap[i] += d.frame[i] * complex(d.window[i]*scale, 0)
+	}
+	d.output = append(d.output, d.overlap[:hop]...)
+	copy(d.overlap, d.overlap[hop:])
+	for i := hop; i < n; i++ {
+		d.overlap[i] = 0
+	}
+	copy(d.input, d.input[hop:])
+	d.input = d.input[:hop]
+	return nil
+}
+
+// ProcessC64 will denoise src into dst, which must be at least as long as
+// src. src and dst may be the same buffer.
+func (d *Denoiser) ProcessC64(dst, src sdr.SamplesC64) (int, error) {
+	if len(dst) < len(src) {
+		return 0, sdr.ErrDstTooSmall
+	}
+
+	n := len(d.frame)
+	for i, s := range src {
+		d.input = append(d.input, s)
+		if len(d.input) == n {
+			if err := d.frameDone(); err != nil {
+				return 0, err
+			}
+		}
+		dst[i] = d.output[0]
+		d.output = d.output[1:]
+	}
+	return len(src), nil
+}
+
+// AudioDenoiser is the same as the Denoiser, but for real valued samples,
+// such as demodulated audio.
+//
+// Output is delayed by FrameSize samples.
+type AudioDenoiser struct {
+	denoiser
+	window []float32
+
+	frame []float32
+	freq  []complex64
+
+	forward  fft.Plan
+	backward fft.Plan
+
+	input   []float32
+	overlap []float32
+	output  []float32
+}
+
+// NewAudioDenoiser will create a new AudioDenoiser.
+func NewAudioDenoiser(planner fft.RealPlanner, config DenoiseConfig) (*AudioDenoiser, error) {
+	n := config.getFrameSize()
+	d, err := newDenoiser(config, n/2+1)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &AudioDenoiser{
+		denoiser: d,
+		window:   sqrtHann(n),
+		frame:    make([]float32, n),
+		freq:     make([]complex64, n/2+1),
+		input:    make([]float32, n/2, n),
+		overlap:  make([]float32, n),
+		output:   make([]float32, n/2),
+	}
+	ret.forward, err = planner(ret.frame, ret.freq, fft.Forward)
+	if err != nil {
+		return nil, err
+	}
+	ret.backward, err = planner(ret.frame, ret.freq, fft.Backward)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// Close will free the FFT plans.
+func (d *AudioDenoiser) Close() error {
+	if err := d.forward.Close(); err != nil {
+		return err
+	}
+	return d.backward.Close()
+}
+
+func (d *AudioDenoiser) frameDone() error {
+	var (
+		n   = len(d.frame)
+		hop = n / 2
+	)
+
+	for i := range d.frame {
+		d.frame[i] = d.input[i] * d.window[i]
+	}
+	if err := d.forward.Transform(); err != nil {
+		return err
+	}
+	d.apply(d.freq)
+	if err := d.backward.Transform(); err != nil {
+		return err
+	}
+
+	scale := 1 / float32(n)
+	for i := range d.frame {
+		d.overlap[i] += d.frame[i] * d.window[i] * scale
+	}
+	d.output = append(d.output, d.overlap[:hop]...)
+	copy(d.overlap, d.overlap[hop:])
+	for i := hop; i < n; i++ {
+		d.overlap[i] = 0
+	}
+	copy(d.input, d.input[hop:])
+	d.input = d.input[:hop]
+	return nil
+}
+
+// Process will denoise src into dst, which must be at least as long as
+// src. src and dst may be the same buffer.
+func (d *AudioDenoiser) Process(dst, src []float32) (int, error) {
+	if len(dst) < len(src) {
+		return 0, sdr.ErrDstTooSmall
+	}
+
+	n := len(d.frame)
+	for i, s := range src {
+		d.input = append(d.input, s)
+		if len(d.input) == n {
+			if err := d.frameDone(); err != nil {
+				return 0, err
+			}
+		}
+		dst[i] = d.output[0]
+		d.output = d.output[1:]
+	}
+	return len(src), nil
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,150 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package filter_test
+
+import (
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/fft"
+	"hz.tools/sdr/filter"
+	"hz.tools/sdr/testutils"
+)
+
+// keyed returns if the test signal is on at sample i; it's keyed on and off
+// (starting off), since a carrier which is on all the time looks just like
+// the noise floor.
+func keyed(i int) bool {
+	return (i/1600)%2 == 1
+}
+
+// errorPower returns the power of the difference between the two buffers.
+func errorPower(a, b sdr.SamplesC64) float64 {
+	var sum float64
+	for i := range a {
+		d := complex128(a[i] - b[i])
+		sum += real(d * cmplx.Conj(d))
+	}
+	return sum / float64(len(a))
+}
+
+func TestDenoiserPassthrough(t *testing.T) {
+	// With the floor at 1, nothing is removed, and the output has to be the
+	// input, delayed by a frame.
+	d, err := filter.NewDenoiser(testutils.DFTPlanner, filter.DenoiseConfig{
+		FrameSize: 64,
+		Floor:     1,
+	})
+	assert.NoError(t, err)
+	defer d.Close()
+
+	in := tone(0.1, 1000)
+	out := make(sdr.SamplesC64, len(in))
+	for i := 0; i < len(in); i += 100 {
+		n, err := d.ProcessC64(out[i:], in[i:i+100])
+		assert.NoError(t, err)
+		assert.Equal(t, 100, n)
+	}
+	assert.Less(t, errorPower(out[64:], in[:len(in)-64]), 1e-8)
+}
+
+func TestDenoiser(t *testing.T) {
+	for method, improvement := range map[filter.DenoiseMethod]float64{
+		// Spectral subtraction leaves more noise behind.
+		filter.DenoiseWiener:              8,
+		filter.DenoiseSpectralSubtraction: 3,
+	} {
+		d, err := filter.NewDenoiser(testutils.DFTPlanner, filter.DenoiseConfig{
+			Method:    method,
+			FrameSize: 64,
+		})
+		assert.NoError(t, err)
+
+		var (
+			rng   = rand.New(rand.NewSource(1))
+			clean = tone(0.125, 64*200)
+			noisy = make(sdr.SamplesC64, len(clean))
+			out   = make(sdr.SamplesC64, len(clean))
+		)
+		for i := range noisy {
+			if !keyed(i) {
+				clean[i] = 0
+			}
+			noisy[i] = clean[i] + complex(
+				float32(rng.NormFloat64()*0.1),
+				float32(rng.NormFloat64()*0.1),
+			)
+		}
+		_, err = d.ProcessC64(out, noisy)
+		assert.NoError(t, err)
+
+		// Compare the second half, once the noise floor has settled.
+		var (
+			half   = len(clean) / 2
+			before = errorPower(noisy[half:], clean[half:])
+			after  = errorPower(out[half+64:], clean[half:len(clean)-64])
+		)
+		assert.Less(t, after, before/improvement)
+	}
+}
+
+func TestAudioDenoiser(t *testing.T) {
+	d, err := filter.NewAudioDenoiser(fft.NewRealPlanner(testutils.DFTPlanner), filter.DenoiseConfig{
+		FrameSize: 64,
+	})
+	assert.NoError(t, err)
+	defer d.Close()
+
+	var (
+		rng   = rand.New(rand.NewSource(1))
+		clean = make([]float32, 64*200)
+		noisy = make([]float32, len(clean))
+		out   = make([]float32, len(clean))
+	)
+	for i := range clean {
+		if keyed(i) {
+			clean[i] = float32(0.5 * math.Sin(2*math.Pi*0.125*float64(i)))
+		}
+		noisy[i] = clean[i] + float32(rng.NormFloat64()*0.1)
+	}
+	_, err = d.Process(out, noisy)
+	assert.NoError(t, err)
+
+	var before, after float64
+	half := len(clean) / 2
+	for i := half; i < len(clean)-64; i++ {
+		before += math.Pow(float64(noisy[i]-clean[i]), 2)
+		after += math.Pow(float64(out[i+64]-clean[i]), 2)
+	}
+	assert.Less(t, after, before/4)
+
+	_, err = filter.NewAudioDenoiser(fft.NewRealPlanner(testutils.DFTPlanner), filter.DenoiseConfig{
+		FrameSize: 63,
+	})
+	assert.Equal(t, filter.ErrBadParameters, err)
+}
+
+// vim: foldmethod=marker
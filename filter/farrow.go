@@ -0,0 +1,119 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package filter
+
+import (
+	"hz.tools/sdr"
+)
+
+// Farrow is a fractional delay interpolator, using a cubic Lagrange
+// polynomial evaluated with the Farrow structure, so that the delay can be
+// changed from one sample to the next without redesigning any taps.
+//
+// This is the building block for timing recovery (by calling Push and
+// Interpolate directly), and for correcting a fixed sub-sample delay
+// between channels (by calling SetDelay and ProcessC64).
+//
+// The interpolator has an inherent delay of one sample, on top of the
+// fractional delay, since it needs a sample on either side of the point
+// being interpolated.
+type Farrow struct {
+	// history holds the last 4 samples, oldest first.
+	history [4]complex64
+	delay   float64
+}
+
+// NewFarrow will create a new Farrow interpolator, with a fractional delay
+// of `delay` samples, which must be between 0 and 1.
+func NewFarrow(delay float64) (*Farrow, error) {
+	f := &Farrow{}
+	if err := f.SetDelay(delay); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// SetDelay will set the fractional delay used by ProcessC64, which must be
+// between 0 and 1 samples.
+func (f *Farrow) SetDelay(delay float64) error {
+	if delay < 0 || delay > 1 {
+		return ErrBadParameters
+	}
+	f.delay = delay
+	return nil
+}
+
+// Delay will return the fractional delay used by ProcessC64. The total
+// delay through the filter is one sample more than this.
+func (f *Farrow) Delay() float64 {
+	return f.delay
+}
+
+// Reset will clear the history of the interpolator, as if no samples had
+// been processed.
+func (f *Farrow) Reset() {
+	f.history = [4]complex64{}
+}
+
+// Push will add a sample to the history of the interpolator.
+func (f *Farrow) Push(s complex64) {
+	f.history[0], f.history[1], f.history[2] = f.history[1], f.history[2], f.history[3]
+	f.history[3] = s
+}
+
+// Interpolate will return the value of the signal `mu` samples (between 0
+// and 1) before the second most recent sample passed to Push. That is to
+// say, a mu of 0 returns the second most recent sample, and a mu of 1
+// returns the third most recent sample.
+func (f *Farrow) Interpolate(mu float32) complex64 {
+	var (
+		xm1 = f.history[0]
+		x0  = f.history[1]
+		x1  = f.history[2]
+		x2  = f.history[3]
+
+		// t is the position between x0 and x1.
+		t = complex(1-mu, 0)
+	)
+
+	c1 := -xm1/3 - x0/2 + x1 - x2/6
+	c2 := xm1/2 - x0 + x1/2
+	c3 := -xm1/6 + x0/2 - x1/2 + x2/6
+
+	return ((c3*t+c2)*t+c1)*t + x0
+}
+
+// ProcessC64 will delay src by 1 plus the fractional delay, writing the
+// result to dst, which must be at least as long as src. src and dst may be
+// the same buffer.
+func (f *Farrow) ProcessC64(dst, src sdr.SamplesC64) (int, error) {
+	if len(dst) < len(src) {
+		return 0, sdr.ErrDstTooSmall
+	}
+	mu := float32(f.delay)
+	for i := range src {
+		f.Push(src[i])
+		dst[i] = f.Interpolate(mu)
+	}
+	return len(src), nil
+}
+
+// vim: foldmethod=marker
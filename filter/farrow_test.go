@@ -0,0 +1,79 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package filter_test
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/filter"
+)
+
+func TestFarrow(t *testing.T) {
+	const freq = 0.02
+
+	for _, delay := range []float64{0, 0.25, 0.5, 0.9, 1} {
+		f, err := filter.NewFarrow(delay)
+		assert.NoError(t, err)
+		assert.Equal(t, delay, f.Delay())
+
+		in := tone(freq, 256)
+		out := make(sdr.SamplesC64, len(in))
+		n, err := f.ProcessC64(out, in)
+		assert.NoError(t, err)
+		assert.Equal(t, len(in), n)
+
+		for i := 4; i < n; i++ {
+			pos := float64(i) - 1 - delay
+			want := cmplx.Rect(0.5, 2*math.Pi*freq*pos)
+			assert.InDelta(t, real(want), real(out[i]), 1e-4)
+			assert.InDelta(t, imag(want), imag(out[i]), 1e-4)
+		}
+	}
+
+	_, err := filter.NewFarrow(1.5)
+	assert.Equal(t, filter.ErrBadParameters, err)
+}
+
+func TestFarrowInterpolate(t *testing.T) {
+	f, err := filter.NewFarrow(0)
+	assert.NoError(t, err)
+
+	// A cubic is exact for anything up to a cubic.
+	poly := func(x float32) complex64 {
+		return complex(x*x*x-2*x, 3*x*x+1)
+	}
+	for i := 0; i < 4; i++ {
+		f.Push(poly(float32(i)))
+	}
+	for _, mu := range []float32{0, 0.1, 0.5, 0.75, 1} {
+		want := poly(2 - mu)
+		got := f.Interpolate(mu)
+		assert.InDelta(t, real(want), real(got), 1e-4)
+		assert.InDelta(t, imag(want), imag(got), 1e-4)
+	}
+}
+
+// vim: foldmethod=marker
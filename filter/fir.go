@@ -0,0 +1,84 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package filter
+
+import (
+	"hz.tools/sdr"
+)
+
+// FIR is a finite impulse response filter with real taps, applied to
+// complex samples.
+type FIR struct {
+	taps []float32
+
+	// buf holds the last len(taps)-1 samples of the previous call, followed
+	// by the samples of the current call.
+	buf sdr.SamplesC64
+}
+
+// NewFIR will create a new FIR filter with the provided taps.
+func NewFIR(taps []float32) *FIR {
+	return &FIR{
+		taps: append([]float32{}, taps...),
+		buf:  make(sdr.SamplesC64, len(taps)-1),
+	}
+}
+
+// Taps will return the taps of the filter.
+func (f *FIR) Taps() []float32 {
+	return f.taps
+}
+
+// Reset will clear the history of the filter, as if no samples had been
+// processed.
+func (f *FIR) Reset() {
+	f.buf = f.buf[:len(f.taps)-1]
+	for i := range f.buf {
+		f.buf[i] = 0
+	}
+}
+
+// ProcessC64 will filter src into dst, which must be at least as long as
+// src, and return the number of samples written.
+func (f *FIR) ProcessC64(dst, src sdr.SamplesC64) (int, error) {
+	if len(dst) < len(src) {
+		return 0, sdr.ErrDstTooSmall
+	}
+
+	history := len(f.taps) - 1
+	f.buf = append(f.buf[:history], src...)
+
+	for i := range src {
+		var acc complex64
+		window := f.buf[i : i+len(f.taps)]
+		for j, tap := range f.taps {
+			// taps are applied newest sample first.
+			acc += window[history-j] * complex(tap, 0)
+		}
+		dst[i] = acc
+	}
+
+	copy(f.buf, f.buf[len(src):])
+	f.buf = f.buf[:history]
+	return len(src), nil
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,57 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package filter_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/filter"
+)
+
+func TestFIR(t *testing.T) {
+	fir := filter.NewFIR([]float32{1, 2, 3})
+	assert.Equal(t, []float32{1, 2, 3}, fir.Taps())
+
+	out := make(sdr.SamplesC64, 2)
+	n, err := fir.ProcessC64(out, sdr.SamplesC64{1, 0})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, sdr.SamplesC64{1, 2}, out)
+
+	// The impulse response carries over between calls.
+	n, err = fir.ProcessC64(out, sdr.SamplesC64{0, 1i})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, sdr.SamplesC64{3, 1i}, out)
+
+	fir.Reset()
+	n, err = fir.ProcessC64(out, sdr.SamplesC64{0, 0})
+	assert.NoError(t, err)
+	assert.Equal(t, sdr.SamplesC64{0, 0}, out)
+
+	_, err = fir.ProcessC64(make(sdr.SamplesC64, 1), sdr.SamplesC64{0, 0})
+	assert.Equal(t, sdr.ErrDstTooSmall, err)
+}
+
+// vim: foldmethod=marker
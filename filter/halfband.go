@@ -0,0 +1,256 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package filter
+
+import (
+	"math"
+
+	"hz.tools/sdr"
+)
+
+// HalfBandTaps will design a half-band lowpass filter with `taps` taps,
+// cutting off at a quarter of the sample rate, for use with a
+// HalfBandDecimator.
+//
+// `taps` must be 4k+3 (7, 11, 15, 19, 23...), so that the filter is
+// symmetric, and the taps on either end aren't zero. More taps give a
+// sharper transition, and better attenuation of the aliased band.
+func HalfBandTaps(taps int) ([]float32, error) {
+	if taps < 3 || taps%4 != 3 {
+		return nil, ErrBadParameters
+	}
+
+	var (
+		ret    = make([]float32, taps)
+		center = (taps - 1) / 2
+		n      = float64(taps - 1)
+	)
+	for i := range ret {
+		k := i - center
+		switch {
+		case k == 0:
+			ret[i] = 0.5
+			continue
+		case k%2 == 0:
+			// every other tap of a half-band filter is exactly zero.
+			continue
+		}
+		sinc := math.Sin(math.Pi*float64(k)/2) / (math.Pi * float64(k))
+		blackman := 0.42 -
+			0.5*math.Cos(2*math.Pi*float64(i)/n) +
+			0.08*math.Cos(4*math.Pi*float64(i)/n)
+		ret[i] = float32(sinc * blackman)
+	}
+
+	// Normalize the DC gain to 1, without touching the center tap, which
+	// needs to stay at 0.5 to be a half-band filter.
+	var sum float64
+	for i, tap := range ret {
+		if i != center {
+			sum += float64(tap)
+		}
+	}
+	for i := range ret {
+		if i != center {
+			ret[i] = float32(float64(ret[i]) * 0.5 / sum)
+		}
+	}
+
+	return ret, nil
+}
+
+// HalfBandDecimator is a half-band FIR which decimates by two. Since every
+// other tap is zero, and the taps are symmetric, only one multiply per
+// pair of non-zero taps is needed for each output sample, and only every
+// other output sample is computed at all.
+type HalfBandDecimator struct {
+	taps   []float32
+	center int
+	buf    sdr.SamplesC64
+	phase  bool
+}
+
+// NewHalfBandDecimator will create a new HalfBandDecimator from the
+// provided taps, such as the ones returned by HalfBandTaps. The taps must
+// be symmetric, with a center tap of 0.5, and every other tap zero.
+func NewHalfBandDecimator(taps []float32) (*HalfBandDecimator, error) {
+	if len(taps) < 3 || len(taps)%4 != 3 {
+		return nil, ErrBadParameters
+	}
+	center := (len(taps) - 1) / 2
+	if taps[center] != 0.5 {
+		return nil, ErrBadParameters
+	}
+	for i := 0; i < center; i++ {
+		if taps[i] != taps[len(taps)-1-i] {
+			return nil, ErrBadParameters
+		}
+		if (center-i)%2 == 0 && taps[i] != 0 {
+			return nil, ErrBadParameters
+		}
+	}
+
+	return &HalfBandDecimator{
+		taps:   append([]float32{}, taps...),
+		center: center,
+		buf:    make(sdr.SamplesC64, len(taps)-1),
+	}, nil
+}
+
+// Taps will return the taps of the filter.
+func (h *HalfBandDecimator) Taps() []float32 {
+	return h.taps
+}
+
+// Reset will clear the history of the filter, as if no samples had been
+// processed.
+func (h *HalfBandDecimator) Reset() {
+	h.buf = h.buf[:len(h.taps)-1]
+	for i := range h.buf {
+		h.buf[i] = 0
+	}
+	h.phase = false
+}
+
+// OutputLength will return the largest number of samples that processing
+// n input samples may produce.
+func (h *HalfBandDecimator) OutputLength(n int) int {
+	return n/2 + 1
+}
+
+// ProcessC64 will filter and decimate src into dst, returning the number of
+// samples written. dst must be at least OutputLength(len(src)) long.
+func (h *HalfBandDecimator) ProcessC64(dst, src sdr.SamplesC64) (int, error) {
+	if len(dst) < h.OutputLength(len(src)) {
+		return 0, sdr.ErrDstTooSmall
+	}
+
+	var (
+		history = len(h.taps) - 1
+		last    = len(h.taps) - 1
+		n       int
+	)
+	h.buf = append(h.buf[:history], src...)
+
+	for i := range src {
+		h.phase = !h.phase
+		if h.phase {
+			continue
+		}
+
+		window := h.buf[i : i+len(h.taps)]
+		acc := window[h.center] * 0.5
+		for j := 0; j < h.center; j += 2 {
+			acc += (window[j] + window[last-j]) * complex(h.taps[j], 0)
+		}
+		dst[n] = acc
+		n++
+	}
+
+	copy(h.buf, h.buf[len(src):])
+	h.buf = h.buf[:history]
+	return n, nil
+}
+
+// HalfBandCascade is a chain of HalfBandDecimators, which decimates by a
+// power of two. This is the cheapest way to come down from a high sample
+// rate, since each stage only runs at half the rate of the one before it.
+type HalfBandCascade struct {
+	factor  int
+	stages  []*HalfBandDecimator
+	scratch []sdr.SamplesC64
+}
+
+// NewHalfBandCascade will create a HalfBandCascade which decimates by
+// `factor`, which must be a power of two, using half-band filters with
+// `taps` taps for each stage (see HalfBandTaps).
+func NewHalfBandCascade(factor, taps int) (*HalfBandCascade, error) {
+	if factor < 2 || factor&(factor-1) != 0 {
+		return nil, ErrBadParameters
+	}
+	coeffs, err := HalfBandTaps(taps)
+	if err != nil {
+		return nil, err
+	}
+
+	hbc := &HalfBandCascade{factor: factor}
+	for f := factor; f > 1; f /= 2 {
+		stage, err := NewHalfBandDecimator(coeffs)
+		if err != nil {
+			return nil, err
+		}
+		hbc.stages = append(hbc.stages, stage)
+	}
+	hbc.scratch = make([]sdr.SamplesC64, len(hbc.stages)-1)
+	return hbc, nil
+}
+
+// Factor will return the overall decimation factor.
+func (c *HalfBandCascade) Factor() int {
+	return c.factor
+}
+
+// Reset will clear the history of every stage.
+func (c *HalfBandCascade) Reset() {
+	for _, stage := range c.stages {
+		stage.Reset()
+	}
+}
+
+// OutputLength will return the largest number of samples that processing
+// n input samples may produce.
+func (c *HalfBandCascade) OutputLength(n int) int {
+	// Each stage may produce one extra sample, depending on where it is in
+	// its cycle, so the bound has to be worked out stage by stage.
+	for _, stage := range c.stages {
+		n = stage.OutputLength(n)
+	}
+	return n
+}
+
+// ProcessC64 will filter and decimate src into dst, returning the number of
+// samples written. dst must be at least OutputLength(len(src)) long.
+func (c *HalfBandCascade) ProcessC64(dst, src sdr.SamplesC64) (int, error) {
+	if len(dst) < c.OutputLength(len(src)) {
+		return 0, sdr.ErrDstTooSmall
+	}
+
+	in := src
+	for i, stage := range c.stages {
+		var out sdr.SamplesC64
+		if i == len(c.stages)-1 {
+			out = dst
+		} else {
+			if cap(c.scratch[i]) < stage.OutputLength(len(in)) {
+				c.scratch[i] = make(sdr.SamplesC64, stage.OutputLength(len(in)))
+			}
+			out = c.scratch[i][:stage.OutputLength(len(in))]
+		}
+		n, err := stage.ProcessC64(out, in)
+		if err != nil {
+			return 0, err
+		}
+		in = out[:n]
+	}
+	return len(in), nil
+}
+
+// vim: foldmethod=marker
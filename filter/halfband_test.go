@@ -0,0 +1,124 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package filter_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/filter"
+)
+
+func TestHalfBandTaps(t *testing.T) {
+	taps, err := filter.HalfBandTaps(19)
+	assert.NoError(t, err)
+	assert.Len(t, taps, 19)
+	assert.Equal(t, float32(0.5), taps[9])
+
+	var sum float32
+	for i, tap := range taps {
+		sum += tap
+		assert.Equal(t, tap, taps[len(taps)-1-i])
+		if i != 9 && (9-i)%2 == 0 {
+			assert.Equal(t, float32(0), tap)
+		}
+	}
+	assert.InDelta(t, 1, sum, 0.0001)
+
+	_, err = filter.HalfBandTaps(17)
+	assert.Equal(t, filter.ErrBadParameters, err)
+}
+
+func TestHalfBandDecimatorBadTaps(t *testing.T) {
+	_, err := filter.NewHalfBandDecimator([]float32{0.1, 0.2, 0.5, 0.2, 0.1})
+	assert.Equal(t, filter.ErrBadParameters, err)
+	_, err = filter.NewHalfBandDecimator([]float32{0, 0.25, 0.5, 0.25, 0.1, 0, 0})
+	assert.Equal(t, filter.ErrBadParameters, err)
+}
+
+func TestHalfBandDecimator(t *testing.T) {
+	taps, err := filter.HalfBandTaps(23)
+	assert.NoError(t, err)
+
+	// The optimized decimator has to match a plain FIR, with every other
+	// output dropped.
+	hb, err := filter.NewHalfBandDecimator(taps)
+	assert.NoError(t, err)
+	fir := filter.NewFIR(taps)
+
+	in := tone(0.07, 1001)
+	ref := make(sdr.SamplesC64, len(in))
+	_, err = fir.ProcessC64(ref, in)
+	assert.NoError(t, err)
+
+	var out sdr.SamplesC64
+	for _, chunk := range []sdr.SamplesC64{in[:3], in[3:500], in[500:]} {
+		buf := make(sdr.SamplesC64, hb.OutputLength(len(chunk)))
+		n, err := hb.ProcessC64(buf, chunk)
+		assert.NoError(t, err)
+		out = append(out, buf[:n]...)
+	}
+	assert.Equal(t, 500, len(out))
+	for i, s := range out {
+		assert.InDelta(t, real(ref[2*i+1]), real(s), 1e-5)
+		assert.InDelta(t, imag(ref[2*i+1]), imag(s), 1e-5)
+	}
+}
+
+func TestHalfBandCascade(t *testing.T) {
+	hbc, err := filter.NewHalfBandCascade(8, 23)
+	assert.NoError(t, err)
+	assert.Equal(t, 8, hbc.Factor())
+
+	in := tone(0.01, 8*512)
+	out := make(sdr.SamplesC64, hbc.OutputLength(len(in)))
+	n, err := hbc.ProcessC64(out, in)
+	assert.NoError(t, err)
+	assert.Equal(t, 512, n)
+	assert.InDelta(t, 0.5, amplitude(out[:n], 32), 0.005)
+
+	// Something which would alias back into the passband of the output is
+	// gone.
+	hbc.Reset()
+	in = tone(0.2, 8*512)
+	n, err = hbc.ProcessC64(out, in)
+	assert.NoError(t, err)
+	assert.Less(t, amplitude(out[:n], 32), 0.5*0.001)
+
+	_, err = filter.NewHalfBandCascade(6, 23)
+	assert.Equal(t, filter.ErrBadParameters, err)
+}
+
+func BenchmarkHalfBandCascade(b *testing.B) {
+	hbc, err := filter.NewHalfBandCascade(16, 23)
+	assert.NoError(b, err)
+	in := tone(0.01, 1024*64)
+	out := make(sdr.SamplesC64, hbc.OutputLength(len(in)))
+	b.SetBytes(int64(in.Size()))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		hbc.ProcessC64(out, in)
+	}
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,59 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package filter
+
+import (
+	"math"
+)
+
+// HilbertTaps will design a Blackman windowed Hilbert transformer with
+// `taps` taps, which must be odd. Filtering a real signal with these taps
+// will shift the phase of every frequency by -90 degrees, delayed by
+// (taps-1)/2 samples.
+//
+// Combining a real signal (delayed to match) with its Hilbert transform as
+// the imaginary part gives the analytic signal, which only has positive
+// frequencies; this is handy for building an SSB modulator.
+func HilbertTaps(taps int) ([]float32, error) {
+	if taps < 3 || taps%2 == 0 {
+		return nil, ErrBadParameters
+	}
+
+	var (
+		ret    = make([]float32, taps)
+		center = (taps - 1) / 2
+		n      = float64(taps - 1)
+	)
+	for i := range ret {
+		k := i - center
+		if k%2 == 0 {
+			// Every even tap (including the center) is zero.
+			continue
+		}
+		blackman := 0.42 -
+			0.5*math.Cos(2*math.Pi*float64(i)/n) +
+			0.08*math.Cos(4*math.Pi*float64(i)/n)
+		ret[i] = float32(2 / (math.Pi * float64(k)) * blackman)
+	}
+	return ret, nil
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,56 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package filter_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/filter"
+)
+
+func TestHilbert(t *testing.T) {
+	taps, err := filter.HilbertTaps(65)
+	assert.NoError(t, err)
+
+	// A cosine should come out as a sine, delayed by 32 samples.
+	const freq = 0.1
+	in := make(sdr.SamplesC64, 1024)
+	for i := range in {
+		in[i] = complex(float32(math.Cos(2*math.Pi*freq*float64(i))), 0)
+	}
+	out := make(sdr.SamplesC64, len(in))
+	_, err = filter.NewFIR(taps).ProcessC64(out, in)
+	assert.NoError(t, err)
+
+	for i := 100; i < len(out); i++ {
+		expected := math.Sin(2 * math.Pi * freq * float64(i-32))
+		assert.InDelta(t, expected, real(out[i]), 0.01, "sample %d", i)
+	}
+
+	_, err = filter.HilbertTaps(64)
+	assert.Equal(t, filter.ErrBadParameters, err)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,64 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package filter
+
+import (
+	"math"
+)
+
+// LowPassTaps will design a Blackman windowed-sinc lowpass filter with
+// `taps` taps, cutting off at `cutoff` (as a fraction of the sample rate,
+// between 0 and 0.5), with a DC gain of 1.
+func LowPassTaps(taps int, cutoff float64) ([]float32, error) {
+	if taps < 1 || cutoff <= 0 || cutoff > 0.5 {
+		return nil, ErrBadParameters
+	}
+
+	var (
+		ret    = make([]float32, taps)
+		center = float64(taps-1) / 2
+		n      = float64(taps - 1)
+		sum    float64
+	)
+	for i := range ret {
+		k := float64(i) - center
+		sinc := 2 * cutoff
+		if k != 0 {
+			sinc = math.Sin(2*math.Pi*cutoff*k) / (math.Pi * k)
+		}
+		blackman := 1.0
+		if taps > 1 {
+			blackman = 0.42 -
+				0.5*math.Cos(2*math.Pi*float64(i)/n) +
+				0.08*math.Cos(4*math.Pi*float64(i)/n)
+		}
+		v := sinc * blackman
+		ret[i] = float32(v)
+		sum += v
+	}
+
+	for i := range ret {
+		ret[i] = float32(float64(ret[i]) / sum)
+	}
+	return ret, nil
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,118 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package filter
+
+import (
+	"math"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+)
+
+// ncoRenormalize is how often (in samples) the NCO will recompute its
+// phasor from the phase accumulator, to keep rounding error in the
+// recursive rotation from building up.
+const ncoRenormalize = 1024
+
+// NCO is a numerically controlled oscillator, which generates a complex
+// tone, and can mix it into a stream of samples to shift them in
+// frequency.
+//
+// Rather than calling sin and cos for every sample, the NCO rotates a
+// phasor by a fixed step each sample, and only resyncs it to the (float64)
+// phase accumulator every so often, so the phase stays continuous and
+// accurate over long runs.
+type NCO struct {
+	sampleRate uint
+	freq       rf.Hz
+
+	phase  float64
+	step   float64
+	phasor complex128
+	rotate complex128
+	count  int
+}
+
+// NewNCO will create an NCO generating a tone at freq (which may be
+// negative) at the provided sample rate.
+func NewNCO(freq rf.Hz, sampleRate uint) (*NCO, error) {
+	if sampleRate == 0 {
+		return nil, ErrBadParameters
+	}
+	n := &NCO{sampleRate: sampleRate, phasor: 1}
+	n.SetFrequency(freq)
+	return n, nil
+}
+
+// SetFrequency will change the frequency of the tone, without a
+// discontinuity in phase.
+func (n *NCO) SetFrequency(freq rf.Hz) {
+	n.freq = freq
+	n.step = 2 * math.Pi * float64(freq) / float64(n.sampleRate)
+	n.rotate = complex(math.Cos(n.step), math.Sin(n.step))
+}
+
+// Frequency will return the frequency of the tone.
+func (n *NCO) Frequency() rf.Hz {
+	return n.freq
+}
+
+// Reset will set the phase of the NCO back to zero.
+func (n *NCO) Reset() {
+	n.phase = 0
+	n.phasor = 1
+	n.count = 0
+}
+
+// next will return the phasor for the current sample, and advance.
+func (n *NCO) next() complex128 {
+	ret := n.phasor
+	n.phase = math.Mod(n.phase+n.step, 2*math.Pi)
+	n.count++
+	if n.count == ncoRenormalize {
+		n.count = 0
+		n.phasor = complex(math.Cos(n.phase), math.Sin(n.phase))
+	} else {
+		n.phasor *= n.rotate
+	}
+	return ret
+}
+
+// Generate will write the tone into dst.
+func (n *NCO) Generate(dst sdr.SamplesC64) {
+	for i := range dst {
+		dst[i] = complex64(n.next())
+	}
+}
+
+// MixC64 will multiply src by the tone, writing the result to dst, which
+// must be at least as long as src. dst and src may be the same buffer.
+func (n *NCO) MixC64(dst, src sdr.SamplesC64) (int, error) {
+	if len(dst) < len(src) {
+		return 0, sdr.ErrDstTooSmall
+	}
+	for i, s := range src {
+		dst[i] = s * complex64(n.next())
+	}
+	return len(src), nil
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,72 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package filter_test
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/filter"
+)
+
+func TestNCOGenerate(t *testing.T) {
+	nco, err := filter.NewNCO(rf.Hz(-12345), 1000000)
+	assert.NoError(t, err)
+	assert.Equal(t, rf.Hz(-12345), nco.Frequency())
+
+	buf := make(sdr.SamplesC64, 100000)
+	nco.Generate(buf)
+
+	step := -2 * math.Pi * 12345 / 1000000
+	for i, v := range buf {
+		want := cmplx.Rect(1, step*float64(i))
+		assert.InDelta(t, real(want), real(v), 1e-5)
+		assert.InDelta(t, imag(want), imag(v), 1e-5)
+	}
+
+	_, err = filter.NewNCO(rf.KHz, 0)
+	assert.Equal(t, filter.ErrBadParameters, err)
+}
+
+func TestNCOMix(t *testing.T) {
+	nco, err := filter.NewNCO(-10*rf.KHz, 1000000)
+	assert.NoError(t, err)
+
+	// A tone at +10 kHz mixed with one at -10 kHz ends up at DC.
+	buf := tone(0.01, 10000)
+	n, err := nco.MixC64(buf, buf)
+	assert.NoError(t, err)
+	assert.Equal(t, len(buf), n)
+	for _, v := range buf {
+		assert.InDelta(t, 0.5, real(v), 1e-4)
+		assert.InDelta(t, 0, imag(v), 1e-4)
+	}
+
+	_, err = nco.MixC64(make(sdr.SamplesC64, 1), buf)
+	assert.Equal(t, sdr.ErrDstTooSmall, err)
+}
+
+// vim: foldmethod=marker
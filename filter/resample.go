@@ -0,0 +1,173 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package filter
+
+import (
+	"fmt"
+
+	"hz.tools/sdr"
+)
+
+var (
+	// ErrRatioTooComplex will be returned if the interpolation factor of a
+	// rational resampler is too large to build a polyphase filter for.
+	ErrRatioTooComplex = fmt.Errorf("filter: resampling ratio is too complex")
+)
+
+// maxInterpolation is the largest interpolation factor (after reducing the
+// ratio) the Resampler will build a filter bank for.
+const maxInterpolation = 1024
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// Resampler is a rational polyphase resampler, which will change the
+// sample rate by interp/decim. This is the same as zero stuffing by interp,
+// lowpass filtering, and dropping all but every decim'th sample, but only
+// the output samples that are kept are ever computed, and the stuffed zeros
+// are never multiplied.
+type Resampler struct {
+	interp int
+	decim  int
+
+	// phases are the interp sub-filters of the prototype lowpass, each
+	// stored newest-sample-first.
+	phases [][]float32
+
+	// buf holds the last len(phase)-1 samples of the previous call, followed
+	// by the samples of the current call.
+	buf sdr.SamplesC64
+
+	// next is the index (at the interpolated rate) of the next output
+	// sample, relative to the first sample of the next call.
+	next int
+}
+
+// NewResampler will create a Resampler to change the sample rate from
+// inRate to outRate. The ratio will be reduced, and the interpolation
+// factor must be no more than 1024 once it has been.
+//
+// tapsPerPhase controls the length of the prototype lowpass filter, which
+// has tapsPerPhase taps for each of the interpolation phases. When
+// decimating by a large factor, more taps are needed for a sharp enough
+// filter; something around 24 taps per phase for every decim/interp is a
+// good place to start.
+func NewResampler(inRate, outRate uint, tapsPerPhase int) (*Resampler, error) {
+	if inRate == 0 || outRate == 0 || tapsPerPhase < 1 {
+		return nil, ErrBadParameters
+	}
+
+	g := gcd(int(inRate), int(outRate))
+	interp, decim := int(outRate)/g, int(inRate)/g
+	if interp > maxInterpolation {
+		return nil, ErrRatioTooComplex
+	}
+
+	factor := interp
+	if decim > factor {
+		factor = decim
+	}
+
+	// Cut off just under the lower of the two Nyquist rates, leaving a bit
+	// of room for the transition band.
+	proto, err := LowPassTaps(interp*tapsPerPhase, 0.45/float64(factor))
+	if err != nil {
+		return nil, err
+	}
+
+	phases := make([][]float32, interp)
+	for p := range phases {
+		phase := make([]float32, tapsPerPhase)
+		for j := range phase {
+			// Each phase is scaled by interp to make up for the energy
+			// lost to the zeros we're not stuffing.
+			phase[j] = proto[p+j*interp] * float32(interp)
+		}
+		phases[p] = phase
+	}
+
+	return &Resampler{
+		interp: interp,
+		decim:  decim,
+		phases: phases,
+		buf:    make(sdr.SamplesC64, tapsPerPhase-1),
+	}, nil
+}
+
+// Ratio will return the reduced interpolation and decimation factors.
+func (r *Resampler) Ratio() (int, int) {
+	return r.interp, r.decim
+}
+
+// Reset will clear the history of the filter, as if no samples had been
+// processed.
+func (r *Resampler) Reset() {
+	r.buf = r.buf[:len(r.phases[0])-1]
+	for i := range r.buf {
+		r.buf[i] = 0
+	}
+	r.next = 0
+}
+
+// OutputLength will return the largest number of samples that ProcessC64
+// may write given n input samples.
+func (r *Resampler) OutputLength(n int) int {
+	return (n*r.interp)/r.decim + 1
+}
+
+// ProcessC64 will resample src into dst, which must be at least
+// OutputLength(len(src)) long, and return the number of samples written.
+func (r *Resampler) ProcessC64(dst, src sdr.SamplesC64) (int, error) {
+	if len(dst) < r.OutputLength(len(src)) {
+		return 0, sdr.ErrDstTooSmall
+	}
+
+	history := len(r.phases[0]) - 1
+	r.buf = append(r.buf[:history], src...)
+
+	var (
+		o     int
+		limit = len(src) * r.interp
+	)
+	for ; r.next < limit; r.next += r.decim {
+		var (
+			acc    complex64
+			phase  = r.phases[r.next%r.interp]
+			newest = history + r.next/r.interp
+		)
+		for j, tap := range phase {
+			acc += r.buf[newest-j] * complex(tap, 0)
+		}
+		dst[o] = acc
+		o++
+	}
+	r.next -= limit
+
+	copy(r.buf, r.buf[len(src):])
+	r.buf = r.buf[:history]
+	return o, nil
+}
+
+// vim: foldmethod=marker
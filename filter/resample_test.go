@@ -0,0 +1,101 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package filter_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/filter"
+)
+
+// resample will run the input through the Resampler in uneven chunks, to
+// make sure the phase carries over between calls.
+func resample(t *testing.T, r *filter.Resampler, in sdr.SamplesC64) sdr.SamplesC64 {
+	var (
+		out    = sdr.SamplesC64{}
+		chunks = []int{1, 7, 100, 1023, 4096}
+	)
+	for i := 0; len(in) > 0; i++ {
+		n := chunks[i%len(chunks)]
+		if n > len(in) {
+			n = len(in)
+		}
+		buf := make(sdr.SamplesC64, r.OutputLength(n))
+		o, err := r.ProcessC64(buf, in[:n])
+		assert.NoError(t, err)
+		out = append(out, buf[:o]...)
+		in = in[n:]
+	}
+	return out
+}
+
+func TestResamplerRatio(t *testing.T) {
+	r, err := filter.NewResampler(2400000, 48000, 24)
+	assert.NoError(t, err)
+	interp, decim := r.Ratio()
+	assert.Equal(t, 1, interp)
+	assert.Equal(t, 50, decim)
+
+	r, err = filter.NewResampler(44100, 48000, 24)
+	assert.NoError(t, err)
+	interp, decim = r.Ratio()
+	assert.Equal(t, 160, interp)
+	assert.Equal(t, 147, decim)
+
+	_, err = filter.NewResampler(48000, 48001, 24)
+	assert.Equal(t, filter.ErrRatioTooComplex, err)
+
+	_, err = filter.NewResampler(0, 48000, 24)
+	assert.Equal(t, filter.ErrBadParameters, err)
+}
+
+func TestResamplerInterpolate(t *testing.T) {
+	r, err := filter.NewResampler(44100, 48000, 24)
+	assert.NoError(t, err)
+
+	// 1 kHz at 44.1 kHz
+	out := resample(t, r, tone(1000.0/44100, 44100))
+	assert.InDelta(t, 48000, len(out), 1)
+	assert.InDelta(t, 0.5, amplitude(out, 100), 0.01)
+
+	_, err = r.ProcessC64(make(sdr.SamplesC64, 1), make(sdr.SamplesC64, 100))
+	assert.Equal(t, sdr.ErrDstTooSmall, err)
+}
+
+func TestResamplerDecimate(t *testing.T) {
+	r, err := filter.NewResampler(10, 1, 240)
+	assert.NoError(t, err)
+
+	// Within the output band, the tone should make it through untouched.
+	out := resample(t, r, tone(0.01, 100000))
+	assert.InDelta(t, 10000, len(out), 1)
+	assert.InDelta(t, 0.5, amplitude(out, 100), 0.01)
+
+	// Well outside of it, it should be filtered out, rather than aliased.
+	r.Reset()
+	out = resample(t, r, tone(0.3, 100000))
+	assert.InDelta(t, 0, amplitude(out, 100), 0.005)
+}
+
+// vim: foldmethod=marker
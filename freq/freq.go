@@ -0,0 +1,173 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package freq
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"hz.tools/rf"
+)
+
+var (
+	// ErrInvalidFrequency will be returned when a frequency can't be parsed.
+	ErrInvalidFrequency = fmt.Errorf("freq: invalid frequency")
+)
+
+// Tuning is a frequency, and a hint as to how it should be demodulated.
+type Tuning struct {
+	Frequency rf.Hz
+	Mode      Mode
+}
+
+// ParseHz will parse a frequency, which is more forgiving than rf.ParseHz:
+// there may be whitespace between the number and the unit, the unit is
+// case-insensitive, and a bare number is taken to be in Hz.
+//
+// Examples of valid frequencies:
+//
+//	146.520MHz
+//	7.074 MHz
+//	-10 khz
+//	1090000000
+func ParseHz(freq string) (rf.Hz, error) {
+	freq = strings.TrimSpace(freq)
+
+	split := strings.IndexFunc(freq, unicode.IsLetter)
+	number, unit := freq, ""
+	if split >= 0 {
+		number, unit = freq[:split], freq[split:]
+	}
+	number = strings.TrimSpace(number)
+
+	var scale rf.Hz
+	switch strings.ToLower(unit) {
+	case "", "hz":
+		scale = 1
+	case "khz", "k":
+		scale = rf.KHz
+	case "mhz", "m":
+		scale = rf.MHz
+	case "ghz", "g":
+		scale = rf.GHz
+	case "thz":
+		scale = rf.THz
+	default:
+		return 0, ErrInvalidFrequency
+	}
+
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, ErrInvalidFrequency
+	}
+	return rf.Hz(value) * scale, nil
+}
+
+// Parse will parse a frequency (see ParseHz), optionally followed by a
+// Mode (see ParseMode), separated by whitespace, such as "7.074 MHz USB".
+func Parse(tuning string) (Tuning, error) {
+	fields := strings.Fields(tuning)
+	if len(fields) == 0 {
+		return Tuning{}, ErrInvalidFrequency
+	}
+
+	// The last field is a mode if it's not a unit, and there's something
+	// before it for the frequency.
+	var mode Mode
+	if last := fields[len(fields)-1]; len(fields) > 1 {
+		if m, err := ParseMode(last); err == nil {
+			mode = m
+			fields = fields[:len(fields)-1]
+		}
+	}
+
+	hz, err := ParseHz(strings.Join(fields, ""))
+	if err != nil {
+		return Tuning{}, err
+	}
+	return Tuning{Frequency: hz, Mode: mode}, nil
+}
+
+// FormatHz will format a frequency for display, such as "146.520 MHz",
+// using the largest unit the frequency is at least one of, and at least 3
+// digits after the decimal point (more if needed to not lose precision),
+// as is usual for a radio display. The result can be parsed by ParseHz.
+func FormatHz(hz rf.Hz) string {
+	var (
+		abs   = hz
+		unit  = "Hz"
+		scale = rf.Hz(1)
+	)
+	if abs < 0 {
+		abs = -abs
+	}
+	switch {
+	case abs >= rf.GHz:
+		unit, scale = "GHz", rf.GHz
+	case abs >= rf.MHz:
+		unit, scale = "MHz", rf.MHz
+	case abs >= rf.KHz:
+		unit, scale = "kHz", rf.KHz
+	}
+
+	value := float64(hz / scale)
+	if scale == 1 {
+		return strconv.FormatFloat(value, 'f', -1, 64) + " " + unit
+	}
+
+	formatted := strconv.FormatFloat(value, 'f', -1, 64)
+	decimals := 0
+	if i := strings.IndexByte(formatted, '.'); i >= 0 {
+		decimals = len(formatted) - i - 1
+	}
+	if decimals < 3 {
+		formatted = strconv.FormatFloat(value, 'f', 3, 64)
+	}
+	return formatted + " " + unit
+}
+
+// String implements the fmt.Stringer interface, such as "7.074 MHz USB".
+func (t Tuning) String() string {
+	if t.Mode == ModeNone {
+		return FormatHz(t.Frequency)
+	}
+	return FormatHz(t.Frequency) + " " + string(t.Mode)
+}
+
+// MarshalText implements the encoding.TextMarshaler interface, so a Tuning
+// can be used in config files.
+func (t Tuning) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (t *Tuning) UnmarshalText(text []byte) error {
+	tuning, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*t = tuning
+	return nil
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,115 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package freq_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/rf"
+	"hz.tools/sdr/freq"
+)
+
+func TestParseHz(t *testing.T) {
+	for in, want := range map[string]rf.Hz{
+		"146.520MHz":  146.52 * rf.MHz,
+		"7.074 MHz":   7.074 * rf.MHz,
+		"-10 khz":     -10 * rf.KHz,
+		"1090000000":  1090 * rf.MHz,
+		" 2.4GHz ":    2.4 * rf.GHz,
+		"100.1m":      100.1 * rf.MHz,
+		"0.5 Hz":      0.5,
+		"+1500 kHz":   1.5 * rf.MHz,
+		"14.0745 MHZ": 14.0745 * rf.MHz,
+	} {
+		hz, err := freq.ParseHz(in)
+		assert.NoError(t, err, in)
+		assert.InDelta(t, float64(want), float64(hz), 1e-3, in)
+	}
+
+	for _, in := range []string{"", "MHz", "12 furlongs", "1.2.3 MHz"} {
+		_, err := freq.ParseHz(in)
+		assert.Equal(t, freq.ErrInvalidFrequency, err, in)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tuning, err := freq.Parse("7.074 MHz USB")
+	assert.NoError(t, err)
+	assert.InDelta(t, 7.074e6, float64(tuning.Frequency), 1e-3)
+	assert.Equal(t, freq.ModeUSB, tuning.Mode)
+
+	tuning, err = freq.Parse("162.55MHz nfm")
+	assert.NoError(t, err)
+	assert.Equal(t, freq.ModeFM, tuning.Mode)
+
+	// A unit on its own isn't a mode.
+	tuning, err = freq.Parse("146.52 M")
+	assert.NoError(t, err)
+	assert.Equal(t, freq.ModeNone, tuning.Mode)
+	assert.InDelta(t, 146.52e6, float64(tuning.Frequency), 1e-3)
+
+	_, err = freq.Parse("7.074 MHz QAM")
+	assert.Equal(t, freq.ErrInvalidFrequency, err)
+
+	_, err = freq.ParseMode("QAM")
+	assert.Equal(t, freq.ErrUnknownMode, err)
+}
+
+func TestFormatHz(t *testing.T) {
+	for hz, want := range map[rf.Hz]string{
+		146.52 * rf.MHz:   "146.520 MHz",
+		7.074 * rf.MHz:    "7.074 MHz",
+		14.0745 * rf.MHz:  "14.0745 MHz",
+		1.09 * rf.GHz:     "1.090 GHz",
+		-12.5 * rf.KHz:    "-12.500 kHz",
+		440:               "440 Hz",
+		100.1234 * rf.MHz: "100.1234 MHz",
+	} {
+		assert.Equal(t, want, freq.FormatHz(hz))
+
+		back, err := freq.ParseHz(want)
+		assert.NoError(t, err)
+		assert.InDelta(t, float64(hz), float64(back), 1e-3)
+	}
+}
+
+func TestTuningText(t *testing.T) {
+	config := struct {
+		Channels []freq.Tuning `json:"channels"`
+	}{}
+	assert.NoError(t, json.Unmarshal(
+		[]byte(`{"channels": ["146.520MHz FM", "7.074 MHz usb", "1090 MHz"]}`),
+		&config,
+	))
+	assert.Len(t, config.Channels, 3)
+	assert.Equal(t, "146.520 MHz FM", config.Channels[0].String())
+	assert.Equal(t, "7.074 MHz USB", config.Channels[1].String())
+	assert.Equal(t, "1.090 GHz", config.Channels[2].String())
+
+	out, err := json.Marshal(config.Channels[1])
+	assert.NoError(t, err)
+	assert.Equal(t, `"7.074 MHz USB"`, string(out))
+}
+
+// vim: foldmethod=marker
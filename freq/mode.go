@@ -0,0 +1,90 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package freq
+
+import (
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrUnknownMode will be returned when parsing a Mode that isn't known.
+	ErrUnknownMode = fmt.Errorf("freq: unknown mode")
+)
+
+// Mode is a hint as to how a signal at a frequency should be demodulated.
+type Mode string
+
+const (
+	// ModeNone means no mode was given.
+	ModeNone Mode = ""
+
+	// ModeAM is amplitude modulation.
+	ModeAM Mode = "AM"
+
+	// ModeFM is narrowband frequency modulation.
+	ModeFM Mode = "FM"
+
+	// ModeWFM is wideband (broadcast) frequency modulation.
+	ModeWFM Mode = "WFM"
+
+	// ModeUSB is upper sideband.
+	ModeUSB Mode = "USB"
+
+	// ModeLSB is lower sideband.
+	ModeLSB Mode = "LSB"
+
+	// ModeCW is continuous wave (morse).
+	ModeCW Mode = "CW"
+
+	// ModeRaw is unprocessed IQ.
+	ModeRaw Mode = "RAW"
+)
+
+var modeAliases = map[string]Mode{
+	"AM":   ModeAM,
+	"FM":   ModeFM,
+	"NFM":  ModeFM,
+	"WFM":  ModeWFM,
+	"WBFM": ModeWFM,
+	"USB":  ModeUSB,
+	"LSB":  ModeLSB,
+	"CW":   ModeCW,
+	"RAW":  ModeRaw,
+	"IQ":   ModeRaw,
+}
+
+// ParseMode will parse a Mode, ignoring case, and accepting common aliases
+// (such as "NFM" for ModeFM, or "WBFM" for ModeWFM). An empty string is
+// ModeNone.
+func ParseMode(mode string) (Mode, error) {
+	mode = strings.TrimSpace(mode)
+	if mode == "" {
+		return ModeNone, nil
+	}
+	m, ok := modeAliases[strings.ToUpper(mode)]
+	if !ok {
+		return ModeNone, ErrUnknownMode
+	}
+	return m, nil
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,153 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package hackrf
+
+// #cgo pkg-config: libhackrf
+//
+// #include <libhackrf/hackrf.h>
+import "C"
+
+import (
+	"bytes"
+	"fmt"
+	"unsafe"
+)
+
+var (
+	// ErrFirmwareTooLarge will be returned by WriteFirmware when the image
+	// will not fit into the HackRF's SPI flash.
+	ErrFirmwareTooLarge = fmt.Errorf("hackrf: firmware image is larger than the SPI flash")
+)
+
+const (
+	// spiflashSize is the size of the W25Q80BV SPI flash on the HackRF One.
+	spiflashSize = 1 << 20
+
+	// spiflashPage is the largest write libhackrf will do in one request.
+	spiflashPage = 256
+)
+
+// FirmwareTooOldError is returned by RequireUSBAPIVersion when the firmware
+// on the HackRF does not implement the USB API version that is required.
+type FirmwareTooOldError struct {
+	// Version is the firmware version string reported by the HackRF.
+	Version string
+
+	// USBAPIVersion is the USB API version the firmware implements.
+	USBAPIVersion uint16
+
+	// Required is the USB API version that was required.
+	Required uint16
+}
+
+// Error implements the error interface.
+func (e FirmwareTooOldError) Error() string {
+	return fmt.Sprintf(
+		"hackrf: firmware %s implements USB API %x.%02x, but %x.%02x is required; "+
+			"update the firmware using hackrf_spiflash(1)",
+		e.Version,
+		e.USBAPIVersion>>8, e.USBAPIVersion&0xFF,
+		e.Required>>8, e.Required&0xFF,
+	)
+}
+
+// Version will return the firmware version string, as reported by the
+// HackRF itself, such as "2023.01.1".
+func (s *Sdr) Version() (string, error) {
+	var out [255]byte
+	if err := rvToErr(C.hackrf_version_string_read(
+		s.dev,
+		(*C.char)(unsafe.Pointer(&out[0])),
+		C.uint8_t(len(out)),
+	)); err != nil {
+		return "", err
+	}
+	if i := bytes.IndexByte(out[:], 0x00); i >= 0 {
+		return string(out[:i]), nil
+	}
+	return string(out[:]), nil
+}
+
+// USBAPIVersion will return the USB API version implemented by the firmware
+// on the HackRF, such as 0x0106. Newer libhackrf calls check this value
+// themselves, and fail with a generic error if the firmware is too old.
+func (s *Sdr) USBAPIVersion() (uint16, error) {
+	var version C.uint16_t
+	if err := rvToErr(C.hackrf_usb_api_version_read(s.dev, &version)); err != nil {
+		return 0, err
+	}
+	return uint16(version), nil
+}
+
+// RequireUSBAPIVersion will return a FirmwareTooOldError if the firmware on
+// the HackRF implements a USB API older than the provided version. This is
+// useful to check before using a feature, since libhackrf
+// will otherwise fail with an unhelpful error mid-stream.
+func (s *Sdr) RequireUSBAPIVersion(min uint16) error {
+	version, err := s.USBAPIVersion()
+	if err != nil {
+		return err
+	}
+	if version >= min {
+		return nil
+	}
+	fw, err := s.Version()
+	if err != nil {
+		fw = "unknown"
+	}
+	return FirmwareTooOldError{
+		Version:       fw,
+		USBAPIVersion: version,
+		Required:      min,
+	}
+}
+
+// WriteFirmware will erase the HackRF's SPI flash, and write the provided
+// firmware image (such as hackrf_one_usb.bin) to it. The new firmware will
+// be used after the HackRF is reset or power cycled.
+//
+// This is the same thing hackrf_spiflash(1) does. If this is interrupted,
+// the HackRF will need to be recovered using DFU mode.
+func (s *Sdr) WriteFirmware(image []byte) error {
+	if len(image) > spiflashSize {
+		return ErrFirmwareTooLarge
+	}
+	if err := rvToErr(C.hackrf_spiflash_erase(s.dev)); err != nil {
+		return err
+	}
+	for offset := 0; offset < len(image); offset += spiflashPage {
+		page := image[offset:]
+		if len(page) > spiflashPage {
+			page = page[:spiflashPage]
+		}
+		if err := rvToErr(C.hackrf_spiflash_write(
+			s.dev,
+			C.uint32_t(offset),
+			C.uint16_t(len(page)),
+			(*C.uchar)(unsafe.Pointer(&page[0])),
+		)); err != nil {
+			return fmt.Errorf("hackrf: failed writing firmware at offset %d: %s", offset, err)
+		}
+	}
+	return nil
+}
+
+// vim: foldmethod=marker
@@ -22,11 +22,13 @@ package hackrf
 
 // #cgo pkg-config: libhackrf
 //
+// #include <stdlib.h>
 // #include <libhackrf/hackrf.h>
 import "C"
 
 import (
 	"fmt"
+	"sync"
 	"unsafe"
 
 	"hz.tools/rf"
@@ -36,10 +38,29 @@ import (
 
 func init() {
 	debug.RegisterRadioDriver("hz.tools/sdr/hackrf.Sdr")
+	sdr.RegisterSerialOpener("hackrf", func(serial string) (sdr.Sdr, error) {
+		if err := Init(); err != nil {
+			return nil, err
+		}
+		return OpenBySerial(serial)
+	})
+	sdr.RegisterEnumerator("hackrf", sdr.ListEnumerator(func() ([]sdr.HardwareInfo, error) {
+		if err := Init(); err != nil {
+			return nil, err
+		}
+		return List()
+	}))
 }
 
 var (
-	hasInit         = false
+	initLock = &sync.Mutex{}
+	hasInit  bool
+
+	// libhackrfInit and libhackrfExit are the libhackrf calls made by Init
+	// and Exit, which are stubbed out in tests.
+	libhackrfInit = func() error { return rvToErr(C.hackrf_init()) }
+	libhackrfExit = func() error { return rvToErr(C.hackrf_exit()) }
+
 	usbBoardMapping = map[uint32]Board{
 		0x604B: BoardJawbreaker,
 		0x6089: BoardHackRfOne,
@@ -48,6 +69,8 @@ var (
 )
 
 func checkInit() error {
+	initLock.Lock()
+	defer initLock.Unlock()
 	if !hasInit {
 		return fmt.Errorf("hackrf: Init was not called")
 	}
@@ -120,18 +143,31 @@ func List() ([]sdr.HardwareInfo, error) {
 // Init *must* be called before the HackRF can be used. Attempting to use the
 // HackRF or calling any HackRF functions may result in error if done before
 // invoking Init.
+//
+// libhackrf is only initialized if it isn't already, so this is safe to call
+// from more than one goroutine. If initializing fails, the next call to Init
+// will try again.
 func Init() error {
-	err := rvToErr(C.hackrf_init())
-	if err == nil {
-		hasInit = true
+	initLock.Lock()
+	defer initLock.Unlock()
+	if hasInit {
+		return nil
+	}
+	if err := libhackrfInit(); err != nil {
+		return err
 	}
-	return err
+	hasInit = true
+	return nil
 }
 
 // Exit will clean up after Init, and should be called when the process is
-// shutting down, or otherwise done with the HackRF.
+// shutting down, or otherwise done with the HackRF. Init must be called
+// again before the HackRF can be used after this.
 func Exit() error {
-	return rvToErr(C.hackrf_exit())
+	initLock.Lock()
+	defer initLock.Unlock()
+	hasInit = false
+	return libhackrfExit()
 }
 
 // Version will return the HackRF library version and release.
@@ -139,22 +175,82 @@ func Version() (string, string) {
 	return C.GoString(C.hackrf_library_version()), C.GoString(C.hackrf_library_release())
 }
 
+// Options contains arguments used to tune how samples from the HackRF are
+// buffered.
+type Options struct {
+	// BufferCount is the number of USB transfers (of 256 KiB each, or
+	// 128K samples) that will be buffered between libhackrf and the
+	// reader. libhackrf's own transfer count is fixed when it's compiled,
+	// so if the reader falls behind for longer than that, libhackrf will
+	// drop samples; this buffer absorbs those stalls instead.
+	//
+	// If set to 0, this will default to 8. If set to -1, no buffer will
+	// be used, and libhackrf will wait on the reader directly.
+	BufferCount int
+
+	// SampleFormats is an ordered list of preferred SampleFormats, most
+	// preferred first. The HackRF only streams sdr.SampleFormatI8, so if
+	// this is set and doesn't contain it, opening the device will fail with
+	// sdr.ErrNoSupportedSampleFormat.
+	SampleFormats []sdr.SampleFormat
+}
+
+func (opts Options) getBufferCount() int {
+	if opts.BufferCount == 0 {
+		return 8
+	}
+	return opts.BufferCount
+}
+
 // Open will open the first HackRF on the system.
 func Open() (*Sdr, error) {
+	return OpenWithOptions(Options{})
+}
+
+// OpenWithOptions will open the first HackRF on the system, using the
+// provided Options.
+func OpenWithOptions(opts Options) (*Sdr, error) {
 	var dev *C.hackrf_device
 
+	if _, err := sdr.NegotiateSampleFormat(
+		opts.SampleFormats,
+		[]sdr.SampleFormat{sdr.SampleFormatI8},
+	); err != nil {
+		return nil, err
+	}
+
 	if err := rvToErr(C.hackrf_open(&dev)); err != nil {
 		return nil, err
 	}
 
 	return &Sdr{
-		dev: dev,
+		dev:         dev,
+		bufferCount: opts.getBufferCount(),
+	}, nil
+}
+
+// OpenBySerial will open the HackRF with the provided Serial, as returned
+// by List or HardwareInfo.
+func OpenBySerial(serial string) (*Sdr, error) {
+	var dev *C.hackrf_device
+
+	cSerial := C.CString(serial)
+	defer C.free(unsafe.Pointer(cSerial))
+
+	if err := rvToErr(C.hackrf_open_by_serial(cSerial, &dev)); err != nil {
+		return nil, err
+	}
+
+	return &Sdr{
+		dev:         dev,
+		bufferCount: Options{}.getBufferCount(),
 	}, nil
 }
 
 // Sdr implements the sdr.Sdr interface for the HackRF One.
 type Sdr struct {
-	dev *C.hackrf_device
+	dev         *C.hackrf_device
+	bufferCount int
 
 	sampleRate      uint
 	centerFrequency rf.Hz
@@ -204,6 +300,14 @@ func (s *Sdr) GetSampleRate() (uint, error) {
 	return s.sampleRate, nil
 }
 
+// SampleRateRanges implements the sdr.SampleRateRanger interface. The
+// HackRF is specified from 2 to 20 Msps.
+func (s *Sdr) SampleRateRanges() ([]sdr.SampleRateRange, error) {
+	return []sdr.SampleRateRange{
+		{Min: 2000000, Max: 20000000},
+	}, nil
+}
+
 // SampleFormat implements the sdr.Sdr interface
 func (s *Sdr) SampleFormat() sdr.SampleFormat {
 	return sdr.SampleFormatI8
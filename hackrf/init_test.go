@@ -0,0 +1,82 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package hackrf
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func stubLibhackrf(t *testing.T, initErrs ...error) (*int, *int) {
+	var inits, exits int
+	oldInit, oldExit := libhackrfInit, libhackrfExit
+	t.Cleanup(func() {
+		libhackrfInit, libhackrfExit = oldInit, oldExit
+		hasInit = false
+	})
+	libhackrfInit = func() error {
+		inits++
+		if len(initErrs) > 0 {
+			err := initErrs[0]
+			initErrs = initErrs[1:]
+			return err
+		}
+		return nil
+	}
+	libhackrfExit = func() error {
+		exits++
+		return nil
+	}
+	return &inits, &exits
+}
+
+func TestInitExitInit(t *testing.T) {
+	inits, exits := stubLibhackrf(t)
+
+	assert.NoError(t, Init())
+	assert.NoError(t, Init())
+	assert.Equal(t, 1, *inits)
+	assert.NoError(t, checkInit())
+
+	assert.NoError(t, Exit())
+	assert.Equal(t, 1, *exits)
+	assert.Error(t, checkInit())
+
+	// libhackrf has been torn down, so it has to be initialized again.
+	assert.NoError(t, Init())
+	assert.Equal(t, 2, *inits)
+	assert.NoError(t, checkInit())
+}
+
+func TestInitRetry(t *testing.T) {
+	inits, _ := stubLibhackrf(t, fmt.Errorf("hackrf: permission denied"))
+
+	assert.Error(t, Init())
+	assert.Error(t, checkInit())
+
+	assert.NoError(t, Init())
+	assert.Equal(t, 2, *inits)
+	assert.NoError(t, checkInit())
+}
+
+// vim: foldmethod=marker
@@ -30,22 +30,35 @@ import "C"
 import (
 	"log"
 	"sync"
+	"time"
 	"unsafe"
 
 	"github.com/mattn/go-pointer"
 
 	"hz.tools/sdr"
+	"hz.tools/sdr/stream"
 )
 
 type rxCallbackState struct {
 	pipeReader sdr.PipeReader
 	pipeWriter sdr.PipeWriter
+	clock      *sdr.HostClock
 }
 
 //export hackrfRxCallback
-func hackrfRxCallback(transfer *C.hackrf_transfer) int {
+func hackrfRxCallback(transfer *C.hackrf_transfer) (rv int) {
+	now := time.Now()
 	state := pointer.Restore(transfer.rx_ctx).(*rxCallbackState)
 
+	// A panic here would unwind through libhackrf and take down the whole
+	// process, so we'll close the pipe and tell libhackrf to stop instead.
+	defer func() {
+		if v := recover(); v != nil {
+			state.pipeWriter.CloseWithError(sdr.DriverPanic(v))
+			rv = -1
+		}
+	}()
+
 	// First, we need to load the incoming bytes from HackRF to a Go
 	// []byte type.
 	//
@@ -71,6 +84,7 @@ func hackrfRxCallback(transfer *C.hackrf_transfer) int {
 		return -1
 	}
 
+	state.clock.Mark(now, bufIQLength)
 	i, err := state.pipeWriter.Write(samples)
 	if err != nil {
 		log.Printf("hackrf: rx: write error %s", err)
@@ -86,12 +100,31 @@ func hackrfRxCallback(transfer *C.hackrf_transfer) int {
 }
 
 // StartRx implements the sdr.Sdr interface.
+//
+// The HackRF has no hardware clock to timestamp samples with, so the
+// returned ReadCloser is an sdr.TimedReader, which estimates the time each
+// sample was received using the host's clock when each USB transfer
+// arrives. See sdr.HostClock for how accurate that is.
 func (s *Sdr) StartRx() (sdr.ReadCloser, error) {
-	pipeReader, pipeWriter := sdr.Pipe(s.sampleRate, sdr.SampleFormatI8)
+	var (
+		pipeReader sdr.PipeReader
+		pipeWriter sdr.PipeWriter
+	)
+	if s.bufferCount > 0 {
+		bp, err := stream.NewBufPipe2(s.bufferCount, s.sampleRate, sdr.SampleFormatI8)
+		if err != nil {
+			return nil, err
+		}
+		pipeReader, pipeWriter = bp, bp
+	} else {
+		pipeReader, pipeWriter = sdr.Pipe(s.sampleRate, sdr.SampleFormatI8)
+	}
 
+	clock := sdr.NewHostClock(s.sampleRate)
 	state := pointer.Save(&rxCallbackState{
 		pipeReader: pipeReader,
 		pipeWriter: pipeWriter,
+		clock:      clock,
 	})
 
 	if err := rvToErr(C.hackrf_start_rx(
@@ -106,7 +139,7 @@ func (s *Sdr) StartRx() (sdr.ReadCloser, error) {
 		lock   = &sync.Mutex{}
 		closed bool
 	)
-	return sdr.ReaderWithCloser(pipeReader, func() error {
+	return sdr.HostTimedReader(sdr.ReaderWithCloser(pipeReader, func() error {
 		lock.Lock()
 		defer lock.Unlock()
 
@@ -119,7 +152,7 @@ func (s *Sdr) StartRx() (sdr.ReadCloser, error) {
 		err := rvToErr(C.hackrf_stop_rx(s.dev))
 		closed = true
 		return err
-	}), nil
+	}), clock), nil
 }
 
 // vim: foldmethod=marker
@@ -44,9 +44,18 @@ type txCallbackState struct {
 }
 
 //export hackrfTxCallback
-func hackrfTxCallback(transfer *C.hackrf_transfer) int {
+func hackrfTxCallback(transfer *C.hackrf_transfer) (rv int) {
 	state := pointer.Restore(transfer.tx_ctx).(*txCallbackState)
 
+	// See hackrfRxCallback; the writer will get the ErrDriverPanic on the
+	// next Write.
+	defer func() {
+		if v := recover(); v != nil {
+			state.pipeReader.CloseWithError(sdr.DriverPanic(v))
+			rv = -1
+		}
+	}()
+
 	// First, we need to load the incoming bytes from HackRF to a Go
 	// []byte type.
 	//
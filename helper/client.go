@@ -0,0 +1,482 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package helper
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+)
+
+// Client is an sdr.Transceiver which proxies all calls to an sdr.Sdr running
+// on the other end of a connection, usually in a helper process started with
+// Exec.
+//
+// If the remote sdr.Sdr does not support rx or tx, StartRx or StartTx will
+// return sdr.ErrNotSupported. If the connection to the helper is lost, all
+// calls will return ErrHelperExited.
+type Client struct {
+	conn io.ReadWriteCloser
+
+	writeLock   *sync.Mutex
+	requestLock *sync.Mutex
+	responses   chan response
+	done        chan struct{}
+	err         error
+
+	hardwareInfo sdr.HardwareInfo
+	sampleFormat sdr.SampleFormat
+	gainStages   sdr.GainStages
+
+	// swap is set if the helper sends samples in a different byte order.
+	swap bool
+
+	rxLock   *sync.Mutex
+	rxWriter sdr.PipeWriter
+
+	// exited is only set if the Client was created by Exec, and will have
+	// the result of cmd.Wait sent to it when the process has exited.
+	cmd    *exec.Cmd
+	exited chan error
+}
+
+// NewClient will create a new Client, talking to a helper on the other side
+// of the provided connection.
+func NewClient(conn io.ReadWriteCloser) (*Client, error) {
+	c := &Client{
+		conn:        conn,
+		writeLock:   &sync.Mutex{},
+		requestLock: &sync.Mutex{},
+		responses:   make(chan response, 1),
+		done:        make(chan struct{}),
+		rxLock:      &sync.Mutex{},
+	}
+	go c.run()
+
+	resp, err := c.request(request{Method: methodHello, ByteOrder: byteOrder})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	c.swap = needsSwap(resp.ByteOrder)
+	c.hardwareInfo = resp.HardwareInfo
+	c.sampleFormat = resp.SampleFormat
+	for _, stage := range resp.GainStages {
+		c.gainStages = append(c.gainStages, stage)
+	}
+	return c, nil
+}
+
+// Exec will start the provided command, which is expected to call
+// ServeParent, and return a Client connected to that process. The command
+// must not have been started.
+//
+// The helper is sent the path to a unix socket in a temporary directory via
+// the EnvSocket environment variable. If the helper exits before
+// connecting, or doesn't connect within the timeout, an error is returned.
+func Exec(cmd *exec.Cmd, timeout time.Duration) (*Client, error) {
+	dir, err := ioutil.TempDir("", "hz.tools-sdr-helper")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "helper.sock")
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	defer listener.Close()
+
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", EnvSocket, path))
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	type acceptResult struct {
+		conn net.Conn
+		err  error
+	}
+	accepted := make(chan acceptResult, 1)
+	go func() {
+		conn, err := listener.Accept()
+		accepted <- acceptResult{conn: conn, err: err}
+	}()
+
+	var conn net.Conn
+	select {
+	case result := <-accepted:
+		if result.err != nil {
+			cmd.Process.Kill()
+			return nil, result.err
+		}
+		conn = result.conn
+	case err := <-exited:
+		if err == nil {
+			err = ErrHelperExited
+		}
+		return nil, fmt.Errorf("helper: helper exited before connecting: %s", err)
+	case <-time.After(timeout):
+		cmd.Process.Kill()
+		return nil, fmt.Errorf("helper: timeout waiting for the helper to connect")
+	}
+
+	c, err := NewClient(conn)
+	if err != nil {
+		cmd.Process.Kill()
+		return nil, err
+	}
+	c.cmd = cmd
+	c.exited = exited
+	return c, nil
+}
+
+// run will read frames from the helper until the connection is closed,
+// handing responses to the caller waiting on a request, and IQ data to the
+// rx pipe, if any.
+func (c *Client) run() {
+	defer close(c.done)
+
+	for {
+		ft, payload, err := readFrame(c.conn)
+		if err != nil {
+			c.err = ErrHelperExited
+			c.closeRx(ErrHelperExited)
+			return
+		}
+
+		switch ft {
+		case frameResponse:
+			var resp response
+			if err := json.Unmarshal(payload, &resp); err != nil {
+				c.err = err
+				c.closeRx(err)
+				c.conn.Close()
+				return
+			}
+			c.responses <- resp
+		case frameRxSamples:
+			c.rxLock.Lock()
+			w := c.rxWriter
+			c.rxLock.Unlock()
+			if w == nil {
+				continue
+			}
+			samples, err := samplesFromBytes(w.SampleFormat(), payload, c.swap)
+			if err != nil {
+				c.closeRx(err)
+				continue
+			}
+			// If the reader has gone away, this will fail, and the rest of
+			// the stream will be dropped until the helper stops sending.
+			w.Write(samples)
+		case frameRxEnd:
+			err := stringToErr(string(payload))
+			if err == nil {
+				err = io.EOF
+			}
+			c.closeRx(err)
+		default:
+			c.err = fmt.Errorf("helper: unexpected frame type 0x%02x", ft)
+			c.closeRx(c.err)
+			c.conn.Close()
+			return
+		}
+	}
+}
+
+func (c *Client) closeRx(err error) {
+	c.rxLock.Lock()
+	defer c.rxLock.Unlock()
+	if c.rxWriter == nil {
+		return
+	}
+	c.rxWriter.CloseWithError(err)
+	c.rxWriter = nil
+}
+
+func (c *Client) writeFrame(ft frameType, payload []byte) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	if err := writeFrame(c.conn, ft, payload); err != nil {
+		return ErrHelperExited
+	}
+	return nil
+}
+
+// request will send the request to the helper, and wait for the response.
+// Only one request may be in flight at a time.
+func (c *Client) request(req request) (response, error) {
+	c.requestLock.Lock()
+	defer c.requestLock.Unlock()
+
+	c.writeLock.Lock()
+	err := writeJSONFrame(c.conn, frameRequest, req)
+	c.writeLock.Unlock()
+	if err != nil {
+		return response{}, ErrHelperExited
+	}
+
+	select {
+	case resp := <-c.responses:
+		return resp, stringToErr(resp.Error)
+	case <-c.done:
+		// The helper may have sent the response right before hanging up
+		// (such as on Close), in which case both are ready, and select
+		// may have picked this case.
+		select {
+		case resp := <-c.responses:
+			return resp, stringToErr(resp.Error)
+		default:
+		}
+		return response{}, c.err
+	}
+}
+
+// Close implements the sdr.Sdr interface.
+//
+// This will close the Sdr in the helper process, and if the Client was
+// created by Exec, wait for the helper to exit.
+func (c *Client) Close() error {
+	_, err := c.request(request{Method: methodClose})
+	c.conn.Close()
+	<-c.done
+
+	if c.exited != nil {
+		select {
+		case <-c.exited:
+		case <-time.After(5 * time.Second):
+			c.cmd.Process.Kill()
+			<-c.exited
+		}
+	}
+	return err
+}
+
+// HardwareInfo implements the sdr.Sdr interface.
+func (c *Client) HardwareInfo() sdr.HardwareInfo {
+	return c.hardwareInfo
+}
+
+// SampleFormat implements the sdr.Sdr interface.
+func (c *Client) SampleFormat() sdr.SampleFormat {
+	return c.sampleFormat
+}
+
+// SetCenterFrequency implements the sdr.Sdr interface.
+func (c *Client) SetCenterFrequency(freq rf.Hz) error {
+	_, err := c.request(request{
+		Method:          methodSetCenterFrequency,
+		CenterFrequency: freq,
+	})
+	return err
+}
+
+// GetCenterFrequency implements the sdr.Sdr interface.
+func (c *Client) GetCenterFrequency() (rf.Hz, error) {
+	resp, err := c.request(request{Method: methodGetCenterFrequency})
+	return resp.CenterFrequency, err
+}
+
+// SetAutomaticGain implements the sdr.Sdr interface.
+func (c *Client) SetAutomaticGain(enabled bool) error {
+	_, err := c.request(request{
+		Method:  methodSetAutomaticGain,
+		Enabled: enabled,
+	})
+	return err
+}
+
+// GetGainStages implements the sdr.Sdr interface.
+func (c *Client) GetGainStages() (sdr.GainStages, error) {
+	if c.gainStages == nil {
+		return nil, sdr.ErrNotSupported
+	}
+	return c.gainStages, nil
+}
+
+// GetGain implements the sdr.Sdr interface.
+func (c *Client) GetGain(gs sdr.GainStage) (float32, error) {
+	resp, err := c.request(request{
+		Method:    methodGetGain,
+		GainStage: gs.String(),
+	})
+	return resp.Gain, err
+}
+
+// SetGain implements the sdr.Sdr interface.
+func (c *Client) SetGain(gs sdr.GainStage, gain float32) error {
+	_, err := c.request(request{
+		Method:    methodSetGain,
+		GainStage: gs.String(),
+		Gain:      gain,
+	})
+	return err
+}
+
+// SetSampleRate implements the sdr.Sdr interface.
+func (c *Client) SetSampleRate(sampleRate uint) error {
+	_, err := c.request(request{
+		Method:     methodSetSampleRate,
+		SampleRate: sampleRate,
+	})
+	return err
+}
+
+// GetSampleRate implements the sdr.Sdr interface.
+func (c *Client) GetSampleRate() (uint, error) {
+	resp, err := c.request(request{Method: methodGetSampleRate})
+	return resp.SampleRate, err
+}
+
+type rxReader struct {
+	sdr.PipeReader
+	client *Client
+}
+
+// Close implements the sdr.ReadCloser interface.
+func (rx rxReader) Close() error {
+	// Close the pipe first, so that the client's read loop will drop any
+	// samples in flight rather than blocking on us.
+	rx.PipeReader.Close()
+	_, err := rx.client.request(request{Method: methodStopRx})
+	return err
+}
+
+// StartRx implements the sdr.Receiver interface.
+//
+// Much like a real device, samples must be read promptly, since the
+// connection to the helper will not process any other responses (such
+// as a call to SetCenterFrequency) while samples are waiting to be read.
+func (c *Client) StartRx() (sdr.ReadCloser, error) {
+	sampleRate, err := c.GetSampleRate()
+	if err != nil {
+		return nil, err
+	}
+
+	pipeReader, pipeWriter := sdr.Pipe(sampleRate, c.sampleFormat)
+
+	c.rxLock.Lock()
+	if c.rxWriter != nil {
+		c.rxLock.Unlock()
+		return nil, fmt.Errorf("helper: rx is already running")
+	}
+	c.rxWriter = pipeWriter
+	c.rxLock.Unlock()
+
+	if _, err := c.request(request{Method: methodStartRx}); err != nil {
+		c.rxLock.Lock()
+		if c.rxWriter == pipeWriter {
+			c.rxWriter = nil
+		}
+		c.rxLock.Unlock()
+		pipeWriter.CloseWithError(err)
+		return nil, err
+	}
+
+	return rxReader{
+		PipeReader: pipeReader,
+		client:     c,
+	}, nil
+}
+
+type txWriter struct {
+	client       *Client
+	sampleRate   uint
+	sampleFormat sdr.SampleFormat
+}
+
+// SampleRate implements the sdr.Writer interface.
+func (tx txWriter) SampleRate() uint {
+	return tx.sampleRate
+}
+
+// SampleFormat implements the sdr.Writer interface.
+func (tx txWriter) SampleFormat() sdr.SampleFormat {
+	return tx.sampleFormat
+}
+
+// Write implements the sdr.Writer interface.
+func (tx txWriter) Write(s sdr.Samples) (int, error) {
+	if s.Format() != tx.sampleFormat {
+		return 0, sdr.ErrSampleFormatMismatch
+	}
+
+	var (
+		chunk = maxFrameLength / tx.sampleFormat.Size()
+		n     int
+	)
+	for n < s.Length() {
+		end := n + chunk
+		if end > s.Length() {
+			end = s.Length()
+		}
+		payload := sdr.MustUnsafeSamplesAsBytes(s.Slice(n, end))
+		if err := tx.client.writeFrame(frameTxSamples, payload); err != nil {
+			return n, err
+		}
+		n = end
+	}
+	return n, nil
+}
+
+// Close implements the sdr.WriteCloser interface.
+func (tx txWriter) Close() error {
+	_, err := tx.client.request(request{Method: methodStopTx})
+	return err
+}
+
+// StartTx implements the sdr.Transmitter interface.
+func (c *Client) StartTx() (sdr.WriteCloser, error) {
+	sampleRate, err := c.GetSampleRate()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.request(request{Method: methodStartTx}); err != nil {
+		return nil, err
+	}
+	return txWriter{
+		client:       c,
+		sampleRate:   sampleRate,
+		sampleFormat: c.sampleFormat,
+	}, nil
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,199 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package helper_test
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/helper"
+	"hz.tools/sdr/mock"
+)
+
+type testGainStage struct{}
+
+func (testGainStage) Range() [2]float32       { return [2]float32{0, 10} }
+func (testGainStage) Type() sdr.GainStageType { return sdr.GainStageTypeRecieve }
+func (testGainStage) String() string          { return "LNA" }
+
+func newMock(rx sdr.ReadCloser, tx sdr.WriteCloser) sdr.Transceiver {
+	return mock.New(mock.Config{
+		SampleRate:   1000,
+		SampleFormat: sdr.SampleFormatI16,
+		Rx:           mock.ThisRx(rx),
+		Tx:           mock.ThisTx(tx),
+		GainStages:   sdr.GainStages{testGainStage{}},
+	})
+}
+
+func newClient(t *testing.T, dev sdr.Sdr) (*helper.Client, chan error) {
+	clientConn, serverConn := net.Pipe()
+	served := make(chan error, 1)
+	go func() {
+		served <- helper.ServeConn(serverConn, dev)
+	}()
+	client, err := helper.NewClient(clientConn)
+	assert.NoError(t, err)
+	return client, served
+}
+
+func TestHelperControl(t *testing.T) {
+	client, served := newClient(t, newMock(nil, nil))
+
+	assert.Equal(t, "mocksdr", client.HardwareInfo().Product)
+	assert.Equal(t, sdr.SampleFormatI16, client.SampleFormat())
+
+	assert.NoError(t, client.SetCenterFrequency(1090*rf.MHz))
+	freq, err := client.GetCenterFrequency()
+	assert.NoError(t, err)
+	assert.Equal(t, 1090*rf.MHz, freq)
+
+	assert.NoError(t, client.SetSampleRate(2048000))
+	sps, err := client.GetSampleRate()
+	assert.NoError(t, err)
+	assert.Equal(t, uint(2048000), sps)
+
+	assert.Equal(t, sdr.ErrNotSupported, client.SetAutomaticGain(true))
+
+	stages, err := client.GetGainStages()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(stages))
+	lna := stages.First(sdr.GainStageTypeRecieve)
+	assert.Equal(t, "LNA", lna.String())
+	assert.Equal(t, [2]float32{0, 10}, lna.Range())
+
+	assert.NoError(t, client.SetGain(lna, 4))
+	gain, err := client.GetGain(lna)
+	assert.NoError(t, err)
+	assert.Equal(t, float32(4), gain)
+
+	assert.NoError(t, client.Close())
+	assert.NoError(t, <-served)
+}
+
+func TestHelperRx(t *testing.T) {
+	pipeReader, pipeWriter := sdr.Pipe(1000, sdr.SampleFormatI16)
+	client, served := newClient(t, newMock(pipeReader, nil))
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		buf := make(sdr.SamplesI16, 10)
+		for i := range buf {
+			buf[i] = [2]int16{int16(i), -int16(i)}
+		}
+		_, err := pipeWriter.Write(buf)
+		assert.NoError(t, err)
+	}()
+
+	rx, err := client.StartRx()
+	assert.NoError(t, err)
+	assert.Equal(t, sdr.SampleFormatI16, rx.SampleFormat())
+
+	buf := make(sdr.SamplesI16, 10)
+	_, err = sdr.ReadFull(rx, buf)
+	assert.NoError(t, err)
+	assert.Equal(t, [2]int16{9, -9}, buf[9])
+	wg.Wait()
+
+	assert.NoError(t, rx.Close())
+	assert.NoError(t, client.Close())
+	assert.NoError(t, <-served)
+}
+
+func TestHelperTx(t *testing.T) {
+	pipeReader, pipeWriter := sdr.Pipe(1000, sdr.SampleFormatI16)
+	client, served := newClient(t, newMock(nil, pipeWriter))
+
+	tx, err := client.StartTx()
+	assert.NoError(t, err)
+
+	_, err = tx.Write(make(sdr.SamplesC64, 10))
+	assert.Equal(t, sdr.ErrSampleFormatMismatch, err)
+
+	go func() {
+		buf := make(sdr.SamplesI16, 10)
+		buf[3] = [2]int16{3, 4}
+		_, err := tx.Write(buf)
+		assert.NoError(t, err)
+	}()
+
+	buf := make(sdr.SamplesI16, 10)
+	_, err = sdr.ReadFull(pipeReader, buf)
+	assert.NoError(t, err)
+	assert.Equal(t, [2]int16{3, 4}, buf[3])
+
+	assert.NoError(t, tx.Close())
+	assert.NoError(t, client.Close())
+	assert.NoError(t, <-served)
+}
+
+func TestHelperConnectionLost(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	go helper.ServeConn(serverConn, newMock(nil, nil))
+
+	client, err := helper.NewClient(clientConn)
+	assert.NoError(t, err)
+
+	serverConn.Close()
+	assert.Equal(t, helper.ErrHelperExited, client.SetCenterFrequency(rf.MHz))
+}
+
+func TestMain(m *testing.M) {
+	// When re-executed by TestHelperExec, this binary will serve a mock
+	// Sdr to the parent rather than running the tests.
+	if os.Getenv(helper.EnvSocket) != "" {
+		if err := helper.ServeParent(newMock(nil, nil)); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestHelperExec(t *testing.T) {
+	client, err := helper.Exec(exec.Command(os.Args[0]), time.Second*10)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "mocksdr", client.HardwareInfo().Product)
+	assert.NoError(t, client.SetCenterFrequency(rf.MHz))
+	freq, err := client.GetCenterFrequency()
+	assert.NoError(t, err)
+	assert.Equal(t, rf.MHz, freq)
+
+	assert.NoError(t, client.Close())
+}
+
+func TestHelperExecExited(t *testing.T) {
+	_, err := helper.Exec(exec.Command("/bin/false"), time.Second*10)
+	assert.Error(t, err)
+}
+
+// vim: foldmethod=marker
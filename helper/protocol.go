@@ -0,0 +1,271 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package helper
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/internal"
+)
+
+var (
+	// ErrFrameTooLarge will be returned if the remote end sends a frame that
+	// is larger than we're willing to allocate.
+	ErrFrameTooLarge = fmt.Errorf("helper: frame is too large")
+
+	// ErrHelperExited will be returned if the connection to the helper has
+	// gone away, usually because the helper process has exited or crashed.
+	ErrHelperExited = fmt.Errorf("helper: connection to helper has been lost")
+)
+
+// maxFrameLength is the largest payload we'll accept from the remote end,
+// which is there to prevent a confused (or crashing) peer from causing
+// an allocation of up to 4 GiB.
+const maxFrameLength = 64 * 1024 * 1024
+
+// frameType is the first byte of every frame sent over the socket, and
+// describes how the payload should be interpreted.
+type frameType uint8
+
+const (
+	// frameRequest is a JSON encoded request from the Client to the helper.
+	frameRequest frameType = 0x01
+
+	// frameResponse is a JSON encoded response from the helper to the Client.
+	frameResponse frameType = 0x02
+
+	// frameRxSamples is a buffer of IQ samples, in the helper's byte
+	// order, from the helper to the Client.
+	frameRxSamples frameType = 0x03
+
+	// frameRxEnd is sent by the helper when the rx stream has ended. The
+	// payload is the error string, if any.
+	frameRxEnd frameType = 0x04
+
+	// frameTxSamples is a buffer of IQ samples, in the Client's byte
+	// order, from the Client to the helper.
+	frameTxSamples frameType = 0x05
+)
+
+// method is the action the Client is requesting the helper take.
+type method string
+
+const (
+	methodHello              method = "Hello"
+	methodClose              method = "Close"
+	methodSetCenterFrequency method = "SetCenterFrequency"
+	methodGetCenterFrequency method = "GetCenterFrequency"
+	methodSetAutomaticGain   method = "SetAutomaticGain"
+	methodGetGain            method = "GetGain"
+	methodSetGain            method = "SetGain"
+	methodSetSampleRate      method = "SetSampleRate"
+	methodGetSampleRate      method = "GetSampleRate"
+	methodStartRx            method = "StartRx"
+	methodStopRx             method = "StopRx"
+	methodStartTx            method = "StartTx"
+	methodStopTx             method = "StopTx"
+)
+
+// request is sent by the Client to the helper. Only the fields relevant to
+// the Method are set.
+type request struct {
+	Method          method
+	CenterFrequency rf.Hz   `json:",omitempty"`
+	SampleRate      uint    `json:",omitempty"`
+	GainStage       string  `json:",omitempty"`
+	Gain            float32 `json:",omitempty"`
+	Enabled         bool    `json:",omitempty"`
+
+	// ByteOrder is only set on Hello, and is the byte order the Client
+	// will send IQ samples in.
+	ByteOrder string `json:",omitempty"`
+}
+
+// gainStage is the wire (and Client side) representation of an
+// sdr.GainStage that lives in the helper process.
+type gainStage struct {
+	Name      string
+	StageType sdr.GainStageType
+	GainRange [2]float32
+}
+
+// Range implements the sdr.GainStage interface.
+func (gs gainStage) Range() [2]float32 {
+	return gs.GainRange
+}
+
+// Type implements the sdr.GainStage interface.
+func (gs gainStage) Type() sdr.GainStageType {
+	return gs.StageType
+}
+
+// String implements the sdr.GainStage interface.
+func (gs gainStage) String() string {
+	return gs.Name
+}
+
+// response is sent by the helper to the Client for every request.
+type response struct {
+	Error           string           `json:",omitempty"`
+	CenterFrequency rf.Hz            `json:",omitempty"`
+	SampleRate      uint             `json:",omitempty"`
+	Gain            float32          `json:",omitempty"`
+	SampleFormat    sdr.SampleFormat `json:",omitempty"`
+	HardwareInfo    sdr.HardwareInfo
+	GainStages      []gainStage `json:",omitempty"`
+
+	// ByteOrder is only set on Hello, and is the byte order the helper
+	// will send IQ samples in.
+	ByteOrder string `json:",omitempty"`
+}
+
+// knownErrors are errors that are mapped back to the same error value on
+// the Client side, so that callers can check against them directly.
+var knownErrors = []error{
+	sdr.ErrNotSupported,
+	sdr.ErrPipeClosed,
+	sdr.ErrSampleFormatMismatch,
+	sdr.ErrSampleFormatUnknown,
+	io.EOF,
+}
+
+// errToString will convert an error into something we can send over the
+// wire.
+func errToString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// stringToErr will turn an error string from the wire back into an error,
+// returning a well known error value if one matches.
+func stringToErr(s string) error {
+	if s == "" {
+		return nil
+	}
+	for _, err := range knownErrors {
+		if err.Error() == s {
+			return err
+		}
+	}
+	return fmt.Errorf("helper: remote error: %s", s)
+}
+
+// writeFrame will write a single frame to the provided io.Writer. This is
+// not safe to call from more than one goroutine at a time.
+func writeFrame(w io.Writer, ft frameType, payload []byte) error {
+	if len(payload) > maxFrameLength {
+		return ErrFrameTooLarge
+	}
+	var header [5]byte
+	header[0] = byte(ft)
+	binary.BigEndian.PutUint32(header[1:], uint32(len(payload)))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+// writeJSONFrame will encode the provided value as JSON, and write it as a
+// single frame.
+func writeJSONFrame(w io.Writer, ft frameType, v interface{}) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return writeFrame(w, ft, payload)
+}
+
+// readFrame will read a single frame from the provided io.Reader.
+func readFrame(r io.Reader) (frameType, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(header[1:])
+	if length > maxFrameLength {
+		return 0, nil, ErrFrameTooLarge
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return frameType(header[0]), payload, nil
+}
+
+// byteOrder is the name of the native byte order, which each end sends
+// to the other on Hello.
+var byteOrder = internal.NativeEndian.String()
+
+// needsSwap will return true if the remote byte order is known, and is not
+// the same as the native byte order.
+func needsSwap(remote string) bool {
+	return remote != "" && remote != byteOrder
+}
+
+// swapBytes will reverse the byte order of each width sized word of the
+// buffer, in place.
+func swapBytes(buf []byte, width int) {
+	if width < 2 {
+		return
+	}
+	for i := 0; i+width <= len(buf); i += width {
+		word := buf[i : i+width]
+		for j, k := 0, width-1; j < k; j, k = j+1, k-1 {
+			word[j], word[k] = word[k], word[j]
+		}
+	}
+}
+
+// samplesFromBytes will allocate a new Samples buffer, and copy the raw
+// samples from the payload into it. If swap is set, the payload is in the
+// other byte order, and each I and Q value will be swapped first.
+func samplesFromBytes(sf sdr.SampleFormat, payload []byte, swap bool) (sdr.Samples, error) {
+	size := sf.Size()
+	if size == 0 || len(payload)%size != 0 {
+		return nil, sdr.ErrSampleFormatUnknown
+	}
+	if swap {
+		swapBytes(payload, size/2)
+	}
+	samples, err := sdr.MakeSamples(sf, len(payload)/size)
+	if err != nil {
+		return nil, err
+	}
+	if samples.Length() == 0 {
+		return samples, nil
+	}
+	buf, err := sdr.UnsafeSamplesAsBytes(samples)
+	if err != nil {
+		return nil, err
+	}
+	copy(buf, payload)
+	return samples, nil
+}
+
+// vim: foldmethod=marker
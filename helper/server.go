@@ -0,0 +1,305 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package helper
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+
+	"hz.tools/sdr"
+)
+
+// EnvSocket is the environment variable that Exec will set on the helper
+// process, containing the path of the unix socket to connect back to.
+const EnvSocket = "HZ_TOOLS_SDR_HELPER_SOCKET"
+
+// ServeParent will connect to the socket provided by the parent process (via
+// the EnvSocket environment variable), and serve the provided sdr.Sdr over
+// that connection until the parent goes away or requests the Sdr be closed.
+//
+// This is intended to be called from the main function of a helper binary,
+// after the device has been opened.
+func ServeParent(dev sdr.Sdr) error {
+	path := os.Getenv(EnvSocket)
+	if path == "" {
+		return fmt.Errorf("helper: %s is not set; not started by helper.Exec?", EnvSocket)
+	}
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return err
+	}
+	return ServeConn(conn, dev)
+}
+
+// ServeConn will serve the provided sdr.Sdr over the provided connection,
+// until the connection is closed, or the Client requests the Sdr be closed.
+//
+// If the sdr.Sdr also implements sdr.Receiver or sdr.Transmitter, the Client
+// will be able to stream IQ samples from or to the device.
+func ServeConn(conn io.ReadWriteCloser, dev sdr.Sdr) error {
+	s := &server{
+		conn:      conn,
+		dev:       dev,
+		writeLock: &sync.Mutex{},
+		rxDone:    make(chan struct{}),
+	}
+	close(s.rxDone)
+	defer conn.Close()
+	return s.run()
+}
+
+type server struct {
+	conn      io.ReadWriteCloser
+	dev       sdr.Sdr
+	writeLock *sync.Mutex
+
+	rx     sdr.ReadCloser
+	rxDone chan struct{}
+	tx     sdr.WriteCloser
+
+	// swap is set if the Client sends samples in a different byte order.
+	swap bool
+}
+
+func (s *server) writeFrame(ft frameType, payload []byte) error {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+	return writeFrame(s.conn, ft, payload)
+}
+
+func (s *server) writeResponse(resp response) error {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+	return writeJSONFrame(s.conn, frameResponse, resp)
+}
+
+// cleanup will stop any running streams. This is called when the connection
+// to the Client goes away, since nothing will ever be able to stop them
+// otherwise.
+func (s *server) cleanup() {
+	s.stopRx()
+	s.stopTx()
+}
+
+func (s *server) run() error {
+	defer s.cleanup()
+
+	for {
+		ft, payload, err := readFrame(s.conn)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		switch ft {
+		case frameRequest:
+			var req request
+			if err := json.Unmarshal(payload, &req); err != nil {
+				return err
+			}
+			resp := s.handle(req)
+			if err := s.writeResponse(resp); err != nil {
+				return err
+			}
+			if req.Method == methodStartRx && resp.Error == "" {
+				// The stream is only started once the response has been
+				// sent, so that the Client has a chance to start reading
+				// before any samples arrive.
+				go s.streamRx(s.rx, s.rxDone)
+			}
+			if req.Method == methodClose {
+				return nil
+			}
+		case frameTxSamples:
+			if s.tx == nil {
+				// The tx stream has been stopped, but there may be frames
+				// still in flight; drop them on the floor.
+				continue
+			}
+			samples, err := samplesFromBytes(s.tx.SampleFormat(), payload, s.swap)
+			if err != nil {
+				return err
+			}
+			if _, err := s.tx.Write(samples); err != nil {
+				s.stopTx()
+			}
+		default:
+			return fmt.Errorf("helper: unexpected frame type 0x%02x", ft)
+		}
+	}
+}
+
+func (s *server) handle(req request) response {
+	var (
+		resp response
+		err  error
+	)
+
+	switch req.Method {
+	case methodHello:
+		s.swap = needsSwap(req.ByteOrder)
+		resp.ByteOrder = byteOrder
+		resp.HardwareInfo = s.dev.HardwareInfo()
+		resp.SampleFormat = s.dev.SampleFormat()
+		stages, gsErr := s.dev.GetGainStages()
+		if gsErr == nil {
+			for _, stage := range stages {
+				resp.GainStages = append(resp.GainStages, gainStage{
+					Name:      stage.String(),
+					StageType: stage.Type(),
+					GainRange: stage.Range(),
+				})
+			}
+		}
+	case methodClose:
+		s.cleanup()
+		err = s.dev.Close()
+	case methodSetCenterFrequency:
+		err = s.dev.SetCenterFrequency(req.CenterFrequency)
+	case methodGetCenterFrequency:
+		resp.CenterFrequency, err = s.dev.GetCenterFrequency()
+	case methodSetAutomaticGain:
+		err = s.dev.SetAutomaticGain(req.Enabled)
+	case methodGetGain:
+		var stage sdr.GainStage
+		if stage, err = s.gainStage(req.GainStage); err == nil {
+			resp.Gain, err = s.dev.GetGain(stage)
+		}
+	case methodSetGain:
+		var stage sdr.GainStage
+		if stage, err = s.gainStage(req.GainStage); err == nil {
+			err = s.dev.SetGain(stage, req.Gain)
+		}
+	case methodSetSampleRate:
+		err = s.dev.SetSampleRate(req.SampleRate)
+	case methodGetSampleRate:
+		resp.SampleRate, err = s.dev.GetSampleRate()
+	case methodStartRx:
+		err = s.startRx()
+	case methodStopRx:
+		s.stopRx()
+	case methodStartTx:
+		err = s.startTx()
+	case methodStopTx:
+		s.stopTx()
+	default:
+		err = fmt.Errorf("helper: unknown method %q", req.Method)
+	}
+
+	resp.Error = errToString(err)
+	return resp
+}
+
+func (s *server) gainStage(name string) (sdr.GainStage, error) {
+	stages, err := s.dev.GetGainStages()
+	if err != nil {
+		return nil, err
+	}
+	stage, ok := stages.Map()[name]
+	if !ok {
+		return nil, fmt.Errorf("helper: no such GainStage: %s", name)
+	}
+	return stage, nil
+}
+
+func (s *server) startRx() error {
+	if s.rx != nil {
+		return fmt.Errorf("helper: rx is already running")
+	}
+	receiver, ok := s.dev.(sdr.Receiver)
+	if !ok {
+		return sdr.ErrNotSupported
+	}
+	rx, err := receiver.StartRx()
+	if err != nil {
+		return err
+	}
+	s.rx = rx
+	s.rxDone = make(chan struct{})
+	return nil
+}
+
+// streamRx will read from the device, and write frames to the Client until
+// the reader returns an error.
+func (s *server) streamRx(rx sdr.ReadCloser, done chan struct{}) {
+	defer close(done)
+
+	buf, err := sdr.MakeSamples(rx.SampleFormat(), 32*1024)
+	if err == nil {
+		for {
+			var n int
+			n, err = rx.Read(buf)
+			if n > 0 {
+				payload := sdr.MustUnsafeSamplesAsBytes(buf.Slice(0, n))
+				if werr := s.writeFrame(frameRxSamples, payload); werr != nil {
+					rx.Close()
+					return
+				}
+			}
+			if err != nil {
+				break
+			}
+		}
+	}
+
+	s.writeFrame(frameRxEnd, []byte(errToString(err)))
+}
+
+func (s *server) stopRx() {
+	if s.rx == nil {
+		return
+	}
+	s.rx.Close()
+	<-s.rxDone
+	s.rx = nil
+}
+
+func (s *server) startTx() error {
+	if s.tx != nil {
+		return fmt.Errorf("helper: tx is already running")
+	}
+	transmitter, ok := s.dev.(sdr.Transmitter)
+	if !ok {
+		return sdr.ErrNotSupported
+	}
+	tx, err := transmitter.StartTx()
+	if err != nil {
+		return err
+	}
+	s.tx = tx
+	return nil
+}
+
+func (s *server) stopTx() {
+	if s.tx == nil {
+		return
+	}
+	s.tx.Close()
+	s.tx = nil
+}
+
+// vim: foldmethod=marker
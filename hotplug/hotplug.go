@@ -0,0 +1,255 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package hotplug
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"hz.tools/sdr"
+)
+
+// Lister will return the HardwareInfo of every device of a particular type
+// currently plugged in, such as hackrf.List or rtl.List.
+type Lister func() ([]sdr.HardwareInfo, error)
+
+// EventType is the kind of change a Watcher noticed.
+type EventType int
+
+const (
+	// Added means the device was plugged in.
+	Added EventType = iota
+
+	// Removed means the device was unplugged.
+	Removed
+)
+
+// String implements the fmt.Stringer interface.
+func (e EventType) String() string {
+	switch e {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a device being plugged in or unplugged.
+type Event struct {
+	Type EventType
+
+	// Driver is the name of the Lister the device was found by.
+	Driver string
+
+	// Info is the HardwareInfo of the device.
+	Info sdr.HardwareInfo
+}
+
+// Notifier tells a Watcher when the set of devices plugged in may have
+// changed, so that the Listers can be polled right away rather than on the
+// next interval. This is implemented using OS specific notifications, such as
+// hz.tools/sdr/hotplug/libusb or hz.tools/sdr/hotplug/iokit.
+type Notifier interface {
+	// Changes will return a channel which is sent on when a device may have
+	// been plugged in or unplugged. Spurious sends are fine, since they
+	// only cause an extra poll.
+	Changes() <-chan struct{}
+
+	// Close will stop sending notifications.
+	Close() error
+}
+
+// notifySettle is how long to wait after a Notifier fires before polling,
+// since the OS (and the drivers) may take a moment to finish enumerating a
+// device, and a single plug event tends to come with a handful of
+// notifications.
+var notifySettle = 250 * time.Millisecond
+
+// Watcher polls a set of Listers, and sends an Event every time a device
+// shows up or goes away.
+type Watcher struct {
+	listers  map[string]Lister
+	interval time.Duration
+	notifier Notifier
+	events   chan Event
+
+	known map[string]map[sdr.HardwareInfo]bool
+
+	closeOnce *sync.Once
+	done      chan struct{}
+}
+
+// Watch will start a Watcher, which polls each of the provided Listers (keyed
+// by driver name) every interval. Devices which are already plugged in when
+// the Watcher starts will be sent as Added Events.
+//
+// If a Lister returns an error, that driver is skipped until the next poll,
+// rather than reporting all of its devices as Removed.
+func Watch(listers map[string]Lister, interval time.Duration) *Watcher {
+	return WatchNotifier(listers, nil, interval)
+}
+
+// WatchNotifier will start a Watcher like Watch, but the Listers will also
+// be polled shortly after every notification from the Notifier. The interval
+// is still used as a fallback, in case a notification is missed; if the
+// interval is 0, the Listers are only polled when notified.
+//
+// The Notifier will be Closed when the Watcher is.
+func WatchNotifier(
+	listers map[string]Lister,
+	notifier Notifier,
+	interval time.Duration,
+) *Watcher {
+	w := &Watcher{
+		listers:   listers,
+		interval:  interval,
+		notifier:  notifier,
+		events:    make(chan Event),
+		known:     map[string]map[sdr.HardwareInfo]bool{},
+		closeOnce: &sync.Once{},
+		done:      make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Events will return the channel Events are sent on. The channel is closed
+// once the Watcher has been Closed.
+func (w *Watcher) Events() <-chan Event {
+	return w.events
+}
+
+// Close will stop the Watcher.
+func (w *Watcher) Close() error {
+	var err error
+	w.closeOnce.Do(func() {
+		close(w.done)
+		if w.notifier != nil {
+			err = w.notifier.Close()
+		}
+	})
+	return err
+}
+
+func (w *Watcher) run() {
+	defer close(w.events)
+
+	var (
+		tick    <-chan time.Time
+		changes <-chan struct{}
+	)
+	if w.interval > 0 {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	if w.notifier != nil {
+		changes = w.notifier.Changes()
+	}
+
+	for {
+		for _, event := range w.poll() {
+			select {
+			case w.events <- event:
+			case <-w.done:
+				return
+			}
+		}
+		if !w.wait(tick, changes) {
+			return
+		}
+	}
+}
+
+// wait will block until it's time to poll again, returning false if the
+// Watcher was Closed first.
+func (w *Watcher) wait(tick <-chan time.Time, changes <-chan struct{}) bool {
+	var settle <-chan time.Time
+	for {
+		select {
+		case <-tick:
+			return true
+		case <-settle:
+			return true
+		case _, ok := <-changes:
+			if !ok {
+				// The Notifier has gone away, so only the interval is left.
+				changes = nil
+				continue
+			}
+			if settle == nil {
+				settle = time.After(notifySettle)
+			}
+		case <-w.done:
+			return false
+		}
+	}
+}
+
+// poll will list every driver's devices, and return the changes since the
+// last poll, in a stable order.
+func (w *Watcher) poll() []Event {
+	drivers := make([]string, 0, len(w.listers))
+	for driver := range w.listers {
+		drivers = append(drivers, driver)
+	}
+	sort.Strings(drivers)
+
+	events := []Event{}
+	for _, driver := range drivers {
+		infos, err := w.listers[driver]()
+		if err != nil {
+			continue
+		}
+
+		var (
+			before = w.known[driver]
+			now    = map[sdr.HardwareInfo]bool{}
+		)
+		for _, info := range infos {
+			now[info] = true
+			if !before[info] {
+				events = append(events, Event{Type: Added, Driver: driver, Info: info})
+			}
+		}
+
+		removed := []sdr.HardwareInfo{}
+		for info := range before {
+			if !now[info] {
+				removed = append(removed, info)
+			}
+		}
+		sort.Slice(removed, func(i, j int) bool {
+			return removed[i].Serial < removed[j].Serial
+		})
+		for _, info := range removed {
+			events = append(events, Event{Type: Removed, Driver: driver, Info: info})
+		}
+
+		w.known[driver] = now
+	}
+	return events
+}
+
+// vim: foldmethod=marker
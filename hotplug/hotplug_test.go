@@ -0,0 +1,132 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package hotplug_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/hotplug"
+)
+
+type devices struct {
+	lock  *sync.Mutex
+	infos []sdr.HardwareInfo
+	err   error
+}
+
+func (d *devices) set(infos []sdr.HardwareInfo, err error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.infos = infos
+	d.err = err
+}
+
+func (d *devices) list() ([]sdr.HardwareInfo, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.infos, d.err
+}
+
+func next(t *testing.T, w *hotplug.Watcher) hotplug.Event {
+	select {
+	case event := <-w.Events():
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for an event")
+		return hotplug.Event{}
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	var (
+		a = sdr.HardwareInfo{Product: "test", Serial: "a"}
+		b = sdr.HardwareInfo{Product: "test", Serial: "b"}
+		d = &devices{lock: &sync.Mutex{}, infos: []sdr.HardwareInfo{a}}
+	)
+
+	w := hotplug.Watch(map[string]hotplug.Lister{"test": d.list}, time.Millisecond)
+	defer w.Close()
+
+	assert.Equal(t, hotplug.Event{Type: hotplug.Added, Driver: "test", Info: a}, next(t, w))
+
+	// An error from the driver isn't the same as everything going away.
+	d.set(nil, fmt.Errorf("oh no"))
+	time.Sleep(10 * time.Millisecond)
+
+	d.set([]sdr.HardwareInfo{b}, nil)
+	assert.Equal(t, hotplug.Event{Type: hotplug.Added, Driver: "test", Info: b}, next(t, w))
+	assert.Equal(t, hotplug.Event{Type: hotplug.Removed, Driver: "test", Info: a}, next(t, w))
+	assert.Equal(t, "removed", hotplug.Removed.String())
+
+	assert.NoError(t, w.Close())
+	for range w.Events() {
+	}
+}
+
+type notifier struct {
+	changes chan struct{}
+	closed  bool
+}
+
+func (n *notifier) Changes() <-chan struct{} { return n.changes }
+func (n *notifier) Close() error             { n.closed = true; return nil }
+
+func TestWatcherNotifier(t *testing.T) {
+	var (
+		a = sdr.HardwareInfo{Product: "test", Serial: "a"}
+		b = sdr.HardwareInfo{Product: "test", Serial: "b"}
+		d = &devices{lock: &sync.Mutex{}, infos: []sdr.HardwareInfo{a}}
+		n = &notifier{changes: make(chan struct{})}
+	)
+
+	// With no interval, the Listers are only polled when notified.
+	w := hotplug.WatchNotifier(map[string]hotplug.Lister{"test": d.list}, n, 0)
+	defer w.Close()
+	assert.Equal(t, hotplug.Event{Type: hotplug.Added, Driver: "test", Info: a}, next(t, w))
+
+	d.set([]sdr.HardwareInfo{a, b}, nil)
+	select {
+	case event := <-w.Events():
+		t.Fatalf("unexpected event without a notification: %v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	n.changes <- struct{}{}
+	n.changes <- struct{}{}
+	assert.Equal(t, hotplug.Event{Type: hotplug.Added, Driver: "test", Info: b}, next(t, w))
+
+	d.set([]sdr.HardwareInfo{b}, nil)
+	n.changes <- struct{}{}
+	assert.Equal(t, hotplug.Event{Type: hotplug.Removed, Driver: "test", Info: a}, next(t, w))
+
+	assert.NoError(t, w.Close())
+	for range w.Events() {
+	}
+	assert.True(t, n.closed)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,109 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+//go:build darwin
+// +build darwin
+
+package iokit
+
+// #cgo LDFLAGS: -framework CoreFoundation -framework IOKit
+//
+// #include <stdlib.h>
+//
+// #include <CoreFoundation/CoreFoundation.h>
+// #include <IOKit/IOKitLib.h>
+//
+// extern void iokitDeviceCallback(void *refcon);
+//
+// typedef struct iokit_watch {
+//   IONotificationPortRef port;
+//   io_iterator_t added;
+//   io_iterator_t removed;
+// } iokit_watch;
+//
+// // Every object on the iterator has to be read off to arm the notification
+// // again, which is also the case for the devices already plugged in when
+// // the notification is added.
+// static void iokit_drain(io_iterator_t iter) {
+//   io_object_t obj;
+//   while ((obj = IOIteratorNext(iter))) {
+//     IOObjectRelease(obj);
+//   }
+// }
+//
+// static void iokit_callback(void *refcon, io_iterator_t iter) {
+//   iokit_drain(iter);
+//   iokitDeviceCallback(refcon);
+// }
+//
+// iokit_watch *iokit_watch_new(void *refcon, kern_return_t *kr) {
+//   iokit_watch *w = calloc(1, sizeof(iokit_watch));
+//   w->port = IONotificationPortCreate(MACH_PORT_NULL);
+//
+//   // IOServiceAddMatchingNotification consumes a reference to the matching
+//   // dictionary, and we use it twice.
+//   CFMutableDictionaryRef match = IOServiceMatching("IOUSBHostDevice");
+//   CFRetain(match);
+//
+//   *kr = IOServiceAddMatchingNotification(w->port, kIOFirstMatchNotification,
+//     match, iokit_callback, refcon, &w->added);
+//   if (*kr != KERN_SUCCESS) {
+//     CFRelease(match);
+//     IONotificationPortDestroy(w->port);
+//     free(w);
+//     return NULL;
+//   }
+//   iokit_drain(w->added);
+//
+//   *kr = IOServiceAddMatchingNotification(w->port, kIOTerminatedNotification,
+//     match, iokit_callback, refcon, &w->removed);
+//   if (*kr != KERN_SUCCESS) {
+//     IOObjectRelease(w->added);
+//     IONotificationPortDestroy(w->port);
+//     free(w);
+//     return NULL;
+//   }
+//   iokit_drain(w->removed);
+//   return w;
+// }
+//
+// void iokit_watch_attach(iokit_watch *w) {
+//   CFRunLoopAddSource(CFRunLoopGetCurrent(),
+//     IONotificationPortGetRunLoopSource(w->port), kCFRunLoopDefaultMode);
+// }
+//
+// void iokit_watch_detach(iokit_watch *w) {
+//   CFRunLoopRemoveSource(CFRunLoopGetCurrent(),
+//     IONotificationPortGetRunLoopSource(w->port), kCFRunLoopDefaultMode);
+// }
+//
+// void iokit_watch_run(double seconds) {
+//   CFRunLoopRunInMode(kCFRunLoopDefaultMode, seconds, false);
+// }
+//
+// void iokit_watch_free(iokit_watch *w) {
+//   IOObjectRelease(w->added);
+//   IOObjectRelease(w->removed);
+//   IONotificationPortDestroy(w->port);
+//   free(w);
+// }
+import "C"
+
+// vim: foldmethod=marker
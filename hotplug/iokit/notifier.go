@@ -0,0 +1,144 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+//go:build darwin
+// +build darwin
+
+package iokit
+
+// #cgo LDFLAGS: -framework CoreFoundation -framework IOKit
+//
+// #include <mach/mach_error.h>
+// #include <IOKit/IOKitLib.h>
+//
+// typedef struct iokit_watch iokit_watch;
+//
+// iokit_watch *iokit_watch_new(void *refcon, kern_return_t *kr);
+// void iokit_watch_attach(iokit_watch *w);
+// void iokit_watch_detach(iokit_watch *w);
+// void iokit_watch_run(double seconds);
+// void iokit_watch_free(iokit_watch *w);
+import "C"
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/mattn/go-pointer"
+)
+
+// runInterval is how long the run loop will wait for notifications before
+// checking if the Notifier has been Closed.
+var runInterval = 250 * time.Millisecond
+
+// krToErr will take the IOKit kern_return_t and turn that into a go error.
+func krToErr(kr C.kern_return_t) error {
+	if kr != C.KERN_SUCCESS {
+		return fmt.Errorf("iokit: %s", C.GoString(C.mach_error_string(C.mach_error_t(kr))))
+	}
+	return nil
+}
+
+// Notifier implements the hotplug.Notifier interface, using IOKit matching
+// notifications for USB devices, which are handled on a CFRunLoop owned by
+// a goroutine started by New.
+type Notifier struct {
+	watch *C.iokit_watch
+	state unsafe.Pointer
+
+	changes chan struct{}
+	closed  int32
+
+	closeOnce *sync.Once
+	done      chan struct{}
+}
+
+// New will create a Notifier which will notify about any USB device being
+// plugged in or unplugged.
+func New() (*Notifier, error) {
+	n := &Notifier{
+		changes:   make(chan struct{}, 1),
+		closeOnce: &sync.Once{},
+		done:      make(chan struct{}),
+	}
+	n.state = pointer.Save(n)
+
+	var kr C.kern_return_t
+	n.watch = C.iokit_watch_new(n.state, &kr)
+	if err := krToErr(kr); err != nil {
+		pointer.Unref(n.state)
+		return nil, err
+	}
+
+	go n.run()
+	return n, nil
+}
+
+//export iokitDeviceCallback
+func iokitDeviceCallback(ptr unsafe.Pointer) {
+	n := pointer.Restore(ptr).(*Notifier)
+	select {
+	case n.changes <- struct{}{}:
+	default:
+		// There's already a notification waiting to be read, which will
+		// cause the same poll this one would have.
+	}
+}
+
+// run will run a CFRunLoop until the Notifier is Closed. The run loop
+// belongs to the OS thread, so this goroutine is locked to it, and since the
+// callback is only invoked from inside the run loop, once this returns,
+// nothing else will send on the changes channel.
+func (n *Notifier) run() {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	defer close(n.done)
+	defer close(n.changes)
+
+	C.iokit_watch_attach(n.watch)
+	defer C.iokit_watch_detach(n.watch)
+
+	for atomic.LoadInt32(&n.closed) == 0 {
+		C.iokit_watch_run(C.double(runInterval.Seconds()))
+	}
+}
+
+// Changes implements the hotplug.Notifier interface.
+func (n *Notifier) Changes() <-chan struct{} {
+	return n.changes
+}
+
+// Close implements the hotplug.Notifier interface.
+func (n *Notifier) Close() error {
+	n.closeOnce.Do(func() {
+		atomic.StoreInt32(&n.closed, 1)
+		<-n.done
+		C.iokit_watch_free(n.watch)
+		pointer.Unref(n.state)
+	})
+	return nil
+}
+
+// vim: foldmethod=marker
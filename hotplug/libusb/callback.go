@@ -0,0 +1,52 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package libusb
+
+// #cgo pkg-config: libusb-1.0
+//
+// #include <libusb.h>
+//
+// extern int libusbHotplugCallback(libusb_context *ctx, libusb_device *dev,
+//                                  libusb_hotplug_event event, void *user_data);
+//
+// int libusb_hotplug_callback(libusb_context *ctx, libusb_device *dev,
+//                             libusb_hotplug_event event, void *user_data) {
+//   return libusbHotplugCallback(ctx, dev, event, user_data);
+// }
+//
+// int libusb_hotplug_register(libusb_context *ctx, int vendor_id,
+//                             int product_id, void *user_data,
+//                             libusb_hotplug_callback_handle *handle) {
+//   return libusb_hotplug_register_callback(
+//     ctx,
+//     LIBUSB_HOTPLUG_EVENT_DEVICE_ARRIVED | LIBUSB_HOTPLUG_EVENT_DEVICE_LEFT,
+//     0,
+//     vendor_id,
+//     product_id,
+//     LIBUSB_HOTPLUG_MATCH_ANY,
+//     libusb_hotplug_callback,
+//     user_data,
+//     handle
+//   );
+// }
+import "C"
+
+// vim: foldmethod=marker
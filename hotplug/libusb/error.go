@@ -0,0 +1,47 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package libusb
+
+// #cgo pkg-config: libusb-1.0
+//
+// #include <libusb.h>
+import "C"
+
+import (
+	"fmt"
+)
+
+var (
+	// ErrNotSupported will be returned if libusb can't send hotplug
+	// notifications on this platform, such as on Windows.
+	ErrNotSupported = fmt.Errorf("libusb: hotplug is not supported on this platform")
+)
+
+// rvToErr will take the libusb error return code and turn that into
+// a go error.
+func rvToErr(rv C.int) error {
+	if rv < 0 {
+		return fmt.Errorf("libusb: %s", C.GoString(C.libusb_error_name(rv)))
+	}
+	return nil
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,176 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package libusb
+
+// #cgo pkg-config: libusb-1.0
+//
+// #include <libusb.h>
+//
+// int libusb_hotplug_register(libusb_context *ctx, int vendor_id,
+//                             int product_id, void *user_data,
+//                             libusb_hotplug_callback_handle *handle);
+import "C"
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/mattn/go-pointer"
+)
+
+// Options will configure which devices a Notifier will notify about.
+type Options struct {
+	// VendorID is the USB vendor ID of the devices to watch. If 0, devices
+	// from any vendor will be watched.
+	VendorID uint16
+
+	// ProductID is the USB product ID of the devices to watch. If 0, devices
+	// with any product ID will be watched.
+	ProductID uint16
+}
+
+func match(id uint16) C.int {
+	if id == 0 {
+		return C.LIBUSB_HOTPLUG_MATCH_ANY
+	}
+	return C.int(id)
+}
+
+// Notifier implements the hotplug.Notifier interface, using libusb hotplug
+// callbacks, which are handled on a goroutine started by New.
+type Notifier struct {
+	ctx    *C.libusb_context
+	handle C.libusb_hotplug_callback_handle
+	state  unsafe.Pointer
+
+	changes chan struct{}
+	closed  int32
+
+	closeOnce *sync.Once
+	done      chan struct{}
+}
+
+// New will create a Notifier which will notify about any USB device being
+// plugged in or unplugged.
+func New() (*Notifier, error) {
+	return NewWithOptions(Options{})
+}
+
+// NewWithOptions will create a Notifier which will notify about the USB
+// devices matching the provided Options being plugged in or unplugged.
+func NewWithOptions(opts Options) (*Notifier, error) {
+	var ctx *C.libusb_context
+	if err := rvToErr(C.libusb_init(&ctx)); err != nil {
+		return nil, err
+	}
+
+	if C.libusb_has_capability(C.LIBUSB_CAP_HAS_HOTPLUG) == 0 {
+		C.libusb_exit(ctx)
+		return nil, ErrNotSupported
+	}
+
+	n := &Notifier{
+		ctx:       ctx,
+		changes:   make(chan struct{}, 1),
+		closeOnce: &sync.Once{},
+		done:      make(chan struct{}),
+	}
+	n.state = pointer.Save(n)
+
+	if err := rvToErr(C.libusb_hotplug_register(
+		ctx,
+		match(opts.VendorID),
+		match(opts.ProductID),
+		n.state,
+		&n.handle,
+	)); err != nil {
+		pointer.Unref(n.state)
+		C.libusb_exit(ctx)
+		return nil, err
+	}
+
+	go n.run()
+	return n, nil
+}
+
+//export libusbHotplugCallback
+func libusbHotplugCallback(
+	ctx *C.libusb_context,
+	dev *C.libusb_device,
+	event C.libusb_hotplug_event,
+	ptr unsafe.Pointer,
+) C.int {
+	n := pointer.Restore(ptr).(*Notifier)
+	select {
+	case n.changes <- struct{}{}:
+	default:
+		// There's already a notification waiting to be read, which will
+		// cause the same poll this one would have.
+	}
+
+	// Returning anything other than 0 would deregister the callback.
+	return 0
+}
+
+// run will handle libusb events until the Notifier is Closed. libusb only
+// invokes the hotplug callback from inside libusb_handle_events, so once
+// this returns, nothing else will send on the changes channel.
+func (n *Notifier) run() {
+	defer close(n.done)
+	defer close(n.changes)
+
+	tv := C.struct_timeval{tv_sec: 1}
+	for atomic.LoadInt32(&n.closed) == 0 {
+		rv := C.libusb_handle_events_timeout_completed(n.ctx, &tv, nil)
+		if rv == C.LIBUSB_ERROR_INTERRUPTED {
+			continue
+		}
+		if err := rvToErr(rv); err != nil {
+			log.Printf("libusb: stopping hotplug notifications: %s", err)
+			return
+		}
+	}
+}
+
+// Changes implements the hotplug.Notifier interface.
+func (n *Notifier) Changes() <-chan struct{} {
+	return n.changes
+}
+
+// Close implements the hotplug.Notifier interface.
+func (n *Notifier) Close() error {
+	n.closeOnce.Do(func() {
+		atomic.StoreInt32(&n.closed, 1)
+
+		// Deregistering the callback will also wake up the event loop, so
+		// we don't have to wait out the timeout.
+		C.libusb_hotplug_deregister_callback(n.ctx, n.handle)
+		<-n.done
+
+		pointer.Unref(n.state)
+		C.libusb_exit(n.ctx)
+	})
+	return nil
+}
+
+// vim: foldmethod=marker
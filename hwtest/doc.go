@@ -0,0 +1,36 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+// Package hwtest contains a standard battery of checks to run against real,
+// attached SDR hardware, to catch driver regressions that can't be caught
+// by tests against a mock.
+//
+// Each driver has a test (only built with the `hwtest` build tag) that
+// opens the first attached device, and runs the battery using RunTest. This
+// will do a tune sweep, a sample rate sweep, measure rx throughput, and, if
+// asked to, transmit a tone and look for it on the receive side.
+//
+//	go test -tags=hwtest ./rtl/
+//
+// If the HWTEST_REPORT environment variable is set to a directory, a
+// report for each device will be written into it.
+package hwtest
+
+// vim: foldmethod=marker
@@ -0,0 +1,394 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package hwtest
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"os"
+	"time"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/testutils"
+)
+
+// Config controls which checks are run, and how.
+type Config struct {
+	// Frequencies to set and read back in the tune sweep. If empty,
+	// 100 MHz, 433.92 MHz and 915 MHz are used.
+	Frequencies []rf.Hz
+
+	// FrequencyTolerance is how far the frequency read back may be from the
+	// one that was set. If 0, this will default to 1 kHz.
+	FrequencyTolerance rf.Hz
+
+	// SampleRates to set and read back in the rate sweep. The last one is
+	// used for the rx throughput and tx loopback checks. If empty, 1.024
+	// Msps and 2.048 Msps are used.
+	SampleRates []uint
+
+	// RxDuration is how long to stream samples for when measuring the rx
+	// throughput. If 0, this will default to 2 seconds.
+	RxDuration time.Duration
+
+	// RateTolerance is the fraction of the sample rate that the measured
+	// throughput may be off by. If 0, this will default to 0.05 (5%).
+	RateTolerance float64
+
+	// Loopback will enable the tx loopback check, which requires that the
+	// TX port be cabled to the RX port, through an attenuator.
+	Loopback bool
+
+	// LoopbackFrequency is the center frequency to use for the tx loopback
+	// check. If 0, the first of the Frequencies is used.
+	LoopbackFrequency rf.Hz
+
+	// LoopbackSNR is the minimum SNR (in dB) that the transmitted tone needs
+	// to be received with. If 0, this will default to 10 dB.
+	LoopbackSNR float64
+}
+
+func (c Config) getFrequencies() []rf.Hz {
+	if len(c.Frequencies) == 0 {
+		return []rf.Hz{100 * rf.MHz, 433.92 * rf.MHz, 915 * rf.MHz}
+	}
+	return c.Frequencies
+}
+
+func (c Config) getFrequencyTolerance() rf.Hz {
+	if c.FrequencyTolerance == 0 {
+		return rf.KHz
+	}
+	return c.FrequencyTolerance
+}
+
+func (c Config) getSampleRates() []uint {
+	if len(c.SampleRates) == 0 {
+		return []uint{1024000, 2048000}
+	}
+	return c.SampleRates
+}
+
+func (c Config) getSampleRate() uint {
+	rates := c.getSampleRates()
+	return rates[len(rates)-1]
+}
+
+func (c Config) getRxDuration() time.Duration {
+	if c.RxDuration == 0 {
+		return time.Second * 2
+	}
+	return c.RxDuration
+}
+
+func (c Config) getRateTolerance() float64 {
+	if c.RateTolerance == 0 {
+		return 0.05
+	}
+	return c.RateTolerance
+}
+
+func (c Config) getLoopbackFrequency() rf.Hz {
+	if c.LoopbackFrequency == 0 {
+		return c.getFrequencies()[0]
+	}
+	return c.LoopbackFrequency
+}
+
+func (c Config) getLoopbackSNR() float64 {
+	if c.LoopbackSNR == 0 {
+		return 10
+	}
+	return c.LoopbackSNR
+}
+
+// ConfigFromEnv will return the provided Config, with Loopback enabled if
+// the HWTEST_LOOPBACK environment variable is set.
+func ConfigFromEnv(c Config) Config {
+	if os.Getenv("HWTEST_LOOPBACK") != "" {
+		c.Loopback = true
+	}
+	return c
+}
+
+// check is a single check in the battery. It returns a human readable
+// detail string, and an error if the check failed. If the check can't be
+// run on this device, a skipped error is returned.
+type check struct {
+	name string
+	run  func(sdr.Sdr, Config) (string, error)
+}
+
+// skipped is returned by a check which doesn't apply to the device.
+type skipped string
+
+func (s skipped) Error() string {
+	return string(s)
+}
+
+var checks = []check{
+	{"tune sweep", tuneSweep},
+	{"rate sweep", rateSweep},
+	{"rx throughput", rxThroughput},
+	{"tx loopback", txLoopback},
+}
+
+// Run will run the battery of checks against the provided device, and
+// return a Report of the results. The device will be left tuned to
+// whatever the last check set it to.
+func Run(dev sdr.Sdr, cfg Config) Report {
+	report := Report{
+		HardwareInfo: dev.HardwareInfo(),
+		SampleFormat: dev.SampleFormat(),
+		Started:      time.Now(),
+	}
+
+	for _, c := range checks {
+		start := time.Now()
+		detail, err := c.run(dev, cfg)
+		result := Result{
+			Name:     c.name,
+			Detail:   detail,
+			Duration: time.Since(start),
+		}
+		switch err := err.(type) {
+		case nil:
+			result.Status = StatusPassed
+		case skipped:
+			result.Status = StatusSkipped
+			result.Detail = err.Error()
+		default:
+			result.Status = StatusFailed
+			result.Detail = err.Error()
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	return report
+}
+
+func tuneSweep(dev sdr.Sdr, cfg Config) (string, error) {
+	tolerance := cfg.getFrequencyTolerance()
+	freqs := cfg.getFrequencies()
+	for _, freq := range freqs {
+		if err := dev.SetCenterFrequency(freq); err != nil {
+			return "", fmt.Errorf("setting %s: %s", freq, err)
+		}
+		got, err := dev.GetCenterFrequency()
+		if err != nil {
+			return "", fmt.Errorf("reading back %s: %s", freq, err)
+		}
+		if diff := got - freq; diff > tolerance || diff < -tolerance {
+			return "", fmt.Errorf("set %s, but read back %s", freq, got)
+		}
+	}
+	return fmt.Sprintf("%d frequencies from %s to %s", len(freqs), freqs[0], freqs[len(freqs)-1]), nil
+}
+
+func rateSweep(dev sdr.Sdr, cfg Config) (string, error) {
+	rates := cfg.getSampleRates()
+	for _, rate := range rates {
+		if err := dev.SetSampleRate(rate); err != nil {
+			return "", fmt.Errorf("setting %d sps: %s", rate, err)
+		}
+		got, err := dev.GetSampleRate()
+		if err != nil {
+			return "", fmt.Errorf("reading back %d sps: %s", rate, err)
+		}
+		if math.Abs(float64(got)-float64(rate)) > float64(rate)*0.001 {
+			return "", fmt.Errorf("set %d sps, but read back %d sps", rate, got)
+		}
+	}
+	return fmt.Sprintf("%d rates from %d to %d sps", len(rates), rates[0], rates[len(rates)-1]), nil
+}
+
+func rxThroughput(dev sdr.Sdr, cfg Config) (string, error) {
+	receiver, ok := dev.(sdr.Receiver)
+	if !ok {
+		return "", skipped("not a receiver")
+	}
+
+	rate := cfg.getSampleRate()
+	if err := dev.SetSampleRate(rate); err != nil {
+		return "", err
+	}
+
+	rx, err := receiver.StartRx()
+	if err != nil {
+		return "", err
+	}
+	defer rx.Close()
+
+	buf, err := sdr.MakeSamples(rx.SampleFormat(), 16*1024)
+	if err != nil {
+		return "", err
+	}
+
+	// Give the device a moment to get going, since some drivers will
+	// deliver a burst of buffered samples on start.
+	warmup := time.Now().Add(cfg.getRxDuration() / 8)
+	for time.Now().Before(warmup) {
+		if _, err := rx.Read(buf); err != nil {
+			return "", err
+		}
+	}
+
+	var (
+		total    int
+		start    = time.Now()
+		duration = cfg.getRxDuration()
+	)
+	for time.Since(start) < duration {
+		n, err := rx.Read(buf)
+		total += n
+		if err != nil {
+			return "", err
+		}
+	}
+
+	var (
+		elapsed  = time.Since(start)
+		measured = float64(total) / elapsed.Seconds()
+		detail   = fmt.Sprintf("%.0f sps measured, %d sps nominal", measured, rate)
+	)
+	if math.Abs(measured-float64(rate)) > float64(rate)*cfg.getRateTolerance() {
+		return "", fmt.Errorf("%s", detail)
+	}
+	return detail, nil
+}
+
+func txLoopback(dev sdr.Sdr, cfg Config) (string, error) {
+	if !cfg.Loopback {
+		return "", skipped("loopback not enabled")
+	}
+	transceiver, ok := dev.(sdr.Transceiver)
+	if !ok {
+		return "", skipped("not a transceiver")
+	}
+
+	var (
+		rate = cfg.getSampleRate()
+		tone = rf.Hz(rate / 8)
+	)
+	if err := dev.SetCenterFrequency(cfg.getLoopbackFrequency()); err != nil {
+		return "", err
+	}
+	if err := dev.SetSampleRate(rate); err != nil {
+		return "", err
+	}
+
+	tx, err := transceiver.StartTx()
+	if err != nil {
+		return "", err
+	}
+
+	// The tone is 1/8th of the sample rate, so 1024 samples is a whole
+	// number of cycles, and can be written over and over again.
+	cw := make(sdr.SamplesC64, 1024)
+	testutils.CW(cw, tone, int(rate), 0)
+	txBuf, err := sdr.MakeSamples(tx.SampleFormat(), len(cw))
+	if err != nil {
+		tx.Close()
+		return "", err
+	}
+	if _, err := sdr.ConvertBuffer(txBuf, cw); err != nil {
+		tx.Close()
+		return "", err
+	}
+
+	stop := make(chan struct{})
+	txDone := make(chan error, 1)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				txDone <- nil
+				return
+			default:
+			}
+			if _, err := tx.Write(txBuf); err != nil {
+				txDone <- err
+				return
+			}
+		}
+	}()
+	defer func() {
+		close(stop)
+		tx.Close()
+		<-txDone
+	}()
+
+	rx, err := transceiver.StartRx()
+	if err != nil {
+		return "", skipped(fmt.Sprintf("can't rx while transmitting: %s", err))
+	}
+	defer rx.Close()
+
+	rxBuf, err := sdr.MakeSamples(rx.SampleFormat(), int(rate/4))
+	if err != nil {
+		return "", err
+	}
+	// Throw away the first chunk, while everything settles.
+	if _, err := sdr.ReadFull(rx, rxBuf); err != nil {
+		return "", err
+	}
+	if _, err := sdr.ReadFull(rx, rxBuf); err != nil {
+		return "", err
+	}
+
+	iq := make(sdr.SamplesC64, rxBuf.Length())
+	if _, err := sdr.ConvertBuffer(iq, rxBuf); err != nil {
+		return "", err
+	}
+
+	snr := toneSNR(iq, tone, rate)
+	detail := fmt.Sprintf("tone at +%s received at %.1f dB SNR", tone, snr)
+	if snr < cfg.getLoopbackSNR() {
+		return "", fmt.Errorf("%s", detail)
+	}
+	return detail, nil
+}
+
+// toneSNR will return the power of the tone at freq, relative to the power
+// of everything else, in dB.
+func toneSNR(iq sdr.SamplesC64, freq rf.Hz, rate uint) float64 {
+	var (
+		corr  complex128
+		total float64
+		step  = -2 * math.Pi * float64(freq) / float64(rate)
+	)
+	for i, s := range iq {
+		v := complex128(s)
+		corr += v * cmplx.Rect(1, step*float64(i))
+		total += real(v)*real(v) + imag(v)*imag(v)
+	}
+	n := float64(len(iq))
+	tone := math.Pow(cmplx.Abs(corr)/n, 2)
+	noise := total/n - tone
+	if noise <= 0 {
+		return math.Inf(1)
+	}
+	return 10 * math.Log10(tone/noise)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,135 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package hwtest_test
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/hwtest"
+	"hz.tools/sdr/mock"
+)
+
+type zeroReader struct{}
+
+func (zeroReader) Read(s sdr.Samples) (int, error) { return s.Length(), nil }
+func (zeroReader) Close() error                    { return nil }
+func (zeroReader) SampleRate() uint                { return 1024000 }
+func (zeroReader) SampleFormat() sdr.SampleFormat  { return sdr.SampleFormatI16 }
+
+func status(report hwtest.Report) map[string]hwtest.Status {
+	ret := map[string]hwtest.Status{}
+	for _, result := range report.Results {
+		ret[result.Name] = result.Status
+	}
+	return ret
+}
+
+func TestRun(t *testing.T) {
+	dev := mock.New(mock.Config{
+		SampleRate:   1024000,
+		SampleFormat: sdr.SampleFormatI16,
+		Rx: func(t sdr.Transceiver) (sdr.ReadCloser, error) {
+			return mock.Clock{}.Reader(zeroReader{}, 1024000), nil
+		},
+	})
+
+	report := hwtest.Run(dev, hwtest.Config{
+		SampleRates: []uint{1024000},
+		RxDuration:  time.Second / 4,
+	})
+	assert.False(t, report.Failed())
+	assert.Equal(t, map[string]hwtest.Status{
+		"tune sweep":    hwtest.StatusPassed,
+		"rate sweep":    hwtest.StatusPassed,
+		"rx throughput": hwtest.StatusPassed,
+		"tx loopback":   hwtest.StatusSkipped,
+	}, status(report))
+
+	buf := &bytes.Buffer{}
+	_, err := report.WriteTo(buf)
+	assert.NoError(t, err)
+	assert.True(t, strings.Contains(buf.String(), "| rx throughput | pass |"))
+}
+
+func TestRunThroughput(t *testing.T) {
+	// The device claims to be at 2 Msps, but only delivers 1 Msps.
+	dev := mock.New(mock.Config{
+		SampleFormat: sdr.SampleFormatI16,
+		Rx: func(t sdr.Transceiver) (sdr.ReadCloser, error) {
+			return mock.Clock{}.Reader(zeroReader{}, 1024000), nil
+		},
+	})
+
+	report := hwtest.Run(dev, hwtest.Config{
+		Frequencies: []rf.Hz{rf.MHz},
+		SampleRates: []uint{2048000},
+		RxDuration:  time.Second / 4,
+	})
+	assert.True(t, report.Failed())
+	assert.Equal(t, hwtest.StatusFailed, status(report)["rx throughput"])
+}
+
+func TestRunLoopback(t *testing.T) {
+	const rate = 1024000
+
+	// Anything transmitted comes back on rx; if nothing is being
+	// transmitted, rx is all zeros.
+	var (
+		lock     = &sync.Mutex{}
+		loopback sdr.ReadCloser
+	)
+	dev := mock.New(mock.Config{
+		SampleRate:   rate,
+		SampleFormat: sdr.SampleFormatI16,
+		Rx: func(sdr.Transceiver) (sdr.ReadCloser, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			if loopback != nil {
+				return loopback, nil
+			}
+			return mock.Clock{}.Reader(zeroReader{}, rate), nil
+		},
+		Tx: func(sdr.Transceiver) (sdr.WriteCloser, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			pipeReader, pipeWriter := sdr.Pipe(rate, sdr.SampleFormatI16)
+			loopback = pipeReader
+			return pipeWriter, nil
+		},
+	})
+
+	report := hwtest.Run(dev, hwtest.Config{
+		SampleRates: []uint{rate},
+		RxDuration:  time.Second / 4,
+		Loopback:    true,
+	})
+	assert.Equal(t, hwtest.StatusPassed, status(report)["tx loopback"])
+}
+
+// vim: foldmethod=marker
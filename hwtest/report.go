@@ -0,0 +1,96 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package hwtest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+
+	"hz.tools/sdr"
+)
+
+// Status is the outcome of a single check.
+type Status string
+
+const (
+	// StatusPassed is set when the check ran, and passed.
+	StatusPassed Status = "pass"
+
+	// StatusFailed is set when the check ran, and failed.
+	StatusFailed Status = "FAIL"
+
+	// StatusSkipped is set when the check doesn't apply to the device, or
+	// wasn't enabled.
+	StatusSkipped Status = "skip"
+)
+
+// Result is the outcome of a single check against the device.
+type Result struct {
+	Name     string
+	Status   Status
+	Detail   string
+	Duration time.Duration
+}
+
+// Report contains the results of running the battery against a device.
+type Report struct {
+	HardwareInfo sdr.HardwareInfo
+	SampleFormat sdr.SampleFormat
+	Started      time.Time
+	Results      []Result
+}
+
+// Failed will return true if any of the checks failed.
+func (r Report) Failed() bool {
+	for _, result := range r.Results {
+		if result.Status == StatusFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteTo will write the Report as a Markdown document.
+func (r Report) WriteTo(w io.Writer) (int64, error) {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "# %s %s (%s)\n\n",
+		r.HardwareInfo.Manufacturer,
+		r.HardwareInfo.Product,
+		r.HardwareInfo.Serial,
+	)
+	fmt.Fprintf(buf, "Run at %s, sample format %s.\n\n",
+		r.Started.Format(time.RFC3339), r.SampleFormat)
+	fmt.Fprintf(buf, "| Check | Status | Duration | Detail |\n")
+	fmt.Fprintf(buf, "|-------|--------|----------|--------|\n")
+	for _, result := range r.Results {
+		fmt.Fprintf(buf, "| %s | %s | %s | %s |\n",
+			result.Name,
+			result.Status,
+			result.Duration.Round(time.Millisecond),
+			result.Detail,
+		)
+	}
+	return buf.WriteTo(w)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,85 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package hwtest
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"hz.tools/sdr"
+)
+
+// reportName will return a file name for the device's report.
+func reportName(info sdr.HardwareInfo) string {
+	name := strings.Join([]string{info.Manufacturer, info.Product, info.Serial}, "-")
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-':
+			return r
+		}
+		return '_'
+	}, name)
+	return name + ".md"
+}
+
+// RunTest will run the battery of checks against the provided device
+// (see Run) as part of a Go test, failing the test if any of the checks
+// fail, and logging the Report.
+//
+// If the HWTEST_REPORT environment variable is set to a directory, the
+// Report will be written into it as well.
+func RunTest(t *testing.T, dev sdr.Sdr, cfg Config) Report {
+	report := Run(dev, ConfigFromEnv(cfg))
+
+	buf := &bytes.Buffer{}
+	report.WriteTo(buf)
+	t.Logf("\n%s", buf.String())
+
+	if dir := os.Getenv("HWTEST_REPORT"); dir != "" {
+		path := filepath.Join(dir, reportName(report.HardwareInfo))
+		if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			t.Errorf("hwtest: failed to write report: %s", err)
+		}
+	}
+
+	for _, result := range report.Results {
+		if result.Status == StatusFailed {
+			t.Errorf("hwtest: %s failed: %s", result.Name, result.Detail)
+		}
+	}
+	return report
+}
+
+// Skip will skip the test if the device could not be opened, which is
+// the expected outcome when running with the hwtest tag on a host without
+// that kind of device attached.
+func Skip(t *testing.T, err error) {
+	if err != nil {
+		t.Skip(fmt.Sprintf("hwtest: no device: %s", err))
+	}
+}
+
+// vim: foldmethod=marker
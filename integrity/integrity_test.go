@@ -0,0 +1,153 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package integrity_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/integrity"
+)
+
+// sliceReader returns the provided buffers, one per Read.
+type sliceReader struct {
+	format  sdr.SampleFormat
+	buffers []sdr.Samples
+}
+
+func (r *sliceReader) Read(s sdr.Samples) (int, error) {
+	if len(r.buffers) == 0 {
+		return 0, sdr.ErrPipeClosed
+	}
+	n, err := sdr.CopySamples(s, r.buffers[0])
+	r.buffers = r.buffers[1:]
+	return n, err
+}
+
+func (r *sliceReader) SampleRate() uint               { return 1000 }
+func (r *sliceReader) SampleFormat() sdr.SampleFormat { return r.format }
+func (r *sliceReader) Close() error                   { return nil }
+
+// prbs will read count buffers of length samples from a PRBS reader.
+func prbs(t *testing.T, format sdr.SampleFormat, count, length int) []sdr.Samples {
+	r, err := integrity.NewPRBSReader(1000, format)
+	assert.NoError(t, err)
+	ret := []sdr.Samples{}
+	for i := 0; i < count; i++ {
+		buf, err := sdr.MakeSamples(format, length)
+		assert.NoError(t, err)
+		_, err = sdr.ReadFull(r, buf)
+		assert.NoError(t, err)
+		ret = append(ret, buf)
+	}
+	return ret
+}
+
+func verify(t *testing.T, v *integrity.Verifier, length int) integrity.Stats {
+	buf, err := sdr.MakeSamples(v.SampleFormat(), length)
+	assert.NoError(t, err)
+	for {
+		if _, err := v.Read(buf); err != nil {
+			break
+		}
+	}
+	return v.Stats()
+}
+
+func TestPRBS(t *testing.T) {
+	for _, format := range []sdr.SampleFormat{
+		sdr.SampleFormatU8,
+		sdr.SampleFormatI8,
+		sdr.SampleFormatI16,
+		sdr.SampleFormatC64,
+	} {
+		buffers := prbs(t, format, 20, 5000)
+		v, err := integrity.NewPRBSVerifier(&sliceReader{format: format, buffers: buffers})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(32767), v.Period())
+
+		stats := verify(t, v, 5000)
+		assert.Equal(t, int64(100000), stats.Samples, "%s", format)
+		assert.Equal(t, int64(0), stats.Dropped, "%s", format)
+		assert.Equal(t, int64(0), stats.Corrupt, "%s", format)
+		assert.Equal(t, 0.0, stats.DropRate())
+	}
+}
+
+func TestPRBSDrops(t *testing.T) {
+	buffers := prbs(t, sdr.SampleFormatC64, 10, 1000)
+
+	// Drop a whole buffer, and part of another.
+	buffers = append(buffers[:3], buffers[4:]...)
+	buffers[5] = buffers[5].Slice(0, 900)
+
+	// And mangle a sample.
+	buffers[7].(sdr.SamplesC64)[10] = complex(0.5, 0.5)
+
+	v, err := integrity.NewPRBSVerifier(&sliceReader{
+		format:  sdr.SampleFormatC64,
+		buffers: buffers,
+	})
+	assert.NoError(t, err)
+
+	stats := verify(t, v, 1000)
+	assert.Equal(t, int64(8900), stats.Samples)
+	assert.Equal(t, int64(1), stats.Corrupt)
+	assert.Equal(t, []integrity.Drop{
+		{Offset: 3000, Samples: 1000},
+		{Offset: 5900, Samples: 100},
+	}, stats.Drops)
+	assert.Equal(t, int64(1100), stats.Dropped)
+	assert.InDelta(t, 0.11, stats.DropRate(), 1e-9)
+}
+
+func counter(start, length int) sdr.SamplesU8 {
+	ret := make(sdr.SamplesU8, length)
+	for i := range ret {
+		ret[i] = [2]uint8{uint8(start + i*2), uint8(start + i*2 + 1)}
+	}
+	return ret
+}
+
+func TestCounter(t *testing.T) {
+	v, err := integrity.NewCounterVerifier(&sliceReader{
+		format: sdr.SampleFormatU8,
+		buffers: []sdr.Samples{
+			counter(10, 100),
+			counter(210, 100),
+			counter(410+20, 100),
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(128), v.Period())
+
+	stats := verify(t, v, 100)
+	assert.Equal(t, int64(300), stats.Samples)
+	assert.Equal(t, int64(0), stats.Corrupt)
+	assert.Equal(t, []integrity.Drop{{Offset: 200, Samples: 10}}, stats.Drops)
+
+	_, err = integrity.NewCounterVerifier(&sliceReader{format: sdr.SampleFormatC64})
+	assert.Equal(t, sdr.ErrSampleFormatMismatch, err)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,107 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package integrity
+
+import (
+	"io"
+	"sync"
+
+	"hz.tools/sdr"
+)
+
+type prbsReader struct {
+	lock         *sync.Mutex
+	sampleRate   uint
+	sampleFormat sdr.SampleFormat
+	pos          int64
+	closed       bool
+}
+
+// NewPRBSReader will create an sdr.ReadCloser which returns a PRBS15
+// sequence (8 bits in each of I and Q), to be written into a transport,
+// and checked on the far side by a Verifier returned by NewPRBSVerifier.
+//
+// The sequence is scaled to be carried exactly by each SampleFormat, so the
+// transport must not change the format, or the values of the samples.
+func NewPRBSReader(sampleRate uint, sampleFormat sdr.SampleFormat) (sdr.ReadCloser, error) {
+	switch sampleFormat {
+	case sdr.SampleFormatU8, sdr.SampleFormatI8, sdr.SampleFormatI16, sdr.SampleFormatC64, sdr.SampleFormatC128:
+	default:
+		return nil, sdr.ErrSampleFormatUnknown
+	}
+	return &prbsReader{
+		lock:         &sync.Mutex{},
+		sampleRate:   sampleRate,
+		sampleFormat: sampleFormat,
+	}, nil
+}
+
+// SampleRate implements the sdr.Reader interface.
+func (p *prbsReader) SampleRate() uint {
+	return p.sampleRate
+}
+
+// SampleFormat implements the sdr.Reader interface.
+func (p *prbsReader) SampleFormat() sdr.SampleFormat {
+	return p.sampleFormat
+}
+
+// Close implements the sdr.Closer interface.
+func (p *prbsReader) Close() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.closed = true
+	return nil
+}
+
+// Read implements the sdr.Reader interface.
+func (p *prbsReader) Read(s sdr.Samples) (int, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.closed {
+		return 0, io.EOF
+	}
+	if s.Format() != p.sampleFormat {
+		return 0, sdr.ErrSampleFormatMismatch
+	}
+
+	for i := 0; i < s.Length(); i++ {
+		b0, b1 := prbs15.at(p.pos), prbs15.at(p.pos+1)
+		p.pos = (p.pos + 2) % prbs15.period()
+
+		switch s := s.(type) {
+		case sdr.SamplesU8:
+			s[i] = [2]uint8{b0, b1}
+		case sdr.SamplesI8:
+			s[i] = [2]int8{int8(b0), int8(b1)}
+		case sdr.SamplesI16:
+			s[i] = [2]int16{int16(int8(b0)), int16(int8(b1))}
+		case sdr.SamplesC64:
+			s[i] = complex(float32(int8(b0))/128, float32(int8(b1))/128)
+		case sdr.SamplesC128:
+			s[i] = complex(float64(int8(b0))/128, float64(int8(b1))/128)
+		}
+	}
+	return s.Length(), nil
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,97 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package integrity
+
+// sequence is a repeating sequence of bytes, where any two consecutive
+// bytes are enough to tell where in the sequence they came from.
+type sequence struct {
+	bytes []byte
+
+	// index maps a pair of consecutive bytes (first byte in the high bits)
+	// to the position of the first byte, or -1 if that pair doesn't occur.
+	index []int32
+}
+
+func newSequence(bytes []byte) *sequence {
+	s := &sequence{
+		bytes: bytes,
+		index: make([]int32, 1<<16),
+	}
+	for i := range s.index {
+		s.index[i] = -1
+	}
+	for i := range bytes {
+		s.index[s.pair(bytes[i], s.at(int64(i)+1))] = int32(i)
+	}
+	return s
+}
+
+func (s *sequence) pair(b0, b1 byte) int {
+	return int(b0)<<8 | int(b1)
+}
+
+// period is the length of the sequence before it repeats.
+func (s *sequence) period() int64 {
+	return int64(len(s.bytes))
+}
+
+// at will return the byte at the provided position.
+func (s *sequence) at(pos int64) byte {
+	return s.bytes[pos%s.period()]
+}
+
+// locate will return the position of the first byte of the pair, or -1.
+func (s *sequence) locate(b0, b1 byte) int64 {
+	return int64(s.index[s.pair(b0, b1)])
+}
+
+// counter is the sequence sent by the rtl-sdr in test mode, where each
+// byte is one larger than the last.
+var counter = func() *sequence {
+	bytes := make([]byte, 256)
+	for i := range bytes {
+		bytes[i] = byte(i)
+	}
+	return newSequence(bytes)
+}()
+
+// prbs15 is the PRBS15 sequence (x^15 + x^14 + 1), 8 bits at a time. Since
+// the sequence is 32767 bits long, which is odd, it takes 32767 bytes to
+// repeat, and since each byte pair contains 16 bits of the sequence, which
+// is more than the 15 bits of LFSR state, a pair can only occur once.
+var prbs15 = func() *sequence {
+	var (
+		state uint16 = 0x7FFF
+		bytes        = make([]byte, 32767)
+	)
+	for i := range bytes {
+		var b byte
+		for j := 0; j < 8; j++ {
+			bit := ((state >> 14) ^ (state >> 13)) & 1
+			state = ((state << 1) | bit) & 0x7FFF
+			b = b<<1 | byte(bit)
+		}
+		bytes[i] = b
+	}
+	return newSequence(bytes)
+}()
+
+// vim: foldmethod=marker
@@ -0,0 +1,266 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package integrity
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"hz.tools/sdr"
+)
+
+// Drop is a run of samples that were missing from the stream.
+type Drop struct {
+	// Offset is the number of samples that were read before the drop.
+	Offset int64
+
+	// Samples is the number of samples that were dropped. Since the
+	// sequence repeats, this is only known modulo the Verifier's Period.
+	Samples int64
+}
+
+// Stats are the results of verifying a stream so far.
+type Stats struct {
+	// Samples is the number of samples that have been read.
+	Samples int64
+
+	// Dropped is the total number of samples that were missing, which is
+	// the sum of the Samples of each Drop.
+	Dropped int64
+
+	// Corrupt is the number of samples that were read, but were not what
+	// was expected, and were not explained by a drop.
+	Corrupt int64
+
+	// Drops are the locations and lengths of each drop.
+	Drops []Drop
+}
+
+// DropRate will return the fraction of samples sent that were dropped.
+func (s Stats) DropRate() float64 {
+	if s.Samples+s.Dropped == 0 {
+		return 0
+	}
+	return float64(s.Dropped) / float64(s.Samples+s.Dropped)
+}
+
+// Verifier is an sdr.ReadCloser which will check the samples read through
+// it against a known sequence, keeping Stats on any samples that were
+// dropped or corrupted along the way.
+//
+// The first sample read is used to find where in the sequence the stream
+// starts, so there's no need to start reading at the very first sample.
+// When a sample doesn't match, the next sample is used to tell a drop
+// apart from a corrupted sample.
+type Verifier struct {
+	sdr.ReadCloser
+
+	seq  *sequence
+	lock *sync.Mutex
+
+	stats  Stats
+	synced bool
+
+	// pos is the position in the sequence of the next expected sample.
+	// If the last sample didn't match, candidate is the position the next
+	// sample would be at if samples were dropped, or -1.
+	pos         int64
+	candidate   int64
+	candidateAt int64
+}
+
+func newVerifier(r sdr.ReadCloser, seq *sequence) *Verifier {
+	return &Verifier{
+		ReadCloser: r,
+		seq:        seq,
+		lock:       &sync.Mutex{},
+		candidate:  -1,
+	}
+}
+
+// NewCounterVerifier will create a Verifier that checks the counter sent by
+// the rtl-sdr in test mode.
+//
+// Since the counter is only 8 bits, it repeats every 128 samples, and the
+// rtl-sdr sends samples in USB transfers which are a multiple of that, so
+// an entire dropped transfer can't be seen. What this will catch is data
+// being lost or mangled anywhere after librtlsdr, such as a partial
+// buffer, or a bug in a conversion.
+func NewCounterVerifier(r sdr.ReadCloser) (*Verifier, error) {
+	if r.SampleFormat() != sdr.SampleFormatU8 {
+		return nil, sdr.ErrSampleFormatMismatch
+	}
+	return newVerifier(r, counter), nil
+}
+
+// NewPRBSVerifier will create a Verifier that checks the sequence generated
+// by a Reader returned by NewPRBSReader. The sequence repeats every 32767
+// samples.
+func NewPRBSVerifier(r sdr.ReadCloser) (*Verifier, error) {
+	switch r.SampleFormat() {
+	case sdr.SampleFormatU8, sdr.SampleFormatI8, sdr.SampleFormatI16, sdr.SampleFormatC64, sdr.SampleFormatC128:
+	default:
+		return nil, sdr.ErrSampleFormatUnknown
+	}
+	return newVerifier(r, prbs15), nil
+}
+
+// Period is the number of samples after which the sequence repeats.
+func (v *Verifier) Period() int64 {
+	if v.seq.period()%2 == 0 {
+		return v.seq.period() / 2
+	}
+	return v.seq.period()
+}
+
+// Stats will return the results of verifying the stream so far.
+func (v *Verifier) Stats() Stats {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	stats := v.stats
+	stats.Drops = append([]Drop{}, v.stats.Drops...)
+	return stats
+}
+
+// Read implements the sdr.Reader interface.
+func (v *Verifier) Read(s sdr.Samples) (int, error) {
+	n, err := v.ReadCloser.Read(s)
+	if n > 0 {
+		if cerr := v.check(s.Slice(0, n)); cerr != nil {
+			return n, cerr
+		}
+	}
+	return n, err
+}
+
+func (v *Verifier) matches(pos int64, b0, b1 byte) bool {
+	return v.seq.at(pos) == b0 && v.seq.at(pos+1) == b1
+}
+
+func (v *Verifier) check(s sdr.Samples) error {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	for i := 0; i < s.Length(); i++ {
+		b0, b1, ok, err := sampleBytes(s, i)
+		if err != nil {
+			return err
+		}
+		offset := v.stats.Samples
+		v.stats.Samples++
+
+		if !v.synced {
+			if !ok || v.seq.locate(b0, b1) < 0 {
+				v.stats.Corrupt++
+				continue
+			}
+			v.pos = v.seq.locate(b0, b1) + 2
+			v.synced = true
+			continue
+		}
+
+		candidate := v.candidate
+		v.candidate = -1
+
+		switch {
+		case ok && v.matches(v.pos, b0, b1):
+			if candidate >= 0 {
+				// The last sample was just mangled.
+				v.stats.Corrupt++
+			}
+			v.pos += 2
+			continue
+		case ok && candidate >= 0 && v.matches(candidate, b0, b1):
+			v.drop(candidate-2, v.pos-2)
+			v.pos = candidate + 2
+			continue
+		}
+
+		if candidate >= 0 {
+			v.stats.Corrupt++
+		}
+		if ok {
+			if k := v.seq.locate(b0, b1); k >= 0 {
+				v.candidate = k + 2
+				v.candidateAt = offset
+			}
+		}
+		if v.candidate < 0 {
+			v.stats.Corrupt++
+		}
+		v.pos += 2
+	}
+	return nil
+}
+
+// drop will record a drop, where the sample at position found was read
+// when the sample at position expected was.
+func (v *Verifier) drop(found, expected int64) {
+	period := v.seq.period()
+	bytes := ((found-expected)%period + period) % period
+	if bytes%2 != 0 {
+		// Only whole samples are dropped, so it must have wrapped around
+		// once more than we can see.
+		bytes += period
+	}
+	drop := Drop{Offset: v.candidateAt, Samples: bytes / 2}
+	v.stats.Drops = append(v.stats.Drops, drop)
+	v.stats.Dropped += drop.Samples
+}
+
+// sampleBytes will return the two bytes of the sequence carried by the
+// sample at the provided index, and false if the sample couldn't have
+// come from a sequence.
+func sampleBytes(s sdr.Samples, i int) (byte, byte, bool, error) {
+	switch s := s.(type) {
+	case sdr.SamplesU8:
+		return s[i][0], s[i][1], true, nil
+	case sdr.SamplesI8:
+		return byte(s[i][0]), byte(s[i][1]), true, nil
+	case sdr.SamplesI16:
+		ok := s[i][0] >= math.MinInt8 && s[i][0] <= math.MaxInt8 &&
+			s[i][1] >= math.MinInt8 && s[i][1] <= math.MaxInt8
+		return byte(s[i][0]), byte(s[i][1]), ok, nil
+	case sdr.SamplesC64:
+		b0, ok0 := floatByte(real(s[i]))
+		b1, ok1 := floatByte(imag(s[i]))
+		return b0, b1, ok0 && ok1, nil
+	case sdr.SamplesC128:
+		r, im := real(s[i]), imag(s[i])
+		b0, ok0 := floatByte(float32(r))
+		b1, ok1 := floatByte(float32(im))
+		exact := float64(float32(r)) == r && float64(float32(im)) == im
+		return b0, b1, ok0 && ok1 && exact, nil
+	default:
+		return 0, 0, false, fmt.Errorf("integrity: %s", sdr.ErrSampleFormatUnknown)
+	}
+}
+
+func floatByte(f float32) (byte, bool) {
+	v := f * 128
+	if v != float32(math.Floor(float64(v))) || v < math.MinInt8 || v > math.MaxInt8 {
+		return 0, false
+	}
+	return byte(int8(v)), true
+}
+
+// vim: foldmethod=marker
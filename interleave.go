@@ -0,0 +1,298 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sdr
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+var (
+	// ErrOddLength will be returned when an interleaved buffer contains an
+	// odd number of values, meaning the last sample has an I value but no
+	// Q value.
+	ErrOddLength = fmt.Errorf("sdr: interleaved buffer has an odd length")
+)
+
+// sliceHeader mirrors the runtime layout of a slice. Unlike the yikes
+// package, the base is kept as an unsafe.Pointer, so the underlying
+// memory stays visible to the garbage collector the whole time.
+type sliceHeader struct {
+	data unsafe.Pointer
+	len  int
+	cap  int
+}
+
+// Interleaved will return the samples as a flat slice of interleaved I and
+// Q values, without copying. This is a view, mutations of the returned slice
+// will modify the Samples (and vice versa).
+func (s SamplesU8) Interleaved() []uint8 {
+	if cap(s) == 0 {
+		return nil
+	}
+	h := sliceHeader{unsafe.Pointer(&s[:1][0]), len(s) * 2, cap(s) * 2}
+	return *(*[]uint8)(unsafe.Pointer(&h))
+}
+
+// Interleaved will return the samples as a flat slice of interleaved I and
+// Q values, without copying. This is a view, mutations of the returned slice
+// will modify the Samples (and vice versa).
+func (s SamplesI8) Interleaved() []int8 {
+	if cap(s) == 0 {
+		return nil
+	}
+	h := sliceHeader{unsafe.Pointer(&s[:1][0]), len(s) * 2, cap(s) * 2}
+	return *(*[]int8)(unsafe.Pointer(&h))
+}
+
+// Interleaved will return the samples as a flat slice of interleaved I and
+// Q values, without copying. This is a view, mutations of the returned slice
+// will modify the Samples (and vice versa).
+func (s SamplesI16) Interleaved() []int16 {
+	if cap(s) == 0 {
+		return nil
+	}
+	h := sliceHeader{unsafe.Pointer(&s[:1][0]), len(s) * 2, cap(s) * 2}
+	return *(*[]int16)(unsafe.Pointer(&h))
+}
+
+// Interleaved will return the samples as a flat slice of interleaved real
+// and imaginary values, without copying. This is a view, mutations of the
+// returned slice will modify the Samples (and vice versa).
+func (s SamplesC64) Interleaved() []float32 {
+	if cap(s) == 0 {
+		return nil
+	}
+	h := sliceHeader{unsafe.Pointer(&s[:1][0]), len(s) * 2, cap(s) * 2}
+	return *(*[]float32)(unsafe.Pointer(&h))
+}
+
+// Interleaved will return the samples as a flat slice of interleaved real
+// and imaginary values, without copying. This is a view, mutations of the
+// returned slice will modify the Samples (and vice versa).
+func (s SamplesC128) Interleaved() []float64 {
+	if cap(s) == 0 {
+		return nil
+	}
+	h := sliceHeader{unsafe.Pointer(&s[:1][0]), len(s) * 2, cap(s) * 2}
+	return *(*[]float64)(unsafe.Pointer(&h))
+}
+
+// ViewU8 will return the provided interleaved I/Q values as SamplesU8,
+// without copying. The buffer must have an even length.
+func ViewU8(buf []uint8) (SamplesU8, error) {
+	if len(buf)%2 != 0 {
+		return nil, ErrOddLength
+	}
+	if cap(buf) < 2 {
+		return SamplesU8{}, nil
+	}
+	h := sliceHeader{unsafe.Pointer(&buf[:1][0]), len(buf) / 2, cap(buf) / 2}
+	return *(*SamplesU8)(unsafe.Pointer(&h)), nil
+}
+
+// ViewI8 will return the provided interleaved I/Q values as SamplesI8,
+// without copying. The buffer must have an even length.
+func ViewI8(buf []int8) (SamplesI8, error) {
+	if len(buf)%2 != 0 {
+		return nil, ErrOddLength
+	}
+	if cap(buf) < 2 {
+		return SamplesI8{}, nil
+	}
+	h := sliceHeader{unsafe.Pointer(&buf[:1][0]), len(buf) / 2, cap(buf) / 2}
+	return *(*SamplesI8)(unsafe.Pointer(&h)), nil
+}
+
+// ViewI16 will return the provided interleaved I/Q values as SamplesI16,
+// without copying. The buffer must have an even length.
+func ViewI16(buf []int16) (SamplesI16, error) {
+	if len(buf)%2 != 0 {
+		return nil, ErrOddLength
+	}
+	if cap(buf) < 2 {
+		return SamplesI16{}, nil
+	}
+	h := sliceHeader{unsafe.Pointer(&buf[:1][0]), len(buf) / 2, cap(buf) / 2}
+	return *(*SamplesI16)(unsafe.Pointer(&h)), nil
+}
+
+// ViewC64 will return the provided interleaved real and imaginary values
+// as SamplesC64, without copying. The buffer must have an even length.
+func ViewC64(buf []float32) (SamplesC64, error) {
+	if len(buf)%2 != 0 {
+		return nil, ErrOddLength
+	}
+	if cap(buf) < 2 {
+		return SamplesC64{}, nil
+	}
+	h := sliceHeader{unsafe.Pointer(&buf[:1][0]), len(buf) / 2, cap(buf) / 2}
+	return *(*SamplesC64)(unsafe.Pointer(&h)), nil
+}
+
+// ViewC128 will return the provided interleaved real and imaginary values
+// as SamplesC128, without copying. The buffer must have an even length.
+func ViewC128(buf []float64) (SamplesC128, error) {
+	if len(buf)%2 != 0 {
+		return nil, ErrOddLength
+	}
+	if cap(buf) < 2 {
+		return SamplesC128{}, nil
+	}
+	h := sliceHeader{unsafe.Pointer(&buf[:1][0]), len(buf) / 2, cap(buf) / 2}
+	return *(*SamplesC128)(unsafe.Pointer(&h)), nil
+}
+
+// viewDst will view a flat destination buffer as Samples, dropping a
+// trailing odd value rather than failing, since there's no sense in
+// refusing to write into a buffer that's one value too large.
+func viewDst(buf interface{}) (Samples, error) {
+	switch buf := buf.(type) {
+	case []uint8:
+		return ViewU8(buf[:len(buf)&^1])
+	case []int8:
+		return ViewI8(buf[:len(buf)&^1])
+	case []int16:
+		return ViewI16(buf[:len(buf)&^1])
+	case []float32:
+		return ViewC64(buf[:len(buf)&^1])
+	case []float64:
+		return ViewC128(buf[:len(buf)&^1])
+	default:
+		return nil, ErrSampleFormatUnknown
+	}
+}
+
+func copyTo(dst interface{}, src Samples) (int, error) {
+	out, err := viewDst(dst)
+	if err != nil {
+		return 0, err
+	}
+	if src.Length() > out.Length() {
+		return 0, ErrDstTooSmall
+	}
+	return ConvertBuffer(out, src)
+}
+
+func copyFrom(dst, in Samples) (int, error) {
+	if in.Length() > dst.Length() {
+		return 0, ErrDstTooSmall
+	}
+	return ConvertBuffer(dst, in)
+}
+
+// CopyToUint8 will copy (converting if needed) the Samples into the
+// provided buffer as interleaved uint8 I/Q values. The number of IQ samples
+// copied is returned.
+func CopyToUint8(dst []uint8, src Samples) (int, error) {
+	return copyTo(dst, src)
+}
+
+// CopyToInt8 will copy (converting if needed) the Samples into the
+// provided buffer as interleaved int8 I/Q values. The number of IQ samples
+// copied is returned.
+func CopyToInt8(dst []int8, src Samples) (int, error) {
+	return copyTo(dst, src)
+}
+
+// CopyToInt16 will copy (converting if needed) the Samples into the
+// provided buffer as interleaved int16 I/Q values. The number of IQ samples
+// copied is returned.
+//
+// This is handy when handing IQ data to audio libraries, which tend to want
+// a flat []int16 with two channels.
+func CopyToInt16(dst []int16, src Samples) (int, error) {
+	return copyTo(dst, src)
+}
+
+// CopyToFloat32 will copy (converting if needed) the Samples into the
+// provided buffer as interleaved float32 I/Q values. The number of IQ
+// samples copied is returned.
+func CopyToFloat32(dst []float32, src Samples) (int, error) {
+	return copyTo(dst, src)
+}
+
+// CopyToFloat64 will copy (converting if needed) the Samples into the
+// provided buffer as interleaved float64 I/Q values. The number of IQ
+// samples copied is returned.
+//
+// This is handy when handing IQ data to libraries such as gonum, which work
+// in float64.
+func CopyToFloat64(dst []float64, src Samples) (int, error) {
+	return copyTo(dst, src)
+}
+
+// CopyFromUint8 will copy (converting if needed) the interleaved uint8 I/Q
+// values into the provided Samples. The number of IQ samples copied is
+// returned.
+func CopyFromUint8(dst Samples, src []uint8) (int, error) {
+	in, err := ViewU8(src)
+	if err != nil {
+		return 0, err
+	}
+	return copyFrom(dst, in)
+}
+
+// CopyFromInt8 will copy (converting if needed) the interleaved int8 I/Q
+// values into the provided Samples. The number of IQ samples copied is
+// returned.
+func CopyFromInt8(dst Samples, src []int8) (int, error) {
+	in, err := ViewI8(src)
+	if err != nil {
+		return 0, err
+	}
+	return copyFrom(dst, in)
+}
+
+// CopyFromInt16 will copy (converting if needed) the interleaved int16 I/Q
+// values into the provided Samples. The number of IQ samples copied is
+// returned.
+func CopyFromInt16(dst Samples, src []int16) (int, error) {
+	in, err := ViewI16(src)
+	if err != nil {
+		return 0, err
+	}
+	return copyFrom(dst, in)
+}
+
+// CopyFromFloat32 will copy (converting if needed) the interleaved float32
+// I/Q values into the provided Samples. The number of IQ samples copied is
+// returned.
+func CopyFromFloat32(dst Samples, src []float32) (int, error) {
+	in, err := ViewC64(src)
+	if err != nil {
+		return 0, err
+	}
+	return copyFrom(dst, in)
+}
+
+// CopyFromFloat64 will copy (converting if needed) the interleaved float64
+// I/Q values into the provided Samples. The number of IQ samples copied is
+// returned.
+func CopyFromFloat64(dst Samples, src []float64) (int, error) {
+	in, err := ViewC128(src)
+	if err != nil {
+		return 0, err
+	}
+	return copyFrom(dst, in)
+}
+
+// vim: foldmethod=marker
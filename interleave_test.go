@@ -0,0 +1,144 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sdr_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+)
+
+func TestInterleavedView(t *testing.T) {
+	s := sdr.SamplesI16{{1, 2}, {3, 4}}
+	flat := s.Interleaved()
+	assert.Equal(t, []int16{1, 2, 3, 4}, flat)
+
+	flat[3] = 40
+	assert.Equal(t, int16(40), s[1][1])
+
+	c := sdr.SamplesC64{complex(1, 2)}
+	assert.Equal(t, []float32{1, 2}, c.Interleaved())
+
+	assert.Nil(t, sdr.SamplesU8(nil).Interleaved())
+	assert.Equal(t, []int8{-1, 1}, sdr.SamplesI8{{-1, 1}}.Interleaved())
+}
+
+func TestView(t *testing.T) {
+	flat := []int16{1, 2, 3, 4}
+	s, err := sdr.ViewI16(flat)
+	assert.NoError(t, err)
+	assert.Equal(t, sdr.SamplesI16{{1, 2}, {3, 4}}, s)
+
+	s[0][0] = 10
+	assert.Equal(t, int16(10), flat[0])
+
+	_, err = sdr.ViewI16(flat[:3])
+	assert.Equal(t, sdr.ErrOddLength, err)
+
+	c, err := sdr.ViewC64([]float32{0.5, -0.5})
+	assert.NoError(t, err)
+	assert.Equal(t, sdr.SamplesC64{complex(0.5, -0.5)}, c)
+
+	u, err := sdr.ViewU8(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, u.Length())
+}
+
+func TestCopyToInt16(t *testing.T) {
+	dst := make([]int16, 5)
+	n, err := sdr.CopyToInt16(dst, sdr.SamplesI8{{-128, 64}, {1, 0}})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []int16{-32768, 16384, 256, 0, 0}, dst)
+
+	_, err = sdr.CopyToInt16(dst[:2], sdr.SamplesI16{{1, 1}, {2, 2}})
+	assert.Equal(t, sdr.ErrDstTooSmall, err)
+}
+
+func TestCopyFloat32(t *testing.T) {
+	dst := make([]float32, 4)
+	n, err := sdr.CopyToFloat32(dst, sdr.SamplesC64{complex(1, -1), complex(0.5, 0)})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []float32{1, -1, 0.5, 0}, dst)
+
+	out := make(sdr.SamplesC64, 2)
+	n, err = sdr.CopyFromFloat32(out, dst)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, sdr.SamplesC64{complex(1, -1), complex(0.5, 0)}, out)
+
+	_, err = sdr.CopyFromFloat32(out, dst[:3])
+	assert.Equal(t, sdr.ErrOddLength, err)
+
+	_, err = sdr.CopyFromFloat32(out[:1], dst)
+	assert.Equal(t, sdr.ErrDstTooSmall, err)
+}
+
+func TestCopyFloat64(t *testing.T) {
+	dst := make([]float64, 4)
+	n, err := sdr.CopyToFloat64(dst, sdr.SamplesC128{complex(1, -1), complex(0.5, 0)})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []float64{1, -1, 0.5, 0}, dst)
+
+	out := make(sdr.SamplesC128, 2)
+	n, err = sdr.CopyFromFloat64(out, dst)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, sdr.SamplesC128{complex(1, -1), complex(0.5, 0)}, out)
+
+	_, err = sdr.CopyFromFloat64(out, dst[:3])
+	assert.Equal(t, sdr.ErrOddLength, err)
+
+	_, err = sdr.CopyFromFloat64(out[:1], dst)
+	assert.Equal(t, sdr.ErrDstTooSmall, err)
+}
+
+func TestCopyFromUint8(t *testing.T) {
+	out := make(sdr.SamplesI8, 1)
+	n, err := sdr.CopyFromUint8(out, []uint8{255, 0})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, sdr.SamplesI8{{127, -128}}, out)
+
+	flat := make([]uint8, 2)
+	n, err = sdr.CopyToUint8(flat, out)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []uint8{255, 0}, flat)
+
+	i8 := make([]int8, 2)
+	_, err = sdr.CopyToInt8(i8, out)
+	assert.NoError(t, err)
+	n, err = sdr.CopyFromInt8(out, i8)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	i16 := make(sdr.SamplesI16, 1)
+	_, err = sdr.CopyFromInt16(i16, []int16{1, 2})
+	assert.NoError(t, err)
+	assert.Equal(t, sdr.SamplesI16{{1, 2}}, i16)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,52 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package simd
+
+func statsComplexNative(buf []complex64) (complex64, float32, float32) {
+	var (
+		sumI, sumQ float64
+		power      float64
+		peak       float32
+	)
+	for _, v := range buf {
+		i, q := real(v), imag(v)
+		sumI += float64(i)
+		sumQ += float64(q)
+		mag := i*i + q*q
+		power += float64(mag)
+		if mag > peak {
+			peak = mag
+		}
+	}
+	return complex(float32(sumI), float32(sumQ)), float32(power), peak
+}
+
+// StatsComplex will walk the provided buffer once, returning the sum of
+// all values, the sum of the squared magnitude of each value, and the
+// largest squared magnitude seen.
+//
+// Callers are expected to do the division (and square root) themselves,
+// this only does the bit that's worth doing in a tight loop.
+func StatsComplex(buf []complex64) (sum complex64, power float32, peak float32) {
+	return statsComplex(buf)
+}
+
+// vim: foldmethod=marker
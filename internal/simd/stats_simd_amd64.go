@@ -0,0 +1,60 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+//go:build !sdr.nosimd
+// +build !sdr.nosimd
+
+package simd
+
+func statsComplex(buf []complex64) (complex64, float32, float32) {
+	var (
+		out  [12]float32
+		even = len(buf) &^ 1
+	)
+
+	if even > 0 {
+		mmxStatsComplex(buf[:even], &out)
+	}
+
+	sum := complex(out[0]+out[2], out[1]+out[3])
+	power := out[4] + out[5] + out[6] + out[7]
+	peak := out[8]
+	if out[10] > peak {
+		peak = out[10]
+	}
+
+	if even != len(buf) {
+		tSum, tPower, tPeak := statsComplexNative(buf[even:])
+		sum += tSum
+		power += tPower
+		if tPeak > peak {
+			peak = tPeak
+		}
+	}
+
+	return sum, power, peak
+}
+
+// mmxStatsComplex will accumulate the sum, squared values and peak squared
+// magnitude of the buffer into out, four float32 lanes at a time. The
+// buffer must be an even length.
+func mmxStatsComplex(buf []complex64, out *[12]float32)
+
+// vim: foldmethod=marker
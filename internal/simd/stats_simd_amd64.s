@@ -0,0 +1,69 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2020
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+//go:build !sdr.nosimd
+// +build !sdr.nosimd
+
+// func mmxStatsComplex(buf []complex64, out *[12]float32)
+TEXT ·mmxStatsComplex(SB), $0-32
+    MOVQ buf_base+0(FP), SI
+    MOVQ buf_len+8(FP), BX
+    MOVQ out+24(FP), DI
+
+    // Each complex64 is 8 bytes, so BX becomes the end of the buffer.
+    SHLQ $3, BX
+    ADDQ SI, BX
+
+    // SI: Current pointer into the complex array
+    // BX: End of the complex array
+    // X4: Running sum of the I/Q values
+    // X5: Running sum of the squared I/Q values
+    // X6: Running peak squared magnitude (in lanes 0 and 2)
+    XORPS X4, X4
+    XORPS X5, X5
+    XORPS X6, X6
+
+stats_complex_loop:
+    CMPQ SI, BX
+    JGE stats_complex_done
+
+    MOVUPS (SI), X0
+    ADDPS X0, X4
+
+    MULPS X0, X0
+    ADDPS X0, X5
+
+    // Swap the I and Q lanes, and add them back in, giving us the
+    // squared magnitude of both complex values.
+    MOVAPS X0, X1
+    SHUFPS $0xB1, X1, X1
+    ADDPS X1, X0
+    MAXPS X0, X6
+
+    ADDQ $16, SI
+    JMP stats_complex_loop
+
+stats_complex_done:
+    MOVUPS X4, 0(DI)
+    MOVUPS X5, 16(DI)
+    MOVUPS X6, 32(DI)
+    RET
+
+// vim: foldmethod=marker
@@ -0,0 +1,64 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package simd_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr/internal/simd"
+)
+
+func TestStatsComplex(t *testing.T) {
+	buf := []complex64{
+		complex(1, 0),
+		complex(0, -1),
+		complex(3, 4),
+		complex(-1, 1),
+		complex(0.5, 0.5),
+	}
+
+	sum, power, peak := simd.StatsComplex(buf)
+	assert.InDelta(t, 3.5, real(sum), 1e-6)
+	assert.InDelta(t, 4.5, imag(sum), 1e-6)
+	assert.InDelta(t, 1+1+25+2+0.5, power, 1e-5)
+	assert.InDelta(t, 25, peak, 1e-6)
+}
+
+func TestStatsComplexPeakTail(t *testing.T) {
+	buf := make([]complex64, 1025)
+	buf[1024] = complex(0, 2)
+	buf[3] = complex(1, 0)
+
+	_, power, peak := simd.StatsComplex(buf)
+	assert.InDelta(t, 5, power, 1e-6)
+	assert.InDelta(t, 4, peak, 1e-6)
+}
+
+func TestStatsComplexEmpty(t *testing.T) {
+	sum, power, peak := simd.StatsComplex(nil)
+	assert.Equal(t, complex64(0), sum)
+	assert.Equal(t, float32(0), power)
+	assert.Equal(t, float32(0), peak)
+}
+
+// vim: foldmethod=marker
@@ -36,6 +36,11 @@ var (
 	// ErrDstTooSmall will be returned when attempting to perform an operation
 	// and the target buffer is too small to use.
 	ErrDstTooSmall = fmt.Errorf("sdr: destination sample buffer is too small")
+
+	// ErrLengthMismatch will be returned when attempting to perform an
+	// operation on two sample buffers which must be the same length, but
+	// are not.
+	ErrLengthMismatch = fmt.Errorf("sdr: sample buffers are not the same length")
 )
 
 // Samples represents a vector of IQ data.
@@ -45,12 +50,14 @@ var (
 // the native format of the SDR without requiring expensive conversions to
 // other types.
 //
-// This package contains 4 Samples implementations:
+// This package contains 6 Samples implementations:
 //
-//   - SamplesU8  - interleaved uint8 values
-//   - SamplesI8  - interleaved int8 values
-//   - SamplesI16 - interleaved int16 values
-//   - SamplesC64 - vector of complex64 values (interleaved float32 values)
+//   - SamplesU8        - interleaved uint8 values
+//   - SamplesI8        - interleaved int8 values
+//   - SamplesI12Packed - packed 12 bit values, 3 bytes per IQ sample
+//   - SamplesI16       - interleaved int16 values
+//   - SamplesC64       - vector of complex64 values (interleaved float32 values)
+//   - SamplesC128      - vector of complex128 values (interleaved float64 values)
 //
 // This should cover most common SDRs, but if you're handing a type of IQ data
 // that is not supported, you may either implement the Samples type yourself
@@ -102,6 +109,10 @@ func (sf SampleFormat) Size() int {
 		return 4
 	case SampleFormatC64:
 		return 8
+	case SampleFormatC128:
+		return 16
+	case SampleFormatI12Packed:
+		return 3
 	default:
 		return 0
 	}
@@ -123,6 +134,14 @@ const (
 	// SampleFormatI8 indicates that SamplesI8 will be handled. See
 	// sdr.SamplesI8 for more information.
 	SampleFormatI8 SampleFormat = 4
+
+	// SampleFormatC128 indicates that SamplesC128 will be handled. See
+	// sdr.SamplesC128 for more information.
+	SampleFormatC128 SampleFormat = 5
+
+	// SampleFormatI12Packed indicates that SamplesI12Packed will be handled.
+	// See sdr.SamplesI12Packed for more information.
+	SampleFormatI12Packed SampleFormat = 6
 )
 
 // MakeSamples will create a buffer of a specified size and type. This will
@@ -142,6 +161,10 @@ func MakeSamples(sampleFormat SampleFormat, sampleSize int) (Samples, error) {
 		return make(SamplesI16, sampleSize), nil
 	case SampleFormatC64:
 		return make(SamplesC64, sampleSize), nil
+	case SampleFormatC128:
+		return make(SamplesC128, sampleSize), nil
+	case SampleFormatI12Packed:
+		return make(SamplesI12Packed, sampleSize), nil
 	default:
 		return nil, ErrSampleFormatUnknown
 	}
@@ -158,6 +181,10 @@ func (sf SampleFormat) String() string {
 		return "interleaved int16"
 	case SampleFormatC64:
 		return "complex64"
+	case SampleFormatC128:
+		return "complex128"
+	case SampleFormatI12Packed:
+		return "packed int12"
 	default:
 		return "unknown"
 	}
@@ -0,0 +1,81 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sdr
+
+import (
+	"math"
+)
+
+// The arithmetic helpers on the fixed point sample formats (Scale, Multiply,
+// Add and Conjugate) all work the same way: the math is done as float32 on
+// the sample values (after removing any DC offset, as with SamplesU8), and
+// the result is rounded to the nearest integer and clipped to the range of
+// the format, rather than being allowed to wrap around.
+
+// roundClip will round v to the nearest integer, and clip it to be between
+// lo and hi (inclusive).
+func roundClip(v, lo, hi float32) float32 {
+	v = float32(math.Round(float64(v)))
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+// scaleTableU8 will compute what each possible uint8 I or Q value becomes
+// when scaled by r, around the 127.5 center point.
+func scaleTableU8(r float32) *[256]uint8 {
+	var tab [256]uint8
+	for v := range tab {
+		tab[v] = uint8(roundClip((float32(v)-127.5)*r+127.5, 0, math.MaxUint8))
+	}
+	return &tab
+}
+
+// scaleTableI8 will compute what each possible int8 I or Q value becomes
+// when scaled by r, indexed by the uint8 bit pattern of the value.
+func scaleTableI8(r float32) *[256]int8 {
+	var tab [256]int8
+	for v := range tab {
+		tab[v] = int8(roundClip(float32(int8(v))*r, math.MinInt8, math.MaxInt8))
+	}
+	return &tab
+}
+
+// productTables will compute the real and imaginary part of each possible
+// 8 bit I or Q value (after being mapped to a float32 by 'value')
+// multiplied by c. Since a complex multiply is
+// (I*re - Q*im) + (I*im + Q*re)j, each output sample only needs four
+// lookups and two additions.
+func productTables(c complex64, value func(uint8) float32) (*[256]float32, *[256]float32) {
+	var re, im [256]float32
+	for v := range re {
+		x := value(uint8(v))
+		re[v] = x * real(c)
+		im[v] = x * imag(c)
+	}
+	return &re, &im
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,187 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sdr_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+)
+
+func TestU8Arithmetic(t *testing.T) {
+	s := sdr.SamplesU8{{255, 0}, {128, 127}, {200, 50}}
+
+	scaled := append(sdr.SamplesU8{}, s...)
+	scaled.Scale(0.5)
+	assert.Equal(t, sdr.SamplesU8{{191, 64}, {128, 127}, {164, 89}}, scaled)
+
+	scaled.Scale(100)
+	assert.Equal(t, sdr.SamplesU8{{255, 0}, {178, 78}, {255, 0}}, scaled)
+
+	conj := append(sdr.SamplesU8{}, s...)
+	conj.Conjugate()
+	assert.Equal(t, sdr.SamplesU8{{255, 255}, {128, 128}, {200, 205}}, conj)
+
+	// Multiplying by j rotates (I, Q) to (-Q, I).
+	rot := append(sdr.SamplesU8{}, s...)
+	rot.Multiply(complex(0, 1))
+	assert.Equal(t, sdr.SamplesU8{{255, 255}, {128, 128}, {205, 200}}, rot)
+
+	sum := sdr.SamplesU8{{200, 100}, {250, 10}}
+	assert.NoError(t, sum.Add(sdr.SamplesU8{{100, 100}, {250, 10}}))
+	assert.Equal(t, sdr.SamplesU8{{173, 73}, {255, 0}}, sum)
+	assert.Equal(t, sdr.ErrLengthMismatch, sum.Add(sdr.SamplesU8{{0, 0}}))
+	assert.Equal(t, sdr.ErrLengthMismatch, sum.Add(sdr.SamplesU8{{0, 0}, {0, 0}, {0, 0}}))
+
+	power := make([]float32, 1)
+	assert.NoError(t, sdr.SamplesU8{{255, 0}}.Power(power))
+	assert.InEpsilon(t, 2, power[0], epsilon)
+	assert.NoError(t, sdr.SamplesU8{{255, 0}}.Magnitude(power))
+	assert.InEpsilon(t, math.Sqrt2, power[0], epsilon)
+	assert.Equal(t, sdr.ErrDstTooSmall, s.Power(power))
+}
+
+func TestI8Arithmetic(t *testing.T) {
+	s := sdr.SamplesI8{{127, -128}, {10, -10}, {-3, 5}}
+
+	scaled := append(sdr.SamplesI8{}, s...)
+	scaled.Scale(0.5)
+	assert.Equal(t, sdr.SamplesI8{{64, -64}, {5, -5}, {-2, 3}}, scaled)
+
+	scaled.Scale(-4)
+	assert.Equal(t, sdr.SamplesI8{{-128, 127}, {-20, 20}, {8, -12}}, scaled)
+
+	conj := append(sdr.SamplesI8{}, s...)
+	conj.Conjugate()
+	assert.Equal(t, sdr.SamplesI8{{127, 127}, {10, 10}, {-3, -5}}, conj)
+
+	rot := append(sdr.SamplesI8{}, s...)
+	rot.Multiply(complex(0, 1))
+	assert.Equal(t, sdr.SamplesI8{{127, 127}, {10, 10}, {-5, -3}}, rot)
+
+	sum := sdr.SamplesI8{{100, -100}, {1, 2}}
+	assert.NoError(t, sum.Add(sdr.SamplesI8{{100, -100}, {3, 4}}))
+	assert.Equal(t, sdr.SamplesI8{{127, -128}, {4, 6}}, sum)
+
+	power := make([]float32, 1)
+	assert.NoError(t, sdr.SamplesI8{{-128, 0}}.Power(power))
+	assert.InEpsilon(t, 1, power[0], epsilon)
+}
+
+func TestI16Arithmetic(t *testing.T) {
+	s := sdr.SamplesI16{{math.MaxInt16, math.MinInt16}, {1000, -1000}}
+
+	scaled := append(sdr.SamplesI16{}, s...)
+	scaled.Scale(2)
+	assert.Equal(t, sdr.SamplesI16{{math.MaxInt16, math.MinInt16}, {2000, -2000}}, scaled)
+
+	conj := append(sdr.SamplesI16{}, s...)
+	conj.Conjugate()
+	assert.Equal(t, sdr.SamplesI16{{math.MaxInt16, math.MaxInt16}, {1000, 1000}}, conj)
+
+	rot := append(sdr.SamplesI16{}, s...)
+	rot.Multiply(complex(0, -1))
+	assert.Equal(t, sdr.SamplesI16{{math.MinInt16, -math.MaxInt16}, {-1000, -1000}}, rot)
+
+	sum := sdr.SamplesI16{{30000, -30000}}
+	assert.NoError(t, sum.Add(sdr.SamplesI16{{30000, -30000}}))
+	assert.Equal(t, sdr.SamplesI16{{math.MaxInt16, math.MinInt16}}, sum)
+
+	power := make([]float32, 1)
+	assert.NoError(t, sdr.SamplesI16{{math.MaxInt16, 0}}.Magnitude(power))
+	assert.InEpsilon(t, 1, power[0], epsilon)
+}
+
+func TestI12PackedArithmetic(t *testing.T) {
+	s := make(sdr.SamplesI12Packed, 2)
+	s.Set(0, [2]int16{2047, -2048})
+	s.Set(1, [2]int16{100, -100})
+
+	s.Scale(2)
+	assert.Equal(t, [2]int16{2047, -2048}, s.Get(0))
+	assert.Equal(t, [2]int16{200, -200}, s.Get(1))
+
+	s.Conjugate()
+	assert.Equal(t, [2]int16{2047, 2047}, s.Get(0))
+	assert.Equal(t, [2]int16{200, 200}, s.Get(1))
+
+	s.Multiply(complex(0, 1))
+	assert.Equal(t, [2]int16{-2047, 2047}, s.Get(0))
+	assert.Equal(t, [2]int16{-200, 200}, s.Get(1))
+
+	assert.NoError(t, s.Add(s))
+	assert.Equal(t, [2]int16{-2048, 2047}, s.Get(0))
+	assert.Equal(t, [2]int16{-400, 400}, s.Get(1))
+}
+
+func TestAddLengthMismatch(t *testing.T) {
+	assert.Equal(t, sdr.ErrLengthMismatch, make(sdr.SamplesC64, 2).Add(make([]complex64, 3)))
+	assert.Equal(t, sdr.ErrLengthMismatch, make(sdr.SamplesC128, 2).Add(make([]complex128, 3)))
+	assert.Equal(t, sdr.ErrLengthMismatch, make(sdr.SamplesI8, 2).Add(make(sdr.SamplesI8, 1)))
+	assert.Equal(t, sdr.ErrLengthMismatch, make(sdr.SamplesI16, 2).Add(make(sdr.SamplesI16, 3)))
+}
+
+func TestC64Conjugate(t *testing.T) {
+	s := sdr.SamplesC64{complex(1, 2), complex(-3, -4)}
+	s.Conjugate()
+	assert.Equal(t, sdr.SamplesC64{complex(1, -2), complex(-3, 4)}, s)
+
+	power := make([]float32, 2)
+	assert.NoError(t, s.Power(power))
+	assert.Equal(t, []float32{5, 25}, power)
+	assert.NoError(t, s.Magnitude(power))
+	assert.InDeltaSlice(t, []float32{float32(math.Sqrt(5)), 5}, power, 1e-6)
+
+	c128 := sdr.SamplesC128{complex(3, 4)}
+	c128.Conjugate()
+	assert.Equal(t, sdr.SamplesC128{complex(3, -4)}, c128)
+	mag := make([]float64, 1)
+	assert.NoError(t, c128.Magnitude(mag))
+	assert.Equal(t, []float64{5}, mag)
+}
+
+// The 8 bit arithmetic should match doing the same thing as complex64 to
+// within a count.
+func TestU8MultiplyMatchesC64(t *testing.T) {
+	var (
+		c     = complex64(complex(0.6, -0.3))
+		u8    = sdr.LookupTableIdentityU8()
+		c64   = make(sdr.SamplesC64, len(u8))
+		check = make(sdr.SamplesC64, len(u8))
+	)
+	_, err := sdr.ConvertBuffer(c64, u8)
+	assert.NoError(t, err)
+	c64.Multiply(c)
+
+	u8.Multiply(c)
+	_, err = sdr.ConvertBuffer(check, u8)
+	assert.NoError(t, err)
+
+	for i := range c64 {
+		assert.InDelta(t, real(c64[i]), real(check[i]), 1/127.5)
+		assert.InDelta(t, imag(c64[i]), imag(check[i]), 1/127.5)
+	}
+}
+
+// vim: foldmethod=marker
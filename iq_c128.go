@@ -0,0 +1,184 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sdr
+
+import (
+	"math"
+	"math/cmplx"
+	"unsafe"
+)
+
+// SamplesC128 indicates that the samples are in a complex128 number, which
+// is itself two interleaved float64 numbers, the i and q value.
+//
+// This is mostly useful for high-precision offline processing, or when
+// handing IQ data to libraries (such as gonum) that work in float64. Most
+// code will want to use SamplesC64 instead, which is half the size.
+type SamplesC128 []complex128
+
+// Format returns the type of this vector, as exported by the SampleFormat
+// enum.
+func (s SamplesC128) Format() SampleFormat {
+	return SampleFormatC128
+}
+
+// Size will return the size of this sdr.Samples in *bytes*. This is used
+// when your code needs to be aware of the underlying storage size. This
+// should usually only be used at i/o boundaries.
+func (s SamplesC128) Size() int {
+	return int(unsafe.Sizeof(complex128(0))) * len(s)
+}
+
+// Length will return the number of IQ samples in this vector of Samples.
+//
+// This is the count of real and imaginary pairs, so in the case
+// of the U8 type, this will be half the size of the vector.
+//
+// This function is usually the correct one to use when processing
+// sample information.
+func (s SamplesC128) Length() int {
+	return len(s)
+}
+
+// Slice will return a slice of the sample buffer from the provided
+// starting position until the ending position. The returned value is
+// assumed to be a slice, which is to say, mutations of the returned
+// Samples will modify the slice from whence it came.
+//
+// samples.Slice(0, 10) is assumed to be the same as samples[:10], except
+// it does not require the typecast to the concrete type implementing
+// this interface.
+func (s SamplesC128) Slice(start, end int) Samples {
+	return s[start:end]
+}
+
+// ToU8 will convert the complex128 data to a vector of interleaved uint8s.
+func (s SamplesC128) ToU8(out SamplesU8) (int, error) {
+	if s.Length() > out.Length() {
+		return 0, ErrDstTooSmall
+	}
+	for i, sample := range s {
+		out[i][0] = uint8((real(sample) * 127.5) + 127.5)
+		out[i][1] = uint8((imag(sample) * 127.5) + 127.5)
+	}
+	return s.Length(), nil
+}
+
+// ToI8 will convert the complex128 data to int8 data.
+func (s SamplesC128) ToI8(out SamplesI8) (int, error) {
+	if s.Length() > out.Length() {
+		return 0, ErrDstTooSmall
+	}
+	for i := range s {
+		out[i] = [2]int8{
+			int8(real(s[i]) * math.MaxInt8),
+			int8(imag(s[i]) * math.MaxInt8),
+		}
+	}
+	return s.Length(), nil
+}
+
+// ToI16 will convert the complex128 data to int16 data.
+func (s SamplesC128) ToI16(out SamplesI16) (int, error) {
+	if s.Length() > out.Length() {
+		return 0, ErrDstTooSmall
+	}
+	for i := range s {
+		out[i] = [2]int16{
+			int16(real(s[i]) * math.MaxInt16),
+			int16(imag(s[i]) * math.MaxInt16),
+		}
+	}
+	return s.Length(), nil
+}
+
+// ToC64 will convert the complex128 data to complex64 data, losing some
+// precision.
+func (s SamplesC128) ToC64(out SamplesC64) (int, error) {
+	if s.Length() > out.Length() {
+		return 0, ErrDstTooSmall
+	}
+	for i := range s {
+		out[i] = complex64(s[i])
+	}
+	return s.Length(), nil
+}
+
+// Scale will multiply each I and Q value by the provided real value 'r'. This
+// will *not* do a complex multiplication, this is the same as if each phasor
+// had their real and imag parts multiplied by the provided real value 'r'.
+func (s SamplesC128) Scale(r float64) {
+	for i := range s {
+		s[i] = complex(real(s[i])*r, imag(s[i])*r)
+	}
+}
+
+// Multiply will conduct a complex multiplication of each phasor in this buffer
+// by a provided complex number 'c'.
+func (s SamplesC128) Multiply(c complex128) {
+	for i := range s {
+		s[i] *= c
+	}
+}
+
+// Add will conduct a complex addition of each phasor in this buffer
+// by a provided complex number 'c', writing results to 'dst'.
+func (s SamplesC128) Add(c []complex128) error {
+	if len(s) != len(c) {
+		return ErrLengthMismatch
+	}
+	for i := range s {
+		s[i] += c[i]
+	}
+	return nil
+}
+
+// Conjugate will replace each phasor in this buffer with its complex
+// conjugate.
+func (s SamplesC128) Conjugate() {
+	for i := range s {
+		s[i] = cmplx.Conj(s[i])
+	}
+}
+
+// Power will write the power (I^2 + Q^2) of each sample to 'dst'.
+func (s SamplesC128) Power(dst []float64) error {
+	if len(dst) < len(s) {
+		return ErrDstTooSmall
+	}
+	for i, v := range s {
+		dst[i] = real(v)*real(v) + imag(v)*imag(v)
+	}
+	return nil
+}
+
+// Magnitude will write the magnitude of each sample to 'dst'.
+func (s SamplesC128) Magnitude(dst []float64) error {
+	if len(dst) < len(s) {
+		return ErrDstTooSmall
+	}
+	for i, v := range s {
+		dst[i] = cmplx.Abs(v)
+	}
+	return nil
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,148 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2020
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sdr_test
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+)
+
+func TestConvertC128ToU8(t *testing.T) {
+	c128Samples := sdr.SamplesC128{complex(1, 1), complex(-1, -1), complex(0, 0)}
+	u8Samples := make(sdr.SamplesU8, 3)
+
+	_, err := c128Samples.ToU8(u8Samples)
+	assert.NoError(t, err)
+	assert.Equal(t, sdr.SamplesU8{{255, 255}, {0, 0}, {127, 127}}, u8Samples)
+
+	u8Samples = make(sdr.SamplesU8, 3)
+	_, err = sdr.ConvertBuffer(u8Samples, c128Samples)
+	assert.NoError(t, err)
+	assert.Equal(t, sdr.SamplesU8{{255, 255}, {0, 0}, {127, 127}}, u8Samples)
+}
+
+func TestConvertC128ToI16(t *testing.T) {
+	c128Samples := sdr.SamplesC128{complex(1, 1), complex(-1, -1), complex(0, 0)}
+	i16Samples := make(sdr.SamplesI16, 3)
+
+	_, err := sdr.ConvertBuffer(i16Samples, c128Samples)
+	assert.NoError(t, err)
+	assert.Equal(t, sdr.SamplesI16{
+		{math.MaxInt16, math.MaxInt16},
+		{-math.MaxInt16, -math.MaxInt16},
+		{0, 0},
+	}, i16Samples)
+}
+
+func TestConvertC128MatchesC64(t *testing.T) {
+	// Converting through C128 should give the same answer as converting
+	// through C64 for every format that fits in a float32.
+	for _, from := range []sdr.Samples{
+		sdr.SamplesU8{{0, 255}, {127, 128}, {12, 200}},
+		sdr.SamplesI8{{-128, 127}, {0, -1}, {12, -100}},
+		sdr.SamplesI16{{-32767, 32767}, {0, -1}, {1200, -10000}},
+		sdr.SamplesC64{complex(-1, 1), complex(0, 0.25), complex(0.5, -0.125)},
+	} {
+		t.Run(from.Format().String(), func(t *testing.T) {
+			c128 := make(sdr.SamplesC128, from.Length())
+			_, err := sdr.ConvertBuffer(c128, from)
+			assert.NoError(t, err)
+
+			c64 := make(sdr.SamplesC64, from.Length())
+			_, err = sdr.ConvertBuffer(c64, from)
+			assert.NoError(t, err)
+
+			for i := range c64 {
+				assert.InDelta(t, real(c64[i]), real(c128[i]), 1e-6)
+				assert.InDelta(t, imag(c64[i]), imag(c128[i]), 1e-6)
+			}
+
+			viaC128, err := sdr.MakeSamples(from.Format(), from.Length())
+			assert.NoError(t, err)
+			_, err = sdr.ConvertBuffer(viaC128, c128)
+			assert.NoError(t, err)
+
+			viaC64, err := sdr.MakeSamples(from.Format(), from.Length())
+			assert.NoError(t, err)
+			_, err = sdr.ConvertBuffer(viaC64, c64)
+			assert.NoError(t, err)
+
+			assert.Equal(t, viaC64, viaC128)
+		})
+	}
+}
+
+func TestConvertC128Precision(t *testing.T) {
+	// A value that doesn't fit into a float32 should survive a trip through
+	// the C128 format, but not through C64.
+	v := complex(1.0/3.0, -1.0/3.0)
+	c128 := sdr.SamplesC128{v}
+
+	out := make(sdr.SamplesC128, 1)
+	n, err := sdr.CopySamples(out, c128)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, v, out[0])
+
+	c64 := make(sdr.SamplesC64, 1)
+	_, err = sdr.ConvertBuffer(c64, c128)
+	assert.NoError(t, err)
+	assert.InDelta(t, real(v), real(c64[0]), epsilon)
+	assert.NotEqual(t, real(v), float64(real(c64[0])))
+}
+
+func TestSamplesC128ByteIO(t *testing.T) {
+	ioReader, ioWriter := io.Pipe()
+	r := sdr.ByteReader(ioReader, binary.BigEndian, 1024, sdr.SampleFormatC128)
+	w := sdr.ByteWriter(ioWriter, binary.BigEndian, 1024, sdr.SampleFormatC128)
+
+	in := sdr.SamplesC128{complex(1.0/3.0, -0.5), complex(math.Pi, math.E)}
+	go func() {
+		_, err := w.Write(in)
+		assert.NoError(t, err)
+	}()
+
+	out := make(sdr.SamplesC128, 2)
+	_, err := sdr.ReadFull(r, out)
+	assert.NoError(t, err)
+	assert.Equal(t, in, out)
+}
+
+func TestSamplesC128Arithmetic(t *testing.T) {
+	s := sdr.SamplesC128{complex(1, 0), complex(0, 1)}
+	s.Scale(2)
+	assert.Equal(t, sdr.SamplesC128{complex(2, 0), complex(0, 2)}, s)
+
+	s.Multiply(1i)
+	assert.Equal(t, sdr.SamplesC128{complex(0, 2), complex(-2, 0)}, s)
+
+	assert.NoError(t, s.Add([]complex128{1, 1}))
+	assert.Equal(t, sdr.SamplesC128{complex(1, 2), complex(-1, 0)}, s)
+	assert.Error(t, s.Add([]complex128{1}))
+}
+
+// vim: foldmethod=marker
@@ -22,6 +22,7 @@ package sdr
 
 import (
 	"math"
+	"math/cmplx"
 	"unsafe"
 
 	"hz.tools/sdr/internal/simd"
@@ -93,15 +94,30 @@ func (s SamplesC64) ToI16(out SamplesI16) (int, error) {
 	if s.Length() > out.Length() {
 		return 0, ErrDstTooSmall
 	}
-	for i := range s {
-		out[i] = [2]int16{
-			int16(real(s[i]) * math.MaxInt16),
-			int16(imag(s[i]) * math.MaxInt16),
-		}
-	}
+	convC64ToI16(s, out)
 	return s.Length(), nil
 }
 
+// convC64ToI16Native is the pure Go conversion, which is used on platforms
+// without a SIMD implementation, and for the tail of the buffer on ones
+// with. Like convI16ToC64Native, it's unrolled and bounds-check-free.
+func convC64ToI16Native(s1 SamplesC64, s2 SamplesI16) {
+	s2 = s2[:len(s1)]
+
+	i := 0
+	for ; i+4 <= len(s1); i += 4 {
+		in := s1[i : i+4 : i+4]
+		out := s2[i : i+4 : i+4]
+		out[0] = [2]int16{int16(real(in[0]) * math.MaxInt16), int16(imag(in[0]) * math.MaxInt16)}
+		out[1] = [2]int16{int16(real(in[1]) * math.MaxInt16), int16(imag(in[1]) * math.MaxInt16)}
+		out[2] = [2]int16{int16(real(in[2]) * math.MaxInt16), int16(imag(in[2]) * math.MaxInt16)}
+		out[3] = [2]int16{int16(real(in[3]) * math.MaxInt16), int16(imag(in[3]) * math.MaxInt16)}
+	}
+	for ; i < len(s1); i++ {
+		s2[i] = [2]int16{int16(real(s1[i]) * math.MaxInt16), int16(imag(s1[i]) * math.MaxInt16)}
+	}
+}
+
 // ToI8 will convert the complex64 data to int8 data.
 func (s SamplesC64) ToI8(out SamplesI8) (int, error) {
 	if s.Length() > out.Length() {
@@ -132,7 +148,51 @@ func (s SamplesC64) Multiply(c complex64) {
 // Add will conduct a complex addition of each phasor in this buffer
 // by a provided complex number 'c', writing results to 'dst'.
 func (s SamplesC64) Add(c []complex64) error {
+	if len(s) != len(c) {
+		return ErrLengthMismatch
+	}
 	return simd.AddComplex(s, c, s)
 }
 
+// ToC128 will convert the complex64 data to complex128 data.
+func (s SamplesC64) ToC128(out SamplesC128) (int, error) {
+	if s.Length() > out.Length() {
+		return 0, ErrDstTooSmall
+	}
+	for i := range s {
+		out[i] = complex128(s[i])
+	}
+	return s.Length(), nil
+}
+
+// Conjugate will replace each phasor in this buffer with its complex
+// conjugate.
+func (s SamplesC64) Conjugate() {
+	for i := range s {
+		s[i] = complex(real(s[i]), -imag(s[i]))
+	}
+}
+
+// Power will write the power (I^2 + Q^2) of each sample to 'dst'.
+func (s SamplesC64) Power(dst []float32) error {
+	if len(dst) < len(s) {
+		return ErrDstTooSmall
+	}
+	for i, v := range s {
+		dst[i] = real(v)*real(v) + imag(v)*imag(v)
+	}
+	return nil
+}
+
+// Magnitude will write the magnitude of each sample to 'dst'.
+func (s SamplesC64) Magnitude(dst []float32) error {
+	if len(dst) < len(s) {
+		return ErrDstTooSmall
+	}
+	for i, v := range s {
+		dst[i] = float32(cmplx.Abs(complex128(v)))
+	}
+	return nil
+}
+
 // vim: foldmethod=marker
@@ -22,6 +22,7 @@ package sdr_test
 
 import (
 	"math"
+	"math/rand"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -144,4 +145,40 @@ func TestC64Add(t *testing.T) {
 	}
 }
 
+func TestConvertC64ToI16Lengths(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	for n := 0; n < 38; n++ {
+		c64Samples := make(sdr.SamplesC64, n)
+		i16Samples := make(sdr.SamplesI16, n)
+		for i := range c64Samples {
+			c64Samples[i] = complex(
+				rng.Float32()*2-1,
+				rng.Float32()*2-1,
+			)
+		}
+		_, err := c64Samples.ToI16(i16Samples)
+		assert.NoError(t, err)
+
+		for i := range c64Samples {
+			assert.Equal(t, [2]int16{
+				int16(real(c64Samples[i]) * math.MaxInt16),
+				int16(imag(c64Samples[i]) * math.MaxInt16),
+			}, i16Samples[i], "n=%d i=%d", n, i)
+		}
+	}
+}
+
+func BenchmarkConvertC64ToI16(b *testing.B) {
+	in := make(sdr.SamplesC64, 1024*16)
+	out := make(sdr.SamplesI16, 1024*16)
+	for i := range in {
+		in[i] = complex(0.5, -0.5)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		in.ToI16(out)
+	}
+}
+
 // vim: foldmethod=marker
@@ -0,0 +1,347 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sdr
+
+import (
+	"math"
+	"unsafe"
+)
+
+// SamplesI12Packed indicates that the samples are being sent as a vector of
+// packed 12 bit signed integers, three bytes per IQ sample, which is 25%
+// smaller than SamplesI16 without losing any precision from a 12 bit ADC
+// (such as the AD936x family).
+//
+// Each sample is a 24 bit little endian word, with I in the low 12 bits, and
+// Q in the high 12 bits:
+//
+//	byte 0: I[7:0]
+//	byte 1: Q[3:0] I[11:8]
+//	byte 2: Q[11:4]
+//
+// Since the layout is defined in bytes, it's the same on every host, and the
+// ByteOrder passed to ByteReader or ByteWriter is ignored.
+//
+// Values range from +2047 to -2048. Conversions to and from other formats
+// are done as if the value was MSB aligned in a SamplesI16 (shifted left 4
+// bits), so converting through SamplesI12Packed only drops the low 4 bits of
+// a SamplesI16.
+type SamplesI12Packed [][3]uint8
+
+// Format returns the type of this vector, as exported by the SampleFormat
+// enum.
+func (s SamplesI12Packed) Format() SampleFormat {
+	return SampleFormatI12Packed
+}
+
+// Size will return the size of this sdr.Samples in *bytes*. This is used
+// when your code needs to be aware of the underlying storage size. This
+// should usually only be used at i/o boundaries.
+func (s SamplesI12Packed) Size() int {
+	return int(unsafe.Sizeof([3]uint8{})) * len(s)
+}
+
+// Length will return the number of IQ samples in this vector of Samples.
+//
+// This is the count of real and imaginary pairs, so each IQ sample is three
+// bytes.
+//
+// This function is usually the correct one to use when processing
+// sample information.
+func (s SamplesI12Packed) Length() int {
+	return len(s)
+}
+
+// Slice will return a slice of the sample buffer from the provided
+// starting position until the ending position. The returned value is
+// assumed to be a slice, which is to say, mutations of the returned
+// Samples will modify the slice from whence it came.
+//
+// samples.Slice(0, 10) is assumed to be the same as samples[:10], except
+// it does not require the typecast to the concrete type implementing
+// this interface.
+func (s SamplesI12Packed) Slice(start, end int) Samples {
+	return s[start:end]
+}
+
+// Get will unpack the IQ sample at index i, returning the I and Q values in
+// the range +2047 to -2048.
+func (s SamplesI12Packed) Get(i int) [2]int16 {
+	v := s[i]
+	return [2]int16{
+		// Shift the sign bit up into the top of the int16, and back down
+		// again to sign extend.
+		int16(uint16(v[0])<<4|uint16(v[1]&0x0F)<<12) >> 4,
+		int16(uint16(v[1]&0xF0)|uint16(v[2])<<8) >> 4,
+	}
+}
+
+// Set will pack the I and Q values (which must be in the range +2047 to
+// -2048; any higher bits are dropped) into the IQ sample at index i.
+func (s SamplesI12Packed) Set(i int, iq [2]int16) {
+	s[i] = [3]uint8{
+		uint8(iq[0]),
+		uint8(iq[0]>>8)&0x0F | uint8(iq[1]<<4),
+		uint8(iq[1] >> 4),
+	}
+}
+
+const (
+	// i12Min and i12Max are the range of each I and Q value in a
+	// SamplesI12Packed.
+	i12Min = -2048
+	i12Max = 2047
+)
+
+// getI16 will unpack the IQ sample at index i, MSB aligned as a SamplesI16.
+func (s SamplesI12Packed) getI16(i int) [2]int16 {
+	v := s.Get(i)
+	return [2]int16{v[0] << 4, v[1] << 4}
+}
+
+// setI16 will pack the MSB aligned int16 IQ sample into index i.
+func (s SamplesI12Packed) setI16(i int, iq [2]int16) {
+	s.Set(i, [2]int16{iq[0] >> 4, iq[1] >> 4})
+}
+
+// floatToI16 will scale a float (clamped to +/-1) to an MSB aligned int16,
+// the same way SamplesC64.ToI16 does.
+func floatToI16(v float64) int16 {
+	return int16(math.Max(-1, math.Min(1, v)) * math.MaxInt16)
+}
+
+// ToU8 will convert the packed int12 data to interleaved uint8 samples.
+func (s SamplesI12Packed) ToU8(out SamplesU8) (int, error) {
+	if s.Length() > out.Length() {
+		return 0, ErrDstTooSmall
+	}
+	for i := range s {
+		v := s.getI16(i)
+		out[i] = [2]uint8{
+			uint8(uint16(int32(v[0])+32768) >> 8),
+			uint8(uint16(int32(v[1])+32768) >> 8),
+		}
+	}
+	return s.Length(), nil
+}
+
+// ToI8 will convert the packed int12 data to interleaved int8 samples.
+func (s SamplesI12Packed) ToI8(out SamplesI8) (int, error) {
+	if s.Length() > out.Length() {
+		return 0, ErrDstTooSmall
+	}
+	for i := range s {
+		v := s.Get(i)
+		out[i] = [2]int8{int8(v[0] >> 4), int8(v[1] >> 4)}
+	}
+	return s.Length(), nil
+}
+
+// ToI16 will convert the packed int12 data to MSB aligned interleaved int16
+// samples.
+func (s SamplesI12Packed) ToI16(out SamplesI16) (int, error) {
+	if s.Length() > out.Length() {
+		return 0, ErrDstTooSmall
+	}
+	for i := range s {
+		out[i] = s.getI16(i)
+	}
+	return s.Length(), nil
+}
+
+// ToC64 will convert the packed int12 data to a vector of complex64 numbers,
+// using the same scale as SamplesI16.ToC64.
+func (s SamplesI12Packed) ToC64(out SamplesC64) (int, error) {
+	if s.Length() > out.Length() {
+		return 0, ErrDstTooSmall
+	}
+	for i := range s {
+		v := s.getI16(i)
+		out[i] = complex(
+			float32(v[0])/math.MaxInt16,
+			float32(v[1])/math.MaxInt16,
+		)
+	}
+	return s.Length(), nil
+}
+
+// ToC128 will convert the packed int12 data to a vector of complex128
+// numbers, using the same scale as SamplesI16.ToC128.
+func (s SamplesI12Packed) ToC128(out SamplesC128) (int, error) {
+	if s.Length() > out.Length() {
+		return 0, ErrDstTooSmall
+	}
+	for i := range s {
+		v := s.getI16(i)
+		out[i] = complex(
+			float64(v[0])/math.MaxInt16,
+			float64(v[1])/math.MaxInt16,
+		)
+	}
+	return s.Length(), nil
+}
+
+// ToI12Packed will pack the uint8 data into packed int12 samples.
+func (s SamplesU8) ToI12Packed(out SamplesI12Packed) (int, error) {
+	if s.Length() > out.Length() {
+		return 0, ErrDstTooSmall
+	}
+	for i := range s {
+		out.setI16(i, [2]int16{
+			int16((int32(s[i][0]) << 8) - 32768),
+			int16((int32(s[i][1]) << 8) - 32768),
+		})
+	}
+	return s.Length(), nil
+}
+
+// ToI12Packed will pack the int8 data into packed int12 samples.
+func (s SamplesI8) ToI12Packed(out SamplesI12Packed) (int, error) {
+	if s.Length() > out.Length() {
+		return 0, ErrDstTooSmall
+	}
+	for i := range s {
+		out.Set(i, [2]int16{int16(s[i][0]) << 4, int16(s[i][1]) << 4})
+	}
+	return s.Length(), nil
+}
+
+// ToI12Packed will pack the MSB aligned int16 data into packed int12
+// samples, dropping the low 4 bits.
+func (s SamplesI16) ToI12Packed(out SamplesI12Packed) (int, error) {
+	if s.Length() > out.Length() {
+		return 0, ErrDstTooSmall
+	}
+	for i := range s {
+		out.setI16(i, s[i])
+	}
+	return s.Length(), nil
+}
+
+// ToI12Packed will pack the complex64 data into packed int12 samples.
+// Values outside of +/-1 are clipped.
+func (s SamplesC64) ToI12Packed(out SamplesI12Packed) (int, error) {
+	if s.Length() > out.Length() {
+		return 0, ErrDstTooSmall
+	}
+	for i := range s {
+		out.setI16(i, [2]int16{
+			floatToI16(float64(real(s[i]))),
+			floatToI16(float64(imag(s[i]))),
+		})
+	}
+	return s.Length(), nil
+}
+
+// ToI12Packed will pack the complex128 data into packed int12 samples.
+// Values outside of +/-1 are clipped.
+func (s SamplesC128) ToI12Packed(out SamplesI12Packed) (int, error) {
+	if s.Length() > out.Length() {
+		return 0, ErrDstTooSmall
+	}
+	for i := range s {
+		out.setI16(i, [2]int16{
+			floatToI16(real(s[i])),
+			floatToI16(imag(s[i])),
+		})
+	}
+	return s.Length(), nil
+}
+
+// Scale will multiply each I and Q value by the provided real value 'r'.
+// Values are rounded and clipped to the int12 range.
+func (s SamplesI12Packed) Scale(r float32) {
+	for i := range s {
+		v := s.Get(i)
+		s.Set(i, [2]int16{
+			int16(roundClip(float32(v[0])*r, i12Min, i12Max)),
+			int16(roundClip(float32(v[1])*r, i12Min, i12Max)),
+		})
+	}
+}
+
+// Multiply will conduct a complex multiplication of each phasor in this
+// buffer by a provided complex number 'c'. Values are rounded and clipped to
+// the int12 range.
+func (s SamplesI12Packed) Multiply(c complex64) {
+	for i := range s {
+		v := s.Get(i)
+		p := complex(float32(v[0]), float32(v[1])) * c
+		s.Set(i, [2]int16{
+			int16(roundClip(real(p), i12Min, i12Max)),
+			int16(roundClip(imag(p), i12Min, i12Max)),
+		})
+	}
+}
+
+// Add will add each phasor in 'c' to the phasor at the same index in this
+// buffer. Values are clipped to the int12 range.
+func (s SamplesI12Packed) Add(c SamplesI12Packed) error {
+	if len(s) != len(c) {
+		return ErrLengthMismatch
+	}
+	for i := range s {
+		a, b := s.Get(i), c.Get(i)
+		s.Set(i, [2]int16{
+			int16(roundClip(float32(a[0])+float32(b[0]), i12Min, i12Max)),
+			int16(roundClip(float32(a[1])+float32(b[1]), i12Min, i12Max)),
+		})
+	}
+	return nil
+}
+
+// Conjugate will replace each phasor in this buffer with its complex
+// conjugate. A Q value of -2048 becomes 2047.
+func (s SamplesI12Packed) Conjugate() {
+	for i := range s {
+		v := s.Get(i)
+		s.Set(i, [2]int16{v[0], int16(roundClip(-float32(v[1]), i12Min, i12Max))})
+	}
+}
+
+// Power will write the power (I^2 + Q^2) of each sample to 'dst', scaled
+// the same way as ToC64, so that a full scale sample is 1.
+func (s SamplesI12Packed) Power(dst []float32) error {
+	if len(dst) < len(s) {
+		return ErrDstTooSmall
+	}
+	for i := range s {
+		v := s.getI16(i)
+		vI := float32(v[0]) / math.MaxInt16
+		vQ := float32(v[1]) / math.MaxInt16
+		dst[i] = vI*vI + vQ*vQ
+	}
+	return nil
+}
+
+// Magnitude will write the magnitude of each sample to 'dst', scaled the
+// same way as ToC64, so that a full scale sample is 1.
+func (s SamplesI12Packed) Magnitude(dst []float32) error {
+	if err := s.Power(dst); err != nil {
+		return err
+	}
+	for i := range s {
+		dst[i] = float32(math.Sqrt(float64(dst[i])))
+	}
+	return nil
+}
+
+// vim: foldmethod=marker
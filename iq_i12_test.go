@@ -0,0 +1,169 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sdr_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+)
+
+func TestI12PackedLayout(t *testing.T) {
+	s := make(sdr.SamplesI12Packed, 1)
+	s.Set(0, [2]int16{0x123, 0x456})
+	assert.Equal(t, [3]uint8{0x23, 0x61, 0x45}, s[0])
+	assert.Equal(t, [2]int16{0x123, 0x456}, s.Get(0))
+
+	s.Set(0, [2]int16{-1, -2048})
+	assert.Equal(t, [3]uint8{0xFF, 0x0F, 0x80}, s[0])
+	assert.Equal(t, [2]int16{-1, -2048}, s.Get(0))
+}
+
+func TestI12PackedGetSet(t *testing.T) {
+	s := make(sdr.SamplesI12Packed, 1)
+	for v := int16(-2048); v <= 2047; v++ {
+		s.Set(0, [2]int16{v, -1 - v})
+		assert.Equal(t, [2]int16{v, -1 - v}, s.Get(0))
+	}
+}
+
+func TestConvertI12PackedI16(t *testing.T) {
+	i16 := sdr.SamplesI16{{math.MaxInt16, math.MinInt16}, {0, -16}, {0x1234, 0x000F}}
+	i12 := make(sdr.SamplesI12Packed, 3)
+	_, err := sdr.ConvertBuffer(i12, i16)
+	assert.NoError(t, err)
+	assert.Equal(t, [2]int16{2047, -2048}, i12.Get(0))
+	assert.Equal(t, [2]int16{0, -1}, i12.Get(1))
+	assert.Equal(t, [2]int16{0x123, 0}, i12.Get(2))
+
+	out := make(sdr.SamplesI16, 3)
+	_, err = sdr.ConvertBuffer(out, i12)
+	assert.NoError(t, err)
+	assert.Equal(t, sdr.SamplesI16{{0x7FF0, math.MinInt16}, {0, -16}, {0x1230, 0}}, out)
+}
+
+func TestConvertI12PackedC64(t *testing.T) {
+	c64 := sdr.SamplesC64{complex(1, -1), complex(0, 0.5), complex(2, -2)}
+	i12 := make(sdr.SamplesI12Packed, 3)
+	_, err := sdr.ConvertBuffer(i12, c64)
+	assert.NoError(t, err)
+	assert.Equal(t, [2]int16{2047, -2048}, i12.Get(0))
+	assert.Equal(t, [2]int16{0, 1023}, i12.Get(1))
+	// Out of range values are clipped, rather than wrapping around.
+	assert.Equal(t, [2]int16{2047, -2048}, i12.Get(2))
+
+	out := make(sdr.SamplesC64, 3)
+	_, err = sdr.ConvertBuffer(out, i12)
+	assert.NoError(t, err)
+	for i, v := range []complex64{complex(1, -1), complex(0, 0.5), complex(1, -1)} {
+		assert.InDelta(t, real(v), real(out[i]), 0.001)
+		assert.InDelta(t, imag(v), imag(out[i]), 0.001)
+	}
+}
+
+func TestConvertI12PackedMatchesI16(t *testing.T) {
+	// Converting through I12Packed should give the same answer as going
+	// through I16, give or take the 4 bits it drops.
+	for _, from := range []sdr.Samples{
+		sdr.SamplesU8{{0, 255}, {127, 128}, {12, 200}},
+		sdr.SamplesI8{{-128, 127}, {0, -1}, {12, -100}},
+		sdr.SamplesC64{complex(-1, 0.99), complex(0, 0.25), complex(0.5, -0.125)},
+		sdr.SamplesC128{complex(-1, 0.99), complex(0, 0.25), complex(0.5, -0.125)},
+	} {
+		t.Run(from.Format().String(), func(t *testing.T) {
+			i12 := make(sdr.SamplesI12Packed, from.Length())
+			_, err := sdr.ConvertBuffer(i12, from)
+			assert.NoError(t, err)
+
+			i16 := make(sdr.SamplesI16, from.Length())
+			_, err = sdr.ConvertBuffer(i16, from)
+			assert.NoError(t, err)
+
+			for i := range i16 {
+				assert.Equal(t, [2]int16{i16[i][0] >> 4, i16[i][1] >> 4}, i12.Get(i))
+			}
+
+			back, err := sdr.MakeSamples(from.Format(), from.Length())
+			assert.NoError(t, err)
+			_, err = sdr.ConvertBuffer(back, i12)
+			assert.NoError(t, err)
+
+			c64 := make(sdr.SamplesC64, from.Length())
+			_, err = sdr.ConvertBuffer(c64, back)
+			assert.NoError(t, err)
+			ref := make(sdr.SamplesC64, from.Length())
+			_, err = sdr.ConvertBuffer(ref, from)
+			assert.NoError(t, err)
+			for i := range ref {
+				assert.InDelta(t, real(ref[i]), real(c64[i]), 0.01)
+				assert.InDelta(t, imag(ref[i]), imag(c64[i]), 0.01)
+			}
+		})
+	}
+}
+
+func TestI12PackedByteIO(t *testing.T) {
+	in := make(sdr.SamplesI12Packed, 2)
+	in.Set(0, [2]int16{0x123, 0x456})
+	in.Set(1, [2]int16{-1, -2048})
+
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		buf := &bytes.Buffer{}
+		w := sdr.ByteWriter(buf, order, 1024, sdr.SampleFormatI12Packed)
+		_, err := w.Write(in)
+		assert.NoError(t, err)
+
+		// The layout is the same no matter the ByteOrder.
+		assert.Equal(t, []byte{0x23, 0x61, 0x45, 0xFF, 0x0F, 0x80}, buf.Bytes())
+
+		r := sdr.ByteReader(buf, order, 1024, sdr.SampleFormatI12Packed)
+		out := make(sdr.SamplesI12Packed, 2)
+		_, err = sdr.ReadFull(r, out)
+		assert.NoError(t, err)
+		assert.Equal(t, in, out)
+	}
+}
+
+func TestStatsI12Packed(t *testing.T) {
+	s := make(sdr.SamplesI12Packed, 3)
+	s.Set(0, [2]int16{1000, -1000})
+	s.Set(1, [2]int16{2047, 0})
+	s.Set(2, [2]int16{0, -2048})
+
+	i16 := make(sdr.SamplesI16, 3)
+	_, err := sdr.ConvertBuffer(i16, s)
+	assert.NoError(t, err)
+
+	stats, err := sdr.Statistics(s)
+	assert.NoError(t, err)
+	expected := i16.Stats()
+	assert.Equal(t, expected.Mean, stats.Mean)
+	assert.Equal(t, expected.Power, stats.Power)
+	assert.Equal(t, 2, stats.Clipped)
+	assert.Equal(t, 2, stats.ClippedRun)
+}
+
+// vim: foldmethod=marker
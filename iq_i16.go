@@ -138,14 +138,31 @@ func (s SamplesI16) ToC64(out SamplesC64) (int, error) {
 	if s.Length() > out.Length() {
 		return 0, ErrDstTooSmall
 	}
-	for i := range s {
-		cI := float32(s[i][0]) / math.MaxInt16
-		cQ := float32(s[i][1]) / math.MaxInt16
-		out[i] = complex(cI, cQ)
-	}
+	convI16ToC64(s, out)
 	return s.Length(), nil
 }
 
+// convI16ToC64Native is the pure Go conversion, which is used on platforms
+// without a SIMD implementation, and for the tail of the buffer on ones
+// with. It's unrolled four samples at a time, and slices each block to a
+// constant length so the compiler can drop the bounds checks.
+func convI16ToC64Native(s1 SamplesI16, s2 SamplesC64) {
+	s2 = s2[:len(s1)]
+
+	i := 0
+	for ; i+4 <= len(s1); i += 4 {
+		in := s1[i : i+4 : i+4]
+		out := s2[i : i+4 : i+4]
+		out[0] = complex(float32(in[0][0])/math.MaxInt16, float32(in[0][1])/math.MaxInt16)
+		out[1] = complex(float32(in[1][0])/math.MaxInt16, float32(in[1][1])/math.MaxInt16)
+		out[2] = complex(float32(in[2][0])/math.MaxInt16, float32(in[2][1])/math.MaxInt16)
+		out[3] = complex(float32(in[3][0])/math.MaxInt16, float32(in[3][1])/math.MaxInt16)
+	}
+	for ; i < len(s1); i++ {
+		s2[i] = complex(float32(s1[i][0])/math.MaxInt16, float32(s1[i][1])/math.MaxInt16)
+	}
+}
+
 // ToI8 will convert the int16 data to interleaved int8 bit samples.
 func (s SamplesI16) ToI8(out SamplesI8) (int, error) {
 	if s.Length() > out.Length() {
@@ -161,4 +178,102 @@ func (s SamplesI16) ToI8(out SamplesI8) (int, error) {
 	return s.Length(), nil
 }
 
+// ToC128 will convert the int16 data to a vector of complex128 numbers.
+func (s SamplesI16) ToC128(out SamplesC128) (int, error) {
+	if s.Length() > out.Length() {
+		return 0, ErrDstTooSmall
+	}
+	for i := range s {
+		out[i] = complex(
+			float64(s[i][0])/math.MaxInt16,
+			float64(s[i][1])/math.MaxInt16,
+		)
+	}
+	return s.Length(), nil
+}
+
+// Scale will multiply each I and Q value by the provided real value 'r'.
+// Values are rounded and clipped to the int16 range.
+func (s SamplesI16) Scale(r float32) {
+	for i := range s {
+		s[i] = [2]int16{
+			int16(roundClip(float32(s[i][0])*r, math.MinInt16, math.MaxInt16)),
+			int16(roundClip(float32(s[i][1])*r, math.MinInt16, math.MaxInt16)),
+		}
+	}
+}
+
+// Multiply will conduct a complex multiplication of each phasor in this
+// buffer by a provided complex number 'c'. Values are rounded and clipped to
+// the int16 range.
+func (s SamplesI16) Multiply(c complex64) {
+	for i := range s {
+		v := complex(float32(s[i][0]), float32(s[i][1])) * c
+		s[i] = [2]int16{
+			int16(roundClip(real(v), math.MinInt16, math.MaxInt16)),
+			int16(roundClip(imag(v), math.MinInt16, math.MaxInt16)),
+		}
+	}
+}
+
+// Add will add each phasor in 'c' to the phasor at the same index in this
+// buffer. Values are clipped to the int16 range.
+func (s SamplesI16) Add(c SamplesI16) error {
+	if len(s) != len(c) {
+		return ErrLengthMismatch
+	}
+	for i := range s {
+		s[i] = [2]int16{
+			clipI16(int32(s[i][0]) + int32(c[i][0])),
+			clipI16(int32(s[i][1]) + int32(c[i][1])),
+		}
+	}
+	return nil
+}
+
+// Conjugate will replace each phasor in this buffer with its complex
+// conjugate. A Q value of -32768 becomes 32767.
+func (s SamplesI16) Conjugate() {
+	for i := range s {
+		s[i][1] = clipI16(-int32(s[i][1]))
+	}
+}
+
+// Power will write the power (I^2 + Q^2) of each sample to 'dst', scaled
+// the same way as ToC64, so that a full scale sample is 1.
+func (s SamplesI16) Power(dst []float32) error {
+	if len(dst) < len(s) {
+		return ErrDstTooSmall
+	}
+	for i := range s {
+		vI := float32(s[i][0]) / math.MaxInt16
+		vQ := float32(s[i][1]) / math.MaxInt16
+		dst[i] = vI*vI + vQ*vQ
+	}
+	return nil
+}
+
+// Magnitude will write the magnitude of each sample to 'dst', scaled the
+// same way as ToC64, so that a full scale sample is 1.
+func (s SamplesI16) Magnitude(dst []float32) error {
+	if err := s.Power(dst); err != nil {
+		return err
+	}
+	for i := range s {
+		dst[i] = float32(math.Sqrt(float64(dst[i])))
+	}
+	return nil
+}
+
+// clipI16 will clip v to the int16 range.
+func clipI16(v int32) int16 {
+	if v > math.MaxInt16 {
+		return math.MaxInt16
+	}
+	if v < math.MinInt16 {
+		return math.MinInt16
+	}
+	return int16(v)
+}
+
 // vim: foldmethod=marker
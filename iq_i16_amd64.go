@@ -0,0 +1,57 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+//go:build !sdr.nosimd
+// +build !sdr.nosimd
+
+package sdr
+
+func convI16ToC64(s1 SamplesI16, s2 SamplesC64) {
+	n := len(s1) &^ 3
+	if n != 0 {
+		sseConvI16ToC64(s1[:n], s2[:n])
+	}
+	if n != len(s1) {
+		convI16ToC64Native(s1[n:], s2[n:])
+	}
+}
+
+func convC64ToI16(s1 SamplesC64, s2 SamplesI16) {
+	n := len(s1) &^ 3
+	if n != 0 {
+		sseConvC64ToI16(s1[:n], s2[:n])
+	}
+	if n != len(s1) {
+		convC64ToI16Native(s1[n:], s2[n:])
+	}
+}
+
+// sseConvI16ToC64 converts 4 samples at a time. len(s1) must be a multiple
+// of 4, and s2 must be at least as long as s1.
+func sseConvI16ToC64(s1 SamplesI16, s2 SamplesC64)
+
+// sseConvC64ToI16 converts 4 samples at a time. len(s1) must be a multiple
+// of 4, and s2 must be at least as long as s1.
+//
+// Values outside of [-1, 1] are saturated to the int16 range rather than
+// wrapped as the pure Go conversion would.
+func sseConvC64ToI16(s1 SamplesC64, s2 SamplesI16)
+
+// vim: foldmethod=marker
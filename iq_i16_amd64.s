@@ -0,0 +1,115 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2020
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+//go:build !sdr.nosimd
+// +build !sdr.nosimd
+
+#include "textflag.h"
+
+/* 32767.0 as an IEEE 754 float32 */
+#define MAX_INT16_F32 $(0x46FFFE00)
+
+TEXT ·sseConvI16ToC64(SB), NOSPLIT, $0-48
+    MOVQ s1_base+0(FP),  SI
+    MOVQ s1_len+8(FP),   CX
+    MOVQ s2_base+24(FP), DI
+
+    /* Broadcast 32767.0 into all four lanes of X2 */
+    MOVQ MAX_INT16_F32, AX
+    MOVQ AX, X2
+    SHUFPS $0, X2, X2
+
+    /* +----+---------------------------+
+     * | X2 | [4]float32 of "32767"     |
+     * | SI | Address for int16 array   |
+     * | DI | Address for float32 array |
+     * | CX | Samples left to convert   |
+     * +----+---------------------------+ */
+
+    SHRQ $2, CX
+    JZ i16_to_c64_done
+
+i16_to_c64_loop:
+    /* Each 8 bytes of input is two IQ samples, or 4 int16 values, which
+     * we sign extend into 4 int32 values. */
+    PMOVSXWD 0(SI), X0
+    PMOVSXWD 8(SI), X1
+
+    CVTPL2PS X0, X0
+    CVTPL2PS X1, X1
+
+    DIVPS X2, X0
+    DIVPS X2, X1
+
+    MOVUPS X0, 0(DI)
+    MOVUPS X1, 16(DI)
+
+    ADDQ $16, SI
+    ADDQ $32, DI
+    DECQ CX
+    JNZ i16_to_c64_loop
+
+i16_to_c64_done:
+    RET
+
+TEXT ·sseConvC64ToI16(SB), NOSPLIT, $0-48
+    MOVQ s1_base+0(FP),  SI
+    MOVQ s1_len+8(FP),   CX
+    MOVQ s2_base+24(FP), DI
+
+    MOVQ MAX_INT16_F32, AX
+    MOVQ AX, X2
+    SHUFPS $0, X2, X2
+
+    /* +----+---------------------------+
+     * | X2 | [4]float32 of "32767"     |
+     * | SI | Address for float32 array |
+     * | DI | Address for int16 array   |
+     * | CX | Samples left to convert   |
+     * +----+---------------------------+ */
+
+    SHRQ $2, CX
+    JZ c64_to_i16_done
+
+c64_to_i16_loop:
+    MOVUPS 0(SI),  X0
+    MOVUPS 16(SI), X1
+
+    MULPS X2, X0
+    MULPS X2, X1
+
+    /* Truncate towards zero, same as a Go int conversion */
+    CVTTPS2PL X0, X0
+    CVTTPS2PL X1, X1
+
+    /* Pack the 8 int32 values into 8 int16 values, saturating */
+    PACKSSLW X1, X0
+
+    MOVUPS X0, 0(DI)
+
+    ADDQ $32, SI
+    ADDQ $16, DI
+    DECQ CX
+    JNZ c64_to_i16_loop
+
+c64_to_i16_done:
+    RET
+
+// vim: foldmethod=marker
@@ -22,6 +22,7 @@ package sdr_test
 
 import (
 	"math"
+	"math/rand"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -101,4 +102,40 @@ func TestConvertI16ToC64(t *testing.T) {
 	assert.InEpsilon(t, -0.5, imag(c64Samples[0]), epsilon)
 }
 
+func TestConvertI16ToC64Lengths(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	for n := 0; n < 38; n++ {
+		i16Samples := make(sdr.SamplesI16, n)
+		c64Samples := make(sdr.SamplesC64, n)
+		for i := range i16Samples {
+			i16Samples[i] = [2]int16{
+				int16(rng.Intn(math.MaxUint16) + math.MinInt16),
+				int16(rng.Intn(math.MaxUint16) + math.MinInt16),
+			}
+		}
+		_, err := i16Samples.ToC64(c64Samples)
+		assert.NoError(t, err)
+
+		for i := range i16Samples {
+			assert.Equal(t, complex(
+				float32(i16Samples[i][0])/math.MaxInt16,
+				float32(i16Samples[i][1])/math.MaxInt16,
+			), c64Samples[i], "n=%d i=%d", n, i)
+		}
+	}
+}
+
+func BenchmarkConvertI16ToC64(b *testing.B) {
+	in := make(sdr.SamplesI16, 1024*16)
+	out := make(sdr.SamplesC64, 1024*16)
+	for i := range in {
+		in[i] = [2]int16{math.MaxInt16, math.MinInt16}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		in.ToC64(out)
+	}
+}
+
 // vim: foldmethod=marker
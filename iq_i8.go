@@ -21,6 +21,7 @@
 package sdr
 
 import (
+	"math"
 	"unsafe"
 )
 
@@ -118,4 +119,102 @@ func convI8ToC64Native(s1 SamplesI8, s2 SamplesC64) {
 	}
 }
 
+// ToC128 will convert the int8 data to a vector of complex128 numbers,
+// using the same scale as ToC64.
+func (s SamplesI8) ToC128(out SamplesC128) (int, error) {
+	if s.Length() > out.Length() {
+		return 0, ErrDstTooSmall
+	}
+	for i := range s {
+		out[i] = complex(float64(s[i][0])/128, float64(s[i][1])/128)
+	}
+	return s.Length(), nil
+}
+
+// Scale will multiply each I and Q value by the provided real value 'r'.
+// Values are rounded and clipped to the int8 range.
+func (s SamplesI8) Scale(r float32) {
+	tab := scaleTableI8(r)
+	for i := range s {
+		s[i] = [2]int8{tab[uint8(s[i][0])], tab[uint8(s[i][1])]}
+	}
+}
+
+// Multiply will conduct a complex multiplication of each phasor in this
+// buffer by a provided complex number 'c'. Values are rounded and clipped to
+// the int8 range.
+//
+// This is done with small per-call lookup tables rather than by converting
+// to complex64. When applying the same multiplier to a lot of data, it may
+// be faster to call Multiply on LookupTableIdentityI8 once, and use that
+// with NewLookupTable.
+func (s SamplesI8) Multiply(c complex64) {
+	re, im := productTables(c, func(v uint8) float32 {
+		return float32(int8(v))
+	})
+	for i := range s {
+		vI, vQ := uint8(s[i][0]), uint8(s[i][1])
+		s[i] = [2]int8{
+			int8(roundClip(re[vI]-im[vQ], math.MinInt8, math.MaxInt8)),
+			int8(roundClip(im[vI]+re[vQ], math.MinInt8, math.MaxInt8)),
+		}
+	}
+}
+
+// Add will add each phasor in 'c' to the phasor at the same index in this
+// buffer. Values are clipped to the int8 range.
+func (s SamplesI8) Add(c SamplesI8) error {
+	if len(s) != len(c) {
+		return ErrLengthMismatch
+	}
+	for i := range s {
+		s[i] = [2]int8{
+			int8(roundClip(float32(s[i][0])+float32(c[i][0]), math.MinInt8, math.MaxInt8)),
+			int8(roundClip(float32(s[i][1])+float32(c[i][1]), math.MinInt8, math.MaxInt8)),
+		}
+	}
+	return nil
+}
+
+// Conjugate will replace each phasor in this buffer with its complex
+// conjugate. A Q value of -128 becomes 127.
+func (s SamplesI8) Conjugate() {
+	for i := range s {
+		if s[i][1] == math.MinInt8 {
+			s[i][1] = math.MaxInt8
+			continue
+		}
+		s[i][1] = -s[i][1]
+	}
+}
+
+// Power will write the power (I^2 + Q^2) of each sample to 'dst', scaled
+// the same way as ToC64.
+func (s SamplesI8) Power(dst []float32) error {
+	if len(dst) < len(s) {
+		return ErrDstTooSmall
+	}
+	var sq [256]float32
+	for v := range sq {
+		x := float32(int8(v)) / 128
+		sq[v] = x * x
+	}
+	for i := range s {
+		dst[i] = sq[uint8(s[i][0])] + sq[uint8(s[i][1])]
+	}
+	return nil
+}
+
+// Magnitude will write the magnitude of each sample to 'dst', scaled the
+// same way as ToC64.
+func (s SamplesI8) Magnitude(dst []float32) error {
+	if err := s.Power(dst); err != nil {
+		return err
+	}
+	for i := range s {
+		dst[i] = float32(math.Sqrt(float64(dst[i])))
+	}
+	return nil
+}
+
 // vim: foldmethod=marker
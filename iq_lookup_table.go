@@ -169,6 +169,8 @@ func lookupTableU8(dst, tab Samples, src SamplesU8) (int, error) {
 		return lookupTableU8ToI16(dst.(SamplesI16), tab.(SamplesI16), src)
 	case SampleFormatC64:
 		return lookupTableU8ToC64(dst.(SamplesC64), tab.(SamplesC64), src)
+	case SampleFormatC128:
+		return lookupTableU8ToC128(dst.(SamplesC128), tab.(SamplesC128), src)
 	default:
 		return 0, ErrSampleFormatUnknown
 	}
@@ -202,6 +204,13 @@ func lookupTableU8ToC64(dst, tab SamplesC64, src SamplesU8) (int, error) {
 	return src.Length(), nil
 }
 
+func lookupTableU8ToC128(dst, tab SamplesC128, src SamplesU8) (int, error) {
+	for i, iq := range src {
+		dst[i] = tab[LookupTableIndexU8(iq)]
+	}
+	return src.Length(), nil
+}
+
 // int8 lookup routines
 
 func lookupTableI8(dst, tab Samples, src SamplesI8) (int, error) {
@@ -217,6 +226,8 @@ func lookupTableI8(dst, tab Samples, src SamplesI8) (int, error) {
 		return lookupTableI8ToI16(dst.(SamplesI16), tab.(SamplesI16), src)
 	case SampleFormatC64:
 		return lookupTableI8ToC64(dst.(SamplesC64), tab.(SamplesC64), src)
+	case SampleFormatC128:
+		return lookupTableI8ToC128(dst.(SamplesC128), tab.(SamplesC128), src)
 	default:
 		return 0, ErrSampleFormatUnknown
 	}
@@ -250,4 +261,11 @@ func lookupTableI8ToC64(dst, tab SamplesC64, src SamplesI8) (int, error) {
 	return src.Length(), nil
 }
 
+func lookupTableI8ToC128(dst, tab SamplesC128, src SamplesI8) (int, error) {
+	for i, iq := range src {
+		dst[i] = tab[LookupTableIndexI8(iq)]
+	}
+	return src.Length(), nil
+}
+
 // vim: foldmethod=marker
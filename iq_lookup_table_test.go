@@ -103,6 +103,20 @@ func TestLookupTableU8(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, n, lref.Length())
 	})
+
+	t.Run("C128", func(t *testing.T) {
+		ltab := make(sdr.SamplesC128, ctab.Length())
+		sdr.ConvertBuffer(ltab, ctab)
+		tab, err := sdr.NewLookupTable(sdr.SampleFormatU8, ltab)
+		assert.NoError(t, err)
+
+		lref := make(sdr.SamplesU8, uref.Length())
+		oref := make(sdr.SamplesC128, uref.Length())
+		n, err := tab.Lookup(oref, lref)
+		assert.NoError(t, err)
+		assert.Equal(t, n, lref.Length())
+		assert.Equal(t, complex128(ctab[sdr.LookupTableIndexU8(lref[0])]), oref[0])
+	})
 }
 
 func TestLookupTableI8(t *testing.T) {
@@ -180,6 +194,20 @@ func TestLookupTableI8(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, n, lref.Length())
 	})
+
+	t.Run("C128", func(t *testing.T) {
+		ltab := make(sdr.SamplesC128, ctab.Length())
+		sdr.ConvertBuffer(ltab, ctab)
+		tab, err := sdr.NewLookupTable(sdr.SampleFormatI8, ltab)
+		assert.NoError(t, err)
+
+		lref := make(sdr.SamplesI8, uref.Length())
+		oref := make(sdr.SamplesC128, uref.Length())
+		n, err := tab.Lookup(oref, lref)
+		assert.NoError(t, err)
+		assert.Equal(t, n, lref.Length())
+		assert.Equal(t, complex128(ctab[sdr.LookupTableIndexI8(lref[0])]), oref[0])
+	})
 }
 
 // vim: foldmethod=marker
@@ -53,6 +53,19 @@ func TestMakeSamples(t *testing.T) {
 	assert.True(t, ok)
 	assert.Equal(t, 1024, len(samplesC64))
 
+	samples, err = sdr.MakeSamples(sdr.SampleFormatC128, 1024)
+	assert.NoError(t, err)
+	samplesC128, ok := samples.(sdr.SamplesC128)
+	assert.True(t, ok)
+	assert.Equal(t, 1024, len(samplesC128))
+
+	samples, err = sdr.MakeSamples(sdr.SampleFormatI12Packed, 1024)
+	assert.NoError(t, err)
+	samplesI12, ok := samples.(sdr.SamplesI12Packed)
+	assert.True(t, ok)
+	assert.Equal(t, 1024, len(samplesI12))
+	assert.Equal(t, 3*1024, samplesI12.Size())
+
 	_, err = sdr.MakeSamples(sdr.SampleFormat(100), 1024)
 	assert.Error(t, err)
 }
@@ -62,6 +75,8 @@ func TestSamplesSize(t *testing.T) {
 	assert.Equal(t, 2, sdr.SampleFormatU8.Size())
 	assert.Equal(t, 4, sdr.SampleFormatI16.Size())
 	assert.Equal(t, 8, sdr.SampleFormatC64.Size())
+	assert.Equal(t, 16, sdr.SampleFormatC128.Size())
+	assert.Equal(t, 3, sdr.SampleFormatI12Packed.Size())
 	assert.Equal(t, 0, sdr.SampleFormat(100).Size())
 }
 
@@ -69,6 +84,8 @@ func TestSamplesString(t *testing.T) {
 	assert.Equal(t, "interleaved uint8", sdr.SampleFormatU8.String())
 	assert.Equal(t, "interleaved int16", sdr.SampleFormatI16.String())
 	assert.Equal(t, "complex64", sdr.SampleFormatC64.String())
+	assert.Equal(t, "complex128", sdr.SampleFormatC128.String())
+	assert.Equal(t, "packed int12", sdr.SampleFormatI12Packed.String())
 	assert.Equal(t, "unknown", sdr.SampleFormat(100).String())
 }
 
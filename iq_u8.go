@@ -21,6 +21,7 @@
 package sdr
 
 import (
+	"math"
 	"unsafe"
 )
 
@@ -120,4 +121,102 @@ func convU8ToC64Native(s1 SamplesU8, s2 SamplesC64) {
 	}
 }
 
+// ToC128 will convert the uint8 data to a vector of complex128 numbers.
+func (s SamplesU8) ToC128(out SamplesC128) (int, error) {
+	if s.Length() > out.Length() {
+		return 0, ErrDstTooSmall
+	}
+	for i := range s {
+		out[i] = complex(
+			(float64(s[i][0])-127.5)/127.5,
+			(float64(s[i][1])-127.5)/127.5,
+		)
+	}
+	return s.Length(), nil
+}
+
+// Scale will multiply each I and Q value by the provided real value 'r',
+// around the 127.5 center point. Values are rounded and clipped to the
+// uint8 range.
+func (s SamplesU8) Scale(r float32) {
+	tab := scaleTableU8(r)
+	for i := range s {
+		s[i] = [2]uint8{tab[s[i][0]], tab[s[i][1]]}
+	}
+}
+
+// Multiply will conduct a complex multiplication of each phasor in this
+// buffer by a provided complex number 'c'. Values are rounded and clipped to
+// the uint8 range.
+//
+// This is done with small per-call lookup tables rather than by converting
+// to complex64. When applying the same multiplier to a lot of data, it may
+// be faster to call Multiply on LookupTableIdentityU8 once, and use that
+// with NewLookupTable.
+func (s SamplesU8) Multiply(c complex64) {
+	re, im := productTables(c, func(v uint8) float32 {
+		return float32(v) - 127.5
+	})
+	for i := range s {
+		vI, vQ := s[i][0], s[i][1]
+		s[i] = [2]uint8{
+			uint8(roundClip(re[vI]-im[vQ]+127.5, 0, math.MaxUint8)),
+			uint8(roundClip(im[vI]+re[vQ]+127.5, 0, math.MaxUint8)),
+		}
+	}
+}
+
+// Add will add each phasor in 'c' to the phasor at the same index in this
+// buffer, treating both as offset around 127.5. Values are rounded and
+// clipped to the uint8 range.
+func (s SamplesU8) Add(c SamplesU8) error {
+	if len(s) != len(c) {
+		return ErrLengthMismatch
+	}
+	for i := range s {
+		s[i] = [2]uint8{
+			uint8(roundClip(float32(s[i][0])+float32(c[i][0])-127.5, 0, math.MaxUint8)),
+			uint8(roundClip(float32(s[i][1])+float32(c[i][1])-127.5, 0, math.MaxUint8)),
+		}
+	}
+	return nil
+}
+
+// Conjugate will replace each phasor in this buffer with its complex
+// conjugate. Since uint8 samples are centered on 127.5, this is exact.
+func (s SamplesU8) Conjugate() {
+	for i := range s {
+		s[i][1] = math.MaxUint8 - s[i][1]
+	}
+}
+
+// Power will write the power (I^2 + Q^2) of each sample to 'dst', scaled
+// the same way as ToC64, so that a full scale sample is 1.
+func (s SamplesU8) Power(dst []float32) error {
+	if len(dst) < len(s) {
+		return ErrDstTooSmall
+	}
+	var sq [256]float32
+	for v := range sq {
+		x := (float32(v) - 127.5) / 127.5
+		sq[v] = x * x
+	}
+	for i := range s {
+		dst[i] = sq[s[i][0]] + sq[s[i][1]]
+	}
+	return nil
+}
+
+// Magnitude will write the magnitude of each sample to 'dst', scaled the
+// same way as ToC64, so that a full scale sample is 1.
+func (s SamplesU8) Magnitude(dst []float32) error {
+	if err := s.Power(dst); err != nil {
+		return err
+	}
+	for i := range s {
+		dst[i] = float32(math.Sqrt(float64(dst[i])))
+	}
+	return nil
+}
+
 // vim: foldmethod=marker
@@ -43,6 +43,10 @@ func UnsafeSamplesAsBytes(buf Samples) ([]byte, error) {
 		base = uintptr(unsafe.Pointer(&buf[0]))
 	case SamplesC64:
 		base = uintptr(unsafe.Pointer(&buf[0]))
+	case SamplesC128:
+		base = uintptr(unsafe.Pointer(&buf[0]))
+	case SamplesI12Packed:
+		base = uintptr(unsafe.Pointer(&buf[0]))
 	default:
 		return nil, ErrSampleFormatUnknown
 	}
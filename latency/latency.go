@@ -0,0 +1,378 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package latency
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"hz.tools/sdr"
+)
+
+var (
+	// ErrNoMarkers will be returned if none of the markers sent made it to
+	// the sdr.Reader before the timeout.
+	ErrNoMarkers = fmt.Errorf("latency: no markers were received")
+)
+
+// Config contains the parameters of a latency measurement.
+type Config struct {
+	// Marker is the IQ burst that will be written to the sdr.Writer, and
+	// searched for in the sdr.Reader. If nil, DefaultMarker will be used.
+	Marker sdr.SamplesC64
+
+	// Threshold is the normalized correlation (from 0 to 1) at which the
+	// Marker is considered to have been received. If zero, 0.8 is used.
+	Threshold float32
+
+	// Count is the number of markers to send. If zero, 10 markers are sent.
+	Count int
+
+	// Interval is the time between markers, which must be longer than the
+	// latency being measured. If zero, 250ms is used.
+	Interval time.Duration
+
+	// Timeout is the amount of time to wait for the last marker to be
+	// received after it's been sent. If zero, 5 seconds is used.
+	Timeout time.Duration
+
+	// BufferLength is the number of samples to Write or Read at a time. This
+	// is part of the latency being measured, so it should match whatever
+	// the application is going to be using. If zero, 16384 is used.
+	BufferLength int
+}
+
+func (c Config) getMarker() sdr.SamplesC64 {
+	if c.Marker != nil {
+		return c.Marker
+	}
+	return DefaultMarker()
+}
+
+func (c Config) getThreshold() float64 {
+	if c.Threshold != 0 {
+		return float64(c.Threshold)
+	}
+	return 0.8
+}
+
+func (c Config) getCount() int {
+	if c.Count != 0 {
+		return c.Count
+	}
+	return 10
+}
+
+func (c Config) getInterval() time.Duration {
+	if c.Interval != 0 {
+		return c.Interval
+	}
+	return time.Millisecond * 250
+}
+
+func (c Config) getTimeout() time.Duration {
+	if c.Timeout != 0 {
+		return c.Timeout
+	}
+	return time.Second * 5
+}
+
+func (c Config) getBufferLength() int {
+	if c.BufferLength != 0 {
+		return c.BufferLength
+	}
+	return 16 * 1024
+}
+
+// Result contains the latency of every marker that was received, in the
+// order they were sent.
+type Result struct {
+	// Latencies is the time between the call to Write with the buffer
+	// containing the marker, and the return of the Read call which contained
+	// the end of the marker.
+	Latencies []time.Duration
+
+	// Lost is the number of markers that were sent, but never found.
+	Lost int
+}
+
+func (r Result) sorted() []time.Duration {
+	ret := make([]time.Duration, len(r.Latencies))
+	copy(ret, r.Latencies)
+	sort.Slice(ret, func(i, j int) bool { return ret[i] < ret[j] })
+	return ret
+}
+
+// Min will return the smallest latency measured.
+func (r Result) Min() time.Duration {
+	return r.Percentile(0)
+}
+
+// Max will return the largest latency measured.
+func (r Result) Max() time.Duration {
+	return r.Percentile(1)
+}
+
+// Mean will return the average latency measured.
+func (r Result) Mean() time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, l := range r.Latencies {
+		total += l
+	}
+	return total / time.Duration(len(r.Latencies))
+}
+
+// Percentile will return the latency at the provided percentile, from 0 to 1
+// (so 0.5 is the median, and 0.99 is the p99), using the nearest rank.
+func (r Result) Percentile(p float64) time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+	sorted := r.sorted()
+	idx := int(p*float64(len(sorted)-1) + 0.5)
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
+
+// String will return a human readable summary of the Result.
+func (r Result) String() string {
+	return fmt.Sprintf(
+		"n=%d lost=%d min=%s p50=%s mean=%s p99=%s max=%s",
+		len(r.Latencies), r.Lost,
+		r.Min(), r.Percentile(0.5), r.Mean(), r.Percentile(0.99), r.Max(),
+	)
+}
+
+// injections keeps track of the host time each marker was written at.
+type injections struct {
+	lock  *sync.Mutex
+	times []time.Time
+}
+
+func (i *injections) add(t time.Time) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.times = append(i.times, t)
+}
+
+// latest will return the index and time of the newest marker that was sent
+// at or before t, or -1 if there was none.
+func (i *injections) latest(t time.Time) (int, time.Time) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	for idx := len(i.times) - 1; idx >= 0; idx-- {
+		if !i.times[idx].After(t) {
+			return idx, i.times[idx]
+		}
+	}
+	return -1, time.Time{}
+}
+
+func (i *injections) count() (int, time.Time) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if len(i.times) == 0 {
+		return 0, time.Time{}
+	}
+	return len(i.times), i.times[len(i.times)-1]
+}
+
+// makeBuffer will convert the complex64 samples into the provided format.
+func makeBuffer(sf sdr.SampleFormat, in sdr.SamplesC64) (sdr.Samples, error) {
+	out, err := sdr.MakeSamples(sf, len(in))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := sdr.ConvertBuffer(out, in); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// transmit will write silence to the sdr.Writer, with a marker every
+// interval, until count markers have been written. After that, silence
+// will be written until stop is closed.
+func transmit(
+	tx sdr.Writer,
+	cfg Config,
+	sent *injections,
+	stop chan struct{},
+) error {
+	var (
+		bufLen   = cfg.getBufferLength()
+		marker   = cfg.getMarker()
+		interval = int(cfg.getInterval().Seconds() * float64(tx.SampleRate()))
+		count    = cfg.getCount()
+		since    = interval
+	)
+
+	markerC64 := make(sdr.SamplesC64, bufLen)
+	copy(markerC64, marker)
+
+	silence, err := makeBuffer(tx.SampleFormat(), make(sdr.SamplesC64, bufLen))
+	if err != nil {
+		return err
+	}
+	markerBuf, err := makeBuffer(tx.SampleFormat(), markerC64)
+	if err != nil {
+		return err
+	}
+
+	for n := 0; ; {
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+
+		buf := silence
+		if n < count && since >= interval {
+			buf = markerBuf
+			since = 0
+			n++
+			sent.add(time.Now())
+		}
+
+		if _, err := tx.Write(buf); err != nil {
+			return err
+		}
+		since += bufLen
+	}
+}
+
+// Measure will write markers to the provided sdr.Writer, and search for them
+// in the provided sdr.Reader, returning the time it took for each marker to
+// make it through. The sdr.Writer and sdr.Reader may be of any SampleFormat,
+// but the sdr.Writer must have a SampleRate set.
+//
+// Silence is written to the Writer between markers, since most radios need
+// to be continuously fed with samples. Once this returns, the caller is
+// expected to Close the sdr.Writer and sdr.Reader.
+func Measure(tx sdr.Writer, rx sdr.Reader, cfg Config) (*Result, error) {
+	if tx.SampleRate() == 0 {
+		return nil, fmt.Errorf("latency: sdr.Writer has no SampleRate")
+	}
+	if len(cfg.getMarker()) > cfg.getBufferLength() {
+		return nil, fmt.Errorf("latency: Marker is longer than the BufferLength")
+	}
+
+	rxBuf, err := sdr.MakeSamples(rx.SampleFormat(), cfg.getBufferLength())
+	if err != nil {
+		return nil, err
+	}
+	iq := make(sdr.SamplesC64, rxBuf.Length())
+
+	var (
+		sent    = &injections{lock: &sync.Mutex{}}
+		stop    = make(chan struct{})
+		txErr   = make(chan error, 1)
+		corr    = newCorrelator(cfg.getMarker(), cfg.getThreshold())
+		count   = cfg.getCount()
+		timeout = cfg.getTimeout()
+		matched = 0
+		result  = &Result{}
+	)
+
+	go func() {
+		txErr <- transmit(tx, cfg, sent, stop)
+	}()
+
+	// shutdown will stop the transmit goroutine, which may be blocked on a
+	// Write until we Read (in the case of a loopback Pipe), so we'll keep
+	// reading until it's done. The last Read may still be pending when this
+	// returns, which is why the caller needs to Close the Reader.
+	shutdown := func() error {
+		close(stop)
+		reads := make(chan error, 1)
+		for {
+			go func() {
+				_, err := rx.Read(rxBuf)
+				reads <- err
+			}()
+			select {
+			case err := <-txErr:
+				return err
+			case err := <-reads:
+				if err != nil {
+					return nil
+				}
+			}
+		}
+	}
+
+	for matched < count {
+		select {
+		case err := <-txErr:
+			return nil, err
+		default:
+		}
+
+		n, err := rx.Read(rxBuf)
+		now := time.Now()
+		if err != nil {
+			close(stop)
+			return nil, err
+		}
+
+		if _, err := sdr.ConvertBuffer(iq, rxBuf.Slice(0, n)); err != nil {
+			close(stop)
+			return nil, err
+		}
+
+		for range corr.find(iq[:n]) {
+			idx, when := sent.latest(now)
+			if idx < matched {
+				// Either a spurious match, or we've already seen this one.
+				continue
+			}
+			result.Lost += idx - matched
+			result.Latencies = append(result.Latencies, now.Sub(when))
+			matched = idx + 1
+		}
+
+		nsent, last := sent.count()
+		if nsent == count && now.Sub(last) > timeout {
+			result.Lost += count - matched
+			break
+		}
+	}
+
+	if err := shutdown(); err != nil {
+		return nil, err
+	}
+
+	if len(result.Latencies) == 0 {
+		return nil, ErrNoMarkers
+	}
+	return result, nil
+}
+
+// vim: foldmethod=marker
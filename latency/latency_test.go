@@ -0,0 +1,130 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package latency_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/latency"
+)
+
+// shortReader will only ever return up to n samples per Read, which
+// causes markers to straddle Read calls.
+type shortReader struct {
+	sdr.Reader
+	n int
+}
+
+func (sr shortReader) Read(s sdr.Samples) (int, error) {
+	if s.Length() > sr.n {
+		s = s.Slice(0, sr.n)
+	}
+	return sr.Reader.Read(s)
+}
+
+func TestMeasureLoopback(t *testing.T) {
+	for _, sf := range []sdr.SampleFormat{
+		sdr.SampleFormatC64,
+		sdr.SampleFormatI16,
+		sdr.SampleFormatI8,
+		sdr.SampleFormatU8,
+	} {
+		t.Run(sf.String(), func(t *testing.T) {
+			pipeReader, pipeWriter := sdr.Pipe(1000000, sf)
+			defer pipeReader.Close()
+
+			result, err := latency.Measure(pipeWriter, pipeReader, latency.Config{
+				Count:        5,
+				Interval:     time.Millisecond * 10,
+				BufferLength: 1024,
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, 5, len(result.Latencies))
+			assert.Equal(t, 0, result.Lost)
+			assert.True(t, result.Max() < time.Second)
+			assert.True(t, result.Min() <= result.Percentile(0.5))
+		})
+	}
+}
+
+func TestMeasureStraddle(t *testing.T) {
+	pipeReader, pipeWriter := sdr.Pipe(1000000, sdr.SampleFormatC64)
+	defer pipeReader.Close()
+
+	result, err := latency.Measure(
+		pipeWriter,
+		shortReader{Reader: pipeReader, n: 77},
+		latency.Config{
+			Count:        5,
+			Interval:     time.Millisecond * 10,
+			BufferLength: 1024,
+		},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, len(result.Latencies))
+}
+
+func TestMeasureNoMarkers(t *testing.T) {
+	pipeReader, pipeWriter := sdr.Pipe(1000000, sdr.SampleFormatC64)
+	defer pipeReader.Close()
+
+	// Write to one pipe, and read from a Reader that will only ever return
+	// silence.
+	go sdr.Copy(sdr.Discard(1000000, sdr.SampleFormatC64), pipeReader)
+	silenceReader, silenceWriter := sdr.Pipe(1000000, sdr.SampleFormatC64)
+	go func() {
+		buf := make(sdr.SamplesC64, 1024)
+		for {
+			if _, err := silenceWriter.Write(buf); err != nil {
+				return
+			}
+		}
+	}()
+	defer silenceReader.Close()
+
+	_, err := latency.Measure(pipeWriter, silenceReader, latency.Config{
+		Count:        2,
+		Interval:     time.Millisecond * 10,
+		Timeout:      time.Millisecond * 100,
+		BufferLength: 1024,
+	})
+	assert.Equal(t, latency.ErrNoMarkers, err)
+}
+
+func TestResult(t *testing.T) {
+	result := latency.Result{
+		Latencies: []time.Duration{
+			time.Millisecond * 3,
+			time.Millisecond * 1,
+			time.Millisecond * 2,
+		},
+	}
+	assert.Equal(t, time.Millisecond, result.Min())
+	assert.Equal(t, time.Millisecond*3, result.Max())
+	assert.Equal(t, time.Millisecond*2, result.Mean())
+	assert.Equal(t, time.Millisecond*2, result.Percentile(0.5))
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,130 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package latency
+
+import (
+	"math"
+
+	"hz.tools/sdr"
+)
+
+// DefaultMarker will return the marker used if none is provided in the
+// Config, which is a 127 chip BPSK maximal length sequence at half of full
+// scale. This has a sharp autocorrelation peak, and doesn't look much like
+// anything else that'll show up over the air.
+func DefaultMarker() sdr.SamplesC64 {
+	var (
+		lfsr   uint8 = 0x7F
+		marker       = make(sdr.SamplesC64, 127)
+	)
+	for i := range marker {
+		// x^7 + x^6 + 1
+		bit := ((lfsr >> 6) ^ (lfsr >> 5)) & 0x01
+		lfsr = ((lfsr << 1) | bit) & 0x7F
+		if bit == 1 {
+			marker[i] = complex(0.5, 0)
+		} else {
+			marker[i] = complex(-0.5, 0)
+		}
+	}
+	return marker
+}
+
+// correlator will search for a marker in a stream of samples, carrying the
+// tail of the previous buffer over so that markers split across two Reads
+// are found.
+type correlator struct {
+	marker     sdr.SamplesC64
+	markerNorm float64
+	threshold  float64
+
+	// history is the last len(marker)-1 samples from the previous call
+	// to find, followed by the current buffer.
+	history sdr.SamplesC64
+
+	// skip is the number of samples to skip before searching again, which
+	// prevents the same marker from being found twice.
+	skip int
+}
+
+func newCorrelator(marker sdr.SamplesC64, threshold float64) *correlator {
+	var norm float64
+	for _, s := range marker {
+		norm += float64(real(s)*real(s) + imag(s)*imag(s))
+	}
+	return &correlator{
+		marker:     marker,
+		markerNorm: math.Sqrt(norm),
+		threshold:  threshold,
+	}
+}
+
+// find will return the offsets into buf at which the marker *ended*, which
+// may be negative if the marker started in a previous buffer. This is the
+// index of the first sample after the marker, minus one.
+func (c *correlator) find(buf sdr.SamplesC64) []int {
+	var (
+		ret     []int
+		ml      = len(c.marker)
+		carried = len(c.history)
+	)
+
+	c.history = append(c.history, buf...)
+
+	for i := 0; i+ml <= len(c.history); i++ {
+		if c.skip > 0 {
+			c.skip--
+			continue
+		}
+
+		var (
+			acc    complex128
+			energy float64
+			window = c.history[i : i+ml]
+		)
+		for j, s := range window {
+			m := c.marker[j]
+			acc += complex128(s) * complex(float64(real(m)), -float64(imag(m)))
+			energy += float64(real(s)*real(s) + imag(s)*imag(s))
+		}
+		if energy == 0 {
+			continue
+		}
+
+		corr := math.Hypot(real(acc), imag(acc)) / (c.markerNorm * math.Sqrt(energy))
+		if corr >= c.threshold {
+			ret = append(ret, i+ml-1-carried)
+			c.skip = ml - 1
+		}
+	}
+
+	// Keep the last ml-1 samples around for the next go, since there may
+	// be a marker that straddles the two buffers.
+	keep := ml - 1
+	if keep > len(c.history) {
+		keep = len(c.history)
+	}
+	consumed := len(c.history) - keep
+	c.history = append(c.history[:0], c.history[consumed:]...)
+	return ret
+}
+
+// vim: foldmethod=marker
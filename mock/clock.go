@@ -0,0 +1,156 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package mock
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"hz.tools/sdr"
+)
+
+// Clock simulates the sample clock of a real radio. Rather than samples
+// being returned as fast as the consumer is able to read them (or written
+// as fast as the producer is able to write them), samples are paced to
+// the sample rate.
+//
+// Real radios never quite run at the requested sample rate; the oscillator
+// will be off by some number of parts per million, and samples arrive in
+// bursts as USB transfers complete. This allows timing sensitive code (such
+// as AFC, resamplers or schedulers) to be tested against something that
+// behaves more like hardware.
+type Clock struct {
+	// DriftPPM is how far off (in parts per million) the simulated sample
+	// clock is from the nominal sample rate. A positive value will cause
+	// samples to be produced (or consumed) faster than the nominal rate.
+	DriftPPM float64
+
+	// Jitter is the maximum amount of random delay added to each Read or
+	// Write, on top of the time it takes for the samples to be clocked
+	// through. Jitter is not cumulative; the long term rate is only
+	// controlled by the sample rate and DriftPPM.
+	Jitter time.Duration
+
+	// Source is used to generate the jitter. If nil, a source seeded with
+	// the current time is used.
+	Source rand.Source
+}
+
+// clock keeps track of the start time and number of samples that have been
+// clocked through.
+type clock struct {
+	lock    *sync.Mutex
+	rate    float64
+	jitter  time.Duration
+	rand    *rand.Rand
+	start   time.Time
+	samples int64
+}
+
+func (c Clock) new(sampleRate uint) *clock {
+	source := c.Source
+	if source == nil {
+		source = rand.NewSource(time.Now().UnixNano())
+	}
+	return &clock{
+		lock:   &sync.Mutex{},
+		rate:   float64(sampleRate) * (1 + c.DriftPPM/1e6),
+		jitter: c.Jitter,
+		rand:   rand.New(source),
+	}
+}
+
+// advance will account for n samples having been clocked through, and
+// return the time at which the last of those samples would have been
+// clocked through.
+func (c *clock) advance(n int) time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.start.IsZero() {
+		c.start = time.Now()
+	}
+	c.samples += int64(n)
+
+	deadline := c.start.Add(time.Duration(float64(c.samples) / c.rate * float64(time.Second)))
+	if c.jitter > 0 {
+		deadline = deadline.Add(time.Duration(c.rand.Int63n(int64(c.jitter))))
+	}
+	return deadline
+}
+
+// wait will sleep until n samples would have been clocked through.
+func (c *clock) wait(n int) {
+	if c.rate <= 0 || n == 0 {
+		return
+	}
+	if d := time.Until(c.advance(n)); d > 0 {
+		time.Sleep(d)
+	}
+}
+
+type clockedReader struct {
+	sdr.ReadCloser
+	clock *clock
+}
+
+// Read implements the sdr.Reader interface.
+func (cr clockedReader) Read(s sdr.Samples) (int, error) {
+	n, err := cr.ReadCloser.Read(s)
+	cr.clock.wait(n)
+	return n, err
+}
+
+type clockedWriter struct {
+	sdr.WriteCloser
+	clock *clock
+}
+
+// Write implements the sdr.Writer interface.
+func (cw clockedWriter) Write(s sdr.Samples) (int, error) {
+	n, err := cw.WriteCloser.Write(s)
+	cw.clock.wait(n)
+	return n, err
+}
+
+// Reader will wrap the provided ReadCloser, and pace Reads to the provided
+// sample rate, adjusted by the Clock's DriftPPM and Jitter. The returned
+// ReadCloser will still report the (nominal) SampleRate of the wrapped
+// ReadCloser, just as a real radio would.
+func (c Clock) Reader(r sdr.ReadCloser, sampleRate uint) sdr.ReadCloser {
+	return clockedReader{
+		ReadCloser: r,
+		clock:      c.new(sampleRate),
+	}
+}
+
+// Writer will wrap the provided WriteCloser, and block Writes until the
+// samples would have been clocked out at the provided sample rate, adjusted
+// by the Clock's DriftPPM and Jitter.
+func (c Clock) Writer(w sdr.WriteCloser, sampleRate uint) sdr.WriteCloser {
+	return clockedWriter{
+		WriteCloser: w,
+		clock:       c.new(sampleRate),
+	}
+}
+
+// vim: foldmethod=marker
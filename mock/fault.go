@@ -0,0 +1,197 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package mock
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"hz.tools/sdr"
+)
+
+var (
+	// ErrTimeout is returned by a FaultTimeout, after the Fault's Delay.
+	ErrTimeout = fmt.Errorf("mock: timeout")
+
+	// ErrOverflow is the default error returned by a FaultError, much like
+	// the overflow error code a radio such as a USRP would report in its
+	// rx metadata.
+	ErrOverflow = fmt.Errorf("mock: overflow")
+
+	// ErrDisconnected is the default error returned by a FaultDisconnect.
+	ErrDisconnected = fmt.Errorf("mock: device disconnected")
+)
+
+// FaultType is the kind of failure a Fault will inject into a stream.
+type FaultType int
+
+const (
+	// FaultTimeout will stall the Read or Write for the Fault's Delay, and
+	// then return ErrTimeout. The stream will continue after that.
+	FaultTimeout FaultType = iota + 1
+
+	// FaultShortRead will cause the Read or Write to process only Length
+	// samples (or half the buffer, if Length is 0), without an error.
+	FaultShortRead
+
+	// FaultError will return the Fault's Err (or ErrOverflow, if Err is
+	// nil) without processing any samples. The stream will continue
+	// after that.
+	FaultError
+
+	// FaultDisconnect will close the underlying stream, and return the
+	// Fault's Err (or ErrDisconnected, if Err is nil) from then on, as if
+	// the device had been unplugged.
+	FaultDisconnect
+)
+
+// Fault is a failure to inject into a stream, once At samples have passed
+// through it.
+type Fault struct {
+	// Type is the kind of Fault to inject.
+	Type FaultType
+
+	// At is the number of samples into the stream the Fault will happen
+	// at. Reads or Writes that would cross At are cut short, so that the
+	// Fault happens on exactly this sample.
+	At int64
+
+	// Delay is how long a FaultTimeout will stall for.
+	Delay time.Duration
+
+	// Length is the number of samples a FaultShortRead will process.
+	Length int
+
+	// Err is the error returned by FaultError or FaultDisconnect.
+	Err error
+}
+
+// Faults is a set of Faults to inject into a stream, to test how code
+// deals with misbehaving hardware, such as reconnect or watchdog logic.
+type Faults []Fault
+
+// faults is the state of a stream that faults are being injected into.
+type faults struct {
+	pending Faults
+	offset  int64
+	err     error
+}
+
+func (f Faults) new() *faults {
+	pending := make(Faults, len(f))
+	copy(pending, f)
+	sort.SliceStable(pending, func(i, j int) bool {
+		return pending[i].At < pending[j].At
+	})
+	return &faults{pending: pending}
+}
+
+// do will call fn on the provided buffer, injecting any pending faults.
+func (f *faults) do(
+	s sdr.Samples,
+	fn func(sdr.Samples) (int, error),
+	closer func() error,
+) (int, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+
+	if len(f.pending) == 0 || f.pending[0].At >= f.offset+int64(s.Length()) {
+		n, err := fn(s)
+		f.offset += int64(n)
+		return n, err
+	}
+
+	fault := f.pending[0]
+	if fault.At > f.offset {
+		// Run right up to the fault, so the next call will hit it.
+		n, err := fn(s.Slice(0, int(fault.At-f.offset)))
+		f.offset += int64(n)
+		return n, err
+	}
+	f.pending = f.pending[1:]
+
+	switch fault.Type {
+	case FaultTimeout:
+		time.Sleep(fault.Delay)
+		return 0, ErrTimeout
+	case FaultShortRead:
+		length := fault.Length
+		if length == 0 {
+			length = s.Length() / 2
+		}
+		if length > s.Length() {
+			length = s.Length()
+		}
+		n, err := fn(s.Slice(0, length))
+		f.offset += int64(n)
+		return n, err
+	case FaultError:
+		if fault.Err != nil {
+			return 0, fault.Err
+		}
+		return 0, ErrOverflow
+	case FaultDisconnect:
+		f.err = fault.Err
+		if f.err == nil {
+			f.err = ErrDisconnected
+		}
+		closer()
+		return 0, f.err
+	default:
+		return 0, fmt.Errorf("mock: unknown fault type %d", fault.Type)
+	}
+}
+
+type faultyReader struct {
+	sdr.ReadCloser
+	faults *faults
+}
+
+// Read implements the sdr.Reader interface.
+func (fr faultyReader) Read(s sdr.Samples) (int, error) {
+	return fr.faults.do(s, fr.ReadCloser.Read, fr.ReadCloser.Close)
+}
+
+type faultyWriter struct {
+	sdr.WriteCloser
+	faults *faults
+}
+
+// Write implements the sdr.Writer interface.
+func (fw faultyWriter) Write(s sdr.Samples) (int, error) {
+	return fw.faults.do(s, fw.WriteCloser.Write, fw.WriteCloser.Close)
+}
+
+// Reader will wrap the provided ReadCloser, injecting the Faults as samples
+// are read. Reads must not be called concurrently.
+func (f Faults) Reader(r sdr.ReadCloser) sdr.ReadCloser {
+	return faultyReader{ReadCloser: r, faults: f.new()}
+}
+
+// Writer will wrap the provided WriteCloser, injecting the Faults as samples
+// are written. Writes must not be called concurrently.
+func (f Faults) Writer(w sdr.WriteCloser) sdr.WriteCloser {
+	return faultyWriter{WriteCloser: w, faults: f.new()}
+}
+
+// vim: foldmethod=marker
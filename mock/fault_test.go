@@ -0,0 +1,132 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package mock_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/mock"
+)
+
+// fullReader always fills the buffer, unlike a Pipe, which makes counting
+// samples a lot easier.
+type fullReader struct{}
+
+func (fullReader) Read(s sdr.Samples) (int, error) { return s.Length(), nil }
+func (fullReader) Close() error                    { return nil }
+func (fullReader) SampleRate() uint                { return 100000 }
+func (fullReader) SampleFormat() sdr.SampleFormat  { return sdr.SampleFormatC64 }
+
+func TestFaultsRx(t *testing.T) {
+	source := fullReader{}
+
+	errLateCommand := fmt.Errorf("late command")
+
+	dev := mock.New(mock.Config{
+		SampleRate:   100000,
+		SampleFormat: sdr.SampleFormatC64,
+		Rx:           mock.ThisRx(source),
+		RxFaults: mock.Faults{
+			{Type: mock.FaultDisconnect, At: 5000},
+			{Type: mock.FaultShortRead, At: 1500, Length: 10},
+			{Type: mock.FaultTimeout, At: 1000, Delay: time.Millisecond * 10},
+			{Type: mock.FaultError, At: 2000},
+			{Type: mock.FaultError, At: 2000, Err: errLateCommand},
+		},
+	})
+
+	rx, err := dev.StartRx()
+	assert.NoError(t, err)
+
+	buf := make(sdr.SamplesC64, 1000)
+	n, err := rx.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, n)
+
+	start := time.Now()
+	n, err = rx.Read(buf)
+	assert.Equal(t, mock.ErrTimeout, err)
+	assert.Equal(t, 0, n)
+	assert.True(t, time.Since(start) >= time.Millisecond*10)
+
+	// Cut short to land on the short read
+	n, err = rx.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 500, n)
+
+	n, err = rx.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, n)
+
+	n, err = rx.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 490, n)
+
+	_, err = rx.Read(buf)
+	assert.Equal(t, mock.ErrOverflow, err)
+	_, err = rx.Read(buf)
+	assert.Equal(t, errLateCommand, err)
+
+	for i := 0; i < 3; i++ {
+		n, err = rx.Read(buf)
+		assert.NoError(t, err)
+		assert.Equal(t, 1000, n)
+	}
+
+	_, err = rx.Read(buf)
+	assert.Equal(t, mock.ErrDisconnected, err)
+	_, err = rx.Read(buf)
+	assert.Equal(t, mock.ErrDisconnected, err)
+
+	// Starting again starts the faults over.
+	rx, err = dev.StartRx()
+	assert.NoError(t, err)
+	_, err = sdr.ReadFull(rx, make(sdr.SamplesC64, 1000))
+	assert.NoError(t, err)
+}
+
+func TestFaultsTx(t *testing.T) {
+	pipeReader, pipeWriter := sdr.Pipe(100000, sdr.SampleFormatC64)
+	go sdr.Copy(sdr.Discard(100000, sdr.SampleFormatC64), pipeReader)
+
+	tx := mock.Faults{{Type: mock.FaultDisconnect, At: 1500}}.Writer(pipeWriter)
+
+	buf := make(sdr.SamplesC64, 1000)
+	n, err := tx.Write(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, n)
+	n, err = tx.Write(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 500, n)
+	_, err = tx.Write(buf)
+	assert.Equal(t, mock.ErrDisconnected, err)
+
+	// The underlying writer was closed, as if the device went away.
+	_, err = pipeWriter.Write(buf)
+	assert.Error(t, err)
+}
+
+// vim: foldmethod=marker
@@ -64,6 +64,21 @@ type Config struct {
 	// GainStages if not nil, will be used as the gain stages supported by the
 	// MockSDR. If nil, No gain stages will be returned or settable.
 	GainStages sdr.GainStages
+
+	// Clock, if not nil, will be used to pace the Rx and Tx streams to the
+	// SampleRate (at the time StartRx or StartTx is called), rather than
+	// returning samples as fast as they're able to be read or written.
+	Clock *Clock
+
+	// RxFaults and TxFaults, if set, will be injected into the Rx and Tx
+	// streams. Each call to StartRx or StartTx starts from the beginning
+	// of the Faults again.
+	RxFaults Faults
+	TxFaults Faults
+
+	// Telemetry, if not nil, will be called by ReadTelemetry. If this is
+	// nil, ReadTelemetry will return sdr.ErrNotSupported.
+	Telemetry func() (sdr.TelemetryReadings, error)
 }
 
 func (m *mockSdr) HardwareInfo() sdr.HardwareInfo {
@@ -74,6 +89,14 @@ func (m *mockSdr) HardwareInfo() sdr.HardwareInfo {
 	}
 }
 
+// ReadTelemetry implements the sdr.Telemetry interface.
+func (m *mockSdr) ReadTelemetry() (sdr.TelemetryReadings, error) {
+	if m.config.Telemetry == nil {
+		return sdr.TelemetryReadings{}, sdr.ErrNotSupported
+	}
+	return m.config.Telemetry()
+}
+
 // Close implements the sdr.Sdr interface.
 func (m *mockSdr) Close() error {
 	return nil
@@ -159,6 +182,12 @@ func (m *mockSdr) StartRx() (sdr.ReadCloser, error) {
 	if rx.SampleFormat() != m.config.SampleFormat {
 		return nil, sdr.ErrNotSupported
 	}
+	if m.config.Clock != nil {
+		rx = m.config.Clock.Reader(rx, m.config.SampleRate)
+	}
+	if len(m.config.RxFaults) > 0 {
+		rx = m.config.RxFaults.Reader(rx)
+	}
 	return rx, nil
 }
 
@@ -175,6 +204,12 @@ func (m *mockSdr) StartTx() (sdr.WriteCloser, error) {
 	if tx.SampleFormat() != m.config.SampleFormat {
 		return nil, sdr.ErrNotSupported
 	}
+	if m.config.Clock != nil {
+		tx = m.config.Clock.Writer(tx, m.config.SampleRate)
+	}
+	if len(m.config.TxFaults) > 0 {
+		tx = m.config.TxFaults.Writer(tx)
+	}
 	return tx, nil
 }
 
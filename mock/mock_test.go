@@ -22,14 +22,18 @@ package mock_test
 
 import (
 	"context"
+	"io"
+	"math/rand"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
 	"hz.tools/rf"
 	"hz.tools/sdr"
 	"hz.tools/sdr/mock"
+	"hz.tools/sdr/stream"
 )
 
 func TestSetGet(t *testing.T) {
@@ -80,6 +84,27 @@ func TestSetRead(t *testing.T) {
 	wg.Wait()
 }
 
+func TestLoopRx(t *testing.T) {
+	loop, err := stream.LoopN(sdr.SamplesI16{{1, 2}, {3, 4}}, 1000, 2)
+	assert.NoError(t, err)
+
+	dev := mock.New(mock.Config{
+		Rx:           mock.ThisRx(sdr.ReaderWithCloser(loop, func() error { return nil })),
+		SampleFormat: sdr.SampleFormatI16,
+	})
+
+	rx, err := dev.StartRx()
+	assert.NoError(t, err)
+
+	buf := make(sdr.SamplesI16, 4)
+	_, err = sdr.ReadFull(rx, buf)
+	assert.NoError(t, err)
+	assert.Equal(t, sdr.SamplesI16{{1, 2}, {3, 4}, {1, 2}, {3, 4}}, buf)
+
+	_, err = rx.Read(buf)
+	assert.Equal(t, io.EOF, err)
+}
+
 func TestSetWrite(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.TODO())
 	defer cancel()
@@ -113,4 +138,117 @@ func TestSetWrite(t *testing.T) {
 	wg.Wait()
 }
 
+func zeroReader(sampleRate uint) sdr.ReadCloser {
+	pipeReader, pipeWriter := sdr.Pipe(sampleRate, sdr.SampleFormatC64)
+	go func() {
+		buf := make(sdr.SamplesC64, 1024)
+		for {
+			if _, err := pipeWriter.Write(buf); err != nil {
+				return
+			}
+		}
+	}()
+	return pipeReader
+}
+
+func timeRead(t *testing.T, rx sdr.Reader, samples int) time.Duration {
+	buf := make(sdr.SamplesC64, 1000)
+	start := time.Now()
+	for i := 0; i < samples; i += buf.Length() {
+		_, err := sdr.ReadFull(rx, buf)
+		assert.NoError(t, err)
+	}
+	return time.Since(start)
+}
+
+func TestClockRx(t *testing.T) {
+	source := zeroReader(100000)
+	defer source.Close()
+
+	dev := mock.New(mock.Config{
+		SampleRate:   100000,
+		SampleFormat: sdr.SampleFormatC64,
+		Rx:           mock.ThisRx(source),
+		Clock:        &mock.Clock{},
+	})
+
+	rx, err := dev.StartRx()
+	assert.NoError(t, err)
+	assert.Equal(t, uint(100000), rx.SampleRate())
+
+	took := timeRead(t, rx, 20000)
+	assert.True(t, took >= time.Millisecond*190, "took %s", took)
+	assert.True(t, took < time.Millisecond*400, "took %s", took)
+}
+
+// fastestRead will time reading from a fresh Clock a few times, and return
+// the fastest, which is the one least disturbed by anything else running.
+func fastestRead(t *testing.T, clock mock.Clock, samples int) time.Duration {
+	var fastest time.Duration
+	for i := 0; i < 3; i++ {
+		source := zeroReader(100000)
+		took := timeRead(t, clock.Reader(source, 100000), samples)
+		source.Close()
+		if i == 0 || took < fastest {
+			fastest = took
+		}
+	}
+	return fastest
+}
+
+func TestClockDrift(t *testing.T) {
+	// 25% fast is a truly awful oscillator, but it makes for a test that
+	// isn't too sensitive to scheduling. Rather than checking the wall
+	// clock, this compares against an undrifted Clock, which should take
+	// 1.25 times as long.
+	var (
+		nominal = fastestRead(t, mock.Clock{}, 20000)
+		drifted = fastestRead(t, mock.Clock{DriftPPM: 250000}, 20000)
+		ratio   = float64(drifted) / float64(nominal)
+	)
+	assert.True(t, drifted >= time.Millisecond*155, "took %s", drifted)
+	assert.InDelta(t, 0.8, ratio, 0.1, "nominal %s, drifted %s", nominal, drifted)
+}
+
+func TestClockJitter(t *testing.T) {
+	source := zeroReader(100000)
+	defer source.Close()
+
+	rx := mock.Clock{
+		Jitter: time.Millisecond * 20,
+		Source: rand.NewSource(1),
+	}.Reader(source, 100000)
+
+	// Jitter isn't cumulative, so the total should still be close to the
+	// nominal rate, plus at most one Jitter.
+	took := timeRead(t, rx, 20000)
+	assert.True(t, took >= time.Millisecond*190, "took %s", took)
+	assert.True(t, took < time.Millisecond*400, "took %s", took)
+}
+
+func TestClockTx(t *testing.T) {
+	pipeReader, pipeWriter := sdr.Pipe(100000, sdr.SampleFormatC64)
+	go sdr.Copy(sdr.Discard(100000, sdr.SampleFormatC64), pipeReader)
+	defer pipeReader.Close()
+
+	dev := mock.New(mock.Config{
+		SampleRate:   100000,
+		SampleFormat: sdr.SampleFormatC64,
+		Tx:           mock.ThisTx(pipeWriter),
+		Clock:        &mock.Clock{},
+	})
+
+	tx, err := dev.StartTx()
+	assert.NoError(t, err)
+
+	start := time.Now()
+	buf := make(sdr.SamplesC64, 1000)
+	for i := 0; i < 20; i++ {
+		_, err := tx.Write(buf)
+		assert.NoError(t, err)
+	}
+	took := time.Since(start)
+	assert.True(t, took >= time.Millisecond*190, "took %s", took)
+}
+
 // vim: foldmethod=marker
@@ -0,0 +1,83 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package mod
+
+import (
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/audio"
+)
+
+// AMConfig controls the behavior of the AM modulator.
+type AMConfig struct {
+	// SampleRate is the sample rate of the IQ samples to produce. This is
+	// required.
+	SampleRate uint
+
+	// Depth is the modulation depth of full scale audio, from 0 to 1. If
+	// 0, this will default to 0.8, leaving some headroom to avoid
+	// overmodulating.
+	Depth float32
+
+	// AudioBandwidth is the highest audio frequency that will be
+	// transmitted. If 0, this will default to 5 kHz.
+	AudioBandwidth rf.Hz
+}
+
+func (c AMConfig) getDepth() float32 {
+	if c.Depth == 0 {
+		return 0.8
+	}
+	return c.Depth
+}
+
+func (c AMConfig) getAudioBandwidth() rf.Hz {
+	if c.AudioBandwidth == 0 {
+		return 5 * rf.KHz
+	}
+	return c.AudioBandwidth
+}
+
+// AM will amplitude modulate (double sideband, with a full carrier) the
+// audio read from r, producing IQ samples at the configured SampleRate.
+// The carrier has a magnitude of 0.5, so full scale audio at full depth
+// will peak at 1.
+func AM(r audio.Reader, cfg AMConfig) (sdr.Reader, error) {
+	audioBandwidth := cfg.getAudioBandwidth()
+	if rf.Hz(cfg.SampleRate) < 2*audioBandwidth {
+		return nil, ErrSampleRateTooLow
+	}
+
+	m, err := newModReader(r, cfg.SampleRate, audioBandwidth)
+	if err != nil {
+		return nil, err
+	}
+
+	depth := cfg.getDepth()
+	m.iqStage = func(s sdr.SamplesC64) {
+		for i, sample := range s {
+			s[i] = complex(0.5*(1+depth*real(sample)), 0)
+		}
+	}
+	return m, nil
+}
+
+// vim: foldmethod=marker
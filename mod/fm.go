@@ -0,0 +1,117 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package mod
+
+import (
+	"math"
+	"time"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/audio"
+)
+
+// FMConfig controls the behavior of the FM modulator.
+type FMConfig struct {
+	// SampleRate is the sample rate of the IQ samples to produce. This is
+	// required.
+	SampleRate uint
+
+	// Deviation is the peak frequency deviation of full scale audio. If 0,
+	// this will default to 5 kHz (narrowband FM voice).
+	Deviation rf.Hz
+
+	// AudioBandwidth is the highest audio frequency that will be
+	// transmitted. If 0, this will default to 3 kHz.
+	AudioBandwidth rf.Hz
+
+	// Preemphasis is the time constant of the pre-emphasis filter (such as
+	// 75µs for broadcast FM in the Americas, or 50µs most other places),
+	// which should match the de-emphasis at the receiver. If 0, no
+	// pre-emphasis will be applied.
+	Preemphasis time.Duration
+}
+
+func (c FMConfig) getDeviation() rf.Hz {
+	if c.Deviation == 0 {
+		return 5 * rf.KHz
+	}
+	return c.Deviation
+}
+
+func (c FMConfig) getAudioBandwidth() rf.Hz {
+	if c.AudioBandwidth == 0 {
+		return 3 * rf.KHz
+	}
+	return c.AudioBandwidth
+}
+
+// FM will frequency modulate the audio read from r, producing IQ samples at
+// the configured SampleRate, with full scale audio deviating the carrier by
+// the configured Deviation. The output has a constant magnitude of 1.
+func FM(r audio.Reader, cfg FMConfig) (sdr.Reader, error) {
+	var (
+		deviation      = cfg.getDeviation()
+		audioBandwidth = cfg.getAudioBandwidth()
+	)
+
+	// Carson's rule.
+	if rf.Hz(cfg.SampleRate) < 2*(deviation+audioBandwidth) {
+		return nil, ErrSampleRateTooLow
+	}
+
+	m, err := newModReader(r, cfg.SampleRate, audioBandwidth)
+	if err != nil {
+		return nil, err
+	}
+
+	if tau := cfg.Preemphasis; tau > 0 {
+		// This is the exact inverse of a one-pole de-emphasis filter
+		// (y[n] = y[n-1] + alpha*(x[n] - y[n-1])), which is what the
+		// demod package uses, and has a gain of 1 at DC.
+		var (
+			alpha = float32(1 - math.Exp(-1/(tau.Seconds()*float64(r.SampleRate()))))
+			prev  float32
+		)
+		m.audioStage = func(s sdr.SamplesC64) {
+			for i, sample := range s {
+				x := real(sample)
+				s[i] = complex((x-(1-alpha)*prev)/alpha, 0)
+				prev = x
+			}
+		}
+	}
+
+	var (
+		phase float64
+		step  = 2 * math.Pi * float64(deviation) / float64(cfg.SampleRate)
+	)
+	m.iqStage = func(s sdr.SamplesC64) {
+		for i, sample := range s {
+			phase = math.Mod(phase+step*float64(real(sample)), 2*math.Pi)
+			sin, cos := math.Sincos(phase)
+			s[i] = complex(float32(cos), float32(sin))
+		}
+	}
+	return m, nil
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,158 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package mod
+
+import (
+	"fmt"
+	"io"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/audio"
+	"hz.tools/sdr/filter"
+)
+
+var (
+	// ErrSampleRateTooLow will be returned if the IQ sample rate is lower
+	// than the audio sample rate, or too low to contain the modulated
+	// signal.
+	ErrSampleRateTooLow = fmt.Errorf("mod: sample rate is too low")
+)
+
+// modReader is the common plumbing of each modulator. The audio is read,
+// lowpass filtered (to the audio bandwidth, or just under the audio nyquist
+// rate, whichever is lower), passed to audioStage at the audio sample rate, resampled
+// to the IQ sample rate, and then passed to iqStage.
+type modReader struct {
+	r          audio.Reader
+	sampleRate uint
+
+	lowpass   *filter.FIR
+	resampler *filter.Resampler
+
+	audioStage func(sdr.SamplesC64)
+	iqStage    func(sdr.SamplesC64)
+
+	audio   []float32
+	work    sdr.SamplesC64
+	out     sdr.SamplesC64
+	pending sdr.SamplesC64
+	err     error
+}
+
+func newModReader(
+	r audio.Reader,
+	sampleRate uint,
+	audioBandwidth rf.Hz,
+) (*modReader, error) {
+	audioRate := r.SampleRate()
+	if sampleRate < audioRate {
+		return nil, ErrSampleRateTooLow
+	}
+	if nyquist := rf.Hz(audioRate) / 2; audioBandwidth > nyquist*0.9 {
+		// The audio can't have anything above the nyquist rate anyway, so
+		// just leave room for the filter's transition band.
+		audioBandwidth = nyquist * 0.9
+	}
+
+	taps, err := filter.LowPassTaps(97, float64(audioBandwidth)/float64(audioRate))
+	if err != nil {
+		return nil, err
+	}
+
+	resampler, err := filter.NewResampler(audioRate, sampleRate, 24)
+	if err != nil {
+		return nil, err
+	}
+
+	const chunk = 4096
+	return &modReader{
+		r:          r,
+		sampleRate: sampleRate,
+		lowpass:    filter.NewFIR(taps),
+		resampler:  resampler,
+		audio:      make([]float32, chunk),
+		work:       make(sdr.SamplesC64, chunk),
+		out:        make(sdr.SamplesC64, resampler.OutputLength(chunk)),
+	}, nil
+}
+
+func (m *modReader) SampleFormat() sdr.SampleFormat {
+	return sdr.SampleFormatC64
+}
+
+func (m *modReader) SampleRate() uint {
+	return m.sampleRate
+}
+
+func (m *modReader) process(n int) error {
+	work := m.work[:n]
+	for i, sample := range m.audio[:n] {
+		work[i] = complex(sample, 0)
+	}
+	if _, err := m.lowpass.ProcessC64(work, work); err != nil {
+		return err
+	}
+	if m.audioStage != nil {
+		m.audioStage(work)
+	}
+
+	o, err := m.resampler.ProcessC64(m.out, work)
+	if err != nil {
+		return err
+	}
+	out := m.out[:o]
+	if m.iqStage != nil {
+		m.iqStage(out)
+	}
+	m.pending = out
+	return nil
+}
+
+func (m *modReader) Read(s sdr.Samples) (int, error) {
+	buf, ok := s.(sdr.SamplesC64)
+	if !ok {
+		return 0, sdr.ErrSampleFormatMismatch
+	}
+
+	for len(m.pending) == 0 {
+		if m.err != nil {
+			return 0, m.err
+		}
+		n, err := m.r.Read(m.audio)
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		m.err = err
+		if n == 0 {
+			continue
+		}
+		if perr := m.process(n); perr != nil {
+			m.err = perr
+			return 0, perr
+		}
+	}
+	n := copy(buf, m.pending)
+	m.pending = m.pending[n:]
+	return n, nil
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,189 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package mod_test
+
+import (
+	"io"
+	"math"
+	"math/cmplx"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/demod"
+	"hz.tools/sdr/mod"
+)
+
+// toneReader is an audio.Reader producing n samples of a tone.
+type toneReader struct {
+	sampleRate uint
+	freq       float64
+	amplitude  float64
+	remaining  int
+	t          int
+}
+
+func (tr *toneReader) SampleRate() uint { return tr.sampleRate }
+
+func (tr *toneReader) Read(buf []float32) (int, error) {
+	if tr.remaining == 0 {
+		return 0, io.EOF
+	}
+	if len(buf) > tr.remaining {
+		buf = buf[:tr.remaining]
+	}
+	for i := range buf {
+		buf[i] = float32(tr.amplitude * math.Sin(2*math.Pi*tr.freq*float64(tr.t)/float64(tr.sampleRate)))
+		tr.t++
+	}
+	tr.remaining -= len(buf)
+	return len(buf), nil
+}
+
+func readAll(t *testing.T, r sdr.Reader) sdr.SamplesC64 {
+	out := sdr.SamplesC64{}
+	buf := make(sdr.SamplesC64, 1000)
+	for {
+		n, err := r.Read(buf)
+		out = append(out, buf[:n]...)
+		if err == io.EOF {
+			return out
+		}
+		assert.NoError(t, err)
+	}
+}
+
+// meanFrequency will return the average frequency (in Hz) of the IQ
+// samples, from the phase change between samples.
+func meanFrequency(s sdr.SamplesC64, sampleRate uint) float64 {
+	var acc complex128
+	for i := 1; i < len(s); i++ {
+		acc += complex128(s[i]) * cmplx.Conj(complex128(s[i-1]))
+	}
+	return cmplx.Phase(acc) * float64(sampleRate) / (2 * math.Pi)
+}
+
+func TestFMRoundTrip(t *testing.T) {
+	for _, emphasis := range []time.Duration{0, 75 * time.Microsecond} {
+		testFMRoundTrip(t, emphasis)
+	}
+}
+
+func testFMRoundTrip(t *testing.T, emphasis time.Duration) {
+	tone := &toneReader{sampleRate: 8000, freq: 1000, amplitude: 0.5, remaining: 8000}
+	iq, err := mod.FM(tone, mod.FMConfig{SampleRate: 48000, Preemphasis: emphasis})
+	assert.NoError(t, err)
+	assert.Equal(t, uint(48000), iq.SampleRate())
+	assert.Equal(t, sdr.SampleFormatC64, iq.SampleFormat())
+
+	deemphasis := emphasis
+	if deemphasis == 0 {
+		deemphasis = -1
+	}
+	out, err := demod.NBFM(iq, demod.FMConfig{
+		AudioSampleRate: 8000,
+		Deemphasis:      deemphasis,
+	})
+	assert.NoError(t, err)
+
+	var (
+		buf = make([]float32, 8000)
+		n   int
+	)
+	for n < len(buf) {
+		i, err := out.Read(buf[n:])
+		n += i
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+	}
+	assert.InDelta(t, 8000, n, 10)
+
+	var sum float64
+	for _, s := range buf[n/2 : n] {
+		sum += float64(s) * float64(s)
+	}
+	assert.InDelta(t, 0.5/math.Sqrt2, math.Sqrt(sum/float64(n/2)), 0.02, "emphasis %s", emphasis)
+}
+
+func TestFMConstantMagnitude(t *testing.T) {
+	tone := &toneReader{sampleRate: 8000, freq: 1000, amplitude: 1, remaining: 8000}
+	iq, err := mod.FM(tone, mod.FMConfig{SampleRate: 48000})
+	assert.NoError(t, err)
+	for _, s := range readAll(t, iq) {
+		assert.InDelta(t, 1, cmplx.Abs(complex128(s)), 1e-4)
+	}
+}
+
+func TestAM(t *testing.T) {
+	tone := &toneReader{sampleRate: 8000, freq: 1000, amplitude: 1, remaining: 8000}
+	iq, err := mod.AM(tone, mod.AMConfig{SampleRate: 48000})
+	assert.NoError(t, err)
+
+	out := readAll(t, iq)
+	var (
+		min = math.Inf(1)
+		max = math.Inf(-1)
+	)
+	for _, s := range out[len(out)/2:] {
+		mag := cmplx.Abs(complex128(s))
+		min = math.Min(min, mag)
+		max = math.Max(max, mag)
+	}
+	assert.InDelta(t, 0.5*(1+0.8), max, 0.02)
+	assert.InDelta(t, 0.5*(1-0.8), min, 0.02)
+}
+
+func TestSSB(t *testing.T) {
+	for _, test := range []struct {
+		sideband mod.Sideband
+		freq     float64
+	}{
+		{mod.USB, 1000},
+		{mod.LSB, -1000},
+	} {
+		tone := &toneReader{sampleRate: 8000, freq: 1000, amplitude: 0.5, remaining: 8000}
+		iq, err := mod.SSB(tone, mod.SSBConfig{SampleRate: 48000, Sideband: test.sideband})
+		assert.NoError(t, err)
+
+		out := readAll(t, iq)[1000:]
+		assert.InDelta(t, test.freq, meanFrequency(out, 48000), 1)
+
+		// The unwanted sideband is suppressed, so the magnitude is
+		// (almost) constant.
+		for _, s := range out[:len(out)-1000] {
+			assert.InDelta(t, 0.5, cmplx.Abs(complex128(s)), 0.02)
+		}
+	}
+}
+
+func TestSampleRateTooLow(t *testing.T) {
+	tone := &toneReader{sampleRate: 8000}
+	_, err := mod.FM(tone, mod.FMConfig{SampleRate: 8000, Deviation: 75000})
+	assert.Equal(t, mod.ErrSampleRateTooLow, err)
+	_, err = mod.AM(tone, mod.AMConfig{SampleRate: 4000})
+	assert.Equal(t, mod.ErrSampleRateTooLow, err)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,116 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package mod
+
+import (
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/audio"
+	"hz.tools/sdr/filter"
+)
+
+// Sideband is which side of the carrier a single sideband signal is on.
+type Sideband bool
+
+var (
+	// USB is the upper sideband, where audio frequencies are above the
+	// carrier.
+	USB Sideband = true
+
+	// LSB is the lower sideband, where audio frequencies are below the
+	// carrier, and inverted.
+	LSB Sideband = false
+)
+
+// SSBConfig controls the behavior of the SSB modulator.
+type SSBConfig struct {
+	// SampleRate is the sample rate of the IQ samples to produce. This is
+	// required.
+	SampleRate uint
+
+	// Sideband is the sideband to transmit. This defaults to LSB, since
+	// that's the zero value.
+	Sideband Sideband
+
+	// AudioBandwidth is the highest audio frequency that will be
+	// transmitted. If 0, this will default to 3 kHz.
+	AudioBandwidth rf.Hz
+}
+
+func (c SSBConfig) getAudioBandwidth() rf.Hz {
+	if c.AudioBandwidth == 0 {
+		return 3 * rf.KHz
+	}
+	return c.AudioBandwidth
+}
+
+// hilbertTaps is the length of the Hilbert transformer used by SSB.
+const hilbertTaps = 65
+
+// SSB will single sideband modulate (with a suppressed carrier) the audio
+// read from r, producing IQ samples at the configured SampleRate.
+//
+// This uses the phasing method: the audio, and the audio phase shifted by
+// 90 degrees (with a Hilbert transformer) are used as the I and Q of the
+// signal, which cancels out the unwanted sideband.
+func SSB(r audio.Reader, cfg SSBConfig) (sdr.Reader, error) {
+	audioBandwidth := cfg.getAudioBandwidth()
+	if rf.Hz(cfg.SampleRate) < audioBandwidth {
+		return nil, ErrSampleRateTooLow
+	}
+
+	m, err := newModReader(r, cfg.SampleRate, audioBandwidth)
+	if err != nil {
+		return nil, err
+	}
+
+	taps, err := filter.HilbertTaps(hilbertTaps)
+	if err != nil {
+		return nil, err
+	}
+	delayTaps := make([]float32, hilbertTaps)
+	delayTaps[hilbertTaps/2] = 1
+
+	var (
+		hilbert = filter.NewFIR(taps)
+		delay   = filter.NewFIR(delayTaps)
+		shifted = sdr.SamplesC64{}
+		sign    = float32(-1)
+	)
+	if cfg.Sideband == USB {
+		sign = 1
+	}
+
+	m.audioStage = func(s sdr.SamplesC64) {
+		if cap(shifted) < len(s) {
+			shifted = make(sdr.SamplesC64, len(s))
+		}
+		shifted = shifted[:len(s)]
+		hilbert.ProcessC64(shifted, s)
+		delay.ProcessC64(s, s)
+		for i := range s {
+			s[i] = complex(real(s[i]), sign*real(shifted[i]))
+		}
+	}
+	return m, nil
+}
+
+// vim: foldmethod=marker
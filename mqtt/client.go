@@ -0,0 +1,269 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package mqtt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"hz.tools/sdr/bus"
+)
+
+var (
+	// ErrConnectionRefused will be returned if the broker refuses the
+	// connection, such as for bad credentials.
+	ErrConnectionRefused = fmt.Errorf("mqtt: connection refused by broker")
+
+	// ErrClosed will be returned when publishing on a closed Client.
+	ErrClosed = fmt.Errorf("mqtt: client is closed")
+)
+
+// Config is the configuration of the connection to the MQTT broker.
+type Config struct {
+	// Address is the host:port of the broker. If no port is provided,
+	// 1883 is used.
+	Address string
+
+	// ClientID identifies this client to the broker. If unset, one is
+	// generated from the current time.
+	ClientID string
+
+	// Username and Password, if set, are sent to the broker.
+	Username string
+	Password string
+
+	// TopicPrefix is prepended to every topic published to, such as
+	// "sdr/".
+	TopicPrefix string
+
+	// KeepAlive is how often a ping is sent to the broker, to keep the
+	// connection open. If unset, this will default to 30 seconds.
+	KeepAlive time.Duration
+
+	// Dial is used to connect to the broker. If unset, net.Dial is used.
+	Dial func(network, address string) (net.Conn, error)
+}
+
+func (c Config) getAddress() string {
+	if _, _, err := net.SplitHostPort(c.Address); err != nil {
+		return net.JoinHostPort(c.Address, "1883")
+	}
+	return c.Address
+}
+
+func (c Config) getClientID() string {
+	if c.ClientID == "" {
+		return fmt.Sprintf("hz.tools-sdr-%d", time.Now().UnixNano())
+	}
+	return c.ClientID
+}
+
+func (c Config) getKeepAlive() time.Duration {
+	if c.KeepAlive == 0 {
+		return time.Second * 30
+	}
+	return c.KeepAlive
+}
+
+func (c Config) getDial() func(string, string) (net.Conn, error) {
+	if c.Dial == nil {
+		return net.Dial
+	}
+	return c.Dial
+}
+
+// Client is a connection to an MQTT broker.
+type Client struct {
+	config Config
+	conn   net.Conn
+
+	lock   *sync.Mutex
+	err    error
+	closed chan struct{}
+}
+
+// Dial will connect to the MQTT broker.
+func Dial(cfg Config) (*Client, error) {
+	conn, err := cfg.getDial()("tcp", cfg.getAddress())
+	if err != nil {
+		return nil, err
+	}
+
+	// Don't wait forever on a broker that accepted the connection, but
+	// isn't speaking MQTT back.
+	conn.SetDeadline(time.Now().Add(time.Second * 10))
+	if err := connect(conn, cfg); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	c := &Client{
+		config: cfg,
+		conn:   conn,
+		lock:   &sync.Mutex{},
+		closed: make(chan struct{}),
+	}
+	go c.read()
+	go c.ping()
+	return c, nil
+}
+
+func connect(conn net.Conn, cfg Config) error {
+	var (
+		body  = &bytes.Buffer{}
+		flags uint8
+	)
+
+	writeString(body, "MQTT")
+	body.WriteByte(4) // 3.1.1
+
+	// Clean session, plus username and password if we have them.
+	flags = 0x02
+	if cfg.Username != "" {
+		flags |= 0x80
+	}
+	if cfg.Password != "" {
+		flags |= 0x40
+	}
+	body.WriteByte(flags)
+	binary.Write(body, binary.BigEndian, uint16(cfg.getKeepAlive()/time.Second))
+
+	writeString(body, cfg.getClientID())
+	if cfg.Username != "" {
+		writeString(body, cfg.Username)
+	}
+	if cfg.Password != "" {
+		writeString(body, cfg.Password)
+	}
+
+	if err := writePacket(conn, packetConnect, 0, body.Bytes()); err != nil {
+		return err
+	}
+
+	pt, _, ack, err := readPacket(conn)
+	if err != nil {
+		return err
+	}
+	if pt != packetConnack || len(ack) != 2 {
+		return ErrMalformedPacket
+	}
+	if ack[1] != 0 {
+		return ErrConnectionRefused
+	}
+	return nil
+}
+
+// read will consume (and discard) packets from the broker, since all we
+// expect are ping responses, until the connection is lost.
+func (c *Client) read() {
+	for {
+		if _, _, _, err := readPacket(c.conn); err != nil {
+			c.closeWithError(err)
+			return
+		}
+	}
+}
+
+func (c *Client) ping() {
+	ticker := time.NewTicker(c.config.getKeepAlive())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.closed:
+			return
+		case <-ticker.C:
+			if err := c.write(packetPingreq, 0, nil); err != nil {
+				c.closeWithError(err)
+				return
+			}
+		}
+	}
+}
+
+func (c *Client) write(pt packetType, flags uint8, body []byte) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	return writePacket(c.conn, pt, flags, body)
+}
+
+func (c *Client) closeWithError(err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	close(c.closed)
+	c.conn.Close()
+}
+
+// Publish will send the payload to the broker at QoS 0, on the provided
+// topic (with the configured TopicPrefix prepended).
+func (c *Client) Publish(topic string, payload []byte, retain bool) error {
+	body := &bytes.Buffer{}
+	writeString(body, c.config.TopicPrefix+topic)
+	body.Write(payload)
+
+	var flags uint8
+	if retain {
+		flags |= 0x01
+	}
+	return c.write(packetPublish, flags, body.Bytes())
+}
+
+// PublishJSON will encode v as JSON, and Publish it.
+func (c *Client) PublishJSON(topic string, v interface{}, retain bool) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.Publish(topic, payload, retain)
+}
+
+// PublishBus will Publish every Message from the Subscription as JSON, to
+// the Message's Topic, until the Subscription is closed (in which case this
+// returns nil) or publishing fails.
+func (c *Client) PublishBus(sub *bus.Subscription) error {
+	for msg := range sub.C() {
+		if err := c.PublishJSON(msg.Topic, msg, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close will disconnect from the broker.
+func (c *Client) Close() error {
+	err := c.write(packetDisconnect, 0, nil)
+	c.closeWithError(ErrClosed)
+	return err
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,84 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package mqtt
+
+import (
+	"hz.tools/rf"
+	"hz.tools/sdr"
+)
+
+// DeviceStatus is a snapshot of the configuration of an SDR, as published
+// by PublishDevice.
+type DeviceStatus struct {
+	Manufacturer string             `json:"manufacturer,omitempty"`
+	Product      string             `json:"product,omitempty"`
+	Serial       string             `json:"serial,omitempty"`
+	Frequency    rf.Hz              `json:"frequency"`
+	SampleRate   uint               `json:"sample_rate"`
+	Gains        map[string]float32 `json:"gains,omitempty"`
+
+	// Temperatures, Voltages and Locked are only set if the SDR implements
+	// sdr.Telemetry.
+	Temperatures map[string]float64 `json:"temperatures,omitempty"`
+	Voltages     map[string]float64 `json:"voltages,omitempty"`
+	Locked       map[string]bool    `json:"locked,omitempty"`
+}
+
+// Status will take a snapshot of the configuration of the SDR. Any values
+// the SDR can't report are left unset.
+func Status(dev sdr.Sdr) DeviceStatus {
+	info := dev.HardwareInfo()
+	status := DeviceStatus{
+		Manufacturer: info.Manufacturer,
+		Product:      info.Product,
+		Serial:       info.Serial,
+		Gains:        map[string]float32{},
+	}
+
+	if freq, err := dev.GetCenterFrequency(); err == nil {
+		status.Frequency = freq
+	}
+	if rate, err := dev.GetSampleRate(); err == nil {
+		status.SampleRate = rate
+	}
+	if stages, err := dev.GetGainStages(); err == nil {
+		for _, stage := range stages {
+			if gain, err := dev.GetGain(stage); err == nil {
+				status.Gains[stage.String()] = gain
+			}
+		}
+	}
+	if telemetry, err := sdr.ReadTelemetry(dev); err == nil {
+		status.Temperatures = telemetry.Temperatures
+		status.Voltages = telemetry.Voltages
+		status.Locked = telemetry.Locked
+	}
+	return status
+}
+
+// PublishDevice will Publish the Status of the SDR as JSON to the provided
+// topic, retained, so that a new subscriber gets the latest state of the
+// device right away.
+func (c *Client) PublishDevice(topic string, dev sdr.Sdr) error {
+	return c.PublishJSON(topic, Status(dev), true)
+}
+
+// vim: foldmethod=marker
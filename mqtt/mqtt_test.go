@@ -0,0 +1,226 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package mqtt_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/bus"
+	"hz.tools/sdr/mock"
+	"hz.tools/sdr/mqtt"
+)
+
+type packet struct {
+	Type  uint8
+	Flags uint8
+	Body  []byte
+}
+
+func readPacket(t *testing.T, r io.Reader) (packet, error) {
+	var b [1]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return packet{}, err
+	}
+	p := packet{Type: b[0] >> 4, Flags: b[0] & 0x0F}
+
+	length, multiplier := 0, 1
+	for {
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return packet{}, err
+		}
+		length += int(b[0]&0x7F) * multiplier
+		multiplier *= 128
+		if b[0]&0x80 == 0 {
+			break
+		}
+	}
+	p.Body = make([]byte, length)
+	_, err := io.ReadFull(r, p.Body)
+	return p, err
+}
+
+func readString(b []byte) (string, []byte) {
+	n := binary.BigEndian.Uint16(b)
+	return string(b[2 : 2+n]), b[2+n:]
+}
+
+// broker will accept a single connection, reply to the CONNECT with the
+// provided return code, and send every packet after that to the channel.
+func broker(t *testing.T, rc uint8) (func(string, string) (net.Conn, error), chan packet) {
+	packets := make(chan packet, 16)
+	return func(network, address string) (net.Conn, error) {
+		client, server := net.Pipe()
+		go func() {
+			defer close(packets)
+			p, err := readPacket(t, server)
+			if err != nil {
+				return
+			}
+			packets <- p
+			server.Write([]byte{0x20, 0x02, 0x00, rc})
+			for {
+				p, err := readPacket(t, server)
+				if err != nil {
+					return
+				}
+				packets <- p
+			}
+		}()
+		return client, nil
+	}, packets
+}
+
+func TestConnect(t *testing.T) {
+	dial, packets := broker(t, 0)
+	client, err := mqtt.Dial(mqtt.Config{
+		Address:  "broker",
+		ClientID: "test",
+		Username: "user",
+		Password: "pass",
+		Dial:     dial,
+	})
+	assert.NoError(t, err)
+
+	connect := <-packets
+	assert.Equal(t, uint8(1), connect.Type)
+	proto, rest := readString(connect.Body)
+	assert.Equal(t, "MQTT", proto)
+	assert.Equal(t, uint8(4), rest[0])
+	assert.Equal(t, uint8(0xC2), rest[1])
+	assert.Equal(t, uint16(30), binary.BigEndian.Uint16(rest[2:]))
+	id, rest := readString(rest[4:])
+	assert.Equal(t, "test", id)
+	user, rest := readString(rest)
+	assert.Equal(t, "user", user)
+	pass, _ := readString(rest)
+	assert.Equal(t, "pass", pass)
+
+	assert.NoError(t, client.Close())
+	assert.Equal(t, uint8(14), (<-packets).Type)
+
+	assert.Equal(t, mqtt.ErrClosed, client.Publish("foo", nil, false))
+}
+
+func TestConnectRefused(t *testing.T) {
+	dial, _ := broker(t, 5)
+	_, err := mqtt.Dial(mqtt.Config{Address: "broker", Dial: dial})
+	assert.Equal(t, mqtt.ErrConnectionRefused, err)
+}
+
+func TestPublishBus(t *testing.T) {
+	dial, packets := broker(t, 0)
+	client, err := mqtt.Dial(mqtt.Config{
+		Address:     "broker",
+		TopicPrefix: "sdr/",
+		Dial:        dial,
+	})
+	assert.NoError(t, err)
+	defer client.Close()
+	<-packets
+
+	b := bus.New()
+	sub := b.Subscribe(10)
+	done := make(chan error)
+	go func() { done <- client.PublishBus(sub) }()
+
+	b.Publish("pager", map[string]string{"text": "hello"})
+	sub.Close()
+	assert.NoError(t, <-done)
+
+	publish := <-packets
+	assert.Equal(t, uint8(3), publish.Type)
+	assert.Equal(t, uint8(0), publish.Flags)
+	topic, payload := readString(publish.Body)
+	assert.Equal(t, "sdr/pager", topic)
+
+	var msg bus.Message
+	assert.NoError(t, json.Unmarshal(payload, &msg))
+	assert.Equal(t, "pager", msg.Topic)
+	assert.True(t, bytes.Contains(payload, []byte(`"text":"hello"`)))
+}
+
+func TestPublishDevice(t *testing.T) {
+	dial, packets := broker(t, 0)
+	client, err := mqtt.Dial(mqtt.Config{Address: "broker", Dial: dial})
+	assert.NoError(t, err)
+	defer client.Close()
+	<-packets
+
+	dev := mock.New(mock.Config{
+		CenterFrequency: rf.MHz * 433,
+		SampleRate:      2048000,
+		SampleFormat:    sdr.SampleFormatC64,
+	})
+	assert.NoError(t, client.PublishDevice("device/mock", dev))
+
+	publish := <-packets
+	assert.Equal(t, uint8(1), publish.Flags)
+	topic, payload := readString(publish.Body)
+	assert.Equal(t, "device/mock", topic)
+
+	var status mqtt.DeviceStatus
+	assert.NoError(t, json.Unmarshal(payload, &status))
+	assert.Equal(t, rf.MHz*433, status.Frequency)
+	assert.Equal(t, uint(2048000), status.SampleRate)
+}
+
+func TestStatusTelemetry(t *testing.T) {
+	status := mqtt.Status(mock.New(mock.Config{}))
+	assert.Nil(t, status.Temperatures)
+
+	status = mqtt.Status(mock.New(mock.Config{
+		Telemetry: func() (sdr.TelemetryReadings, error) {
+			return sdr.TelemetryReadings{
+				Temperatures: map[string]float64{"board": 41.5},
+				Locked:       map[string]bool{"ref": true},
+			}, nil
+		},
+	}))
+	assert.Equal(t, 41.5, status.Temperatures["board"])
+	assert.True(t, status.Locked["ref"])
+	assert.Nil(t, status.Voltages)
+}
+
+func TestKeepAlive(t *testing.T) {
+	dial, packets := broker(t, 0)
+	client, err := mqtt.Dial(mqtt.Config{
+		Address:   "broker",
+		KeepAlive: time.Millisecond * 10,
+		Dial:      dial,
+	})
+	assert.NoError(t, err)
+	defer client.Close()
+	<-packets
+
+	assert.Equal(t, uint8(12), (<-packets).Type)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,119 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package mqtt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+var (
+	// ErrPacketTooLarge will be returned if a packet is larger than MQTT
+	// can encode.
+	ErrPacketTooLarge = fmt.Errorf("mqtt: packet is too large")
+
+	// ErrMalformedPacket will be returned if the broker sends something
+	// that can't be parsed.
+	ErrMalformedPacket = fmt.Errorf("mqtt: malformed packet")
+)
+
+// packetType is the high nibble of the first byte of every MQTT packet.
+type packetType uint8
+
+const (
+	packetConnect    packetType = 1
+	packetConnack    packetType = 2
+	packetPublish    packetType = 3
+	packetPingreq    packetType = 12
+	packetPingresp   packetType = 13
+	packetDisconnect packetType = 14
+)
+
+// maxRemainingLength is the largest length the four byte variable length
+// encoding can hold.
+const maxRemainingLength = 268435455
+
+func writeString(buf *bytes.Buffer, s string) {
+	binary.Write(buf, binary.BigEndian, uint16(len(s)))
+	buf.WriteString(s)
+}
+
+func writePacket(w io.Writer, pt packetType, flags uint8, body []byte) error {
+	if len(body) > maxRemainingLength {
+		return ErrPacketTooLarge
+	}
+
+	header := []byte{uint8(pt)<<4 | flags&0x0F}
+	length := len(body)
+	for {
+		digit := uint8(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 0x80
+		}
+		header = append(header, digit)
+		if length == 0 {
+			break
+		}
+	}
+
+	if _, err := w.Write(append(header, body...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func readPacket(r io.Reader) (packetType, uint8, []byte, error) {
+	var b [1]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, 0, nil, err
+	}
+	pt := packetType(b[0] >> 4)
+	flags := b[0] & 0x0F
+
+	var (
+		length     int
+		multiplier = 1
+	)
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, 0, nil, ErrMalformedPacket
+		}
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return 0, 0, nil, err
+		}
+		length += int(b[0]&0x7F) * multiplier
+		multiplier *= 128
+		if b[0]&0x80 == 0 {
+			break
+		}
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, 0, nil, err
+	}
+	return pt, flags, body, nil
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,97 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sdr
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	// ErrUnknownDriver will be returned by OpenBySerial if no driver by
+	// that name has been registered. Usually this means the driver package
+	// hasn't been imported.
+	ErrUnknownDriver = fmt.Errorf("sdr: unknown driver")
+
+	// ErrDuplicateDriver will be returned by RegisterSerialOpener or
+	// RegisterEnumerator if a driver by that name has already been
+	// registered.
+	ErrDuplicateDriver = fmt.Errorf("sdr: driver already registered")
+)
+
+// SerialOpener will open the SDR with the provided Serial, as reported
+// by the HardwareInfo of that SDR.
+type SerialOpener func(serial string) (Sdr, error)
+
+var (
+	serialOpenersLock = &sync.Mutex{}
+	serialOpeners     = map[string]SerialOpener{}
+)
+
+// RegisterSerialOpener will register a driver by name (such as "rtl" or
+// "hackrf"), to be used by OpenBySerial. This is usually called by the
+// driver package's init function.
+func RegisterSerialOpener(driver string, opener SerialOpener) error {
+	serialOpenersLock.Lock()
+	defer serialOpenersLock.Unlock()
+	if _, ok := serialOpeners[driver]; ok {
+		return ErrDuplicateDriver
+	}
+	serialOpeners[driver] = opener
+	return nil
+}
+
+// SerialDrivers will return the names of all drivers that have registered
+// a SerialOpener, in sorted order.
+func SerialDrivers() []string {
+	serialOpenersLock.Lock()
+	defer serialOpenersLock.Unlock()
+	ret := make([]string, 0, len(serialOpeners))
+	for name := range serialOpeners {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// OpenBySerial will open the SDR with the provided Serial using the named
+// driver. Unlike opening devices by index, this gives hosts with more than
+// one of the same kind of SDR a stable way to address each one regardless
+// of the order they were enumerated in.
+//
+// The driver package must be imported for it to be known here, for
+// instance:
+//
+//	import _ "hz.tools/sdr/rtl"
+//
+//	dev, err := sdr.OpenBySerial("rtl", "00000001")
+func OpenBySerial(driver, serial string) (Sdr, error) {
+	serialOpenersLock.Lock()
+	opener, ok := serialOpeners[driver]
+	serialOpenersLock.Unlock()
+	if !ok {
+		return nil, ErrUnknownDriver
+	}
+	return opener(serial)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,53 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sdr_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/mock"
+)
+
+func TestOpenBySerial(t *testing.T) {
+	_, err := sdr.OpenBySerial("open-test", "1234")
+	assert.Equal(t, sdr.ErrUnknownDriver, err)
+
+	assert.NoError(t, sdr.RegisterSerialOpener("open-test", func(serial string) (sdr.Sdr, error) {
+		if serial != "1234" {
+			return nil, sdr.ErrNotSupported
+		}
+		return mock.New(mock.Config{}), nil
+	}))
+	assert.Equal(t, sdr.ErrDuplicateDriver, sdr.RegisterSerialOpener("open-test", nil))
+	assert.Contains(t, sdr.SerialDrivers(), "open-test")
+
+	dev, err := sdr.OpenBySerial("open-test", "1234")
+	assert.NoError(t, err)
+	assert.NotNil(t, dev)
+
+	_, err = sdr.OpenBySerial("open-test", "4321")
+	assert.Equal(t, sdr.ErrNotSupported, err)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,114 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package oscillator
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"time"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+)
+
+var (
+	// ErrTauOutOfRange will be returned if a requested tau is shorter than
+	// one sample, or too long to compute given the length of the capture.
+	ErrTauOutOfRange = fmt.Errorf("oscillator: tau is out of range for this capture")
+)
+
+// Phase will return the unwrapped phase (in radians) of the carrier at the
+// provided offset frequency within the iq buffer, at each sample. If the
+// carrier is exactly at that frequency, this will be constant; any drift
+// or noise in the oscillator will show up as changes in phase over time.
+//
+// This assumes the carrier is the only thing of note in the capture; it's
+// best to filter around the carrier before calling this.
+func Phase(iq sdr.SamplesC64, sampleRate uint, carrier rf.Hz) []float64 {
+	var (
+		ret  = make([]float64, len(iq))
+		last float64
+		wrap float64
+		tau  = math.Pi * 2
+	)
+
+	for i, s := range iq {
+		now := float64(i) / float64(sampleRate)
+		phase := cmplx.Phase(
+			complex128(s) * cmplx.Exp(complex(0, -tau*float64(carrier)*now)),
+		)
+
+		if i > 0 {
+			switch delta := phase - last; {
+			case delta > math.Pi:
+				wrap -= tau
+			case delta < -math.Pi:
+				wrap += tau
+			}
+		}
+		last = phase
+		ret[i] = phase + wrap
+	}
+	return ret
+}
+
+// AllanDeviation will compute the overlapping Allan deviation of an
+// oscillator at each of the provided averaging times (tau), given the phase
+// of a carrier (as returned by Phase) sampled at sampleRate, and the nominal
+// frequency of that carrier at RF (so, the frequency the oscillator under
+// test was generating, *not* the offset within the capture).
+//
+// Each tau is rounded to the nearest whole number of samples, and must be
+// at least one sample, and at most a third of the capture.
+func AllanDeviation(
+	phase []float64,
+	sampleRate uint,
+	nominal rf.Hz,
+	taus []time.Duration,
+) ([]float64, error) {
+	var (
+		ret = make([]float64, len(taus))
+		n   = len(phase)
+		// Convert from radians of phase into seconds of time error.
+		scale = 1 / (2 * math.Pi * float64(nominal))
+	)
+
+	for i, tau := range taus {
+		m := int(math.Round(tau.Seconds() * float64(sampleRate)))
+		if m < 1 || n-2*m < m {
+			return nil, ErrTauOutOfRange
+		}
+
+		var sum float64
+		for j := 0; j < n-2*m; j++ {
+			d := (phase[j+2*m] - 2*phase[j+m] + phase[j]) * scale
+			sum += d * d
+		}
+
+		tauS := float64(m) / float64(sampleRate)
+		ret[i] = math.Sqrt(sum / (2 * tauS * tauS * float64(n-2*m)))
+	}
+
+	return ret, nil
+}
+
+// vim: foldmethod=marker
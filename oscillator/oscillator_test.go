@@ -0,0 +1,127 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package oscillator_test
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/oscillator"
+	"hz.tools/sdr/testutils"
+)
+
+// carrier generates a carrier at freq, with the provided phase (in radians)
+// at each sample.
+func carrier(n int, sampleRate uint, freq rf.Hz, phase func(i int) float64) sdr.SamplesC64 {
+	ret := make(sdr.SamplesC64, n)
+	for i := range ret {
+		now := float64(i) / float64(sampleRate)
+		ret[i] = complex64(cmplx.Exp(complex(0, 2*math.Pi*float64(freq)*now+phase(i))))
+	}
+	return ret
+}
+
+func TestPhase(t *testing.T) {
+	iq := carrier(1000, 1000, rf.Hz(100), func(i int) float64 {
+		return float64(i) * 0.5
+	})
+	phase := oscillator.Phase(iq, 1000, rf.Hz(100))
+	for i := range phase {
+		assert.InDelta(t, float64(i)*0.5, phase[i]-phase[0], 1e-3)
+	}
+}
+
+func TestAllanDeviationDrift(t *testing.T) {
+	const (
+		sampleRate = 1000
+		drift      = 1e-9 // fractional frequency drift per second
+	)
+	nominal := rf.MHz * 10
+
+	// A linear drift in frequency is a quadratic in phase, for which the
+	// Allan deviation is drift*tau/sqrt(2).
+	phase := make([]float64, 10000)
+	for i := range phase {
+		now := float64(i) / sampleRate
+		phase[i] = 2 * math.Pi * float64(nominal) * drift * now * now / 2
+	}
+
+	taus := []time.Duration{time.Millisecond * 10, time.Second, time.Second * 3}
+	adev, err := oscillator.AllanDeviation(phase, sampleRate, nominal, taus)
+	assert.NoError(t, err)
+	for i, tau := range taus {
+		assert.InEpsilon(t, drift*tau.Seconds()/math.Sqrt2, adev[i], 1e-6)
+	}
+
+	_, err = oscillator.AllanDeviation(phase, sampleRate, nominal, []time.Duration{time.Second * 4})
+	assert.Equal(t, oscillator.ErrTauOutOfRange, err)
+	_, err = oscillator.AllanDeviation(phase, sampleRate, nominal, []time.Duration{time.Microsecond})
+	assert.Equal(t, oscillator.ErrTauOutOfRange, err)
+}
+
+func TestAllanDeviationOffset(t *testing.T) {
+	// A constant frequency offset doesn't count against stability.
+	iq := carrier(4000, 1000, rf.Hz(100), func(i int) float64 {
+		return float64(i) * 0.001
+	})
+	phase := oscillator.Phase(iq, 1000, rf.Hz(100))
+	adev, err := oscillator.AllanDeviation(phase, 1000, rf.MHz, []time.Duration{time.Second})
+	assert.NoError(t, err)
+	assert.InDelta(t, 0, adev[0], 1e-10)
+}
+
+func TestPhaseNoise(t *testing.T) {
+	const (
+		sampleRate = 1024
+		beta       = 0.01
+	)
+
+	// Small phase modulation creates a pair of sidebands each beta/2 the
+	// amplitude of the carrier.
+	iq := carrier(1024, sampleRate, rf.Hz(128), func(i int) float64 {
+		return beta * math.Sin(2*math.Pi*32*float64(i)/sampleRate)
+	})
+
+	noise, err := oscillator.PhaseNoise(
+		testutils.DFTPlanner, iq, sampleRate, rf.Hz(128),
+		[]rf.Hz{32, -32, 64},
+	)
+	assert.NoError(t, err)
+
+	expected := 20*math.Log10(beta/2) - 10*math.Log10(1.5)
+	assert.InDelta(t, expected, noise[0], 0.1)
+	assert.InDelta(t, expected, noise[1], 0.1)
+	assert.True(t, noise[2] < expected-40)
+
+	_, err = oscillator.PhaseNoise(
+		testutils.DFTPlanner, iq, sampleRate, rf.Hz(128),
+		[]rf.Hz{500},
+	)
+	assert.Equal(t, oscillator.ErrOffsetOutOfRange, err)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,107 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package oscillator
+
+import (
+	"fmt"
+	"math"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/fft"
+)
+
+var (
+	// ErrOffsetOutOfRange will be returned if a requested offset from the
+	// carrier falls outside the captured bandwidth.
+	ErrOffsetOutOfRange = fmt.Errorf("oscillator: offset is out of range for this capture")
+)
+
+// hannENBW is the equivalent noise bandwidth of the Hann window, in bins.
+const hannENBW = 1.5
+
+// PhaseNoise will compute the single-sideband phase noise of the carrier at
+// the provided offset frequency within the iq buffer, at each of the
+// requested offsets from the carrier, in dBc/Hz.
+//
+// The capture is Hann windowed, and transformed in one FFT the length of the
+// iq buffer, so the resolution of the measurement is the sample rate divided
+// by the length of the buffer. Offsets closer to the carrier than a few bins
+// will be dominated by the carrier itself.
+//
+// Positive offsets measure the upper sideband, and negative offsets measure
+// the lower sideband.
+func PhaseNoise(
+	planner fft.Planner,
+	iq sdr.SamplesC64,
+	sampleRate uint,
+	carrier rf.Hz,
+	offsets []rf.Hz,
+) ([]float64, error) {
+	var (
+		n         = len(iq)
+		windowed  = make(sdr.SamplesC64, n)
+		frequency = make([]complex64, n)
+	)
+
+	for i, s := range iq {
+		w := 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n))
+		windowed[i] = s * complex(float32(w), 0)
+	}
+
+	if err := fft.TransformOnce(planner, windowed, frequency, fft.Forward); err != nil {
+		return nil, err
+	}
+
+	slice := fft.NewFrequencySlice(frequency, sampleRate, fft.ZeroFirst)
+	power := make([]float32, n)
+	if err := slice.Power(power); err != nil {
+		return nil, err
+	}
+
+	carrierBin, err := slice.BinByFreq(carrier)
+	if err != nil {
+		return nil, err
+	}
+
+	// The Hann window smears a carrier over three bins, so sum them all
+	// up to get the total carrier power.
+	var carrierPower float64
+	for i := -1; i <= 1; i++ {
+		carrierPower += float64(power[(carrierBin+i+n)%n])
+	}
+
+	rbw := float64(slice.BinBandwidth()) * hannENBW
+	ret := make([]float64, len(offsets))
+	for i, offset := range offsets {
+		bin, err := slice.BinByFreq(carrier + offset)
+		if err != nil {
+			return nil, ErrOffsetOutOfRange
+		}
+		// The carrier sum above includes the window's ENBW, so scale the
+		// bin back up to get to dBc, then normalize to a 1 Hz bandwidth.
+		ret[i] = 10*math.Log10(float64(power[bin])*hannENBW/carrierPower) -
+			10*math.Log10(rbw)
+	}
+	return ret, nil
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,333 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package paint
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"math/rand"
+	"time"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/fft"
+)
+
+var (
+	// ErrBandwidthTooWide will be returned if the Bandwidth of the painted
+	// image is wider than the sample rate.
+	ErrBandwidthTooWide = fmt.Errorf("paint: bandwidth is wider than the sample rate")
+
+	// ErrEmptyImage will be returned if the image has no pixels.
+	ErrEmptyImage = fmt.Errorf("paint: image is empty")
+)
+
+// Config controls how an image is painted.
+type Config struct {
+	// Planner is used to perform the inverse FFTs. This is required.
+	Planner fft.Planner
+
+	// SampleRate is the sample rate of the generated IQ samples. This is
+	// required.
+	SampleRate uint
+
+	// Bandwidth is how wide the image is on the waterfall, centered on
+	// 0 Hz. If 0, this will default to half of the SampleRate.
+	Bandwidth rf.Hz
+
+	// LineDuration is how long each row of the image is transmitted for.
+	// If 0, this will default to 50ms.
+	LineDuration time.Duration
+
+	// FFTSize is the size of the inverse FFT used for each frame. Larger
+	// sizes give finer frequency resolution, at the expense of time
+	// resolution. If 0, this will default to 1024.
+	FFTSize int
+
+	// Amplitude is the RMS amplitude of a fully white row. Since the
+	// phase of each bin is randomized, peaks will be somewhat above
+	// this. If 0, this will default to 0.25.
+	Amplitude float32
+
+	// Seed is used to seed the random phase of each bin, so that the output
+	// is repeatable.
+	Seed int64
+}
+
+func (c Config) getBandwidth() rf.Hz {
+	if c.Bandwidth == 0 {
+		return rf.Hz(c.SampleRate) / 2
+	}
+	return c.Bandwidth
+}
+
+func (c Config) getLineDuration() time.Duration {
+	if c.LineDuration == 0 {
+		return 50 * time.Millisecond
+	}
+	return c.LineDuration
+}
+
+func (c Config) getFFTSize() int {
+	if c.FFTSize == 0 {
+		return 1024
+	}
+	return c.FFTSize
+}
+
+func (c Config) getAmplitude() float32 {
+	if c.Amplitude == 0 {
+		return 0.25
+	}
+	return c.Amplitude
+}
+
+// column maps a single FFT bin to the image column drawn in that bin.
+type column struct {
+	bin int
+	x   int
+}
+
+// Reader is an sdr.Reader which produces the IQ samples for a painted
+// image.
+type Reader struct {
+	img  image.Image
+	rand *rand.Rand
+
+	sampleRate    uint
+	amplitude     float32
+	columns       []column
+	framesPerLine int
+
+	// row is the image row currently being painted, counting up from the
+	// bottom of the image, and frame is the frame within that row.
+	row   int
+	frame int
+
+	window  []float32
+	freq    []complex64
+	iq      sdr.SamplesC64
+	plan    fft.Plan
+	overlap sdr.SamplesC64
+	pending sdr.SamplesC64
+	flushed bool
+}
+
+// NewReader will create a Reader that paints the provided image.
+//
+// The bottom row of the image is transmitted first, so that the image is
+// the right way up on a waterfall that scrolls downward (with the newest
+// samples at the top). The image is converted to grayscale, and the
+// brightness of each pixel sets the amplitude of that slice of spectrum.
+func NewReader(img image.Image, cfg Config) (*Reader, error) {
+	var (
+		bounds    = img.Bounds()
+		fftSize   = cfg.getFFTSize()
+		hop       = fftSize / 2
+		bandwidth = cfg.getBandwidth()
+	)
+
+	if bounds.Empty() {
+		return nil, ErrEmptyImage
+	}
+	if cfg.Planner == nil || cfg.SampleRate == 0 {
+		return nil, fmt.Errorf("paint: Planner and SampleRate are required")
+	}
+	if fftSize < 4 || fftSize%2 != 0 {
+		return nil, fmt.Errorf("paint: FFTSize must be an even number of at least 4")
+	}
+	if bandwidth > rf.Hz(cfg.SampleRate) {
+		return nil, ErrBandwidthTooWide
+	}
+
+	var (
+		width   = bounds.Dx()
+		binHz   = float64(cfg.SampleRate) / float64(fftSize)
+		halfBW  = float64(bandwidth) / 2
+		columns = []column{}
+	)
+	for k := -fftSize / 2; k < fftSize/2; k++ {
+		freq := float64(k) * binHz
+		if freq < -halfBW || freq >= halfBW {
+			continue
+		}
+		x := int((freq + halfBW) / float64(bandwidth) * float64(width))
+		if x >= width {
+			x = width - 1
+		}
+		bin := k
+		if bin < 0 {
+			bin += fftSize
+		}
+		columns = append(columns, column{bin: bin, x: bounds.Min.X + x})
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("paint: bandwidth is narrower than one fft bin")
+	}
+
+	framesPerLine := int(cfg.getLineDuration().Seconds() * float64(cfg.SampleRate) / float64(hop))
+	if framesPerLine < 1 {
+		framesPerLine = 1
+	}
+
+	// A periodic Hann window at 50% overlap sums to exactly 1, so the
+	// overlap-added frames don't add any amplitude ripple.
+	window := make([]float32, fftSize)
+	for i := range window {
+		window[i] = float32(0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(fftSize)))
+	}
+
+	freq := make([]complex64, fftSize)
+	iq := make(sdr.SamplesC64, fftSize)
+	plan, err := cfg.Planner(iq, freq, fft.Backward)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Reader{
+		img:           img,
+		rand:          rand.New(rand.NewSource(cfg.Seed)),
+		sampleRate:    cfg.SampleRate,
+		amplitude:     cfg.getAmplitude(),
+		columns:       columns,
+		framesPerLine: framesPerLine,
+		window:        window,
+		freq:          freq,
+		iq:            iq,
+		plan:          plan,
+		overlap:       make(sdr.SamplesC64, hop),
+	}, nil
+}
+
+// SampleFormat implements the sdr.Reader interface.
+func (r *Reader) SampleFormat() sdr.SampleFormat {
+	return sdr.SampleFormatC64
+}
+
+// SampleRate implements the sdr.Reader interface.
+func (r *Reader) SampleRate() uint {
+	return r.sampleRate
+}
+
+// Length returns the total number of samples the Reader will produce.
+func (r *Reader) Length() int {
+	hop := len(r.overlap)
+	return (r.img.Bounds().Dy()*r.framesPerLine + 1) * hop
+}
+
+// Close will release the FFT plan.
+func (r *Reader) Close() error {
+	return r.plan.Close()
+}
+
+// next will synthesize the next frame, and return the hop samples which are
+// now complete.
+func (r *Reader) next() error {
+	var (
+		bounds = r.img.Bounds()
+		hop    = len(r.overlap)
+		y      = bounds.Max.Y - 1 - r.row
+	)
+
+	for i := range r.freq {
+		r.freq[i] = 0
+	}
+
+	var energy float64
+	for _, col := range r.columns {
+		gray := color.Gray16Model.Convert(r.img.At(col.x, y)).(color.Gray16)
+		mag := float64(gray.Y) / math.MaxUint16
+		if mag == 0 {
+			continue
+		}
+		energy += mag * mag
+		phase := r.rand.Float64() * 2 * math.Pi
+		r.freq[col.bin] = complex64(complex(mag*math.Cos(phase), mag*math.Sin(phase)))
+	}
+
+	if err := r.plan.Transform(); err != nil {
+		return err
+	}
+
+	// The scale of the Backward transform depends on the Planner, so
+	// rather than guess, scale each frame to the expected RMS directly.
+	var scale float32
+	if energy > 0 {
+		target := r.amplitude * float32(math.Sqrt(energy/float64(len(r.columns))))
+		if rms := r.iq.Stats().RMS(); rms > 0 {
+			scale = target / rms
+		}
+	}
+	for i := range r.iq {
+		r.iq[i] *= complex(scale*r.window[i], 0)
+	}
+
+	out := make(sdr.SamplesC64, hop)
+	for i := range out {
+		out[i] = r.overlap[i] + r.iq[i]
+	}
+	copy(r.overlap, r.iq[hop:])
+	r.pending = out
+
+	r.frame++
+	if r.frame == r.framesPerLine {
+		r.frame = 0
+		r.row++
+	}
+	return nil
+}
+
+// Read implements the sdr.Reader interface.
+func (r *Reader) Read(s sdr.Samples) (int, error) {
+	buf, ok := s.(sdr.SamplesC64)
+	if !ok {
+		return 0, sdr.ErrSampleFormatMismatch
+	}
+
+	var n int
+	for n < len(buf) {
+		if len(r.pending) == 0 {
+			switch {
+			case r.row < r.img.Bounds().Dy():
+				if err := r.next(); err != nil {
+					return n, err
+				}
+			case !r.flushed:
+				r.pending = r.overlap
+				r.flushed = true
+			default:
+				if n == 0 {
+					return 0, io.EOF
+				}
+				return n, nil
+			}
+		}
+		i := copy(buf[n:], r.pending)
+		r.pending = r.pending[i:]
+		n += i
+	}
+	return n, nil
+}
+
+// vim: foldmethod=marker
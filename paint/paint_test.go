@@ -0,0 +1,138 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package paint_test
+
+import (
+	"image"
+	"image/color"
+	"io"
+	"math/cmplx"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/fft"
+	"hz.tools/sdr/paint"
+	"hz.tools/sdr/testutils"
+)
+
+func testConfig() paint.Config {
+	// 100 Hz bins, 32 bins wide, 20 frames per line.
+	return paint.Config{
+		Planner:      testutils.DFTPlanner,
+		SampleRate:   6400,
+		Bandwidth:    3200,
+		FFTSize:      64,
+		LineDuration: 100 * time.Millisecond,
+	}
+}
+
+func readAll(t *testing.T, r *paint.Reader) sdr.SamplesC64 {
+	out := sdr.SamplesC64{}
+	buf := make(sdr.SamplesC64, 100)
+	for {
+		n, err := r.Read(buf)
+		out = append(out, buf[:n]...)
+		if err == io.EOF {
+			return out
+		}
+		assert.NoError(t, err)
+	}
+}
+
+func peakBin(t *testing.T, iq sdr.SamplesC64) int {
+	freq := make([]complex64, len(iq))
+	assert.NoError(t, fft.TransformOnce(testutils.DFTPlanner, iq, freq, fft.Forward))
+	var (
+		peak    float64
+		peakBin = -1
+	)
+	for i, f := range freq {
+		if mag := cmplx.Abs(complex128(f)); mag > peak {
+			peak, peakBin = mag, i
+		}
+	}
+	return peakBin
+}
+
+func TestPaint(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 4, 2))
+	img.SetGray(0, 0, color.Gray{Y: 0xFF})
+	img.SetGray(3, 1, color.Gray{Y: 0xFF})
+
+	r, err := paint.NewReader(img, testConfig())
+	assert.NoError(t, err)
+	defer r.Close()
+
+	out := readAll(t, r)
+	assert.Equal(t, r.Length(), len(out))
+	assert.Equal(t, 41*32, len(out))
+
+	// The bottom row goes first, and the rightmost column is the top
+	// quarter of the band (800 Hz to 1600 Hz).
+	bin := peakBin(t, out[256:320])
+	assert.True(t, bin >= 8 && bin < 16, "bin %d", bin)
+
+	// Then the top row, with the leftmost column (-1600 Hz to -800 Hz).
+	bin = peakBin(t, out[640+256:640+320])
+	assert.True(t, bin >= 48 && bin < 56, "bin %d", bin)
+}
+
+func TestPaintAmplitude(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 8, 4))
+	for x := 0; x < 8; x++ {
+		for y := 0; y < 4; y++ {
+			img.SetGray(x, y, color.Gray{Y: 0xFF})
+		}
+	}
+
+	cfg := testConfig()
+	cfg.Amplitude = 0.1
+	r, err := paint.NewReader(img, cfg)
+	assert.NoError(t, err)
+	defer r.Close()
+
+	out := readAll(t, r)
+	assert.InDelta(t, 0.1, out[64:len(out)-64].Stats().RMS(), 0.02)
+}
+
+func TestPaintBlank(t *testing.T) {
+	r, err := paint.NewReader(image.NewGray(image.Rect(0, 0, 4, 4)), testConfig())
+	assert.NoError(t, err)
+	defer r.Close()
+
+	out := readAll(t, r)
+	assert.Equal(t, float32(0), out.Stats().Peak)
+}
+
+func TestPaintErrors(t *testing.T) {
+	cfg := testConfig()
+	cfg.Bandwidth = 12800
+	_, err := paint.NewReader(image.NewGray(image.Rect(0, 0, 4, 4)), cfg)
+	assert.Equal(t, paint.ErrBandwidthTooWide, err)
+
+	_, err = paint.NewReader(image.NewGray(image.Rect(0, 0, 0, 0)), testConfig())
+	assert.Equal(t, paint.ErrEmptyImage, err)
+}
+
+// vim: foldmethod=marker
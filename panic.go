@@ -0,0 +1,77 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sdr
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// ErrDriverPanic is the error a stream will be closed with if a goroutine
+// (or callback from C) servicing that stream panicked. Rather than taking
+// down the whole process, the panic is recovered, and the Reader or Writer
+// will return this error, so that the application is able to log the Stack
+// and restart the stream (or the device) as needed.
+type ErrDriverPanic struct {
+	// Value is the value that was passed to panic.
+	Value interface{}
+
+	// Stack is the formatted stack trace of the goroutine that panicked,
+	// as returned by runtime/debug.Stack.
+	Stack []byte
+}
+
+// Error implements the error interface.
+func (e ErrDriverPanic) Error() string {
+	return fmt.Sprintf("sdr: driver panic: %v", e.Value)
+}
+
+// DriverPanic will create a new ErrDriverPanic from the value returned by
+// recover, capturing the stack of the current goroutine. This must be called
+// from the deferred function that called recover, or the stack will not
+// contain the frames that caused the panic.
+//
+// For most goroutines, RecoverDriverPanic is easier to use. DriverPanic is
+// useful for callbacks from C, which need to set a return value after
+// recovering.
+func DriverPanic(v interface{}) ErrDriverPanic {
+	return ErrDriverPanic{
+		Value: v,
+		Stack: debug.Stack(),
+	}
+}
+
+// RecoverDriverPanic will recover from a panic, and close the provided
+// PipeWriter (or anything with a CloseWithError method, such as a
+// stream.RingBuffer) with an ErrDriverPanic.
+//
+// This must be directly deferred, since recover only works when called from
+// the deferred function itself. It should be the last defer in the function,
+// so that it is run before any other deferred Close calls.
+//
+//	defer sdr.RecoverDriverPanic(pipeWriter)
+func RecoverDriverPanic(closer interface{ CloseWithError(error) error }) {
+	if v := recover(); v != nil {
+		closer.CloseWithError(DriverPanic(v))
+	}
+}
+
+// vim: foldmethod=marker
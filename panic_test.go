@@ -0,0 +1,63 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sdr_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+)
+
+func TestRecoverDriverPanic(t *testing.T) {
+	pipeReader, pipeWriter := sdr.Pipe(0, sdr.SampleFormatC64)
+
+	go func() {
+		defer sdr.RecoverDriverPanic(pipeWriter)
+		var buf sdr.SamplesC64
+		buf[10] = 1
+	}()
+
+	_, err := pipeReader.Read(make(sdr.SamplesC64, 10))
+	assert.Error(t, err)
+
+	panicErr, ok := err.(sdr.ErrDriverPanic)
+	assert.True(t, ok)
+	assert.Contains(t, panicErr.Error(), "sdr: driver panic: ")
+	assert.True(t, strings.Contains(string(panicErr.Stack), "panic_test.go"))
+}
+
+func TestDriverPanic(t *testing.T) {
+	var err error
+	func() {
+		defer func() {
+			if v := recover(); v != nil {
+				err = sdr.DriverPanic(v)
+			}
+		}()
+		panic("oh no")
+	}()
+	assert.Equal(t, "sdr: driver panic: oh no", err.Error())
+}
+
+// vim: foldmethod=marker
@@ -23,6 +23,8 @@ package sdr
 import (
 	"context"
 	"fmt"
+	"io"
+	"sync"
 )
 
 var (
@@ -70,7 +72,12 @@ type pipe struct {
 	samplesPerSecond uint
 	format           SampleFormat
 
-	err error
+	// lock guards err and closed. The first call to Close or CloseWithError
+	// wins, and sets the error that will be returned from all subsequent
+	// calls to Read or Write.
+	lock   *sync.Mutex
+	closed bool
+	err    error
 }
 
 // Read implements the sdr.Reader interface.
@@ -93,10 +100,45 @@ func (pipe *pipe) Read(b Samples) (int, error) {
 	}
 }
 
+// WriteTo implements the sdr.WriterTo interface.
+//
+// Rather than copying the samples into an intermediate buffer, the Samples
+// passed to Write are handed directly to the provided Writer. This will
+// return when the Pipe is closed, or the Writer returns an error.
+func (pipe *pipe) WriteTo(w Writer) (int64, error) {
+	if w.SampleFormat() != pipe.format {
+		return 0, ErrSampleFormatMismatch
+	}
+
+	var written int64
+	for {
+		select {
+		case sample := <-pipe.samplesCh:
+			n, err := w.Write(sample)
+			pipe.readSamplesCh <- n
+			written += int64(n)
+			if err != nil {
+				return written, err
+			}
+			if n != sample.Length() {
+				return written, ErrShortWrite
+			}
+		case <-pipe.context.Done():
+			err := pipe.getErr()
+			if err == io.EOF {
+				err = nil
+			}
+			return written, err
+		}
+	}
+}
+
 func (pipe *pipe) getErr() error {
 	if err := pipe.context.Err(); err == nil {
 		return nil
 	}
+	pipe.lock.Lock()
+	defer pipe.lock.Unlock()
 	if pipe.err != nil {
 		return pipe.err
 	}
@@ -141,14 +183,20 @@ func (pipe *pipe) SampleFormat() SampleFormat {
 	return pipe.format
 }
 
-// Close implements the sdr.ReadCloser/sdr.WriteCloser interface.
+// CloseWithError implements the sdr.PipeReader/sdr.PipeWriter interface.
+//
+// Like io.Pipe, only the first call to Close or CloseWithError will set the
+// error, subsequent calls will not overwrite it. The error is set before
+// the Pipe's context is cancelled, so any Read or Write that returns due to
+// the Pipe being closed will always see this error.
 func (pipe *pipe) CloseWithError(err error) error {
-	pipe.err = err
-	return pipe.Close()
-}
+	pipe.lock.Lock()
+	if !pipe.closed {
+		pipe.closed = true
+		pipe.err = err
+	}
+	pipe.lock.Unlock()
 
-// Close implements the sdr.ReadCloser/sdr.WriteCloser interface.
-func (pipe *pipe) Close() error {
 	// This should explicitly be not doing anything further, since the core
 	// mechanism here is that the context is cancelled / timed out, so relying
 	// on this method being called is not a safe assumption. This is merely
@@ -159,9 +207,18 @@ func (pipe *pipe) Close() error {
 	return nil
 }
 
+// Close implements the sdr.ReadCloser/sdr.WriteCloser interface.
+func (pipe *pipe) Close() error {
+	return pipe.CloseWithError(nil)
+}
+
 // Pipe will create a new sdr.Reader and sdr.Writer that will allow writes
 // to pass through and show up to a reader. This allows "patching" a Write
 // endpoint into a "Read" endpoint.
+//
+// The Pipe is unbuffered; a Write will block until Reads have consumed all
+// the samples, or the Pipe is closed. Close and CloseWithError may be called
+// from any goroutine, and will unblock any pending Read or Write calls.
 func Pipe(samplesPerSecond uint, format SampleFormat) (PipeReader, PipeWriter) {
 	ctx := context.Background()
 	return PipeWithContext(ctx, samplesPerSecond, format)
@@ -181,6 +238,7 @@ func PipeWithContext(
 		cancel:           cancel,
 		format:           format,
 		samplesPerSecond: samplesPerSecond,
+		lock:             &sync.Mutex{},
 		samplesCh:        make(chan Samples),
 		readSamplesCh:    make(chan int),
 	}
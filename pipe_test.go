@@ -220,6 +220,61 @@ func TestPipeParts(t *testing.T) {
 	wg.Wait()
 }
 
+func TestPipeCloseWithErrorFirstWins(t *testing.T) {
+	pipeReader, pipeWriter := sdr.Pipe(0, sdr.SampleFormatU8)
+	assert.NoError(t, pipeWriter.CloseWithError(io.EOF))
+	assert.NoError(t, pipeReader.CloseWithError(io.ErrUnexpectedEOF))
+	assert.NoError(t, pipeReader.Close())
+
+	_, err := pipeReader.Read(make(sdr.SamplesU8, 10))
+	assert.Equal(t, io.EOF, err)
+	_, err = pipeWriter.Write(make(sdr.SamplesU8, 10))
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestPipeCloseStress(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		pipeReader, pipeWriter := sdr.Pipe(0, sdr.SampleFormatU8)
+		closeErr := fmt.Errorf("closed: %d", i)
+
+		wg := sync.WaitGroup{}
+		for j := 0; j < 4; j++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				buf := make(sdr.SamplesU8, 128)
+				for {
+					if _, err := pipeWriter.Write(buf); err != nil {
+						assert.Equal(t, closeErr, err)
+						return
+					}
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				buf := make(sdr.SamplesU8, 64)
+				for {
+					if _, err := pipeReader.Read(buf); err != nil {
+						assert.Equal(t, closeErr, err)
+						return
+					}
+				}
+			}()
+		}
+
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			pipeWriter.CloseWithError(closeErr)
+		}()
+		go func() {
+			defer wg.Done()
+			pipeReader.CloseWithError(closeErr)
+		}()
+		wg.Wait()
+	}
+}
+
 func BenchmarkPipe(b *testing.B) {
 	pipeReader, pipeWriter := sdr.Pipe(0, sdr.SampleFormatC64)
 
@@ -0,0 +1,106 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package pluto
+
+import (
+	"fmt"
+	"strings"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/pluto/iio"
+)
+
+// openBySerial is the sdr.SerialOpener for the Pluto. A Connection from
+// Enumerate is already an IIO URI; anything else is taken to be the serial
+// of a Pluto to look for.
+func openBySerial(serial string) (sdr.Sdr, error) {
+	uri := serial
+	if !strings.Contains(uri, ":") {
+		var err error
+		if uri, err = uriBySerial(serial); err != nil {
+			return nil, err
+		}
+	}
+	dev, err := Open(uri)
+	if err != nil {
+		return nil, err
+	}
+	return dev, nil
+}
+
+// Enumerate will scan for PlutoSDRs using every backend libiio supports,
+// without opening any of them. The Connection of each DeviceDescription is
+// the IIO URI to pass to Open.
+//
+// The Serial is parsed from the description libiio gives, which only USB
+// devices include.
+func Enumerate() ([]sdr.DeviceDescription, error) {
+	infos, err := iio.Scan("")
+	if err != nil {
+		return nil, err
+	}
+
+	ret := []sdr.DeviceDescription{}
+	for _, info := range infos {
+		if !strings.Contains(info.Description, "PlutoSDR") {
+			continue
+		}
+		ret = append(ret, sdr.DeviceDescription{
+			HardwareInfo: sdr.HardwareInfo{
+				Manufacturer: "Analog Devices",
+				Product:      "PlutoSDR",
+				Serial:       descriptionSerial(info.Description),
+			},
+			Connection: info.URI,
+		})
+	}
+	return ret, nil
+}
+
+// descriptionSerial will pull the "serial=" field out of a libiio context
+// description, such as "0456:b673 (Analog Devices Inc. PlutoSDR
+// (ADALM-PLUTO)), serial=104473b04a060014ffff2300ac6d3fa9e0".
+func descriptionSerial(description string) string {
+	i := strings.Index(description, "serial=")
+	if i < 0 {
+		return ""
+	}
+	serial := description[i+len("serial="):]
+	if end := strings.IndexAny(serial, ", "); end >= 0 {
+		serial = serial[:end]
+	}
+	return serial
+}
+
+func uriBySerial(serial string) (string, error) {
+	descs, err := Enumerate()
+	if err != nil {
+		return "", err
+	}
+	for _, desc := range descs {
+		if desc.HardwareInfo.Serial == serial {
+			return desc.Connection, nil
+		}
+	}
+	return "", fmt.Errorf("pluto: no PlutoSDR with serial %q found", serial)
+}
+
+// vim: foldmethod=marker
@@ -23,6 +23,7 @@ package pluto
 import (
 	"fmt"
 	"math"
+	"strings"
 
 	"hz.tools/sdr"
 )
@@ -83,15 +84,48 @@ var (
 	}
 )
 
-// SetAutomaticGain implements the sdr.Sdr interface.
+// GainMode is the RX gain control mode of the AD9361.
+type GainMode string
+
+const (
+	// GainModeManual will hold the RX gain at whatever was set with SetGain.
+	GainModeManual GainMode = "manual"
+
+	// GainModeSlowAttack is an AGC suited to slowly changing signals,
+	// such as continuous FM or digital broadcast signals.
+	GainModeSlowAttack GainMode = "slow_attack"
+
+	// GainModeFastAttack is an AGC suited to bursty signals, such as
+	// TDD or packet based signals, which need the gain to settle quickly.
+	GainModeFastAttack GainMode = "fast_attack"
+
+	// GainModeHybrid is the slow attack AGC, with gain changes triggered
+	// by the control input pin rather than automatically.
+	GainModeHybrid GainMode = "hybrid"
+)
+
+// SetGainMode will set the RX gain control mode.
+func (s *Sdr) SetGainMode(mode GainMode) error {
+	// TODO(paultag): Should this be both Rx and Tx? What does AGC on
+	// Tx mean? Defaulting to just Rx for now.
+	return s.voltage0Rx.WriteString("gain_control_mode", string(mode))
+}
+
+// GetGainMode will return the current RX gain control mode.
+func (s *Sdr) GetGainMode() (GainMode, error) {
+	mode, err := s.voltage0Rx.ReadString("gain_control_mode")
+	return GainMode(strings.TrimSpace(mode)), err
+}
+
+// SetAutomaticGain implements the sdr.Sdr interface. This will set the
+// gain control mode to the Options.AutomaticGainMode (slow attack, unless
+// set), or to GainModeManual.
 func (s *Sdr) SetAutomaticGain(autoGain bool) error {
-	var gcm = "manual"
+	var gcm = GainModeManual
 	if autoGain {
-		gcm = "slow_attack"
+		gcm = s.automaticGainMode
 	}
-	// TODO(paultag): Should this be both Rx and Tx? What does AGC on
-	// Tx mean? Defaulting to just Rx for now.
-	return s.voltage0Rx.WriteString("gain_control_mode", gcm)
+	return s.SetGainMode(gcm)
 }
 
 // GetGainStages implements the sdr.Sdr interface.
@@ -99,12 +133,27 @@ func (s *Sdr) GetGainStages() (sdr.GainStages, error) {
 	return sdr.GainStages{rxHardwareGain, txHardwareGain}, nil
 }
 
-// GetGain implements the sdr.Sdr interface.
-func (s *Sdr) GetGain(sdr.GainStage) (float32, error) {
-	return 0, sdr.ErrNotSupported
+// GetGain implements the sdr.Sdr interface. When an AGC is running, this
+// is the gain the AGC has picked.
+func (s *Sdr) GetGain(gainStage sdr.GainStage) (float32, error) {
+	var (
+		gain float64
+		err  error
+	)
+	switch gainStage {
+	case rxHardwareGain:
+		gain, err = s.voltage0Rx.ReadFloat64("hardwaregain")
+	case txHardwareGain:
+		gain, err = s.voltage0Tx.ReadFloat64("hardwaregain")
+	default:
+		return 0, fmt.Errorf("pluto: unknown gain stage: %s", gainStage.String())
+	}
+	return float32(gain), err
 }
 
-// SetGain implements the sdr.Sdr interface.
+// SetGain implements the sdr.Sdr interface. The AD9361 ignores the RX gain
+// while an AGC is running, so setting the RX gain will also set the gain
+// control mode to GainModeManual.
 func (s *Sdr) SetGain(gainStage sdr.GainStage, gain float32) error {
 	switch gainStage {
 	case rxHardwareGain:
@@ -112,6 +161,9 @@ func (s *Sdr) SetGain(gainStage sdr.GainStage, gain float32) error {
 		if err != nil {
 			return err
 		}
+		if err := s.SetGainMode(GainModeManual); err != nil {
+			return err
+		}
 		return s.voltage0Rx.WriteFloat64("hardwaregain", gain)
 	case txHardwareGain:
 		gain, err := txHardwareGain.Clamp(float64(gain))
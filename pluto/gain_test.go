@@ -66,4 +66,18 @@ func TestGainStepsTx(t *testing.T) {
 	assert.Equal(t, -2.0, gain)
 }
 
+func TestAutomaticGainModeDefault(t *testing.T) {
+	assert.Equal(t, GainModeSlowAttack, Options{}.getAutomaticGainMode())
+	assert.Equal(t, GainModeFastAttack, Options{
+		AutomaticGainMode: GainModeFastAttack,
+	}.getAutomaticGainMode())
+}
+
+func TestProfileDefault(t *testing.T) {
+	assert.Equal(t, ProfilePlutoSDR, Options{}.getProfile())
+	assert.Equal(t, ProfileADRV9361, Options{
+		Profile: ProfileADRV9361,
+	}.getProfile())
+}
+
 // vim: foldmethod=marker
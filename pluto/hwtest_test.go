@@ -0,0 +1,50 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+//go:build hwtest
+// +build hwtest
+
+package pluto_test
+
+import (
+	"os"
+	"testing"
+
+	"hz.tools/rf"
+	"hz.tools/sdr/hwtest"
+	"hz.tools/sdr/pluto"
+)
+
+func TestHardware(t *testing.T) {
+	endpoint := os.Getenv("HWTEST_PLUTO_URI")
+	if endpoint == "" {
+		endpoint = "ip:192.168.2.1"
+	}
+	dev, err := pluto.Open(endpoint)
+	hwtest.Skip(t, err)
+	defer dev.Close()
+
+	hwtest.RunTest(t, dev, hwtest.Config{
+		Frequencies: []rf.Hz{100 * rf.MHz, 915 * rf.MHz, 2400 * rf.MHz},
+		SampleRates: []uint{2048000, 4000000},
+	})
+}
+
+// vim: foldmethod=marker
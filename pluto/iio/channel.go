@@ -91,6 +91,25 @@ func (c Channel) WriteInt64(name string, value int64) error {
 	return syscall.Errno(-errno)
 }
 
+// ReadFloat64 will read a float64 channel attribute from the backing device.
+func (c Channel) ReadFloat64(name string) (float64, error) {
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+
+	cValue := C.double(0)
+
+	errno := C.iio_channel_attr_read_double(
+		c.handle,
+		cName,
+		&cValue,
+	)
+	if errno != 0 {
+		return 0, syscall.Errno(-errno)
+	}
+
+	return float64(cValue), nil
+}
+
 // WriteFloat64 will write an float64 channel attribute to the backing device.
 // (this is otherwise known as WriteDouble, but I've chosen the Go types here)
 func (c Channel) WriteFloat64(name string, value float64) error {
@@ -122,6 +141,19 @@ func (c Channel) WriteBool(name string, value bool) error {
 	return nil
 }
 
+// ReadString will read a string channel attribute from the backing device.
+func (c Channel) ReadString(name string) (string, error) {
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+
+	var buf [1024]C.char
+	errno := C.iio_channel_attr_read(c.handle, cName, &buf[0], C.size_t(len(buf)))
+	if errno < 0 {
+		return "", syscall.Errno(-errno)
+	}
+	return C.GoString(&buf[0]), nil
+}
+
 // WriteString will write a string channel attribute to the backing device.
 func (c Channel) WriteString(name, value string) error {
 	cName := C.CString(name)
@@ -0,0 +1,79 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package iio
+
+// #cgo pkg-config: libiio
+//
+// #include <iio.h>
+// #include <stdlib.h>
+import "C"
+
+import (
+	"unsafe"
+)
+
+// ContextInfo describes an IIO context found by Scan, which may be passed
+// to Open by its URI.
+type ContextInfo struct {
+	// URI is the URI to pass to Open, such as "usb:1.2.5" or
+	// "ip:192.168.2.1".
+	URI string
+
+	// Description is the backend's human readable description of the
+	// context, which for USB devices includes the product and serial.
+	Description string
+}
+
+// Scan will list the IIO contexts that can be found with the provided
+// backend (such as "usb" or "ip"). If the backend is empty, every backend
+// libiio knows how to scan will be used.
+func Scan(backend string) ([]ContextInfo, error) {
+	var cBackend *C.char
+	if backend != "" {
+		cBackend = C.CString(backend)
+		defer C.free(unsafe.Pointer(cBackend))
+	}
+
+	scan, err := C.iio_create_scan_context(cBackend, 0)
+	if scan == nil {
+		return nil, err
+	}
+	defer C.iio_scan_context_destroy(scan)
+
+	var infos **C.struct_iio_context_info
+	n, err := C.iio_scan_context_get_info_list(scan, &infos)
+	if n < 0 {
+		return nil, err
+	}
+	defer C.iio_context_info_list_free(infos)
+
+	ret := make([]ContextInfo, int(n))
+	list := (*[1 << 20]*C.struct_iio_context_info)(unsafe.Pointer(infos))[:int(n):int(n)]
+	for i, info := range list {
+		ret[i] = ContextInfo{
+			URI:         C.GoString(C.iio_context_info_get_uri(info)),
+			Description: C.GoString(C.iio_context_info_get_description(info)),
+		}
+	}
+	return ret, nil
+}
+
+// vim: foldmethod=marker
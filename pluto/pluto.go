@@ -31,27 +31,16 @@ import (
 
 func init() {
 	debug.RegisterRadioDriver("hz.tools/sdr/pluto.Sdr")
+	sdr.RegisterSerialOpener("pluto", openBySerial)
+	sdr.RegisterEnumerator("pluto", Enumerate)
 }
 
-var (
-	// plutoPhyName is the name of the transceiver itself, used for control
-	// over things like samples per second or frequency.
-	plutoPhyName = "ad9361-phy"
-
-	// plutoRxName is the name of the RX ADC, to read samples from the rx
-	// antenna.
-	plutoRxName = "cf-ad9361-lpc"
-
-	// plutoTxName is the name of the TX DAC, to write samples to the
-	// tx side of the house.
-	plutoTxName = "cf-ad9361-dds-core-lpc"
-)
-
 // Sdr is an interface to the underlying PlutoSDR endpoint. This will allow
 // the user to interact with the Pluto as any other hz.tools/sdr.Sdr. This
 // implements both the Receiver and Transmitter (Transceiver) interface.
 type Sdr struct {
 	endpoint    string
+	profile     Profile
 	ictx        *iio.Context
 	phy         *iio.Device
 	voltage0Rx  *iio.Channel
@@ -68,8 +57,11 @@ type Sdr struct {
 	rxWindowSize         int
 	rxKernelBuffersCount uint
 	checkOverruns        bool
+	automaticGainMode    GainMode
 
-	samplesPerSecond uint
+	rxSamplesPerSecond uint
+	txSamplesPerSecond uint
+	sampleFormat       sdr.SampleFormat
 }
 
 // Open will create a PlutoSDR handle with the default set of
@@ -108,6 +100,44 @@ type Options struct {
 	// CheckOverruns will check to see if there's been an overrun when refilling
 	// the IQ buffer.
 	CheckOverruns bool
+
+	// SampleFormats is an ordered list of preferred SampleFormats, most
+	// preferred first. The PlutoSDR streams sdr.SampleFormatI16 (the
+	// default), and sdr.SampleFormatI12Packed, which is packed by the
+	// driver from the 12 bit ADC samples. The iio buffers are always 16 bit,
+	// so packing doesn't save any USB or network bandwidth, but does save a
+	// quarter of the memory or disk used by captures. If this is set and
+	// doesn't contain either, opening the device will fail with
+	// sdr.ErrNoSupportedSampleFormat.
+	SampleFormats []sdr.SampleFormat
+
+	// AutomaticGainMode is the gain control mode used when automatic gain
+	// is turned on with SetAutomaticGain. If empty, this will default to
+	// GainModeSlowAttack.
+	AutomaticGainMode GainMode
+
+	// Profile is the AD936x board being opened. If unset, this will default
+	// to ProfilePlutoSDR. OpenADRV9364 and OpenADRV9361 set this.
+	Profile Profile
+
+	// RxChannel and TxChannel pick which receive and transmit chains to
+	// use, on boards with more than one (such as the ADRV9361).
+	RxChannel int
+	TxChannel int
+}
+
+func (opts Options) getProfile() Profile {
+	if opts.Profile == (Profile{}) {
+		return ProfilePlutoSDR
+	}
+	return opts.Profile
+}
+
+func (opts Options) getAutomaticGainMode() GainMode {
+	if opts.AutomaticGainMode == "" {
+		return GainModeSlowAttack
+	}
+	return opts.AutomaticGainMode
 }
 
 // OpenWithOptions will establish a connection to a PlutoSDR, and return a handle to
@@ -121,12 +151,28 @@ func OpenWithOptions(endpoint string, opts Options) (*Sdr, error) {
 		txKernelBuffersCount = opts.TxKernelBuffersCount
 	)
 
+	profile := opts.getProfile()
+	if opts.RxChannel < 0 || opts.RxChannel >= profile.RxChannels {
+		return nil, ErrNoSuchChannel
+	}
+	if opts.TxChannel < 0 || opts.TxChannel >= profile.TxChannels {
+		return nil, ErrNoSuchChannel
+	}
+
+	sampleFormat, err := sdr.NegotiateSampleFormat(
+		opts.SampleFormats,
+		[]sdr.SampleFormat{sdr.SampleFormatI16, sdr.SampleFormatI12Packed},
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	ictx, err := iio.Open(endpoint)
 	if err != nil {
 		return nil, err
 	}
 
-	phy, err := ictx.FindDevice(plutoPhyName)
+	phy, err := ictx.FindDevice(profile.PhyName)
 	if err != nil {
 		return nil, err
 	}
@@ -141,28 +187,31 @@ func OpenWithOptions(endpoint string, opts Options) (*Sdr, error) {
 		return nil, err
 	}
 
-	voltage0Rx, err := phy.FindChannel("voltage0", iio.ChannelDirectionRead)
+	// Each chain has its own voltageN channel on the phy (for gain), and a
+	// pair of voltage channels (I and Q) on the ADC or DAC.
+	voltage0Rx, err := phy.FindChannel(fmt.Sprintf("voltage%d", opts.RxChannel), iio.ChannelDirectionRead)
 	if err != nil {
 		return nil, err
 	}
 
-	voltage0Tx, err := phy.FindChannel("voltage0", iio.ChannelDirectionWrite)
+	voltage0Tx, err := phy.FindChannel(fmt.Sprintf("voltage%d", opts.TxChannel), iio.ChannelDirectionWrite)
 	if err != nil {
 		return nil, err
 	}
 
-	rx, err := openRx(ictx, rxWindowSize)
+	rx, err := openRx(ictx, profile.RxName, opts.RxChannel, rxWindowSize)
 	if err != nil {
 		return nil, err
 	}
 
-	tx, err := openTx(ictx, txWindowSize)
+	tx, err := openTx(ictx, profile.TxName, opts.TxChannel, txWindowSize)
 	if err != nil {
 		return nil, err
 	}
 
 	s := &Sdr{
 		endpoint: endpoint,
+		profile:  profile,
 
 		ictx:        ictx,
 		phy:         phy,
@@ -177,6 +226,9 @@ func OpenWithOptions(endpoint string, opts Options) (*Sdr, error) {
 		rxWindowSize:         rxWindowSize,
 		rxKernelBuffersCount: rxKernelBuffersCount,
 		checkOverruns:        opts.CheckOverruns,
+		automaticGainMode:    opts.getAutomaticGainMode(),
+
+		sampleFormat: sampleFormat,
 
 		rx: rx,
 		tx: tx,
@@ -234,23 +286,30 @@ func (s *Sdr) HardwareInfo() sdr.HardwareInfo {
 
 // SetCenterFrequency implements the sdr.Sdr interface.
 func (s *Sdr) SetCenterFrequency(r rf.Hz) error {
-	if err := s.altVoltage0.WriteInt64("frequency", int64(r)); err != nil {
+	if err := s.SetRxCenterFrequency(r); err != nil {
 		return err
 	}
-	if err := s.altVoltage1.WriteInt64("frequency", int64(r)); err != nil {
-		return err
-	}
-	return nil
+	return s.SetTxCenterFrequency(r)
+}
+
+// SetRxCenterFrequency implements the sdr.DuplexTuner interface.
+func (s *Sdr) SetRxCenterFrequency(r rf.Hz) error {
+	return s.altVoltage0.WriteInt64("frequency", int64(r))
+}
+
+// SetTxCenterFrequency implements the sdr.DuplexTuner interface.
+func (s *Sdr) SetTxCenterFrequency(r rf.Hz) error {
+	return s.altVoltage1.WriteInt64("frequency", int64(r))
 }
 
 // GetCenterFrequency implements the sdr.Sdr interface.
 func (s *Sdr) GetCenterFrequency() (rf.Hz, error) {
-	rxFreq, err := s.altVoltage0.ReadInt64("frequency")
+	rxFreq, err := s.GetRxCenterFrequency()
 	if err != nil {
 		return rf.Hz(0), err
 	}
 
-	txFreq, err := s.altVoltage1.ReadInt64("frequency")
+	txFreq, err := s.GetTxCenterFrequency()
 	if err != nil {
 		return rf.Hz(0), err
 	}
@@ -259,44 +318,137 @@ func (s *Sdr) GetCenterFrequency() (rf.Hz, error) {
 		return rf.Hz(0), fmt.Errorf("pluto: rx and tx frequencies are different")
 	}
 
-	return rf.Hz(rxFreq), nil
+	return rxFreq, nil
 }
 
+// GetRxCenterFrequency will return the center frequency of the receive
+// chain.
+func (s *Sdr) GetRxCenterFrequency() (rf.Hz, error) {
+	freq, err := s.altVoltage0.ReadInt64("frequency")
+	return rf.Hz(freq), err
+}
+
+// GetTxCenterFrequency will return the center frequency of the transmit
+// chain.
+func (s *Sdr) GetTxCenterFrequency() (rf.Hz, error) {
+	freq, err := s.altVoltage1.ReadInt64("frequency")
+	return rf.Hz(freq), err
+}
+
+// minSamplesPerSecond is the slowest the AD9361 can be clocked without
+// the FIR decimation / interpolation filters.
+const minSamplesPerSecond = 2083336
+
 // SetSampleRate implements the sdr.Sdr interface.
 func (s *Sdr) SetSampleRate(sps uint) error {
-	if sps < 2083336 {
+	if err := s.SetRxSampleRate(sps); err != nil {
+		return err
+	}
+	return s.SetTxSampleRate(sps)
+}
+
+// SetRxSampleRate implements the sdr.DuplexSampleRater interface. This also
+// sets the receive bandwidth to match.
+//
+// The AD9361 clocks both chains from the same PLL, so not every pair of
+// rates is possible; the device may move the transmit rate as well. Both
+// rates are read back after the change, so GetTxSampleRate will report
+// what the device picked.
+func (s *Sdr) SetRxSampleRate(sps uint) error {
+	return s.setChainSampleRate(s.voltage0Rx, sps)
+}
+
+// SetTxSampleRate implements the sdr.DuplexSampleRater interface. This also
+// sets the transmit bandwidth to match. The same caveats as SetRxSampleRate
+// apply.
+func (s *Sdr) SetTxSampleRate(sps uint) error {
+	return s.setChainSampleRate(s.voltage0Tx, sps)
+}
+
+func (s *Sdr) setChainSampleRate(ch *iio.Channel, sps uint) error {
+	if sps < minSamplesPerSecond {
 		// TODO(paultag): Add in decimation bits.
 		return fmt.Errorf("pluto: minimum samples per second is 2083336")
 	}
 
-	// TODO(paultag): The tx and rx should be independently controllable
-	// for full duplex devices such as Pluto.
-	if err := s.voltage0Rx.WriteInt64("sampling_frequency", int64(sps)); err != nil {
+	if err := ch.WriteInt64("sampling_frequency", int64(sps)); err != nil {
 		return err
 	}
-	if err := s.voltage0Rx.WriteInt64("rf_bandwidth", int64(sps)); err != nil {
+	if err := ch.WriteInt64("rf_bandwidth", int64(sps)); err != nil {
 		return err
 	}
-	if err := s.voltage0Tx.WriteInt64("sampling_frequency", int64(sps)); err != nil {
+	return s.readSampleRates()
+}
+
+// readSampleRates will read back the sample rate of both chains.
+func (s *Sdr) readSampleRates() error {
+	rxSps, err := s.voltage0Rx.ReadInt64("sampling_frequency")
+	if err != nil {
 		return err
 	}
-	if err := s.voltage0Tx.WriteInt64("rf_bandwidth", int64(sps)); err != nil {
+	txSps, err := s.voltage0Tx.ReadInt64("sampling_frequency")
+	if err != nil {
 		return err
 	}
+	s.rxSamplesPerSecond = uint(rxSps)
+	s.txSamplesPerSecond = uint(txSps)
+	return nil
+}
 
-	s.samplesPerSecond = sps
-
+// SetBandwidth implements the sdr.BandwidthSetter interface.
+//
+// SetSampleRate sets the bandwidth to match the sample rate, so this must be
+// called after SetSampleRate to take effect.
+func (s *Sdr) SetBandwidth(bw rf.Hz) error {
+	if err := s.voltage0Rx.WriteInt64("rf_bandwidth", int64(bw)); err != nil {
+		return err
+	}
+	if err := s.voltage0Tx.WriteInt64("rf_bandwidth", int64(bw)); err != nil {
+		return err
+	}
 	return nil
 }
 
+// GetBandwidth implements the sdr.BandwidthSetter interface.
+func (s *Sdr) GetBandwidth() (rf.Hz, error) {
+	rxBw, err := s.voltage0Rx.ReadInt64("rf_bandwidth")
+	if err != nil {
+		return rf.Hz(0), err
+	}
+
+	txBw, err := s.voltage0Tx.ReadInt64("rf_bandwidth")
+	if err != nil {
+		return rf.Hz(0), err
+	}
+
+	if rxBw != txBw {
+		return rf.Hz(0), fmt.Errorf("pluto: rx and tx bandwidths are different")
+	}
+
+	return rf.Hz(rxBw), nil
+}
+
 // GetSampleRate implements the sdr.Sdr interface.
 func (s *Sdr) GetSampleRate() (uint, error) {
-	return s.samplesPerSecond, nil
+	if s.rxSamplesPerSecond != s.txSamplesPerSecond {
+		return 0, fmt.Errorf("pluto: rx and tx sample rates are different")
+	}
+	return s.rxSamplesPerSecond, nil
+}
+
+// GetRxSampleRate will return the sample rate of the receive chain.
+func (s *Sdr) GetRxSampleRate() (uint, error) {
+	return s.rxSamplesPerSecond, nil
+}
+
+// GetTxSampleRate will return the sample rate of the transmit chain.
+func (s *Sdr) GetTxSampleRate() (uint, error) {
+	return s.txSamplesPerSecond, nil
 }
 
 // SampleFormat implements the sdr.Sdr interface.
 func (s *Sdr) SampleFormat() sdr.SampleFormat {
-	return sdr.SampleFormatI16
+	return s.sampleFormat
 }
 
 // vim: foldmethod=marker
@@ -0,0 +1,117 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package pluto
+
+import (
+	"fmt"
+)
+
+var (
+	// ErrNoSuchChannel will be returned if the RxChannel or TxChannel
+	// requested doesn't exist on the board's Profile.
+	ErrNoSuchChannel = fmt.Errorf("pluto: channel does not exist on this board")
+)
+
+// Profile describes a board built around an AD936x transceiver, and how
+// its libiio devices are laid out. The control and streaming code is the
+// same for every AD936x board; only the Profile differs.
+type Profile struct {
+	// Name is a human readable name of the board.
+	Name string
+
+	// Chip is the transceiver on the board, such as "AD9363".
+	Chip string
+
+	// PhyName is the name of the iio device of the transceiver itself,
+	// used for control over things like sample rate or frequency.
+	PhyName string
+
+	// RxName is the name of the iio device of the RX ADC.
+	RxName string
+
+	// TxName is the name of the iio device of the TX DAC.
+	TxName string
+
+	// RxChannels and TxChannels are the number of receive and transmit
+	// chains the board has.
+	RxChannels int
+	TxChannels int
+}
+
+var (
+	// ProfilePlutoSDR is the ADALM-Pluto, a 1R1T AD9363 in a USB stick.
+	ProfilePlutoSDR = Profile{
+		Name:       "ADALM-Pluto",
+		Chip:       "AD9363",
+		PhyName:    "ad9361-phy",
+		RxName:     "cf-ad9361-lpc",
+		TxName:     "cf-ad9361-dds-core-lpc",
+		RxChannels: 1,
+		TxChannels: 1,
+	}
+
+	// ProfileADRV9364 is the ADRV9364-Z7020 SoM (on its breakout board),
+	// a 1R1T AD9364.
+	ProfileADRV9364 = Profile{
+		Name:       "ADRV9364-Z7020",
+		Chip:       "AD9364",
+		PhyName:    "ad9361-phy",
+		RxName:     "cf-ad9361-lpc",
+		TxName:     "cf-ad9361-dds-core-lpc",
+		RxChannels: 1,
+		TxChannels: 1,
+	}
+
+	// ProfileADRV9361 is the ADRV9361-Z7035 SoM, a 2R2T AD9361. Pick which
+	// of the chains to use with Options.RxChannel and Options.TxChannel.
+	ProfileADRV9361 = Profile{
+		Name:       "ADRV9361-Z7035",
+		Chip:       "AD9361",
+		PhyName:    "ad9361-phy",
+		RxName:     "cf-ad9361-lpc",
+		TxName:     "cf-ad9361-dds-core-lpc",
+		RxChannels: 2,
+		TxChannels: 2,
+	}
+)
+
+// OpenADRV9364 will establish a connection to an ADRV9364 SoM. The endpoint
+// is the same as for OpenWithOptions; any Profile in the Options is
+// replaced with ProfileADRV9364.
+func OpenADRV9364(endpoint string, opts Options) (*Sdr, error) {
+	opts.Profile = ProfileADRV9364
+	return OpenWithOptions(endpoint, opts)
+}
+
+// OpenADRV9361 will establish a connection to an ADRV9361 SoM. The endpoint
+// is the same as for OpenWithOptions; any Profile in the Options is
+// replaced with ProfileADRV9361.
+func OpenADRV9361(endpoint string, opts Options) (*Sdr, error) {
+	opts.Profile = ProfileADRV9361
+	return OpenWithOptions(endpoint, opts)
+}
+
+// Profile will return the Profile of the board this Sdr was opened with.
+func (s *Sdr) Profile() Profile {
+	return s.profile
+}
+
+// vim: foldmethod=marker
@@ -21,6 +21,7 @@
 package pluto
 
 import (
+	"fmt"
 	"time"
 	"unsafe"
 
@@ -36,18 +37,18 @@ type rx struct {
 	windowSize int
 }
 
-func openRx(ictx *iio.Context, windowSize int) (*rx, error) {
-	lpc, err := ictx.FindDevice(plutoRxName)
+func openRx(ictx *iio.Context, name string, channel int, windowSize int) (*rx, error) {
+	lpc, err := ictx.FindDevice(name)
 	if err != nil {
 		return nil, err
 	}
 
-	rxi, err := lpc.FindChannel("voltage0", iio.ChannelDirectionRead)
+	rxi, err := lpc.FindChannel(fmt.Sprintf("voltage%d", 2*channel), iio.ChannelDirectionRead)
 	if err != nil {
 		return nil, err
 	}
 
-	rxq, err := lpc.FindChannel("voltage1", iio.ChannelDirectionRead)
+	rxq, err := lpc.FindChannel(fmt.Sprintf("voltage%d", 2*channel+1), iio.ChannelDirectionRead)
 	if err != nil {
 		return nil, err
 	}
@@ -66,6 +67,10 @@ type readCloser struct {
 	checkOverruns bool
 	sdr           *Sdr
 	buf           sdr.SamplesI16
+
+	// packed is used to pack buf before writing it out, if the Sdr is
+	// using sdr.SampleFormatI12Packed.
+	packed sdr.SamplesI12Packed
 }
 
 func (rc *readCloser) Read(iq sdr.Samples) (int, error) {
@@ -143,9 +148,20 @@ func (rc *readCloser) run() error {
 			return err
 		}
 		buf = buf[:i/4]
-		buf.ShiftLSBToMSBBits(12)
 
-		n, err := rc.writer.Write(buf)
+		var out sdr.Samples = buf
+		if rc.packed != nil {
+			// The ADC samples are LSB aligned 12 bit values, which can be
+			// packed as-is.
+			for j := range buf {
+				rc.packed.Set(j, buf[j])
+			}
+			out = rc.packed[:len(buf)]
+		} else {
+			buf.ShiftLSBToMSBBits(12)
+		}
+
+		n, err := rc.writer.Write(out)
 		if err != nil {
 			return err
 		}
@@ -156,8 +172,8 @@ func (rc *readCloser) run() error {
 // StartRx implements the sdr.Sdr interface.
 func (s *Sdr) StartRx() (sdr.ReadCloser, error) {
 	ring, err := stream.NewRingBuffer(
-		s.samplesPerSecond,
-		sdr.SampleFormatI16,
+		s.rxSamplesPerSecond,
+		s.sampleFormat,
 		stream.RingBufferOptions{
 			Slots:      32,
 			SlotLength: s.rxWindowSize,
@@ -175,8 +191,12 @@ func (s *Sdr) StartRx() (sdr.ReadCloser, error) {
 		sdr:           s,
 		buf:           make(sdr.SamplesI16, s.rxWindowSize),
 	}
+	if s.sampleFormat == sdr.SampleFormatI12Packed {
+		rc.packed = make(sdr.SamplesI12Packed, s.rxWindowSize)
+	}
 
 	go func() {
+		defer sdr.RecoverDriverPanic(ring)
 		if err := rc.run(); err != nil {
 			ring.CloseWithError(err)
 		}
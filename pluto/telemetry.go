@@ -0,0 +1,122 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package pluto
+
+import (
+	"hz.tools/sdr"
+	"hz.tools/sdr/pluto/iio"
+)
+
+const (
+	// plutoXADCName is the name of the Zynq's on-chip ADC, which monitors
+	// the die temperature and supply rails.
+	plutoXADCName = "xadc"
+)
+
+// xadcVoltages maps the xadc voltage channels to the name of the supply
+// rail they monitor.
+var xadcVoltages = map[string]string{
+	"voltage0": "vccint",
+	"voltage1": "vccaux",
+	"voltage2": "vccbram",
+	"voltage3": "vccpint",
+	"voltage4": "vccpaux",
+	"voltage5": "vccoddr",
+	"voltage6": "vrefp",
+	"voltage7": "vrefn",
+}
+
+// ReadTelemetry implements the sdr.Telemetry interface.
+//
+// This will read the AD9361 temperature (as "ad9361"), and from the Zynq
+// xadc, the die temperature (as "zynq") and supply voltages (such as
+// "vccint"). The Pluto doesn't expose PLL lock state, so Locked is always
+// empty.
+func (s *Sdr) ReadTelemetry() (sdr.TelemetryReadings, error) {
+	readings := sdr.TelemetryReadings{
+		Temperatures: map[string]float64{},
+		Voltages:     map[string]float64{},
+		Locked:       map[string]bool{},
+	}
+
+	temp0, err := s.phy.FindChannel("temp0", iio.ChannelDirectionRead)
+	if err != nil {
+		return readings, err
+	}
+	// in millidegrees Celsius
+	mc, err := temp0.ReadInt64("input")
+	if err != nil {
+		return readings, err
+	}
+	readings.Temperatures["ad9361"] = float64(mc) / 1000
+
+	xadc, err := s.ictx.FindDevice(plutoXADCName)
+	if err != nil {
+		// Older firmware doesn't export the xadc over the network; that's
+		// not worth failing over.
+		return readings, nil
+	}
+
+	if temp0, err := xadc.FindChannel("temp0", iio.ChannelDirectionRead); err == nil {
+		temp, err := readXADC(temp0, true)
+		if err != nil {
+			return readings, err
+		}
+		readings.Temperatures["zynq"] = temp
+	}
+
+	for channel, name := range xadcVoltages {
+		voltage, err := xadc.FindChannel(channel, iio.ChannelDirectionRead)
+		if err != nil {
+			continue
+		}
+		v, err := readXADC(voltage, false)
+		if err != nil {
+			return readings, err
+		}
+		readings.Voltages[name] = v
+	}
+
+	return readings, nil
+}
+
+// readXADC will read the value of an xadc channel, which is reported
+// as raw counts, and a scale (and, for temperatures, an offset) to turn
+// them into millidegrees Celsius or millivolts.
+func readXADC(channel *iio.Channel, hasOffset bool) (float64, error) {
+	raw, err := channel.ReadFloat64("raw")
+	if err != nil {
+		return 0, err
+	}
+	scale, err := channel.ReadFloat64("scale")
+	if err != nil {
+		return 0, err
+	}
+	var offset float64
+	if hasOffset {
+		if offset, err = channel.ReadFloat64("offset"); err != nil {
+			return 0, err
+		}
+	}
+	return (raw + offset) * scale / 1000, nil
+}
+
+// vim: foldmethod=marker
@@ -21,6 +21,7 @@
 package pluto
 
 import (
+	"fmt"
 	"sync"
 	"unsafe"
 
@@ -35,18 +36,18 @@ type tx struct {
 	windowSize int
 }
 
-func openTx(ictx *iio.Context, windowSize int) (*tx, error) {
-	dds, err := ictx.FindDevice(plutoTxName)
+func openTx(ictx *iio.Context, name string, channel int, windowSize int) (*tx, error) {
+	dds, err := ictx.FindDevice(name)
 	if err != nil {
 		return nil, err
 	}
 
-	txi, err := dds.FindChannel("voltage0", iio.ChannelDirectionWrite)
+	txi, err := dds.FindChannel(fmt.Sprintf("voltage%d", 2*channel), iio.ChannelDirectionWrite)
 	if err != nil {
 		return nil, err
 	}
 
-	txq, err := dds.FindChannel("voltage1", iio.ChannelDirectionWrite)
+	txq, err := dds.FindChannel(fmt.Sprintf("voltage%d", 2*channel+1), iio.ChannelDirectionWrite)
 	if err != nil {
 		return nil, err
 	}
@@ -65,6 +66,10 @@ type writeCloser struct {
 	reader sdr.PipeReader
 	sdr    *Sdr
 	buf    sdr.SamplesI16
+
+	// packed is read from the Pipe and unpacked into buf, if the Sdr is
+	// using sdr.SampleFormatI12Packed.
+	packed sdr.SamplesI12Packed
 }
 
 func (wc *writeCloser) Write(iq sdr.Samples) (int, error) {
@@ -126,11 +131,20 @@ func (wc *writeCloser) run() error {
 			return err
 		}
 
-		n, err := sdr.ReadFull(wc.reader, buf)
+		var in sdr.Samples = buf
+		if wc.packed != nil {
+			in = wc.packed
+		}
+		n, err := sdr.ReadFull(wc.reader, in)
 		if err != nil && n == 0 {
 			return err
 		}
 		buf := buf[:n]
+		if wc.packed != nil {
+			if _, err := sdr.ConvertBuffer(buf, wc.packed[:n]); err != nil {
+				return err
+			}
+		}
 
 		_, err = ibuf.CopyToBufferFromUnsafe(
 			*tx.txi,
@@ -151,7 +165,7 @@ func (wc *writeCloser) run() error {
 
 // StartTx implements the sdr.Sdr interface.
 func (s *Sdr) StartTx() (sdr.WriteCloser, error) {
-	pipeReader, pipeWriter := sdr.Pipe(s.samplesPerSecond, sdr.SampleFormatI16)
+	pipeReader, pipeWriter := sdr.Pipe(s.txSamplesPerSecond, s.sampleFormat)
 
 	wg := &sync.WaitGroup{}
 	wg.Add(1)
@@ -163,8 +177,12 @@ func (s *Sdr) StartTx() (sdr.WriteCloser, error) {
 		sdr:    s,
 		buf:    make(sdr.SamplesI16, s.txWindowSize),
 	}
+	if s.sampleFormat == sdr.SampleFormatI12Packed {
+		wc.packed = make(sdr.SamplesI12Packed, s.txWindowSize)
+	}
 
 	go func() {
+		defer sdr.RecoverDriverPanic(pipeWriter)
 		if err := wc.run(); err != nil {
 			pipeWriter.CloseWithError(err)
 		}
@@ -57,7 +57,7 @@ func (sp SamplesPool) Get() Samples {
 // Under the hood this is a wrapped sync.Pool.
 func NewSamplesPool(format SampleFormat, length int) (*SamplesPool, error) {
 	switch format {
-	case SampleFormatU8, SampleFormatI8, SampleFormatI16, SampleFormatC64:
+	case SampleFormatU8, SampleFormatI8, SampleFormatI16, SampleFormatC64, SampleFormatC128, SampleFormatI12Packed:
 		break
 	default:
 		return nil, ErrSampleFormatUnknown
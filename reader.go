@@ -68,6 +68,17 @@ type ReadWriteCloser interface {
 	Closer
 }
 
+// AtLeastReader is the interface that wraps the ReadAtLeast method.
+//
+// Readers which are able to fill a buffer more efficiently than repeated
+// calls to Read (for instance, by handing the underlying byte slice to
+// io.ReadAtLeast) can implement this, and ReadFull and ReadAtLeast will
+// use it.
+type AtLeastReader interface {
+	// ReadAtLeast has the same semantics as sdr.ReadAtLeast.
+	ReadAtLeast(buf Samples, min int) (int, error)
+}
+
 // ReadFull reads exactly len(buf) bytes from r into buf.
 func ReadFull(r Reader, buf Samples) (int, error) {
 	return ReadAtLeast(r, buf, buf.Length())
@@ -95,6 +106,9 @@ func ReadAtLeast(r Reader, buf Samples, min int) (int, error) {
 	if buf.Length() < min {
 		return 0, ErrShortBuffer
 	}
+	if alr, ok := r.(AtLeastReader); ok {
+		return alr.ReadAtLeast(buf, min)
+	}
 	var (
 		n   int
 		err error
@@ -126,7 +140,7 @@ func (mr *multiReader) Read(s Samples) (int, error) {
 	}
 	i, err := mr.readers[mr.idx].Read(s)
 	if err == io.EOF {
-		if mr.idx >= len(mr.readers) {
+		if mr.idx+1 >= len(mr.readers) {
 			mr.err = io.EOF
 			return i, err
 		}
@@ -77,6 +77,11 @@ func TestMultiReaderU8(t *testing.T) {
 		}
 	}
 	wg.Wait()
+
+	_, err = multiReader.Read(buf)
+	assert.Equal(t, io.EOF, err)
+	_, err = multiReader.Read(buf)
+	assert.Equal(t, io.EOF, err)
 }
 
 func TestMultiReaderError(t *testing.T) {
@@ -0,0 +1,34 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+// Package remote allows any sdr.Sdr to be used over the network.
+//
+// Unlike hz.tools/sdr/rtltcp, which speaks the rtl_tcp protocol (and as such
+// is limited to uint8 samples and rtl-sdr semantics), the remote protocol
+// supports any sample format, gain stage discovery, and getting as well as
+// setting the frequency and sample rate. Both receive and transmit are
+// supported, if the device on the Server side supports them.
+//
+// The protocol is the same one used by hz.tools/sdr/helper to run an
+// sdr.Sdr out-of-process, so the Client returned by Dial is a
+// helper.Client.
+package remote
+
+// vim: foldmethod=marker
@@ -0,0 +1,131 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package remote_test
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/mock"
+	"hz.tools/sdr/remote"
+)
+
+type testGainStage struct{}
+
+func (testGainStage) Range() [2]float32       { return [2]float32{0, 10} }
+func (testGainStage) Type() sdr.GainStageType { return sdr.GainStageTypeRecieve }
+func (testGainStage) String() string          { return "LNA" }
+
+func serve(t *testing.T, dev sdr.Sdr) (string, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	server := remote.Server{
+		Handler: func(context.Context) (sdr.Sdr, error) {
+			return dev, nil
+		},
+	}
+	go server.Serve(listener)
+	return listener.Addr().String(), func() { listener.Close() }
+}
+
+func TestRemote(t *testing.T) {
+	pipeReader, pipeWriter := sdr.Pipe(1000, sdr.SampleFormatC64)
+	dev := mock.New(mock.Config{
+		SampleRate:   1000,
+		SampleFormat: sdr.SampleFormatC64,
+		Rx:           mock.ThisRx(pipeReader),
+		GainStages:   sdr.GainStages{testGainStage{}},
+	})
+
+	addr, stop := serve(t, dev)
+	defer stop()
+
+	client, err := remote.Dial("tcp", addr)
+	assert.NoError(t, err)
+	assert.Equal(t, sdr.SampleFormatC64, client.SampleFormat())
+
+	assert.NoError(t, client.SetCenterFrequency(433*rf.MHz))
+	freq, err := client.GetCenterFrequency()
+	assert.NoError(t, err)
+	assert.Equal(t, 433*rf.MHz, freq)
+
+	assert.NoError(t, client.SetSampleRate(2400000))
+	sps, err := client.GetSampleRate()
+	assert.NoError(t, err)
+	assert.Equal(t, uint(2400000), sps)
+
+	stages, err := client.GetGainStages()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(stages))
+	assert.Equal(t, "LNA", stages[0].String())
+	assert.NoError(t, client.SetGain(stages[0], 7))
+	gain, err := client.GetGain(stages[0])
+	assert.NoError(t, err)
+	assert.Equal(t, float32(7), gain)
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		buf := make(sdr.SamplesC64, 100)
+		for i := range buf {
+			buf[i] = complex(float32(i), -float32(i))
+		}
+		_, err := pipeWriter.Write(buf)
+		assert.NoError(t, err)
+	}()
+
+	rx, err := client.StartRx()
+	assert.NoError(t, err)
+	buf := make(sdr.SamplesC64, 100)
+	_, err = sdr.ReadFull(rx, buf)
+	assert.NoError(t, err)
+	assert.Equal(t, complex64(complex(99, -99)), buf[99])
+	wg.Wait()
+
+	assert.NoError(t, rx.Close())
+	assert.NoError(t, client.Close())
+}
+
+func TestRemoteNoTx(t *testing.T) {
+	dev := mock.New(mock.Config{
+		SampleRate:   1000,
+		SampleFormat: sdr.SampleFormatI16,
+	})
+
+	addr, stop := serve(t, dev)
+	defer stop()
+
+	client, err := remote.Dial("tcp", addr)
+	assert.NoError(t, err)
+
+	_, err = client.StartTx()
+	assert.Equal(t, sdr.ErrNotSupported, err)
+	assert.NoError(t, client.Close())
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,133 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package remote
+
+import (
+	"context"
+	"log"
+	"net"
+	"sync"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/helper"
+)
+
+// ServerHandler will return the sdr.Sdr to be used by the incoming
+// connection. The Server takes ownership of the sdr.Sdr, and will close it
+// once the connection has ended.
+type ServerHandler func(context.Context) (sdr.Sdr, error)
+
+// Server will listen for incoming connections, and serve an sdr.Sdr to each
+// of them.
+type Server struct {
+	// (Optional) TCP address to listen on.
+	Addr string
+
+	// Handler will be called when a new connection comes in, and be used to
+	// get the sdr.Sdr to serve to the remote end.
+	Handler ServerHandler
+
+	// ConnContext will create a context based on the provided net.Conn
+	ConnContext func(ctx context.Context, c net.Conn) context.Context
+}
+
+// closeOnce wraps an sdr.Sdr to make sure it's only closed once, since it
+// will be closed by both the Client (if it asks), and the Server when the
+// connection ends.
+type closeOnce struct {
+	sdr.Sdr
+	once *sync.Once
+	err  error
+}
+
+// Close implements the sdr.Sdr interface.
+func (c *closeOnce) Close() error {
+	c.once.Do(func() {
+		c.err = c.Sdr.Close()
+	})
+	return c.err
+}
+
+// StartRx implements the sdr.Receiver interface.
+func (c *closeOnce) StartRx() (sdr.ReadCloser, error) {
+	receiver, ok := c.Sdr.(sdr.Receiver)
+	if !ok {
+		return nil, sdr.ErrNotSupported
+	}
+	return receiver.StartRx()
+}
+
+// StartTx implements the sdr.Transmitter interface.
+func (c *closeOnce) StartTx() (sdr.WriteCloser, error) {
+	transmitter, ok := c.Sdr.(sdr.Transmitter)
+	if !ok {
+		return nil, sdr.ErrNotSupported
+	}
+	return transmitter.StartTx()
+}
+
+func (s Server) serveConn(ctx context.Context, conn net.Conn) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if s.ConnContext != nil {
+		ctx = s.ConnContext(ctx, conn)
+	}
+
+	dev, err := s.Handler(ctx)
+	if err != nil {
+		log.Printf("Error accepting new connection - closing connection")
+		log.Println(err)
+		conn.Close()
+		return err
+	}
+
+	wrapped := &closeOnce{Sdr: dev, once: &sync.Once{}}
+	defer wrapped.Close()
+
+	return helper.ServeConn(conn, wrapped)
+}
+
+// Serve will accept connections from the provided listener, and serve
+// client requests.
+func (s Server) Serve(listener net.Listener) error {
+	ctx := context.TODO()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go s.serveConn(ctx, conn)
+	}
+}
+
+// ListenAndServe will listen for incoming connections on Addr, and serve
+// client requests.
+func (s Server) ListenAndServe() error {
+	listener, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(listener)
+}
+
+// vim: foldmethod=marker
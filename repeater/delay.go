@@ -0,0 +1,86 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package repeater
+
+import (
+	"io"
+	"time"
+
+	"hz.tools/sdr"
+)
+
+// silence is an sdr.Reader which will return a fixed number of zero
+// complex64 samples before returning an io.EOF.
+type silence struct {
+	remaining  int
+	sampleRate uint
+}
+
+func (s *silence) SampleFormat() sdr.SampleFormat {
+	return sdr.SampleFormatC64
+}
+
+func (s *silence) SampleRate() uint {
+	return s.sampleRate
+}
+
+func (s *silence) Read(buf sdr.Samples) (int, error) {
+	bufC64, ok := buf.(sdr.SamplesC64)
+	if !ok {
+		return 0, sdr.ErrSampleFormatMismatch
+	}
+	if s.remaining == 0 {
+		return 0, io.EOF
+	}
+	n := len(bufC64)
+	if n > s.remaining {
+		n = s.remaining
+	}
+	for i := range bufC64[:n] {
+		bufC64[i] = 0
+	}
+	s.remaining -= n
+	return n, nil
+}
+
+// Delay will delay the provided Reader by the provided Duration (rounded
+// down to the nearest whole sample), by emitting silence before the first
+// sample read from the Reader.
+//
+// Since the transmit stream keeps flowing while the Squelch is closed, this
+// also means the last Duration worth of signal is drained out the
+// transmitter once the squelch closes, rather than being cut off. Only
+// SampleFormatC64 is supported.
+func Delay(r sdr.Reader, d time.Duration) (sdr.Reader, error) {
+	if r.SampleFormat() != sdr.SampleFormatC64 {
+		return nil, sdr.ErrSampleFormatMismatch
+	}
+	n := int(d.Seconds() * float64(r.SampleRate()))
+	if n <= 0 {
+		return r, nil
+	}
+	return sdr.MultiReader(&silence{
+		remaining:  n,
+		sampleRate: r.SampleRate(),
+	}, r)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,244 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package repeater
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/stream"
+)
+
+var (
+	// ErrSplitNotSupported will be returned if the RxFrequency and
+	// TxFrequency differ, but the device is not able to tune the receive
+	// and transmit chains independently.
+	ErrSplitNotSupported = fmt.Errorf("repeater: device can not tune rx and tx independently")
+)
+
+// Config contains the configuration for a Repeater.
+type Config struct {
+	// RxFrequency is the frequency to listen on.
+	RxFrequency rf.Hz
+
+	// TxFrequency is the frequency to transmit on. If this is different
+	// than the RxFrequency, the device must implement sdr.DuplexTuner.
+	TxFrequency rf.Hz
+
+	// SampleRate is the sample rate to run the device at.
+	SampleRate uint
+
+	// Process, if set, will be applied to the received complex64 samples
+	// before the squelch, and must return complex64 samples at the same
+	// sample rate. This is where a demodulator and modulator pair would go
+	// for a regenerative repeater. If nil, the received samples are
+	// retransmitted as-is.
+	Process func(sdr.Reader) (sdr.Reader, error)
+
+	// SquelchLevel is the power (in dBFS) at which to start repeating.
+	SquelchLevel float32
+
+	// SquelchHang is how long the squelch stays open after the signal drops
+	// below the SquelchLevel. If 0, this will default to 250ms.
+	SquelchHang time.Duration
+
+	// Delay is how long to delay the received signal by before it is
+	// transmitted. If 0, the signal is not delayed.
+	Delay time.Duration
+}
+
+func (c Config) getSquelchHang() time.Duration {
+	if c.SquelchHang == 0 {
+		return 250 * time.Millisecond
+	}
+	return c.SquelchHang
+}
+
+// Repeater is a running repeater.
+type Repeater struct {
+	squelch *Squelch
+	rx      sdr.ReadCloser
+	tx      sdr.WriteCloser
+
+	done chan struct{}
+	err  error
+
+	closeOnce sync.Once
+}
+
+// tune will set the receive and transmit center frequencies.
+func tune(dev sdr.Transceiver, cfg Config) error {
+	if dt, ok := dev.(sdr.DuplexTuner); ok {
+		if err := dt.SetRxCenterFrequency(cfg.RxFrequency); err != nil {
+			return err
+		}
+		return dt.SetTxCenterFrequency(cfg.TxFrequency)
+	}
+	if cfg.RxFrequency != cfg.TxFrequency {
+		return ErrSplitNotSupported
+	}
+	return dev.SetCenterFrequency(cfg.RxFrequency)
+}
+
+// Start will configure the device, start the receive and transmit streams,
+// and begin repeating.
+func Start(dev sdr.Transceiver, cfg Config) (*Repeater, error) {
+	if err := dev.SetSampleRate(cfg.SampleRate); err != nil {
+		return nil, err
+	}
+	if err := tune(dev, cfg); err != nil {
+		return nil, err
+	}
+
+	rx, err := dev.StartRx()
+	if err != nil {
+		return nil, err
+	}
+
+	r, squelch, err := pipeline(rx, cfg)
+	if err != nil {
+		rx.Close()
+		return nil, err
+	}
+
+	tx, err := dev.StartTx()
+	if err != nil {
+		rx.Close()
+		return nil, err
+	}
+
+	w, err := stream.ConvertWriter(tx, sdr.SampleFormatC64)
+	if err != nil {
+		rx.Close()
+		tx.Close()
+		return nil, err
+	}
+
+	rpt := &Repeater{
+		squelch: squelch,
+		rx:      rx,
+		tx:      tx,
+		done:    make(chan struct{}),
+	}
+
+	go func() {
+		defer close(rpt.done)
+		_, rpt.err = sdr.Copy(w, r)
+	}()
+
+	return rpt, nil
+}
+
+// pipeline will build the receive half of the repeater, returning the
+// Reader to be copied to the transmitter.
+func pipeline(rx sdr.Reader, cfg Config) (sdr.Reader, *Squelch, error) {
+	var (
+		r   sdr.Reader = rx
+		err error
+	)
+
+	if r.SampleFormat() != sdr.SampleFormatC64 {
+		r, err = stream.ConvertReader(r, sdr.SampleFormatC64)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if cfg.Process != nil {
+		r, err = cfg.Process(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		if r.SampleFormat() != sdr.SampleFormatC64 {
+			return nil, nil, sdr.ErrSampleFormatMismatch
+		}
+		if r.SampleRate() != rx.SampleRate() {
+			return nil, nil, fmt.Errorf("repeater: Process changed the sample rate")
+		}
+	}
+
+	squelch, err := NewSquelch(r, cfg.SquelchLevel, cfg.getSquelchHang())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r, err = Delay(squelch, cfg.Delay)
+	if err != nil {
+		return nil, nil, err
+	}
+	return r, squelch, nil
+}
+
+// Open will return true if the squelch is open, and the received signal is
+// being repeated.
+func (rpt *Repeater) Open() bool {
+	return rpt.squelch.Open()
+}
+
+// Done returns a channel which is closed when the repeater stops, either due
+// to Close being called or an error on the receive or transmit stream.
+func (rpt *Repeater) Done() <-chan struct{} {
+	return rpt.done
+}
+
+// Close will stop the repeater. The receive stream is closed first, and
+// the repeater waits for any samples in flight to be written before the
+// transmit stream is closed.
+func (rpt *Repeater) Close() error {
+	var err error
+	rpt.closeOnce.Do(func() {
+		err = rpt.rx.Close()
+		<-rpt.done
+		if terr := rpt.tx.Close(); err == nil {
+			err = terr
+		}
+	})
+	return err
+}
+
+// Err will return the error that stopped the repeater, if any. This must
+// only be called once the Done channel has been closed.
+func (rpt *Repeater) Err() error {
+	return rpt.err
+}
+
+// Run will start a repeater, and block until the context is cancelled or
+// the repeater fails.
+func Run(ctx context.Context, dev sdr.Transceiver, cfg Config) error {
+	rpt, err := Start(dev, cfg)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		return rpt.Close()
+	case <-rpt.Done():
+		rpt.Close()
+		return rpt.Err()
+	}
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,185 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package repeater_test
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/mock"
+	"hz.tools/sdr/repeater"
+)
+
+// burstReader returns blocks of 100 samples, where the blocks listed in
+// loud are at an amplitude of 0.5, and the rest are silent.
+type burstReader struct {
+	block  int
+	blocks int
+	loud   map[int]bool
+}
+
+func (br *burstReader) SampleFormat() sdr.SampleFormat { return sdr.SampleFormatC64 }
+func (br *burstReader) SampleRate() uint               { return 1000 }
+func (br *burstReader) Close() error                   { return nil }
+
+func (br *burstReader) Read(s sdr.Samples) (int, error) {
+	if br.block >= br.blocks {
+		return 0, io.EOF
+	}
+	buf := s.(sdr.SamplesC64)
+	if len(buf) > 100 {
+		buf = buf[:100]
+	}
+	for i := range buf {
+		buf[i] = 0
+		if br.loud[br.block] {
+			buf[i] = complex(0.5, 0)
+		}
+	}
+	br.block++
+	return len(buf), nil
+}
+
+type collectWriter struct {
+	lock    sync.Mutex
+	samples sdr.SamplesC64
+}
+
+func (cw *collectWriter) SampleFormat() sdr.SampleFormat { return sdr.SampleFormatC64 }
+func (cw *collectWriter) SampleRate() uint               { return 1000 }
+func (cw *collectWriter) Close() error                   { return nil }
+
+func (cw *collectWriter) Write(s sdr.Samples) (int, error) {
+	cw.lock.Lock()
+	defer cw.lock.Unlock()
+	cw.samples = append(cw.samples, s.(sdr.SamplesC64)...)
+	return s.Length(), nil
+}
+
+func newDevice(rx *burstReader, tx *collectWriter) sdr.Transceiver {
+	return mock.New(mock.Config{
+		SampleRate:   1000,
+		SampleFormat: sdr.SampleFormatC64,
+		Rx:           mock.ThisRx(rx),
+		Tx:           mock.ThisTx(tx),
+	})
+}
+
+type duplexDevice struct {
+	sdr.Transceiver
+	rx, tx rf.Hz
+}
+
+func (d *duplexDevice) SetRxCenterFrequency(freq rf.Hz) error {
+	d.rx = freq
+	return nil
+}
+
+func (d *duplexDevice) SetTxCenterFrequency(freq rf.Hz) error {
+	d.tx = freq
+	return nil
+}
+
+func TestRepeater(t *testing.T) {
+	rx := &burstReader{blocks: 10, loud: map[int]bool{1: true, 2: true}}
+	tx := &collectWriter{}
+	dev := &duplexDevice{Transceiver: newDevice(rx, tx)}
+
+	err := repeater.Run(context.Background(), dev, repeater.Config{
+		RxFrequency:  rf.MHz * 146.34,
+		TxFrequency:  rf.MHz * 146.94,
+		SampleRate:   1000,
+		SquelchLevel: -20,
+		SquelchHang:  time.Millisecond,
+		Delay:        100 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, rf.MHz*146.34, dev.rx)
+	assert.Equal(t, rf.MHz*146.94, dev.tx)
+
+	// 100 samples of delay, and 1000 samples of input.
+	assert.Equal(t, 1100, len(tx.samples))
+	for i, sample := range tx.samples {
+		if i >= 200 && i < 400 {
+			assert.Equal(t, complex64(complex(0.5, 0)), sample, "sample %d", i)
+		} else {
+			assert.Equal(t, complex64(0), sample, "sample %d", i)
+		}
+	}
+}
+
+func TestRepeaterSquelchClosed(t *testing.T) {
+	rx := &burstReader{blocks: 10, loud: map[int]bool{1: true}}
+	tx := &collectWriter{}
+
+	err := repeater.Run(context.Background(), newDevice(rx, tx), repeater.Config{
+		RxFrequency:  rf.MHz * 146.52,
+		TxFrequency:  rf.MHz * 146.52,
+		SampleRate:   1000,
+		SquelchLevel: 0,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, len(tx.samples))
+	for _, sample := range tx.samples {
+		assert.Equal(t, complex64(0), sample)
+	}
+}
+
+func TestRepeaterProcess(t *testing.T) {
+	rx := &burstReader{blocks: 2, loud: map[int]bool{0: true, 1: true}}
+	tx := &collectWriter{}
+
+	called := false
+	err := repeater.Run(context.Background(), newDevice(rx, tx), repeater.Config{
+		RxFrequency:  rf.MHz * 146.52,
+		TxFrequency:  rf.MHz * 146.52,
+		SampleRate:   1000,
+		SquelchLevel: -20,
+		Process: func(r sdr.Reader) (sdr.Reader, error) {
+			called = true
+			return r, nil
+		},
+	})
+	assert.NoError(t, err)
+	assert.True(t, called)
+	assert.Equal(t, 200, len(tx.samples))
+	assert.Equal(t, complex64(complex(0.5, 0)), tx.samples[0])
+}
+
+func TestRepeaterSplitNotSupported(t *testing.T) {
+	dev := newDevice(&burstReader{}, &collectWriter{})
+	err := repeater.Run(context.Background(), dev, repeater.Config{
+		RxFrequency: rf.MHz * 146.34,
+		TxFrequency: rf.MHz * 146.94,
+		SampleRate:  1000,
+	})
+	assert.Equal(t, repeater.ErrSplitNotSupported, err)
+}
+
+// vim: foldmethod=marker
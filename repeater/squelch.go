@@ -0,0 +1,111 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package repeater
+
+import (
+	"sync/atomic"
+	"time"
+
+	"hz.tools/sdr"
+)
+
+// Squelch is an sdr.Reader that will zero out any samples while the power
+// of the underlying stream is below a threshold, and pass samples through
+// unmodified while the squelch is open.
+//
+// The squelch opens as soon as a buffer is read with a power at or above
+// the threshold, and will stay open until the power has remained below the
+// threshold for the hang time, to avoid chopping up a signal which fades
+// briefly.
+type Squelch struct {
+	r         sdr.Reader
+	threshold float32
+	hang      int
+
+	// since is the number of samples read since the power was last above
+	// the threshold.
+	since int
+
+	open int32
+}
+
+// NewSquelch will create a new Squelch gating the provided Reader, which
+// must be in SampleFormatC64. The threshold is in dBFS (as returned by
+// sdr.Stats.DBFS).
+func NewSquelch(r sdr.Reader, threshold float32, hang time.Duration) (*Squelch, error) {
+	if r.SampleFormat() != sdr.SampleFormatC64 {
+		return nil, sdr.ErrSampleFormatMismatch
+	}
+	hangSamples := int(hang.Seconds() * float64(r.SampleRate()))
+	return &Squelch{
+		r:         r,
+		threshold: threshold,
+		hang:      hangSamples,
+		since:     hangSamples + 1,
+	}, nil
+}
+
+// Open will return true if the squelch is currently passing samples.
+func (s *Squelch) Open() bool {
+	return atomic.LoadInt32(&s.open) == 1
+}
+
+// SampleFormat implements the sdr.Reader interface.
+func (s *Squelch) SampleFormat() sdr.SampleFormat {
+	return sdr.SampleFormatC64
+}
+
+// SampleRate implements the sdr.Reader interface.
+func (s *Squelch) SampleRate() uint {
+	return s.r.SampleRate()
+}
+
+// Read implements the sdr.Reader interface.
+func (s *Squelch) Read(buf sdr.Samples) (int, error) {
+	bufC64, ok := buf.(sdr.SamplesC64)
+	if !ok {
+		return 0, sdr.ErrSampleFormatMismatch
+	}
+
+	n, err := s.r.Read(bufC64)
+	if n == 0 {
+		return n, err
+	}
+
+	block := bufC64[:n]
+	if block.Stats().DBFS() >= s.threshold {
+		s.since = 0
+	} else {
+		s.since += n
+	}
+
+	if s.since > s.hang {
+		atomic.StoreInt32(&s.open, 0)
+		for i := range block {
+			block[i] = 0
+		}
+	} else {
+		atomic.StoreInt32(&s.open, 1)
+	}
+	return n, err
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,200 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package kerberos
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"sync"
+	"time"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/calibrate"
+	"hz.tools/sdr/rtl/kerberos/internal"
+	"hz.tools/sdr/stream"
+)
+
+// Calibration is a per-channel phase and amplitude equalizer for a set of
+// coherent streams. It holds a complex correction for each channel, which
+// is applied by a (SIMD) multiply on each stream, and can be updated while
+// the streams are being read, to track drift between the tuners as they
+// warm up.
+//
+// A single Calibration is meant to live as long as the coherent receive
+// does, being measured again every so often (ideally with the noise source
+// on, or with some other strong signal common to all channels).
+type Calibration struct {
+	lock        *sync.Mutex
+	smoothing   float64
+	corrections []complex64
+}
+
+// NewCalibration will create a Calibration for `channels` channels, which
+// starts off not changing anything. smoothing is how much of each new
+// measurement is folded into the correction, from 0 (ignore measurements)
+// to 1 (use each measurement as-is).
+func NewCalibration(channels int, smoothing float64) (*Calibration, error) {
+	if channels <= 0 || smoothing < 0 || smoothing > 1 {
+		return nil, fmt.Errorf("rtl/kerberos: invalid calibration parameters")
+	}
+	corrections := make([]complex64, channels)
+	for i := range corrections {
+		corrections[i] = 1
+	}
+	return &Calibration{
+		lock:        &sync.Mutex{},
+		smoothing:   smoothing,
+		corrections: corrections,
+	}, nil
+}
+
+// Corrections will return a copy of the current per-channel corrections.
+func (c *Calibration) Corrections() []complex64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]complex64{}, c.corrections...)
+}
+
+func (c *Calibration) correction(channel int) complex64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.corrections[channel]
+}
+
+type calibratedReader struct {
+	sdr.Reader
+	cal     *Calibration
+	channel int
+}
+
+func (cr calibratedReader) Read(s sdr.Samples) (int, error) {
+	sC64, ok := s.(sdr.SamplesC64)
+	if !ok {
+		return 0, sdr.ErrSampleFormatMismatch
+	}
+	i, err := cr.Reader.Read(sC64)
+	if i > 0 {
+		sC64[:i].Multiply(cr.cal.correction(cr.channel))
+	}
+	return i, err
+}
+
+// Apply will convert each of the provided readers to SampleFormatC64, and
+// multiply each sample by the correction for that channel. Future updates
+// to the Calibration will be applied to the returned Readers as they're
+// read, and they should be used for any further calls to Measure.
+func (c *Calibration) Apply(readers []sdr.Reader) ([]sdr.Reader, error) {
+	if len(readers) != len(c.corrections) {
+		return nil, fmt.Errorf("rtl/kerberos: calibration has %d channels, got %d readers",
+			len(c.corrections), len(readers))
+	}
+
+	ret := make([]sdr.Reader, len(readers))
+	for i := range readers {
+		r := readers[i]
+		if r.SampleFormat() != sdr.SampleFormatC64 {
+			var err error
+			r, err = stream.ConvertReader(r, sdr.SampleFormatC64)
+			if err != nil {
+				return nil, err
+			}
+		}
+		ret[i] = calibratedReader{Reader: r, cal: c, channel: i}
+	}
+	return ret, nil
+}
+
+// Set will replace the corrections outright, such as when the streams have
+// just been started, and any previous phase relationship is meaningless.
+func (c *Calibration) Set(corrections []complex64) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if len(corrections) != len(c.corrections) {
+		return fmt.Errorf("rtl/kerberos: wrong number of corrections")
+	}
+	copy(c.corrections, corrections)
+	return nil
+}
+
+// Update will fold in a residual correction measured on the already
+// corrected streams, moving the correction for each channel part of the
+// way (based on the smoothing) towards it, so that noisy measurements
+// don't cause the phase to jump around.
+func (c *Calibration) Update(residual []complex64) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if len(residual) != len(c.corrections) {
+		return fmt.Errorf("rtl/kerberos: wrong number of corrections")
+	}
+
+	for i, r := range residual {
+		var (
+			mag   = math.Pow(cmplx.Abs(complex128(r)), c.smoothing)
+			phase = cmplx.Phase(complex128(r)) * c.smoothing
+		)
+		if mag == 0 || math.IsNaN(mag) || math.IsInf(mag, 0) {
+			continue
+		}
+		c.corrections[i] *= complex64(cmplx.Rect(mag, phase))
+	}
+	return nil
+}
+
+// Measure will read from the provided (corrected) readers, such as the
+// ones returned by Apply, measure what's left of the phase and amplitude
+// difference between channels, and Update the corrections. This consumes
+// samples from the readers.
+func (c *Calibration) Measure(readers []sdr.Reader) error {
+	residual, err := internal.ChannelCorrections(readers)
+	if err != nil {
+		return err
+	}
+	return c.Update(residual)
+}
+
+// MeasureWithNoiseSource will turn on the noise source (such as the one
+// returned by CoherentSdr.NoiseSource), discard samples for the settle
+// time, Measure, and then turn the noise source off again.
+func (c *Calibration) MeasureWithNoiseSource(
+	noise calibrate.Switch,
+	settle time.Duration,
+	readers []sdr.Reader,
+) error {
+	return calibrate.With(noise, func() error {
+		if len(readers) == 0 {
+			return fmt.Errorf("rtl/kerberos: no readers to measure")
+		}
+		n := int(settle.Seconds() * float64(readers[0].SampleRate()))
+		if n > 0 {
+			bufs := make([]sdr.SamplesC64, len(readers))
+			for i := range bufs {
+				bufs[i] = make(sdr.SamplesC64, n)
+			}
+			if err := internal.ReadBuffers(readers, bufs); err != nil {
+				return err
+			}
+		}
+		return c.Measure(readers)
+	})
+}
+
+// vim: foldmethod=marker
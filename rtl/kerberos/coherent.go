@@ -24,30 +24,88 @@ import (
 	"fmt"
 
 	"hz.tools/sdr"
+	"hz.tools/sdr/calibrate"
 	"hz.tools/sdr/fft"
 	"hz.tools/sdr/rtl/kerberos/internal"
 	"hz.tools/sdr/stream"
 )
 
-// CoherentSdr will return a "meta-sdr" that tunes each of the 4 Kerberos SDR
+// CoherentSdr will return a "meta-sdr" that tunes each of the Kerberos SDR
 // dongles to the same frequency, and allow for a StartCoherentRx call.
 type CoherentSdr struct {
-	*Sdr
-	planner fft.Planner
+	Sdr
+	planner     fft.Planner
+	subSample   bool
+	calibration *Calibration
+	tracking    *TrackingOptions
+	noise       calibrate.Switch
 }
 
-// NewCoherent will create a new CoherentSdr.
+// NewCoherent will create a new CoherentSdr. If planner is nil,
+// fft.DefaultPlanner is used.
 func NewCoherent(planner fft.Planner, i1, i2, i3, i4 uint, windowSize uint) (*CoherentSdr, error) {
-	sdr, err := New(i1, i2, i3, i4, windowSize)
+	return NewCoherentN(planner, []uint{i1, i2, i3, i4}, windowSize)
+}
+
+// NewCoherentN will create a new CoherentSdr out of any number of RTL-SDR
+// dongles sharing a clock, such as the 5 in a KrakenSDR. If planner is nil,
+// fft.DefaultPlanner is used.
+func NewCoherentN(planner fft.Planner, indexes []uint, windowSize uint) (*CoherentSdr, error) {
+	if planner == nil {
+		planner = fft.DefaultPlanner
+	}
+	sdr, err := NewN(indexes, windowSize)
 	if err != nil {
 		return nil, err
 	}
 	return &CoherentSdr{
-		Sdr:     sdr,
+		Sdr:     *sdr,
 		planner: planner,
+		noise:   calibrate.BiasT(sdr),
 	}, nil
 }
 
+// SetSubSampleAlignment will turn on (or off) alignment of the streams to
+// a fraction of a sample. By default, streams are only aligned to the
+// nearest whole sample, which leaves up to half a sample of skew between
+// streams.
+//
+// When this is on, the streams returned by StartCoherentRx will be in
+// SampleFormatC64 rather than SampleFormatU8, since the fractional delay
+// is applied with a Farrow interpolator (see filter.Farrow).
+func (c *CoherentSdr) SetSubSampleAlignment(on bool) {
+	c.subSample = on
+}
+
+// SetCalibration will set a Calibration to use for the next StartCoherentRx.
+// Rather than only correcting the phase of each stream once, the streams
+// will be corrected for phase and amplitude by the Calibration, which is
+// reset when the receive starts (since the phase offsets are different
+// every time the tuners start), and can be re-measured to track drift
+// while the streams are being read.
+//
+// When a Calibration is set, the streams returned by StartCoherentRx will
+// be in SampleFormatC64.
+func (c *CoherentSdr) SetCalibration(cal *Calibration) {
+	c.calibration = cal
+}
+
+// NoiseSource will return a calibrate.Switch which controls the noise source
+// shared by all the channels, for use with Calibration.MeasureWithNoiseSource
+// (or a calibrate.YFactor measurement).
+func (c *CoherentSdr) NoiseSource() calibrate.Switch {
+	return c.noise
+}
+
+// SetNoiseSource will change how the noise source shared by all the channels
+// is switched on and off. By default, this is the bias tee of the 0th
+// dongle, which is where the KerberosSDR has its RNG. Boards which have the
+// noise source on another GPIO pin can use calibrate.GPIO, and boards with
+// an inverted control line can use calibrate.Invert.
+func (c *CoherentSdr) SetNoiseSource(sw calibrate.Switch) {
+	c.noise = sw
+}
+
 // CoherentReadCloser is a slice of ReadClosers, which are in sample lock.
 type CoherentReadCloser sdr.ReadClosers
 
@@ -81,6 +139,59 @@ func (cr CoherentReadCloser) Sync(planner fft.Planner) ([]complex64, error) {
 	return internal.PhaseOffsets(readers)
 }
 
+// syncSubSample will align the buffers to the nearest sample, and then
+// replace each ReadCloser with a C64 ReadCloser which is delayed by
+// whatever fraction of a sample is left over.
+func (cr CoherentReadCloser) syncSubSample(planner fft.Planner) error {
+	readers, err := cr.ReadersC64()
+	if err != nil {
+		return err
+	}
+	if err := internal.AlignReaders(planner, readers); err != nil {
+		return err
+	}
+	delays, err := internal.FractionalDelays(planner, readers)
+	if err != nil {
+		return err
+	}
+	for i := range cr {
+		r, err := stream.FractionalDelay(readers[i], delays[i])
+		if err != nil {
+			return err
+		}
+		cr[i] = sdr.ReaderWithCloser(r, cr[i].Close)
+	}
+	return nil
+}
+
+// calibrate will align the buffers, measure the phase and amplitude
+// differences into the Calibration, and replace each ReadCloser with one
+// that has the Calibration applied.
+func (cr CoherentReadCloser) calibrate(planner fft.Planner, cal *Calibration) error {
+	readers, err := cr.ReadersC64()
+	if err != nil {
+		return err
+	}
+	if err := internal.AlignReaders(planner, readers); err != nil {
+		return err
+	}
+	corrections, err := internal.ChannelCorrections(readers)
+	if err != nil {
+		return err
+	}
+	if err := cal.Set(corrections); err != nil {
+		return err
+	}
+	calibrated, err := cal.Apply(readers)
+	if err != nil {
+		return err
+	}
+	for i := range cr {
+		cr[i] = sdr.ReaderWithCloser(calibrated[i], cr[i].Close)
+	}
+	return nil
+}
+
 // Close will close all the ReadClosers.
 func (cr CoherentReadCloser) Close() error {
 	for _, rc := range cr {
@@ -91,22 +202,13 @@ func (cr CoherentReadCloser) Close() error {
 	return nil
 }
 
-// StartCoherentRx will start all the RTL dongles, align the Readers, and
-// return a slice of CoherentReadCloser objects.
-//
-// This will toggle the BiasT feature (RNG), and also flip the AGC on.
-// If the AGC is not needed, it needs to be explicitly turned off after
-// this function call.
-func (c *CoherentSdr) StartCoherentRx() (sdr.ReadClosers, error) {
-	var (
-		err     error
-		sps     uint
-		planner = c.planner
-		k       = c.Sdr
-		ret     = make(CoherentReadCloser, len(k))
-	)
+// startRx will check that all the dongles are at the same sample rate,
+// turn on the AGC and the noise source, and start all the dongles. The
+// streams are not aligned yet.
+func (c *CoherentSdr) startRx() (CoherentReadCloser, error) {
+	k := c.Sdr
 
-	sps, err = k[0].GetSampleRate()
+	sps, err := k[0].GetSampleRate()
 	if err != nil {
 		return nil, err
 	}
@@ -121,40 +223,76 @@ func (c *CoherentSdr) StartCoherentRx() (sdr.ReadClosers, error) {
 	}
 
 	if err := c.SetAutomaticGain(true); err != nil {
-		ret.Close()
 		return nil, err
 	}
 
-	if err := c.SetBiasT(true); err != nil {
-		ret.Close()
+	if err := c.noise.Set(true); err != nil {
 		return nil, err
 	}
 
+	ret := make(CoherentReadCloser, len(k))
 	for i := range k {
 		ret[i], err = k[i].StartRx()
 		if err != nil {
+			ret[:i].Close()
 			return nil, err
 		}
 	}
+	return ret, nil
+}
+
+// StartCoherentRx will start all the RTL dongles, align the Readers, and
+// return a slice of CoherentReadCloser objects.
+//
+// This will toggle the noise source (RNG), and also flip the AGC on.
+// If the AGC is not needed, it needs to be explicitly turned off after
+// this function call.
+//
+// If SetTracking was called, the streams will be kept aligned while they're
+// being read, see TrackingOptions.
+func (c *CoherentSdr) StartCoherentRx() (sdr.ReadClosers, error) {
+	if c.tracking != nil {
+		return c.startTrackedRx(*c.tracking)
+	}
 
-	rotations, err := ret.Sync(planner)
+	planner := c.planner
+	ret, err := c.startRx()
 	if err != nil {
-		ret.Close()
 		return nil, err
 	}
 
-	for i := range ret {
-		r, err := stream.Multiply(ret[i], rotations[i])
+	if c.subSample {
+		if err := ret.syncSubSample(planner); err != nil {
+			ret.Close()
+			return nil, err
+		}
+	}
+
+	if c.calibration != nil {
+		if err := ret.calibrate(planner, c.calibration); err != nil {
+			ret.Close()
+			return nil, err
+		}
+	} else {
+		rotations, err := ret.Sync(planner)
 		if err != nil {
+			ret.Close()
 			return nil, err
 		}
-		ret[i] = sdr.ReaderWithCloser(r, ret[i].Close)
+
+		for i := range ret {
+			r, err := stream.Multiply(ret[i], rotations[i])
+			if err != nil {
+				return nil, err
+			}
+			ret[i] = sdr.ReaderWithCloser(r, ret[i].Close)
+		}
 	}
 
 	go func() {
 		// Do this in a goroutine since the Rx needs to be consumed for
 		// this to go through. This isn't good.
-		if err := c.SetBiasT(false); err != nil {
+		if err := c.noise.Set(false); err != nil {
 			ret.Close()
 		}
 	}()
@@ -121,7 +121,7 @@ func checkAlignment(planner fft.Planner, readers []sdr.Reader, bufs []sdr.Sample
 	}
 
 	for i := 1; i < len(bufs); i++ {
-		cc, err := ccr.Correlate(bufs[0], bufs[1])
+		cc, err := ccr.Correlate(bufs[0], bufs[i])
 		if err != nil {
 			return nil, err
 		}
@@ -270,6 +270,194 @@ func PhaseOffsets(readers []sdr.Reader) ([]complex64, error) {
 	return ret, nil
 }
 
+// ChannelCorrections will compute the complex correction for each reader
+// which best matches it to the 0th reader, in a least squares sense. Unlike
+// PhaseOffsets, this corrects amplitude as well as phase. The 0th index
+// will always be 1.
+func ChannelCorrections(readers []sdr.Reader) ([]complex64, error) {
+	bufs := make([]sdr.SamplesC64, len(readers))
+	for i := range bufs {
+		bufs[i] = make(sdr.SamplesC64, 1024*64)
+	}
+	if err := ReadBuffers(readers, bufs); err != nil {
+		return nil, err
+	}
+	return BufferCorrections(bufs), nil
+}
+
+// BufferCorrections is ChannelCorrections, but for buffers which have
+// already been read.
+func BufferCorrections(bufs []sdr.SamplesC64) []complex64 {
+	ret := make([]complex64, len(bufs))
+	ret[0] = 1
+	for j := 1; j < len(bufs); j++ {
+		var (
+			cross complex128
+			pow   float64
+		)
+		for i := range bufs[j] {
+			cross += complex128(conjMult(bufs[0][i], bufs[j][i]))
+			pow += float64(real(bufs[j][i])*real(bufs[j][i]) + imag(bufs[j][i])*imag(bufs[j][i]))
+		}
+		if pow == 0 {
+			ret[j] = 1
+			continue
+		}
+		ret[j] = complex64(cross / complex(pow, 0))
+	}
+	return ret
+}
+
+// PhaseResiduals will compare each (already corrected) buffer to the 0th
+// buffer, and return the unit rotation which would line its phase up, along
+// with the coherence (from 0 to 1) between the two. A low coherence means
+// the buffers don't have much signal in common, and the rotation is not to
+// be trusted. The 0th index will always be 1.
+func PhaseResiduals(bufs []sdr.SamplesC64) ([]complex64, []float64) {
+	var (
+		rotations = make([]complex64, len(bufs))
+		coherence = make([]float64, len(bufs))
+		pow0      float64
+	)
+	for _, el := range bufs[0] {
+		pow0 += float64(real(el)*real(el) + imag(el)*imag(el))
+	}
+	rotations[0] = 1
+	coherence[0] = 1
+
+	for j := 1; j < len(bufs); j++ {
+		var (
+			cross complex128
+			pow   float64
+		)
+		for i := range bufs[j] {
+			cross += complex128(conjMult(bufs[0][i], bufs[j][i]))
+			pow += float64(real(bufs[j][i])*real(bufs[j][i]) + imag(bufs[j][i])*imag(bufs[j][i]))
+		}
+		mag := cmplx.Abs(cross)
+		if mag == 0 || pow == 0 || pow0 == 0 {
+			rotations[j] = 1
+			continue
+		}
+		rotations[j] = complex64(cross / complex(mag, 0))
+		coherence[j] = mag / math.Sqrt(pow*pow0)
+	}
+	return rotations, coherence
+}
+
+// Lags will cross-correlate each buffer with the 0th buffer, and return how
+// far apart they are, to a fraction of a sample. As with the alignment
+// offsets, a positive number means the 0th buffer is that many samples
+// behind the nth buffer. The 0th index will always be 0.
+//
+// Like FractionalDelays, this needs a wideband signal common to all the
+// buffers (such as the noise source) to mean anything.
+func Lags(planner fft.Planner, bufs []sdr.SamplesC64) ([]float64, error) {
+	ccr, err := NewCrossCorrelater(planner, len(bufs[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	pow := func(el complex64) float64 {
+		return float64(real(el)*real(el) + imag(el)*imag(el))
+	}
+
+	ret := make([]float64, len(bufs))
+	for i := 1; i < len(bufs); i++ {
+		cc, err := ccr.Correlate(bufs[0], bufs[i])
+		if err != nil {
+			return nil, err
+		}
+
+		var (
+			leng    = len(cc)
+			peak    int
+			peakPow = math.Inf(-1)
+		)
+		for ci, el := range cc {
+			if p := pow(el); p > peakPow {
+				peakPow = p
+				peak = ci
+			}
+		}
+
+		var (
+			before = pow(cc[(peak+leng-1)%leng])
+			after  = pow(cc[(peak+1)%leng])
+			denom  = before - 2*peakPow + after
+			lag    = float64(peak)
+		)
+		if denom != 0 {
+			lag += 0.5 * (before - after) / denom
+		}
+		if lag > float64(leng/2) {
+			lag -= float64(leng)
+		}
+		ret[i] = lag
+	}
+	return ret, nil
+}
+
+// FractionalDelays will compute how far apart the (already aligned) readers
+// are, to a fraction of a sample, and return how much each reader needs to
+// be delayed by to line up with the latest of them. Delays are between 0
+// and 1 sample.
+//
+// This works by fitting a parabola to the peak of the cross-correlation
+// and its two neighbours, which means it needs a wideband signal (such as
+// the RNG) to be accurate.
+func FractionalDelays(planner fft.Planner, readers []sdr.Reader) ([]float64, error) {
+	bufs := make([]sdr.SamplesC64, len(readers))
+	for i := range bufs {
+		bufs[i] = make(sdr.SamplesC64, 1024*64)
+	}
+	ccr, err := NewCrossCorrelater(planner, len(bufs[0]))
+	if err != nil {
+		return nil, err
+	}
+	if err := ReadBuffers(readers, bufs); err != nil {
+		return nil, err
+	}
+
+	var (
+		offsets = make([]float64, len(readers))
+		min     float64
+	)
+	for i := 1; i < len(bufs); i++ {
+		cc, err := ccr.Correlate(bufs[0], bufs[i])
+		if err != nil {
+			return nil, err
+		}
+
+		pow := func(el complex64) float64 {
+			return float64(real(el)*real(el) + imag(el)*imag(el))
+		}
+		var (
+			before = pow(cc[len(cc)-1])
+			peak   = pow(cc[0])
+			after  = pow(cc[1])
+			denom  = before - 2*peak + after
+		)
+		if denom != 0 {
+			offsets[i] = 0.5 * (before - after) / denom
+		}
+
+		// A positive offset means the 0th reader is behind the nth reader,
+		// so the nth reader needs to be delayed.
+		if offsets[i] < min {
+			min = offsets[i]
+		}
+	}
+
+	for i := range offsets {
+		offsets[i] -= min
+		if offsets[i] > 1 {
+			offsets[i] = 1
+		}
+	}
+	return offsets, nil
+}
+
 // AlignReaders will align multiple readers to be in sample lock.
 func AlignReaders(planner fft.Planner, readers []sdr.Reader) error {
 	lenr := len(readers)
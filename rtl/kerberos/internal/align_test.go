@@ -0,0 +1,93 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package internal_test
+
+import (
+	"math/cmplx"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/fft"
+	"hz.tools/sdr/rtl/kerberos/internal"
+)
+
+func noise(n int) sdr.SamplesC64 {
+	r := rand.New(rand.NewSource(1))
+	ret := make(sdr.SamplesC64, n)
+	for i := range ret {
+		ret[i] = complex(float32(r.NormFloat64()), float32(r.NormFloat64()))
+	}
+	return ret
+}
+
+// delay will return buf, circularly delayed by n samples.
+func delay(buf sdr.SamplesC64, n int) sdr.SamplesC64 {
+	ret := make(sdr.SamplesC64, len(buf))
+	for i := range buf {
+		ret[(i+n)%len(buf)] = buf[i]
+	}
+	return ret
+}
+
+func TestLags(t *testing.T) {
+	ref := noise(1024)
+	lags, err := internal.Lags(fft.DefaultPlanner, []sdr.SamplesC64{
+		delay(ref, 3),
+		ref,
+		delay(ref, 3),
+		delay(ref, 5),
+	})
+	assert.NoError(t, err)
+	assert.Len(t, lags, 4)
+	assert.Equal(t, 0.0, lags[0])
+	assert.InDelta(t, 3, lags[1], 0.1)
+	assert.InDelta(t, 0, lags[2], 0.1)
+	assert.InDelta(t, -2, lags[3], 0.1)
+}
+
+func TestPhaseResiduals(t *testing.T) {
+	var (
+		ref      = noise(1024)
+		rotated  = make(sdr.SamplesC64, len(ref))
+		rotation = complex64(cmplx.Rect(1, 0.5))
+	)
+	copy(rotated, ref)
+	rotated.Multiply(rotation)
+
+	rotations, coherence := internal.PhaseResiduals([]sdr.SamplesC64{
+		ref, rotated, delay(ref, 100),
+	})
+
+	assert.Equal(t, complex64(1), rotations[0])
+	assert.Equal(t, 1.0, coherence[0])
+
+	// Rotating the channel by the residual brings it back in line.
+	assert.InDelta(t, 0, cmplx.Phase(complex128(rotation*rotations[1])), 1e-4)
+	assert.InDelta(t, 1, coherence[1], 1e-4)
+
+	// Once it's out of alignment, there's nothing in common.
+	assert.Less(t, coherence[2], 0.2)
+}
+
+// vim: foldmethod=marker
@@ -151,6 +151,7 @@ func GraftReaders(planner fft.Planner, readers []sdr.Reader) (sdr.ReadCloser, er
 		pipeWriter: pipeWriter,
 	}
 	go func() {
+		defer sdr.RecoverDriverPanic(pipeWriter)
 		if err := gr.do(); err != nil {
 			log.Printf("Error grafting: %s\n", err)
 		}
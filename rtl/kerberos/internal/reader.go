@@ -44,6 +44,28 @@ func ReadBuffers(readers []sdr.Reader, bufs []sdr.SamplesC64) error {
 	return err
 }
 
+// WriteBuffers will Write each buffer to the matching writer, all at the
+// same time, so that a slow reader on the other end of one writer doesn't
+// hold up the rest until it's read everything.
+func WriteBuffers(writers []sdr.Writer, bufs []sdr.SamplesC64) error {
+	errs := make([]error, len(writers))
+	wg := sync.WaitGroup{}
+	wg.Add(len(writers))
+	for i := range writers {
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = writers[i].Write(bufs[i])
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func scaleComplex(el complex64, scale float32) complex64 {
 	return complex(
 		real(el)/scale,
@@ -21,6 +21,8 @@
 package kerberos
 
 import (
+	"fmt"
+
 	"hz.tools/rf"
 	"hz.tools/sdr"
 	"hz.tools/sdr/debug"
@@ -31,23 +33,48 @@ func init() {
 	debug.RegisterRadioDriver("hz.tools/sdr/rtl/kerberos.Sdr")
 }
 
-// Sdr is a Kerberos SDR, 4 RTL-SDR dongles in one!
-type Sdr [4]*rtl.Sdr
+var (
+	// ErrTooFewChannels will be returned if an Sdr is created with fewer
+	// than two RTL-SDR dongles, which isn't very coherent.
+	ErrTooFewChannels = fmt.Errorf("rtl/kerberos: at least two channels are required")
+)
+
+// Sdr is a Kerberos SDR, 4 RTL-SDR dongles in one! This will also work with
+// any other set of RTL-SDR dongles sharing a clock, such as the 5 in a
+// KrakenSDR. The 0th dongle is the one with the noise source (or RNG) on
+// its GPIO pins.
+type Sdr []*rtl.Sdr
 
 // New will create a new Kerberos SDR
 func New(i1, i2, i3, i4 uint, windowSize uint) (*Sdr, error) {
+	return NewN([]uint{i1, i2, i3, i4}, windowSize)
+}
+
+// NewKraken will create a new KrakenSDR, which is 5 RTL-SDR dongles
+// sharing a clock and a noise source.
+func NewKraken(i1, i2, i3, i4, i5 uint, windowSize uint) (*Sdr, error) {
+	return NewN([]uint{i1, i2, i3, i4, i5}, windowSize)
+}
+
+// NewN will create a new Sdr out of any number of RTL-SDR dongles which
+// share a clock, in the order of the provided device indexes.
+func NewN(indexes []uint, windowSize uint) (*Sdr, error) {
+	if len(indexes) < 2 {
+		return nil, ErrTooFewChannels
+	}
+
 	var (
-		err  error
-		sdr  = &Sdr{}
-		idxs = [4]uint{i1, i2, i3, i4}
+		err error
+		sdr = make(Sdr, len(indexes))
 	)
 	for i := range sdr {
-		sdr[i], err = rtl.New(idxs[i], windowSize)
+		sdr[i], err = rtl.New(indexes[i], windowSize)
 		if err != nil {
+			sdr[:i].Close()
 			return nil, err
 		}
 	}
-	return sdr, nil
+	return &sdr, nil
 }
 
 // Close implements the sdr.Sdr interface.
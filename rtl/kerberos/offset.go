@@ -41,7 +41,8 @@ type OffsetSdr struct {
 	centerFreq rf.Hz
 }
 
-// NewOffset will create a new OffsetSdr.
+// NewOffset will create a new OffsetSdr. If planner is nil,
+// fft.DefaultPlanner is used.
 func NewOffset(planner fft.Planner, i1, i2, i3, i4 uint, windowSize uint) (*OffsetSdr, error) {
 	sdr, err := NewCoherent(planner, i1, i2, i3, i4, windowSize)
 	if err != nil {
@@ -49,7 +50,7 @@ func NewOffset(planner fft.Planner, i1, i2, i3, i4 uint, windowSize uint) (*Offs
 	}
 	return &OffsetSdr{
 		CoherentSdr: sdr,
-		planner:     planner,
+		planner:     sdr.planner,
 		centerFreq:  rf.Hz(0),
 	}, nil
 }
@@ -0,0 +1,336 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package kerberos
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/calibrate"
+	"hz.tools/sdr/fft"
+	"hz.tools/sdr/rtl/kerberos/internal"
+)
+
+var (
+	// ErrTrackingSubSample will be returned if StartCoherentRx is called with
+	// both tracking and sub-sample alignment turned on, since the fractional
+	// delay is fixed when the streams start, and can't be tracked.
+	ErrTrackingSubSample = fmt.Errorf("rtl/kerberos: tracking can't be used with sub-sample alignment")
+)
+
+// TrackingOptions control how the streams from a CoherentSdr are kept in
+// alignment while they're being read. See CoherentSdr.SetTracking.
+type TrackingOptions struct {
+	// BlockLength is the number of samples read from each channel at a time,
+	// which are corrected and measured together. If 0, this will default
+	// to 65536.
+	BlockLength int
+
+	// MinCoherence is how much signal (from 0 to 1) a block has to have in
+	// common with the 0th channel for it to be used to track the phase. A
+	// block of uncorrelated noise is no use at all. If 0, this will default
+	// to 0.8.
+	MinCoherence float64
+
+	// Smoothing is how much of each block's measurement is folded into the
+	// correction, as with NewCalibration. This is only used if SetCalibration
+	// was not called. If 0, this will default to 0.1.
+	Smoothing float64
+
+	// CheckInterval is how often the noise source is turned on to check how
+	// far apart the streams have drifted. If 0, this will default to 10
+	// seconds. If negative, the alignment is never checked, and only the
+	// phase is tracked.
+	CheckInterval time.Duration
+
+	// Settle is how long to throw away samples for after the noise source is
+	// turned on or off, while the tuners react. If 0, this will default to
+	// 50 milliseconds.
+	Settle time.Duration
+
+	// ResyncThreshold is how far apart (in samples) the streams have to
+	// drift before they're aligned again. If 0, this will default to 0.5.
+	ResyncThreshold float64
+}
+
+func (opts TrackingOptions) getBlockLength() int {
+	if opts.BlockLength == 0 {
+		return 1024 * 64
+	}
+	return opts.BlockLength
+}
+
+func (opts TrackingOptions) getMinCoherence() float64 {
+	if opts.MinCoherence == 0 {
+		return 0.8
+	}
+	return opts.MinCoherence
+}
+
+func (opts TrackingOptions) getSmoothing() float64 {
+	if opts.Smoothing == 0 {
+		return 0.1
+	}
+	return opts.Smoothing
+}
+
+func (opts TrackingOptions) getCheckInterval() time.Duration {
+	if opts.CheckInterval == 0 {
+		return time.Second * 10
+	}
+	return opts.CheckInterval
+}
+
+func (opts TrackingOptions) getSettle() time.Duration {
+	if opts.Settle == 0 {
+		return time.Millisecond * 50
+	}
+	return opts.Settle
+}
+
+func (opts TrackingOptions) getResyncThreshold() float64 {
+	if opts.ResyncThreshold == 0 {
+		return 0.5
+	}
+	return opts.ResyncThreshold
+}
+
+// SetTracking will turn on (or, if nil, off) tracking for the next
+// StartCoherentRx. Rather than aligning the streams once when they start,
+// the streams will be read in lock-step, and:
+//
+//   - The phase of each block is compared to the 0th channel, and any drift
+//     is folded into the Calibration (one is created if SetCalibration was
+//     not called).
+//
+//   - Every so often, the noise source is turned on, and the streams are
+//     checked to see if they're still in sample lock. If they've drifted
+//     past the threshold, they're aligned again, and the Calibration is
+//     reset. The samples read while the noise source is on are dropped, so
+//     there will be a gap in the streams (on every channel at once).
+//
+// When tracking is on, the streams returned by StartCoherentRx will be in
+// SampleFormatC64, and all of them need to be read, since one channel will
+// not be read any further ahead than the others.
+func (c *CoherentSdr) SetTracking(opts *TrackingOptions) {
+	c.tracking = opts
+}
+
+type tracker struct {
+	planner fft.Planner
+	opts    TrackingOptions
+	noise   calibrate.Switch
+	cal     *Calibration
+
+	readers []sdr.Reader
+	writers []sdr.Writer
+	bufs    []sdr.SamplesC64
+}
+
+// discard will read and throw away d worth of samples from every channel.
+func (t *tracker) discard(d time.Duration) error {
+	n := int(d.Seconds() * float64(t.readers[0].SampleRate()))
+	bufs := make([]sdr.SamplesC64, len(t.bufs))
+	for n > 0 {
+		l := len(t.bufs[0])
+		if n < l {
+			l = n
+		}
+		for i := range bufs {
+			bufs[i] = t.bufs[i][:l]
+		}
+		if err := internal.ReadBuffers(t.readers, bufs); err != nil {
+			return err
+		}
+		n -= l
+	}
+	return nil
+}
+
+// resync will align the streams to the nearest sample, and measure the
+// Calibration from scratch. This needs the noise source to be on.
+func (t *tracker) resync() error {
+	if err := internal.AlignReaders(t.planner, t.readers); err != nil {
+		return err
+	}
+	corrections, err := internal.ChannelCorrections(t.readers)
+	if err != nil {
+		return err
+	}
+	return t.cal.Set(corrections)
+}
+
+// correct will apply the Calibration to each of the buffers.
+func (t *tracker) correct() {
+	for i := range t.bufs {
+		t.bufs[i].Multiply(t.cal.correction(i))
+	}
+}
+
+// check will turn on the noise source, and check how far apart the streams
+// have drifted. If it's past the threshold they're aligned again, otherwise
+// the Calibration is updated from the noise source.
+func (t *tracker) check() error {
+	err := calibrate.With(t.noise, func() error {
+		if err := t.discard(t.opts.getSettle()); err != nil {
+			return err
+		}
+		if err := internal.ReadBuffers(t.readers, t.bufs); err != nil {
+			return err
+		}
+
+		lags, err := internal.Lags(t.planner, t.bufs)
+		if err != nil {
+			return err
+		}
+		for _, lag := range lags {
+			if math.Abs(lag) > t.opts.getResyncThreshold() {
+				return t.resync()
+			}
+		}
+
+		t.correct()
+		return t.cal.Update(internal.BufferCorrections(t.bufs))
+	})
+	if err != nil {
+		return err
+	}
+	return t.discard(t.opts.getSettle())
+}
+
+// track will fold any phase drift in the (corrected) buffers into the
+// Calibration, for the channels which have enough signal in common with
+// the 0th channel.
+func (t *tracker) track() error {
+	rotations, coherence := internal.PhaseResiduals(t.bufs)
+	for i := range rotations {
+		if coherence[i] < t.opts.getMinCoherence() {
+			rotations[i] = 1
+		}
+	}
+	return t.cal.Update(rotations)
+}
+
+func (t *tracker) run() error {
+	var (
+		interval  = t.opts.getCheckInterval()
+		lastCheck = time.Now()
+	)
+	for {
+		if interval > 0 && time.Since(lastCheck) >= interval {
+			if err := t.check(); err != nil {
+				return err
+			}
+			lastCheck = time.Now()
+		}
+
+		if err := internal.ReadBuffers(t.readers, t.bufs); err != nil {
+			return err
+		}
+		t.correct()
+		if err := t.track(); err != nil {
+			return err
+		}
+		if err := internal.WriteBuffers(t.writers, t.bufs); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *CoherentSdr) startTrackedRx(opts TrackingOptions) (sdr.ReadClosers, error) {
+	if c.subSample {
+		return nil, ErrTrackingSubSample
+	}
+
+	var (
+		err error
+		cal = c.calibration
+	)
+	if cal == nil {
+		cal, err = NewCalibration(len(c.Sdr), opts.getSmoothing())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rcs, err := c.startRx()
+	if err != nil {
+		return nil, err
+	}
+	readers, err := rcs.ReadersC64()
+	if err != nil {
+		c.noise.Set(false)
+		rcs.Close()
+		return nil, err
+	}
+
+	t := &tracker{
+		planner: c.planner,
+		opts:    opts,
+		noise:   c.noise,
+		cal:     cal,
+		readers: readers,
+		writers: make([]sdr.Writer, len(readers)),
+		bufs:    make([]sdr.SamplesC64, len(readers)),
+	}
+	for i := range t.bufs {
+		t.bufs[i] = make(sdr.SamplesC64, opts.getBlockLength())
+	}
+
+	// The noise source was turned on by startRx.
+	if err := t.resync(); err != nil {
+		c.noise.Set(false)
+		rcs.Close()
+		return nil, err
+	}
+	if err := c.noise.Set(false); err != nil {
+		rcs.Close()
+		return nil, err
+	}
+	if err := t.discard(opts.getSettle()); err != nil {
+		rcs.Close()
+		return nil, err
+	}
+
+	var (
+		sps = readers[0].SampleRate()
+		ret = make(sdr.ReadClosers, len(readers))
+		pws = make([]sdr.PipeWriter, len(readers))
+	)
+	for i := range ret {
+		ret[i], pws[i] = sdr.Pipe(sps, sdr.SampleFormatC64)
+		t.writers[i] = pws[i]
+	}
+
+	go func() {
+		defer rcs.Close()
+		err := t.run()
+		for _, pw := range pws {
+			pw.CloseWithError(err)
+		}
+	}()
+
+	return ret, nil
+}
+
+// vim: foldmethod=marker
@@ -40,6 +40,10 @@ import (
 
 func init() {
 	debug.RegisterRadioDriver("hz.tools/sdr/rtl.Sdr")
+	sdr.RegisterSerialOpener("rtl", func(serial string) (sdr.Sdr, error) {
+		return OpenBySerial(serial, 0)
+	})
+	sdr.RegisterEnumerator("rtl", sdr.ListEnumerator(List))
 }
 
 // DeviceCount will return the number of rtlsdr devices present on the
@@ -48,6 +52,20 @@ func DeviceCount() uint {
 	return uint(C.rtlsdr_get_device_count())
 }
 
+// List will return the sdr.HardwareInfo for all rtlsdr devices that are
+// plugged into this system.
+func List() ([]sdr.HardwareInfo, error) {
+	ret := []sdr.HardwareInfo{}
+	for i := uint(0); i < DeviceCount(); i++ {
+		info, err := InfoByDeviceIndex(i)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, *info)
+	}
+	return ret, nil
+}
+
 // DeviceIndexBySerial will get the device index that has the Serial provided.
 func DeviceIndexBySerial(serial string) (uint, error) {
 	index := C.rtlsdr_get_index_by_serial(C.CString(serial))
@@ -68,6 +86,46 @@ func DeviceIndexBySerial(serial string) (uint, error) {
 	return uint(index), nil
 }
 
+// Options contains arguments used to tune how the rtlsdr moves data over
+// USB. These are the first things to look at when chasing dropped samples.
+type Options struct {
+	// WindowSize is the size, in bytes, of each USB transfer, and therefore
+	// how many bytes of iq samples are delivered per callback. It must be a
+	// multiple of 512. If set to 0, this will default to 256 KiB.
+	WindowSize uint
+
+	// BufferCount is the number of USB transfers librtlsdr will keep in
+	// flight. If set to 0, this will use the librtlsdr default of 15.
+	BufferCount uint
+
+	// RingSlots is the number of windows of samples buffered between
+	// librtlsdr and the reader. If the reader falls further behind than
+	// this, the rx stream is closed with a stream.ErrRingBufferOverrun,
+	// rather than samples being silently dropped. If set to 0, this will
+	// default to 32.
+	RingSlots uint
+
+	// SampleFormats is an ordered list of preferred SampleFormats, most
+	// preferred first. The rtl-sdr only streams sdr.SampleFormatU8, so if
+	// this is set and doesn't contain it, opening the device will fail with
+	// sdr.ErrNoSupportedSampleFormat.
+	SampleFormats []sdr.SampleFormat
+}
+
+func (opts Options) getRingSlots() uint {
+	if opts.RingSlots == 0 {
+		return 32
+	}
+	return opts.RingSlots
+}
+
+func (opts Options) getWindowSize() uint {
+	if opts.WindowSize == 0 {
+		return 16 * 32 * 512
+	}
+	return opts.WindowSize
+}
+
 // New will create a new Sdr struct, and initialize the internal
 // handles as required.
 //
@@ -79,13 +137,23 @@ func DeviceIndexBySerial(serial string) (uint, error) {
 //
 //	per callback.
 func New(index uint, windowSize uint) (*Sdr, error) {
-	if windowSize == 0 {
-		windowSize = 16 * 32 * 512
+	return NewWithOptions(index, Options{WindowSize: windowSize})
+}
+
+// NewWithOptions will create a new Sdr struct, much like New, using the
+// provided Options.
+func NewWithOptions(index uint, opts Options) (*Sdr, error) {
+	if _, err := sdr.NegotiateSampleFormat(
+		opts.SampleFormats,
+		[]sdr.SampleFormat{sdr.SampleFormatU8},
+	); err != nil {
+		return nil, err
 	}
 
 	ret := Sdr{
-		windowSize: windowSize,
-		ifStages:   &e4k.Stages{},
+		opts:      opts,
+		ifStages:  &e4k.Stages{},
+		bandwidth: new(rf.Hz),
 	}
 	if err := rvToErr(C.rtlsdr_open(&ret.handle, C.uint(index))); err != nil {
 		return nil, err
@@ -99,13 +167,25 @@ func New(index uint, windowSize uint) (*Sdr, error) {
 	return &ret, nil
 }
 
+// OpenBySerial will open the rtlsdr with the provided Serial, rather than
+// by index, which may change as devices are plugged and unplugged. The
+// windowSize is the same as New.
+func OpenBySerial(serial string, windowSize uint) (*Sdr, error) {
+	index, err := DeviceIndexBySerial(serial)
+	if err != nil {
+		return nil, err
+	}
+	return New(index, windowSize)
+}
+
 // Sdr is a handle to internal rtlsdr state used by the underlying C
 // library.
 type Sdr struct {
-	handle     *C.rtlsdr_dev_t
-	windowSize uint
+	handle *C.rtlsdr_dev_t
+	opts   Options
 
 	ifStages     *e4k.Stages
+	bandwidth    *rf.Hz
 	hardwareInfo sdr.HardwareInfo
 }
 
@@ -219,10 +299,20 @@ func (r Sdr) GetSampleRate() (uint, error) {
 	return uint(C.rtlsdr_get_sample_rate(r.handle)), nil
 }
 
+// SampleRateRanges implements the sdr.SampleRateRanger interface. These
+// are the rates librtlsdr will accept, although rates above 2.4 Msps are
+// known to drop samples on a lot of hardware.
+func (r Sdr) SampleRateRanges() ([]sdr.SampleRateRange, error) {
+	return []sdr.SampleRateRange{
+		{Min: 225001, Max: 300000},
+		{Min: 900001, Max: 3200000},
+	}, nil
+}
+
 // GetSamplesPerWindow will return the number of samples contained in one
 // windows-worth of iq data.
 func (r Sdr) GetSamplesPerWindow() (uint, error) {
-	return r.windowSize / 2, nil
+	return r.opts.getWindowSize() / 2, nil
 }
 
 // ResetBuffer will reset the internal rtlsdr buffer(s).
@@ -245,7 +335,8 @@ func (r Sdr) GetPPM() int {
 // Test mode will cause every byte to be the one larger than the next byte,
 // and on overflow, return to 0. This is useful to detect cases where you're
 // dropping packets, or to ensure that your code can fully process the data
-// end-to-end in real-time.
+// end-to-end in real-time. See integrity.NewCounterVerifier, which will
+// check the counter as it's read.
 func (r Sdr) SetTestMode(on bool) error {
 	if on {
 		return rvToErr(C.rtlsdr_set_testmode(r.handle, 1))
@@ -262,6 +353,71 @@ func (r Sdr) SetBiasT(on bool) error {
 	return rvToErr(C.rtlsdr_set_bias_tee(r.handle, 0))
 }
 
+// SetOffsetTuning will enable or disable offset tuning. This is only useful
+// on the E4000 tuner, which has a zero-IF architecture, and will otherwise
+// have a DC spike in the middle of the band. The R820T and R828D tuners
+// already use a low IF, and librtlsdr will return an error if this is
+// called on them.
+func (r Sdr) SetOffsetTuning(on bool) error {
+	if on {
+		return rvToErr(C.rtlsdr_set_offset_tuning(r.handle, 1))
+	}
+	return rvToErr(C.rtlsdr_set_offset_tuning(r.handle, 0))
+}
+
+// GetOffsetTuning will return true if offset tuning is enabled.
+func (r Sdr) GetOffsetTuning() (bool, error) {
+	rv := C.rtlsdr_get_offset_tuning(r.handle)
+	if rv < 0 {
+		return false, rvToErr(rv)
+	}
+	return rv == 1, nil
+}
+
+// hasIFBandwidth will return true if the tuner has an adjustable IF filter
+// that librtlsdr knows how to control.
+func (r Sdr) hasIFBandwidth() bool {
+	switch r.Tuner() {
+	case TunerR820T, TunerR828D:
+		return true
+	default:
+		return false
+	}
+}
+
+// SetBandwidth implements the sdr.BandwidthSetter interface, setting the IF
+// filter bandwidth of the R820T or R828D tuner. Other tuners will return
+// sdr.ErrNotSupported. A bandwidth of 0 will have librtlsdr pick the
+// bandwidth based on the sample rate, which is the default.
+//
+// Unlike some drivers, the bandwidth is kept when the sample rate is
+// changed.
+func (r Sdr) SetBandwidth(bw rf.Hz) error {
+	if !r.hasIFBandwidth() {
+		return sdr.ErrNotSupported
+	}
+	if err := rvToErr(C.rtlsdr_set_tuner_bandwidth(r.handle, C.uint32_t(bw))); err != nil {
+		return err
+	}
+	*r.bandwidth = bw
+	return nil
+}
+
+// GetBandwidth implements the sdr.BandwidthSetter interface. librtlsdr
+// can't read the bandwidth back, so this returns the last value passed to
+// SetBandwidth, or the sample rate if the bandwidth is being picked
+// automatically.
+func (r Sdr) GetBandwidth() (rf.Hz, error) {
+	if !r.hasIFBandwidth() {
+		return 0, sdr.ErrNotSupported
+	}
+	if *r.bandwidth == 0 {
+		sps, err := r.GetSampleRate()
+		return rf.Hz(sps), err
+	}
+	return *r.bandwidth, nil
+}
+
 // Tuner will return the rtlsdr Tuner type. This can be used to determine
 // the behavior of some of the Gain options, as well as well as performance.
 func (r Sdr) Tuner() Tuner {
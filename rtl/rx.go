@@ -31,44 +31,60 @@ package rtl
 import "C"
 
 import (
+	"context"
 	"log"
+	"time"
 	"unsafe"
 
 	"github.com/mattn/go-pointer"
 
 	"hz.tools/sdr"
+	"hz.tools/sdr/stream"
+	"hz.tools/sdr/yikes"
 )
 
 type callbackContext struct {
-	pipeReader sdr.PipeReader
-	pipeWriter sdr.PipeWriter
-	pool       *sdr.SamplesPool
+	handle *C.rtlsdr_dev_t
+	ring   *stream.RingBuffer
+	clock  *sdr.HostClock
 }
 
 //export rtlsdrRxCallback
 func rtlsdrRxCallback(cBuf *C.char, cBufLen C.uint32_t, ptr unsafe.Pointer) {
+	now := time.Now()
 	context := pointer.Restore(ptr).(*callbackContext)
 
-	buf := C.GoBytes(unsafe.Pointer(cBuf), C.int(cBufLen))
-	samples := context.pool.Get().(sdr.SamplesU8)
-	defer context.pool.Put(samples)
-
-	n := copy(sdr.MustUnsafeSamplesAsBytes(samples), buf)
-	// n here is in bytes; but since we have two bytes for each sample,
-	// we need to cut it in half to deal with the number of samples
-	// actually written to the buffer.
-	samples = samples[:n/2]
-
-	_, err := context.pipeWriter.Write(samples)
+	// A panic here would unwind through librtlsdr and take down the whole
+	// process, so we'll close the ring instead, which will cause the reader
+	// to get an ErrDriverPanic.
+	defer sdr.RecoverDriverPanic(context.ring)
+
+	// cBufLen here is in bytes; but since we have two bytes for each
+	// sample, we need to cut it in half to get the number of samples.
+	samples, err := yikes.Samples(
+		uintptr(unsafe.Pointer(cBuf)),
+		int(cBufLen)/2,
+		sdr.SampleFormatU8,
+	)
 	if err != nil {
-		// TODO(paultag): Set an error condition and crater the rx path
-		// on this one I think.
-		log.Println(err)
+		context.ring.CloseWithError(err)
+		C.rtlsdr_cancel_async(context.handle)
+		return
 	}
+
+	if _, err := context.ring.Write(samples); err != nil {
+		// Either the reader has closed the ring, or it's fallen so far
+		// behind we've overrun it, which the reader will find out about
+		// once it has read what's left. Either way, we're done here, and
+		// these samples will never be read, so they're not Marked.
+		C.rtlsdr_cancel_async(context.handle)
+		return
+	}
+	context.clock.Mark(now, samples.Length())
 }
 
 type rx struct {
-	sdr.ReadCloser
+	sdr.TimedReadCloser
 	rtlSdr Sdr
 }
 
@@ -76,54 +92,95 @@ func (rx rx) Close() error {
 	if err := rvToErr(C.rtlsdr_cancel_async(rx.rtlSdr.handle)); err != nil {
 		log.Printf("Error stopping rx: %s", err)
 	}
-	return rx.ReadCloser.Close()
+	return rx.TimedReadCloser.Close()
 }
 
 // StartRx will start to receive IQ samples, ready for consumption from the
 // returned ReadCloser.
+//
+// Samples are buffered in a stream.RingBuffer of Options.RingSlots windows.
+// If the reader falls further behind than that, the stream is closed, and
+// reads will return a stream.ErrRingBufferOverrun once the buffered samples
+// have been read.
+//
+// The rtl-sdr has no hardware clock to timestamp samples with, so the
+// returned ReadCloser is an sdr.TimedReader, which estimates the time each
+// sample was received using the host's clock when each USB transfer
+// arrives. See sdr.HostClock for how accurate that is.
 func (r Sdr) StartRx() (sdr.ReadCloser, error) {
+	return r.StartRxWithContext(context.Background())
+}
+
+// StartRxWithContext implements the sdr.ContextReceiver interface.
+//
+// This is the same as StartRx, but once the context is done, librtlsdr's
+// async read is cancelled, and reads will return the context's error once
+// the buffered samples have been read.
+func (r Sdr) StartRxWithContext(ctx context.Context) (sdr.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	sps, err := r.GetSampleRate()
 	if err != nil {
 		return nil, err
 	}
 
-	pipeReader, pipeWriter := sdr.Pipe(sps, sdr.SampleFormatU8)
-
 	if err := r.ResetBuffer(); err != nil {
 		return nil, err
 	}
 
-	var windowSize uint = 16 * 32 * 512
-	if r.windowSize != 0 {
-		windowSize = r.windowSize
+	windowSize := r.opts.getWindowSize()
+
+	ring, err := stream.NewRingBuffer(sps, sdr.SampleFormatU8, stream.RingBufferOptions{
+		Slots:          int(r.opts.getRingSlots()),
+		SlotLength:     int(windowSize / 2),
+		BlockReads:     true,
+		CloseOnOverrun: true,
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	pool, err := sdr.NewSamplesPool(sdr.SampleFormatU8, int(windowSize/2))
+	reader, err := stream.RingBufferReader(ring)
 	if err != nil {
 		return nil, err
 	}
 
 	cc := &callbackContext{
-		pipeReader: pipeReader,
-		pipeWriter: pipeWriter,
-		pool:       pool,
+		handle: r.handle,
+		ring:   ring,
+		clock:  sdr.NewHostClock(sps),
 	}
 
 	state := pointer.Save(cc)
+	done := make(chan struct{})
 
 	go func(r Sdr, state unsafe.Pointer) {
+		defer close(done)
 		defer pointer.Unref(state)
 		err := rvToErr(C.rtlsdr_read_async(
 			r.handle,
 			C.rtlsdr_read_async_cb_t(C.rtlsdr_rx_callback),
-			state, 0, C.uint32_t(windowSize),
+			state, C.uint32_t(r.opts.BufferCount), C.uint32_t(windowSize),
 		))
-		pipeReader.CloseWithError(err)
+		ring.CloseWithError(err)
 	}(r, state)
 
+	go func() {
+		select {
+		case <-ctx.Done():
+			// Close the ring first, since the async read returning after
+			// being cancelled would otherwise close it without an error.
+			ring.CloseWithError(ctx.Err())
+			C.rtlsdr_cancel_async(r.handle)
+		case <-done:
+		}
+	}()
+
 	return rx{
-		ReadCloser: pipeReader,
-		rtlSdr:     r,
+		TimedReadCloser: sdr.HostTimedReader(reader, cc.clock),
+		rtlSdr:          r,
 	}, nil
 }
 
@@ -0,0 +1,179 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package rtltcp
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"log"
+	"net"
+	"sync"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/rtl"
+	"hz.tools/sdr/stream"
+)
+
+// channelConn tracks the stream.Channel serving a client of a Server with
+// a Channelizer. The Channel is replaced when the client changes the
+// sample rate.
+type channelConn struct {
+	lock    sync.Mutex
+	c       *stream.Channelizer
+	center  rf.Hz
+	channel *stream.Channel
+}
+
+func (cc *channelConn) current() *stream.Channel {
+	cc.lock.Lock()
+	defer cc.lock.Unlock()
+	return cc.channel
+}
+
+func (cc *channelConn) setFrequency(freq rf.Hz) error {
+	cc.lock.Lock()
+	defer cc.lock.Unlock()
+	return cc.channel.SetOffset(freq - cc.center)
+}
+
+func (cc *channelConn) setSampleRate(sampleRate uint) error {
+	cc.lock.Lock()
+	defer cc.lock.Unlock()
+
+	if sampleRate == cc.channel.SampleRate() {
+		return nil
+	}
+
+	channel, err := cc.c.Channel(cc.channel.Offset(), sampleRate)
+	if err != nil {
+		return err
+	}
+	old := cc.channel
+	cc.channel = channel
+	return old.Close()
+}
+
+func (cc *channelConn) handle(request Request) error {
+	arg := request.Argument
+	switch request.Command {
+	case CommandSetFreq:
+		log.Printf("Setting channel freq to %s\n", rf.Hz(arg))
+		return cc.setFrequency(rf.Hz(arg))
+	case CommandSetSampleRate:
+		log.Printf("Setting channel SampleRate to %d\n", arg)
+		return cc.setSampleRate(uint(arg))
+	default:
+		// Everything else would change the shared receiver.
+		log.Printf("Ignoring command on shared receiver: %s\n", request.Command)
+	}
+	return nil
+}
+
+func (s Server) serveChannel(ctx context.Context, cancel context.CancelFunc, conn net.Conn) error {
+	sampleRate := s.ChannelSampleRate
+	if sampleRate == 0 {
+		sampleRate = s.Channelizer.SampleRate()
+	}
+
+	channel, err := s.Channelizer.Channel(0, sampleRate)
+	if err != nil {
+		log.Printf("Error creating channel - closing connection")
+		log.Println(err)
+		return err
+	}
+
+	cc := &channelConn{
+		c:       s.Channelizer,
+		center:  s.CenterFrequency,
+		channel: channel,
+	}
+	defer func() { cc.current().Close() }()
+
+	if err := binary.Write(conn, binary.BigEndian, &DongleInfo{
+		Magic:     [4]byte{'R', 'T', 'L', '0'},
+		TunerType: uint32(rtl.TunerR820T),
+	}); err != nil {
+		log.Printf("Error writing DongleInfo\n")
+		log.Println(err)
+		return err
+	}
+
+	go func() {
+		defer cancel()
+		req := Request{}
+		for {
+			if ctx.Err() != nil {
+				return
+			}
+			if err := binary.Read(conn, binary.BigEndian, &req); err != nil {
+				log.Printf("Error reading command; discarding\n")
+				log.Println(err)
+				if err == io.EOF {
+					return
+				}
+				continue
+			}
+			if err := cc.handle(req); err != nil {
+				log.Printf("Error processing command; discarding\n")
+				log.Printf("%#v\n", err)
+				continue
+			}
+		}
+	}()
+
+	var (
+		writer = sdr.ByteWriter(conn, binary.LittleEndian, 0, sdr.SampleFormatU8)
+		buf    = make(sdr.SamplesC64, 16*1024)
+		u8     = make(sdr.SamplesU8, len(buf))
+	)
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		channel := cc.current()
+		n, err := channel.Read(buf)
+		if n > 0 {
+			if _, err := sdr.ConvertBuffer(u8[:n], buf[:n]); err != nil {
+				return err
+			}
+			if _, err := writer.Write(u8[:n]); err != nil {
+				log.Printf("Error copying samples\n")
+				log.Println(err)
+				return err
+			}
+		}
+		if err != nil {
+			if cc.current() != channel {
+				// The channel was swapped out from under us by a sample
+				// rate change; keep going with the new one.
+				continue
+			}
+			log.Printf("Error reading channel\n")
+			log.Println(err)
+			return err
+		}
+	}
+}
+
+// vim: foldmethod=marker
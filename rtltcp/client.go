@@ -220,4 +220,13 @@ func (c *Client) SetTestMode(yn bool) error {
 	})
 }
 
+// SetOffsetTuning will ask the server to enable or disable offset tuning,
+// which is only useful if the server's device has an E4000 tuner.
+func (c *Client) SetOffsetTuning(yn bool) error {
+	return c.SendCommand(Request{
+		Command:  CommandSetOffsetTuning,
+		Argument: bool2uint32(yn),
+	})
+}
+
 // vim: foldmethod=marker
@@ -0,0 +1,333 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package rtltcp
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/rtl"
+	"hz.tools/sdr/stream"
+)
+
+var (
+	// ErrNotOwner will be returned when a client of a Fanout sends a command
+	// it isn't allowed to, either because another client owns the receiver,
+	// or because the Fanout is read-only.
+	ErrNotOwner = fmt.Errorf("rtltcp: client does not own the shared receiver")
+
+	// ErrUnknownFanoutPolicy will be returned by NewFanout if the Policy is
+	// not one of the FanoutPolicy constants.
+	ErrUnknownFanoutPolicy = fmt.Errorf("rtltcp: unknown fanout policy")
+)
+
+// FanoutPolicy controls which clients of a Fanout are able to send
+// commands (such as tuning or changing the gain) to the shared receiver.
+type FanoutPolicy int
+
+const (
+	// FanoutFirstWriterWins will allow the first client to send a command
+	// to own the receiver until it disconnects. Commands from every other
+	// client are ignored until then.
+	FanoutFirstWriterWins FanoutPolicy = iota
+
+	// FanoutReadOnly will ignore commands from every client; the receiver
+	// must be configured before being passed to NewFanout.
+	FanoutReadOnly
+)
+
+// FanoutOptions controls how a Fanout buffers samples for each client.
+type FanoutOptions struct {
+	// Policy controls which clients may send commands to the receiver.
+	Policy FanoutPolicy
+
+	// Slots is the number of buffers kept for each client. A client that
+	// falls more than this many buffers behind will have new buffers
+	// dropped until it catches up (see stream.TeeDrop), rather than slowing
+	// down every other client. If 0, this will default to 32.
+	Slots int
+
+	// SlotLength is the number of samples in each buffer. If 0, this will
+	// default to 16384.
+	SlotLength int
+}
+
+func (o FanoutOptions) getSlots() int {
+	if o.Slots == 0 {
+		return 32
+	}
+	return o.Slots
+}
+
+func (o FanoutOptions) getSlotLength() int {
+	if o.SlotLength == 0 {
+		return 16 * 1024
+	}
+	return o.SlotLength
+}
+
+// Fanout will share one sdr.Receiver between every client of a Server, so
+// that one device can feed a number of decoders over the network. The
+// receiver is started when the first client connects and stopped once the
+// last client disconnects.
+//
+// Each client reads from its own branch of a stream.Splitter, so one slow
+// client will drop samples rather than holding up the rest.
+type Fanout struct {
+	dev  sdr.Receiver
+	opts FanoutOptions
+
+	// startLock is held while starting or stopping the receiver, so that
+	// a new stream is never started while the last one is being Closed.
+	startLock sync.Mutex
+
+	lock     sync.Mutex
+	clients  map[*fanoutClient]struct{}
+	owner    *fanoutClient
+	reader   sdr.ReadCloser
+	splitter *stream.Splitter
+
+	// done is closed once the Splitter for the last stream has stopped,
+	// and that stream has been Closed.
+	done chan struct{}
+}
+
+type fanoutClient struct {
+	reader *stream.TeeReader
+}
+
+// NewFanout will create a new Fanout sharing the provided Receiver.
+func NewFanout(dev sdr.Receiver, opts FanoutOptions) (*Fanout, error) {
+	switch opts.Policy {
+	case FanoutFirstWriterWins, FanoutReadOnly:
+	default:
+		return nil, ErrUnknownFanoutPolicy
+	}
+	return &Fanout{
+		dev:     dev,
+		opts:    opts,
+		clients: map[*fanoutClient]struct{}{},
+	}, nil
+}
+
+// Clients will return the number of connected clients.
+func (f *Fanout) Clients() int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return len(f.clients)
+}
+
+// subscribe will add a new client, starting the receiver if this is the
+// first one.
+func (f *Fanout) subscribe() (*fanoutClient, error) {
+	f.startLock.Lock()
+	defer f.startLock.Unlock()
+
+	f.lock.Lock()
+	if f.reader == nil && f.done != nil {
+		// The last stream may still be being torn down; wait for it to stop
+		// before starting a new one, since most drivers can't run two
+		// streams at once.
+		done := f.done
+		f.lock.Unlock()
+		<-done
+		f.lock.Lock()
+	}
+	defer f.lock.Unlock()
+
+	if f.reader == nil {
+		reader, err := f.dev.StartRx()
+		if err != nil {
+			return nil, err
+		}
+		u8Reader, err := stream.ConvertReader(reader, sdr.SampleFormatU8)
+		if err != nil {
+			reader.Close()
+			return nil, err
+		}
+		f.reader = reader
+		f.splitter = stream.NewSplitter(u8Reader, f.opts.getSlotLength())
+		f.done = make(chan struct{})
+		go f.watch(reader, f.splitter, f.done)
+	}
+
+	branch, err := f.splitter.Branch(stream.TeeBranch{
+		Policy:  stream.TeeDrop,
+		Buffers: f.opts.getSlots(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	client := &fanoutClient{reader: branch}
+	f.clients[client] = struct{}{}
+	return client, nil
+}
+
+// unsubscribe will remove the client, releasing ownership of the receiver
+// if it held it, and stopping the receiver if it was the last one.
+func (f *Fanout) unsubscribe(client *fanoutClient) {
+	var (
+		reader   sdr.ReadCloser
+		splitter *stream.Splitter
+	)
+
+	f.startLock.Lock()
+	defer f.startLock.Unlock()
+
+	f.lock.Lock()
+	client.reader.Close()
+	delete(f.clients, client)
+	if f.owner == client {
+		f.owner = nil
+	}
+	if len(f.clients) == 0 {
+		reader, splitter = f.reader, f.splitter
+		f.reader, f.splitter = nil, nil
+	}
+	f.lock.Unlock()
+
+	// The receiver is closed without the lock held, since watch may be
+	// waiting on the lock. subscribe won't start it again until this has
+	// returned, and the Splitter has stopped too.
+	if reader != nil {
+		splitter.Close()
+		reader.Close()
+	}
+}
+
+// watch will wait for the Splitter reading from the receiver to stop, and
+// if that wasn't because every client left, log why and reset the Fanout
+// so the next client will start the receiver again.
+func (f *Fanout) watch(reader sdr.ReadCloser, splitter *stream.Splitter, done chan struct{}) {
+	defer close(done)
+	<-splitter.Done()
+
+	f.lock.Lock()
+	if f.reader != reader {
+		// The receiver was stopped on purpose, because every client left.
+		f.lock.Unlock()
+		return
+	}
+	log.Printf("Error reading from shared receiver\n")
+	log.Println(splitter.Err())
+	f.reader, f.splitter = nil, nil
+	f.lock.Unlock()
+
+	reader.Close()
+}
+
+// authorize will check that the client is allowed to send commands to the
+// receiver, taking ownership of it if nobody else has.
+func (f *Fanout) authorize(client *fanoutClient) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	switch f.opts.Policy {
+	case FanoutFirstWriterWins:
+		if f.owner == nil {
+			f.owner = client
+		}
+		if f.owner == client {
+			return nil
+		}
+	}
+	return ErrNotOwner
+}
+
+func (s Server) serveFanout(ctx context.Context, cancel context.CancelFunc, conn net.Conn) error {
+	client, err := s.Fanout.subscribe()
+	if err != nil {
+		log.Printf("Error subscribing to shared receiver - closing connection")
+		log.Println(err)
+		return err
+	}
+	defer s.Fanout.unsubscribe(client)
+
+	dev := s.Fanout.dev
+	tuner := rtl.TunerE4000
+	if tunerable, ok := dev.(Tunerable); ok {
+		tuner = tunerable.Tuner()
+	}
+
+	if err := binary.Write(conn, binary.BigEndian, &DongleInfo{
+		Magic:     [4]byte{'R', 'T', 'L', '0'},
+		TunerType: uint32(tuner),
+	}); err != nil {
+		log.Printf("Error writing DongleInfo\n")
+		log.Println(err)
+		return err
+	}
+
+	handler := s.CommandHandler
+	if handler == nil {
+		handler = NewDefaultCommandHandler(
+			s.GainStageName,
+			s.IFGainStageName,
+		)
+	}
+
+	go func() {
+		defer cancel()
+		defer client.reader.Close()
+		req := Request{}
+		for {
+			if ctx.Err() != nil {
+				return
+			}
+			if err := binary.Read(conn, binary.BigEndian, &req); err != nil {
+				log.Printf("Error reading command; discarding\n")
+				log.Println(err)
+				if err == io.EOF {
+					return
+				}
+				continue
+			}
+			if err := s.Fanout.authorize(client); err != nil {
+				log.Printf("Ignoring command on shared receiver: %s\n", req.Command)
+				continue
+			}
+			if err := handler(ctx, dev, req); err != nil {
+				log.Printf("Error processing command; discarding\n")
+				log.Printf("%#v\n", err)
+				continue
+			}
+		}
+	}()
+
+	// Copy a whole slot at a time, rather than sdr.Copy's default buffer.
+	writer := sdr.ByteWriter(conn, binary.LittleEndian, 0, sdr.SampleFormatU8)
+	buf := make(sdr.SamplesU8, s.Fanout.opts.getSlotLength())
+	if _, err := sdr.CopyBuffer(writer, client.reader, buf); err != nil {
+		log.Printf("Error copying samples\n")
+		log.Println(err)
+		return err
+	}
+	return nil
+}
+
+// vim: foldmethod=marker
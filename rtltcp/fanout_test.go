@@ -0,0 +1,182 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package rtltcp_test
+
+import (
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/mock"
+	"hz.tools/sdr/rtltcp"
+	"hz.tools/sdr/siggen"
+	"hz.tools/sdr/stream"
+)
+
+// fanoutSource counts the rx streams the Fanout has running at once, since
+// a real driver can only run one.
+type fanoutSource struct {
+	active  int32
+	overlap int32
+	starts  int32
+}
+
+type fanoutStream struct {
+	sdr.Reader
+	src    *fanoutSource
+	closed int32
+}
+
+func (fs *fanoutStream) Read(s sdr.Samples) (int, error) {
+	if atomic.LoadInt32(&fs.closed) == 1 {
+		return 0, io.EOF
+	}
+	return fs.Reader.Read(s)
+}
+
+func (fs *fanoutStream) Close() error {
+	if atomic.CompareAndSwapInt32(&fs.closed, 0, 1) {
+		// Tearing down a driver's stream takes a while; a new stream
+		// started before this is done would overlap with it.
+		time.Sleep(time.Millisecond * 5)
+		atomic.AddInt32(&fs.src.active, -1)
+	}
+	return nil
+}
+
+func (src *fanoutSource) StartRx(sdr.Transceiver) (sdr.ReadCloser, error) {
+	reader, err := siggen.CW(siggen.Config{
+		SampleRate:   1024 * 1024,
+		SampleFormat: sdr.SampleFormatU8,
+	}, rf.KHz*10)
+	if err != nil {
+		return nil, err
+	}
+	atomic.AddInt32(&src.starts, 1)
+	if atomic.AddInt32(&src.active, 1) > 1 {
+		atomic.StoreInt32(&src.overlap, 1)
+	}
+	return &fanoutStream{Reader: reader, src: src}, nil
+}
+
+func serveFanout(t *testing.T, opts rtltcp.FanoutOptions) (*rtltcp.Fanout, *fanoutSource, string, func()) {
+	src := &fanoutSource{}
+	dev := mock.New(mock.Config{
+		SampleRate:   1024 * 1024,
+		SampleFormat: sdr.SampleFormatU8,
+		Rx:           src.StartRx,
+	})
+	fanout, err := rtltcp.NewFanout(dev, opts)
+	assert.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go rtltcp.Server{Fanout: fanout}.Serve(listener)
+	return fanout, src, listener.Addr().String(), func() { listener.Close() }
+}
+
+func readFanout(t *testing.T, addr string, n int) {
+	conn, err := net.Dial("tcp", addr)
+	assert.NoError(t, err)
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	info := make([]byte, 12)
+	_, err = io.ReadFull(conn, info)
+	assert.NoError(t, err)
+	assert.Equal(t, "RTL0", string(info[:4]))
+
+	buf := make([]byte, n)
+	_, err = io.ReadFull(conn, buf)
+	assert.NoError(t, err)
+}
+
+func waitForClients(t *testing.T, fanout *rtltcp.Fanout) {
+	deadline := time.Now().Add(5 * time.Second)
+	for fanout.Clients() != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond * 10)
+	}
+	assert.Equal(t, 0, fanout.Clients())
+}
+
+func TestFanoutLargeSlots(t *testing.T) {
+	slotLength := 64 * 1024
+	fanout, _, addr, stop := serveFanout(t, rtltcp.FanoutOptions{
+		SlotLength: slotLength,
+	})
+	defer stop()
+
+	// Each U8 sample is two bytes on the wire; read a few slots worth.
+	readFanout(t, addr, slotLength*2*4)
+	waitForClients(t, fanout)
+}
+
+func TestServerChannelizerAndFanout(t *testing.T) {
+	fanout, err := rtltcp.NewFanout(mock.New(mock.Config{}), rtltcp.FanoutOptions{})
+	assert.NoError(t, err)
+
+	pipeReader, pipeWriter := sdr.Pipe(1024, sdr.SampleFormatC64)
+	defer pipeWriter.Close()
+	channelizer, err := stream.NewChannelizer(pipeReader, stream.ChannelizerOptions{})
+	assert.NoError(t, err)
+	defer channelizer.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	assert.Equal(t, rtltcp.ErrChannelizerAndFanout, rtltcp.Server{
+		Fanout:      fanout,
+		Channelizer: channelizer,
+	}.Serve(listener))
+}
+
+func TestFanoutChurn(t *testing.T) {
+	fanout, src, addr, stop := serveFanout(t, rtltcp.FanoutOptions{
+		SlotLength: 1024,
+	})
+	defer stop()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				readFanout(t, addr, 1024*2)
+			}
+		}()
+	}
+	wg.Wait()
+	waitForClients(t, fanout)
+
+	assert.NotZero(t, atomic.LoadInt32(&src.starts))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&src.overlap))
+}
+
+// vim: foldmethod=marker
@@ -38,6 +38,10 @@ import (
 var (
 	// ErrSDRNotFound will be returned if no SDR can be acquired.
 	ErrSDRNotFound = fmt.Errorf("rtltcp: SDR Not Found")
+
+	// ErrChannelizerAndFanout will be returned by Serve if a Server has
+	// both a Channelizer and a Fanout set, since only one can be used.
+	ErrChannelizerAndFanout = fmt.Errorf("rtltcp: both Channelizer and Fanout are set")
 )
 
 // ServerHandler will return an SDR to be used by the incoming
@@ -75,6 +79,31 @@ type Server struct {
 
 	// ConnContext will create a context based on the provided net.Conn
 	ConnContext func(ctx context.Context, c net.Conn) context.Context
+
+	// (Optional) Channelizer, if set, will serve every connection from a
+	// single wideband receiver, rather than calling Handler for each. Each
+	// client gets its own stream.Channel, which is tuned within the
+	// bandwidth of the receiver when the client sets a frequency or sample
+	// rate, rather than retuning the radio. Clients can't change the gain
+	// of the shared receiver, so those commands are ignored.
+	Channelizer *stream.Channelizer
+
+	// (Optional) CenterFrequency is the frequency the receiver feeding the
+	// Channelizer is tuned to, which is used to work out the offset of
+	// each client's channel.
+	CenterFrequency rf.Hz
+
+	// (Optional) ChannelSampleRate is the sample rate of each client's
+	// channel, until the client requests another. If 0, this will default
+	// to the sample rate of the Channelizer.
+	ChannelSampleRate uint
+
+	// (Optional) Fanout, if set, will serve every connection from the one
+	// shared receiver, rather than calling Handler for each. Every client
+	// gets the same IQ stream, and the Fanout's Policy decides which client
+	// (if any) can tune or change the gain of the receiver. This can't be
+	// set along with a Channelizer.
+	Fanout *Fanout
 }
 
 // NewDefaultCommandHandler will create the default rtltcp CommandHandler
@@ -120,7 +149,15 @@ func NewDefaultCommandHandler(defaultGainStageName, defaultIFGainStageName strin
 		case CommandSetBiasTee:
 			// TODO(paultag): This one may be worth implementing.
 			return nil
-		case CommandSetAGCMode, CommandSetDirectSampling, CommandSetOffsetTuning:
+		case CommandSetOffsetTuning:
+			offsetTuner, ok := dev.(OffsetTuner)
+			if !ok {
+				log.Printf("device can't offset tune, ignoring\n")
+				return nil
+			}
+			log.Printf("Setting offset tuning to %t\n", arg != 0)
+			return offsetTuner.SetOffsetTuning(arg != 0)
+		case CommandSetAGCMode, CommandSetDirectSampling:
 			// Ignore!
 			return nil
 		default:
@@ -137,6 +174,13 @@ type Tunerable interface {
 	Tuner() rtl.Tuner
 }
 
+// OffsetTuner is an interface that allows the Sdr to enable or disable
+// offset tuning, such as the rtl.Sdr with an E4000 tuner. If the device
+// doesn't implement it, CommandSetOffsetTuning is ignored.
+type OffsetTuner interface {
+	SetOffsetTuning(bool) error
+}
+
 func (s Server) serveConn(ctx context.Context, conn net.Conn) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer conn.Close()
@@ -146,6 +190,14 @@ func (s Server) serveConn(ctx context.Context, conn net.Conn) error {
 		ctx = s.ConnContext(ctx, conn)
 	}
 
+	if s.Channelizer != nil {
+		return s.serveChannel(ctx, cancel, conn)
+	}
+
+	if s.Fanout != nil {
+		return s.serveFanout(ctx, cancel, conn)
+	}
+
 	dev, err := s.Handler(ctx)
 	if err != nil {
 		log.Printf("Error accepting new connection - closing connection")
@@ -236,6 +288,10 @@ func (s Server) serveConn(ctx context.Context, conn net.Conn) error {
 // Serve will accept connections from the provided listener, and serve
 // client requests.
 func (s Server) Serve(listener net.Listener) error {
+	if s.Channelizer != nil && s.Fanout != nil {
+		return ErrChannelizerAndFanout
+	}
+
 	ctx := context.TODO()
 	// TODO: Have this configurable in the Server struct, and augment this
 	// with peer info.
@@ -251,6 +307,9 @@ func (s Server) Serve(listener net.Listener) error {
 
 // ListenAndServe will listen for incoming requests and return them as required.
 func (s Server) ListenAndServe() error {
+	if s.Channelizer != nil && s.Fanout != nil {
+		return ErrChannelizerAndFanout
+	}
 	listener, err := net.Listen("tcp", s.Addr)
 	if err != nil {
 		return err
@@ -0,0 +1,61 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sdr
+
+import (
+	"fmt"
+)
+
+var (
+	// ErrNoSupportedSampleFormat will be returned by NegotiateSampleFormat
+	// if none of the preferred SampleFormats are supported.
+	ErrNoSupportedSampleFormat = fmt.Errorf("sdr: none of the preferred sample formats are supported")
+)
+
+// NegotiateSampleFormat will pick a SampleFormat for a driver to use, given
+// an ordered list of the formats the caller would like (most preferred
+// first), and the formats the driver supports (with the driver's default
+// first).
+//
+// The first preferred format that is supported is returned. If no
+// preference is given, the driver's default is returned. Callers can use
+// this to (for instance) prefer SampleFormatI8 over SampleFormatI16 over
+// SampleFormatC64 to save USB bandwidth, and drivers should use this rather
+// than each inventing their own rules. The chosen format is reported by the
+// SampleFormat method of the Sdr.
+func NegotiateSampleFormat(preferred, supported []SampleFormat) (SampleFormat, error) {
+	if len(supported) == 0 {
+		return 0, ErrNoSupportedSampleFormat
+	}
+	if len(preferred) == 0 {
+		return supported[0], nil
+	}
+	for _, want := range preferred {
+		for _, have := range supported {
+			if want == have {
+				return want, nil
+			}
+		}
+	}
+	return 0, ErrNoSupportedSampleFormat
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,57 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sdr_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+)
+
+func TestNegotiateSampleFormat(t *testing.T) {
+	supported := []sdr.SampleFormat{
+		sdr.SampleFormatI16,
+		sdr.SampleFormatI8,
+		sdr.SampleFormatC64,
+	}
+
+	for _, test := range []struct {
+		preferred []sdr.SampleFormat
+		expected  sdr.SampleFormat
+		err       error
+	}{
+		{nil, sdr.SampleFormatI16, nil},
+		{[]sdr.SampleFormat{sdr.SampleFormatI8, sdr.SampleFormatI16}, sdr.SampleFormatI8, nil},
+		{[]sdr.SampleFormat{sdr.SampleFormatU8, sdr.SampleFormatC64}, sdr.SampleFormatC64, nil},
+		{[]sdr.SampleFormat{sdr.SampleFormatU8}, 0, sdr.ErrNoSupportedSampleFormat},
+	} {
+		sf, err := sdr.NegotiateSampleFormat(test.preferred, supported)
+		assert.Equal(t, test.err, err)
+		assert.Equal(t, test.expected, sf)
+	}
+
+	_, err := sdr.NegotiateSampleFormat(nil, nil)
+	assert.Equal(t, sdr.ErrNoSupportedSampleFormat, err)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,212 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sdr
+
+import (
+	"fmt"
+)
+
+var (
+	// ErrNoSampleRates will be returned if a device reports that it has no
+	// valid sample rates, or none are usable for the requested rate.
+	ErrNoSampleRates = fmt.Errorf("sdr: no usable sample rates")
+)
+
+// SampleRateRange is a range of valid sample rates, from Min to Max
+// (inclusive), in increments of Step. If Step is 0, any rate in the range
+// is valid. A single fixed rate is represented with Min and Max equal.
+type SampleRateRange struct {
+	Min  uint
+	Max  uint
+	Step uint
+}
+
+// Contains will return true if the provided rate is valid for this range.
+func (srr SampleRateRange) Contains(rate uint) bool {
+	if rate < srr.Min || rate > srr.Max {
+		return false
+	}
+	if srr.Step == 0 {
+		return true
+	}
+	return (rate-srr.Min)%srr.Step == 0
+}
+
+// Nearest will return the valid rate in this range closest to the provided
+// rate. If two rates are equally close, the higher one is returned, since
+// it keeps all the requested bandwidth.
+func (srr SampleRateRange) Nearest(rate uint) uint {
+	if rate <= srr.Min {
+		return srr.Min
+	}
+	if rate >= srr.Max {
+		return srr.Max
+	}
+	if srr.Step == 0 {
+		return rate
+	}
+
+	lower := srr.Min + ((rate-srr.Min)/srr.Step)*srr.Step
+	upper := lower + srr.Step
+	if upper > srr.Max {
+		return lower
+	}
+	if rate-lower < upper-rate {
+		return lower
+	}
+	return upper
+}
+
+// SampleRateRanger is implemented by Sdrs that are able to report which
+// sample rates they are able to be set to.
+type SampleRateRanger interface {
+	// SampleRateRanges will return the ranges of valid sample rates.
+	SampleRateRanges() ([]SampleRateRange, error)
+}
+
+// sampleRateRanges will return the SampleRateRanges of the device, or
+// ErrNotSupported if it can't report them.
+func sampleRateRanges(dev Sdr) ([]SampleRateRange, error) {
+	srr, ok := dev.(SampleRateRanger)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	ranges, err := srr.SampleRateRanges()
+	if err != nil {
+		return nil, err
+	}
+	if len(ranges) == 0 {
+		return nil, ErrNoSampleRates
+	}
+	return ranges, nil
+}
+
+func absDiff(a, b uint) uint {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// NearestSampleRate will return the valid sample rate of the device closest
+// to the desired rate. If two rates are equally close, the higher one is
+// returned.
+//
+// If the Sdr does not implement SampleRateRanger, ErrNotSupported will be
+// returned.
+func NearestSampleRate(dev Sdr, desired uint) (uint, error) {
+	ranges, err := sampleRateRanges(dev)
+	if err != nil {
+		return 0, err
+	}
+
+	best := ranges[0].Nearest(desired)
+	for _, srr := range ranges[1:] {
+		rate := srr.Nearest(desired)
+		var (
+			diff     = absDiff(rate, desired)
+			bestDiff = absDiff(best, desired)
+		)
+		if diff < bestDiff || (diff == bestDiff && rate > best) {
+			best = rate
+		}
+	}
+	return best, nil
+}
+
+// SampleRateSuggestion is a sample rate to set a device to, along with the
+// ratio to resample the IQ data by to get to an exact sample rate.
+type SampleRateSuggestion struct {
+	// SampleRate is the rate the device should be set to.
+	SampleRate uint
+
+	// Interpolation and Decimation are the (reduced) factors to change the
+	// sample rate by, from SampleRate to the desired rate. If both are 1,
+	// the device is able to produce the desired rate directly.
+	Interpolation uint
+	Decimation    uint
+}
+
+// Exact will return true if no resampling is required.
+func (srs SampleRateSuggestion) Exact() bool {
+	return srs.Interpolation == 1 && srs.Decimation == 1
+}
+
+func gcd(a, b uint) uint {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// SuggestSampleRate will pick a sample rate for the device to use when
+// the desired rate is needed exactly downstream, along with the ratio to
+// resample by (for instance, with stream.Resample) to get there.
+//
+// If the device supports the desired rate, it will be used as-is. If not,
+// the lowest valid integer multiple of the desired rate is used, so that
+// only decimation is required. Failing that, the nearest valid rate is used,
+// which may require a complex rational resampler.
+func SuggestSampleRate(dev Sdr, desired uint) (SampleRateSuggestion, error) {
+	if desired == 0 {
+		return SampleRateSuggestion{}, ErrNoSampleRates
+	}
+
+	ranges, err := sampleRateRanges(dev)
+	if err != nil {
+		return SampleRateSuggestion{}, err
+	}
+
+	var max uint
+	for _, srr := range ranges {
+		if srr.Max > max {
+			max = srr.Max
+		}
+	}
+
+	for rate := desired; rate <= max; rate += desired {
+		for _, srr := range ranges {
+			if srr.Contains(rate) {
+				return SampleRateSuggestion{
+					SampleRate:    rate,
+					Interpolation: 1,
+					Decimation:    rate / desired,
+				}, nil
+			}
+		}
+	}
+
+	rate, err := NearestSampleRate(dev, desired)
+	if err != nil {
+		return SampleRateSuggestion{}, err
+	}
+	if rate == 0 {
+		return SampleRateSuggestion{}, ErrNoSampleRates
+	}
+	g := gcd(rate, desired)
+	return SampleRateSuggestion{
+		SampleRate:    rate,
+		Interpolation: desired / g,
+		Decimation:    rate / g,
+	}, nil
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,115 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sdr_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+)
+
+type rangeSdr struct {
+	sdr.Sdr
+	ranges []sdr.SampleRateRange
+}
+
+func (r rangeSdr) SampleRateRanges() ([]sdr.SampleRateRange, error) {
+	return r.ranges, nil
+}
+
+// rtlRanges are roughly the rates an rtl-sdr accepts.
+var rtlRanges = rangeSdr{ranges: []sdr.SampleRateRange{
+	{Min: 225001, Max: 300000},
+	{Min: 900001, Max: 3200000},
+}}
+
+func TestSampleRateRange(t *testing.T) {
+	srr := sdr.SampleRateRange{Min: 1000, Max: 2000, Step: 100}
+	assert.True(t, srr.Contains(1500))
+	assert.False(t, srr.Contains(1550))
+	assert.False(t, srr.Contains(2100))
+
+	assert.Equal(t, uint(1000), srr.Nearest(10))
+	assert.Equal(t, uint(2000), srr.Nearest(3000))
+	assert.Equal(t, uint(1500), srr.Nearest(1520))
+	assert.Equal(t, uint(1600), srr.Nearest(1550))
+}
+
+func TestNearestSampleRate(t *testing.T) {
+	for _, test := range []struct {
+		desired  uint
+		expected uint
+	}{
+		{2048000, 2048000},
+		{48000, 225001},
+		{500000, 300000},
+		{700000, 900001},
+		{10000000, 3200000},
+	} {
+		rate, err := sdr.NearestSampleRate(rtlRanges, test.desired)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, rate)
+	}
+
+	airspy := rangeSdr{ranges: []sdr.SampleRateRange{
+		{Min: 912000, Max: 912000},
+		{Min: 768000, Max: 768000},
+		{Min: 456000, Max: 456000},
+	}}
+	rate, err := sdr.NearestSampleRate(airspy, 800000)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(768000), rate)
+
+	_, err = sdr.NearestSampleRate(rangeSdr{}, 800000)
+	assert.Equal(t, sdr.ErrNoSampleRates, err)
+}
+
+func TestSuggestSampleRate(t *testing.T) {
+	srs, err := sdr.SuggestSampleRate(rtlRanges, 2400000)
+	assert.NoError(t, err)
+	assert.True(t, srs.Exact())
+	assert.Equal(t, uint(2400000), srs.SampleRate)
+
+	srs, err = sdr.SuggestSampleRate(rtlRanges, 48000)
+	assert.NoError(t, err)
+	assert.Equal(t, sdr.SampleRateSuggestion{
+		SampleRate:    240000,
+		Interpolation: 1,
+		Decimation:    5,
+	}, srs)
+
+	fixed := rangeSdr{ranges: []sdr.SampleRateRange{{Min: 912000, Max: 912000}}}
+	srs, err = sdr.SuggestSampleRate(fixed, 48000)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(19), srs.Decimation)
+
+	srs, err = sdr.SuggestSampleRate(fixed, 44100)
+	assert.NoError(t, err)
+	assert.Equal(t, sdr.SampleRateSuggestion{
+		SampleRate:    912000,
+		Interpolation: 147,
+		Decimation:    3040,
+	}, srs)
+}
+
+// vim: foldmethod=marker
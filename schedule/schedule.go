@@ -0,0 +1,218 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidCron will be returned if a cron expression can't be parsed.
+	ErrInvalidCron = fmt.Errorf("schedule: invalid cron expression")
+)
+
+// Schedule determines when a Job runs.
+type Schedule interface {
+	// Next will return the first time the Job should start strictly after
+	// the provided time, or the zero time if it will never run again.
+	Next(time.Time) time.Time
+}
+
+type every struct {
+	interval time.Duration
+	offset   time.Duration
+}
+
+// Every will return a Schedule that runs every interval, aligned to the
+// interval since the Unix epoch (so an interval of an hour runs on the
+// hour), plus the provided offset.
+func Every(interval, offset time.Duration) Schedule {
+	return every{interval: interval, offset: offset}
+}
+
+func (e every) Next(after time.Time) time.Time {
+	next := after.Add(-e.offset).Truncate(e.interval).Add(e.offset)
+	for !next.After(after) {
+		next = next.Add(e.interval)
+	}
+	return next
+}
+
+type at []time.Time
+
+// At will return a Schedule that runs at each of the provided times, such
+// as the start of each predicted satellite pass.
+func At(times ...time.Time) Schedule {
+	ret := make(at, len(times))
+	copy(ret, times)
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Before(ret[j]) })
+	return ret
+}
+
+func (a at) Next(after time.Time) time.Time {
+	for _, t := range a {
+		if t.After(after) {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// cron is a parsed cron expression; each field is a set of allowed values.
+type cron struct {
+	minute, hour, dom, month, dow map[int]bool
+	domAny, dowAny                bool
+	location                      *time.Location
+}
+
+// Cron will parse a standard five field cron expression (minute, hour, day of
+// month, month, and day of week), evaluated in the provided location. Each
+// field may be "*", a value, a range ("1-5"), a step ("*/15" or "0-30/10"),
+// or a comma separated list of those. As with cron, if both the day of month
+// and day of week are restricted, a day matching either will run.
+func Cron(expr string, location *time.Location) (Schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, ErrInvalidCron
+	}
+
+	var (
+		c   = cron{location: location}
+		err error
+	)
+	if c.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, err
+	}
+	if c.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, err
+	}
+	if c.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, err
+	}
+	if c.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, err
+	}
+	if c.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, err
+	}
+	// Both 0 and 7 are Sunday.
+	if c.dow[7] {
+		c.dow[0] = true
+	}
+	c.domAny = fields[2] == "*"
+	c.dowAny = fields[4] == "*"
+	return c, nil
+}
+
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+	ret := map[int]bool{}
+	for _, part := range strings.Split(field, ",") {
+		var (
+			rng  = part
+			step = 1
+			err  error
+		)
+		if i := strings.Index(part, "/"); i >= 0 {
+			rng = part[:i]
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return nil, ErrInvalidCron
+			}
+		}
+
+		lo, hi := min, max
+		switch {
+		case rng == "*":
+		case strings.Contains(rng, "-"):
+			bounds := strings.SplitN(rng, "-", 2)
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, ErrInvalidCron
+			}
+			if hi, err = strconv.Atoi(bounds[1]); err != nil {
+				return nil, ErrInvalidCron
+			}
+		default:
+			if lo, err = strconv.Atoi(rng); err != nil {
+				return nil, ErrInvalidCron
+			}
+			hi = lo
+			if step != 1 {
+				hi = max
+			}
+		}
+
+		if lo < min || hi > max || lo > hi {
+			return nil, ErrInvalidCron
+		}
+		for i := lo; i <= hi; i += step {
+			ret[i] = true
+		}
+	}
+	return ret, nil
+}
+
+func (c cron) dayMatches(t time.Time) bool {
+	dom := c.dom[t.Day()]
+	dow := c.dow[int(t.Weekday())]
+	switch {
+	case c.domAny && c.dowAny:
+		return true
+	case c.domAny:
+		return dow
+	case c.dowAny:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+func (c cron) Next(after time.Time) time.Time {
+	t := after.In(c.location).Truncate(time.Minute).Add(time.Minute)
+
+	// Any valid expression will match within a few years (Feb 29th on a
+	// specific weekday being the worst case), so give up after that.
+	limit := t.AddDate(8, 0, 0)
+	for t.Before(limit) {
+		if !c.month[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, c.location)
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, c.location)
+			continue
+		}
+		if !c.hour[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, c.location)
+			continue
+		}
+		if !c.minute[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,158 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package schedule_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr/schedule"
+)
+
+func date(year int, month time.Month, day, hour, min int) time.Time {
+	return time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+}
+
+func TestCron(t *testing.T) {
+	for _, tc := range []struct {
+		Expr     string
+		After    time.Time
+		Expected time.Time
+	}{
+		{"*/15 * * * *", date(2023, 3, 1, 10, 7), date(2023, 3, 1, 10, 15)},
+		{"*/15 * * * *", date(2023, 3, 1, 10, 15), date(2023, 3, 1, 10, 30)},
+		{"0 * * * *", date(2023, 12, 31, 23, 30), date(2024, 1, 1, 0, 0)},
+		// Saturday to Monday morning.
+		{"0 9 * * 1-5", date(2023, 3, 4, 12, 0), date(2023, 3, 6, 9, 0)},
+		{"30 6,18 * * *", date(2023, 3, 1, 7, 0), date(2023, 3, 1, 18, 30)},
+		{"0 0 29 2 *", date(2023, 3, 1, 0, 0), date(2024, 2, 29, 0, 0)},
+		// Either the 1st, or a Sunday.
+		{"0 0 1 * 7", date(2023, 3, 2, 0, 0), date(2023, 3, 5, 0, 0)},
+	} {
+		s, err := schedule.Cron(tc.Expr, time.UTC)
+		assert.NoError(t, err, tc.Expr)
+		assert.Equal(t, tc.Expected, s.Next(tc.After), tc.Expr)
+	}
+
+	for _, expr := range []string{
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+	} {
+		_, err := schedule.Cron(expr, time.UTC)
+		assert.Equal(t, schedule.ErrInvalidCron, err, expr)
+	}
+}
+
+func TestEvery(t *testing.T) {
+	s := schedule.Every(time.Hour, 0)
+	assert.Equal(t, date(2023, 3, 1, 11, 0), s.Next(date(2023, 3, 1, 10, 7)))
+	assert.Equal(t, date(2023, 3, 1, 11, 0), s.Next(date(2023, 3, 1, 10, 0)))
+
+	s = schedule.Every(time.Hour, time.Minute*5)
+	assert.Equal(t, date(2023, 3, 1, 11, 5), s.Next(date(2023, 3, 1, 10, 7)))
+	assert.Equal(t, date(2023, 3, 1, 10, 5), s.Next(date(2023, 3, 1, 10, 1)))
+}
+
+func TestAt(t *testing.T) {
+	s := schedule.At(date(2023, 3, 2, 0, 0), date(2023, 3, 1, 0, 0))
+	assert.Equal(t, date(2023, 3, 1, 0, 0), s.Next(date(2023, 1, 1, 0, 0)))
+	assert.Equal(t, date(2023, 3, 2, 0, 0), s.Next(date(2023, 3, 1, 0, 0)))
+	assert.True(t, s.Next(date(2023, 3, 2, 0, 0)).IsZero())
+}
+
+func TestScheduler(t *testing.T) {
+	var (
+		start   = time.Now().Add(time.Millisecond * 20)
+		lock    = sync.Mutex{}
+		results = map[string]schedule.Result{}
+		done    = make(chan struct{}, 3)
+	)
+
+	s := schedule.New()
+	s.OnResult = func(r schedule.Result) {
+		lock.Lock()
+		defer lock.Unlock()
+		results[r.Job] = r
+		done <- struct{}{}
+	}
+
+	sleep := func(d time.Duration) func(context.Context) error {
+		return func(ctx context.Context) error {
+			time.Sleep(d)
+			return nil
+		}
+	}
+
+	assert.NoError(t, s.Add(schedule.Job{
+		Name:     "first",
+		Schedule: schedule.At(start),
+		Window:   time.Second,
+		Devices:  []string{"rtl0"},
+		Run:      sleep(time.Millisecond * 100),
+	}))
+	assert.NoError(t, s.Add(schedule.Job{
+		Name:     "waits",
+		Schedule: schedule.At(start.Add(time.Millisecond * 10)),
+		Window:   time.Second,
+		Devices:  []string{"rtl0", "rtl1"},
+		Run:      sleep(0),
+	}))
+	assert.NoError(t, s.Add(schedule.Job{
+		Name:     "busy",
+		Schedule: schedule.At(start.Add(time.Millisecond * 10)),
+		Window:   time.Millisecond * 20,
+		Devices:  []string{"rtl0"},
+		Run:      sleep(0),
+	}))
+	assert.Equal(t, schedule.ErrDuplicateJob, s.Add(schedule.Job{Name: "busy"}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	finished := make(chan error)
+	go func() { finished <- s.Run(ctx) }()
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-done:
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out waiting for jobs")
+		}
+	}
+	// Every Schedule is done, so Run will return on its own.
+	assert.NoError(t, <-finished)
+	cancel()
+
+	assert.NoError(t, results["first"].Err)
+	assert.NoError(t, results["waits"].Err)
+	assert.False(t, results["waits"].Start.Before(results["first"].End))
+	assert.Equal(t, schedule.ErrDeviceBusy, results["busy"].Err)
+	assert.True(t, results["busy"].Start.IsZero())
+}
+
+// vim: foldmethod=marker
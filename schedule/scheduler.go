@@ -0,0 +1,246 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"hz.tools/sdr"
+)
+
+var (
+	// ErrDeviceBusy is the error a Result will have if the devices a Job
+	// needs were not free before the end of its window.
+	ErrDeviceBusy = fmt.Errorf("schedule: device was busy for the whole window")
+
+	// ErrDuplicateJob will be returned if a Job is added with the same name
+	// as one already added.
+	ErrDuplicateJob = fmt.Errorf("schedule: a job with that name already exists")
+)
+
+// Job is a capture task to run on a Schedule.
+type Job struct {
+	// Name uniquely identifies the Job.
+	Name string
+
+	// Schedule determines when the Job starts.
+	Schedule Schedule
+
+	// Window is how long the Job may run for. The context passed to Run
+	// is canceled at the end of the Window, and Run must then release any
+	// hardware it opened and return promptly.
+	Window time.Duration
+
+	// Devices are the keys (such as serial numbers) of the devices the
+	// Job needs exclusive access to. A Job will wait (up to the end of its
+	// Window) for any other Job using the same devices to finish.
+	Devices []string
+
+	// Run will open the devices, run the capture pipeline, and write out
+	// the results.
+	Run func(context.Context) error
+}
+
+// Result is the outcome of a single run of a Job.
+type Result struct {
+	// Job is the Name of the Job.
+	Job string
+
+	// Scheduled is when the Job was scheduled to start.
+	Scheduled time.Time
+
+	// Start and End are when the Job actually ran. If the Job never
+	// started (because its devices were busy), Start is the zero time.
+	Start time.Time
+	End   time.Time
+
+	// Err is the error Run returned, or ErrDeviceBusy. A panic in Run is
+	// reported as an sdr.ErrDriverPanic.
+	Err error
+}
+
+// Scheduler runs Jobs on their Schedules.
+type Scheduler struct {
+	lock    *sync.Mutex
+	jobs    []Job
+	devices map[string]chan struct{}
+
+	// OnResult, if set, is called with the Result of every run of every
+	// Job. It may be called from many goroutines at once.
+	OnResult func(Result)
+
+	// Now returns the current time. If unset, time.Now is used. This is
+	// mostly useful for tests.
+	Now func() time.Time
+}
+
+// New will create a new Scheduler with no Jobs.
+func New() *Scheduler {
+	return &Scheduler{
+		lock:    &sync.Mutex{},
+		devices: map[string]chan struct{}{},
+	}
+}
+
+func (s *Scheduler) now() time.Time {
+	if s.Now == nil {
+		return time.Now()
+	}
+	return s.Now()
+}
+
+// Add will add a Job to the Scheduler. Jobs must be added before calling
+// Run.
+func (s *Scheduler) Add(job Job) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, j := range s.jobs {
+		if j.Name == job.Name {
+			return ErrDuplicateJob
+		}
+	}
+	for _, device := range job.Devices {
+		if _, ok := s.devices[device]; !ok {
+			s.devices[device] = make(chan struct{}, 1)
+		}
+	}
+	s.jobs = append(s.jobs, job)
+	return nil
+}
+
+// Run will run every Job on its Schedule until the context is canceled (or
+// every Schedule has no more runs), and then wait for any running Jobs to
+// return.
+func (s *Scheduler) Run(ctx context.Context) error {
+	s.lock.Lock()
+	jobs := s.jobs
+	s.lock.Unlock()
+
+	wg := sync.WaitGroup{}
+	for _, job := range jobs {
+		wg.Add(1)
+		go func(job Job) {
+			defer wg.Done()
+			s.loop(ctx, job)
+		}(job)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	wg := sync.WaitGroup{}
+	defer wg.Wait()
+
+	last := s.now()
+	for {
+		next := job.Schedule.Next(last)
+		if next.IsZero() {
+			return
+		}
+		last = next
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(next.Sub(s.now())):
+		}
+
+		// Runs are started in their own goroutine, so a Job which
+		// overruns (or waits on a device) doesn't push back the next
+		// scheduled start.
+		wg.Add(1)
+		go func(scheduled time.Time) {
+			defer wg.Done()
+			result := s.run(ctx, job, scheduled)
+			if s.OnResult != nil {
+				s.OnResult(result)
+			}
+		}(next)
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, job Job, scheduled time.Time) Result {
+	result := Result{Job: job.Name, Scheduled: scheduled}
+
+	ctx, cancel := context.WithDeadline(ctx, scheduled.Add(job.Window))
+	defer cancel()
+
+	release, err := s.acquire(ctx, job.Devices)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer release()
+
+	result.Start = s.now()
+	result.Err = call(ctx, job.Run)
+	result.End = s.now()
+	return result
+}
+
+// acquire will take the locks of each of the devices, in a consistent order
+// so that two Jobs sharing more than one device can't deadlock.
+func (s *Scheduler) acquire(ctx context.Context, devices []string) (func(), error) {
+	sorted := append([]string{}, devices...)
+	sort.Strings(sorted)
+
+	held := []chan struct{}{}
+	release := func() {
+		for _, ch := range held {
+			<-ch
+		}
+	}
+
+	for _, device := range sorted {
+		s.lock.Lock()
+		ch := s.devices[device]
+		s.lock.Unlock()
+
+		select {
+		case ch <- struct{}{}:
+			held = append(held, ch)
+		case <-ctx.Done():
+			release()
+			if ctx.Err() == context.DeadlineExceeded {
+				return nil, ErrDeviceBusy
+			}
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
+
+func call(ctx context.Context, fn func(context.Context) error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = sdr.DriverPanic(v)
+		}
+	}()
+	return fn(ctx)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,177 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sdrplay
+
+// #include <sdrplay_api.h>
+import "C"
+
+import (
+	"fmt"
+
+	"hz.tools/sdr"
+)
+
+const (
+	minIFGainReduction = 20
+	maxIFGainReduction = 59
+)
+
+// SetAutomaticGain implements the sdr.Sdr interface. This controls the
+// IF AGC loop in the API service; the LNA state is left alone.
+func (s *Sdr) SetAutomaticGain(state bool) error {
+	agc := &s.rxParams().ctrlParams.agc
+	if state {
+		agc.enable = C.sdrplay_api_AGC_CTRL_EN
+	} else {
+		agc.enable = C.sdrplay_api_AGC_DISABLE
+	}
+	if err := s.update(C.sdrplay_api_Update_Ctrl_Agc); err != nil {
+		return fmt.Errorf("sdrplay.Sdr.SetAutomaticGain: %s", err)
+	}
+	return nil
+}
+
+type gainStage interface {
+	// SetGain will set the gain on the specified Stage.
+	SetGain(*Sdr, float32) error
+
+	// SetGain will get the gain on the specified Stage.
+	GetGain(*Sdr) (float32, error)
+}
+
+// GetGain implements the sdr.Sdr interface.
+func (s *Sdr) GetGain(gs sdr.GainStage) (float32, error) {
+	stage, ok := gs.(gainStage)
+	if !ok {
+		return 0, fmt.Errorf("sdrplay.Sdr.GetGain: unknown GainStage")
+	}
+	return stage.GetGain(s)
+}
+
+// SetGain implements the sdr.Sdr interface.
+func (s *Sdr) SetGain(gs sdr.GainStage, gain float32) error {
+	stage, ok := gs.(gainStage)
+	if !ok {
+		return fmt.Errorf("sdrplay.Sdr.SetGain: unknown GainStage")
+	}
+	return stage.SetGain(s, gain)
+}
+
+// GetGainStages implements the sdr.Sdr interface.
+func (s *Sdr) GetGainStages() (sdr.GainStages, error) {
+	return sdr.GainStages{
+		lnaGain{maxState: s.model.maxLNAState()},
+		ifGain{},
+	}, nil
+}
+
+// clampRound will round the provided value to the nearest integer, within
+// the provided bounds.
+func clampRound(v float32, lo, hi int) int {
+	var i int
+	if v < 0 {
+		i = int(v - 0.5)
+	} else {
+		i = int(v + 0.5)
+	}
+	if i < lo {
+		return lo
+	}
+	if i > hi {
+		return hi
+	}
+	return i
+}
+
+// lnaGain is the RF front-end LNA state. The SDRplay API expresses this
+// as an index into a per-model, per-band table of gain reductions, where 0
+// is the most gain. This is exposed as the negated index, so that larger
+// values still mean more gain.
+type lnaGain struct {
+	maxState int
+}
+
+// Type implements the sdr.GainStage interface.
+func (lg lnaGain) Type() sdr.GainStageType {
+	return sdr.GainStageTypeRecieve | sdr.GainStageTypeAttenuator
+}
+
+// Range implements the sdr.GainStage interface.
+func (lg lnaGain) Range() [2]float32 {
+	return [2]float32{-float32(lg.maxState), 0}
+}
+
+// String implements the sdr.GainStage interface.
+func (lg lnaGain) String() string {
+	return "LNA"
+}
+
+// SetGain implements the gainStage interface.
+func (lg lnaGain) SetGain(s *Sdr, gain float32) error {
+	state := -clampRound(gain, -lg.maxState, 0)
+	s.rxParams().tunerParams.gain.LNAstate = C.uchar(state)
+	if err := s.update(C.sdrplay_api_Update_Tuner_Gr); err != nil {
+		return fmt.Errorf("sdrplay.Sdr.SetGain: %s", err)
+	}
+	return nil
+}
+
+// GetGain implements the gainStage interface.
+func (lg lnaGain) GetGain(s *Sdr) (float32, error) {
+	return -float32(s.rxParams().tunerParams.gain.LNAstate), nil
+}
+
+// ifGain is the IF gain reduction, in dB. This is exposed as a negative
+// gain, so that larger values mean more gain. While the IF AGC is enabled,
+// the API service will override this value.
+type ifGain struct{}
+
+// Type implements the sdr.GainStage interface.
+func (ig ifGain) Type() sdr.GainStageType {
+	return sdr.GainStageTypeRecieve | sdr.GainStageTypeIF | sdr.GainStageTypeAttenuator
+}
+
+// Range implements the sdr.GainStage interface.
+func (ig ifGain) Range() [2]float32 {
+	return [2]float32{-maxIFGainReduction, -minIFGainReduction}
+}
+
+// String implements the sdr.GainStage interface.
+func (ig ifGain) String() string {
+	return "IF"
+}
+
+// SetGain implements the gainStage interface.
+func (ig ifGain) SetGain(s *Sdr, gain float32) error {
+	gr := -clampRound(gain, -maxIFGainReduction, -minIFGainReduction)
+	s.rxParams().tunerParams.gain.gRdB = C.int(gr)
+	if err := s.update(C.sdrplay_api_Update_Tuner_Gr); err != nil {
+		return fmt.Errorf("sdrplay.Sdr.SetGain: %s", err)
+	}
+	return nil
+}
+
+// GetGain implements the gainStage interface.
+func (ig ifGain) GetGain(s *Sdr) (float32, error) {
+	return -float32(s.rxParams().tunerParams.gain.gRdB), nil
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,253 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sdrplay
+
+// #include <sdrplay_api.h>
+//
+// extern void sdrplay_stream_callback(short*, short*, sdrplay_api_StreamCbParamsT*, unsigned int, unsigned int, void*);
+// extern void sdrplay_event_callback(sdrplay_api_EventT, sdrplay_api_TunerSelectT, sdrplay_api_EventParamsT*, void*);
+import "C"
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"unsafe"
+
+	"github.com/mattn/go-pointer"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/stream"
+)
+
+const (
+	// ringSlots is the number of callbacks worth of samples which are
+	// buffered between the API service and the reader.
+	ringSlots = 64
+
+	// ringSlotLength is the number of samples in each ring slot. Callbacks
+	// handing over more than this are split over more than one slot.
+	ringSlotLength = 8 * 1024
+)
+
+type callbackContext struct {
+	ctx  context.Context
+	ring *stream.RingBuffer
+	dev  *C.sdrplay_api_DeviceT
+	buf  sdr.SamplesI16
+}
+
+// rxStream is the stream started by StartRx. It's kept on the Sdr, so that
+// either closing the returned ReadCloser or closing the Sdr will tear it
+// down, whichever happens first.
+type rxStream struct {
+	cancel context.CancelFunc
+	ring   *stream.RingBuffer
+	state  unsafe.Pointer
+
+	once *sync.Once
+	err  error
+}
+
+// close will stop the API service, wake up any blocked reads, and drop the
+// callback state. Only the first call does anything.
+func (rs *rxStream) close(s *Sdr) error {
+	rs.once.Do(func() {
+		rs.cancel()
+		if s.rx == rs {
+			s.rx = nil
+		}
+		rs.err = rvToErr(C.sdrplay_api_Uninit(s.dev.dev))
+		// Only once the stream has been torn down is it safe to drop the
+		// callback state, since the API service may call in until Uninit
+		// returns.
+		pointer.Unref(rs.state)
+		rs.ring.Close()
+	})
+	return rs.err
+}
+
+type rx struct {
+	sdr.ReadCloser
+
+	stream *rxStream
+	s      *Sdr
+}
+
+func (rx rx) Close() error {
+	if err := rx.stream.close(rx.s); err != nil {
+		return fmt.Errorf("sdrplay rx.Close(): %s", err)
+	}
+	return nil
+}
+
+//export sdrplayStreamCallback
+func sdrplayStreamCallback(
+	xi, xq *C.short,
+	params *C.sdrplay_api_StreamCbParamsT,
+	numSamples, reset C.uint,
+	cbContext unsafe.Pointer,
+) {
+	context, ok := pointer.Restore(cbContext).(*callbackContext)
+	if !ok {
+		return
+	}
+
+	// A panic here would unwind through the SDRplay API and take down the
+	// whole process, so we'll close the ring instead, which will cause the
+	// reader to get an ErrDriverPanic.
+	defer sdr.RecoverDriverPanic(context.ring)
+
+	// There's no way to tell the API service to stop from within the
+	// callback, so once we're done, we'll just drop samples until Uninit
+	// is called.
+	if context.ctx.Err() != nil {
+		return
+	}
+
+	n := int(numSamples)
+	if n == 0 {
+		return
+	}
+	if cap(context.buf) < n {
+		context.buf = make(sdr.SamplesI16, n)
+	}
+	buf := context.buf[:n]
+
+	// The API hands us I and Q as two separate arrays, but the rest of
+	// hz.tools/sdr expects interleaved IQ.
+	is := (*[1 << 28]int16)(unsafe.Pointer(xi))[:n:n]
+	qs := (*[1 << 28]int16)(unsafe.Pointer(xq))[:n:n]
+	for i := range buf {
+		buf[i] = [2]int16{is[i], qs[i]}
+	}
+
+	// This is called on the API service's thread, so it must not block on
+	// the reader. The ring never blocks a Write; if the reader has fallen
+	// too far behind, the ring is closed with an ErrRingBufferOverrun, and
+	// samples are dropped until Uninit is called.
+	for len(buf) > 0 {
+		chunk := buf
+		if len(chunk) > ringSlotLength {
+			chunk = chunk[:ringSlotLength]
+		}
+		if _, err := context.ring.Write(chunk); err != nil {
+			return
+		}
+		buf = buf[len(chunk):]
+	}
+}
+
+//export sdrplayEventCallback
+func sdrplayEventCallback(
+	eventID C.sdrplay_api_EventT,
+	tuner C.sdrplay_api_TunerSelectT,
+	params *C.sdrplay_api_EventParamsT,
+	cbContext unsafe.Pointer,
+) {
+	context, ok := pointer.Restore(cbContext).(*callbackContext)
+	if !ok {
+		return
+	}
+
+	switch eventID {
+	case C.sdrplay_api_PowerOverloadChange:
+		// The API service requires that overload notifications be
+		// acknowledged, otherwise it will not send any further ones.
+		C.sdrplay_api_Update(
+			context.dev.dev,
+			tuner,
+			C.sdrplay_api_Update_Ctrl_OverloadMsgAck,
+			C.sdrplay_api_Update_Ext1_None,
+		)
+	case C.sdrplay_api_DeviceRemoved:
+		context.ring.CloseWithError(fmt.Errorf("sdrplay: device removed"))
+	}
+}
+
+// StartRx implements the sdr.Receiver interface.
+//
+// Samples are buffered in a stream.RingBuffer between the API service and
+// the reader. If the reader falls further behind than that, reads will
+// return a stream.ErrRingBufferOverrun once the buffered samples have been
+// read.
+//
+// Only Tuner A is supported; on devices with two tuners, the second is
+// never enabled, so the Tuner B stream callback (which shares the Tuner A
+// callback) is never invoked.
+func (s *Sdr) StartRx() (sdr.ReadCloser, error) {
+	if s.rx != nil {
+		return nil, fmt.Errorf("sdrplay.Sdr.StartRx: already streaming")
+	}
+
+	sps, err := s.GetSampleRate()
+	if err != nil {
+		return nil, err
+	}
+
+	ring, err := stream.NewRingBuffer(sps, sdr.SampleFormatI16, stream.RingBufferOptions{
+		Slots:          ringSlots,
+		SlotLength:     ringSlotLength,
+		BlockReads:     true,
+		CloseOnOverrun: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	reader, err := stream.RingBufferReader(ring)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cc := &callbackContext{
+		ctx:  ctx,
+		ring: ring,
+		dev:  s.dev,
+	}
+	state := pointer.Save(cc)
+
+	fns := C.sdrplay_api_CallbackFnsT{
+		StreamACbFn: C.sdrplay_api_StreamCallback_t(C.sdrplay_stream_callback),
+		StreamBCbFn: C.sdrplay_api_StreamCallback_t(C.sdrplay_stream_callback),
+		EventCbFn:   C.sdrplay_api_EventCallback_t(C.sdrplay_event_callback),
+	}
+
+	if err := rvToErr(C.sdrplay_api_Init(s.dev.dev, &fns, state)); err != nil {
+		cancel()
+		pointer.Unref(state)
+		return nil, fmt.Errorf("sdrplay.Sdr.StartRx: %s", err)
+	}
+	s.rx = &rxStream{
+		cancel: cancel,
+		ring:   ring,
+		state:  state,
+		once:   &sync.Once{},
+	}
+
+	return rx{
+		ReadCloser: reader,
+		stream:     s.rx,
+		s:          s,
+	}, nil
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,49 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sdrplay
+
+// #include <sdrplay_api.h>
+//
+// extern void sdrplayStreamCallback(short*, short*, sdrplay_api_StreamCbParamsT*, unsigned int, unsigned int, void*);
+// extern void sdrplayEventCallback(sdrplay_api_EventT, sdrplay_api_TunerSelectT, sdrplay_api_EventParamsT*, void*);
+//
+// void sdrplay_stream_callback(
+//   short *xi,
+//   short *xq,
+//   sdrplay_api_StreamCbParamsT *params,
+//   unsigned int numSamples,
+//   unsigned int reset,
+//   void *cbContext
+// ) {
+//   sdrplayStreamCallback(xi, xq, params, numSamples, reset, cbContext);
+// }
+//
+// void sdrplay_event_callback(
+//   sdrplay_api_EventT eventId,
+//   sdrplay_api_TunerSelectT tuner,
+//   sdrplay_api_EventParamsT *params,
+//   void *cbContext
+// ) {
+//   sdrplayEventCallback(eventId, tuner, params, cbContext);
+// }
+import "C"
+
+// vim: foldmethod=marker
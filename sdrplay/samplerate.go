@@ -0,0 +1,115 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sdrplay
+
+// #include <sdrplay_api.h>
+import "C"
+
+import (
+	"fmt"
+)
+
+const (
+	minADCRate = 2000000
+	maxADCRate = 10660000
+	maxDecim   = 32
+)
+
+// bandwidths are the IF filter bandwidths the tuner supports, in order.
+var bandwidths = []struct {
+	hz uint
+	bw C.sdrplay_api_Bw_MHzT
+}{
+	{200000, C.sdrplay_api_BW_0_200},
+	{300000, C.sdrplay_api_BW_0_300},
+	{600000, C.sdrplay_api_BW_0_600},
+	{1536000, C.sdrplay_api_BW_1_536},
+	{5000000, C.sdrplay_api_BW_5_000},
+	{6000000, C.sdrplay_api_BW_6_000},
+	{7000000, C.sdrplay_api_BW_7_000},
+	{8000000, C.sdrplay_api_BW_8_000},
+}
+
+// bandwidthFor will return the widest IF filter that fits within the
+// provided sample rate.
+func bandwidthFor(sampleRate uint) C.sdrplay_api_Bw_MHzT {
+	bw := bandwidths[0].bw
+	for _, b := range bandwidths {
+		if b.hz > sampleRate {
+			break
+		}
+		bw = b.bw
+	}
+	return bw
+}
+
+// SetSampleRate implements the sdr.Sdr interface.
+//
+// The RSP ADC runs between 2 and 10.66 MSPS; lower rates are reached by
+// running the ADC at a power-of-two multiple of the requested rate, and
+// using the API service's decimator to bring it back down.
+func (s *Sdr) SetSampleRate(sampleRate uint) error {
+	decim := uint(1)
+	for sampleRate*decim < minADCRate && decim < maxDecim {
+		decim *= 2
+	}
+	adcRate := sampleRate * decim
+	if adcRate < minADCRate || adcRate > maxADCRate {
+		return fmt.Errorf("sdrplay.Sdr.SetSampleRate: %d is out of range", sampleRate)
+	}
+
+	var (
+		rx     = s.rxParams()
+		reason = C.sdrplay_api_Update_Dev_Fs |
+			C.sdrplay_api_Update_Ctrl_Decimation |
+			C.sdrplay_api_Update_Tuner_BwType
+	)
+
+	s.params.devParams.fsFreq.fsHz = C.double(adcRate)
+	rx.tunerParams.bwType = bandwidthFor(sampleRate)
+	if decim == 1 {
+		rx.ctrlParams.decimation.enable = 0
+		rx.ctrlParams.decimation.decimationFactor = 1
+	} else {
+		rx.ctrlParams.decimation.enable = 1
+		rx.ctrlParams.decimation.decimationFactor = C.uchar(decim)
+		rx.ctrlParams.decimation.wideBandSignal = 1
+	}
+
+	if err := s.update(C.sdrplay_api_ReasonForUpdateT(reason)); err != nil {
+		return fmt.Errorf("sdrplay.Sdr.SetSampleRate: %s", err)
+	}
+	return nil
+}
+
+// GetSampleRate implements the sdr.Sdr interface.
+func (s *Sdr) GetSampleRate() (uint, error) {
+	var (
+		rx      = s.rxParams()
+		adcRate = uint(s.params.devParams.fsFreq.fsHz)
+	)
+	if rx.ctrlParams.decimation.enable == 0 {
+		return adcRate, nil
+	}
+	return adcRate / uint(rx.ctrlParams.decimation.decimationFactor), nil
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,330 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sdrplay
+
+// #cgo LDFLAGS: -lsdrplay_api
+//
+// #include <stdlib.h>
+// #include <sdrplay_api.h>
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/debug"
+)
+
+func init() {
+	debug.RegisterRadioDriver("hz.tools/sdr/sdrplay.Sdr")
+	sdr.RegisterSerialOpener("sdrplay", func(serial string) (sdr.Sdr, error) {
+		return OpenBySerial(serial)
+	})
+	sdr.RegisterEnumerator("sdrplay", sdr.ListEnumerator(List))
+}
+
+func rvToErr(rv C.sdrplay_api_ErrT) error {
+	if rv != C.sdrplay_api_Success {
+		errString := C.GoString(C.sdrplay_api_GetErrorString(rv))
+		return fmt.Errorf("sdrplay: %s (code: %d)", errString, int(rv))
+	}
+	return nil
+}
+
+// Model represents the type of RSP hardware, as reported by the hwVer
+// field of the SDRplay API.
+type Model uint8
+
+var (
+	// ModelRSP1 is the original RSP1.
+	ModelRSP1 Model = C.SDRPLAY_RSP1_ID
+
+	// ModelRSP1A is the RSP1A.
+	ModelRSP1A Model = C.SDRPLAY_RSP1A_ID
+
+	// ModelRSP2 is the RSP2 (and RSP2pro).
+	ModelRSP2 Model = C.SDRPLAY_RSP2_ID
+
+	// ModelRSPduo is the dual tuner RSPduo.
+	ModelRSPduo Model = C.SDRPLAY_RSPduo_ID
+
+	// ModelRSPdx is the RSPdx.
+	ModelRSPdx Model = C.SDRPLAY_RSPdx_ID
+)
+
+// String will return a human readable string representing the hardware.
+func (m Model) String() string {
+	switch m {
+	case ModelRSP1:
+		return "RSP1"
+	case ModelRSP1A:
+		return "RSP1A"
+	case ModelRSP2:
+		return "RSP2"
+	case ModelRSPduo:
+		return "RSPduo"
+	case ModelRSPdx:
+		return "RSPdx"
+	default:
+		return "unknown"
+	}
+}
+
+// maxLNAState returns the highest LNA state the model supports in any
+// band. Lower bands support fewer states; the API service will reject
+// states that are out of range for the tuned frequency.
+func (m Model) maxLNAState() int {
+	switch m {
+	case ModelRSP1:
+		return 3
+	case ModelRSP2:
+		return 8
+	case ModelRSPdx:
+		return 27
+	default:
+		return 9
+	}
+}
+
+// APIVersion will return the version of the SDRplay API service, as
+// reported by the service itself.
+func APIVersion() (float32, error) {
+	if err := rvToErr(C.sdrplay_api_Open()); err != nil {
+		return 0, err
+	}
+	defer C.sdrplay_api_Close()
+
+	var ver C.float
+	if err := rvToErr(C.sdrplay_api_ApiVersion(&ver)); err != nil {
+		return 0, err
+	}
+	return float32(ver), nil
+}
+
+// devices will return all RSP devices known to the API service. The
+// API must be open, and the device API must be locked.
+func devices() ([]C.sdrplay_api_DeviceT, error) {
+	var (
+		devs = make([]C.sdrplay_api_DeviceT, C.SDRPLAY_MAX_DEVICES)
+		ndev C.uint
+	)
+	if err := rvToErr(C.sdrplay_api_GetDevices(
+		&devs[0], &ndev, C.uint(len(devs)),
+	)); err != nil {
+		return nil, err
+	}
+	return devs[:int(ndev)], nil
+}
+
+func deviceInfo(dev *C.sdrplay_api_DeviceT) sdr.HardwareInfo {
+	return sdr.HardwareInfo{
+		Manufacturer: "SDRplay",
+		Product:      Model(dev.hwVer).String(),
+		Serial:       C.GoString(&dev.SerNo[0]),
+	}
+}
+
+// List will return the sdr.HardwareInfo for all RSP devices that the
+// SDRplay API service can see.
+func List() ([]sdr.HardwareInfo, error) {
+	if err := rvToErr(C.sdrplay_api_Open()); err != nil {
+		return nil, err
+	}
+	defer C.sdrplay_api_Close()
+
+	C.sdrplay_api_LockDeviceApi()
+	defer C.sdrplay_api_UnlockDeviceApi()
+
+	devs, err := devices()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := []sdr.HardwareInfo{}
+	for i := range devs {
+		ret = append(ret, deviceInfo(&devs[i]))
+	}
+	return ret, nil
+}
+
+// OpenBySerial will open an RSP by its Serial Number.
+func OpenBySerial(serial string) (*Sdr, error) {
+	return open(&serial)
+}
+
+// Open will open the first RSP the API service comes across.
+func Open() (*Sdr, error) {
+	return open(nil)
+}
+
+func open(serial *string) (*Sdr, error) {
+	if err := rvToErr(C.sdrplay_api_Open()); err != nil {
+		return nil, err
+	}
+
+	s, err := selectDevice(serial)
+	if err != nil {
+		C.sdrplay_api_Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func selectDevice(serial *string) (*Sdr, error) {
+	C.sdrplay_api_LockDeviceApi()
+	defer C.sdrplay_api_UnlockDeviceApi()
+
+	devs, err := devices()
+	if err != nil {
+		return nil, err
+	}
+
+	var dev *C.sdrplay_api_DeviceT
+	for i := range devs {
+		if serial == nil || C.GoString(&devs[i].SerNo[0]) == *serial {
+			dev = &devs[i]
+			break
+		}
+	}
+	if dev == nil {
+		if serial == nil {
+			return nil, fmt.Errorf("sdrplay: no devices found")
+		}
+		return nil, fmt.Errorf("sdrplay: no device matching serial %q found", *serial)
+	}
+
+	if Model(dev.hwVer) == ModelRSPduo {
+		dev.tuner = C.sdrplay_api_Tuner_A
+		dev.rspDuoMode = C.sdrplay_api_RspDuoMode_Single_Tuner
+	}
+
+	// The device struct is copied into C memory, since the API service
+	// holds on to it for the lifetime of the selection.
+	cdev := (*C.sdrplay_api_DeviceT)(C.malloc(C.sizeof_sdrplay_api_DeviceT))
+	*cdev = *dev
+
+	if err := rvToErr(C.sdrplay_api_SelectDevice(cdev)); err != nil {
+		C.free(unsafe.Pointer(cdev))
+		return nil, err
+	}
+
+	var params *C.sdrplay_api_DeviceParamsT
+	if err := rvToErr(C.sdrplay_api_GetDeviceParams(cdev.dev, &params)); err != nil {
+		C.sdrplay_api_ReleaseDevice(cdev)
+		C.free(unsafe.Pointer(cdev))
+		return nil, err
+	}
+
+	s := &Sdr{
+		dev:    cdev,
+		params: params,
+		model:  Model(cdev.hwVer),
+		info:   deviceInfo(cdev),
+	}
+
+	// Start off with the IF AGC disabled, and zero IF, so that what we get
+	// out is baseband IQ at the configured sample rate.
+	rx := s.rxParams()
+	rx.tunerParams.ifType = C.sdrplay_api_IF_Zero
+	rx.ctrlParams.agc.enable = C.sdrplay_api_AGC_DISABLE
+
+	return s, nil
+}
+
+// Sdr is an SDRplay RSP device attached to the host.
+type Sdr struct {
+	dev    *C.sdrplay_api_DeviceT
+	params *C.sdrplay_api_DeviceParamsT
+	model  Model
+	info   sdr.HardwareInfo
+
+	// rx is the stream started by StartRx, or nil if the Sdr isn't
+	// streaming.
+	rx *rxStream
+}
+
+// Model will return the type of RSP that this Sdr is.
+func (s *Sdr) Model() Model {
+	return s.model
+}
+
+// rxParams returns the channel parameters for Tuner A, which is the only
+// tuner in use.
+func (s *Sdr) rxParams() *C.sdrplay_api_RxChannelParamsT {
+	return s.params.rxChannelA
+}
+
+// update will push a parameter change to the API service. Before the
+// stream has been started, parameters are read on Init, so this is a no-op.
+func (s *Sdr) update(reason C.sdrplay_api_ReasonForUpdateT) error {
+	if s.rx == nil {
+		return nil
+	}
+	return rvToErr(C.sdrplay_api_Update(
+		s.dev.dev,
+		s.dev.tuner,
+		reason,
+		C.sdrplay_api_Update_Ext1_None,
+	))
+}
+
+// SetCenterFrequency implements the sdr.Sdr interface
+func (s *Sdr) SetCenterFrequency(cf rf.Hz) error {
+	s.rxParams().tunerParams.rfFreq.rfHz = C.double(cf)
+	if err := s.update(C.sdrplay_api_Update_Tuner_Frf); err != nil {
+		return fmt.Errorf("sdrplay.Sdr.SetCenterFrequency: %s", err)
+	}
+	return nil
+}
+
+// GetCenterFrequency implements the sdr.Sdr interface.
+func (s *Sdr) GetCenterFrequency() (rf.Hz, error) {
+	return rf.Hz(s.rxParams().tunerParams.rfFreq.rfHz), nil
+}
+
+// Close implements the sdr.Sdr interface. If the Sdr is still streaming,
+// the stream is torn down first, and reads will return sdr.ErrPipeClosed
+// once the buffered samples have been read.
+func (s *Sdr) Close() error {
+	if s.rx != nil {
+		s.rx.close(s)
+	}
+	C.sdrplay_api_LockDeviceApi()
+	err := rvToErr(C.sdrplay_api_ReleaseDevice(s.dev))
+	C.sdrplay_api_UnlockDeviceApi()
+	C.free(unsafe.Pointer(s.dev))
+	C.sdrplay_api_Close()
+	return err
+}
+
+// HardwareInfo implements the sdr.Sdr interface.
+func (s *Sdr) HardwareInfo() sdr.HardwareInfo {
+	return s.info
+}
+
+// SampleFormat implements the sdr.Sdr interface.
+func (s *Sdr) SampleFormat() sdr.SampleFormat {
+	return sdr.SampleFormatI16
+}
+
+// vim: foldmethod=marker
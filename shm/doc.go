@@ -0,0 +1,36 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+// Package shm contains a shared memory transport for IQ samples, so that
+// two processes on the same host can exchange samples at rates where
+// copying them through a socket would be the bottleneck.
+//
+// A Writer creates a named ring buffer (a file in Dir, usually /dev/shm,
+// mapped into memory by both processes), and any number of processes may
+// find it by name with List, but only one Reader may be attached at a time.
+// The ring has a small header with the sample rate and format, so the
+// Reader needs nothing but the name to get started.
+//
+// Samples are passed without any copies beyond the one into and out of
+// the ring. When the ring is full, Write will block until the Reader has
+// caught up, just like an sdr.Pipe.
+package shm
+
+// vim: foldmethod=marker
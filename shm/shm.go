@@ -0,0 +1,416 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package shm
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"hz.tools/sdr"
+)
+
+var (
+	// ErrInvalidName will be returned if the name of a ring contains
+	// anything other than letters, numbers, '.', '-' or '_'.
+	ErrInvalidName = fmt.Errorf("shm: invalid ring name")
+
+	// ErrBadHeader will be returned by Open if the file isn't a ring, or
+	// was created by an incompatible version of this package.
+	ErrBadHeader = fmt.Errorf("shm: not a ring, or an incompatible version")
+
+	// ErrReaderAttached will be returned by Open if another Reader is
+	// already attached to the ring.
+	ErrReaderAttached = fmt.Errorf("shm: a reader is already attached")
+)
+
+// EnvDir is the environment variable which, if set, overrides the directory
+// rings are created in. Both processes must agree on it.
+const EnvDir = "HZ_TOOLS_SDR_SHM_DIR"
+
+const (
+	filePrefix = "hz.tools-sdr-"
+	fileSuffix = ".iq"
+
+	magic   = "HZSDRSHM"
+	version = 1
+
+	// headerSize is the size of the header before the ring itself. The
+	// read and write positions are kept on their own cache lines, since
+	// they're written by different processes.
+	headerSize = 4096
+
+	offVersion      = 8
+	offSampleFormat = 12
+	offSampleRate   = 16
+	offCapacity     = 24
+	offWriterClosed = 32
+	offReaderClosed = 36
+	offReaderOpen   = 40
+	offWritePos     = 64
+	offReadPos      = 128
+
+	// pollInterval is how long to sleep between checks for the other
+	// side to make room in (or put samples into) the ring.
+	pollInterval = 50 * time.Microsecond
+)
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// Dir returns the directory rings are created in.
+func Dir() string {
+	if dir := os.Getenv(EnvDir); dir != "" {
+		return dir
+	}
+	if fi, err := os.Stat("/dev/shm"); err == nil && fi.IsDir() {
+		return "/dev/shm"
+	}
+	return os.TempDir()
+}
+
+func path(name string) (string, error) {
+	if !validName.MatchString(name) {
+		return "", ErrInvalidName
+	}
+	return filepath.Join(Dir(), filePrefix+name+fileSuffix), nil
+}
+
+// List will return the names of all rings in Dir, in sorted order.
+func List() ([]string, error) {
+	fis, err := ioutil.ReadDir(Dir())
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, fi := range fis {
+		name := fi.Name()
+		if !strings.HasPrefix(name, filePrefix) || !strings.HasSuffix(name, fileSuffix) {
+			continue
+		}
+		names = append(names, strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), fileSuffix))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ring is the shared state of a mapped ring, from either side.
+//
+// Reads and Writes hold lock for reading, and Close will take it for
+// writing before the ring is unmapped, so that a Read or Write in another
+// goroutine never touches the ring after that. closed is guarded by lock.
+type ring struct {
+	lock   *sync.RWMutex
+	closed bool
+
+	mem          []byte
+	data         []byte
+	capacity     uint64
+	sampleRate   uint
+	sampleFormat sdr.SampleFormat
+}
+
+func (r *ring) uint32(off int) *uint32 {
+	return (*uint32)(unsafe.Pointer(&r.mem[off]))
+}
+
+func (r *ring) uint64(off int) *uint64 {
+	return (*uint64)(unsafe.Pointer(&r.mem[off]))
+}
+
+// SampleRate implements the sdr.Reader and sdr.Writer interfaces.
+func (r *ring) SampleRate() uint {
+	return r.sampleRate
+}
+
+// SampleFormat implements the sdr.Reader and sdr.Writer interfaces.
+func (r *ring) SampleFormat() sdr.SampleFormat {
+	return r.sampleFormat
+}
+
+// copyIn will copy buf into the ring at pos, wrapping around the end.
+func (r *ring) copyIn(pos uint64, buf []byte) {
+	off := pos % r.capacity
+	n := copy(r.data[off:], buf)
+	copy(r.data, buf[n:])
+}
+
+// copyOut will copy from the ring at pos into buf, wrapping around the end.
+func (r *ring) copyOut(pos uint64, buf []byte) {
+	off := pos % r.capacity
+	n := copy(buf, r.data[off:])
+	copy(buf[n:], r.data)
+}
+
+// Writer is the sdr.WriteCloser side of a ring.
+type Writer struct {
+	ring
+	path string
+}
+
+// Create will create a new ring with the provided name, able to hold
+// capacity samples, and return the Writer side of it. The ring will be
+// removed when the Writer is closed, although a Reader that's attached
+// will be able to read everything that was written.
+func Create(name string, sampleRate uint, sampleFormat sdr.SampleFormat, capacity int) (*Writer, error) {
+	path, err := path(name)
+	if err != nil {
+		return nil, err
+	}
+	if capacity <= 0 {
+		return nil, fmt.Errorf("shm: capacity must be positive")
+	}
+	size := headerSize + capacity*sampleFormat.Size()
+
+	// The ring is set up under a temporary name, and then moved into
+	// place, so a Reader will never find a half-written header.
+	fd, err := ioutil.TempFile(Dir(), "."+filePrefix)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	if err := fd.Truncate(int64(size)); err != nil {
+		os.Remove(fd.Name())
+		return nil, err
+	}
+	mem, err := mmap(fd, size)
+	if err != nil {
+		os.Remove(fd.Name())
+		return nil, err
+	}
+
+	w := &Writer{
+		ring: ring{
+			lock:         &sync.RWMutex{},
+			mem:          mem,
+			data:         mem[headerSize:],
+			capacity:     uint64(capacity * sampleFormat.Size()),
+			sampleRate:   sampleRate,
+			sampleFormat: sampleFormat,
+		},
+		path: path,
+	}
+	copy(mem, magic)
+	*w.uint32(offVersion) = version
+	*w.uint32(offSampleFormat) = uint32(sampleFormat)
+	*w.uint64(offSampleRate) = uint64(sampleRate)
+	*w.uint64(offCapacity) = w.capacity
+
+	if err := os.Rename(fd.Name(), path); err != nil {
+		munmap(mem)
+		os.Remove(fd.Name())
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write implements the sdr.Writer interface.
+func (w *Writer) Write(s sdr.Samples) (int, error) {
+	if s.Format() != w.sampleFormat {
+		return 0, sdr.ErrSampleFormatMismatch
+	}
+	buf := sdr.MustUnsafeSamplesAsBytes(s)
+	size := uint64(w.sampleFormat.Size())
+
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	if w.closed {
+		return 0, sdr.ErrPipeClosed
+	}
+
+	var written int
+	for len(buf) > 0 {
+		if atomic.LoadUint32(w.uint32(offWriterClosed)) != 0 {
+			return written, sdr.ErrPipeClosed
+		}
+		if atomic.LoadUint32(w.uint32(offReaderClosed)) != 0 {
+			return written, sdr.ErrPipeClosed
+		}
+
+		writePos := atomic.LoadUint64(w.uint64(offWritePos))
+		readPos := atomic.LoadUint64(w.uint64(offReadPos))
+		free := w.capacity - (writePos - readPos)
+		if free == 0 {
+			time.Sleep(pollInterval)
+			continue
+		}
+		if free > uint64(len(buf)) {
+			free = uint64(len(buf))
+		}
+		w.copyIn(writePos, buf[:free])
+		atomic.StoreUint64(w.uint64(offWritePos), writePos+free)
+
+		buf = buf[free:]
+		written += int(free / size)
+	}
+	return written, nil
+}
+
+// Close implements the sdr.Closer interface. The Reader will be able to
+// read any samples left in the ring, and will then get an io.EOF.
+func (w *Writer) Close() error {
+	w.lock.RLock()
+	if w.closed {
+		w.lock.RUnlock()
+		return nil
+	}
+	atomic.StoreUint32(w.uint32(offWriterClosed), 1)
+	w.lock.RUnlock()
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	err := os.Remove(w.path)
+	if uerr := munmap(w.mem); err == nil {
+		err = uerr
+	}
+	return err
+}
+
+// Reader is the sdr.ReadCloser side of a ring.
+type Reader struct {
+	ring
+}
+
+// Open will attach to the ring with the provided name, created by Create.
+func Open(name string) (*Reader, error) {
+	path, err := path(name)
+	if err != nil {
+		return nil, err
+	}
+
+	fd, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	fi, err := fd.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Size() < headerSize {
+		return nil, ErrBadHeader
+	}
+	mem, err := mmap(fd, int(fi.Size()))
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Reader{ring: ring{
+		lock: &sync.RWMutex{},
+		mem:  mem,
+		data: mem[headerSize:],
+	}}
+	if string(mem[:len(magic)]) != magic || *r.uint32(offVersion) != version {
+		munmap(mem)
+		return nil, ErrBadHeader
+	}
+	r.sampleFormat = sdr.SampleFormat(*r.uint32(offSampleFormat))
+	r.sampleRate = uint(*r.uint64(offSampleRate))
+	r.capacity = *r.uint64(offCapacity)
+	if r.capacity != uint64(len(r.data)) || r.sampleFormat.Size() == 0 {
+		munmap(mem)
+		return nil, ErrBadHeader
+	}
+
+	if !atomic.CompareAndSwapUint32(r.uint32(offReaderOpen), 0, 1) {
+		munmap(mem)
+		return nil, ErrReaderAttached
+	}
+	return r, nil
+}
+
+// Read implements the sdr.Reader interface. Read will block until at least
+// one sample is able to be read.
+func (r *Reader) Read(s sdr.Samples) (int, error) {
+	if s.Format() != r.sampleFormat {
+		return 0, sdr.ErrSampleFormatMismatch
+	}
+	buf := sdr.MustUnsafeSamplesAsBytes(s)
+
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	if r.closed {
+		return 0, sdr.ErrPipeClosed
+	}
+
+	for {
+		if atomic.LoadUint32(r.uint32(offReaderClosed)) != 0 {
+			return 0, sdr.ErrPipeClosed
+		}
+
+		// The writer closed flag has to be checked before the position,
+		// so that nothing written before the Close is missed.
+		closed := atomic.LoadUint32(r.uint32(offWriterClosed)) != 0
+		readPos := atomic.LoadUint64(r.uint64(offReadPos))
+		writePos := atomic.LoadUint64(r.uint64(offWritePos))
+
+		available := writePos - readPos
+		if available == 0 {
+			if closed {
+				return 0, io.EOF
+			}
+			time.Sleep(pollInterval)
+			continue
+		}
+		if available > uint64(len(buf)) {
+			available = uint64(len(buf))
+		}
+		r.copyOut(readPos, buf[:available])
+		atomic.StoreUint64(r.uint64(offReadPos), readPos+available)
+		return int(available) / r.sampleFormat.Size(), nil
+	}
+}
+
+// Close implements the sdr.Closer interface. Any Write in progress on the
+// other side will return an sdr.ErrPipeClosed.
+func (r *Reader) Close() error {
+	r.lock.RLock()
+	if r.closed {
+		r.lock.RUnlock()
+		return nil
+	}
+	atomic.StoreUint32(r.uint32(offReaderClosed), 1)
+	r.lock.RUnlock()
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	return munmap(r.mem)
+}
+
+// vim: foldmethod=marker
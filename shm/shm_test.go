@@ -0,0 +1,143 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package shm_test
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/shm"
+)
+
+func tempDir(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "shm")
+	assert.NoError(t, err)
+	os.Setenv(shm.EnvDir, dir)
+	return func() {
+		os.Unsetenv(shm.EnvDir)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestRing(t *testing.T) {
+	defer tempDir(t)()
+
+	w, err := shm.Create("test-ring", 1000000, sdr.SampleFormatI16, 1000)
+	assert.NoError(t, err)
+
+	names, err := shm.List()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"test-ring"}, names)
+
+	r, err := shm.Open("test-ring")
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1000000), r.SampleRate())
+	assert.Equal(t, sdr.SampleFormatI16, r.SampleFormat())
+
+	_, err = shm.Open("test-ring")
+	assert.Equal(t, shm.ErrReaderAttached, err)
+
+	// Write a lot more than the ring holds, in odd sized chunks, so that
+	// the ring wraps around part way through a buffer.
+	go func() {
+		buf := make(sdr.SamplesI16, 333)
+		var n int16
+		for i := 0; i < 90; i++ {
+			for j := range buf {
+				buf[j] = [2]int16{n, -n}
+				n++
+			}
+			_, err := w.Write(buf)
+			assert.NoError(t, err)
+		}
+		assert.NoError(t, w.Close())
+	}()
+
+	buf := make(sdr.SamplesI16, 256)
+	var expected int16
+	for {
+		n, err := r.Read(buf)
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		for _, sample := range buf[:n] {
+			assert.Equal(t, [2]int16{expected, -expected}, sample)
+			expected++
+		}
+	}
+	assert.Equal(t, int16(29970), expected)
+	assert.NoError(t, r.Close())
+
+	names, err = shm.List()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, names)
+}
+
+func TestRingReaderClosed(t *testing.T) {
+	defer tempDir(t)()
+
+	w, err := shm.Create("closed", 1000, sdr.SampleFormatC64, 10)
+	assert.NoError(t, err)
+	defer w.Close()
+
+	r, err := shm.Open("closed")
+	assert.NoError(t, err)
+
+	_, err = w.Write(make(sdr.SamplesC64, 10))
+	assert.NoError(t, err)
+
+	_, err = r.Read(make(sdr.SamplesI8, 10))
+	assert.Equal(t, sdr.ErrSampleFormatMismatch, err)
+
+	assert.NoError(t, r.Close())
+	_, err = r.Read(make(sdr.SamplesC64, 10))
+	assert.Equal(t, sdr.ErrPipeClosed, err)
+
+	// The ring is full, and nobody will ever read it.
+	_, err = w.Write(make(sdr.SamplesC64, 10))
+	assert.Equal(t, sdr.ErrPipeClosed, err)
+}
+
+func TestRingNames(t *testing.T) {
+	defer tempDir(t)()
+
+	_, err := shm.Create("../escape", 1000, sdr.SampleFormatC64, 10)
+	assert.Equal(t, shm.ErrInvalidName, err)
+
+	_, err = shm.Open("missing")
+	assert.True(t, os.IsNotExist(err))
+
+	assert.NoError(t, ioutil.WriteFile(
+		shm.Dir()+"/hz.tools-sdr-bogus.iq",
+		make([]byte, 8192),
+		0644,
+	))
+	_, err = shm.Open("bogus")
+	assert.Equal(t, shm.ErrBadHeader, err)
+}
+
+// vim: foldmethod=marker
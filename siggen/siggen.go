@@ -0,0 +1,222 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package siggen
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/stream"
+)
+
+var (
+	// ErrNoSampleRate will be returned if a generator is created without a
+	// SampleRate.
+	ErrNoSampleRate = fmt.Errorf("siggen: no sample rate set")
+)
+
+const tau = math.Pi * 2
+
+// Config contains the parameters shared by all the generators.
+type Config struct {
+	// SampleRate is the number of samples per second to generate. This must
+	// be set.
+	SampleRate uint
+
+	// SampleFormat is the format of the generated samples. If 0, this will
+	// default to sdr.SampleFormatC64.
+	SampleFormat sdr.SampleFormat
+
+	// Amplitude is the peak amplitude of the CW and Chirp generators, where
+	// 1 is full scale. If 0, this will default to 1.
+	Amplitude float64
+}
+
+func (c Config) getSampleFormat() sdr.SampleFormat {
+	if c.SampleFormat == 0 {
+		return sdr.SampleFormatC64
+	}
+	return c.SampleFormat
+}
+
+func (c Config) getAmplitude() float64 {
+	if c.Amplitude == 0 {
+		return 1
+	}
+	return c.Amplitude
+}
+
+type reader struct {
+	sampleRate uint
+	fill       func(sdr.SamplesC64)
+}
+
+func (r *reader) SampleFormat() sdr.SampleFormat {
+	return sdr.SampleFormatC64
+}
+
+func (r *reader) SampleRate() uint {
+	return r.sampleRate
+}
+
+func (r *reader) Read(s sdr.Samples) (int, error) {
+	sC64, ok := s.(sdr.SamplesC64)
+	if !ok {
+		return 0, sdr.ErrSampleFormatMismatch
+	}
+	r.fill(sC64)
+	return len(sC64), nil
+}
+
+// newReader will create a Reader from the fill function, converted to the
+// SampleFormat in the Config.
+func newReader(cfg Config, fill func(sdr.SamplesC64)) (sdr.Reader, error) {
+	if cfg.SampleRate == 0 {
+		return nil, ErrNoSampleRate
+	}
+	r := &reader{sampleRate: cfg.SampleRate, fill: fill}
+	if cfg.getSampleFormat() == sdr.SampleFormatC64 {
+		return r, nil
+	}
+	return stream.ConvertReader(r, cfg.getSampleFormat())
+}
+
+// oscillator keeps track of the phase of a carrier from one sample to the
+// next, wrapping it to keep the precision from running away.
+type oscillator struct {
+	phase float64
+}
+
+func (o *oscillator) next(step float64) float64 {
+	phase := o.phase
+	o.phase = math.Mod(o.phase+step, tau)
+	return phase
+}
+
+// CW will generate a carrier at the provided frequency, relative to the
+// center frequency.
+func CW(cfg Config, freq rf.Hz) (sdr.Reader, error) {
+	var (
+		amplitude = cfg.getAmplitude()
+		step      = tau * float64(freq) / float64(cfg.SampleRate)
+		osc       = oscillator{}
+	)
+	return newReader(cfg, func(buf sdr.SamplesC64) {
+		for i := range buf {
+			sin, cos := math.Sincos(osc.next(step))
+			buf[i] = complex64(complex(amplitude*cos, amplitude*sin))
+		}
+	})
+}
+
+// Tone is a single carrier, as part of a MultiTone generator.
+type Tone struct {
+	// Frequency of the carrier, relative to the center frequency.
+	Frequency rf.Hz
+
+	// Amplitude of the carrier. The power of the Tone is Amplitude squared.
+	Amplitude float64
+
+	// Phase of the carrier at the first sample, in radians.
+	Phase float64
+}
+
+// MultiTone will generate the sum of all the provided Tones. The Amplitude
+// in the Config is not used, since each Tone has its own; the sum of the
+// amplitudes should be kept under 1 to avoid clipping.
+func MultiTone(cfg Config, tones ...Tone) (sdr.Reader, error) {
+	var (
+		steps = make([]float64, len(tones))
+		oscs  = make([]oscillator, len(tones))
+	)
+	for i, tone := range tones {
+		steps[i] = tau * float64(tone.Frequency) / float64(cfg.SampleRate)
+		oscs[i].phase = math.Mod(tone.Phase, tau)
+	}
+	return newReader(cfg, func(buf sdr.SamplesC64) {
+		for i := range buf {
+			var sample complex128
+			for j, tone := range tones {
+				sin, cos := math.Sincos(oscs[j].next(steps[j]))
+				sample += complex(tone.Amplitude*cos, tone.Amplitude*sin)
+			}
+			buf[i] = complex64(sample)
+		}
+	})
+}
+
+// Chirp will generate a linear chirp, sweeping from the start frequency to
+// the stop frequency (both relative to the center frequency) over the
+// period, and then starting again from the start frequency. The phase is
+// continuous from one sweep to the next.
+func Chirp(cfg Config, start, stop rf.Hz, period time.Duration) (sdr.Reader, error) {
+	length := int(period.Seconds() * float64(cfg.SampleRate))
+	if length <= 0 {
+		return nil, fmt.Errorf("siggen: chirp period is shorter than a sample")
+	}
+
+	var (
+		amplitude = cfg.getAmplitude()
+		startStep = tau * float64(start) / float64(cfg.SampleRate)
+		stepStep  = tau * float64(stop-start) / float64(cfg.SampleRate) / float64(length)
+		osc       = oscillator{}
+		n         int
+	)
+	return newReader(cfg, func(buf sdr.SamplesC64) {
+		for i := range buf {
+			sin, cos := math.Sincos(osc.next(startStep + stepStep*float64(n)))
+			buf[i] = complex64(complex(amplitude*cos, amplitude*sin))
+			n = (n + 1) % length
+		}
+	})
+}
+
+// Noise will generate additive white gaussian noise, with a total power
+// (across both I and Q) of `power`, where a full scale carrier has a power
+// of 1. The noise is not clipped, so very loud noise may clip if converted
+// to an integer SampleFormat.
+//
+// If the source is nil, a fixed seed will be used, so the noise will be
+// the same every time.
+func Noise(cfg Config, power float64, source rand.Source) (sdr.Reader, error) {
+	if power < 0 {
+		return nil, fmt.Errorf("siggen: noise power must not be negative")
+	}
+	if source == nil {
+		source = rand.NewSource(1)
+	}
+
+	var (
+		r      = rand.New(source)
+		stdDev = math.Sqrt(power / 2)
+	)
+	return newReader(cfg, func(buf sdr.SamplesC64) {
+		for i := range buf {
+			buf[i] = complex64(complex(r.NormFloat64()*stdDev, r.NormFloat64()*stdDev))
+		}
+	})
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,140 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package siggen_test
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/siggen"
+)
+
+func read(t *testing.T, r sdr.Reader, n int) sdr.SamplesC64 {
+	buf := make(sdr.SamplesC64, n)
+	_, err := sdr.ReadFull(r, buf)
+	assert.NoError(t, err)
+	return buf
+}
+
+// frequency will return the frequency between two adjacent samples.
+func frequency(a, b complex64, sampleRate uint) float64 {
+	return cmplx.Phase(complex128(b*complex(real(a), -imag(a)))) / (2 * math.Pi) * float64(sampleRate)
+}
+
+func power(buf sdr.SamplesC64) float64 {
+	var ret float64
+	for _, s := range buf {
+		ret += float64(real(s)*real(s) + imag(s)*imag(s))
+	}
+	return ret / float64(len(buf))
+}
+
+func TestCW(t *testing.T) {
+	r, err := siggen.CW(siggen.Config{SampleRate: 1000, Amplitude: 0.5}, rf.Hz(100))
+	assert.NoError(t, err)
+	assert.Equal(t, sdr.SampleFormatC64, r.SampleFormat())
+	assert.Equal(t, uint(1000), r.SampleRate())
+
+	buf := read(t, r, 20)
+	assert.InDelta(t, 0.5, real(buf[0]), 1e-6)
+	for i := 1; i < len(buf); i++ {
+		assert.InDelta(t, 100, frequency(buf[i-1], buf[i], 1000), 1e-3)
+		assert.InDelta(t, 0.5, cmplx.Abs(complex128(buf[i])), 1e-6)
+	}
+
+	// The phase carries on from the last Read.
+	next := read(t, r, 1)
+	assert.InDelta(t, 100, frequency(buf[len(buf)-1], next[0], 1000), 1e-3)
+}
+
+func TestMultiTone(t *testing.T) {
+	r, err := siggen.MultiTone(
+		siggen.Config{SampleRate: 1024},
+		siggen.Tone{Frequency: 64, Amplitude: 0.5},
+		siggen.Tone{Frequency: -128, Amplitude: 0.25, Phase: 1},
+	)
+	assert.NoError(t, err)
+
+	buf := read(t, r, 1024)
+	assert.InDelta(t, 0.5*0.5+0.25*0.25, power(buf), 1e-4)
+	assert.InDelta(t, 0.5+0.25*math.Cos(1), real(buf[0]), 1e-6)
+}
+
+func TestChirp(t *testing.T) {
+	r, err := siggen.Chirp(siggen.Config{SampleRate: 1000}, -100, 100, time.Second)
+	assert.NoError(t, err)
+
+	buf := read(t, r, 2000)
+	assert.InDelta(t, -100, frequency(buf[0], buf[1], 1000), 1)
+	assert.InDelta(t, 0, frequency(buf[500], buf[501], 1000), 1)
+	assert.InDelta(t, 100, frequency(buf[998], buf[999], 1000), 1)
+
+	// And back to the start.
+	assert.InDelta(t, -100, frequency(buf[1000], buf[1001], 1000), 1)
+
+	_, err = siggen.Chirp(siggen.Config{SampleRate: 1000}, 0, 1, time.Microsecond)
+	assert.Error(t, err)
+}
+
+func TestNoise(t *testing.T) {
+	r, err := siggen.Noise(siggen.Config{SampleRate: 1000}, 0.01, nil)
+	assert.NoError(t, err)
+
+	buf := read(t, r, 1024*64)
+	assert.InDelta(t, 0.01, power(buf), 0.001)
+
+	// The default source is deterministic.
+	r, err = siggen.Noise(siggen.Config{SampleRate: 1000}, 0.01, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, buf[:16], read(t, r, 16))
+
+	_, err = siggen.Noise(siggen.Config{SampleRate: 1000}, -1, nil)
+	assert.Error(t, err)
+}
+
+func TestFormat(t *testing.T) {
+	r, err := siggen.CW(siggen.Config{
+		SampleRate:   1000,
+		SampleFormat: sdr.SampleFormatI16,
+		Amplitude:    0.5,
+	}, rf.Hz(0))
+	assert.NoError(t, err)
+	assert.Equal(t, sdr.SampleFormatI16, r.SampleFormat())
+
+	buf := make(sdr.SamplesI16, 4)
+	_, err = sdr.ReadFull(r, buf)
+	assert.NoError(t, err)
+	assert.InDelta(t, math.MaxInt16/2, buf[0][0], 2)
+	assert.Equal(t, int16(0), buf[0][1])
+}
+
+func TestNoSampleRate(t *testing.T) {
+	_, err := siggen.CW(siggen.Config{}, rf.Hz(0))
+	assert.Equal(t, siggen.ErrNoSampleRate, err)
+}
+
+// vim: foldmethod=marker
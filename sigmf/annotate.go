@@ -0,0 +1,121 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sigmf
+
+import (
+	"fmt"
+	"sync"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/decoder"
+)
+
+// Annotator wraps a decoder.Decoder, and turns every Event it emits into an
+// Annotation, located in time by counting the samples passed to Process,
+// and in frequency by the center frequency of the recording.
+//
+// The Annotator is itself a decoder.Decoder, so it can be used with
+// decoder.Run, in place of the Decoder it wraps.
+type Annotator struct {
+	decoder.Decoder
+
+	center rf.Hz
+	start  uint64
+
+	lock        *sync.Mutex
+	processed   uint64
+	annotations []Annotation
+}
+
+// NewAnnotator will create a new Annotator wrapping the provided Decoder.
+// center is the center frequency of the recording, and start is the sample
+// of the recording which will be passed to Process first (usually 0).
+func NewAnnotator(d decoder.Decoder, center rf.Hz, start uint64) *Annotator {
+	return &Annotator{
+		Decoder: d,
+		center:  center,
+		start:   start,
+		lock:    &sync.Mutex{},
+	}
+}
+
+// Process implements the decoder.Decoder interface.
+func (a *Annotator) Process(iq sdr.SamplesC64) ([]decoder.Event, error) {
+	events, err := a.Decoder.Process(iq)
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	for _, event := range events {
+		a.annotations = append(a.annotations, a.annotate(event, uint64(len(iq))))
+	}
+	a.processed += uint64(len(iq))
+	return events, err
+}
+
+// annotate will turn an Event into an Annotation. n is the number of
+// samples in the buffer the Event was emitted from, which is what the
+// Annotation covers if the Event doesn't say where it came from. The lock
+// must be held.
+func (a *Annotator) annotate(event decoder.Event, n uint64) Annotation {
+	var (
+		start     = a.start + a.processed
+		count     = n
+		bandwidth = event.Bandwidth
+		label     = event.Label
+	)
+	if event.Length != 0 {
+		start = a.start + event.Start
+		count = event.Length
+	}
+	if bandwidth == 0 {
+		bandwidth = a.Decoder.Bandwidth()
+	}
+	if label == "" {
+		label = event.Decoder
+	}
+
+	ann := Annotation{
+		SampleStart: start,
+		SampleCount: count,
+		Label:       label,
+		Generator:   event.Decoder,
+	}
+	if a.center != 0 {
+		center := a.center + event.Offset
+		ann.FreqLowerEdge = float64(center - bandwidth/2)
+		ann.FreqUpperEdge = float64(center + bandwidth/2)
+	}
+	if stringer, ok := event.Payload.(fmt.Stringer); ok {
+		ann.Comment = stringer.String()
+	}
+	return ann
+}
+
+// Annotations will return the Annotations generated so far.
+func (a *Annotator) Annotations() []Annotation {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return append([]Annotation{}, a.annotations...)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,117 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sigmf_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/decoder"
+	"hz.tools/sdr/sigmf"
+)
+
+type message string
+
+func (m message) String() string { return string(m) }
+
+// burst is a Decoder which emits an Event for every run of samples with a
+// real component over 0.5; some with an extent, some without.
+type burst struct {
+	offset uint64
+}
+
+func (b *burst) Name() string     { return "test-burst" }
+func (b *burst) SampleRate() uint { return 1000 }
+func (b *burst) Bandwidth() rf.Hz { return 10 * rf.KHz }
+func (b *burst) Close() error     { return nil }
+
+func (b *burst) Process(iq sdr.SamplesC64) ([]decoder.Event, error) {
+	events := []decoder.Event{}
+	for i, s := range iq {
+		switch {
+		case real(s) > 1.5:
+			events = append(events, decoder.Event{
+				Decoder:   b.Name(),
+				Time:      time.Now(),
+				Payload:   message("hello"),
+				Label:     "greeting",
+				Start:     b.offset + uint64(i),
+				Length:    20,
+				Offset:    rf.KHz,
+				Bandwidth: 2 * rf.KHz,
+			})
+		case real(s) > 0.5:
+			events = append(events, decoder.Event{
+				Decoder: b.Name(),
+				Time:    time.Now(),
+			})
+		}
+	}
+	b.offset += uint64(len(iq))
+	return events, nil
+}
+
+func TestAnnotator(t *testing.T) {
+	a := sigmf.NewAnnotator(&burst{}, 100*rf.MHz, 50)
+	assert.Equal(t, "test-burst", a.Name())
+
+	buf := make(sdr.SamplesC64, 100)
+	events, err := a.Process(buf)
+	assert.NoError(t, err)
+	assert.Len(t, events, 0)
+
+	buf[10] = 2
+	buf[60] = 1
+	events, err = a.Process(buf)
+	assert.NoError(t, err)
+	assert.Len(t, events, 2)
+
+	assert.Equal(t, []sigmf.Annotation{
+		{
+			SampleStart:   160,
+			SampleCount:   20,
+			FreqLowerEdge: 100e6,
+			FreqUpperEdge: 100.002e6,
+			Label:         "greeting",
+			Comment:       "hello",
+			Generator:     "test-burst",
+		},
+		{
+			SampleStart:   150,
+			SampleCount:   100,
+			FreqLowerEdge: 99.995e6,
+			FreqUpperEdge: 100.005e6,
+			Label:         "test-burst",
+			Generator:     "test-burst",
+		},
+	}, a.Annotations())
+
+	meta, err := sigmf.NewMeta(sdr.SampleFormatC64, 1000)
+	assert.NoError(t, err)
+	meta.AddAnnotations(a.Annotations()...)
+	assert.Equal(t, uint64(150), meta.Annotations[0].SampleStart)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,44 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sigmf
+
+import (
+	"strings"
+)
+
+const (
+	// DataExtension is the file extension of a SigMF dataset file.
+	DataExtension = ".sigmf-data"
+
+	// MetaExtension is the file extension of a SigMF metadata file.
+	MetaExtension = ".sigmf-meta"
+)
+
+// Paths will return the dataset and metadata file paths of a SigMF
+// recording. The path may be the base name of the recording, or the path
+// to either of the two files.
+func Paths(path string) (data, meta string) {
+	base := strings.TrimSuffix(path, DataExtension)
+	base = strings.TrimSuffix(base, MetaExtension)
+	return base + DataExtension, base + MetaExtension
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,125 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sigmf_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/sigmf"
+)
+
+func TestPaths(t *testing.T) {
+	for _, path := range []string{
+		"/tmp/rec",
+		"/tmp/rec.sigmf-data",
+		"/tmp/rec.sigmf-meta",
+	} {
+		data, meta := sigmf.Paths(path)
+		assert.Equal(t, "/tmp/rec.sigmf-data", data)
+		assert.Equal(t, "/tmp/rec.sigmf-meta", meta)
+	}
+}
+
+func TestWriterReader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sigmf")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "rec")
+
+	w, err := sigmf.Create(path, sdr.SampleFormatI16, 2048000)
+	assert.NoError(t, err)
+
+	w.SetHardwareInfo(sdr.HardwareInfo{
+		Manufacturer: "Acme",
+		Product:      "Radio",
+		Serial:       "1234",
+	})
+	w.SetCenterFrequency(rf.MHz * 100)
+
+	in := sdr.SamplesI16{{1, -1}, {2, -2}, {3, -3}}
+	n, err := w.Write(in)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	w.SetCenterFrequency(rf.MHz * 101)
+	w.SetCenterFrequency(rf.MHz * 102)
+	_, err = w.Write(in[:1])
+	assert.NoError(t, err)
+
+	w.AddAnnotations(sigmf.Annotation{SampleStart: 1, SampleCount: 2, Label: "hi"})
+	assert.NoError(t, w.Close())
+
+	_, err = w.Write(in)
+	assert.Equal(t, sigmf.ErrClosed, err)
+
+	r, err := sigmf.Open(path + sigmf.MetaExtension)
+	assert.NoError(t, err)
+	defer r.Close()
+
+	assert.Equal(t, uint(2048000), r.SampleRate())
+	assert.Equal(t, sdr.SampleFormatI16, r.SampleFormat())
+	assert.Equal(t, rf.MHz*100, r.CenterFrequency())
+
+	meta := r.Meta()
+	assert.Equal(t, "Acme Radio (serial 1234)", meta.Global.Hardware)
+	assert.Len(t, meta.Captures, 2)
+	assert.Equal(t, uint64(3), meta.Captures[1].SampleStart)
+	assert.Equal(t, float64(102e6), meta.Captures[1].Frequency)
+	assert.NotEmpty(t, meta.Captures[0].Datetime)
+	assert.Equal(t, "hi", meta.Annotations[0].Label)
+
+	out := make(sdr.SamplesI16, 10)
+	n, err = sdr.ReadFull(r, out[:4])
+	assert.NoError(t, err)
+	assert.Equal(t, 4, n)
+	assert.Equal(t, sdr.SamplesI16{{1, -1}, {2, -2}, {3, -3}, {1, -1}}, out[:4])
+
+	_, err = r.Read(out)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestNewReaderBigEndian(t *testing.T) {
+	meta, err := sigmf.NewMeta(sdr.SampleFormatI16, 1000)
+	assert.NoError(t, err)
+	meta.Global.Datatype = "ci16_be"
+
+	buf := bytes.Buffer{}
+	assert.NoError(t, binary.Write(&buf, binary.BigEndian, []int16{0x102, -3}))
+
+	r, err := sigmf.NewReader(meta, &buf)
+	assert.NoError(t, err)
+	out := make(sdr.SamplesI16, 1)
+	_, err = sdr.ReadFull(r, out)
+	assert.NoError(t, err)
+	assert.Equal(t, sdr.SamplesI16{{0x102, -3}}, out)
+}
+
+// vim: foldmethod=marker
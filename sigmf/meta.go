@@ -0,0 +1,173 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sigmf
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"hz.tools/sdr"
+)
+
+const (
+	// Version is the version of the SigMF specification written.
+	Version = "1.0.0"
+)
+
+var (
+	// ErrUnsupportedDatatype will be returned if a SigMF datatype doesn't
+	// map to an sdr.SampleFormat, or the other way around.
+	ErrUnsupportedDatatype = fmt.Errorf("sigmf: unsupported datatype")
+)
+
+// Meta is the contents of a SigMF metadata file.
+type Meta struct {
+	Global      Global       `json:"global"`
+	Captures    []Capture    `json:"captures"`
+	Annotations []Annotation `json:"annotations"`
+}
+
+// Global contains information about the recording as a whole.
+type Global struct {
+	Datatype    string  `json:"core:datatype"`
+	SampleRate  float64 `json:"core:sample_rate,omitempty"`
+	Version     string  `json:"core:version"`
+	Description string  `json:"core:description,omitempty"`
+	Author      string  `json:"core:author,omitempty"`
+	Hardware    string  `json:"core:hw,omitempty"`
+	Recorder    string  `json:"core:recorder,omitempty"`
+}
+
+// Capture describes a contiguous run of samples, such as where the tuner
+// was retuned.
+type Capture struct {
+	SampleStart uint64  `json:"core:sample_start"`
+	Frequency   float64 `json:"core:frequency,omitempty"`
+	Datetime    string  `json:"core:datetime,omitempty"`
+}
+
+// Annotation describes something found in the recording, such as a
+// decoded message.
+type Annotation struct {
+	SampleStart   uint64  `json:"core:sample_start"`
+	SampleCount   uint64  `json:"core:sample_count,omitempty"`
+	FreqLowerEdge float64 `json:"core:freq_lower_edge,omitempty"`
+	FreqUpperEdge float64 `json:"core:freq_upper_edge,omitempty"`
+	Label         string  `json:"core:label,omitempty"`
+	Comment       string  `json:"core:comment,omitempty"`
+	Generator     string  `json:"core:generator,omitempty"`
+}
+
+// Datatype will return the SigMF datatype for the provided SampleFormat.
+func Datatype(format sdr.SampleFormat) (string, error) {
+	switch format {
+	case sdr.SampleFormatC64:
+		return "cf32_le", nil
+	case sdr.SampleFormatC128:
+		return "cf64_le", nil
+	case sdr.SampleFormatI16:
+		return "ci16_le", nil
+	case sdr.SampleFormatI8:
+		return "ci8", nil
+	case sdr.SampleFormatU8:
+		return "cu8", nil
+	default:
+		return "", ErrUnsupportedDatatype
+	}
+}
+
+// SampleFormat will return the sdr.SampleFormat for the provided SigMF
+// datatype.
+func SampleFormat(datatype string) (sdr.SampleFormat, error) {
+	switch datatype {
+	case "cf32_le", "cf32_be":
+		return sdr.SampleFormatC64, nil
+	case "cf64_le", "cf64_be":
+		return sdr.SampleFormatC128, nil
+	case "ci16_le", "ci16_be":
+		return sdr.SampleFormatI16, nil
+	case "ci8":
+		return sdr.SampleFormatI8, nil
+	case "cu8":
+		return sdr.SampleFormatU8, nil
+	default:
+		return 0, ErrUnsupportedDatatype
+	}
+}
+
+// byteOrder will return the byte order of the provided SigMF datatype. The
+// single byte types don't have a byte order, so they're little endian as
+// far as anyone here is concerned.
+func byteOrder(datatype string) binary.ByteOrder {
+	if strings.HasSuffix(datatype, "_be") {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
+
+// NewMeta will create a new Meta for a recording of samples in the
+// provided format and sample rate.
+func NewMeta(format sdr.SampleFormat, sampleRate uint) (*Meta, error) {
+	datatype, err := Datatype(format)
+	if err != nil {
+		return nil, err
+	}
+	return &Meta{
+		Global: Global{
+			Datatype:   datatype,
+			SampleRate: float64(sampleRate),
+			Version:    Version,
+		},
+		Captures:    []Capture{},
+		Annotations: []Annotation{},
+	}, nil
+}
+
+// AddAnnotations will add the provided Annotations, keeping the Annotations
+// sorted by SampleStart, as the specification requires.
+func (m *Meta) AddAnnotations(annotations ...Annotation) {
+	m.Annotations = append(m.Annotations, annotations...)
+	sort.SliceStable(m.Annotations, func(i, j int) bool {
+		return m.Annotations[i].SampleStart < m.Annotations[j].SampleStart
+	})
+}
+
+// Write will write the Meta as JSON to the provided io.Writer.
+func (m *Meta) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m)
+}
+
+// ReadMeta will read a Meta from JSON in the provided io.Reader.
+func ReadMeta(r io.Reader) (*Meta, error) {
+	m := &Meta{}
+	if err := json.NewDecoder(r).Decode(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// vim: foldmethod=marker
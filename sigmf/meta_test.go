@@ -0,0 +1,71 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sigmf_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/sigmf"
+)
+
+func TestDatatype(t *testing.T) {
+	for _, format := range []sdr.SampleFormat{
+		sdr.SampleFormatC64,
+		sdr.SampleFormatI16,
+		sdr.SampleFormatI8,
+		sdr.SampleFormatU8,
+	} {
+		datatype, err := sigmf.Datatype(format)
+		assert.NoError(t, err)
+		back, err := sigmf.SampleFormat(datatype)
+		assert.NoError(t, err)
+		assert.Equal(t, format, back)
+	}
+
+	_, err := sigmf.SampleFormat("rf64_be")
+	assert.Equal(t, sigmf.ErrUnsupportedDatatype, err)
+}
+
+func TestMeta(t *testing.T) {
+	meta, err := sigmf.NewMeta(sdr.SampleFormatI16, 2048000)
+	assert.NoError(t, err)
+	meta.Captures = append(meta.Captures, sigmf.Capture{Frequency: 1090e6})
+	meta.AddAnnotations(
+		sigmf.Annotation{SampleStart: 100, SampleCount: 10, Label: "b"},
+		sigmf.Annotation{SampleStart: 5, SampleCount: 10, Label: "a"},
+	)
+
+	buf := bytes.Buffer{}
+	assert.NoError(t, meta.Write(&buf))
+	assert.Contains(t, buf.String(), `"core:datatype": "ci16_le"`)
+	assert.Contains(t, buf.String(), `"core:sample_rate": 2048000`)
+
+	back, err := sigmf.ReadMeta(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, meta, back)
+	assert.Equal(t, "a", back.Annotations[0].Label)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,109 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sigmf
+
+import (
+	"io"
+	"os"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+)
+
+// NewReader will return an sdr.Reader over the raw samples in data, decoded
+// as described by the provided Meta.
+func NewReader(meta *Meta, data io.Reader) (sdr.Reader, error) {
+	format, err := SampleFormat(meta.Global.Datatype)
+	if err != nil {
+		return nil, err
+	}
+	return sdr.ByteReader(
+		data,
+		byteOrder(meta.Global.Datatype),
+		uint(meta.Global.SampleRate),
+		format,
+	), nil
+}
+
+// Reader will play back a SigMF recording from disk. The SampleRate and
+// SampleFormat of the Reader are taken from the recording's metadata.
+type Reader struct {
+	sdr.Reader
+
+	meta *Meta
+	data *os.File
+}
+
+// Open will open a SigMF recording for playback. The path may be the base
+// name of the recording, or the path to either the dataset or metadata
+// file.
+func Open(path string) (*Reader, error) {
+	dataPath, metaPath := Paths(path)
+
+	metaFile, err := os.Open(metaPath)
+	if err != nil {
+		return nil, err
+	}
+	defer metaFile.Close()
+
+	meta, err := ReadMeta(metaFile)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.Open(dataPath)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := NewReader(meta, data)
+	if err != nil {
+		data.Close()
+		return nil, err
+	}
+
+	return &Reader{
+		Reader: r,
+		meta:   meta,
+		data:   data,
+	}, nil
+}
+
+// Meta will return the metadata of the recording.
+func (r *Reader) Meta() *Meta {
+	return r.meta
+}
+
+// CenterFrequency will return the center frequency of the first Capture
+// in the recording, or 0 if the recording doesn't say.
+func (r *Reader) CenterFrequency() rf.Hz {
+	if len(r.meta.Captures) == 0 {
+		return 0
+	}
+	return rf.Hz(r.meta.Captures[0].Frequency)
+}
+
+// Close implements the sdr.ReadCloser interface.
+func (r *Reader) Close() error {
+	return r.data.Close()
+}
+
+// vim: foldmethod=marker
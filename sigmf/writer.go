@@ -0,0 +1,205 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sigmf
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+)
+
+var (
+	// ErrClosed will be returned when writing to a Writer that has already
+	// been closed.
+	ErrClosed = fmt.Errorf("sigmf: writer is closed")
+)
+
+// datetimeFormat is the ISO 8601 format SigMF expects timestamps in.
+const datetimeFormat = "2006-01-02T15:04:05.000Z"
+
+// Writer will record samples to a SigMF recording on disk. Samples are
+// written to the dataset file as they come in, and the metadata file is
+// written when the Writer is closed.
+type Writer struct {
+	lock *sync.Mutex
+
+	w        sdr.Writer
+	buf      *bufio.Writer
+	data     *os.File
+	metaPath string
+
+	meta    *Meta
+	written uint64
+	closed  bool
+}
+
+// Create will create a new SigMF recording. The path may be the base name
+// of the recording, or the path to either the dataset or metadata file.
+// Any existing recording at that path will be truncated.
+func Create(path string, format sdr.SampleFormat, sampleRate uint) (*Writer, error) {
+	meta, err := NewMeta(format, sampleRate)
+	if err != nil {
+		return nil, err
+	}
+	meta.Global.Recorder = "hz.tools/sdr"
+
+	dataPath, metaPath := Paths(path)
+	data, err := os.Create(dataPath)
+	if err != nil {
+		return nil, err
+	}
+	buf := bufio.NewWriter(data)
+
+	return &Writer{
+		lock:     &sync.Mutex{},
+		w:        sdr.ByteWriter(buf, byteOrder(meta.Global.Datatype), sampleRate, format),
+		buf:      buf,
+		data:     data,
+		metaPath: metaPath,
+		meta:     meta,
+	}, nil
+}
+
+// SampleFormat implements the sdr.Writer interface.
+func (w *Writer) SampleFormat() sdr.SampleFormat {
+	return w.w.SampleFormat()
+}
+
+// SampleRate implements the sdr.Writer interface.
+func (w *Writer) SampleRate() uint {
+	return w.w.SampleRate()
+}
+
+// Write implements the sdr.Writer interface.
+func (w *Writer) Write(s sdr.Samples) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.closed {
+		return 0, ErrClosed
+	}
+
+	n, err := w.w.Write(s)
+	w.written += uint64(n)
+	return n, err
+}
+
+// SetCenterFrequency will record that the samples written from here on out
+// were captured at the provided center frequency, by adding a Capture
+// starting at the next sample to be written, stamped with the current time.
+func (w *Writer) SetCenterFrequency(freq rf.Hz) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	capture := Capture{
+		SampleStart: w.written,
+		Frequency:   float64(freq),
+		Datetime:    time.Now().UTC().Format(datetimeFormat),
+	}
+
+	// If nothing has been written since the last Capture started, there's
+	// no sense in keeping it around; it describes zero samples.
+	if n := len(w.meta.Captures); n > 0 && w.meta.Captures[n-1].SampleStart == w.written {
+		w.meta.Captures[n-1] = capture
+		return
+	}
+	w.meta.Captures = append(w.meta.Captures, capture)
+}
+
+// SetHardwareInfo will record the hardware the recording was made with.
+func (w *Writer) SetHardwareInfo(hi sdr.HardwareInfo) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	parts := []string{}
+	for _, part := range []string{hi.Manufacturer, hi.Product} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if hi.Serial != "" {
+		parts = append(parts, fmt.Sprintf("(serial %s)", hi.Serial))
+	}
+	w.meta.Global.Hardware = strings.Join(parts, " ")
+}
+
+// Describe will record the HardwareInfo and current center frequency of
+// the provided Sdr. This is usually called right after Create, with the
+// radio the samples are going to come from.
+func (w *Writer) Describe(dev sdr.Sdr) error {
+	freq, err := dev.GetCenterFrequency()
+	if err != nil {
+		return err
+	}
+	w.SetHardwareInfo(dev.HardwareInfo())
+	w.SetCenterFrequency(freq)
+	return nil
+}
+
+// AddAnnotations will add the provided Annotations to the recording.
+func (w *Writer) AddAnnotations(annotations ...Annotation) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.meta.AddAnnotations(annotations...)
+}
+
+// Meta will return the metadata of the recording so far. Changes to the
+// returned Meta before the Writer is closed will be written out.
+func (w *Writer) Meta() *Meta {
+	return w.meta
+}
+
+// Close will flush the dataset to disk, and write the metadata file.
+func (w *Writer) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	if err := w.buf.Flush(); err != nil {
+		w.data.Close()
+		return err
+	}
+	if err := w.data.Close(); err != nil {
+		return err
+	}
+
+	metaFile, err := os.Create(w.metaPath)
+	if err != nil {
+		return err
+	}
+	if err := w.meta.Write(metaFile); err != nil {
+		metaFile.Close()
+		return err
+	}
+	return metaFile.Close()
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,86 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package soak
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+
+	"hz.tools/sdr"
+)
+
+// Report contains the Snapshots taken over the course of a soak, and any
+// problems found.
+type Report struct {
+	Started      time.Time
+	Elapsed      time.Duration
+	SampleRate   uint
+	SampleFormat sdr.SampleFormat
+
+	// Samples is the total number of samples read from the pipeline.
+	Samples uint64
+
+	Snapshots []Snapshot
+	Failures  []string
+}
+
+// Failed will return true if any problems were found.
+func (r Report) Failed() bool {
+	return len(r.Failures) != 0
+}
+
+// WriteTo will write the Report as a Markdown document.
+func (r Report) WriteTo(w io.Writer) (int64, error) {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "# Soak\n\n")
+	fmt.Fprintf(buf, "Run at %s for %s, %d samples at %d sps (%s).\n\n",
+		r.Started.Format(time.RFC3339),
+		r.Elapsed.Round(time.Second),
+		r.Samples,
+		r.SampleRate,
+		r.SampleFormat,
+	)
+	if len(r.Failures) == 0 {
+		fmt.Fprintf(buf, "No problems found.\n\n")
+	}
+	for _, failure := range r.Failures {
+		fmt.Fprintf(buf, " - FAIL: %s\n", failure)
+	}
+	if len(r.Failures) != 0 {
+		fmt.Fprintf(buf, "\n")
+	}
+	fmt.Fprintf(buf, "| Elapsed | Samples | Heap | Objects | Goroutines |\n")
+	fmt.Fprintf(buf, "|---------|---------|------|---------|------------|\n")
+	for _, s := range r.Snapshots {
+		fmt.Fprintf(buf, "| %s | %d | %d | %d | %d |\n",
+			s.Elapsed.Round(time.Second),
+			s.Samples,
+			s.HeapAlloc,
+			s.HeapObjects,
+			s.Goroutines,
+		)
+	}
+	return buf.WriteTo(w)
+}
+
+// vim: foldmethod=marker
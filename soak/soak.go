@@ -0,0 +1,310 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package soak
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"hz.tools/sdr"
+)
+
+// Pipeline will build the stream stack under test, returning the end of
+// the pipeline to be drained. Closing the returned ReadCloser must tear
+// down the whole pipeline, including any goroutines it started.
+type Pipeline func() (sdr.ReadCloser, error)
+
+// Config controls how long the soak runs for, and what's considered a
+// failure.
+type Config struct {
+	// Duration is how long to run the pipeline for. If 0, this will default
+	// to one hour.
+	Duration time.Duration
+
+	// Interval is how often to take a Snapshot. If 0, this will default to
+	// one sixtieth of the Duration.
+	Interval time.Duration
+
+	// Warmup is how long to let the pipeline run before taking the baseline
+	// Snapshot that growth is measured against, so that buffers, pools and
+	// caches filling up aren't counted as leaks. If 0, this will default to
+	// one Interval.
+	Warmup time.Duration
+
+	// BufferLength is the number of samples to Read at a time. If 0, this
+	// will default to 32K samples.
+	BufferLength int
+
+	// MaxHeapGrowth is how many bytes the live heap may grow by between the
+	// baseline Snapshot and the last Snapshot. If 0, this will default to
+	// 16 MiB.
+	MaxHeapGrowth uint64
+
+	// MaxGoroutineGrowth is how many more goroutines may be running at the
+	// end of the soak than at the baseline, and how many may be left
+	// behind once the pipeline has been closed.
+	MaxGoroutineGrowth int
+
+	// RateTolerance is the fraction of the expected sample count that the
+	// samples read may be off by, where the expected sample count is the
+	// SampleRate times the time spent reading. This only makes sense for
+	// pipelines that are clocked (such as a real radio, or a mock with a
+	// Clock). If 0, the sample count isn't checked.
+	RateTolerance float64
+
+	// Logf, if not nil, will be called with each Snapshot as it's taken.
+	Logf func(format string, args ...interface{})
+}
+
+func (c Config) getDuration() time.Duration {
+	if c.Duration == 0 {
+		return time.Hour
+	}
+	return c.Duration
+}
+
+func (c Config) getInterval() time.Duration {
+	if c.Interval == 0 {
+		return c.getDuration() / 60
+	}
+	return c.Interval
+}
+
+func (c Config) getWarmup() time.Duration {
+	if c.Warmup == 0 {
+		return c.getInterval()
+	}
+	return c.Warmup
+}
+
+func (c Config) getBufferLength() int {
+	if c.BufferLength == 0 {
+		return 32 * 1024
+	}
+	return c.BufferLength
+}
+
+func (c Config) getMaxHeapGrowth() uint64 {
+	if c.MaxHeapGrowth == 0 {
+		return 16 * 1024 * 1024
+	}
+	return c.MaxHeapGrowth
+}
+
+// Snapshot is the state of the process at a point during the soak.
+type Snapshot struct {
+	// Elapsed is the time since the pipeline was started.
+	Elapsed time.Duration
+
+	// Samples is the total number of samples read so far.
+	Samples uint64
+
+	// HeapAlloc and HeapObjects are the live heap (after a GC), as
+	// reported by runtime.MemStats.
+	HeapAlloc   uint64
+	HeapObjects uint64
+
+	// Goroutines is the number of goroutines running.
+	Goroutines int
+}
+
+func takeSnapshot(start time.Time, samples uint64) Snapshot {
+	var mem runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&mem)
+	return Snapshot{
+		Elapsed:     time.Since(start),
+		Samples:     samples,
+		HeapAlloc:   mem.HeapAlloc,
+		HeapObjects: mem.HeapObjects,
+		Goroutines:  runtime.NumGoroutine(),
+	}
+}
+
+// drain will read from the pipeline until it returns an error, or the
+// context is canceled, counting the samples read.
+func drain(ctx context.Context, r sdr.Reader, buf sdr.Samples, samples *uint64) error {
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+		n, err := r.Read(buf)
+		atomic.AddUint64(samples, uint64(n))
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Run will build the pipeline, and read from it until the Config's
+// Duration has passed (or the context is canceled), taking a Snapshot every
+// Interval. The pipeline is then closed, and the Report checked for leaks,
+// stalls and drift.
+func Run(ctx context.Context, pipeline Pipeline, cfg Config) Report {
+	report := Report{Started: time.Now()}
+	baseGoroutines := runtime.NumGoroutine()
+
+	r, err := pipeline()
+	if err != nil {
+		report.fail("pipeline: %s", err)
+		return report
+	}
+	report.SampleRate = r.SampleRate()
+	report.SampleFormat = r.SampleFormat()
+
+	buf, err := sdr.MakeSamples(r.SampleFormat(), cfg.getBufferLength())
+	if err != nil {
+		r.Close()
+		report.fail("pipeline: %s", err)
+		return report
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.getDuration())
+	defer cancel()
+
+	var (
+		samples uint64
+		readErr error
+		wg      sync.WaitGroup
+		start   = time.Now()
+	)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer cancel()
+		readErr = drain(ctx, r, buf, &samples)
+	}()
+
+	var (
+		ticker   = time.NewTicker(cfg.getInterval())
+		warmup   = cfg.getWarmup()
+		baseline = -1
+	)
+	defer ticker.Stop()
+
+	snapshot := func() {
+		s := takeSnapshot(start, atomic.LoadUint64(&samples))
+		report.Snapshots = append(report.Snapshots, s)
+		if baseline < 0 && s.Elapsed >= warmup {
+			baseline = len(report.Snapshots) - 1
+		}
+		if cfg.Logf != nil {
+			cfg.Logf("soak: %s: %d samples, %d bytes heap, %d goroutines",
+				s.Elapsed.Round(time.Second), s.Samples, s.HeapAlloc, s.Goroutines)
+		}
+	}
+
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-ticker.C:
+			snapshot()
+		}
+	}
+	snapshot()
+	report.Elapsed = time.Since(start)
+
+	if err := r.Close(); err != nil {
+		report.fail("close: %s", err)
+	}
+	wg.Wait()
+	report.Samples = atomic.LoadUint64(&samples)
+
+	if readErr != nil && readErr != io.EOF && readErr != sdr.ErrPipeClosed {
+		report.fail("read: %s", readErr)
+	} else if readErr != nil && report.Elapsed < cfg.getDuration() {
+		report.fail("read: pipeline ended after %s", report.Elapsed.Round(time.Millisecond))
+	}
+
+	if baseline < 0 {
+		baseline = 0
+	}
+	report.check(report.Snapshots[baseline], cfg)
+
+	// Give the pipeline a little while to wind down before counting
+	// goroutines which have been left behind.
+	leftover := runtime.NumGoroutine() - baseGoroutines
+	for deadline := time.Now().Add(time.Second * 2); leftover > cfg.MaxGoroutineGrowth && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond * 50)
+		leftover = runtime.NumGoroutine() - baseGoroutines
+	}
+	if leftover > cfg.MaxGoroutineGrowth {
+		report.fail("goroutines: %d left running after close", leftover)
+	}
+
+	return report
+}
+
+// check will look for growth, stalls and drift between the baseline and
+// the last Snapshot.
+func (r *Report) check(base Snapshot, cfg Config) {
+	last := r.Snapshots[len(r.Snapshots)-1]
+
+	if last.HeapAlloc > base.HeapAlloc {
+		if growth := last.HeapAlloc - base.HeapAlloc; growth > cfg.getMaxHeapGrowth() {
+			r.fail("heap: grew by %d bytes (%d objects) after %s",
+				growth, int64(last.HeapObjects)-int64(base.HeapObjects),
+				base.Elapsed.Round(time.Second))
+		}
+	}
+
+	if growth := last.Goroutines - base.Goroutines; growth > cfg.MaxGoroutineGrowth {
+		r.fail("goroutines: grew by %d after %s", growth, base.Elapsed.Round(time.Second))
+	}
+
+	// The final Snapshot may land right after the last tick, so only
+	// compare Snapshots which had a decent amount of time to make progress.
+	for i := 1; i < len(r.Snapshots); i++ {
+		if r.Snapshots[i].Elapsed-r.Snapshots[i-1].Elapsed < cfg.getInterval()/2 {
+			continue
+		}
+		if r.Snapshots[i].Samples == r.Snapshots[i-1].Samples {
+			r.fail("samples: stalled at %d between %s and %s",
+				r.Snapshots[i].Samples,
+				r.Snapshots[i-1].Elapsed.Round(time.Second),
+				r.Snapshots[i].Elapsed.Round(time.Second))
+			break
+		}
+	}
+
+	if cfg.RateTolerance > 0 && r.SampleRate > 0 {
+		expected := float64(r.SampleRate) * r.Elapsed.Seconds()
+		drift := (float64(r.Samples) - expected) / expected
+		if drift > cfg.RateTolerance || drift < -cfg.RateTolerance {
+			r.fail("samples: read %d, expected %.0f (%+.2f%%)",
+				r.Samples, expected, drift*100)
+		}
+	}
+}
+
+func (r *Report) fail(format string, args ...interface{}) {
+	r.Failures = append(r.Failures, fmt.Sprintf(format, args...))
+}
+
+// vim: foldmethod=marker
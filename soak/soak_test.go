@@ -0,0 +1,131 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package soak_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/mock"
+	"hz.tools/sdr/soak"
+)
+
+type zeroReader struct {
+	done chan struct{}
+	keep *[]sdr.Samples
+}
+
+func (zeroReader) SampleRate() uint               { return 100000 }
+func (zeroReader) SampleFormat() sdr.SampleFormat { return sdr.SampleFormatC64 }
+
+func (z zeroReader) Read(s sdr.Samples) (int, error) {
+	select {
+	case <-z.done:
+		return 0, sdr.ErrPipeClosed
+	default:
+	}
+	if z.keep != nil {
+		*z.keep = append(*z.keep, make(sdr.SamplesC64, 16*1024))
+	}
+	return s.Length(), nil
+}
+
+func (z zeroReader) Close() error {
+	close(z.done)
+	return nil
+}
+
+func clocked() (sdr.ReadCloser, error) {
+	return mock.Clock{}.Reader(zeroReader{done: make(chan struct{})}, 100000), nil
+}
+
+var shortConfig = soak.Config{
+	Duration:      time.Millisecond * 300,
+	Interval:      time.Millisecond * 50,
+	BufferLength:  1024,
+	RateTolerance: 0.2,
+}
+
+func TestSoak(t *testing.T) {
+	report := soak.Run(context.Background(), clocked, shortConfig)
+	assert.False(t, report.Failed(), "%v", report.Failures)
+	assert.True(t, len(report.Snapshots) >= 5)
+	assert.InDelta(t, 30000, report.Samples, 6000)
+
+	buf := &bytes.Buffer{}
+	_, err := report.WriteTo(buf)
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), "No problems found.")
+}
+
+func TestSoakGoroutineLeak(t *testing.T) {
+	stop := make(chan struct{})
+	defer close(stop)
+
+	report := soak.Run(context.Background(), func() (sdr.ReadCloser, error) {
+		go func() { <-stop }()
+		return clocked()
+	}, shortConfig)
+	assert.True(t, report.Failed())
+	assert.Contains(t, report.Failures[0], "left running after close")
+}
+
+func TestSoakHeapGrowth(t *testing.T) {
+	var keep []sdr.Samples
+	cfg := shortConfig
+	cfg.MaxHeapGrowth = 1024 * 1024
+	cfg.RateTolerance = 0
+
+	report := soak.Run(context.Background(), func() (sdr.ReadCloser, error) {
+		z := zeroReader{done: make(chan struct{}), keep: &keep}
+		return mock.Clock{}.Reader(z, 100000), nil
+	}, cfg)
+	assert.True(t, report.Failed())
+	assert.Contains(t, report.Failures[0], "heap: grew by")
+}
+
+func TestSoakEarlyEnd(t *testing.T) {
+	report := soak.Run(context.Background(), func() (sdr.ReadCloser, error) {
+		z := zeroReader{done: make(chan struct{})}
+		r := mock.Clock{}.Reader(z, 100000)
+		go func() {
+			time.Sleep(time.Millisecond * 100)
+			z.Close()
+		}()
+		return sdr.ReaderWithCloser(r, func() error { return nil }), nil
+	}, shortConfig)
+	assert.True(t, report.Failed())
+	assert.Contains(t, report.Failures[0], "pipeline ended after")
+}
+
+func TestRunTestSkipped(t *testing.T) {
+	if _, err := soak.ConfigFromEnv(soak.Config{}); err != nil {
+		t.Skip()
+	}
+	soak.RunTest(t, clocked, shortConfig)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,74 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package soak
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+// ConfigFromEnv will return the provided Config, with the Duration taken
+// from the SOAK_DURATION environment variable (such as "4h"), if set.
+func ConfigFromEnv(c Config) (Config, error) {
+	if env := os.Getenv("SOAK_DURATION"); env != "" {
+		d, err := time.ParseDuration(env)
+		if err != nil {
+			return c, err
+		}
+		c.Duration = d
+	}
+	return c, nil
+}
+
+// RunTest will soak the pipeline (see Run) as part of a Go test, failing
+// the test if any problems are found, and logging the Report.
+//
+// Soaks take a long time, so the test is skipped unless the SOAK_DURATION
+// environment variable is set. Remember to pass -timeout to go test, or
+// the soak will be killed after 10 minutes.
+func RunTest(t *testing.T, pipeline Pipeline, cfg Config) Report {
+	if os.Getenv("SOAK_DURATION") == "" {
+		t.Skip("soak: SOAK_DURATION not set")
+	}
+	cfg, err := ConfigFromEnv(cfg)
+	if err != nil {
+		t.Fatalf("soak: SOAK_DURATION: %s", err)
+	}
+	if cfg.Logf == nil {
+		cfg.Logf = t.Logf
+	}
+
+	report := Run(context.Background(), pipeline, cfg)
+
+	buf := &bytes.Buffer{}
+	report.WriteTo(buf)
+	t.Logf("\n%s", buf.String())
+
+	for _, failure := range report.Failures {
+		t.Errorf("soak: %s", failure)
+	}
+	return report
+}
+
+// vim: foldmethod=marker
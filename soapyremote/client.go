@@ -0,0 +1,565 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package soapyremote
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/debug"
+)
+
+func init() {
+	debug.RegisterRadioDriver("hz.tools/sdr/soapyremote.Client")
+}
+
+var (
+	// ErrStreamActive will be returned by StartRx if a stream is already
+	// running on this Client.
+	ErrStreamActive = fmt.Errorf("soapyremote: stream already active")
+)
+
+const (
+	// DefaultPort is the TCP port SoapyRemote servers listen on.
+	DefaultPort = 55132
+)
+
+// Options contains the configuration used when dialing a SoapyRemote
+// server.
+type Options struct {
+	// Args are the SoapySDR device arguments used to pick and open the
+	// device on the server, such as {"driver": "rtlsdr"}. If empty, the
+	// server will open the first device it finds.
+	Args map[string]string
+
+	// Format is the sample format the server should stream samples in.
+	// If 0, this will default to sdr.SampleFormatI16.
+	Format sdr.SampleFormat
+
+	// Channel is the device channel to control and stream from.
+	Channel int
+
+	// MTU is the largest datagram the server may send. If 0, this will
+	// default to 1500 bytes.
+	MTU uint
+
+	// Window is the number of bytes the server may have in flight before
+	// waiting for an acknowledgement. If 0, this will default to 4 MiB.
+	Window uint
+
+	// Timeout is how long to wait on the server before giving up. If 0,
+	// calls will block forever.
+	Timeout time.Duration
+}
+
+func (o Options) getFormat() sdr.SampleFormat {
+	if o.Format == 0 {
+		return sdr.SampleFormatI16
+	}
+	return o.Format
+}
+
+func (o Options) getMTU() uint {
+	if o.MTU == 0 {
+		return 1500
+	}
+	return o.MTU
+}
+
+func (o Options) getWindow() uint {
+	if o.Window == 0 {
+		return 4 * 1024 * 1024
+	}
+	return o.Window
+}
+
+// soapyFormat will return the SoapySDR name of the sample format.
+func soapyFormat(sf sdr.SampleFormat) (string, error) {
+	switch sf {
+	case sdr.SampleFormatU8:
+		return "CU8", nil
+	case sdr.SampleFormatI8:
+		return "CS8", nil
+	case sdr.SampleFormatI16:
+		return "CS16", nil
+	case sdr.SampleFormatC64:
+		return "CF32", nil
+	case sdr.SampleFormatC128:
+		return "CF64", nil
+	default:
+		return "", sdr.ErrSampleFormatUnknown
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Client is an sdr.Sdr implementation that controls and streams from a
+// device served by a SoapyRemote server (SoapySDRServer).
+//
+// No SoapySDR libraries are needed on this end; the client speaks the
+// SoapyRemote rpc protocol over TCP, and receives samples over UDP.
+type Client struct {
+	lock sync.Mutex
+	conn net.Conn
+	opts Options
+
+	format       sdr.SampleFormat
+	hardwareInfo sdr.HardwareInfo
+	stream       *rxStream
+}
+
+// Dial will connect to the SoapyRemote server at the provided address, and
+// open the device matching opts.Args. If no port is given, DefaultPort is
+// used.
+func Dial(address string, opts Options) (*Client, error) {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, strconv.Itoa(DefaultPort))
+	}
+
+	format := opts.getFormat()
+	if _, err := soapyFormat(format); err != nil {
+		return nil, err
+	}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+	if opts.Timeout > 0 {
+		conn, err = net.DialTimeout("tcp", address, opts.Timeout)
+	} else {
+		conn, err = net.Dial("tcp", address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{conn: conn, opts: opts, format: format}
+	if err := c.callVoid(func(p *packer) {
+		p.Call(callMake)
+		p.Kwargs(opts.Args)
+	}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if err := c.loadHardwareInfo(); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// call will send one rpc call to the server, and return the unpacker
+// over its reply.
+func (c *Client) call(args func(*packer)) (*unpacker, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.opts.Timeout > 0 {
+		if err := c.conn.SetDeadline(time.Now().Add(c.opts.Timeout)); err != nil {
+			return nil, err
+		}
+	}
+
+	var p packer
+	args(&p)
+	if _, err := p.WriteTo(c.conn); err != nil {
+		return nil, err
+	}
+	return readPacket(c.conn)
+}
+
+// callVoid will send one rpc call to the server, and check it returned
+// without an exception.
+func (c *Client) callVoid(args func(*packer)) error {
+	u, err := c.call(args)
+	if err != nil {
+		return err
+	}
+	u.Void()
+	return u.Err()
+}
+
+// channelCall will pack the call, the receive direction and the channel,
+// followed by any arguments.
+func (c *Client) channelCall(id rpcCallID, args func(*packer)) func(*packer) {
+	return func(p *packer) {
+		p.Call(id)
+		p.Char(int8(directionRx))
+		p.Int32(int32(c.opts.Channel))
+		if args != nil {
+			args(p)
+		}
+	}
+}
+
+func (c *Client) loadHardwareInfo() error {
+	u, err := c.call(func(p *packer) { p.Call(callDriverKey) })
+	if err != nil {
+		return err
+	}
+	driver := u.String()
+	if err := u.Err(); err != nil {
+		return err
+	}
+
+	u, err = c.call(func(p *packer) { p.Call(callHardwareKey) })
+	if err != nil {
+		return err
+	}
+	hardware := u.String()
+	if err := u.Err(); err != nil {
+		return err
+	}
+
+	u, err = c.call(func(p *packer) { p.Call(callHardwareInfo) })
+	if err != nil {
+		return err
+	}
+	info := u.Kwargs()
+	if err := u.Err(); err != nil {
+		return err
+	}
+
+	c.hardwareInfo = sdr.HardwareInfo{
+		Manufacturer: driver,
+		Product:      hardware,
+		Serial:       info["serial"],
+	}
+	return nil
+}
+
+// Close will stop any running stream, release the device on the server
+// and hang up.
+func (c *Client) Close() error {
+	c.lock.Lock()
+	stream := c.stream
+	c.lock.Unlock()
+	if stream != nil {
+		stream.Close()
+	}
+
+	unmakeErr := c.callVoid(func(p *packer) { p.Call(callUnmake) })
+	c.callVoid(func(p *packer) { p.Call(callHangup) })
+	if err := c.conn.Close(); err != nil {
+		return err
+	}
+	return unmakeErr
+}
+
+// HardwareInfo implements the sdr.Sdr interface.
+func (c *Client) HardwareInfo() sdr.HardwareInfo {
+	return c.hardwareInfo
+}
+
+// SampleFormat implements the sdr.Sdr interface.
+func (c *Client) SampleFormat() sdr.SampleFormat {
+	return c.format
+}
+
+// SetCenterFrequency implements the sdr.Sdr interface.
+func (c *Client) SetCenterFrequency(freq rf.Hz) error {
+	return c.callVoid(c.channelCall(callSetFrequency, func(p *packer) {
+		p.Float64(float64(freq))
+		p.Kwargs(nil)
+	}))
+}
+
+// GetCenterFrequency implements the sdr.Sdr interface.
+func (c *Client) GetCenterFrequency() (rf.Hz, error) {
+	u, err := c.call(c.channelCall(callGetFrequency, nil))
+	if err != nil {
+		return 0, err
+	}
+	freq := u.Float64()
+	return rf.Hz(freq), u.Err()
+}
+
+// SetSampleRate implements the sdr.Sdr interface.
+func (c *Client) SetSampleRate(rate uint) error {
+	return c.callVoid(c.channelCall(callSetSampleRate, func(p *packer) {
+		p.Float64(float64(rate))
+	}))
+}
+
+// GetSampleRate implements the sdr.Sdr interface.
+func (c *Client) GetSampleRate() (uint, error) {
+	u, err := c.call(c.channelCall(callGetSampleRate, nil))
+	if err != nil {
+		return 0, err
+	}
+	rate := u.Float64()
+	return uint(rate), u.Err()
+}
+
+// SetAutomaticGain implements the sdr.Sdr interface.
+func (c *Client) SetAutomaticGain(automatic bool) error {
+	return c.callVoid(c.channelCall(callSetGainMode, func(p *packer) {
+		p.Bool(automatic)
+	}))
+}
+
+type gainStage struct {
+	name  string
+	gainR Range
+}
+
+func (g gainStage) Range() [2]float32 {
+	return [2]float32{float32(g.gainR.Minimum), float32(g.gainR.Maximum)}
+}
+
+func (g gainStage) Type() sdr.GainStageType {
+	return sdr.GainStageTypeRecieve
+}
+
+func (g gainStage) String() string {
+	return g.name
+}
+
+// GetGainStages implements the sdr.Sdr interface.
+func (c *Client) GetGainStages() (sdr.GainStages, error) {
+	u, err := c.call(c.channelCall(callListGains, nil))
+	if err != nil {
+		return nil, err
+	}
+	names := u.StringList()
+	if err := u.Err(); err != nil {
+		return nil, err
+	}
+
+	stages := sdr.GainStages{}
+	for _, name := range names {
+		name := name
+		u, err := c.call(c.channelCall(callGetGainRangeElemnt, func(p *packer) {
+			p.String(name)
+		}))
+		if err != nil {
+			return nil, err
+		}
+		gainR := u.Range()
+		if err := u.Err(); err != nil {
+			return nil, err
+		}
+		stages = append(stages, gainStage{name: name, gainR: gainR})
+	}
+	return stages, nil
+}
+
+// GetGain implements the sdr.Sdr interface.
+func (c *Client) GetGain(stage sdr.GainStage) (float32, error) {
+	u, err := c.call(c.channelCall(callGetGainElement, func(p *packer) {
+		p.String(stage.String())
+	}))
+	if err != nil {
+		return 0, err
+	}
+	gain := u.Float64()
+	return float32(gain), u.Err()
+}
+
+// SetGain implements the sdr.Sdr interface.
+func (c *Client) SetGain(stage sdr.GainStage, gain float32) error {
+	return c.callVoid(c.channelCall(callSetGainElement, func(p *packer) {
+		p.String(stage.String())
+		p.Float64(float64(gain))
+	}))
+}
+
+// rxStream is a running receive stream on the server.
+type rxStream struct {
+	sdr.Reader
+
+	client *Client
+	id     int32
+	data   *net.UDPConn
+	status *net.UDPConn
+	once   sync.Once
+}
+
+// Close implements the sdr.ReadCloser interface.
+func (s *rxStream) Close() error {
+	var err error
+	s.once.Do(func() {
+		c := s.client
+		c.callVoid(func(p *packer) {
+			p.Call(callDeactivateStream)
+			p.Int32(s.id)
+			p.Int32(0)
+			p.Int64(0)
+		})
+		err = c.callVoid(func(p *packer) {
+			p.Call(callCloseStream)
+			p.Int32(s.id)
+		})
+		s.data.Close()
+		s.status.Close()
+
+		c.lock.Lock()
+		c.stream = nil
+		c.lock.Unlock()
+	})
+	return err
+}
+
+// listenUDP will bind a UDP socket on the same local address used to
+// talk to the server, so the server can reach it.
+func (c *Client) listenUDP() (*net.UDPConn, string, error) {
+	local, ok := c.conn.LocalAddr().(*net.TCPAddr)
+	if !ok {
+		return nil, "", fmt.Errorf("soapyremote: connection is not tcp")
+	}
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: local.IP})
+	if err != nil {
+		return nil, "", err
+	}
+	port := strconv.Itoa(conn.LocalAddr().(*net.UDPAddr).Port)
+	return conn, port, nil
+}
+
+// StartRx implements the sdr.Receiver interface.
+//
+// Samples are sent by the server over UDP. Read from the returned reader
+// promptly; the server stops sending once the flow control window is
+// full, but anything dropped on the network is gone.
+func (c *Client) StartRx() (sdr.ReadCloser, error) {
+	c.lock.Lock()
+	active := c.stream != nil
+	c.lock.Unlock()
+	if active {
+		return nil, ErrStreamActive
+	}
+
+	format, err := soapyFormat(c.format)
+	if err != nil {
+		return nil, err
+	}
+
+	rate, err := c.GetSampleRate()
+	if err != nil {
+		return nil, err
+	}
+
+	data, dataPort, err := c.listenUDP()
+	if err != nil {
+		return nil, err
+	}
+	status, statusPort, err := c.listenUDP()
+	if err != nil {
+		data.Close()
+		return nil, err
+	}
+
+	var (
+		mtu    = c.opts.getMTU()
+		window = c.opts.getWindow()
+	)
+
+	u, err := c.call(func(p *packer) {
+		p.Call(callSetupStream)
+		p.Char(int8(directionRx))
+		p.String(format)
+		p.SizeList([]int{c.opts.Channel})
+		p.Kwargs(map[string]string{
+			"remote:mtu":    strconv.FormatUint(uint64(mtu), 10),
+			"remote:window": strconv.FormatUint(uint64(window), 10),
+			"remote:prot":   "udp",
+		})
+		p.String(dataPort)
+		p.String(statusPort)
+	})
+	if err == nil {
+		err = u.Err()
+	}
+	var (
+		id         = u.Int32()
+		serverPort = u.String()
+	)
+	if err == nil {
+		err = u.Err()
+	}
+	if err != nil {
+		data.Close()
+		status.Close()
+		return nil, err
+	}
+
+	remote := c.conn.RemoteAddr().(*net.TCPAddr)
+	port, err := strconv.Atoi(serverPort)
+	if err != nil {
+		data.Close()
+		status.Close()
+		return nil, err
+	}
+	server := &net.UDPAddr{IP: remote.IP, Port: port, Zone: remote.Zone}
+
+	dr := newDatagramReader(data, server, mtu, window, c.format.Size(), c.opts.Timeout)
+	stream := &rxStream{
+		Reader: sdr.ByteReader(dr, binary.LittleEndian, rate, c.format),
+		client: c,
+		id:     id,
+		data:   data,
+		status: status,
+	}
+	c.lock.Lock()
+	c.stream = stream
+	c.lock.Unlock()
+
+	// Open the flow control window before activating, so the server can
+	// start sending right away.
+	if err := dr.ack(); err != nil {
+		stream.Close()
+		return nil, err
+	}
+
+	u, err = c.call(func(p *packer) {
+		p.Call(callActivateStream)
+		p.Int32(id)
+		p.Int32(0)
+		p.Int64(0)
+		p.Int32(0)
+	})
+	if err == nil {
+		u.Int32()
+		err = u.Err()
+	}
+	if err != nil {
+		stream.Close()
+		return nil, err
+	}
+	return stream, nil
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,313 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package soapyremote
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+)
+
+func TestFloat64RoundTrip(t *testing.T) {
+	for _, v := range []float64{0, 1, -1, 0.1, 1.42e9, -3.5e-12, 2.4e6} {
+		var p packer
+		p.Float64(v)
+		var buf bytes.Buffer
+		_, err := p.WriteTo(&buf)
+		assert.NoError(t, err)
+
+		u, err := readPacket(&buf)
+		assert.NoError(t, err)
+		assert.Equal(t, v, u.Float64())
+		assert.NoError(t, u.Err())
+	}
+}
+
+func TestRangeWithoutStep(t *testing.T) {
+	var p packer
+	p.tag(rpcRange)
+	p.Float64(-10)
+	p.Float64(30)
+	p.Int32(7)
+	var buf bytes.Buffer
+	_, err := p.WriteTo(&buf)
+	assert.NoError(t, err)
+
+	u, err := readPacket(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, Range{Minimum: -10, Maximum: 30}, u.Range())
+	assert.Equal(t, int32(7), u.Int32())
+	assert.NoError(t, u.Err())
+}
+
+func TestBadPacket(t *testing.T) {
+	_, err := readPacket(bytes.NewReader(make([]byte, 32)))
+	assert.Equal(t, ErrBadPacket, err)
+
+	// A length past maxPacketLength is rejected before allocating for it.
+	header := make([]byte, rpcHeaderLength)
+	binary.BigEndian.PutUint32(header[0:], rpcHeaderWord)
+	binary.BigEndian.PutUint32(header[4:], rpcVersion)
+	binary.BigEndian.PutUint32(header[8:], 0xFFFFFFFF)
+	_, err = readPacket(bytes.NewReader(header))
+	assert.Equal(t, ErrBadPacket, err)
+}
+
+func TestException(t *testing.T) {
+	var p packer
+	p.Exception("no such gain")
+	var buf bytes.Buffer
+	_, err := p.WriteTo(&buf)
+	assert.NoError(t, err)
+
+	u, err := readPacket(&buf)
+	assert.NoError(t, err)
+	u.Float64()
+	assert.Equal(t, RemoteError{Message: "no such gain"}, u.Err())
+}
+
+// fakeServer is just enough of a SoapyRemote server to drive the Client.
+type fakeServer struct {
+	t        *testing.T
+	listener net.Listener
+
+	lock      sync.Mutex
+	freq      float64
+	rate      float64
+	gain      float64
+	agc       bool
+	format    string
+	args      map[string]string
+	client    *net.UDPAddr
+	data      *net.UDPConn
+	datagrams int
+	done      chan struct{}
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	s := &fakeServer{t: t, listener: l, rate: 1e6, done: make(chan struct{})}
+	go s.serve()
+	return s
+}
+
+func (s *fakeServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *fakeServer) serve() {
+	defer close(s.done)
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	for {
+		u, err := readPacket(conn)
+		if err != nil {
+			return
+		}
+		var reply packer
+		hangup := s.handle(u, &reply)
+		if _, err := reply.WriteTo(conn); err != nil || hangup {
+			return
+		}
+	}
+}
+
+func (s *fakeServer) handle(u *unpacker, reply *packer) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	switch u.Call() {
+	case callMake:
+		s.args = u.Kwargs()
+		reply.Void()
+	case callUnmake:
+		reply.Void()
+	case callHangup:
+		reply.Void()
+		return true
+	case callDriverKey:
+		reply.String("fake")
+	case callHardwareKey:
+		reply.String("FakeRadio")
+	case callHardwareInfo:
+		reply.Kwargs(map[string]string{"serial": "1234"})
+	case callSetFrequency:
+		u.Char()
+		u.Int32()
+		s.freq = u.Float64()
+		u.Kwargs()
+		reply.Void()
+	case callGetFrequency:
+		u.Char()
+		u.Int32()
+		reply.Float64(s.freq)
+	case callSetSampleRate:
+		u.Char()
+		u.Int32()
+		s.rate = u.Float64()
+		reply.Void()
+	case callGetSampleRate:
+		reply.Float64(s.rate)
+	case callSetGainMode:
+		u.Char()
+		u.Int32()
+		s.agc = u.Bool()
+		reply.Void()
+	case callListGains:
+		reply.StringList([]string{"LNA"})
+	case callGetGainRangeElemnt:
+		reply.Range(Range{Minimum: 0, Maximum: 40, Step: 1})
+	case callSetGainElement:
+		u.Char()
+		u.Int32()
+		if u.String() != "LNA" {
+			reply.Exception("no such gain")
+			break
+		}
+		s.gain = u.Float64()
+		reply.Void()
+	case callGetGainElement:
+		reply.Float64(s.gain)
+	case callSetupStream:
+		u.Char()
+		s.format = u.String()
+		u.SizeList()
+		u.Kwargs()
+		port, _ := strconv.Atoi(u.String())
+		_ = u.String() // status port
+		s.client = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port}
+		s.data, _ = net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		reply.Int32(9)
+		reply.String(strconv.Itoa(s.data.LocalAddr().(*net.UDPAddr).Port))
+	case callActivateStream:
+		go s.stream(s.data, s.client)
+		reply.Int32(0)
+	case callDeactivateStream:
+		reply.Void()
+	case callCloseStream:
+		s.data.Close()
+		reply.Void()
+	default:
+		reply.Exception("not implemented")
+	}
+	return false
+}
+
+// stream will wait for the client to open the window, then send a few
+// datagrams of I16 samples counting up.
+func (s *fakeServer) stream(conn *net.UDPConn, client *net.UDPAddr) {
+	var ack [datagramHeaderLength]byte
+	if _, _, err := conn.ReadFromUDP(ack[:]); err != nil {
+		return
+	}
+
+	var (
+		pkt = make([]byte, datagramHeaderLength+4*100)
+		v   int16
+	)
+	for seq := uint32(0); seq < 4; seq++ {
+		datagramHeader{
+			Bytes:    uint32(len(pkt)),
+			Sequence: seq,
+			Elems:    100,
+		}.marshal(pkt)
+		for i := datagramHeaderLength; i < len(pkt); i += 2 {
+			pkt[i] = byte(v)
+			pkt[i+1] = byte(v >> 8)
+			v++
+		}
+		conn.WriteToUDP(pkt, client)
+	}
+}
+
+func TestClient(t *testing.T) {
+	srv := newFakeServer(t)
+
+	dev, err := Dial(srv.Addr(), Options{Args: map[string]string{"driver": "fake"}})
+	assert.NoError(t, err)
+
+	assert.Equal(t, sdr.HardwareInfo{
+		Manufacturer: "fake",
+		Product:      "FakeRadio",
+		Serial:       "1234",
+	}, dev.HardwareInfo())
+	assert.Equal(t, sdr.SampleFormatI16, dev.SampleFormat())
+
+	assert.NoError(t, dev.SetCenterFrequency(rf.MHz*433.92))
+	freq, err := dev.GetCenterFrequency()
+	assert.NoError(t, err)
+	assert.Equal(t, rf.MHz*433.92, freq)
+
+	assert.NoError(t, dev.SetSampleRate(2048000))
+	rate, err := dev.GetSampleRate()
+	assert.NoError(t, err)
+	assert.Equal(t, uint(2048000), rate)
+
+	assert.NoError(t, dev.SetAutomaticGain(true))
+
+	stages, err := dev.GetGainStages()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(stages))
+	assert.Equal(t, [2]float32{0, 40}, stages[0].Range())
+	assert.NoError(t, dev.SetGain(stages[0], 20))
+	gain, err := dev.GetGain(stages[0])
+	assert.NoError(t, err)
+	assert.Equal(t, float32(20), gain)
+
+	err = dev.SetGain(gainStage{name: "VGA"}, 20)
+	assert.Equal(t, RemoteError{Message: "no such gain"}, err)
+
+	rx, err := dev.StartRx()
+	assert.NoError(t, err)
+	assert.Equal(t, uint(2048000), rx.SampleRate())
+	assert.Equal(t, "CS16", srv.format)
+
+	_, err = dev.StartRx()
+	assert.Equal(t, ErrStreamActive, err)
+
+	buf := make(sdr.SamplesI16, 150)
+	_, err = sdr.ReadFull(rx, buf)
+	assert.NoError(t, err)
+	for i := range buf {
+		assert.Equal(t, [2]int16{int16(2 * i), int16(2*i + 1)}, buf[i])
+	}
+
+	assert.NoError(t, rx.Close())
+	assert.NoError(t, dev.Close())
+	<-srv.done
+	assert.True(t, srv.agc)
+	assert.Equal(t, map[string]string{"driver": "fake"}, srv.args)
+}
+
+// vim: foldmethod=marker
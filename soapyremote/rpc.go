@@ -0,0 +1,451 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package soapyremote
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+var (
+	// ErrBadPacket will be returned if a packet from the server doesn't
+	// have a valid header or trailer.
+	ErrBadPacket = fmt.Errorf("soapyremote: malformed rpc packet")
+
+	// ErrUnexpectedType will be returned if the server replies with a value
+	// of a different type than the call returns.
+	ErrUnexpectedType = fmt.Errorf("soapyremote: unexpected type in rpc reply")
+)
+
+// RemoteError is an exception thrown by the device (or server) on the
+// other end of the connection.
+type RemoteError struct {
+	Message string
+}
+
+// Error implements the error interface.
+func (e RemoteError) Error() string {
+	return fmt.Sprintf("soapyremote: remote error: %s", e.Message)
+}
+
+const (
+	rpcHeaderWord  uint32 = 0x53525043 // "SRPC"
+	rpcTrailerWord uint32 = 0x43505253 // "CPRS"
+	rpcVersion     uint32 = 0x00000405
+
+	rpcHeaderLength  = 12
+	rpcTrailerLength = 4
+
+	// maxPacketLength is the largest rpc packet we'll accept from the
+	// server, which is there to prevent a confused (or malicious) peer from
+	// causing an allocation of up to 4 GiB. rpc packets only carry control
+	// calls and their replies, so this is very generous.
+	maxPacketLength = 16 * 1024 * 1024
+)
+
+// rpcType is the tag written before every value on the wire.
+type rpcType uint8
+
+const (
+	rpcChar          rpcType = 0
+	rpcBool          rpcType = 1
+	rpcInt32         rpcType = 2
+	rpcInt64         rpcType = 3
+	rpcFloat64       rpcType = 4
+	rpcComplex128    rpcType = 5
+	rpcString        rpcType = 6
+	rpcRange         rpcType = 7
+	rpcRangeList     rpcType = 8
+	rpcStringList    rpcType = 9
+	rpcFloat64List   rpcType = 10
+	rpcKwargs        rpcType = 11
+	rpcKwargsList    rpcType = 12
+	rpcException     rpcType = 13
+	rpcVoid          rpcType = 14
+	rpcCall          rpcType = 15
+	rpcSizeList      rpcType = 16
+	rpcMantissaShift         = 53
+)
+
+// rpcCallID is the function being called on the server.
+type rpcCallID int32
+
+const (
+	callMake      rpcCallID = 1
+	callUnmake    rpcCallID = 2
+	callHangup    rpcCallID = 3
+	callServerID  rpcCallID = 4
+	callDriverKey rpcCallID = 100
+
+	callHardwareKey  rpcCallID = 101
+	callHardwareInfo rpcCallID = 102
+
+	callSetupStream      rpcCallID = 300
+	callCloseStream      rpcCallID = 301
+	callActivateStream   rpcCallID = 302
+	callDeactivateStream rpcCallID = 303
+
+	callListGains          rpcCallID = 700
+	callSetGainMode        rpcCallID = 701
+	callSetGainElement     rpcCallID = 704
+	callGetGainElement     rpcCallID = 706
+	callGetGainRangeElemnt rpcCallID = 708
+
+	callSetFrequency rpcCallID = 800
+	callGetFrequency rpcCallID = 802
+
+	callSetSampleRate rpcCallID = 900
+	callGetSampleRate rpcCallID = 901
+)
+
+// direction is the SoapySDR direction of a channel.
+type direction int8
+
+const (
+	directionTx direction = 0
+	directionRx direction = 1
+)
+
+// Range is a SoapySDR range, such as the limits of a gain stage.
+type Range struct {
+	Minimum float64
+	Maximum float64
+	Step    float64
+}
+
+// packer builds an rpc packet to send to the server.
+type packer struct {
+	buf bytes.Buffer
+}
+
+func (p *packer) tag(t rpcType) {
+	p.buf.WriteByte(byte(t))
+}
+
+func (p *packer) Char(v int8) {
+	p.tag(rpcChar)
+	p.buf.WriteByte(byte(v))
+}
+
+func (p *packer) Bool(v bool) {
+	p.tag(rpcBool)
+	if v {
+		p.buf.WriteByte(1)
+		return
+	}
+	p.buf.WriteByte(0)
+}
+
+func (p *packer) Int32(v int32) {
+	p.tag(rpcInt32)
+	binary.Write(&p.buf, binary.BigEndian, v)
+}
+
+func (p *packer) Int64(v int64) {
+	p.tag(rpcInt64)
+	binary.Write(&p.buf, binary.BigEndian, v)
+}
+
+// Float64 will pack a float64 as a base 2 exponent and integer mantissa,
+// the same way frexp splits it, so it doesn't depend on the float layout
+// of either end.
+func (p *packer) Float64(v float64) {
+	p.tag(rpcFloat64)
+	frac, exp := math.Frexp(v)
+	p.Int32(int32(exp))
+	p.Int64(int64(math.Ldexp(frac, rpcMantissaShift)))
+}
+
+func (p *packer) String(v string) {
+	p.tag(rpcString)
+	p.Int32(int32(len(v)))
+	p.buf.WriteString(v)
+}
+
+func (p *packer) StringList(v []string) {
+	p.tag(rpcStringList)
+	p.Int32(int32(len(v)))
+	for _, s := range v {
+		p.String(s)
+	}
+}
+
+func (p *packer) Range(v Range) {
+	p.tag(rpcRange)
+	p.Float64(v.Minimum)
+	p.Float64(v.Maximum)
+	p.Float64(v.Step)
+}
+
+func (p *packer) Kwargs(v map[string]string) {
+	p.tag(rpcKwargs)
+	p.Int32(int32(len(v)))
+	for _, key := range sortedKeys(v) {
+		p.String(key)
+		p.String(v[key])
+	}
+}
+
+func (p *packer) SizeList(v []int) {
+	p.tag(rpcSizeList)
+	p.Int32(int32(len(v)))
+	for _, s := range v {
+		p.Int32(int32(s))
+	}
+}
+
+func (p *packer) Call(c rpcCallID) {
+	p.tag(rpcCall)
+	p.Int32(int32(c))
+}
+
+func (p *packer) Void() {
+	p.tag(rpcVoid)
+}
+
+func (p *packer) Exception(msg string) {
+	p.tag(rpcException)
+	p.String(msg)
+}
+
+// WriteTo will frame the packed values with the rpc header and trailer, and
+// write the packet to w.
+func (p *packer) WriteTo(w io.Writer) (int64, error) {
+	var (
+		length = rpcHeaderLength + p.buf.Len() + rpcTrailerLength
+		pkt    = make([]byte, length)
+	)
+	binary.BigEndian.PutUint32(pkt[0:], rpcHeaderWord)
+	binary.BigEndian.PutUint32(pkt[4:], rpcVersion)
+	binary.BigEndian.PutUint32(pkt[8:], uint32(length))
+	copy(pkt[rpcHeaderLength:], p.buf.Bytes())
+	binary.BigEndian.PutUint32(pkt[length-rpcTrailerLength:], rpcTrailerWord)
+
+	n, err := w.Write(pkt)
+	return int64(n), err
+}
+
+// unpacker reads values out of an rpc packet from the server.
+type unpacker struct {
+	buf *bytes.Reader
+	err error
+}
+
+// readPacket will read one rpc packet from r.
+func readPacket(r io.Reader) (*unpacker, error) {
+	var header [rpcHeaderLength]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	if binary.BigEndian.Uint32(header[0:]) != rpcHeaderWord {
+		return nil, ErrBadPacket
+	}
+	length := binary.BigEndian.Uint32(header[8:])
+	if length < rpcHeaderLength+rpcTrailerLength || length > maxPacketLength {
+		return nil, ErrBadPacket
+	}
+
+	body := make([]byte, length-rpcHeaderLength)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	trailer := body[len(body)-rpcTrailerLength:]
+	if binary.BigEndian.Uint32(trailer) != rpcTrailerWord {
+		return nil, ErrBadPacket
+	}
+	return &unpacker{buf: bytes.NewReader(body[:len(body)-rpcTrailerLength])}, nil
+}
+
+// peek will return the type of the next value, without consuming it.
+func (u *unpacker) peek() (rpcType, bool) {
+	if u.err != nil || u.buf.Len() == 0 {
+		return 0, false
+	}
+	b, _ := u.buf.ReadByte()
+	u.buf.UnreadByte()
+	return rpcType(b), true
+}
+
+// expect will consume the type tag of the next value, checking it is the
+// expected type. If the server sent an exception, the RemoteError is
+// stored instead.
+func (u *unpacker) expect(t rpcType) bool {
+	if u.err != nil {
+		return false
+	}
+	b, err := u.buf.ReadByte()
+	if err != nil {
+		u.err = err
+		return false
+	}
+	got := rpcType(b)
+	if got == rpcException && t != rpcException {
+		// unpack the message of the exception, and report that instead.
+		msg := u.String()
+		if u.err == nil {
+			u.err = RemoteError{Message: msg}
+		}
+		return false
+	}
+	if got != t {
+		u.err = ErrUnexpectedType
+		return false
+	}
+	return true
+}
+
+func (u *unpacker) read(v interface{}) {
+	if u.err != nil {
+		return
+	}
+	u.err = binary.Read(u.buf, binary.BigEndian, v)
+}
+
+func (u *unpacker) Char() int8 {
+	var v int8
+	if u.expect(rpcChar) {
+		u.read(&v)
+	}
+	return v
+}
+
+func (u *unpacker) Bool() bool {
+	var v uint8
+	if u.expect(rpcBool) {
+		u.read(&v)
+	}
+	return v != 0
+}
+
+func (u *unpacker) Int32() int32 {
+	var v int32
+	if u.expect(rpcInt32) {
+		u.read(&v)
+	}
+	return v
+}
+
+func (u *unpacker) Int64() int64 {
+	var v int64
+	if u.expect(rpcInt64) {
+		u.read(&v)
+	}
+	return v
+}
+
+func (u *unpacker) Float64() float64 {
+	if !u.expect(rpcFloat64) {
+		return 0
+	}
+	exp := u.Int32()
+	mantissa := u.Int64()
+	return math.Ldexp(float64(mantissa), int(exp)-rpcMantissaShift)
+}
+
+func (u *unpacker) String() string {
+	if !u.expect(rpcString) {
+		return ""
+	}
+	n := u.Int32()
+	if u.err != nil {
+		return ""
+	}
+	if n < 0 || int(n) > u.buf.Len() {
+		u.err = ErrBadPacket
+		return ""
+	}
+	v := make([]byte, n)
+	u.read(v)
+	return string(v)
+}
+
+func (u *unpacker) StringList() []string {
+	if !u.expect(rpcStringList) {
+		return nil
+	}
+	n := u.Int32()
+	var ret []string
+	for i := int32(0); i < n && u.err == nil; i++ {
+		ret = append(ret, u.String())
+	}
+	return ret
+}
+
+// Range will unpack a Range. Older servers don't send the step, so it's
+// only read if the next value is a float64.
+func (u *unpacker) Range() Range {
+	var r Range
+	if !u.expect(rpcRange) {
+		return r
+	}
+	r.Minimum = u.Float64()
+	r.Maximum = u.Float64()
+	if t, ok := u.peek(); ok && t == rpcFloat64 {
+		r.Step = u.Float64()
+	}
+	return r
+}
+
+func (u *unpacker) Kwargs() map[string]string {
+	if !u.expect(rpcKwargs) {
+		return nil
+	}
+	n := u.Int32()
+	ret := map[string]string{}
+	for i := int32(0); i < n && u.err == nil; i++ {
+		key := u.String()
+		ret[key] = u.String()
+	}
+	return ret
+}
+
+func (u *unpacker) SizeList() []int {
+	if !u.expect(rpcSizeList) {
+		return nil
+	}
+	n := u.Int32()
+	var ret []int
+	for i := int32(0); i < n && u.err == nil; i++ {
+		ret = append(ret, int(u.Int32()))
+	}
+	return ret
+}
+
+func (u *unpacker) Call() rpcCallID {
+	if !u.expect(rpcCall) {
+		return 0
+	}
+	return rpcCallID(u.Int32())
+}
+
+func (u *unpacker) Void() {
+	u.expect(rpcVoid)
+}
+
+// Err will return the first error hit while unpacking.
+func (u *unpacker) Err() error {
+	return u.err
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,166 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package soapyremote
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+)
+
+var (
+	// ErrBadDatagram will be returned if a stream datagram from the server
+	// is truncated, or its header doesn't match its length.
+	ErrBadDatagram = fmt.Errorf("soapyremote: malformed stream datagram")
+)
+
+const (
+	datagramHeaderLength = 24
+)
+
+// datagramHeader is the header sent before every chunk of samples, and
+// as the body of flow control acknowledgements going back to the server.
+type datagramHeader struct {
+	Bytes    uint32
+	Sequence uint32
+	Elems    uint32
+	Flags    int32
+	Time     int64
+}
+
+func (h datagramHeader) marshal(b []byte) {
+	binary.BigEndian.PutUint32(b[0:], h.Bytes)
+	binary.BigEndian.PutUint32(b[4:], h.Sequence)
+	binary.BigEndian.PutUint32(b[8:], h.Elems)
+	binary.BigEndian.PutUint32(b[12:], uint32(h.Flags))
+	binary.BigEndian.PutUint64(b[16:], uint64(h.Time))
+}
+
+func (h *datagramHeader) unmarshal(b []byte) {
+	h.Bytes = binary.BigEndian.Uint32(b[0:])
+	h.Sequence = binary.BigEndian.Uint32(b[4:])
+	h.Elems = binary.BigEndian.Uint32(b[8:])
+	h.Flags = int32(binary.BigEndian.Uint32(b[12:]))
+	h.Time = int64(binary.BigEndian.Uint64(b[16:]))
+}
+
+// datagramReader is an io.Reader over the payloads of the stream
+// datagrams sent by the server. It keeps the server's flow control
+// window open by acknowledging datagrams as they're read.
+type datagramReader struct {
+	conn       *net.UDPConn
+	server     *net.UDPAddr
+	timeout    time.Duration
+	sampleSize int
+
+	// maxInFlight is the number of datagrams the server may send before
+	// waiting for an acknowledgement.
+	maxInFlight uint32
+	sinceAck    uint32
+	sequence    uint32
+
+	buf     []byte
+	pending []byte
+}
+
+func newDatagramReader(
+	conn *net.UDPConn,
+	server *net.UDPAddr,
+	mtu, window uint,
+	sampleSize int,
+	timeout time.Duration,
+) *datagramReader {
+	maxInFlight := uint32(window / mtu)
+	if maxInFlight == 0 {
+		maxInFlight = 1
+	}
+	return &datagramReader{
+		conn:        conn,
+		server:      server,
+		timeout:     timeout,
+		sampleSize:  sampleSize,
+		maxInFlight: maxInFlight,
+		buf:         make([]byte, mtu),
+	}
+}
+
+// ack will tell the server the last sequence number we've seen, and how
+// many more datagrams it may send past that.
+func (dr *datagramReader) ack() error {
+	var pkt [datagramHeaderLength]byte
+	datagramHeader{
+		Bytes:    datagramHeaderLength,
+		Sequence: dr.sequence,
+		Elems:    dr.maxInFlight,
+	}.marshal(pkt[:])
+	dr.sinceAck = 0
+	_, err := dr.conn.WriteToUDP(pkt[:], dr.server)
+	return err
+}
+
+// next will block until the next datagram arrives, and stash its payload.
+func (dr *datagramReader) next() error {
+	if dr.timeout > 0 {
+		if err := dr.conn.SetReadDeadline(time.Now().Add(dr.timeout)); err != nil {
+			return err
+		}
+	}
+	n, _, err := dr.conn.ReadFromUDP(dr.buf)
+	if err != nil {
+		return err
+	}
+	if n < datagramHeaderLength {
+		return ErrBadDatagram
+	}
+
+	var header datagramHeader
+	header.unmarshal(dr.buf)
+	if int(header.Bytes) != n {
+		return ErrBadDatagram
+	}
+	dr.pending = dr.buf[datagramHeaderLength:n]
+	dr.sequence = header.Sequence
+
+	// Acknowledge well before the window closes, so the server never has
+	// to stall waiting for us.
+	dr.sinceAck++
+	if dr.sinceAck >= dr.maxInFlight/8 {
+		return dr.ack()
+	}
+	return nil
+}
+
+// Read implements the io.Reader interface. Only whole samples are
+// returned, so the payload is never split mid-sample.
+func (dr *datagramReader) Read(p []byte) (int, error) {
+	for len(dr.pending) == 0 {
+		if err := dr.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, dr.pending)
+	n -= n % dr.sampleSize
+	dr.pending = dr.pending[n:]
+	return n, nil
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,314 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package spectrum
+
+import (
+	"fmt"
+	"math"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/fft"
+)
+
+var (
+	// ErrBadOverlap will be returned if the Overlap is not between 0
+	// (inclusive) and 1 (exclusive).
+	ErrBadOverlap = fmt.Errorf("spectrum: overlap must be at least 0 and less than 1")
+)
+
+// Config contains the parameters used to compute spectrum frames.
+type Config struct {
+	// Planner is used to create the FFT plan. If nil, this will default to
+	// fft.DefaultPlanner.
+	Planner fft.Planner
+
+	// Size is the number of samples in each FFT, and the number of bins in
+	// each Frame. If 0, this will default to 1024.
+	Size int
+
+	// Window is the window function applied to each segment before the
+	// FFT. If nil, this will default to Hann.
+	Window Window
+
+	// Overlap is the fraction of each segment that is shared with the
+	// previous one, from 0 (no overlap) up to (but not including) 1. Hann
+	// and Blackman-Harris windows are usually run with 0.5 and 0.75
+	// respectively, to avoid ignoring the samples near the edges of each
+	// segment.
+	Overlap float64
+
+	// Averages is the number of segments averaged together into each
+	// Frame. More averages give a smoother noise floor, at the cost of a
+	// slower update rate. If 0, this will default to 1.
+	Averages int
+}
+
+func (c Config) getPlanner() fft.Planner {
+	if c.Planner == nil {
+		return fft.DefaultPlanner
+	}
+	return c.Planner
+}
+
+func (c Config) getSize() int {
+	if c.Size == 0 {
+		return 1024
+	}
+	return c.Size
+}
+
+func (c Config) getWindow() Window {
+	if c.Window == nil {
+		return Hann
+	}
+	return c.Window
+}
+
+func (c Config) getAverages() int {
+	if c.Averages == 0 {
+		return 1
+	}
+	return c.Averages
+}
+
+// Frame is one averaged spectrum estimate.
+type Frame struct {
+	// Power is the power in each bin, in the fft.NegativeFirst order,
+	// scaled so that a carrier centered on a bin reads the same as its
+	// time-domain power (a full scale carrier is 1, or 0 dBFS) regardless
+	// of the window used.
+	Power []float32
+
+	// SampleRate is the sample rate of the IQ data the Frame was computed
+	// from.
+	SampleRate uint
+
+	// NoiseBandwidth is the equivalent noise bandwidth of the window, in
+	// bins. Noise is spread over every bin, so the Power of noise in a bin
+	// will read this much higher than a bin-wide slice of noise would.
+	NoiseBandwidth float64
+
+	// Segments is the number of FFT segments averaged into this Frame.
+	Segments int
+}
+
+// BinBandwidth is the amount of frequency each bin represents.
+func (f Frame) BinBandwidth() rf.Hz {
+	return fft.BinBandwidth(len(f.Power), f.SampleRate)
+}
+
+// FreqByBin will return the center frequency of the provided bin, relative
+// to the center frequency of the IQ data.
+func (f Frame) FreqByBin(bin int) (rf.Hz, error) {
+	return fft.FreqByBin(len(f.Power), f.SampleRate, fft.NegativeFirst, bin)
+}
+
+// BinByFreq will return the bin containing the provided frequency, relative
+// to the center frequency of the IQ data.
+func (f Frame) BinByFreq(freq rf.Hz) (int, error) {
+	return fft.BinByFreq(len(f.Power), f.SampleRate, fft.NegativeFirst, freq)
+}
+
+// DBFS will write the Power of each bin to dst in dBFS.
+func (f Frame) DBFS(dst []float32) error {
+	return fft.PowerToDB(dst, f.Power)
+}
+
+// Density will write the power spectral density of each bin to dst, in
+// full scale power per Hz. This corrects for the NoiseBandwidth of the
+// window, so that the noise floor reads the same no matter what the window,
+// FFT size or sample rate are.
+func (f Frame) Density(dst []float32) error {
+	if len(dst) < len(f.Power) {
+		return sdr.ErrDstTooSmall
+	}
+	scale := 1 / (f.NoiseBandwidth * float64(f.BinBandwidth()))
+	for i, p := range f.Power {
+		dst[i] = float32(float64(p) * scale)
+	}
+	return nil
+}
+
+// Spectrum will read IQ samples from an sdr.Reader, and compute averaged
+// power spectrum Frames using Welch's method.
+type Spectrum struct {
+	r        sdr.Reader
+	raw      sdr.Samples
+	segment  sdr.SamplesC64
+	windowed sdr.SamplesC64
+	freq     []complex64
+	plan     fft.Plan
+	window   []float32
+	hop      int
+	averages int
+	primed   bool
+
+	scale float64
+	enbw  float64
+	acc   []float64
+}
+
+// New will create a new Spectrum reading from the provided sdr.Reader. Any
+// SampleFormat may be used, samples are converted to complex64 internally.
+func New(r sdr.Reader, cfg Config) (*Spectrum, error) {
+	if cfg.Overlap < 0 || cfg.Overlap >= 1 {
+		return nil, ErrBadOverlap
+	}
+
+	var (
+		size = cfg.getSize()
+		hop  = size - int(math.Round(cfg.Overlap*float64(size)))
+	)
+	if hop < 1 {
+		hop = 1
+	}
+
+	raw, err := sdr.MakeSamples(r.SampleFormat(), hop)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Spectrum{
+		r:        r,
+		raw:      raw,
+		segment:  make(sdr.SamplesC64, size),
+		windowed: make(sdr.SamplesC64, size),
+		freq:     make([]complex64, size),
+		window:   cfg.getWindow()(size),
+		hop:      hop,
+		averages: cfg.getAverages(),
+		acc:      make([]float64, size),
+	}
+
+	var sum, sumSq float64
+	for _, w := range s.window {
+		sum += float64(w)
+		sumSq += float64(w) * float64(w)
+	}
+	// The coherent gain of the window (sum squared) is what a carrier
+	// centered on a bin is scaled by, so divide that back out.
+	s.scale = 1 / (sum * sum)
+	s.enbw = float64(size) * sumSq / (sum * sum)
+
+	s.plan, err = cfg.getPlanner()(s.windowed, s.freq, fft.Forward)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Size returns the number of bins in each Frame.
+func (s *Spectrum) Size() int {
+	return len(s.segment)
+}
+
+// SampleRate returns the sample rate of the underlying Reader.
+func (s *Spectrum) SampleRate() uint {
+	return s.r.SampleRate()
+}
+
+// read will read n samples into the end of the segment buffer, converting
+// them to complex64 as needed.
+func (s *Spectrum) read(dst sdr.SamplesC64) error {
+	for len(dst) > 0 {
+		n := len(dst)
+		if n > s.raw.Length() {
+			n = s.raw.Length()
+		}
+		buf := s.raw.Slice(0, n)
+		if _, err := sdr.ReadFull(s.r, buf); err != nil {
+			return err
+		}
+		if _, err := sdr.ConvertBuffer(dst[:n], buf); err != nil {
+			return err
+		}
+		dst = dst[n:]
+	}
+	return nil
+}
+
+// next will advance the segment buffer by one hop.
+func (s *Spectrum) next() error {
+	if !s.primed {
+		if err := s.read(s.segment); err != nil {
+			return err
+		}
+		s.primed = true
+		return nil
+	}
+	if s.hop >= len(s.segment) {
+		return s.read(s.segment)
+	}
+	copy(s.segment, s.segment[s.hop:])
+	return s.read(s.segment[len(s.segment)-s.hop:])
+}
+
+// Next will read enough samples to compute the next Frame, and return it.
+// The Power slice of the returned Frame is newly allocated, and may be kept
+// by the caller.
+func (s *Spectrum) Next() (Frame, error) {
+	for i := range s.acc {
+		s.acc[i] = 0
+	}
+
+	for seg := 0; seg < s.averages; seg++ {
+		if err := s.next(); err != nil {
+			return Frame{}, err
+		}
+		for i, w := range s.window {
+			s.windowed[i] = s.segment[i] * complex(w, 0)
+		}
+		if err := s.plan.Transform(); err != nil {
+			return Frame{}, err
+		}
+		for i, bin := range s.freq {
+			s.acc[i] += float64(real(bin))*float64(real(bin)) +
+				float64(imag(bin))*float64(imag(bin))
+		}
+	}
+
+	var (
+		size  = len(s.acc)
+		half  = size / 2
+		power = make([]float32, size)
+		scale = s.scale / float64(s.averages)
+	)
+	// Write the bins out in NegativeFirst order.
+	for i, p := range s.acc {
+		power[(i+half)%size] = float32(p * scale)
+	}
+
+	return Frame{
+		Power:          power,
+		SampleRate:     s.r.SampleRate(),
+		NoiseBandwidth: s.enbw,
+		Segments:       s.averages,
+	}, nil
+}
+
+// Close will release the FFT plan. This will not close the underlying
+// Reader.
+func (s *Spectrum) Close() error {
+	return s.plan.Close()
+}
+
+// vim: foldmethod=marker
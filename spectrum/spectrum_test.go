@@ -0,0 +1,198 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package spectrum_test
+
+import (
+	"io"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/spectrum"
+	"hz.tools/sdr/testutils"
+)
+
+// samplesReader is an sdr.Reader over a fixed buffer, which keeps track of
+// how many samples have been read.
+type samplesReader struct {
+	buf        sdr.Samples
+	sampleRate uint
+	read       int
+}
+
+func (r *samplesReader) SampleRate() uint               { return r.sampleRate }
+func (r *samplesReader) SampleFormat() sdr.SampleFormat { return r.buf.Format() }
+
+func (r *samplesReader) Read(buf sdr.Samples) (int, error) {
+	if r.read >= r.buf.Length() {
+		return 0, io.EOF
+	}
+	n, err := sdr.CopySamples(buf, r.buf.Slice(r.read, r.buf.Length()))
+	r.read += n
+	return n, err
+}
+
+func TestWindows(t *testing.T) {
+	assert.InDeltaSlice(t, []float32{0, 0.5, 1, 0.5}, spectrum.Hann(4), 1e-6)
+	assert.InDeltaSlice(t, spectrum.Rectangular(16), spectrum.Kaiser(0)(16), 1e-6)
+
+	for _, window := range []spectrum.Window{
+		spectrum.Hann,
+		spectrum.BlackmanHarris,
+		spectrum.Kaiser(8.6),
+	} {
+		w := window(64)
+		assert.Len(t, w, 64)
+		// Periodic windows peak in the middle, and are symmetric about it.
+		assert.InDelta(t, 1, w[32], 1e-3)
+		for i := 1; i < 32; i++ {
+			assert.InDelta(t, w[32-i], w[32+i], 1e-6)
+		}
+	}
+}
+
+func TestSpectrumTone(t *testing.T) {
+	const (
+		sampleRate = 256000
+		size       = 128
+	)
+
+	for _, window := range []spectrum.Window{
+		spectrum.Rectangular,
+		spectrum.Hann,
+		spectrum.BlackmanHarris,
+		spectrum.Kaiser(8.6),
+	} {
+		iq := make(sdr.SamplesC64, size*4)
+		testutils.CW(iq, rf.KHz*20, sampleRate, 0)
+		for i := range iq {
+			iq[i] *= 0.5
+		}
+
+		s, err := spectrum.New(&samplesReader{buf: iq, sampleRate: sampleRate}, spectrum.Config{
+			Planner:  testutils.DFTPlanner,
+			Size:     size,
+			Window:   window,
+			Overlap:  0.5,
+			Averages: 4,
+		})
+		assert.NoError(t, err)
+
+		frame, err := s.Next()
+		assert.NoError(t, err)
+		assert.NoError(t, s.Close())
+		assert.Equal(t, 4, frame.Segments)
+		assert.Len(t, frame.Power, size)
+
+		bin, err := frame.BinByFreq(rf.KHz * 20)
+		assert.NoError(t, err)
+		assert.Equal(t, size/2+10, bin)
+
+		peak := 0
+		for i, p := range frame.Power {
+			if p > frame.Power[peak] {
+				peak = i
+			}
+		}
+		assert.Equal(t, bin, peak)
+		assert.InDelta(t, 0.25, frame.Power[peak], 0.001)
+	}
+}
+
+func TestSpectrumDensity(t *testing.T) {
+	const (
+		sampleRate = 100000
+		size       = 64
+		sigma      = 0.1
+	)
+
+	r := rand.New(rand.NewSource(1))
+	iq := make(sdr.SamplesC64, size*200)
+	for i := range iq {
+		iq[i] = complex(
+			float32(r.NormFloat64()*sigma/math.Sqrt2),
+			float32(r.NormFloat64()*sigma/math.Sqrt2),
+		)
+	}
+
+	for _, window := range []spectrum.Window{
+		spectrum.Rectangular,
+		spectrum.BlackmanHarris,
+	} {
+		s, err := spectrum.New(&samplesReader{buf: iq, sampleRate: sampleRate}, spectrum.Config{
+			Planner:  testutils.DFTPlanner,
+			Size:     size,
+			Window:   window,
+			Averages: 150,
+		})
+		assert.NoError(t, err)
+		frame, err := s.Next()
+		assert.NoError(t, err)
+
+		density := make([]float32, size)
+		assert.NoError(t, frame.Density(density))
+
+		var mean float64
+		for _, d := range density {
+			mean += float64(d) / size
+		}
+		assert.InDelta(t, sigma*sigma/sampleRate, mean, sigma*sigma/sampleRate*0.1)
+	}
+}
+
+func TestSpectrumOverlap(t *testing.T) {
+	iq := make(sdr.SamplesI16, 1024)
+	sr := &samplesReader{buf: iq, sampleRate: 1000}
+
+	s, err := spectrum.New(sr, spectrum.Config{
+		Planner:  testutils.DFTPlanner,
+		Size:     64,
+		Overlap:  0.75,
+		Averages: 4,
+	})
+	assert.NoError(t, err)
+
+	_, err = s.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, 64+3*16, sr.read)
+
+	_, err = s.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, 64+7*16, sr.read)
+}
+
+func TestSpectrumConfig(t *testing.T) {
+	sr := &samplesReader{buf: make(sdr.SamplesC64, 16), sampleRate: 1000}
+
+	_, err := spectrum.New(sr, spectrum.Config{Overlap: 1})
+	assert.Equal(t, spectrum.ErrBadOverlap, err)
+
+	s, err := spectrum.New(sr, spectrum.Config{Size: 32})
+	assert.NoError(t, err)
+	_, err = s.Next()
+	assert.Error(t, err)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,108 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package spectrum
+
+import (
+	"math"
+)
+
+// Window will return the `n` coefficients of a window function, to be
+// multiplied against each segment of samples before the FFT.
+//
+// All windows in this package are periodic (rather than symmetric), which
+// is what is wanted for spectral analysis.
+type Window func(n int) []float32
+
+// Rectangular is the "no window" window, every coefficient is 1. This has
+// the narrowest main lobe, but very high sidelobes.
+func Rectangular(n int) []float32 {
+	ret := make([]float32, n)
+	for i := range ret {
+		ret[i] = 1
+	}
+	return ret
+}
+
+// Hann is a raised cosine window, and a good default for most uses.
+func Hann(n int) []float32 {
+	return cosineWindow(n, 0.5, 0.5)
+}
+
+// BlackmanHarris is the 4-term Blackman-Harris window, which has sidelobes
+// about 92 dB down, at the cost of a main lobe about twice as wide as Hann.
+// This is useful when looking for weak signals next to strong ones.
+func BlackmanHarris(n int) []float32 {
+	return cosineWindow(n, 0.35875, 0.48829, 0.14128, 0.01168)
+}
+
+// Kaiser will return a Kaiser window with the shape parameter `beta`.
+// Larger values of beta trade a wider main lobe for lower sidelobes; 0 is
+// the Rectangular window, about 5 is similar to Hann, and about 8.6 is
+// similar to Blackman.
+func Kaiser(beta float64) Window {
+	return func(n int) []float32 {
+		ret := make([]float32, n)
+		denom := besselI0(beta)
+		for i := range ret {
+			x := 2*float64(i)/float64(n) - 1
+			ret[i] = float32(besselI0(beta*math.Sqrt(1-x*x)) / denom)
+		}
+		return ret
+	}
+}
+
+// cosineWindow will compute a generalized cosine window with the provided
+// coefficients, alternating in sign.
+func cosineWindow(n int, coefficients ...float64) []float32 {
+	ret := make([]float32, n)
+	for i := range ret {
+		var (
+			acc  float64
+			sign = 1.0
+		)
+		for k, a := range coefficients {
+			acc += sign * a * math.Cos(2*math.Pi*float64(k*i)/float64(n))
+			sign = -sign
+		}
+		ret[i] = float32(acc)
+	}
+	return ret
+}
+
+// besselI0 is the zeroth order modified Bessel function of the first kind,
+// computed by its power series.
+func besselI0(x float64) float64 {
+	var (
+		sum  = 1.0
+		term = 1.0
+		half = x / 2
+	)
+	for k := 1; k < 100; k++ {
+		term *= (half / float64(k)) * (half / float64(k))
+		sum += term
+		if term < sum*1e-12 {
+			break
+		}
+	}
+	return sum
+}
+
+// vim: foldmethod=marker
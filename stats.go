@@ -0,0 +1,331 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sdr
+
+import (
+	"math"
+
+	"hz.tools/sdr/internal/simd"
+)
+
+// Stats are cheap summary statistics over a buffer of Samples, computed
+// in a single pass without converting to complex64 first. All values are
+// normalized to the same full scale as the ToC64 conversion, so a full
+// scale signal has a Peak of (about) 1 no matter the SampleFormat.
+//
+// These are intended for things like AGC, squelch, clipping detection or
+// level meters, where the numbers are needed for every buffer and the
+// cost of a conversion just to measure power adds up.
+type Stats struct {
+	// Mean is the average of all the IQ values in the buffer, which is to
+	// say, the DC offset.
+	Mean complex64
+
+	// Power is the mean squared magnitude of the buffer.
+	Power float32
+
+	// Peak is the largest magnitude seen in the buffer.
+	Peak float32
+
+	// Clipped is the number of samples where either the I or Q value was
+	// at (or beyond) full scale.
+	Clipped int
+
+	// ClippedRun is the length of the longest run of consecutive clipped
+	// samples. A few isolated clipped samples are usually harmless, but a
+	// long run is a good sign the gain is set too high.
+	ClippedRun int
+}
+
+// RMS will return the root mean square of the buffer, which is the square
+// root of the Power.
+func (s Stats) RMS() float32 {
+	return float32(math.Sqrt(float64(s.Power)))
+}
+
+// DBFS will return the Power of the buffer in dB relative to full scale.
+// An empty or silent buffer will return -Inf.
+func (s Stats) DBFS() float32 {
+	return float32(10 * math.Log10(float64(s.Power)))
+}
+
+// clipRun tracks the run-length of clipped samples.
+type clipRun struct {
+	count   int
+	current int
+	longest int
+}
+
+func (c *clipRun) add(clipped bool) {
+	if !clipped {
+		c.current = 0
+		return
+	}
+	c.count++
+	c.current++
+	if c.current > c.longest {
+		c.longest = c.current
+	}
+}
+
+// Statistics will compute the Stats of any of the Samples types known to
+// this package.
+func Statistics(s Samples) (Stats, error) {
+	switch s := s.(type) {
+	case SamplesU8:
+		return s.Stats(), nil
+	case SamplesI8:
+		return s.Stats(), nil
+	case SamplesI16:
+		return s.Stats(), nil
+	case SamplesC64:
+		return s.Stats(), nil
+	case SamplesC128:
+		return s.Stats(), nil
+	case SamplesI12Packed:
+		return s.Stats(), nil
+	default:
+		return Stats{}, ErrSampleFormatUnknown
+	}
+}
+
+// Stats will compute summary statistics over the buffer. See Stats for
+// details.
+func (s SamplesC64) Stats() Stats {
+	if len(s) == 0 {
+		return Stats{}
+	}
+
+	sum, power, peak := simd.StatsComplex(s)
+
+	// If nothing in the buffer has a magnitude of 1 or more, neither the I
+	// nor Q values can be at full scale, so there's no need to look.
+	var clip clipRun
+	if peak >= 1 {
+		for _, v := range s {
+			i, q := real(v), imag(v)
+			clip.add(i >= 1 || i <= -1 || q >= 1 || q <= -1)
+		}
+	}
+
+	n := float32(len(s))
+	return Stats{
+		Mean:       complex(real(sum)/n, imag(sum)/n),
+		Power:      power / n,
+		Peak:       float32(math.Sqrt(float64(peak))),
+		Clipped:    clip.count,
+		ClippedRun: clip.longest,
+	}
+}
+
+// Stats will compute summary statistics over the buffer. See Stats for
+// details.
+func (s SamplesI16) Stats() Stats {
+	if len(s) == 0 {
+		return Stats{}
+	}
+
+	var (
+		sumI, sumQ int64
+		power      int64
+		peak       int64
+		clip       clipRun
+	)
+
+	for _, v := range s {
+		i, q := int64(v[0]), int64(v[1])
+		sumI += i
+		sumQ += q
+		mag := i*i + q*q
+		power += mag
+		if mag > peak {
+			peak = mag
+		}
+		clip.add(i == math.MaxInt16 || i == math.MinInt16 ||
+			q == math.MaxInt16 || q == math.MinInt16)
+	}
+
+	n := float64(len(s))
+	return Stats{
+		Mean: complex(
+			float32(float64(sumI)/n/math.MaxInt16),
+			float32(float64(sumQ)/n/math.MaxInt16),
+		),
+		Power:      float32(float64(power) / n / (math.MaxInt16 * math.MaxInt16)),
+		Peak:       float32(math.Sqrt(float64(peak)) / math.MaxInt16),
+		Clipped:    clip.count,
+		ClippedRun: clip.longest,
+	}
+}
+
+// Stats will compute summary statistics over the buffer. See Stats for
+// details.
+func (s SamplesI8) Stats() Stats {
+	if len(s) == 0 {
+		return Stats{}
+	}
+
+	var (
+		sumI, sumQ int64
+		power      int64
+		peak       int64
+		clip       clipRun
+	)
+
+	for _, v := range s {
+		i, q := int64(v[0]), int64(v[1])
+		sumI += i
+		sumQ += q
+		mag := i*i + q*q
+		power += mag
+		if mag > peak {
+			peak = mag
+		}
+		clip.add(i == math.MaxInt8 || i == math.MinInt8 ||
+			q == math.MaxInt8 || q == math.MinInt8)
+	}
+
+	n := float64(len(s))
+	return Stats{
+		Mean:       complex(float32(float64(sumI)/n/128), float32(float64(sumQ)/n/128)),
+		Power:      float32(float64(power) / n / (128 * 128)),
+		Peak:       float32(math.Sqrt(float64(peak)) / 128),
+		Clipped:    clip.count,
+		ClippedRun: clip.longest,
+	}
+}
+
+// Stats will compute summary statistics over the buffer. See Stats for
+// details.
+func (s SamplesU8) Stats() Stats {
+	if len(s) == 0 {
+		return Stats{}
+	}
+
+	var (
+		sumI, sumQ int64
+		power      int64
+		peak       int64
+		clip       clipRun
+	)
+
+	// The zero point of a U8 sample is 127.5, so everything here is
+	// doubled to keep the math in integers, and undone at the end.
+	for _, v := range s {
+		i, q := int64(v[0])*2-255, int64(v[1])*2-255
+		sumI += i
+		sumQ += q
+		mag := i*i + q*q
+		power += mag
+		if mag > peak {
+			peak = mag
+		}
+		clip.add(v[0] == 0 || v[0] == math.MaxUint8 ||
+			v[1] == 0 || v[1] == math.MaxUint8)
+	}
+
+	n := float64(len(s))
+	return Stats{
+		Mean:       complex(float32(float64(sumI)/n/255), float32(float64(sumQ)/n/255)),
+		Power:      float32(float64(power) / n / (255 * 255)),
+		Peak:       float32(math.Sqrt(float64(peak)) / 255),
+		Clipped:    clip.count,
+		ClippedRun: clip.longest,
+	}
+}
+
+// Stats will compute summary statistics over the buffer. See Stats for
+// details.
+func (s SamplesC128) Stats() Stats {
+	if len(s) == 0 {
+		return Stats{}
+	}
+
+	var (
+		sum   complex128
+		power float64
+		peak  float64
+		clip  clipRun
+	)
+	for _, v := range s {
+		i, q := real(v), imag(v)
+		sum += v
+		mag := i*i + q*q
+		power += mag
+		if mag > peak {
+			peak = mag
+		}
+		clip.add(i >= 1 || i <= -1 || q >= 1 || q <= -1)
+	}
+
+	n := float64(len(s))
+	return Stats{
+		Mean:       complex64(sum / complex(n, 0)),
+		Power:      float32(power / n),
+		Peak:       float32(math.Sqrt(peak)),
+		Clipped:    clip.count,
+		ClippedRun: clip.longest,
+	}
+}
+
+// Stats will compute summary statistics over the buffer. See Stats for
+// details. Values are scaled the same way as SamplesI16, after MSB aligning
+// them.
+func (s SamplesI12Packed) Stats() Stats {
+	if len(s) == 0 {
+		return Stats{}
+	}
+
+	var (
+		sumI, sumQ int64
+		power      int64
+		peak       int64
+		clip       clipRun
+	)
+
+	for j := range s {
+		v := s.Get(j)
+		clip.add(v[0] == 2047 || v[0] == -2048 || v[1] == 2047 || v[1] == -2048)
+
+		i, q := int64(v[0])<<4, int64(v[1])<<4
+		sumI += i
+		sumQ += q
+		mag := i*i + q*q
+		power += mag
+		if mag > peak {
+			peak = mag
+		}
+	}
+
+	n := float64(len(s))
+	return Stats{
+		Mean: complex(
+			float32(float64(sumI)/n/math.MaxInt16),
+			float32(float64(sumQ)/n/math.MaxInt16),
+		),
+		Power:      float32(float64(power) / n / (math.MaxInt16 * math.MaxInt16)),
+		Peak:       float32(math.Sqrt(float64(peak)) / math.MaxInt16),
+		Clipped:    clip.count,
+		ClippedRun: clip.longest,
+	}
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,172 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sdr_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+)
+
+func statsFromC64(t *testing.T, s sdr.Samples) sdr.Stats {
+	out := make(sdr.SamplesC64, s.Length())
+	_, err := sdr.ConvertBuffer(out, s)
+	assert.NoError(t, err)
+
+	var (
+		sum   complex128
+		power float64
+		peak  float64
+	)
+	for _, v := range out {
+		sum += complex128(v)
+		mag := float64(real(v)*real(v) + imag(v)*imag(v))
+		power += mag
+		peak = math.Max(peak, mag)
+	}
+	n := float64(len(out))
+	return sdr.Stats{
+		Mean:  complex64(sum / complex(n, 0)),
+		Power: float32(power / n),
+		Peak:  float32(math.Sqrt(peak)),
+	}
+}
+
+func assertStatsNear(t *testing.T, expected, actual sdr.Stats) {
+	assert.InDelta(t, real(expected.Mean), real(actual.Mean), 1e-3)
+	assert.InDelta(t, imag(expected.Mean), imag(actual.Mean), 1e-3)
+	assert.InDelta(t, expected.Power, actual.Power, 1e-3)
+	assert.InDelta(t, expected.Peak, actual.Peak, 1e-3)
+}
+
+func TestStatsC64(t *testing.T) {
+	s := sdr.SamplesC64{
+		complex(0.5, 0),
+		complex(1, 0),
+		complex(0, -1),
+		complex(0.1, 0.1),
+		complex(-1, 1),
+	}
+	stats := s.Stats()
+	assertStatsNear(t, statsFromC64(t, s), stats)
+	assert.Equal(t, 3, stats.Clipped)
+	assert.Equal(t, 2, stats.ClippedRun)
+	assert.InDelta(t, math.Sqrt2, stats.Peak, 1e-6)
+}
+
+func TestStatsC128(t *testing.T) {
+	s := sdr.SamplesC128{
+		complex(0.5, 0),
+		complex(1, 0),
+		complex(0, -1),
+		complex(0.1, 0.1),
+		complex(-1, 1),
+	}
+	stats := s.Stats()
+	assertStatsNear(t, statsFromC64(t, s), stats)
+	assert.Equal(t, 3, stats.Clipped)
+	assert.Equal(t, 2, stats.ClippedRun)
+	assert.InDelta(t, math.Sqrt2, stats.Peak, 1e-6)
+}
+
+func TestStatsI16(t *testing.T) {
+	s := sdr.SamplesI16{
+		{1000, -1000},
+		{math.MaxInt16, 0},
+		{0, math.MinInt16},
+		{-20000, 12},
+	}
+	stats := s.Stats()
+	assertStatsNear(t, statsFromC64(t, s), stats)
+	assert.Equal(t, 2, stats.Clipped)
+	assert.Equal(t, 2, stats.ClippedRun)
+}
+
+func TestStatsI8(t *testing.T) {
+	s := sdr.SamplesI8{
+		{127, 0},
+		{10, -10},
+		{-128, 3},
+		{64, 64},
+	}
+	stats := s.Stats()
+	assertStatsNear(t, statsFromC64(t, s), stats)
+	assert.Equal(t, 2, stats.Clipped)
+	assert.Equal(t, 1, stats.ClippedRun)
+}
+
+func TestStatsU8(t *testing.T) {
+	s := sdr.SamplesU8{
+		{127, 128},
+		{200, 10},
+		{255, 127},
+		{0, 128},
+	}
+	stats := s.Stats()
+	assertStatsNear(t, statsFromC64(t, s), stats)
+	assert.Equal(t, 2, stats.Clipped)
+	assert.Equal(t, 2, stats.ClippedRun)
+}
+
+func TestStatsSilence(t *testing.T) {
+	stats := sdr.SamplesU8{{127, 128}, {128, 127}}.Stats()
+	assert.InDelta(t, 0, real(stats.Mean), 1e-6)
+	assert.InDelta(t, 0, imag(stats.Mean), 1e-6)
+	assert.True(t, stats.DBFS() < -40)
+
+	assert.Equal(t, sdr.Stats{}, sdr.SamplesC64{}.Stats())
+}
+
+func TestStatistics(t *testing.T) {
+	stats, err := sdr.Statistics(sdr.SamplesC64{complex(0.5, 0), complex(-0.5, 0)})
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.5, stats.RMS(), 1e-6)
+	assert.InDelta(t, -6.02, stats.DBFS(), 1e-2)
+}
+
+func BenchmarkStatsC64(b *testing.B) {
+	in := make(sdr.SamplesC64, 1024*16)
+	for i := range in {
+		in[i] = complex(0.5, -0.5)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		in.Stats()
+	}
+}
+
+func BenchmarkStatsI16(b *testing.B) {
+	in := make(sdr.SamplesI16, 1024*16)
+	for i := range in {
+		in[i] = [2]int16{1024, -1024}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		in.Stats()
+	}
+}
+
+// vim: foldmethod=marker
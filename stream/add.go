@@ -39,12 +39,34 @@ import (
 // sure you've adjusted the gain correctly on the readers with the stream.Gain
 // reader.
 func Add(readers ...sdr.Reader) (sdr.Reader, error) {
+	if len(readers) == 1 {
+		return readers[0], nil
+	}
+	return newAddReader(readers, nil)
+}
 
-	switch len(readers) {
-	case 0:
+// AddWithGains is Add, but each of the readers is multiplied by the
+// matching complex gain before it's summed. A real gain will scale the
+// reader, and a complex gain will rotate it as well, which can line up the
+// phase of antenna branches before they're combined.
+//
+// The readers must be SampleFormatC64, and there must be one gain for each
+// reader.
+func AddWithGains(readers []sdr.Reader, gains []complex64) (sdr.Reader, error) {
+	if len(gains) != len(readers) {
+		return nil, fmt.Errorf("stream.Add: Number of gains doesn't match the readers")
+	}
+	for _, reader := range readers {
+		if reader.SampleFormat() != sdr.SampleFormatC64 {
+			return nil, sdr.ErrSampleFormatMismatch
+		}
+	}
+	return newAddReader(readers, gains)
+}
+
+func newAddReader(readers []sdr.Reader, gains []complex64) (sdr.Reader, error) {
+	if len(readers) == 0 {
 		return nil, fmt.Errorf("stream.Add: No readers passed")
-	case 1:
-		return readers[0], nil
 	}
 
 	var (
@@ -74,6 +96,7 @@ func Add(readers ...sdr.Reader) (sdr.Reader, error) {
 		sampleFormat: sampleFormat,
 		sampleRate:   sampleRate,
 		readers:      readers,
+		gains:        gains,
 	}, nil
 }
 
@@ -81,6 +104,7 @@ type addReader struct {
 	sampleFormat sdr.SampleFormat
 	sampleRate   uint
 	readers      []sdr.Reader
+	gains        []complex64
 	err          error
 }
 
@@ -113,8 +137,12 @@ func (ar *addReader) AddI16(out sdr.SamplesI16, buffers ...sdr.Samples) {
 }
 
 func (ar *addReader) AddC64(out sdr.SamplesC64, buffers ...sdr.Samples) {
-	for _, buf := range buffers {
-		simd.AddComplex(out, buf.(sdr.SamplesC64), out)
+	for i, buf := range buffers {
+		bufC64 := buf.(sdr.SamplesC64)
+		if ar.gains != nil && ar.gains[i] != 1 {
+			bufC64.Multiply(ar.gains[i])
+		}
+		simd.AddComplex(out, bufC64, out)
 	}
 }
 
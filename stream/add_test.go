@@ -134,6 +134,58 @@ func TestAddReaderI16(t *testing.T) {
 	wg.Wait()
 }
 
+func TestAddReaderWithGains(t *testing.T) {
+	pipeReader1, pipeWriter1 := sdr.Pipe(10000, sdr.SampleFormatC64)
+	pipeReader2, pipeWriter2 := sdr.Pipe(10000, sdr.SampleFormatC64)
+
+	buf := make(sdr.SamplesC64, 1000)
+	for i := range buf {
+		buf[i] = complex64(complex(1, 2))
+	}
+
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, err := pipeWriter1.Write(buf)
+		assert.NoError(t, err)
+	}()
+	go func() {
+		defer wg.Done()
+		_, err := pipeWriter2.Write(buf)
+		assert.NoError(t, err)
+	}()
+
+	mix, err := stream.AddWithGains(
+		[]sdr.Reader{pipeReader1, pipeReader2},
+		[]complex64{0.5, 1i},
+	)
+	assert.NoError(t, err)
+
+	outBuf := make(sdr.SamplesC64, 1000)
+	_, err = sdr.ReadFull(mix, outBuf)
+	assert.NoError(t, err)
+
+	// (1+2i) * 0.5 + (1+2i) * i
+	for i := range outBuf {
+		assert.InDelta(t, -1.5, real(outBuf[i]), 1e-6)
+		assert.InDelta(t, 2, imag(outBuf[i]), 1e-6)
+	}
+
+	wg.Wait()
+}
+
+func TestAddReaderWithGainsMismatch(t *testing.T) {
+	pipeReader1, _ := sdr.Pipe(10000, sdr.SampleFormatC64)
+	pipeReader2, _ := sdr.Pipe(10000, sdr.SampleFormatI8)
+
+	_, err := stream.AddWithGains([]sdr.Reader{pipeReader1}, []complex64{1, 1})
+	assert.Error(t, err)
+
+	_, err = stream.AddWithGains([]sdr.Reader{pipeReader1, pipeReader2}, []complex64{1, 1})
+	assert.Equal(t, sdr.ErrSampleFormatMismatch, err)
+}
+
 func BenchmarkAddComplex2(b *testing.B) {
 	pipeReader, _ := sdr.Pipe(10000, sdr.SampleFormatC64)
 	mixReader, err := stream.Add(pipeReader, pipeReader, pipeReader)
@@ -0,0 +1,168 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package stream
+
+import (
+	"math"
+	"math/cmplx"
+	"time"
+
+	"hz.tools/sdr"
+)
+
+// AGCOptions controls the behavior of the AGC loop.
+type AGCOptions struct {
+	// Reference is the magnitude that the AGC will try to hold the output
+	// samples at. If 0, this will default to 0.5.
+	Reference float32
+
+	// Attack is the time constant used when the input level is rising. A
+	// short attack keeps a sudden strong signal from clipping. If 0, this
+	// will default to 1ms.
+	Attack time.Duration
+
+	// Decay is the time constant used when the input level is falling. A
+	// longer decay keeps the AGC from pumping up the noise between words
+	// or symbols. If 0, this will default to 100ms.
+	Decay time.Duration
+
+	// MaxGain is the largest gain the AGC will apply, to keep the noise
+	// floor from being amplified to full scale when there's no signal
+	// present. If 0, this will default to 10000 (80 dB).
+	MaxGain float32
+}
+
+func (o AGCOptions) getReference() float32 {
+	if o.Reference == 0 {
+		return 0.5
+	}
+	return o.Reference
+}
+
+func (o AGCOptions) getAttack() time.Duration {
+	if o.Attack == 0 {
+		return time.Millisecond
+	}
+	return o.Attack
+}
+
+func (o AGCOptions) getDecay() time.Duration {
+	if o.Decay == 0 {
+		return 100 * time.Millisecond
+	}
+	return o.Decay
+}
+
+func (o AGCOptions) getMaxGain() float32 {
+	if o.MaxGain == 0 {
+		return 10000
+	}
+	return o.MaxGain
+}
+
+// agcCoefficient returns the one-pole smoothing coefficient for the time
+// constant tau at the provided sample rate.
+func agcCoefficient(tau time.Duration, sampleRate uint) float32 {
+	samples := tau.Seconds() * float64(sampleRate)
+	if samples < 1 {
+		return 1
+	}
+	return float32(1 - math.Exp(-1/samples))
+}
+
+type agcReader struct {
+	r sdr.Reader
+
+	reference float32
+	attack    float32
+	decay     float32
+	maxGain   float32
+
+	// envelope is the tracked magnitude of the input signal.
+	envelope float32
+}
+
+func (a *agcReader) SampleFormat() sdr.SampleFormat {
+	return sdr.SampleFormatC64
+}
+
+func (a *agcReader) SampleRate() uint {
+	return a.r.SampleRate()
+}
+
+func (a *agcReader) Read(s sdr.Samples) (int, error) {
+	sC64, ok := s.(sdr.SamplesC64)
+	if !ok {
+		return 0, sdr.ErrSampleFormatMismatch
+	}
+
+	i, err := a.r.Read(sC64)
+	if i > 0 {
+		a.process(sC64[:i])
+	}
+	return i, err
+}
+
+func (a *agcReader) process(s sdr.SamplesC64) {
+	var (
+		envelope = a.envelope
+		minEnv   = a.reference / a.maxGain
+	)
+	for i, sample := range s {
+		mag := float32(cmplx.Abs(complex128(sample)))
+		if mag > envelope {
+			envelope += (mag - envelope) * a.attack
+		} else {
+			envelope += (mag - envelope) * a.decay
+		}
+
+		gain := a.maxGain
+		if envelope > minEnv {
+			gain = a.reference / envelope
+		}
+		s[i] = sample * complex(gain, 0)
+	}
+	a.envelope = envelope
+}
+
+// AGC will apply a software automatic gain control loop to the samples read
+// from the provided Reader, scaling the output so that the magnitude of the
+// signal is held near opts.Reference.
+//
+// The level of the input is tracked with a fast attack (to catch strong
+// signals before they clip) and a slower decay. This is handy in front of
+// demodulators that expect a normalized amplitude. Only SampleFormatC64 is
+// supported.
+func AGC(r sdr.Reader, opts AGCOptions) (sdr.Reader, error) {
+	if r.SampleFormat() != sdr.SampleFormatC64 {
+		return nil, sdr.ErrSampleFormatMismatch
+	}
+	sampleRate := r.SampleRate()
+	return &agcReader{
+		r:         r,
+		reference: opts.getReference(),
+		attack:    agcCoefficient(opts.getAttack(), sampleRate),
+		decay:     agcCoefficient(opts.getDecay(), sampleRate),
+		maxGain:   opts.getMaxGain(),
+	}, nil
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,87 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package stream_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/stream"
+)
+
+func TestAGC(t *testing.T) {
+	for _, level := range []float32{0.01, 0.1, 1.5} {
+		in, stop := toneReader(1000000, 64, sdr.SampleFormatC64)
+		r, err := stream.AGC(stream.Gain(in, level), stream.AGCOptions{
+			Reference: 0.25,
+		})
+		assert.NoError(t, err)
+
+		rms, _ := readRMS(t, r, 1000000)
+		assert.InDelta(t, 0.25, rms, 0.01, "level %f", level)
+		stop()
+	}
+}
+
+func TestAGCMaxGain(t *testing.T) {
+	in, stop := toneReader(1000000, 64, sdr.SampleFormatC64)
+	defer stop()
+	r, err := stream.AGC(stream.Gain(in, 0.001), stream.AGCOptions{
+		MaxGain: 10,
+		Decay:   time.Millisecond,
+	})
+	assert.NoError(t, err)
+
+	// 0.5 * 0.001 * 10
+	rms, _ := readRMS(t, r, 100000)
+	assert.InDelta(t, 0.005, rms, 0.0005)
+}
+
+func TestAGCAttack(t *testing.T) {
+	// Settle on a quiet signal, then jump up by 40 dB.
+	pipeReader, pipeWriter := sdr.Pipe(1000000, sdr.SampleFormatC64)
+	go func() {
+		quiet := make(sdr.SamplesC64, 500000)
+		loud := make(sdr.SamplesC64, 20000)
+		for i := range quiet {
+			quiet[i] = complex(0.005, 0)
+		}
+		for i := range loud {
+			loud[i] = complex(0.5, 0)
+		}
+		pipeWriter.Write(quiet)
+		pipeWriter.Write(loud)
+		pipeWriter.Close()
+	}()
+
+	r, err := stream.AGC(pipeReader, stream.AGCOptions{Reference: 0.25})
+	assert.NoError(t, err)
+
+	rms, _ := readRMS(t, r, 500000)
+	assert.InDelta(t, 0.25, rms, 0.01)
+
+	// With a 1ms attack, the gain is back in line well within 10ms.
+	_, out := readRMS(t, r, 20000)
+	assert.InDelta(t, 0.25, out[10000:].Stats().RMS(), 0.01)
+}
@@ -35,6 +35,7 @@ type Beamform struct {
 	sdr.Reader
 
 	readers sdr.Readers
+	mvdr    *mvdrReader
 	config  BeamformConfig
 }
 
@@ -129,6 +130,9 @@ func BeamformAngles(
 
 // SetPhaseAngles will set the phase angle to shift every stream by.
 func (b *Beamform) SetPhaseAngles(angles []complex64) error {
+	if b.mvdr != nil {
+		return b.mvdr.SetPhaseAngles(angles)
+	}
 	if len(angles) != len(b.readers) {
 		return fmt.Errorf("Beamform.SetPhaseAngles: angles must match the reader length")
 	}
@@ -138,14 +142,69 @@ func (b *Beamform) SetPhaseAngles(angles []complex64) error {
 	return nil
 }
 
+// BeamformMode selects how the Beamform Reader combines the streams.
+type BeamformMode int
+
+const (
+	// BeamformDelayAndSum will rotate each stream by the phase angle, and
+	// add them together. The gain towards the beam is the number of
+	// streams.
+	BeamformDelayAndSum BeamformMode = iota
+
+	// BeamformMVDR will weight each stream to pass the beam with a gain
+	// of one, while minimizing everything else (the Minimum Variance
+	// Distortionless Response, or Capon, beamformer), which will null out
+	// strong signals arriving from other directions. The weights are
+	// computed from a running estimate of the covariance of the streams.
+	BeamformMVDR
+)
+
 // BeamformConfig contains configuration for the combined samples.
 type BeamformConfig struct {
 	Angles []complex64
+
+	// Mode selects the beamformer to use. By default, this is
+	// BeamformDelayAndSum.
+	Mode BeamformMode
+
+	// Smoothing is how much of the covariance of each Read is folded into
+	// the running covariance estimate, from 0 to 1, when using
+	// BeamformMVDR. If 0, this will default to 0.1.
+	Smoothing float64
+
+	// DiagonalLoading is added to the diagonal of the covariance (scaled
+	// by the average power of the streams) before computing the
+	// BeamformMVDR weights, which keeps the beamformer from going wild
+	// when the covariance is poorly conditioned, at the cost of shallower
+	// nulls. If 0, this will default to 0.01.
+	DiagonalLoading float64
+}
+
+func (c BeamformConfig) getSmoothing() float64 {
+	if c.Smoothing == 0 {
+		return 0.1
+	}
+	return c.Smoothing
+}
+
+func (c BeamformConfig) getDiagonalLoading() float64 {
+	if c.DiagonalLoading == 0 {
+		return 0.01
+	}
+	return c.DiagonalLoading
 }
 
 // ReadBeamform will create a new sdr.Reader from a series of coherent
 // sdr.Readers using the provided phase angles.
 func ReadBeamform(rs sdr.Readers, cfg BeamformConfig) (*Beamform, error) {
+	switch cfg.Mode {
+	case BeamformDelayAndSum:
+	case BeamformMVDR:
+		return readBeamformMVDR(rs, cfg)
+	default:
+		return nil, fmt.Errorf("ReadBeamform: unknown beamform mode")
+	}
+
 	multReaders := make(sdr.Readers, len(rs))
 	for i := range rs {
 		reader, err := ConvertReader(rs[i], sdr.SampleFormatC64)
@@ -0,0 +1,273 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package stream
+
+import (
+	"fmt"
+	"math/cmplx"
+	"sync"
+
+	"hz.tools/sdr"
+)
+
+// mvdrReader is the BeamformMVDR implementation of the Beamform Reader.
+type mvdrReader struct {
+	sampleRate uint
+	readers    sdr.Readers
+	bufs       []sdr.SamplesC64
+
+	smoothing float64
+	loading   float64
+
+	lock       *sync.Mutex
+	steering   []complex128
+	covariance [][]complex128
+	primed     bool
+	weights    []complex64
+	err        error
+}
+
+func readBeamformMVDR(rs sdr.Readers, cfg BeamformConfig) (*Beamform, error) {
+	if len(rs) == 0 {
+		return nil, fmt.Errorf("ReadBeamform: no readers passed")
+	}
+
+	readers := make(sdr.Readers, len(rs))
+	for i := range rs {
+		if rs[i].SampleRate() != rs[0].SampleRate() {
+			return nil, fmt.Errorf("ReadBeamform: readers are not all the same rate")
+		}
+		var err error
+		readers[i], err = ConvertReader(rs[i], sdr.SampleFormatC64)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	covariance := make([][]complex128, len(rs))
+	for i := range covariance {
+		covariance[i] = make([]complex128, len(rs))
+	}
+
+	mr := &mvdrReader{
+		sampleRate: rs[0].SampleRate(),
+		readers:    readers,
+		bufs:       make([]sdr.SamplesC64, len(rs)),
+		smoothing:  cfg.getSmoothing(),
+		loading:    cfg.getDiagonalLoading(),
+		lock:       &sync.Mutex{},
+		covariance: covariance,
+	}
+
+	angles := cfg.Angles
+	if angles == nil {
+		angles = make([]complex64, len(rs))
+		for i := range angles {
+			angles[i] = 1
+		}
+	}
+	if err := mr.SetPhaseAngles(angles); err != nil {
+		return nil, err
+	}
+
+	return &Beamform{
+		Reader: mr,
+		mvdr:   mr,
+		config: cfg,
+	}, nil
+}
+
+// SetPhaseAngles will set the steering vector, from the same phase angles
+// as would be used for delay-and-sum.
+func (mr *mvdrReader) SetPhaseAngles(angles []complex64) error {
+	if len(angles) != len(mr.readers) {
+		return fmt.Errorf("Beamform.SetPhaseAngles: angles must match the reader length")
+	}
+
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	// The phase angles rotate each stream to line up with the beam, so the
+	// steering vector (the phase each stream sees from the beam) is the
+	// conjugate of that.
+	mr.steering = make([]complex128, len(angles))
+	for i, angle := range angles {
+		mr.steering[i] = cmplx.Conj(complex128(angle))
+	}
+	if mr.primed {
+		mr.updateWeights()
+	}
+	return nil
+}
+
+func (mr *mvdrReader) SampleFormat() sdr.SampleFormat {
+	return sdr.SampleFormatC64
+}
+
+func (mr *mvdrReader) SampleRate() uint {
+	return mr.sampleRate
+}
+
+func (mr *mvdrReader) Read(s sdr.Samples) (int, error) {
+	if mr.err != nil {
+		return 0, mr.err
+	}
+
+	out, ok := s.(sdr.SamplesC64)
+	if !ok {
+		return 0, sdr.ErrSampleFormatMismatch
+	}
+
+	for i, reader := range mr.readers {
+		if cap(mr.bufs[i]) < len(out) {
+			mr.bufs[i] = make(sdr.SamplesC64, len(out))
+		}
+		mr.bufs[i] = mr.bufs[i][:len(out)]
+		if _, err := sdr.ReadFull(reader, mr.bufs[i]); err != nil {
+			// Just like stream.Add, a partial read from any one reader
+			// leaves the streams out of alignment, so this is sticky.
+			mr.err = err
+			return 0, err
+		}
+	}
+
+	mr.lock.Lock()
+	mr.updateCovariance(len(out))
+	mr.updateWeights()
+	weights := mr.weights
+	mr.lock.Unlock()
+
+	for si := range out {
+		var acc complex64
+		for i, w := range weights {
+			acc += w * mr.bufs[i][si]
+		}
+		out[si] = acc
+	}
+	return len(out), nil
+}
+
+// updateCovariance will fold the covariance of the current buffers into the
+// running estimate. The lock must be held.
+func (mr *mvdrReader) updateCovariance(n int) {
+	if n == 0 {
+		return
+	}
+
+	alpha := complex(mr.smoothing, 0)
+	if !mr.primed {
+		alpha = 1
+		mr.primed = true
+	}
+
+	for i := range mr.bufs {
+		for j := i; j < len(mr.bufs); j++ {
+			var acc complex128
+			for k := 0; k < n; k++ {
+				acc += complex128(mr.bufs[i][k] * complex(real(mr.bufs[j][k]), -imag(mr.bufs[j][k])))
+			}
+			acc /= complex(float64(n), 0)
+
+			mr.covariance[i][j] = (1-alpha)*mr.covariance[i][j] + alpha*acc
+			mr.covariance[j][i] = cmplx.Conj(mr.covariance[i][j])
+		}
+	}
+}
+
+// updateWeights will compute the MVDR weights from the covariance and the
+// steering vector. The lock must be held.
+func (mr *mvdrReader) updateWeights() {
+	var (
+		n     = len(mr.steering)
+		power float64
+	)
+	for i := 0; i < n; i++ {
+		power += real(mr.covariance[i][i])
+	}
+	power /= float64(n)
+
+	r := make([][]complex128, n)
+	for i := range r {
+		r[i] = append([]complex128{}, mr.covariance[i]...)
+		r[i][i] += complex(mr.loading*power, 0)
+	}
+
+	// w = R^-1 a / (a^H R^-1 a)
+	u, ok := solveComplex(r, mr.steering)
+	var norm complex128
+	for i := range u {
+		norm += cmplx.Conj(mr.steering[i]) * u[i]
+	}
+	if !ok || norm == 0 {
+		// There's nothing to go on (such as all zero input), so fall back
+		// to the (normalized) delay-and-sum weights.
+		u = mr.steering
+		norm = complex(float64(n), 0)
+	}
+
+	// The output is w^H x, so store the conjugate of the weights to
+	// multiply each stream by.
+	mr.weights = make([]complex64, n)
+	for i := range u {
+		mr.weights[i] = complex64(cmplx.Conj(u[i] / norm))
+	}
+}
+
+// solveComplex will solve the linear system a x = b using Gaussian
+// elimination with partial pivoting. a is modified.
+func solveComplex(a [][]complex128, b []complex128) ([]complex128, bool) {
+	var (
+		n = len(b)
+		x = append([]complex128{}, b...)
+	)
+
+	for col := 0; col < n; col++ {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if cmplx.Abs(a[row][col]) > cmplx.Abs(a[pivot][col]) {
+				pivot = row
+			}
+		}
+		if a[pivot][col] == 0 {
+			return nil, false
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		x[col], x[pivot] = x[pivot], x[col]
+
+		for row := col + 1; row < n; row++ {
+			f := a[row][col] / a[col][col]
+			for k := col; k < n; k++ {
+				a[row][k] -= f * a[col][k]
+			}
+			x[row] -= f * x[col]
+		}
+	}
+
+	for row := n - 1; row >= 0; row-- {
+		for k := row + 1; k < n; k++ {
+			x[row] -= a[row][k] * x[k]
+		}
+		x[row] /= a[row][row]
+	}
+	return x, true
+}
+
+// vim: foldmethod=marker
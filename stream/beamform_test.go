@@ -28,6 +28,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"hz.tools/rf"
+	"hz.tools/sdr"
 	"hz.tools/sdr/stream"
 )
 
@@ -246,4 +247,72 @@ func TestBeamformMath2DWavelengthHard(t *testing.T) {
 	assert.InEpsilon(t, -math.Pi, cmplx.Phase(cmplx.Conj(complex128(rotations[1]))), 1e-4)
 }
 
+// beamformGains will return the gain of the provided beamformer towards
+// signals from the `look` and `jam` angles.
+func beamformGains(t *testing.T, mode stream.BeamformMode, look, jam float64) (float64, float64) {
+	var (
+		freq      = 900 * rf.MHz
+		distances = []float64{0, 0.1, 0.2, 0.3}
+		lookA     = stream.BeamformAngles(freq, look, distances)
+		jamA      = stream.BeamformAngles(freq, jam, distances)
+
+		signal = make(sdr.SamplesC64, 1000)
+		jammer = make(sdr.SamplesC64, 1000)
+	)
+	for i := range signal {
+		signal[i] = complex64(cmplx.Rect(0.1, 2*math.Pi*10*float64(i)/1000))
+		jammer[i] = complex64(cmplx.Rect(0.8, 2*math.Pi*37*float64(i)/1000))
+	}
+
+	readers := make(sdr.Readers, len(distances))
+	for i := range readers {
+		pattern := make(sdr.SamplesC64, len(signal))
+		for j := range pattern {
+			pattern[j] = signal[j]*complex64(cmplx.Conj(complex128(lookA[i]))) +
+				jammer[j]*complex64(cmplx.Conj(complex128(jamA[i])))
+		}
+		r, stop := patternReader(1e6, pattern)
+		defer stop()
+		readers[i] = r
+	}
+
+	bf, err := stream.ReadBeamform(readers, stream.BeamformConfig{
+		Angles: lookA,
+		Mode:   mode,
+	})
+	assert.NoError(t, err)
+
+	out := make(sdr.SamplesC64, len(signal))
+	for i := 0; i < 20; i++ {
+		_, err = sdr.ReadFull(bf, out)
+		assert.NoError(t, err)
+	}
+
+	gain := func(ref sdr.SamplesC64) float64 {
+		var (
+			cross complex128
+			pow   float64
+		)
+		for i := range ref {
+			cross += complex128(out[i]) * cmplx.Conj(complex128(ref[i]))
+			pow += real(complex128(ref[i]) * cmplx.Conj(complex128(ref[i])))
+		}
+		return cmplx.Abs(cross) / pow
+	}
+	return gain(signal), gain(jammer)
+}
+
+func TestBeamformMVDR(t *testing.T) {
+	look, jam := beamformGains(t, stream.BeamformDelayAndSum, 0, 35)
+	assert.InDelta(t, 4, look, 0.01)
+	dasRejection := jam / look
+
+	look, jam = beamformGains(t, stream.BeamformMVDR, 0, 35)
+	assert.InDelta(t, 1, look, 0.05)
+	mvdrRejection := jam / look
+
+	assert.Less(t, mvdrRejection, 0.05)
+	assert.Less(t, mvdrRejection, dasRejection/4)
+}
+
 // vim: foldmethod=marker
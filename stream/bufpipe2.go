@@ -39,15 +39,26 @@ func dupe(s1 sdr.Samples) (sdr.Samples, int, error) {
 
 // BufPipe2 is a new (more stable?) and experimental implementation
 // of a buffered sdr.Pipe
+//
+// Writes will copy the samples into an internal buffer, and return without
+// waiting for a Read, unless the buffer is full. Close and CloseWithError may
+// be called from any goroutine. Any Write that returned before the BufPipe2
+// was closed will be readable before the close error is returned to the
+// reader, and any Write blocked on a full buffer will return the close error.
 type BufPipe2 struct {
 	lock *sync.Mutex
 
 	sampleRate   uint
 	sampleFormat sdr.SampleFormat
 
-	err    error
-	closed bool
-	buf    chan sdr.Samples
+	// err and closed are guarded by lock. done is closed when the BufPipe2
+	// has been closed, and inflight tracks Writes which have passed the
+	// closed check, but have not yet returned.
+	err      error
+	closed   bool
+	done     chan struct{}
+	inflight *sync.WaitGroup
+	buf      chan sdr.Samples
 
 	pipeReader sdr.PipeReader
 	pipeWriter sdr.PipeWriter
@@ -64,24 +75,36 @@ func (p *BufPipe2) SampleRate() uint {
 }
 
 // CloseWithError will close the pipe, and return the provided error on any
-// subsequent call.
+// subsequent call. Only the first call to Close or CloseWithError will set
+// the error.
 func (p *BufPipe2) CloseWithError(err error) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.closed {
+		return nil
+	}
+	p.closed = true
 	p.err = err
-	p.Close()
+	close(p.done)
 	return nil
 }
 
 // Close will close the BufPipe, which will remain readable until the end
 // of buffered data.
 func (p *BufPipe2) Close() error {
+	return p.CloseWithError(nil)
+}
+
+func (p *BufPipe2) getErr() error {
 	p.lock.Lock()
 	defer p.lock.Unlock()
-	if p.closed {
+	if !p.closed {
 		return nil
 	}
-	p.closed = true
-	close(p.buf)
-	return nil
+	if p.err == nil {
+		return sdr.ErrPipeClosed
+	}
+	return p.err
 }
 
 // Read implements the sdr.ReadWriter interface.
@@ -97,44 +120,51 @@ func (p *BufPipe2) Write(s sdr.Samples) (int, error) {
 	}
 
 	p.lock.Lock()
-	defer p.lock.Unlock()
 	if p.closed {
-		if p.err == nil {
-			return 0, sdr.ErrPipeClosed
-		}
-		return 0, p.err
+		p.lock.Unlock()
+		return 0, p.getErr()
+	}
+	p.inflight.Add(1)
+	p.lock.Unlock()
+	defer p.inflight.Done()
+
+	select {
+	case p.buf <- s2:
+		return i, nil
+	case <-p.done:
+		return 0, p.getErr()
 	}
-	// TODO(paultag): add in blocking handling
-	p.buf <- s2
-	return i, nil
 }
 
 func (p *BufPipe2) do() {
-	defer p.Close()
-	defer func() { p.pipeWriter.CloseWithError(p.err) }()
-
 	for {
 		select {
-		case s1, ok := <-p.buf:
-			if !ok {
-				p.lock.Lock()
-				p.err = sdr.ErrPipeClosed
-				p.lock.Unlock()
-				return
-			}
-			_, err := p.pipeWriter.Write(s1)
-			if err != nil {
+		case s1 := <-p.buf:
+			if _, err := p.pipeWriter.Write(s1); err != nil {
 				// If we caught an error and we don't have an error
 				// ourselves (like a context error), we're going to
 				// go ahead and set the error condition and bail.
-				p.lock.Lock()
-				if p.err == nil {
-					p.err = err
-					p.CloseWithError(err)
-				}
-				p.lock.Unlock()
+				p.CloseWithError(err)
+				p.pipeWriter.CloseWithError(p.getErr())
 				return
 			}
+		case <-p.done:
+			// Wait for any Writes that raced the Close to either land in
+			// the buffer or give up, and then drain what's left before
+			// passing the error along to the reader.
+			p.inflight.Wait()
+			for {
+				select {
+				case s1 := <-p.buf:
+					if _, err := p.pipeWriter.Write(s1); err != nil {
+						p.pipeWriter.CloseWithError(err)
+						return
+					}
+				default:
+					p.pipeWriter.CloseWithError(p.getErr())
+					return
+				}
+			}
 		}
 	}
 }
@@ -145,9 +175,11 @@ func NewBufPipe2(capacity int, sampleRate uint, sampleFormat sdr.SampleFormat) (
 	pipeReader, pipeWriter := sdr.Pipe(sampleRate, sampleFormat)
 	buf := make(chan sdr.Samples, capacity)
 	pipe := &BufPipe2{
-		lock: &sync.Mutex{},
-		err:  nil,
-		buf:  buf,
+		lock:     &sync.Mutex{},
+		err:      nil,
+		done:     make(chan struct{}),
+		inflight: &sync.WaitGroup{},
+		buf:      buf,
 
 		sampleRate:   sampleRate,
 		sampleFormat: sampleFormat,
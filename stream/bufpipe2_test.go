@@ -22,6 +22,7 @@ package stream_test
 
 import (
 	"fmt"
+	"io"
 	"sync"
 	"testing"
 
@@ -87,6 +88,79 @@ func TestBufPipe2Close(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestBufPipe2CloseWithError(t *testing.T) {
+	pipe, err := stream.NewBufPipe2(4, 0, sdr.SampleFormatU8)
+	assert.NoError(t, err)
+
+	_, err = pipe.Write(make(sdr.SamplesU8, 10))
+	assert.NoError(t, err)
+
+	assert.NoError(t, pipe.CloseWithError(io.EOF))
+	assert.NoError(t, pipe.CloseWithError(io.ErrUnexpectedEOF))
+
+	_, err = pipe.Write(make(sdr.SamplesU8, 10))
+	assert.Equal(t, io.EOF, err)
+
+	n, err := pipe.Read(make(sdr.SamplesU8, 10))
+	assert.NoError(t, err)
+	assert.Equal(t, 10, n)
+
+	_, err = pipe.Read(make(sdr.SamplesU8, 10))
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestBufPipe2CloseStress(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		pipe, err := stream.NewBufPipe2(2, 0, sdr.SampleFormatU8)
+		assert.NoError(t, err)
+
+		var (
+			wg      = sync.WaitGroup{}
+			lock    = sync.Mutex{}
+			written int
+			read    int
+		)
+
+		for j := 0; j < 4; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				buf := make(sdr.SamplesU8, 16)
+				for {
+					n, err := pipe.Write(buf)
+					if err != nil {
+						return
+					}
+					lock.Lock()
+					written += n
+					lock.Unlock()
+				}
+			}()
+		}
+
+		readDone := make(chan struct{})
+		go func() {
+			defer close(readDone)
+			buf := make(sdr.SamplesU8, 16)
+			for {
+				n, err := pipe.Read(buf)
+				read += n
+				if err != nil {
+					assert.Equal(t, sdr.ErrPipeClosed, err)
+					return
+				}
+			}
+		}()
+
+		assert.NoError(t, pipe.Close())
+		wg.Wait()
+		<-readDone
+
+		// Every successful Write must have been seen by the reader.
+		assert.Equal(t, written, read)
+	}
+}
+
 func BenchmarkBufPipe2(b *testing.B) {
 	for _, i := range []int{0, 1, 8, 128} {
 		b.Run(fmt.Sprintf("Cap-%d", i), func(b *testing.B) {
@@ -0,0 +1,108 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package stream
+
+import (
+	"fmt"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/filter"
+)
+
+type cancelReader struct {
+	primary   sdr.Reader
+	reference sdr.Reader
+	canceller filter.Canceller
+
+	buf sdr.SamplesC64
+	err error
+}
+
+// CancelInterference will return a Reader which reads from the primary
+// Reader, and removes the interference present on the reference Reader
+// (such as an auxiliary antenna pointed at a co-located transmitter), using
+// the provided adaptive filter (such as filter.NewLMS or filter.NewRLS).
+//
+// The two Readers must be coherent and sample aligned, and at the same
+// sample rate. Samples are converted to SampleFormatC64 if needed.
+func CancelInterference(
+	primary, reference sdr.Reader,
+	canceller filter.Canceller,
+) (sdr.Reader, error) {
+	if primary.SampleRate() != reference.SampleRate() {
+		return nil, fmt.Errorf("stream.CancelInterference: readers are not the same rate")
+	}
+
+	var err error
+	primary, err = ConvertReader(primary, sdr.SampleFormatC64)
+	if err != nil {
+		return nil, err
+	}
+	reference, err = ConvertReader(reference, sdr.SampleFormatC64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cancelReader{
+		primary:   primary,
+		reference: reference,
+		canceller: canceller,
+	}, nil
+}
+
+func (cr *cancelReader) SampleFormat() sdr.SampleFormat {
+	return sdr.SampleFormatC64
+}
+
+func (cr *cancelReader) SampleRate() uint {
+	return cr.primary.SampleRate()
+}
+
+func (cr *cancelReader) Read(s sdr.Samples) (int, error) {
+	if cr.err != nil {
+		return 0, cr.err
+	}
+
+	out, ok := s.(sdr.SamplesC64)
+	if !ok {
+		return 0, sdr.ErrSampleFormatMismatch
+	}
+
+	if cap(cr.buf) < len(out) {
+		cr.buf = make(sdr.SamplesC64, len(out))
+	}
+	ref := cr.buf[:len(out)]
+
+	// Just like stream.Add, a partial read from either reader leaves the
+	// streams out of alignment, so errors are sticky.
+	if _, err := sdr.ReadFull(cr.primary, out); err != nil {
+		cr.err = err
+		return 0, err
+	}
+	if _, err := sdr.ReadFull(cr.reference, ref); err != nil {
+		cr.err = err
+		return 0, err
+	}
+
+	return cr.canceller.ProcessC64(out, out, ref)
+}
+
+// vim: foldmethod=marker
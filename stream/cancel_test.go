@@ -0,0 +1,75 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package stream_test
+
+import (
+	"math/cmplx"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/filter"
+	"hz.tools/sdr/stream"
+	"hz.tools/sdr/testutils"
+)
+
+func TestCancelInterference(t *testing.T) {
+	var (
+		signal    = make(sdr.SamplesC64, 1000)
+		jammer    = make(sdr.SamplesC64, 1000)
+		primary   = make(sdr.SamplesC64, 1000)
+		reference = make(sdr.SamplesC64, 1000)
+	)
+	testutils.CW(signal, rf.Hz(10e3), 1e6, 0)
+	testutils.MultiTone(jammer, 1e6,
+		testutils.Tone{Frequency: rf.Hz(37e3), Amplitude: 5},
+		testutils.Tone{Frequency: rf.Hz(-120e3), Amplitude: 3},
+	)
+	for i := range primary {
+		signal[i] *= 0.1
+		primary[i] = signal[i] + jammer[i]*(0.6+0.2i)
+		reference[i] = jammer[i]
+	}
+
+	primaryReader, stopPrimary := patternReader(1e6, primary)
+	defer stopPrimary()
+	referenceReader, stopReference := patternReader(1e6, reference)
+	defer stopReference()
+
+	rls, err := filter.NewRLS(2, 0.999, 1)
+	assert.NoError(t, err)
+	r, err := stream.CancelInterference(primaryReader, referenceReader, rls)
+	assert.NoError(t, err)
+	assert.Equal(t, sdr.SampleFormatC64, r.SampleFormat())
+
+	out := make(sdr.SamplesC64, len(signal))
+	for i := 0; i < 5; i++ {
+		_, err = sdr.ReadFull(r, out)
+		assert.NoError(t, err)
+	}
+	for i := range out {
+		assert.InDelta(t, 0, cmplx.Abs(complex128(out[i]-signal[i])), 0.01)
+	}
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,217 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package stream
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+)
+
+var (
+	// ErrChannelOutOfBand will be returned if a Channel would extend past
+	// the edges of the Channelizer's bandwidth.
+	ErrChannelOutOfBand = fmt.Errorf("stream: channel is outside of the channelizer bandwidth")
+)
+
+// ChannelizerOptions contains configurable options for a Channelizer.
+type ChannelizerOptions struct {
+	// BlockLength is the number of samples read from the wideband Reader
+	// at a time, and handed to each Channel. If 0, this will default to
+	// 32768.
+	BlockLength int
+
+	// Buffer is the number of blocks that may be queued for each Channel.
+	// If a Channel falls further behind than this, blocks are dropped for
+	// that Channel (see Channel.Dropped), rather than holding up every
+	// other Channel. If 0, this will default to 16.
+	Buffer int
+}
+
+func (opts ChannelizerOptions) getBlockLength() int {
+	if opts.BlockLength == 0 {
+		return 32 * 1024
+	}
+	return opts.BlockLength
+}
+
+func (opts ChannelizerOptions) getBuffer() int {
+	if opts.Buffer == 0 {
+		return 16
+	}
+	return opts.Buffer
+}
+
+// Channelizer will read a single wideband stream of IQ samples, and serve
+// any number of narrower Channels from it, each with its own offset from
+// the center frequency and sample rate. Each Channel is a frequency shift
+// followed by a Resample (see DownConvert), fed by its own branch of a
+// Splitter with the TeeDrop Policy, so that one slow consumer doesn't stall
+// the others.
+type Channelizer struct {
+	r        sdr.Reader
+	opts     ChannelizerOptions
+	splitter *Splitter
+}
+
+// NewChannelizer will create a new Channelizer, and start reading from the
+// provided Reader. Readers that aren't SampleFormatC64 are converted first
+// (see ConvertReader).
+//
+// The Channelizer will read from the Reader until it returns an error, or
+// Close is called. The Reader is not closed by the Channelizer.
+func NewChannelizer(r sdr.Reader, opts ChannelizerOptions) (*Channelizer, error) {
+	if r.SampleFormat() != sdr.SampleFormatC64 {
+		var err error
+		r, err = ConvertReader(r, sdr.SampleFormatC64)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Channelizer{
+		r:        r,
+		opts:     opts,
+		splitter: NewSplitter(r, opts.getBlockLength()),
+	}, nil
+}
+
+// SampleRate is the sample rate of the wideband Reader.
+func (c *Channelizer) SampleRate() uint {
+	return c.r.SampleRate()
+}
+
+// checkBand will ensure that a channel at the provided offset and sample
+// rate fits within the wideband stream.
+func (c *Channelizer) checkBand(offset rf.Hz, sampleRate uint) error {
+	var (
+		wide  = rf.Hz(c.SampleRate()) / 2
+		half  = rf.Hz(sampleRate) / 2
+		upper = offset + half
+		lower = offset - half
+	)
+	if sampleRate == 0 || upper > wide || lower < -wide {
+		return ErrChannelOutOfBand
+	}
+	return nil
+}
+
+// Channel will create a new Channel, centered `offset` from the center of
+// the wideband stream, at the provided sample rate. The Channel must be
+// Closed when it's no longer needed.
+func (c *Channelizer) Channel(offset rf.Hz, sampleRate uint) (*Channel, error) {
+	if err := c.checkBand(offset, sampleRate); err != nil {
+		return nil, err
+	}
+
+	input, err := c.splitter.Branch(TeeBranch{
+		Policy:  TeeDrop,
+		Buffers: c.opts.getBuffer(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	shifted, err := ShiftFrequency(input, offset)
+	if err != nil {
+		input.Close()
+		return nil, err
+	}
+	resampled, err := Resample(shifted, sampleRate)
+	if err != nil {
+		input.Close()
+		return nil, err
+	}
+
+	return &Channel{
+		Reader:      resampled,
+		channelizer: c,
+		input:       input,
+		shifter:     shifted,
+		offset:      offset,
+	}, nil
+}
+
+// Close will stop reading from the wideband Reader, and close every
+// Channel. The wideband Reader is not closed.
+func (c *Channelizer) Close() error {
+	return c.splitter.Close()
+}
+
+// Channel is a narrow slice of the Channelizer's wideband stream, shifted to
+// DC and resampled. It's an sdr.ReadCloser of SampleFormatC64.
+type Channel struct {
+	sdr.Reader
+
+	channelizer *Channelizer
+	input       *TeeReader
+	shifter     sdr.Reader
+
+	lock   sync.Mutex
+	offset rf.Hz
+}
+
+// Offset returns the current offset of the Channel from the center of the
+// wideband stream.
+func (ch *Channel) Offset() rf.Hz {
+	ch.lock.Lock()
+	defer ch.lock.Unlock()
+	return ch.offset
+}
+
+// SetOffset will retune the Channel to a new offset from the center of the
+// wideband stream, without a discontinuity in phase.
+func (ch *Channel) SetOffset(offset rf.Hz) error {
+	if err := ch.channelizer.checkBand(offset, ch.SampleRate()); err != nil {
+		return err
+	}
+
+	ch.lock.Lock()
+	defer ch.lock.Unlock()
+	if err := Reconfigure(context.Background(), ch.shifter, Params{
+		"offset": offset,
+	}); err != nil {
+		return err
+	}
+	ch.offset = offset
+	return nil
+}
+
+// Dropped returns the number of wideband samples that were dropped
+// because this Channel wasn't being read quickly enough.
+func (ch *Channel) Dropped() int64 {
+	return ch.input.Dropped()
+}
+
+// Close will remove the Channel from the Channelizer. Any pending or future
+// Reads will return an error.
+func (ch *Channel) Close() error {
+	ch.input.Close()
+	if closer, ok := ch.Reader.(interface{ Close() error }); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,96 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package stream_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/stream"
+)
+
+func TestChannelizer(t *testing.T) {
+	// 2048 cycles per 32K samples at 1.2288 Msps is a tone at 76.8 kHz.
+	in, stop := toneReader(1228800, 2048, sdr.SampleFormatI16)
+	defer stop()
+
+	c, err := stream.NewChannelizer(in, stream.ChannelizerOptions{})
+	assert.NoError(t, err)
+	defer c.Close()
+	assert.Equal(t, uint(1228800), c.SampleRate())
+
+	_, err = c.Channel(600*rf.KHz, 76800)
+	assert.Equal(t, stream.ErrChannelOutOfBand, err)
+	_, err = c.Channel(0, 2457600)
+	assert.Equal(t, stream.ErrChannelOutOfBand, err)
+
+	on, err := c.Channel(76800, 76800)
+	assert.NoError(t, err)
+	defer on.Close()
+	assert.Equal(t, uint(76800), on.SampleRate())
+	assert.Equal(t, sdr.SampleFormatC64, on.SampleFormat())
+
+	off, err := c.Channel(-300*rf.KHz, 76800)
+	assert.NoError(t, err)
+
+	rms, _ := readRMS(t, on, 8192)
+	assert.InDelta(t, 0.5, rms, 0.02)
+
+	rms, _ = readRMS(t, off, 8192)
+	assert.InDelta(t, 0, rms, 0.02)
+
+	assert.Equal(t, stream.ErrChannelOutOfBand, off.SetOffset(-600*rf.KHz))
+	assert.NoError(t, off.SetOffset(76800))
+	assert.Equal(t, rf.Hz(76800), off.Offset())
+	rms, _ = readRMS(t, off, 8192)
+	assert.InDelta(t, 0.5, rms, 0.02)
+
+	assert.NoError(t, off.Close())
+	_, err = sdr.ReadFull(off, make(sdr.SamplesC64, 1024*1024))
+	assert.Error(t, err)
+
+	// The other channel is unaffected.
+	rms, _ = readRMS(t, on, 8192)
+	assert.InDelta(t, 0.5, rms, 0.02)
+}
+
+func TestChannelizerClose(t *testing.T) {
+	in, stop := toneReader(1228800, 2048, sdr.SampleFormatC64)
+	defer stop()
+
+	c, err := stream.NewChannelizer(in, stream.ChannelizerOptions{})
+	assert.NoError(t, err)
+
+	ch, err := c.Channel(0, 1228800)
+	assert.NoError(t, err)
+	assert.NoError(t, c.Close())
+
+	_, err = sdr.ReadFull(ch, make(sdr.SamplesC64, 1024*1024))
+	assert.Equal(t, sdr.ErrPipeClosed, err)
+
+	_, err = c.Channel(0, 1228800)
+	assert.Equal(t, sdr.ErrPipeClosed, err)
+}
+
+// vim: foldmethod=marker
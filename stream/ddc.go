@@ -0,0 +1,134 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package stream
+
+import (
+	"context"
+	"sync"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/filter"
+)
+
+type frequencyShifter struct {
+	r    sdr.Reader
+	lock *sync.Mutex
+	nco  *filter.NCO
+}
+
+// Reconfigure implements the Reconfigurer interface. The only parameter
+// understood is "offset", which must be an rf.Hz.
+func (fs *frequencyShifter) Reconfigure(ctx context.Context, params Params) error {
+	var offset rf.Hz
+	for key, value := range params {
+		switch key {
+		case "offset":
+			v, ok := value.(rf.Hz)
+			if !ok {
+				return ErrInvalidParameter
+			}
+			offset = v
+		default:
+			return ErrUnknownParameter
+		}
+	}
+
+	if _, ok := params["offset"]; !ok {
+		return nil
+	}
+
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+	fs.nco.SetFrequency(-offset)
+	return nil
+}
+
+func (fs *frequencyShifter) SampleFormat() sdr.SampleFormat {
+	return sdr.SampleFormatC64
+}
+
+func (fs *frequencyShifter) SampleRate() uint {
+	return fs.r.SampleRate()
+}
+
+func (fs *frequencyShifter) Read(s sdr.Samples) (int, error) {
+	sC64, ok := s.(sdr.SamplesC64)
+	if !ok {
+		return 0, sdr.ErrSampleFormatMismatch
+	}
+
+	n, err := fs.r.Read(sC64)
+	if n > 0 {
+		fs.lock.Lock()
+		fs.nco.MixC64(sC64[:n], sC64[:n])
+		fs.lock.Unlock()
+	}
+	return n, err
+}
+
+// ShiftFrequency will mix the provided Reader with a complex oscillator
+// (see filter.NCO), so that a signal at `offset` from the center of the
+// Reader is moved to DC. This allows tuning digitally within the captured
+// bandwidth, without retuning the radio.
+//
+// Note that this is the opposite sign convention to ShiftReader, which
+// moves the whole band up by its `shift`: ShiftFrequency(r, offset) moves
+// the same signals to the same place as ShiftReader(r, -offset).
+//
+// Readers that aren't SampleFormatC64 are converted first (see
+// ConvertReader), so the returned Reader is always SampleFormatC64. The
+// returned Reader implements Reconfigurer, so the offset can be changed
+// while it's in use, without a discontinuity in phase.
+func ShiftFrequency(r sdr.Reader, offset rf.Hz) (sdr.Reader, error) {
+	if r.SampleFormat() != sdr.SampleFormatC64 {
+		var err error
+		r, err = ConvertReader(r, sdr.SampleFormatC64)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	nco, err := filter.NewNCO(-offset, r.SampleRate())
+	if err != nil {
+		return nil, err
+	}
+
+	return &frequencyShifter{
+		r:    r,
+		lock: &sync.Mutex{},
+		nco:  nco,
+	}, nil
+}
+
+// DownConvert is a basic digital down converter. The signal at `offset`
+// from the center of the Reader is shifted to DC (see ShiftFrequency), and
+// the stream is filtered and resampled to outRate (see Resample), so that
+// only the slice of the band around `offset` is kept.
+func DownConvert(r sdr.Reader, offset rf.Hz, outRate uint) (sdr.Reader, error) {
+	shifted, err := ShiftFrequency(r, offset)
+	if err != nil {
+		return nil, err
+	}
+	return Resample(shifted, outRate)
+}
+
+// vim: foldmethod=marker
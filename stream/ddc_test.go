@@ -0,0 +1,149 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package stream_test
+
+import (
+	"context"
+	"math/cmplx"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/stream"
+	"hz.tools/sdr/testutils"
+)
+
+func TestShiftFrequency(t *testing.T) {
+	const sampleRate = 2400000
+
+	// This is converted to SampleFormatC64 in chunks of 32K samples.
+	in := make(sdr.SamplesI16, 32*1024)
+	cw := make(sdr.SamplesC64, len(in))
+	testutils.CW(cw, 100*rf.KHz, sampleRate, 0)
+	_, err := sdr.ConvertBuffer(in, cw)
+	assert.NoError(t, err)
+
+	pipeReader, pipeWriter := sdr.Pipe(sampleRate, sdr.SampleFormatI16)
+	r, err := stream.ShiftFrequency(pipeReader, 100*rf.KHz)
+	assert.NoError(t, err)
+	assert.Equal(t, sdr.SampleFormatC64, r.SampleFormat())
+	assert.Equal(t, uint(sampleRate), r.SampleRate())
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		pipeWriter.Write(in)
+	}()
+
+	buf := make(sdr.SamplesC64, len(in))
+	_, err = sdr.ReadFull(r, buf)
+	assert.NoError(t, err)
+	pipeReader.Close()
+	wg.Wait()
+
+	// The tone is now at DC, so every sample has the same phase.
+	phase := cmplx.Phase(complex128(buf[0]))
+	for _, v := range buf {
+		assert.InDelta(t, phase, cmplx.Phase(complex128(v)), 0.01)
+	}
+
+	reconfigurer, ok := r.(stream.Reconfigurer)
+	assert.True(t, ok)
+	assert.NoError(t, reconfigurer.Reconfigure(context.Background(), stream.Params{
+		"offset": 200 * rf.KHz,
+	}))
+	assert.Equal(t, stream.ErrInvalidParameter, reconfigurer.Reconfigure(
+		context.Background(), stream.Params{"offset": 1},
+	))
+}
+
+func TestShiftFrequencyMatchesShiftReader(t *testing.T) {
+	const sampleRate = 2400000
+
+	cw := make(sdr.SamplesC64, 32*1024)
+	testutils.CW(cw, 100*rf.KHz, sampleRate, 0)
+
+	read := func(fn func(sdr.Reader) (sdr.Reader, error)) sdr.SamplesC64 {
+		pipeReader, pipeWriter := sdr.Pipe(sampleRate, sdr.SampleFormatC64)
+		defer pipeReader.Close()
+		r, err := fn(pipeReader)
+		assert.NoError(t, err)
+		go pipeWriter.Write(cw)
+		buf := make(sdr.SamplesC64, len(cw))
+		_, err = sdr.ReadFull(r, buf)
+		assert.NoError(t, err)
+		return buf
+	}
+
+	shifted := read(func(r sdr.Reader) (sdr.Reader, error) {
+		return stream.ShiftFrequency(r, 100*rf.KHz)
+	})
+	reversed := read(func(r sdr.Reader) (sdr.Reader, error) {
+		return stream.ShiftReader(r, -100*rf.KHz)
+	})
+
+	// Both move the tone to DC, so the phase between the two stays fixed,
+	// whatever each oscillator's starting phase was.
+	offset := cmplx.Phase(complex128(shifted[0]) * cmplx.Conj(complex128(reversed[0])))
+	for i := range shifted {
+		delta := cmplx.Phase(complex128(shifted[i]) * cmplx.Conj(complex128(reversed[i])))
+		assert.InDelta(t, offset, delta, 0.01)
+	}
+}
+
+func TestDownConvert(t *testing.T) {
+	const sampleRate = 2400000
+
+	in := make(sdr.SamplesC64, sampleRate/4)
+	testutils.MultiTone(in, sampleRate,
+		testutils.Tone{Frequency: 300 * rf.KHz, Amplitude: 0.5},
+		testutils.Tone{Frequency: -200 * rf.KHz, Amplitude: 0.5},
+	)
+
+	pipeReader, pipeWriter := sdr.Pipe(sampleRate, sdr.SampleFormatC64)
+	r, err := stream.DownConvert(pipeReader, 300*rf.KHz, 48000)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(48000), r.SampleRate())
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		pipeWriter.Write(in)
+	}()
+
+	buf := make(sdr.SamplesC64, 2048)
+	_, err = sdr.ReadFull(r, buf)
+	assert.NoError(t, err)
+	pipeReader.Close()
+	wg.Wait()
+
+	// Only the tone we tuned to should be left, at DC.
+	for _, v := range buf[100:] {
+		assert.InDelta(t, 0.5, cmplx.Abs(complex128(v)), 0.01)
+	}
+}
+
+// vim: foldmethod=marker
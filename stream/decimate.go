@@ -22,11 +22,99 @@ package stream
 
 import (
 	"hz.tools/sdr"
+	"hz.tools/sdr/filter"
 )
 
+// c64Filter is something which processes complex64 samples, such as a
+// filter.Decimator or filter.Resampler.
+type c64Filter interface {
+	OutputLength(int) int
+	ProcessC64(dst, src sdr.SamplesC64) (int, error)
+}
+
+// filterReader will run the provided filter over the Reader, converting to
+// and from complex64 if the Reader is in some other format, so that the
+// returned Reader has the same SampleFormat as the one passed in.
+func filterReader(in sdr.Reader, outRate uint, f c64Filter) (sdr.Reader, error) {
+	var (
+		format       = in.SampleFormat()
+		inputLength  = 32 * 1024
+		outputLength = f.OutputLength(inputLength)
+		inC64        sdr.SamplesC64
+		outC64       sdr.SamplesC64
+	)
+
+	if format != sdr.SampleFormatC64 {
+		inC64 = make(sdr.SamplesC64, inputLength)
+		outC64 = make(sdr.SamplesC64, outputLength)
+	}
+
+	return ReadTransformer(in, ReadTransformerConfig{
+		InputBufferLength:  inputLength,
+		OutputBufferLength: outputLength,
+		OutputSampleRate:   outRate,
+		OutputSampleFormat: format,
+		Proc: func(inBuf sdr.Samples, outBuf sdr.Samples) (int, error) {
+			if format == sdr.SampleFormatC64 {
+				return f.ProcessC64(outBuf.(sdr.SamplesC64), inBuf.(sdr.SamplesC64))
+			}
+
+			n, err := sdr.ConvertBuffer(inC64, inBuf)
+			if err != nil {
+				return 0, err
+			}
+			n, err = f.ProcessC64(outC64, inC64[:n])
+			if err != nil {
+				return 0, err
+			}
+			return sdr.ConvertBuffer(outBuf, outC64[:n])
+		},
+	})
+}
+
+// Decimate will low-pass filter the provided Reader, and then reduce the
+// sample rate by the provided factor (see filter.Decimator), so that
+// signals outside the new (narrower) band don't alias into it.
+//
+// This is nearly always what you want instead of DecimateReader, which
+// only drops samples. Samples are filtered as complex64, so other formats
+// are converted on the way in and back out again.
+func Decimate(in sdr.Reader, factor uint) (sdr.Reader, error) {
+	if factor == 1 {
+		return in, nil
+	}
+	d, err := filter.NewDecimator(int(factor))
+	if err != nil {
+		return nil, err
+	}
+	return filterReader(in, in.SampleRate()/factor, d)
+}
+
+// Interpolate will increase the sample rate of the provided Reader by the
+// provided factor, filtering out the images of the signal that would
+// otherwise show up every input sample rate apart, using a polyphase FIR
+// (see filter.Resampler).
+//
+// Like Decimate, samples are filtered as complex64, so other formats are
+// converted on the way in and back out again.
+func Interpolate(in sdr.Reader, factor uint) (sdr.Reader, error) {
+	if factor == 1 {
+		return in, nil
+	}
+	if factor == 0 {
+		return nil, filter.ErrBadParameters
+	}
+	r, err := filter.NewResampler(1, factor, 24)
+	if err != nil {
+		return nil, err
+	}
+	return filterReader(in, in.SampleRate()*factor, r)
+}
+
 // DecimateReader will take even Nth sample (where N is the `factor` argument)
 // from an sdr.Reader, and provide the downsampled or compressed iq stream
-// through the returned Reader.
+// through the returned Reader. No filtering is done, so anything outside of
+// the new band will alias into it; see Decimate.
 //
 // This will reduce the sample rate by the provided factor (so if the input
 // Reader is at 18 Msps, and we apply a factor of 10 Decimation, we'll get
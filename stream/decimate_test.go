@@ -21,14 +21,18 @@
 package stream_test
 
 import (
+	"math"
+	"math/cmplx"
 	"sync"
 
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
+	"hz.tools/rf"
 	"hz.tools/sdr"
 	"hz.tools/sdr/stream"
+	"hz.tools/sdr/testutils"
 )
 
 func TestDecimateBufferU8(t *testing.T) {
@@ -165,4 +169,117 @@ func TestDecimateSkippyboi(t *testing.T) {
 	wg.Wait()
 }
 
+// toneReader will repeat a tone at `cycles` cycles per 32K samples in the
+// provided format, forever.
+func toneReader(sampleRate uint, cycles int, format sdr.SampleFormat) (sdr.ReadCloser, func()) {
+	pattern := make(sdr.SamplesC64, 32*1024)
+	testutils.CW(pattern, rf.Hz(cycles)*rf.Hz(sampleRate)/rf.Hz(len(pattern)), int(sampleRate), 0)
+	pattern.Scale(0.5)
+
+	buf, _ := sdr.MakeSamples(format, len(pattern))
+	sdr.ConvertBuffer(buf, pattern)
+
+	pipeReader, pipeWriter := sdr.Pipe(sampleRate, format)
+	go func() {
+		for {
+			if _, err := pipeWriter.Write(buf); err != nil {
+				return
+			}
+		}
+	}()
+	return pipeReader, func() { pipeReader.Close() }
+}
+
+// readRMS will read n samples from the reader, and return the RMS of the
+// last half of them, once the filters have settled.
+func readRMS(t *testing.T, r sdr.Reader, n int) (float32, sdr.SamplesC64) {
+	buf, err := sdr.MakeSamples(r.SampleFormat(), n)
+	assert.NoError(t, err)
+	_, err = sdr.ReadFull(r, buf)
+	assert.NoError(t, err)
+
+	out := make(sdr.SamplesC64, n)
+	_, err = sdr.ConvertBuffer(out, buf)
+	assert.NoError(t, err)
+	return out[n/2:].Stats().RMS(), out
+}
+
+func TestDecimate(t *testing.T) {
+	for _, format := range []sdr.SampleFormat{
+		sdr.SampleFormatC64,
+		sdr.SampleFormatI16,
+		sdr.SampleFormatU8,
+	} {
+		// 64 cycles per 32K samples is well inside the band after decimating
+		// by 12.
+		in, stop := toneReader(1200000, 64, format)
+		r, err := stream.Decimate(in, 12)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(100000), r.SampleRate())
+		assert.Equal(t, format, r.SampleFormat())
+		rms, _ := readRMS(t, r, 8192)
+		assert.InDelta(t, 0.5, rms, 0.03, "%s", format)
+		stop()
+
+		// 1911 cycles per 32K is at 0.7 of the output sample rate, which
+		// would alias into the band if samples were only dropped.
+		in, stop = toneReader(1200000, 1911, format)
+		r, err = stream.Decimate(in, 12)
+		assert.NoError(t, err)
+		rms, _ = readRMS(t, r, 8192)
+		assert.True(t, rms < 0.02, "%s: alias rms %f", format, rms)
+		stop()
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	in, stop := toneReader(48000, 256, sdr.SampleFormatC64)
+	defer stop()
+
+	r, err := stream.Interpolate(in, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(192000), r.SampleRate())
+
+	rms, out := readRMS(t, r, 4*32*1024)
+	assert.InDelta(t, 0.5, rms, 0.02)
+
+	// The tone should step by a quarter of the phase it did at the input
+	// rate, with no images bending it around.
+	step := 2 * math.Pi * 256 / (32 * 1024) / 4
+	for i := len(out) / 2; i < len(out)-1; i++ {
+		got := cmplx.Phase(complex128(out[i+1] * complex(real(out[i]), -imag(out[i]))))
+		assert.InDelta(t, step, got, 0.01)
+	}
+
+	_, err = stream.Interpolate(in, 0)
+	assert.Error(t, err)
+}
+
+func benchmarkDecimate(b *testing.B, format sdr.SampleFormat) {
+	in, stop := toneReader(2400000, 64, format)
+	defer stop()
+
+	r, err := stream.Decimate(in, 10)
+	assert.NoError(b, err)
+
+	buf, _ := sdr.MakeSamples(format, 32*1024/10)
+	b.SetBytes(int64(format.Size() * 32 * 1024))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sdr.ReadFull(r, buf)
+	}
+}
+
+func BenchmarkDecimateU8(b *testing.B) {
+	benchmarkDecimate(b, sdr.SampleFormatU8)
+}
+
+func BenchmarkDecimateI16(b *testing.B) {
+	benchmarkDecimate(b, sdr.SampleFormatI16)
+}
+
+func BenchmarkDecimateC64(b *testing.B) {
+	benchmarkDecimate(b, sdr.SampleFormatC64)
+}
+
 // vim: foldmethod=marker
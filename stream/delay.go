@@ -0,0 +1,74 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package stream
+
+import (
+	"hz.tools/sdr"
+	"hz.tools/sdr/filter"
+)
+
+type fractionalDelayReader struct {
+	r      sdr.Reader
+	farrow *filter.Farrow
+}
+
+func (fdr *fractionalDelayReader) SampleFormat() sdr.SampleFormat {
+	return fdr.r.SampleFormat()
+}
+
+func (fdr *fractionalDelayReader) SampleRate() uint {
+	return fdr.r.SampleRate()
+}
+
+func (fdr *fractionalDelayReader) Read(s sdr.Samples) (int, error) {
+	sC64, ok := s.(sdr.SamplesC64)
+	if !ok {
+		return 0, sdr.ErrSampleFormatMismatch
+	}
+
+	i, err := fdr.r.Read(sC64)
+	if i > 0 {
+		if _, ferr := fdr.farrow.ProcessC64(sC64[:i], sC64[:i]); ferr != nil {
+			return 0, ferr
+		}
+	}
+	return i, err
+}
+
+// FractionalDelay will delay the samples read from the provided Reader by
+// a fraction of a sample (between 0 and 1), plus one whole sample, using a
+// cubic Farrow interpolator (see filter.Farrow).
+//
+// This is handy to line up channels which are off by less than a sample,
+// which can't be fixed by dropping samples. Only SampleFormatC64 is
+// supported.
+func FractionalDelay(r sdr.Reader, delay float64) (sdr.Reader, error) {
+	if r.SampleFormat() != sdr.SampleFormatC64 {
+		return nil, sdr.ErrSampleFormatMismatch
+	}
+	farrow, err := filter.NewFarrow(delay)
+	if err != nil {
+		return nil, err
+	}
+	return &fractionalDelayReader{r: r, farrow: farrow}, nil
+}
+
+// vim: foldmethod=marker
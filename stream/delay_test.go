@@ -0,0 +1,76 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package stream_test
+
+import (
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/stream"
+	"hz.tools/sdr/testutils"
+)
+
+func TestFractionalDelay(t *testing.T) {
+	const (
+		sampleRate = 1.8e6
+		freq       = rf.Hz(20e3)
+		delay      = 0.3
+	)
+
+	var (
+		in   = make(sdr.SamplesC64, 1024*8)
+		want = make(sdr.SamplesC64, 1024*8)
+	)
+	testutils.CW(in, freq, sampleRate, 0)
+	testutils.CW(want, freq, sampleRate, -2*math.Pi*float64(freq)*(1+delay)/sampleRate)
+
+	pipeReader, pipeWriter := sdr.Pipe(sampleRate, sdr.SampleFormatC64)
+	delayReader, err := stream.FractionalDelay(pipeReader, delay)
+	assert.NoError(t, err)
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, err := pipeWriter.Write(in)
+		assert.NoError(t, err)
+	}()
+
+	buf := make(sdr.SamplesC64, len(in))
+	_, err = sdr.ReadFull(delayReader, buf)
+	assert.NoError(t, err)
+	wg.Wait()
+
+	for i := 4; i < len(buf); i++ {
+		assert.InDelta(t, real(want[i]), real(buf[i]), 0.0001)
+		assert.InDelta(t, imag(want[i]), imag(buf[i]), 0.0001)
+	}
+
+	_, err = stream.FractionalDelay(pipeReader, 2)
+	assert.Error(t, err)
+}
+
+// vim: foldmethod=marker
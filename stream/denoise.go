@@ -0,0 +1,85 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package stream
+
+import (
+	"hz.tools/sdr"
+	"hz.tools/sdr/fft"
+	"hz.tools/sdr/filter"
+)
+
+// Denoise is an sdr.Reader which reduces the noise of narrowband IQ read
+// from an underlying Reader (see filter.Denoiser), such as a weak SSB or AM
+// signal which has been shifted to baseband and decimated.
+type Denoise struct {
+	r        sdr.Reader
+	denoiser *filter.Denoiser
+}
+
+// NewDenoise will create a new Denoise Reader. The provided Reader must be
+// in SampleFormatC64. Samples are delayed by the FrameSize.
+func NewDenoise(
+	r sdr.Reader,
+	planner fft.Planner,
+	config filter.DenoiseConfig,
+) (*Denoise, error) {
+	if r.SampleFormat() != sdr.SampleFormatC64 {
+		return nil, sdr.ErrSampleFormatMismatch
+	}
+	denoiser, err := filter.NewDenoiser(planner, config)
+	if err != nil {
+		return nil, err
+	}
+	return &Denoise{r: r, denoiser: denoiser}, nil
+}
+
+// SampleFormat implements the sdr.Reader interface.
+func (d *Denoise) SampleFormat() sdr.SampleFormat {
+	return sdr.SampleFormatC64
+}
+
+// SampleRate implements the sdr.Reader interface.
+func (d *Denoise) SampleRate() uint {
+	return d.r.SampleRate()
+}
+
+// Read implements the sdr.Reader interface.
+func (d *Denoise) Read(s sdr.Samples) (int, error) {
+	sC64, ok := s.(sdr.SamplesC64)
+	if !ok {
+		return 0, sdr.ErrSampleFormatMismatch
+	}
+	i, err := d.r.Read(sC64)
+	if i > 0 {
+		if _, derr := d.denoiser.ProcessC64(sC64[:i], sC64[:i]); derr != nil {
+			return 0, derr
+		}
+	}
+	return i, err
+}
+
+// Close will release the FFT plans. This will not close the underlying
+// Reader.
+func (d *Denoise) Close() error {
+	return d.denoiser.Close()
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,112 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package stream
+
+import (
+	"fmt"
+	"io"
+
+	"hz.tools/sdr"
+)
+
+type loopReader struct {
+	samples    sdr.Samples
+	sampleRate uint
+
+	// count is how many times to play the samples back, or 0 forever.
+	count  int
+	plays  int
+	offset int
+}
+
+func (lr *loopReader) SampleFormat() sdr.SampleFormat {
+	return lr.samples.Format()
+}
+
+func (lr *loopReader) SampleRate() uint {
+	return lr.sampleRate
+}
+
+func (lr *loopReader) Read(s sdr.Samples) (int, error) {
+	if s.Format() != lr.samples.Format() {
+		return 0, sdr.ErrSampleFormatMismatch
+	}
+
+	var n int
+	for n < s.Length() {
+		if lr.count != 0 && lr.plays >= lr.count {
+			if n == 0 {
+				return 0, io.EOF
+			}
+			break
+		}
+
+		i, err := sdr.CopySamples(
+			s.Slice(n, s.Length()),
+			lr.samples.Slice(lr.offset, lr.samples.Length()),
+		)
+		if err != nil {
+			return n, err
+		}
+		n += i
+		lr.offset += i
+
+		if lr.offset == lr.samples.Length() {
+			lr.offset = 0
+			lr.plays++
+		}
+	}
+	return n, nil
+}
+
+// Loop will return a Reader which plays back the provided samples at the
+// provided sample rate, over and over, forever. This is handy for sending
+// a canned recording (such as a beacon) to a Transmitter, or for feeding
+// a known signal into a test (such as with mock.ThisRx).
+//
+// The samples are copied, so the buffer may be reused once this returns.
+func Loop(samples sdr.Samples, rate uint) (sdr.Reader, error) {
+	return LoopN(samples, rate, 0)
+}
+
+// LoopN is Loop, but will return io.EOF after the samples have been played
+// back `count` times. If count is 0, this will loop forever.
+func LoopN(samples sdr.Samples, rate uint, count int) (sdr.Reader, error) {
+	if samples.Length() == 0 {
+		return nil, fmt.Errorf("stream.Loop: no samples to loop")
+	}
+	if count < 0 {
+		return nil, fmt.Errorf("stream.Loop: count must not be negative")
+	}
+
+	samples, _, err := dupe(samples)
+	if err != nil {
+		return nil, err
+	}
+
+	return &loopReader{
+		samples:    samples,
+		sampleRate: rate,
+		count:      count,
+	}, nil
+}
+
+// vim: foldmethod=marker
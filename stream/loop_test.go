@@ -0,0 +1,84 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package stream_test
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/stream"
+)
+
+func TestLoop(t *testing.T) {
+	samples := sdr.SamplesC64{1, 2, 3}
+	r, err := stream.Loop(samples, 1000)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1000), r.SampleRate())
+	assert.Equal(t, sdr.SampleFormatC64, r.SampleFormat())
+
+	// Changing the buffer after the fact doesn't change the loop.
+	samples[0] = 10
+
+	buf := make(sdr.SamplesC64, 8)
+	n, err := sdr.ReadFull(r, buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 8, n)
+	assert.Equal(t, sdr.SamplesC64{1, 2, 3, 1, 2, 3, 1, 2}, buf)
+
+	n, err = r.Read(buf[:2])
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, sdr.SamplesC64{3, 1}, buf[:2])
+
+	_, err = r.Read(make(sdr.SamplesU8, 2))
+	assert.Equal(t, sdr.ErrSampleFormatMismatch, err)
+}
+
+func TestLoopN(t *testing.T) {
+	r, err := stream.LoopN(sdr.SamplesC64{1, 2, 3}, 1000, 2)
+	assert.NoError(t, err)
+
+	buf := make(sdr.SamplesC64, 4)
+	n, err := r.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, n)
+
+	n, err = r.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, sdr.SamplesC64{2, 3}, buf[:2])
+
+	_, err = r.Read(buf)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestLoopInvalid(t *testing.T) {
+	_, err := stream.Loop(sdr.SamplesC64{}, 1000)
+	assert.Error(t, err)
+
+	_, err = stream.LoopN(sdr.SamplesC64{1}, 1000, -1)
+	assert.Error(t, err)
+}
+
+// vim: foldmethod=marker
@@ -75,11 +75,15 @@ func Multiply(r sdr.Reader, m complex64) (sdr.Reader, error) {
 	switch r.SampleFormat() {
 	case sdr.SampleFormatI8:
 		ret := &int8MultiplyReader{r: r}
-		ret.SetMultiplier(m)
+		if err := ret.SetMultiplier(m); err != nil {
+			return nil, err
+		}
 		return ret, nil
 	case sdr.SampleFormatU8:
 		ret := &uint8MultiplyReader{r: r}
-		ret.SetMultiplier(m)
+		if err := ret.SetMultiplier(m); err != nil {
+			return nil, err
+		}
 		return ret, nil
 	case sdr.SampleFormatC64:
 		return &multiplyReader{r: r, m: m}, nil
@@ -140,7 +144,7 @@ func (mr *uint8MultiplyReader) Read(s sdr.Samples) (int, error) {
 // SetMultiplier is an undocumented API to update the complex value
 // after the construction of the Reader. For the uint8 variant, this has
 // a one-time CPU hit.
-func (mr *uint8MultiplyReader) SetMultiplier(m complex64) {
+func (mr *uint8MultiplyReader) SetMultiplier(m complex64) error {
 	var (
 		// This is 65535 samples of work to change the multiply const,
 		// which, while not 0, is a lot better than the O(n). FWIW, 65535
@@ -165,10 +169,16 @@ func (mr *uint8MultiplyReader) SetMultiplier(m complex64) {
 	// Here, we'll round trip it through Complex64 once, do a SIMD optimized
 	// multiply operation, and return the uint8 buffer as a lookup table.
 
-	sdr.ConvertBuffer(cbuf, ubuf)
+	if _, err := sdr.ConvertBuffer(cbuf, ubuf); err != nil {
+		return err
+	}
 	cbuf.Multiply(m)
-	sdr.ConvertBuffer(ubuf, cbuf)
+	if _, err := sdr.ConvertBuffer(ubuf, cbuf); err != nil {
+		return err
+	}
+	mr.m = m
 	mr.tab = ubuf
+	return nil
 }
 
 type int8MultiplyReader struct {
@@ -209,7 +219,10 @@ func (mr *int8MultiplyReader) Read(s sdr.Samples) (int, error) {
 // SetMultiplier is an undocumented API to update the complex value
 // after the construction of the Reader. For the int8 variant, this has
 // a one-time CPU hit.
-func (mr *int8MultiplyReader) SetMultiplier(m complex64) {
+//
+// If the lookup table can't be built, the previous multiplier is left in
+// place and the error is returned.
+func (mr *int8MultiplyReader) SetMultiplier(m complex64) error {
 	var (
 		// This is 65535 samples of work to change the multiply const,
 		// which, while not 0, is a lot better than the O(n). FWIW, 65535
@@ -220,21 +233,25 @@ func (mr *int8MultiplyReader) SetMultiplier(m complex64) {
 
 		ubuf = sdr.LookupTableIdentityI8()
 		cbuf = make(sdr.SamplesC64, 65536)
-		err  error
 	)
 
 	// Here, we'll round trip it through Complex64 once, do a SIMD optimized
 	// multiply operation, and return the int8 buffer as a lookup table.
 
-	sdr.ConvertBuffer(cbuf, ubuf)
+	if _, err := sdr.ConvertBuffer(cbuf, ubuf); err != nil {
+		return err
+	}
 	cbuf.Multiply(m)
-	sdr.ConvertBuffer(ubuf, cbuf)
-	mr.tab, err = sdr.NewLookupTable(sdr.SampleFormatI8, ubuf)
+	if _, err := sdr.ConvertBuffer(ubuf, cbuf); err != nil {
+		return err
+	}
+	tab, err := sdr.NewLookupTable(sdr.SampleFormatI8, ubuf)
 	if err != nil {
-		// This shouldn't be reachable since we're positive of the construction,
-		// although we ought to return something nicer than this.
-		panic(err)
+		return err
 	}
+	mr.m = m
+	mr.tab = tab
+	return nil
 }
 
 // vim: foldmethod=marker
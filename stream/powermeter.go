@@ -0,0 +1,228 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package stream
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"hz.tools/sdr"
+)
+
+// powerMeterBuckets is the number of pieces the PowerMeter Window is broken
+// up into. The power is reported over the most recent full Window worth of
+// buckets, plus whatever has been read into the current one.
+const powerMeterBuckets = 16
+
+// PowerMeterOptions controls how a PowerMeter averages the stream.
+type PowerMeterOptions struct {
+	// Window is the length of time the running power is averaged over. If
+	// 0, this will default to 100ms.
+	Window time.Duration
+
+	// Callback, if set, will be called with the running power in dBFS
+	// after every Read. This is called on the goroutine doing the Read, so
+	// it must not block.
+	Callback func(dBFS float32)
+}
+
+func (o PowerMeterOptions) getWindow() time.Duration {
+	if o.Window == 0 {
+		return 100 * time.Millisecond
+	}
+	return o.Window
+}
+
+// PowerMeter wraps an sdr.Reader, passing samples through untouched, while
+// keeping track of the average power of the samples read over the last
+// Window. This is useful for squelch, checking gain settings, or as one
+// half of an SNR estimate.
+//
+// Power is measured in the same full scale units as sdr.Stats, where a full
+// scale carrier reads as 0 dBFS, no matter what the SampleFormat is.
+type PowerMeter struct {
+	sdr.Reader
+
+	callback func(float32)
+
+	lock       sync.Mutex
+	bucketSize int
+	buckets    [powerMeterBuckets]float64
+	bucket     int
+	filled     int
+	current    float64
+	count      int
+}
+
+// NewPowerMeter will create a new PowerMeter reading from the provided
+// Reader. The PowerMeter must be read from in place of the provided Reader.
+func NewPowerMeter(in sdr.Reader, opts PowerMeterOptions) (*PowerMeter, error) {
+	samples := int(opts.getWindow().Seconds() * float64(in.SampleRate()))
+	bucketSize := samples / powerMeterBuckets
+	if bucketSize < 1 {
+		bucketSize = 1
+	}
+	return &PowerMeter{
+		Reader:     in,
+		callback:   opts.Callback,
+		bucketSize: bucketSize,
+	}, nil
+}
+
+// Read implements the sdr.Reader interface.
+func (pm *PowerMeter) Read(s sdr.Samples) (int, error) {
+	n, err := pm.Reader.Read(s)
+	if n == 0 {
+		return n, err
+	}
+
+	pm.lock.Lock()
+	perr := pm.process(s, n)
+	power := pm.power()
+	pm.lock.Unlock()
+
+	if perr != nil {
+		return n, perr
+	}
+	if pm.callback != nil {
+		pm.callback(powerToDBFS(power))
+	}
+	return n, err
+}
+
+// process will add the power of the first n samples of s. This has to
+// switch on the concrete type up front, since slicing an sdr.Samples
+// allocates.
+func (pm *PowerMeter) process(s sdr.Samples, n int) error {
+	switch s := s.(type) {
+	case sdr.SamplesU8:
+		for _, v := range s[:n] {
+			i := (float64(v[0]) - 127.5) / 127.5
+			q := (float64(v[1]) - 127.5) / 127.5
+			pm.add(i*i + q*q)
+		}
+	case sdr.SamplesI8:
+		for _, v := range s[:n] {
+			i := float64(v[0]) / 128
+			q := float64(v[1]) / 128
+			pm.add(i*i + q*q)
+		}
+	case sdr.SamplesI16:
+		for _, v := range s[:n] {
+			i := float64(v[0]) / math.MaxInt16
+			q := float64(v[1]) / math.MaxInt16
+			pm.add(i*i + q*q)
+		}
+	case sdr.SamplesI12Packed:
+		for j := 0; j < n; j++ {
+			// Scaled the same way as SamplesI12Packed.ToC64.
+			v := s.Get(j)
+			i := float64(v[0]<<4) / math.MaxInt16
+			q := float64(v[1]<<4) / math.MaxInt16
+			pm.add(i*i + q*q)
+		}
+	case sdr.SamplesC64:
+		for _, v := range s[:n] {
+			i, q := float64(real(v)), float64(imag(v))
+			pm.add(i*i + q*q)
+		}
+	case sdr.SamplesC128:
+		for _, v := range s[:n] {
+			pm.add(real(v)*real(v) + imag(v)*imag(v))
+		}
+	default:
+		return sdr.ErrSampleFormatUnknown
+	}
+	return nil
+}
+
+// add will add the power of one sample to the current bucket, moving on to
+// the next bucket once it's full.
+func (pm *PowerMeter) add(p float64) {
+	pm.current += p
+	pm.count++
+	if pm.count < pm.bucketSize {
+		return
+	}
+	pm.buckets[pm.bucket] = pm.current
+	pm.bucket = (pm.bucket + 1) % powerMeterBuckets
+	if pm.filled < powerMeterBuckets {
+		pm.filled++
+	}
+	pm.current = 0
+	pm.count = 0
+}
+
+// power will return the average power over the window. The lock must be
+// held.
+func (pm *PowerMeter) power() float64 {
+	var (
+		sum   = pm.current
+		count = pm.count
+	)
+	for i := 0; i < pm.filled; i++ {
+		sum += pm.buckets[i]
+	}
+	count += pm.filled * pm.bucketSize
+	if count == 0 {
+		return 0
+	}
+	return sum / float64(count)
+}
+
+// Power will return the average power over the last Window, where a full
+// scale carrier is 1.
+func (pm *PowerMeter) Power() float32 {
+	pm.lock.Lock()
+	defer pm.lock.Unlock()
+	return float32(pm.power())
+}
+
+// RMS will return the root mean square of the samples over the last Window,
+// where a full scale carrier is 1.
+func (pm *PowerMeter) RMS() float32 {
+	return float32(math.Sqrt(float64(pm.Power())))
+}
+
+// DBFS will return the average power over the last Window in dBFS. If
+// nothing has been read yet, this will be -Inf.
+func (pm *PowerMeter) DBFS() float32 {
+	pm.lock.Lock()
+	defer pm.lock.Unlock()
+	return powerToDBFS(pm.power())
+}
+
+// Reset will throw away the power measured so far.
+func (pm *PowerMeter) Reset() {
+	pm.lock.Lock()
+	defer pm.lock.Unlock()
+	pm.bucket = 0
+	pm.filled = 0
+	pm.current = 0
+	pm.count = 0
+}
+
+func powerToDBFS(p float64) float32 {
+	return float32(10 * math.Log10(p))
+}
+
+// vim: foldmethod=marker
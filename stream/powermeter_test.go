@@ -0,0 +1,127 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package stream_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/stream"
+)
+
+func TestPowerMeter(t *testing.T) {
+	for _, format := range []sdr.SampleFormat{
+		sdr.SampleFormatC64,
+		sdr.SampleFormatC128,
+		sdr.SampleFormatI16,
+		sdr.SampleFormatI8,
+		sdr.SampleFormatU8,
+	} {
+		in, stop := toneReader(1000000, 64, format)
+
+		var last float32
+		pm, err := stream.NewPowerMeter(in, stream.PowerMeterOptions{
+			Window:   10 * time.Millisecond,
+			Callback: func(dBFS float32) { last = dBFS },
+		})
+		assert.NoError(t, err)
+
+		// The tone is at 0.5 of full scale, or about -6 dBFS.
+		rms, _ := readRMS(t, pm, 100000)
+		assert.InDelta(t, 0.5, rms, 0.01, "%s", format)
+		assert.InDelta(t, 0.5, pm.RMS(), 0.01, "%s", format)
+		assert.InDelta(t, 0.25, pm.Power(), 0.01, "%s", format)
+		// int8 is scaled by 127 on the way in, but 128 on the way out.
+		assert.InDelta(t, -6.02, pm.DBFS(), 0.2, "%s", format)
+		assert.Equal(t, pm.DBFS(), last)
+
+		pm.Reset()
+		assert.Equal(t, float32(0), pm.Power())
+		stop()
+	}
+}
+
+func TestPowerMeterWindow(t *testing.T) {
+	pipeReader, pipeWriter := sdr.Pipe(100000, sdr.SampleFormatC64)
+	go func() {
+		loud := make(sdr.SamplesC64, 50000)
+		quiet := make(sdr.SamplesC64, 20000)
+		for i := range loud {
+			loud[i] = complex(1, 0)
+		}
+		for i := range quiet {
+			quiet[i] = complex(0.1, 0)
+		}
+		pipeWriter.Write(loud)
+		pipeWriter.Write(quiet)
+		pipeWriter.Close()
+	}()
+
+	pm, err := stream.NewPowerMeter(pipeReader, stream.PowerMeterOptions{
+		Window: 500 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+
+	buf := make(sdr.SamplesC64, 50000)
+	_, err = sdr.ReadFull(pm, buf)
+	assert.NoError(t, err)
+	assert.InDelta(t, 0, pm.DBFS(), 0.01)
+
+	// 62.5ms later, the 500ms window should now be 12.5% quiet at
+	// -20 dBFS, and 87.5% loud.
+	_, err = sdr.ReadFull(pm, buf[:6250])
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.125*0.01+0.875, pm.Power(), 0.001)
+
+	// And after another 62.5ms, 25% quiet.
+	_, err = sdr.ReadFull(pm, buf[:6250])
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.25*0.01+0.75, pm.Power(), 0.001)
+}
+
+type staticReader struct {
+	buf sdr.SamplesI16
+}
+
+func (r staticReader) SampleFormat() sdr.SampleFormat { return sdr.SampleFormatI16 }
+func (r staticReader) SampleRate() uint               { return 1000000 }
+func (r staticReader) Read(s sdr.Samples) (int, error) {
+	return copy(s.(sdr.SamplesI16), r.buf), nil
+}
+
+func TestPowerMeterAllocs(t *testing.T) {
+	r := staticReader{buf: make(sdr.SamplesI16, 4096)}
+	pm, err := stream.NewPowerMeter(r, stream.PowerMeterOptions{
+		Callback: func(float32) {},
+	})
+	assert.NoError(t, err)
+
+	var s sdr.Samples = make(sdr.SamplesI16, 4096)
+	allocs := testing.AllocsPerRun(100, func() {
+		pm.Read(s)
+	})
+	assert.Equal(t, float64(0), allocs)
+}
+
+// vim: foldmethod=marker
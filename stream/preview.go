@@ -0,0 +1,202 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package stream
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/filter"
+)
+
+// Preview is a heavily decimated copy of a full rate stream, for showing
+// activity in a UI (such as a waterfall) without getting in the way of the
+// full rate processing.
+//
+// Samples are decimated with a CIC filter as they're read from the full
+// rate stream, which costs a handful of additions per sample. If the
+// Preview isn't read quickly enough, decimated buffers are dropped rather
+// than slowing down the full rate stream.
+type Preview struct {
+	rate    uint
+	buf     chan sdr.SamplesC64
+	pending sdr.SamplesC64
+	dropped uint64
+
+	lock   *sync.Mutex
+	closed bool
+	err    error
+	done   chan struct{}
+}
+
+type previewTap struct {
+	sdr.Reader
+	preview *Preview
+	cic     *filter.CICDecimator
+	conv    sdr.SamplesC64
+}
+
+// NewPreview will attach a Preview to the provided Reader, decimated by the
+// provided factor, and holding up to `buffers` Reads worth of decimated
+// samples (or 16, if 0) before dropping them.
+//
+// The returned Reader must be used in place of the provided Reader for the
+// full rate processing, since the Preview is fed as samples are read
+// through it. The Preview is always in SampleFormatC64.
+func NewPreview(in sdr.Reader, factor uint, buffers int) (sdr.Reader, *Preview, error) {
+	cic, err := filter.NewCICDecimator(int(factor), 4)
+	if err != nil {
+		return nil, nil, err
+	}
+	if buffers == 0 {
+		buffers = 16
+	}
+	preview := &Preview{
+		rate: in.SampleRate() / factor,
+		buf:  make(chan sdr.SamplesC64, buffers),
+		lock: &sync.Mutex{},
+		done: make(chan struct{}),
+	}
+	return &previewTap{
+		Reader:  in,
+		preview: preview,
+		cic:     cic,
+	}, preview, nil
+}
+
+// Read implements the sdr.Reader interface.
+func (t *previewTap) Read(s sdr.Samples) (int, error) {
+	n, err := t.Reader.Read(s)
+	if n > 0 && !t.preview.isClosed() {
+		if perr := t.feed(s.Slice(0, n)); perr != nil {
+			t.preview.closeWithError(perr)
+		}
+	}
+	if err != nil {
+		t.preview.closeWithError(err)
+	}
+	return n, err
+}
+
+func (t *previewTap) feed(s sdr.Samples) error {
+	iq, ok := s.(sdr.SamplesC64)
+	if !ok {
+		if cap(t.conv) < s.Length() {
+			t.conv = make(sdr.SamplesC64, s.Length())
+		}
+		iq = t.conv[:s.Length()]
+		if _, err := sdr.ConvertBuffer(iq, s); err != nil {
+			return err
+		}
+	}
+
+	out := make(sdr.SamplesC64, t.cic.OutputLength(len(iq)))
+	n, err := t.cic.ProcessC64(out, iq)
+	if err != nil {
+		return err
+	}
+	out = out[:n]
+	if len(out) == 0 {
+		return nil
+	}
+
+	select {
+	case t.preview.buf <- out:
+	default:
+		atomic.AddUint64(&t.preview.dropped, uint64(len(out)))
+	}
+	return nil
+}
+
+func (p *Preview) isClosed() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.closed
+}
+
+func (p *Preview) closeWithError(err error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.closed {
+		return
+	}
+	p.closed = true
+	p.err = err
+	close(p.done)
+}
+
+// SampleRate implements the sdr.Reader interface.
+func (p *Preview) SampleRate() uint {
+	return p.rate
+}
+
+// SampleFormat implements the sdr.Reader interface.
+func (p *Preview) SampleFormat() sdr.SampleFormat {
+	return sdr.SampleFormatC64
+}
+
+// Dropped will return the number of decimated samples that were dropped
+// because the Preview wasn't read quickly enough.
+func (p *Preview) Dropped() uint64 {
+	return atomic.LoadUint64(&p.dropped)
+}
+
+// Read implements the sdr.Reader interface. This will block until the full
+// rate stream is read.
+func (p *Preview) Read(s sdr.Samples) (int, error) {
+	iq, ok := s.(sdr.SamplesC64)
+	if !ok {
+		return 0, sdr.ErrSampleFormatMismatch
+	}
+
+	if len(p.pending) == 0 {
+		select {
+		case p.pending = <-p.buf:
+		case <-p.done:
+			// Anything that was buffered before the stream ended is still
+			// worth reading.
+			select {
+			case p.pending = <-p.buf:
+			default:
+				p.lock.Lock()
+				defer p.lock.Unlock()
+				if p.err == nil {
+					return 0, sdr.ErrPipeClosed
+				}
+				return 0, p.err
+			}
+		}
+	}
+
+	n := copy(iq, p.pending)
+	p.pending = p.pending[n:]
+	return n, nil
+}
+
+// Close implements the sdr.Closer interface. This will stop samples being
+// decimated for the Preview, but won't affect the full rate stream.
+func (p *Preview) Close() error {
+	p.closeWithError(nil)
+	return nil
+}
+
+// vim: foldmethod=marker
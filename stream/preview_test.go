@@ -0,0 +1,115 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package stream_test
+
+import (
+	"math/cmplx"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/stream"
+)
+
+// patternReader will repeat the provided pattern, forever.
+func patternReader(sampleRate uint, pattern sdr.SamplesC64) (sdr.ReadCloser, func()) {
+	pipeReader, pipeWriter := sdr.Pipe(sampleRate, sdr.SampleFormatC64)
+	go func() {
+		buf := make(sdr.SamplesC64, 1000*len(pattern))
+		for i := range buf {
+			buf[i] = pattern[i%len(pattern)]
+		}
+		for {
+			if _, err := pipeWriter.Write(buf); err != nil {
+				return
+			}
+		}
+	}()
+	return pipeReader, func() { pipeReader.Close() }
+}
+
+// readPreview will read 100000 samples from the full rate Reader, and then
+// return the magnitude of the last sample in the Preview.
+func readPreview(t *testing.T, full sdr.Reader, preview *stream.Preview) float64 {
+	previewBuf := make(sdr.SamplesC64, 100)
+	fullBuf := make(sdr.SamplesC64, 10000)
+	var last complex64
+	for i := 0; i < 10; i++ {
+		_, err := sdr.ReadFull(full, fullBuf)
+		assert.NoError(t, err)
+
+		_, err = sdr.ReadFull(preview, previewBuf)
+		assert.NoError(t, err)
+		last = previewBuf[len(previewBuf)-1]
+	}
+	return cmplx.Abs(complex128(last))
+}
+
+func TestPreview(t *testing.T) {
+	in, stop := patternReader(1000000, sdr.SamplesC64{1})
+	defer stop()
+
+	full, preview, err := stream.NewPreview(in, 100, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1000000), full.SampleRate())
+	assert.Equal(t, uint(10000), preview.SampleRate())
+
+	assert.InDelta(t, 1, readPreview(t, full, preview), 1e-3)
+}
+
+func TestPreviewStopband(t *testing.T) {
+	// Fs/4 is well out of the 10 kHz preview.
+	in, stop := patternReader(1000000, sdr.SamplesC64{1, 1i, -1, -1i})
+	defer stop()
+
+	full, preview, err := stream.NewPreview(in, 100, 0)
+	assert.NoError(t, err)
+	assert.InDelta(t, 0, readPreview(t, full, preview), 1e-3)
+}
+
+func TestPreviewDrops(t *testing.T) {
+	in, stop := patternReader(1000000, sdr.SamplesC64{1})
+
+	full, preview, err := stream.NewPreview(in, 100, 2)
+	assert.NoError(t, err)
+
+	// Nobody's reading the preview, which must not stop the full rate
+	// stream.
+	buf := make(sdr.SamplesC64, 10000)
+	for i := 0; i < 10; i++ {
+		_, err := sdr.ReadFull(full, buf)
+		assert.NoError(t, err)
+	}
+	assert.True(t, preview.Dropped() > 0)
+
+	stop()
+	_, err = full.Read(buf)
+	assert.Error(t, err)
+
+	// What's buffered is still readable, and then the error comes through.
+	for err = nil; err == nil; {
+		_, err = preview.Read(buf)
+	}
+	assert.Equal(t, sdr.ErrPipeClosed, err)
+}
+
+// vim: foldmethod=marker
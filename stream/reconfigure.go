@@ -0,0 +1,66 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package stream
+
+import (
+	"context"
+	"fmt"
+
+	"hz.tools/sdr"
+)
+
+var (
+	// ErrUnknownParameter will be returned by Reconfigure if a parameter
+	// is passed that the stage doesn't know about.
+	ErrUnknownParameter = fmt.Errorf("stream: unknown reconfigure parameter")
+
+	// ErrInvalidParameter will be returned by Reconfigure if a parameter
+	// is of the wrong type, or has a value that doesn't make sense.
+	ErrInvalidParameter = fmt.Errorf("stream: invalid reconfigure parameter")
+)
+
+// Params are the parameters to change on a running stage. Each stage
+// documents which parameters it understands, and what type the value must be.
+type Params map[string]interface{}
+
+// Reconfigurer is a pipeline stage which can have its parameters changed
+// while it's running, without tearing down the stream feeding into it.
+type Reconfigurer interface {
+	// Reconfigure will apply the provided parameters to the stage. Either
+	// all parameters are applied, or (if an error is returned) none are.
+	//
+	// If the change can't be applied right away (for instance, because a
+	// Read is in flight), this will wait until it can, or the context is
+	// done, in which case the context's error is returned.
+	Reconfigure(context.Context, Params) error
+}
+
+// Reconfigure will call Reconfigure on the provided stage, if it implements
+// the Reconfigurer interface, otherwise sdr.ErrNotSupported is returned.
+func Reconfigure(ctx context.Context, stage interface{}, params Params) error {
+	r, ok := stage.(Reconfigurer)
+	if !ok {
+		return sdr.ErrNotSupported
+	}
+	return r.Reconfigure(ctx, params)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,146 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package stream_test
+
+import (
+	"context"
+	"math/cmplx"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/stream"
+)
+
+func constantReader(sampleRate uint) (sdr.ReadCloser, func()) {
+	pipeReader, pipeWriter := sdr.Pipe(sampleRate, sdr.SampleFormatC64)
+	go func() {
+		buf := make(sdr.SamplesC64, 1024)
+		for i := range buf {
+			buf[i] = 1
+		}
+		for {
+			if _, err := pipeWriter.Write(buf); err != nil {
+				return
+			}
+		}
+	}()
+	return pipeReader, func() { pipeReader.Close() }
+}
+
+func TestReconfigureShift(t *testing.T) {
+	in, stop := constantReader(1000)
+	defer stop()
+
+	r, err := stream.ShiftReader(in, 0)
+	assert.NoError(t, err)
+
+	buf := make(sdr.SamplesC64, 100)
+	_, err = sdr.ReadFull(r, buf)
+	assert.NoError(t, err)
+	for _, s := range buf {
+		assert.InDelta(t, 1, real(s), 1e-5)
+	}
+
+	ctx := context.Background()
+	assert.NoError(t, stream.Reconfigure(ctx, r, stream.Params{"shift": rf.Hz(250)}))
+
+	// At a quarter of the sample rate, each sample rotates by 90 degrees.
+	_, err = sdr.ReadFull(r, buf)
+	assert.NoError(t, err)
+	for i := 1; i < len(buf); i++ {
+		delta := cmplx.Phase(complex128(buf[i] * complex(real(buf[i-1]), -imag(buf[i-1]))))
+		assert.InDelta(t, 1.5708, delta, 1e-3)
+	}
+
+	assert.Equal(t, stream.ErrUnknownParameter, stream.Reconfigure(ctx, r, stream.Params{"gain": 1}))
+	assert.Equal(t, stream.ErrInvalidParameter, stream.Reconfigure(ctx, r, stream.Params{"shift": 1}))
+	assert.Equal(t, sdr.ErrNotSupported, stream.Reconfigure(ctx, in, stream.Params{}))
+}
+
+func TestSwapReader(t *testing.T) {
+	in, stop := constantReader(1000)
+	defer stop()
+
+	decimate := func(factor uint) stream.Stage {
+		return func(r sdr.Reader) (sdr.Reader, error) {
+			return stream.DecimateReader(r, factor)
+		}
+	}
+
+	sr, err := stream.NewSwapReader(in, decimate(2))
+	assert.NoError(t, err)
+	defer sr.Close()
+	assert.Equal(t, uint(500), sr.SampleRate())
+
+	buf := make(sdr.SamplesC64, 100)
+	_, err = sdr.ReadFull(sr, buf)
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	assert.NoError(t, sr.Reconfigure(ctx, stream.Params{"stage": decimate(4)}))
+	assert.Equal(t, uint(250), sr.SampleRate())
+
+	_, err = sdr.ReadFull(sr, buf)
+	assert.NoError(t, err)
+	assert.Equal(t, complex64(1), buf[99])
+
+	assert.Equal(t, stream.ErrInvalidParameter, sr.Reconfigure(ctx, stream.Params{"stage": 4}))
+	assert.Equal(t, sdr.ErrNotSupported, sr.Reconfigure(ctx, stream.Params{"shift": rf.Hz(1)}))
+}
+
+func TestSwapReaderReconfigurePassthrough(t *testing.T) {
+	in, stop := constantReader(1000)
+	defer stop()
+
+	sr, err := stream.NewSwapReader(in, func(r sdr.Reader) (sdr.Reader, error) {
+		return stream.ShiftReader(r, 0)
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, sr.Reconfigure(context.Background(), stream.Params{"shift": rf.Hz(10)}))
+}
+
+func TestSwapReaderContext(t *testing.T) {
+	pipeReader, _ := sdr.Pipe(1000, sdr.SampleFormatC64)
+	defer pipeReader.Close()
+
+	sr, err := stream.NewSwapReader(pipeReader, func(r sdr.Reader) (sdr.Reader, error) {
+		return r, nil
+	})
+	assert.NoError(t, err)
+
+	// Nothing is ever written, so this Read blocks until the pipe is
+	// closed.
+	go sr.Read(make(sdr.SamplesC64, 10))
+	time.Sleep(time.Millisecond * 10)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	err = sr.Swap(ctx, func(r sdr.Reader) (sdr.Reader, error) {
+		return r, nil
+	})
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,73 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package stream
+
+import (
+	"hz.tools/sdr"
+	"hz.tools/sdr/filter"
+)
+
+// Resample will change the sample rate of the provided Reader to outRate,
+// using a rational polyphase resampler (see filter.Resampler). Both rates
+// are reduced to a ratio of interp/decim, and the interpolation factor must
+// be no more than 1024 once reduced; 2.4 Msps to 48 kHz is 1/50, and 44.1 kHz
+// to 48 kHz is 160/147.
+//
+// Only SampleFormatC64 is supported; other formats can be converted
+// first with ConvertReader.
+func Resample(r sdr.Reader, outRate uint) (sdr.Reader, error) {
+	if r.SampleFormat() != sdr.SampleFormatC64 {
+		return nil, sdr.ErrSampleFormatMismatch
+	}
+
+	inRate := r.SampleRate()
+	if inRate == outRate {
+		return r, nil
+	}
+
+	// Scale the filter length with the decimation, so that the lowpass is
+	// just as sharp relative to the output rate.
+	tapsPerPhase := 24
+	if outRate < inRate {
+		tapsPerPhase *= int((inRate + outRate - 1) / outRate)
+	}
+
+	resampler, err := filter.NewResampler(inRate, outRate, tapsPerPhase)
+	if err != nil {
+		return nil, err
+	}
+
+	inputLength := 32 * 1024
+	return ReadTransformer(r, ReadTransformerConfig{
+		InputBufferLength:  inputLength,
+		OutputBufferLength: resampler.OutputLength(inputLength),
+		OutputSampleRate:   outRate,
+		OutputSampleFormat: sdr.SampleFormatC64,
+		Proc: func(inBuf sdr.Samples, outBuf sdr.Samples) (int, error) {
+			return resampler.ProcessC64(
+				outBuf.(sdr.SamplesC64),
+				inBuf.(sdr.SamplesC64),
+			)
+		},
+	})
+}
+
+// vim: foldmethod=marker
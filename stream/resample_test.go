@@ -0,0 +1,80 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package stream_test
+
+import (
+	"math"
+	"math/cmplx"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/stream"
+	"hz.tools/sdr/testutils"
+)
+
+func TestResample(t *testing.T) {
+	const (
+		inRate  = 2400000
+		outRate = 48000
+		freq    = rf.Hz(5000)
+	)
+
+	in := make(sdr.SamplesC64, inRate/4)
+	testutils.CW(in, freq, inRate, 0)
+
+	pipeReader, pipeWriter := sdr.Pipe(inRate, sdr.SampleFormatC64)
+	r, err := stream.Resample(pipeReader, outRate)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(outRate), r.SampleRate())
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		// This will be cut off when the input is closed below, since the
+		// resampler reads in whole chunks.
+		pipeWriter.Write(in)
+	}()
+
+	buf := make(sdr.SamplesC64, 4096)
+	_, err = sdr.ReadFull(r, buf)
+	assert.NoError(t, err)
+	pipeReader.Close()
+	wg.Wait()
+
+	// Once the filter has settled, the tone should step by the same phase
+	// each sample at the new rate.
+	step := 2 * math.Pi * float64(freq) / outRate
+	for i := 100; i < len(buf)-1; i++ {
+		got := cmplx.Phase(complex128(buf[i+1] * complex(real(buf[i]), -imag(buf[i]))))
+		assert.InDelta(t, step, got, 0.001)
+	}
+
+	i16Reader, _ := sdr.Pipe(inRate, sdr.SampleFormatI16)
+	_, err = stream.Resample(i16Reader, outRate)
+	assert.Equal(t, sdr.ErrSampleFormatMismatch, err)
+}
+
+// vim: foldmethod=marker
@@ -29,11 +29,12 @@ import (
 )
 
 var (
-	// // ErrRingBufferOverrun will be returned if a Write operation on a
-	// // Ring Buffer catches up to the Read head.
-	// //
-	// // This error is only returned if BlockWrites is set to False.
-	// ErrRingBufferOverrun error = fmt.Errorf("RingBuffer: Buffer Overrun")
+	// ErrRingBufferOverrun will be returned if a Write operation on a
+	// Ring Buffer catches up to the Read head.
+	//
+	// This error is only returned if CloseOnOverrun is set to True,
+	// otherwise the oldest unread slot is silently overwritten.
+	ErrRingBufferOverrun = fmt.Errorf("RingBuffer: Buffer Overrun")
 
 	// ErrRingBufferUnderrun will be returned if a Read operation on a
 	// Ring Buffer catches up to the Write head. This can be a temporary
@@ -57,6 +58,13 @@ type RingBufferOptions struct {
 	// if the Read cursor has caught up with the Write cursor.
 	BlockReads bool
 
+	// CloseOnOverrun will close the Ring Buffer with an ErrRingBufferOverrun
+	// if a Write catches up to the Read cursor, rather than overwriting the
+	// oldest unread slot. Reads will get the slots written before the
+	// overrun, followed by the ErrRingBufferOverrun, so that a slow reader
+	// finds out that samples were lost.
+	CloseOnOverrun bool
+
 	// IQBufferAllocator will be passed the configured RingBufferOptions,
 	// and allocate an sdr.Samples object that is long enough for the
 	// Buffer (Slots*SlotLength at minimum). If Nil, this will use
@@ -197,6 +205,9 @@ func (rb *RingBuffer) getErr() error {
 }
 
 // Read implements the sdr.Reader interface.
+//
+// Any slots that were written before the RingBuffer was closed will be
+// returned before the close error is.
 func (rb *RingBuffer) Read(buf sdr.Samples) (int, error) {
 	if buf.Length() < rb.slotLength() {
 		return 0, fmt.Errorf("RingBuffer: Slot is larger than the target Read buffer")
@@ -205,8 +216,12 @@ func (rb *RingBuffer) Read(buf sdr.Samples) (int, error) {
 	rb.lock.Lock()
 	defer rb.lock.Unlock()
 
-	id := rb.advanceReadCursor()
-	if id == -1 {
+	var id int
+	for {
+		if id = rb.advanceReadCursor(); id != -1 {
+			break
+		}
+
 		// This is reached when there's nothing in the buffer.
 
 		if err := rb.getErr(); err != nil {
@@ -218,20 +233,9 @@ func (rb *RingBuffer) Read(buf sdr.Samples) (int, error) {
 			return 0, ErrRingBufferUnderrun
 		}
 
-		// attempt to move the cursor forward.
-		for ; id == -1; id = rb.advanceReadCursor() {
-			// Attempt to aquire the lock until we have data that we
-			// can read from the next slot.
-			rb.cond.Wait()
-
-			if err := rb.getErr(); err != nil {
-				return 0, err
-			}
-		}
-
-		if err := rb.getErr(); err != nil {
-			return 0, err
-		}
+		// Wait until a Write or Close wakes us up, and then check again to
+		// see if we have data that we can read from the next slot.
+		rb.cond.Wait()
 	}
 
 	slot, err := rb.slot(id)
@@ -261,9 +265,15 @@ func (rb *RingBuffer) Write(buf sdr.Samples) (int, error) {
 		return 0, err
 	}
 
-	// advance the write header, blow away any existing data for now
-	// TODO: add in a block toggle.
-	id, _ := rb.advanceWriteCursor(true)
+	// advance the write header, blowing away the oldest slot if we're full,
+	// unless we've been asked to report that.
+	id, _ := rb.advanceWriteCursor(!rb.opts.CloseOnOverrun)
+	if id == -1 {
+		rb.closed = true
+		rb.err = ErrRingBufferOverrun
+		rb.cond.Broadcast()
+		return 0, ErrRingBufferOverrun
+	}
 
 	slot, err := rb.slot(id)
 	if err != nil {
@@ -278,16 +288,26 @@ func (rb *RingBuffer) Write(buf sdr.Samples) (int, error) {
 }
 
 // CloseWithError will set the error state on the Ring Buffer.
+//
+// Only the first call to Close or CloseWithError will set the error state,
+// later calls will not overwrite it. All blocked Reads will be woken up,
+// and will return the error once the remaining slots have been read.
 func (rb *RingBuffer) CloseWithError(err error) error {
+	rb.lock.Lock()
+	defer rb.lock.Unlock()
+
+	if rb.closed {
+		return nil
+	}
+	rb.closed = true
 	rb.err = err
-	return rb.Close()
+	rb.cond.Broadcast()
+	return nil
 }
 
 // Close implements the sdr.Closer interface.
 func (rb *RingBuffer) Close() error {
-	rb.closed = true
-	rb.cond.Signal()
-	return nil
+	return rb.CloseWithError(nil)
 }
 
 // SampleFormat implements the sdr.ReadWriteCloser interface.
@@ -300,6 +320,42 @@ func (rb *RingBuffer) SampleRate() uint {
 	return rb.rate
 }
 
+// ringBufferReader will read from a RingBuffer into buffers of any size, by
+// holding on to whatever is left of the last slot read.
+type ringBufferReader struct {
+	*RingBuffer
+	buf     sdr.Samples
+	pending sdr.Samples
+}
+
+func (r *ringBufferReader) Read(s sdr.Samples) (int, error) {
+	if r.pending == nil || r.pending.Length() == 0 {
+		if s.Length() >= r.buf.Length() {
+			return r.RingBuffer.Read(s)
+		}
+		n, err := r.RingBuffer.Read(r.buf)
+		if err != nil {
+			return 0, err
+		}
+		r.pending = r.buf.Slice(0, n)
+	}
+	n, err := sdr.CopySamples(s, r.pending)
+	r.pending = r.pending.Slice(n, r.pending.Length())
+	return n, err
+}
+
+// RingBufferReader will return a ReadCloser over the RingBuffer which can be
+// Read into buffers of any size. RingBuffer.Read will only Read into a
+// buffer of at least SlotLength samples, which is not what most code
+// handed an sdr.Reader expects.
+func RingBufferReader(rb *RingBuffer) (sdr.ReadCloser, error) {
+	buf, err := sdr.MakeSamples(rb.SampleFormat(), rb.slotLength())
+	if err != nil {
+		return nil, err
+	}
+	return &ringBufferReader{RingBuffer: rb, buf: buf}, nil
+}
+
 // NewRingBuffer will create a RingBuffer with the provided options
 func NewRingBuffer(
 	rate uint,
@@ -170,6 +170,42 @@ func TestRingBufferOverrun(t *testing.T) {
 
 }
 
+func TestRingBufferCloseOnOverrun(t *testing.T) {
+	b := make(sdr.SamplesC64, 1024)
+
+	rb, err := stream.NewRingBuffer(0, sdr.SampleFormatC64, stream.RingBufferOptions{
+		Slots:          4,
+		SlotLength:     1024,
+		CloseOnOverrun: true,
+	})
+	assert.NoError(t, err)
+
+	for i := 1; i <= 3; i++ {
+		b[0] = complex(float32(i), 0)
+		_, err = rb.Write(b)
+		assert.NoError(t, err)
+	}
+
+	// The fourth write would overwrite the first slot, which hasn't been
+	// read yet.
+	b[0] = 4
+	_, err = rb.Write(b)
+	assert.Equal(t, stream.ErrRingBufferOverrun, err)
+
+	_, err = rb.Write(b)
+	assert.Equal(t, stream.ErrRingBufferOverrun, err)
+
+	// The slots written before the overrun are still there.
+	for i := 1; i <= 3; i++ {
+		_, err = rb.Read(b)
+		assert.NoError(t, err)
+		assert.Equal(t, complex(float32(i), 0), b[0])
+	}
+
+	_, err = rb.Read(b)
+	assert.Equal(t, stream.ErrRingBufferOverrun, err)
+}
+
 func BenchmarkRing(b *testing.B) {
 	rb, err := stream.NewRingBuffer(0, sdr.SampleFormatC64, stream.RingBufferOptions{
 		Slots:      32,
@@ -286,4 +322,120 @@ func TestUnsafeRingBufferUnsafe(t *testing.T) {
 	assert.Equal(t, uint8(0xAB), buf[0][1])
 }
 
+func TestRingBufferCloseWakesAllReaders(t *testing.T) {
+	rb, err := stream.NewRingBuffer(0, sdr.SampleFormatU8, stream.RingBufferOptions{
+		Slots:      10,
+		SlotLength: 1024,
+		BlockReads: true,
+	})
+	assert.NoError(t, err)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := rb.Read(make(sdr.SamplesU8, 1024))
+			assert.Equal(t, io.EOF, err)
+		}()
+	}
+
+	assert.NoError(t, rb.CloseWithError(io.EOF))
+	wg.Wait()
+}
+
+func TestRingBufferCloseDrains(t *testing.T) {
+	rb, err := stream.NewRingBuffer(0, sdr.SampleFormatU8, stream.RingBufferOptions{
+		Slots:      10,
+		SlotLength: 1024,
+		BlockReads: true,
+	})
+	assert.NoError(t, err)
+
+	_, err = rb.Write(make(sdr.SamplesU8, 10))
+	assert.NoError(t, err)
+	assert.NoError(t, rb.CloseWithError(io.EOF))
+	assert.NoError(t, rb.CloseWithError(io.ErrUnexpectedEOF))
+
+	_, err = rb.Write(make(sdr.SamplesU8, 10))
+	assert.Equal(t, io.EOF, err)
+
+	n, err := rb.Read(make(sdr.SamplesU8, 1024))
+	assert.NoError(t, err)
+	assert.Equal(t, 10, n)
+
+	_, err = rb.Read(make(sdr.SamplesU8, 1024))
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestRingBufferCloseStress(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		rb, err := stream.NewRingBuffer(0, sdr.SampleFormatU8, stream.RingBufferOptions{
+			Slots:      4,
+			SlotLength: 128,
+			BlockReads: true,
+		})
+		assert.NoError(t, err)
+
+		wg := sync.WaitGroup{}
+		for j := 0; j < 4; j++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				buf := make(sdr.SamplesU8, 128)
+				for {
+					if _, err := rb.Write(buf); err != nil {
+						return
+					}
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				buf := make(sdr.SamplesU8, 128)
+				for {
+					if _, err := rb.Read(buf); err != nil {
+						assert.Equal(t, sdr.ErrPipeClosed, err)
+						return
+					}
+				}
+			}()
+		}
+		assert.NoError(t, rb.Close())
+		wg.Wait()
+	}
+}
+
+func TestRingBufferReader(t *testing.T) {
+	rb, err := stream.NewRingBuffer(0, sdr.SampleFormatU8, stream.RingBufferOptions{
+		Slots:      4,
+		SlotLength: 16,
+	})
+	assert.NoError(t, err)
+
+	in := make(sdr.SamplesU8, 16)
+	for i := range in {
+		in[i] = [2]uint8{uint8(i), uint8(i)}
+	}
+	_, err = rb.Write(in)
+	assert.NoError(t, err)
+	_, err = rb.Write(in[:8])
+	assert.NoError(t, err)
+	assert.NoError(t, rb.CloseWithError(io.EOF))
+
+	r, err := stream.RingBufferReader(rb)
+	assert.NoError(t, err)
+
+	var out sdr.SamplesU8
+	buf := make(sdr.SamplesU8, 5)
+	for {
+		n, err := r.Read(buf)
+		out = append(out, buf[:n]...)
+		if err != nil {
+			assert.Equal(t, io.EOF, err)
+			break
+		}
+	}
+	assert.Equal(t, append(in, in[:8]...), out)
+}
+
 // vim: foldmethod=marker
@@ -21,7 +21,9 @@
 package stream
 
 import (
+	"context"
 	"math"
+	"sync"
 
 	"hz.tools/rf"
 	"hz.tools/sdr"
@@ -29,10 +31,38 @@ import (
 
 type shiftReader struct {
 	r     sdr.Reader
+	lock  *sync.Mutex
 	shift rf.Hz
 	fn    func(rf.Hz, sdr.SamplesC64)
 }
 
+// Reconfigure implements the Reconfigurer interface. The only parameter
+// understood is "shift", which must be an rf.Hz.
+func (sr *shiftReader) Reconfigure(ctx context.Context, params Params) error {
+	var shift rf.Hz
+	for key, value := range params {
+		switch key {
+		case "shift":
+			v, ok := value.(rf.Hz)
+			if !ok {
+				return ErrInvalidParameter
+			}
+			shift = v
+		default:
+			return ErrUnknownParameter
+		}
+	}
+
+	if _, ok := params["shift"]; !ok {
+		return nil
+	}
+
+	sr.lock.Lock()
+	defer sr.lock.Unlock()
+	sr.shift = shift
+	return nil
+}
+
 func (sr *shiftReader) SampleFormat() sdr.SampleFormat {
 	return sr.r.SampleFormat()
 }
@@ -57,7 +87,10 @@ func (sr *shiftReader) Read(s sdr.Samples) (int, error) {
 	// TODO(paultag): Fix this to be safe when the above format checks
 	// grow.
 	sC64 := s.Slice(0, n).(sdr.SamplesC64)
-	sr.fn(sr.shift, sC64)
+	sr.lock.Lock()
+	shift := sr.shift
+	sr.lock.Unlock()
+	sr.fn(shift, sC64)
 	return n, nil
 }
 
@@ -65,27 +98,32 @@ func (sr *shiftReader) Read(s sdr.Samples) (int, error) {
 // buffers.
 func ShiftBuffer(sampleRate uint) func(rf.Hz, sdr.SamplesC64) {
 	var (
-		ts  float64
-		inc = (1 / float64(sampleRate))
-		tau = math.Pi * 2
+		phase float64
+		inc   = (1 / float64(sampleRate))
+		tau   = math.Pi * 2
 	)
 
 	return func(freq rf.Hz, buf sdr.SamplesC64) {
-		shift := float64(freq)
+		step := tau * float64(freq) * inc
 		for j := range buf {
-			ts += inc
-			if ts > tau {
-				ts -= tau
-			}
+			// Track the phase rather than the time, so that the phase stays
+			// continuous when it's wrapped, or when the frequency changes.
+			phase = math.Mod(phase+step, tau)
 
-			im, rl := math.Sincos(tau * shift * ts)
+			im, rl := math.Sincos(phase)
 			buf[j] = buf[j] * complex64(complex(rl, im))
 		}
 	}
 }
 
-// ShiftReader will shift the iq samples by the target frequency. So a carrier
-// at the provided shift frequency offset will be read through at DC.
+// ShiftReader will shift the iq samples up by the target frequency, by
+// mixing with e^(j*2*pi*shift*t). So a carrier at the negative of the
+// provided shift frequency offset will be read through at DC; to move a
+// signal at some offset to DC, either pass -offset here, or see
+// ShiftFrequency, which takes the offset of the signal itself.
+//
+// The returned Reader implements Reconfigurer, so the shift can be changed
+// while the Reader is in use, without losing phase continuity.
 func ShiftReader(r sdr.Reader, shift rf.Hz) (sdr.Reader, error) {
 	switch r.SampleFormat() {
 	case sdr.SampleFormatC64:
@@ -96,6 +134,7 @@ func ShiftReader(r sdr.Reader, shift rf.Hz) (sdr.Reader, error) {
 
 	return &shiftReader{
 		r:     r,
+		lock:  &sync.Mutex{},
 		shift: shift,
 		fn:    ShiftBuffer(r.SampleRate()),
 	}, nil
@@ -21,6 +21,8 @@
 package stream_test
 
 import (
+	"math"
+	"math/cmplx"
 	"sync"
 	"testing"
 
@@ -71,4 +73,38 @@ func TestShifter(t *testing.T) {
 	wg.Wait()
 }
 
+// TestShiftBufferPhase checks that the phase of the shift is continuous
+// across buffers, and stays continuous well past 2*pi seconds, which is
+// where the time counter used to wrap and jump the phase.
+func TestShiftBufferPhase(t *testing.T) {
+	const sampleRate = 100
+
+	var (
+		shift = rf.Hz(0.3)
+		step  = 2 * math.Pi * float64(shift) / sampleRate
+		fn    = stream.ShiftBuffer(sampleRate)
+		last  complex128
+	)
+
+	// 10 seconds of DC, shifted in uneven chunks.
+	for i, chunk := 0, 0; i < sampleRate*10; i += chunk {
+		chunk = 7 + i%13
+		buf := make(sdr.SamplesC64, chunk)
+		for j := range buf {
+			buf[j] = 1
+		}
+		fn(shift, buf)
+
+		for j, s := range buf {
+			if i+j == 0 {
+				last = complex128(s)
+				continue
+			}
+			delta := cmplx.Phase(complex128(s) * cmplx.Conj(last))
+			assert.InDelta(t, step, delta, 1e-5, "sample %d", i+j)
+			last = complex128(s)
+		}
+	}
+}
+
 // vim: foldmethod=marker
@@ -0,0 +1,104 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+//go:build soak
+// +build soak
+
+package stream_test
+
+import (
+	"sync"
+	"testing"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/mock"
+	"hz.tools/sdr/soak"
+	"hz.tools/sdr/stream"
+)
+
+const soakRate = 2048000
+
+type soakSource struct {
+	lock   *sync.Mutex
+	closed bool
+}
+
+func (soakSource) SampleRate() uint               { return soakRate }
+func (soakSource) SampleFormat() sdr.SampleFormat { return sdr.SampleFormatC64 }
+
+func (s soakSource) Read(buf sdr.Samples) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return 0, sdr.ErrPipeClosed
+	}
+	return buf.Length(), nil
+}
+
+func (s *soakSource) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.closed = true
+	return nil
+}
+
+// soakPipeline will copy a clocked source into the provided pipe from a
+// goroutine, and return the read end of the pipe.
+func soakPipeline(pipe sdr.ReadWriteCloser) sdr.ReadCloser {
+	src := mock.Clock{}.Reader(&soakSource{lock: &sync.Mutex{}}, soakRate)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		sdr.Copy(pipe, src)
+		pipe.Close()
+	}()
+	return sdr.ReaderWithCloser(pipe, func() error {
+		src.Close()
+		pipe.Close()
+		<-done
+		return nil
+	})
+}
+
+func TestSoakBufPipe2(t *testing.T) {
+	soak.RunTest(t, func() (sdr.ReadCloser, error) {
+		pipe, err := stream.NewBufPipe2(16, soakRate, sdr.SampleFormatC64)
+		if err != nil {
+			return nil, err
+		}
+		return soakPipeline(pipe), nil
+	}, soak.Config{RateTolerance: 0.01})
+}
+
+func TestSoakRingBuffer(t *testing.T) {
+	soak.RunTest(t, func() (sdr.ReadCloser, error) {
+		rb, err := stream.NewRingBuffer(soakRate, sdr.SampleFormatC64, stream.RingBufferOptions{
+			Slots:      64,
+			SlotLength: 32 * 1024,
+			BlockReads: true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return soakPipeline(rb), nil
+	}, soak.Config{RateTolerance: 0.01})
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,217 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package stream
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"hz.tools/sdr"
+)
+
+var (
+	// ErrStageDetached will be returned to a stage that has been swapped
+	// out of a SwapReader, when it tries to read from the input.
+	ErrStageDetached = fmt.Errorf("stream: stage has been swapped out")
+)
+
+// Stage builds a processing stage (such as a demodulator, or a
+// DecimateReader) on top of the provided input Reader.
+type Stage func(sdr.Reader) (sdr.Reader, error)
+
+// SwapReader runs a Stage over an input Reader, and allows the Stage to be
+// swapped for another at runtime (say, to change the decimation factor, or
+// switch demodulators) without tearing down the input stream, such as the
+// StartRx of a radio.
+//
+// Any samples buffered inside the old Stage are lost during a swap. Since a
+// new Stage may have a different SampleRate or SampleFormat, consumers
+// should check them again after a swap.
+type SwapReader struct {
+	input *gatedInput
+
+	// sema is a lock that can be waited on in a select, so Reconfigure can
+	// give up if its context is done while a Read is in flight.
+	sema  chan struct{}
+	gate  *gate
+	stage sdr.Reader
+}
+
+// NewSwapReader will create a new SwapReader, running the provided Stage
+// over the input.
+func NewSwapReader(in sdr.Reader, stage Stage) (*SwapReader, error) {
+	sr := &SwapReader{
+		input: &gatedInput{lock: &sync.Mutex{}, r: in},
+		sema:  make(chan struct{}, 1),
+	}
+	if err := sr.attach(stage); err != nil {
+		return nil, err
+	}
+	return sr, nil
+}
+
+func (sr *SwapReader) attach(stage Stage) error {
+	g := &gate{input: sr.input}
+	r, err := stage(g)
+	if err != nil {
+		return err
+	}
+
+	if sr.gate != nil {
+		sr.input.lock.Lock()
+		sr.gate.detached = true
+		sr.input.lock.Unlock()
+		if closer, ok := sr.stage.(sdr.ReadCloser); ok {
+			closer.Close()
+		}
+	}
+
+	sr.gate = g
+	sr.stage = r
+	return nil
+}
+
+// Swap will replace the running Stage with a new Stage built on the same
+// input. If building the new Stage fails, the old Stage is left running.
+func (sr *SwapReader) Swap(ctx context.Context, stage Stage) error {
+	select {
+	case sr.sema <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-sr.sema }()
+	return sr.attach(stage)
+}
+
+// Reconfigure implements the Reconfigurer interface. The only parameter
+// understood is "stage", which must be a Stage (or a function with the same
+// signature), and is passed to Swap. Any other parameters are passed along
+// to the running Stage, if it implements Reconfigurer.
+func (sr *SwapReader) Reconfigure(ctx context.Context, params Params) error {
+	rest := Params{}
+	var stage Stage
+	for key, value := range params {
+		if key != "stage" {
+			rest[key] = value
+			continue
+		}
+		switch v := value.(type) {
+		case Stage:
+			stage = v
+		case func(sdr.Reader) (sdr.Reader, error):
+			stage = v
+		default:
+			return ErrInvalidParameter
+		}
+	}
+
+	if stage != nil {
+		if err := sr.Swap(ctx, stage); err != nil {
+			return err
+		}
+	}
+	if len(rest) == 0 {
+		return nil
+	}
+
+	select {
+	case sr.sema <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	r := sr.stage
+	<-sr.sema
+	return Reconfigure(ctx, r, rest)
+}
+
+// SampleRate implements the sdr.Reader interface, returning the SampleRate
+// of the running Stage.
+func (sr *SwapReader) SampleRate() uint {
+	sr.sema <- struct{}{}
+	defer func() { <-sr.sema }()
+	return sr.stage.SampleRate()
+}
+
+// SampleFormat implements the sdr.Reader interface, returning the
+// SampleFormat of the running Stage.
+func (sr *SwapReader) SampleFormat() sdr.SampleFormat {
+	sr.sema <- struct{}{}
+	defer func() { <-sr.sema }()
+	return sr.stage.SampleFormat()
+}
+
+// Read implements the sdr.Reader interface.
+func (sr *SwapReader) Read(s sdr.Samples) (int, error) {
+	sr.sema <- struct{}{}
+	defer func() { <-sr.sema }()
+	return sr.stage.Read(s)
+}
+
+// Close will close the running Stage (if it's an sdr.ReadCloser). This will
+// not close the input Reader.
+func (sr *SwapReader) Close() error {
+	sr.sema <- struct{}{}
+	defer func() { <-sr.sema }()
+
+	sr.input.lock.Lock()
+	sr.gate.detached = true
+	sr.input.lock.Unlock()
+
+	if closer, ok := sr.stage.(sdr.ReadCloser); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// gatedInput is the input to a SwapReader, shared by every Stage that has
+// been attached, so that only one of them is reading at once.
+type gatedInput struct {
+	lock *sync.Mutex
+	r    sdr.Reader
+}
+
+// gate is the Reader passed to a single Stage. Once detached, reads will
+// fail, so that a Stage which reads from a goroutine (such as anything
+// built on ReadTransformer) will stop stealing samples from its successor.
+type gate struct {
+	input    *gatedInput
+	detached bool
+}
+
+func (g *gate) SampleRate() uint {
+	return g.input.r.SampleRate()
+}
+
+func (g *gate) SampleFormat() sdr.SampleFormat {
+	return g.input.r.SampleFormat()
+}
+
+func (g *gate) Read(s sdr.Samples) (int, error) {
+	g.input.lock.Lock()
+	defer g.input.lock.Unlock()
+	if g.detached {
+		return 0, ErrStageDetached
+	}
+	return g.input.r.Read(s)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,378 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package stream
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"hz.tools/sdr"
+)
+
+// TeePolicy controls what a Tee does when one of its branches isn't being
+// read as fast as the source is.
+type TeePolicy int
+
+const (
+	// TeeBlock will stop reading from the source until the branch catches
+	// up, which holds up every other branch too. Nothing is lost, which
+	// is what a recorder wants.
+	TeeBlock TeePolicy = iota
+
+	// TeeDrop will drop the samples for that branch (and only that branch)
+	// until it catches up, which is what a display wants.
+	TeeDrop
+)
+
+// TeeBranch configures one of the Readers returned by TeeWithOptions.
+type TeeBranch struct {
+	// Policy is what to do when the branch's buffers are full.
+	Policy TeePolicy
+
+	// Buffers is the number of reads from the source which are buffered
+	// for this branch before the Policy kicks in. If 0, this will default
+	// to 32.
+	Buffers int
+}
+
+func (b TeeBranch) getBuffers() int {
+	if b.Buffers == 0 {
+		return 32
+	}
+	return b.Buffers
+}
+
+// TeeOptions control the behavior of TeeWithOptions.
+type TeeOptions struct {
+	// Branches configures each Reader to be returned, in order.
+	Branches []TeeBranch
+
+	// BufferLength is the number of samples read from the source at a time.
+	// If 0, this will default to 16384.
+	BufferLength int
+}
+
+func (opts TeeOptions) getBufferLength() int {
+	if opts.BufferLength == 0 {
+		return 16384
+	}
+	return opts.BufferLength
+}
+
+type teeBranch struct {
+	// dropped is first, so it's 64 bit aligned for sync/atomic.
+	dropped int64
+
+	policy TeePolicy
+	buf    chan sdr.Samples
+	done   chan struct{}
+	err    error
+
+	pipeWriter sdr.PipeWriter
+}
+
+func (b *teeBranch) do() {
+	defer close(b.done)
+	for s := range b.buf {
+		if _, err := b.pipeWriter.Write(s); err != nil {
+			// The Reader was closed, there's nobody left to write to.
+			return
+		}
+	}
+	b.pipeWriter.CloseWithError(b.err)
+}
+
+// send will hand the samples to the branch, following its policy, and
+// return false if the branch's Reader has been closed. A blocked send will
+// give up if stop is closed.
+func (b *teeBranch) send(s sdr.Samples, stop chan struct{}) bool {
+	if b.policy == TeeDrop {
+		select {
+		case <-b.done:
+			return false
+		case b.buf <- s:
+		default:
+			atomic.AddInt64(&b.dropped, int64(s.Length()))
+		}
+		return true
+	}
+
+	select {
+	case <-b.done:
+		return false
+	case <-stop:
+		return true
+	case b.buf <- s:
+		return true
+	}
+}
+
+// TeeReader is one branch of a Tee or a Splitter.
+type TeeReader struct {
+	sdr.PipeReader
+
+	branch *teeBranch
+}
+
+// Dropped returns the number of samples that were dropped because this
+// branch wasn't being read quickly enough. This is always 0 for a branch
+// with the TeeBlock Policy.
+func (tr *TeeReader) Dropped() int64 {
+	return atomic.LoadInt64(&tr.branch.dropped)
+}
+
+type tee struct {
+	reader       sdr.Reader
+	bufferLength int
+
+	// keepReading will keep the source being read even when there are no
+	// branches left, since a Splitter may have more added later.
+	keepReading bool
+
+	lock     sync.Mutex
+	branches []*teeBranch
+	closed   bool
+	err      error
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	finished chan struct{}
+}
+
+func newTee(r sdr.Reader, bufferLength int, keepReading bool) *tee {
+	return &tee{
+		reader:       r,
+		bufferLength: bufferLength,
+		keepReading:  keepReading,
+		stop:         make(chan struct{}),
+		finished:     make(chan struct{}),
+	}
+}
+
+func (t *tee) add(branch TeeBranch) (*TeeReader, error) {
+	select {
+	case <-t.stop:
+		return nil, sdr.ErrPipeClosed
+	default:
+	}
+
+	pipeReader, pipeWriter := sdr.Pipe(t.reader.SampleRate(), t.reader.SampleFormat())
+	b := &teeBranch{
+		policy:     branch.Policy,
+		buf:        make(chan sdr.Samples, branch.getBuffers()),
+		done:       make(chan struct{}),
+		pipeWriter: pipeWriter,
+	}
+
+	t.lock.Lock()
+	if t.closed {
+		err := t.err
+		t.lock.Unlock()
+		return nil, err
+	}
+	t.branches = append(t.branches, b)
+	t.lock.Unlock()
+
+	go b.do()
+	return &TeeReader{PipeReader: pipeReader, branch: b}, nil
+}
+
+func (t *tee) close() {
+	t.stopOnce.Do(func() { close(t.stop) })
+}
+
+func (t *tee) run() {
+	err := t.do()
+
+	t.lock.Lock()
+	t.closed = true
+	t.err = err
+	for _, b := range t.branches {
+		b.err = err
+		close(b.buf)
+	}
+	t.branches = nil
+	t.lock.Unlock()
+
+	close(t.finished)
+}
+
+func (t *tee) do() error {
+	for {
+		select {
+		case <-t.stop:
+			return sdr.ErrPipeClosed
+		default:
+		}
+
+		// Every branch holds on to the buffer until its Reader has copied
+		// it out, so it can't be reused.
+		buf, err := sdr.MakeSamples(t.reader.SampleFormat(), t.bufferLength)
+		if err != nil {
+			return err
+		}
+
+		n, err := t.reader.Read(buf)
+		if n > 0 {
+			buf = buf.Slice(0, n)
+
+			t.lock.Lock()
+			branches := append([]*teeBranch{}, t.branches...)
+			t.lock.Unlock()
+
+			var gone []*teeBranch
+			for _, b := range branches {
+				if !b.send(buf, t.stop) {
+					gone = append(gone, b)
+				}
+			}
+
+			if len(gone) > 0 {
+				t.lock.Lock()
+				t.branches = removeTeeBranches(t.branches, gone)
+				live := len(t.branches)
+				t.lock.Unlock()
+
+				if live == 0 && !t.keepReading {
+					return sdr.ErrPipeClosed
+				}
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func removeTeeBranches(branches, gone []*teeBranch) []*teeBranch {
+	ret := branches[:0]
+	for _, b := range branches {
+		keep := true
+		for _, g := range gone {
+			if b == g {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			ret = append(ret, b)
+		}
+	}
+	return ret
+}
+
+// Tee will read from the provided Reader, and return n Readers, each of
+// which gets a copy of every sample. This allows one rx stream to feed a
+// recorder, a demodulator and a display at the same time. Every branch
+// will block the source if it falls behind; see TeeWithOptions to drop
+// samples instead.
+//
+// Any error from the source (such as io.EOF) will be returned from every
+// branch, once each has read what was buffered before it. Closing a
+// branch will drop it; once all the branches are closed, the source will
+// no longer be read from.
+//
+// Each returned Reader is a *TeeReader.
+func Tee(r sdr.Reader, n int) ([]sdr.ReadCloser, error) {
+	return TeeWithOptions(r, TeeOptions{
+		Branches: make([]TeeBranch, n),
+	})
+}
+
+// TeeWithOptions is Tee, but with a TeeBranch for each Reader to be
+// returned, to control how slow consumers are handled.
+func TeeWithOptions(r sdr.Reader, opts TeeOptions) ([]sdr.ReadCloser, error) {
+	if len(opts.Branches) == 0 {
+		return nil, fmt.Errorf("stream.Tee: no branches requested")
+	}
+
+	var (
+		t   = newTee(r, opts.getBufferLength(), false)
+		ret = make([]sdr.ReadCloser, len(opts.Branches))
+	)
+
+	for i, branch := range opts.Branches {
+		reader, err := t.add(branch)
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = reader
+	}
+
+	go t.run()
+	return ret, nil
+}
+
+// Splitter is a Tee which branches can be added to while it's running, for
+// when consumers come and go, such as network clients of one radio. Unlike
+// a Tee, a Splitter will keep reading from the source when it has no
+// branches, until it's Closed.
+type Splitter struct {
+	t *tee
+}
+
+// NewSplitter will start reading from the provided Reader, `bufferLength`
+// samples at a time, handing a copy of each read to every branch. If
+// bufferLength is 0, this will default to 16384.
+//
+// The Reader is not closed by the Splitter.
+func NewSplitter(r sdr.Reader, bufferLength int) *Splitter {
+	t := newTee(r, TeeOptions{BufferLength: bufferLength}.getBufferLength(), true)
+	go t.run()
+	return &Splitter{t: t}
+}
+
+// Branch will add a new branch to the Splitter, which will get a copy of
+// every sample read from the source from now on. Closing the returned
+// Reader removes the branch.
+//
+// Once the Splitter has stopped, this will return the error it stopped
+// with, or sdr.ErrPipeClosed if it was Closed.
+func (s *Splitter) Branch(branch TeeBranch) (*TeeReader, error) {
+	return s.t.add(branch)
+}
+
+// Done returns a channel which is closed once the Splitter has stopped
+// reading from the source, and every branch has been handed the error.
+func (s *Splitter) Done() <-chan struct{} {
+	return s.t.finished
+}
+
+// Err returns the error the Splitter stopped with, which is the error from
+// the source, or sdr.ErrPipeClosed if it was Closed. This is nil until the
+// Splitter is Done.
+func (s *Splitter) Err() error {
+	s.t.lock.Lock()
+	defer s.t.lock.Unlock()
+	return s.t.err
+}
+
+// Close will stop the Splitter once the Read in progress returns, and
+// every branch will return sdr.ErrPipeClosed once it has read what was
+// buffered for it. The source Reader is not closed, so if it may block
+// for a long time, Close it too.
+func (s *Splitter) Close() error {
+	s.t.close()
+	return nil
+}
+
+// vim: foldmethod=marker
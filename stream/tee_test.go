@@ -0,0 +1,183 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package stream_test
+
+import (
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/stream"
+)
+
+// teeSource will return a Reader which counts up from 0 for n samples,
+// and then returns io.EOF.
+func teeSource(n int) sdr.Reader {
+	pipeReader, pipeWriter := sdr.Pipe(1024, sdr.SampleFormatC64)
+	go func() {
+		buf := make(sdr.SamplesC64, 128)
+		for i := 0; i < n; i += len(buf) {
+			for j := range buf {
+				buf[j] = complex(float32(i+j), 0)
+			}
+			if _, err := pipeWriter.Write(buf); err != nil {
+				return
+			}
+		}
+		pipeWriter.CloseWithError(io.EOF)
+	}()
+	return pipeReader
+}
+
+func readAll(r sdr.Reader) (sdr.SamplesC64, error) {
+	var (
+		ret sdr.SamplesC64
+		buf = make(sdr.SamplesC64, 100)
+	)
+	for {
+		n, err := r.Read(buf)
+		ret = append(ret, buf[:n]...)
+		if err != nil {
+			return ret, err
+		}
+	}
+}
+
+func TestTee(t *testing.T) {
+	branches, err := stream.Tee(teeSource(1024*16), 3)
+	assert.NoError(t, err)
+	assert.Len(t, branches, 3)
+
+	wg := sync.WaitGroup{}
+	wg.Add(len(branches))
+	for _, branch := range branches {
+		go func(branch sdr.Reader) {
+			defer wg.Done()
+			samples, err := readAll(branch)
+			assert.Equal(t, io.EOF, err)
+			assert.Len(t, samples, 1024*16)
+			for i, s := range samples {
+				if s != complex(float32(i), 0) {
+					assert.Equal(t, complex(float32(i), 0), s)
+					return
+				}
+			}
+		}(branch)
+	}
+	wg.Wait()
+}
+
+func TestTeeDrop(t *testing.T) {
+	branches, err := stream.TeeWithOptions(teeSource(1024*64), stream.TeeOptions{
+		BufferLength: 1024,
+		Branches: []stream.TeeBranch{
+			{Policy: stream.TeeBlock},
+			{Policy: stream.TeeDrop, Buffers: 1},
+		},
+	})
+	assert.NoError(t, err)
+
+	// The dropping branch isn't read until the blocking branch is done,
+	// which would deadlock if it held up the source.
+	samples, err := readAll(branches[0])
+	assert.Equal(t, io.EOF, err)
+	assert.Len(t, samples, 1024*64)
+
+	samples, err = readAll(branches[1])
+	assert.Equal(t, io.EOF, err)
+	assert.True(t, len(samples) < 1024*64, "nothing was dropped")
+	assert.Equal(t, int64(1024*64-len(samples)), branches[1].(*stream.TeeReader).Dropped())
+}
+
+func TestTeeClose(t *testing.T) {
+	branches, err := stream.Tee(teeSource(1024*64), 2)
+	assert.NoError(t, err)
+
+	assert.NoError(t, branches[1].Close())
+
+	samples, err := readAll(branches[0])
+	assert.Equal(t, io.EOF, err)
+	assert.Len(t, samples, 1024*64)
+}
+
+func TestSplitter(t *testing.T) {
+	pipeReader, pipeWriter := sdr.Pipe(1024, sdr.SampleFormatC64)
+	splitter := stream.NewSplitter(pipeReader, 128)
+
+	// With no branches, the source is still read (and discarded).
+	_, err := pipeWriter.Write(make(sdr.SamplesC64, 1024))
+	assert.NoError(t, err)
+
+	first, err := splitter.Branch(stream.TeeBranch{})
+	assert.NoError(t, err)
+	second, err := splitter.Branch(stream.TeeBranch{})
+	assert.NoError(t, err)
+
+	go pipeWriter.Write(make(sdr.SamplesC64, 1024))
+	for _, branch := range []sdr.Reader{first, second} {
+		_, err := sdr.ReadFull(branch, make(sdr.SamplesC64, 1024))
+		assert.NoError(t, err)
+	}
+
+	// A closed branch is dropped, and the rest carry on.
+	assert.NoError(t, first.Close())
+	go pipeWriter.Write(make(sdr.SamplesC64, 1024))
+	_, err = sdr.ReadFull(second, make(sdr.SamplesC64, 1024))
+	assert.NoError(t, err)
+
+	pipeWriter.CloseWithError(io.EOF)
+	<-splitter.Done()
+	assert.Equal(t, io.EOF, splitter.Err())
+	_, err = readAll(second)
+	assert.Equal(t, io.EOF, err)
+
+	_, err = splitter.Branch(stream.TeeBranch{})
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestSplitterClose(t *testing.T) {
+	pipeReader, pipeWriter := sdr.Pipe(1024, sdr.SampleFormatC64)
+	defer pipeWriter.Close()
+	splitter := stream.NewSplitter(pipeReader, 128)
+
+	branch, err := splitter.Branch(stream.TeeBranch{})
+	assert.NoError(t, err)
+	assert.NoError(t, splitter.Close())
+
+	_, err = splitter.Branch(stream.TeeBranch{})
+	assert.Equal(t, sdr.ErrPipeClosed, err)
+
+	// The source is mid-Read, which is only unblocked by closing it.
+	pipeReader.Close()
+	<-splitter.Done()
+	_, err = branch.Read(make(sdr.SamplesC64, 128))
+	assert.Equal(t, sdr.ErrPipeClosed, err)
+}
+
+func TestTeeNoBranches(t *testing.T) {
+	_, err := stream.Tee(teeSource(0), 0)
+	assert.Error(t, err)
+}
+
+// vim: foldmethod=marker
@@ -21,11 +21,49 @@
 package stream
 
 import (
+	"fmt"
 	"time"
 
 	"hz.tools/sdr"
 )
 
+type throttleReader struct {
+	r sdr.Reader
+
+	// rate is the number of samples to hand out for every second of wall
+	// clock time.
+	rate    float64
+	start   time.Time
+	samples int64
+}
+
+func (tr *throttleReader) SampleFormat() sdr.SampleFormat {
+	return tr.r.SampleFormat()
+}
+
+func (tr *throttleReader) SampleRate() uint {
+	return tr.r.SampleRate()
+}
+
+func (tr *throttleReader) Read(s sdr.Samples) (int, error) {
+	if tr.start.IsZero() {
+		tr.start = time.Now()
+	}
+
+	n, err := tr.r.Read(s)
+	tr.samples += int64(n)
+
+	// Hold on to the samples until the time they would have finished
+	// arriving from a live radio. This is worked out from when the first
+	// Read was made, rather than the last, so that time spent by the caller
+	// between Reads doesn't slowly add up to a drift.
+	due := tr.start.Add(time.Duration(float64(tr.samples) / tr.rate * float64(time.Second)))
+	if wait := time.Until(due); wait > 0 {
+		time.Sleep(wait)
+	}
+	return n, err
+}
+
 // Throttle will read the sdr.Reader's SampleRate, and throttle
 // the stream to play the Reader back "real time".
 func Throttle(r sdr.Reader) (sdr.Reader, error) {
@@ -33,34 +71,23 @@ func Throttle(r sdr.Reader) (sdr.Reader, error) {
 }
 
 // ThrottleSecondsPerSecond will read the sdr.Reader's SampleRate, and throttle
-// the stream to play the Reader back where the duration 'd' passes every
-// second.
+// the stream to play the Reader back where one second of samples takes the
+// duration 'd' of wall clock time. A 'd' of 4 seconds will play the Reader
+// back 4 times slower than real time, and 250 milliseconds 4 times faster.
+//
+// Any error from the Reader (such as an io.EOF at the end of a file) will
+// be returned as soon as it's hit.
 func ThrottleSecondsPerSecond(r sdr.Reader, d time.Duration) (sdr.Reader, error) {
-	// Let's search for an easy window to size to use.
-	var windowRate uint = 20
-	for ; r.SampleRate()%windowRate == 0; windowRate++ {
+	if r.SampleRate() == 0 {
+		return nil, fmt.Errorf("stream.Throttle: reader has no sample rate")
 	}
-	pipeReader, pipeWriter := sdr.Pipe(r.SampleRate(), r.SampleFormat())
-	buf, err := sdr.MakeSamples(r.SampleFormat(), int(r.SampleRate()/windowRate))
-	if err != nil {
-		return nil, err
+	if d <= 0 {
+		return nil, fmt.Errorf("stream.Throttle: duration must be positive")
 	}
-	go func() {
-		defer pipeWriter.Close()
-		clock := time.NewTicker(d / time.Duration(windowRate))
-		defer clock.Stop()
-		for {
-			_, err := sdr.ReadFull(r, buf)
-			if err != nil {
-				return
-			}
-			select {
-			case <-clock.C:
-				pipeWriter.Write(buf)
-			}
-		}
-	}()
-	return pipeReader, nil
+	return &throttleReader{
+		r:    r,
+		rate: float64(r.SampleRate()) / d.Seconds(),
+	}, nil
 }
 
 // vim: foldmethod=marker
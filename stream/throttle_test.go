@@ -0,0 +1,77 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package stream_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/stream"
+)
+
+func TestThrottle(t *testing.T) {
+	r, err := stream.Throttle(stream.Noise(stream.NoiseConfig{SampleRate: 10000}))
+	assert.NoError(t, err)
+	assert.Equal(t, uint(10000), r.SampleRate())
+
+	var (
+		buf   = make(sdr.SamplesC64, 500)
+		start = time.Now()
+	)
+	for i := 0; i < 4; i++ {
+		_, err := sdr.ReadFull(r, buf)
+		assert.NoError(t, err)
+	}
+	// 2000 samples at 10000 samples per second.
+	elapsed := time.Since(start)
+	assert.True(t, elapsed >= 200*time.Millisecond, "too fast: %s", elapsed)
+	assert.True(t, elapsed < 400*time.Millisecond, "too slow: %s", elapsed)
+}
+
+func TestThrottleSecondsPerSecond(t *testing.T) {
+	r, err := stream.ThrottleSecondsPerSecond(
+		stream.Noise(stream.NoiseConfig{SampleRate: 10000}),
+		time.Second*4,
+	)
+	assert.NoError(t, err)
+
+	var (
+		buf   = make(sdr.SamplesC64, 500)
+		start = time.Now()
+	)
+	_, err = sdr.ReadFull(r, buf)
+	assert.NoError(t, err)
+
+	// 500 samples is 50ms, played back 4 times slower.
+	elapsed := time.Since(start)
+	assert.True(t, elapsed >= 200*time.Millisecond, "too fast: %s", elapsed)
+	assert.True(t, elapsed < 400*time.Millisecond, "too slow: %s", elapsed)
+}
+
+func TestThrottleNoSampleRate(t *testing.T) {
+	_, err := stream.Throttle(stream.Noise(stream.NoiseConfig{}))
+	assert.Error(t, err)
+}
+
+// vim: foldmethod=marker
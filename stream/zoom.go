@@ -0,0 +1,113 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package stream
+
+import (
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/fft"
+)
+
+// Zoom will perform a "zoom FFT" over a narrow slice of an sdr.Reader's
+// band. Rather than doing a massive FFT of the full input in order to get
+// fine frequency resolution, the slice of interest is shifted to DC, and
+// filtered and decimated (see Decimate) before doing a small FFT at the
+// reduced sample rate. This is very handy to inspect narrow signals such as
+// CW or SSB in detail.
+type Zoom struct {
+	r         sdr.Reader
+	iq        sdr.SamplesC64
+	frequency []complex64
+	plan      fft.Plan
+}
+
+// NewZoom will create a new Zoom over the provided Reader, centered on the
+// `center` offset frequency of the Reader, producing FFTs of `length` bins
+// spanning the Reader's sample rate divided by `factor`.
+func NewZoom(
+	r sdr.Reader,
+	planner fft.Planner,
+	center rf.Hz,
+	factor uint,
+	length int,
+) (*Zoom, error) {
+	switch r.SampleFormat() {
+	case sdr.SampleFormatC64:
+		break
+	default:
+		return nil, sdr.ErrSampleFormatUnknown
+	}
+
+	shifted, err := ShiftReader(r, -center)
+	if err != nil {
+		return nil, err
+	}
+
+	decimated, err := Decimate(shifted, factor)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		iq        = make(sdr.SamplesC64, length)
+		frequency = make([]complex64, length)
+	)
+
+	plan, err := planner(iq, frequency, fft.Forward)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Zoom{
+		r:         decimated,
+		iq:        iq,
+		frequency: frequency,
+		plan:      plan,
+	}, nil
+}
+
+// SampleRate is the sample rate the FFT is performed at, after decimation.
+// This is also the span of frequencies covered by the FrequencySlice.
+func (z *Zoom) SampleRate() uint {
+	return z.r.SampleRate()
+}
+
+// Next will read enough samples from the underlying Reader to perform the
+// next FFT, and return the result. Frequencies in the returned FrequencySlice
+// are relative to the center frequency passed to NewZoom.
+//
+// The returned FrequencySlice is only valid until the next call to Next.
+func (z *Zoom) Next() (fft.FrequencySlice, error) {
+	if _, err := sdr.ReadFull(z.r, z.iq); err != nil {
+		return fft.FrequencySlice{}, err
+	}
+	if err := z.plan.Transform(); err != nil {
+		return fft.FrequencySlice{}, err
+	}
+	return fft.NewFrequencySlice(z.frequency, z.r.SampleRate(), fft.ZeroFirst), nil
+}
+
+// Close will release the FFT plan. This will not close the underlying Reader.
+func (z *Zoom) Close() error {
+	return z.plan.Close()
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,105 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package stream_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/stream"
+	"hz.tools/sdr/testutils"
+)
+
+func TestZoom(t *testing.T) {
+	const sampleRate = 1024000
+
+	cw := make(sdr.SamplesC64, 64*1024)
+	testutils.CW(cw, rf.Hz(101000), sampleRate, 0)
+
+	pipeReader, pipeWriter := sdr.Pipe(sampleRate, sdr.SampleFormatC64)
+	go pipeWriter.Write(cw)
+	defer pipeReader.Close()
+
+	zoom, err := stream.NewZoom(pipeReader, testutils.DFTPlanner, rf.Hz(100000), 64, 256)
+	assert.NoError(t, err)
+	defer zoom.Close()
+	assert.Equal(t, uint(16000), zoom.SampleRate())
+
+	// Skip the first FFT, which includes the decimation filter settling.
+	_, err = zoom.Next()
+	assert.NoError(t, err)
+	slice, err := zoom.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, rf.Hz(62.5), slice.BinBandwidth())
+
+	peak, peakPower := -1, float32(0)
+	power := make([]float32, len(slice.Frequency))
+	assert.NoError(t, slice.Power(power))
+	for i, p := range power {
+		if p > peakPower {
+			peak, peakPower = i, p
+		}
+	}
+
+	freq, err := slice.FreqByBin(peak)
+	assert.NoError(t, err)
+	assert.Equal(t, rf.Hz(1000), freq)
+	assert.InDelta(t, 1, peakPower, 0.1)
+}
+
+func TestZoomAlias(t *testing.T) {
+	const sampleRate = 1024000
+
+	// 12 kHz above the center is outside of the 16 kHz wide zoomed slice,
+	// and would alias to -4 kHz if not filtered out before decimating.
+	cw := make(sdr.SamplesC64, 64*1024)
+	testutils.CW(cw, rf.Hz(112000), sampleRate, 0)
+
+	pipeReader, pipeWriter := sdr.Pipe(sampleRate, sdr.SampleFormatC64)
+	go pipeWriter.Write(cw)
+	defer pipeReader.Close()
+
+	zoom, err := stream.NewZoom(pipeReader, testutils.DFTPlanner, rf.Hz(100000), 64, 256)
+	assert.NoError(t, err)
+	defer zoom.Close()
+
+	_, err = zoom.Next()
+	assert.NoError(t, err)
+	slice, err := zoom.Next()
+	assert.NoError(t, err)
+
+	power := make([]float32, len(slice.Frequency))
+	assert.NoError(t, slice.Power(power))
+	for _, p := range power {
+		assert.Less(t, p, float32(0.01))
+	}
+}
+
+func TestZoomFormat(t *testing.T) {
+	pipeReader, _ := sdr.Pipe(1024000, sdr.SampleFormatU8)
+	_, err := stream.NewZoom(pipeReader, testutils.DFTPlanner, rf.Hz(0), 64, 256)
+	assert.Equal(t, sdr.ErrSampleFormatUnknown, err)
+}
+
+// vim: foldmethod=marker
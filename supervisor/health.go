@@ -0,0 +1,147 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package supervisor
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// State is the current state of a Pipeline.
+type State string
+
+const (
+	// StatePending means the Supervisor has not been started.
+	StatePending State = "pending"
+
+	// StateRunning means the Pipeline is currently running.
+	StateRunning State = "running"
+
+	// StateBackoff means the Pipeline has returned, and is waiting to be
+	// restarted.
+	StateBackoff State = "backoff"
+
+	// StateStopped means the Pipeline has returned, and will not be
+	// restarted, but didn't fail.
+	StateStopped State = "stopped"
+
+	// StateFailed means the Pipeline has failed, and will not be
+	// restarted.
+	StateFailed State = "failed"
+)
+
+// Status is the health of a single Pipeline.
+type Status struct {
+	// Name of the Pipeline.
+	Name string
+
+	// State the Pipeline is in.
+	State State
+
+	// Since is when the Pipeline entered the current State.
+	Since time.Time
+
+	// Restarts is the number of times the Pipeline has been restarted.
+	Restarts int
+
+	// LastError is the error the Pipeline last returned with, if any.
+	LastError error
+
+	// LastErrorTime is when LastError happened.
+	LastErrorTime time.Time
+}
+
+// Healthy returns true if the Pipeline is running, or has stopped cleanly.
+func (s Status) Healthy() bool {
+	switch s.State {
+	case StateRunning, StateStopped:
+		return true
+	default:
+		return false
+	}
+}
+
+// MarshalJSON will encode the Status as JSON, with the LastError as its
+// string.
+func (s Status) MarshalJSON() ([]byte, error) {
+	var lastError string
+	if s.LastError != nil {
+		lastError = s.LastError.Error()
+	}
+	return json.Marshal(struct {
+		Name          string    `json:"name"`
+		State         State     `json:"state"`
+		Healthy       bool      `json:"healthy"`
+		Since         time.Time `json:"since"`
+		Restarts      int       `json:"restarts"`
+		LastError     string    `json:"last_error,omitempty"`
+		LastErrorTime time.Time `json:"last_error_time,omitempty"`
+	}{
+		Name:          s.Name,
+		State:         s.State,
+		Healthy:       s.Healthy(),
+		Since:         s.Since,
+		Restarts:      s.Restarts,
+		LastError:     lastError,
+		LastErrorTime: s.LastErrorTime,
+	})
+}
+
+// Health is the aggregate health of every Pipeline.
+type Health struct {
+	// Healthy is true if every Pipeline is Healthy.
+	Healthy bool `json:"healthy"`
+
+	// Pipelines holds the Status of every Pipeline, in the order they
+	// were added.
+	Pipelines []Status `json:"pipelines"`
+}
+
+// Health will return a snapshot of the health of every Pipeline.
+func (s *Supervisor) Health() Health {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	h := Health{Healthy: true}
+	for _, p := range s.pipelines {
+		st := *s.status[p.Name]
+		if !st.Healthy() {
+			h.Healthy = false
+		}
+		h.Pipelines = append(h.Pipelines, st)
+	}
+	return h
+}
+
+// ServeHTTP will write the Health of the Supervisor as JSON, with a 503
+// status code if anything is unhealthy, so this can be pointed at directly
+// by a monitoring system.
+func (s *Supervisor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h := s.Health()
+	w.Header().Set("Content-Type", "application/json")
+	if !h.Healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(h)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,247 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"hz.tools/sdr"
+)
+
+var (
+	// ErrDuplicatePipeline will be returned if a Pipeline is added with the
+	// same name as one already added.
+	ErrDuplicatePipeline = fmt.Errorf("supervisor: a pipeline with that name already exists")
+
+	// ErrRunning will be returned if a Pipeline is added after the
+	// Supervisor has been started.
+	ErrRunning = fmt.Errorf("supervisor: supervisor is already running")
+)
+
+// Restart controls when a Pipeline is restarted after it returns.
+type Restart uint8
+
+const (
+	// RestartOnFailure will restart the Pipeline if it returns an error
+	// (or panics), but not if it returns nil.
+	RestartOnFailure Restart = iota
+
+	// RestartAlways will restart the Pipeline whenever it returns.
+	RestartAlways
+
+	// RestartNever will never restart the Pipeline.
+	RestartNever
+)
+
+// Pipeline is a long running task managed by the Supervisor.
+type Pipeline struct {
+	// Name uniquely identifies the Pipeline.
+	Name string
+
+	// Run is the body of the Pipeline. It must return promptly once the
+	// context is canceled. A panic is recovered, and treated as an
+	// sdr.ErrDriverPanic error.
+	Run func(context.Context) error
+
+	// Restart is the restart policy of the Pipeline.
+	Restart Restart
+
+	// MaxRestarts is the number of times the Pipeline will be restarted
+	// before being marked as Failed. Zero means no limit.
+	MaxRestarts int
+
+	// MinBackoff is the time to wait before the first restart. Each
+	// consecutive restart doubles the wait, up to MaxBackoff. If unset,
+	// this defaults to one second.
+	MinBackoff time.Duration
+
+	// MaxBackoff is the longest time to wait before a restart. If unset,
+	// this defaults to one minute.
+	MaxBackoff time.Duration
+
+	// ResetAfter is how long the Pipeline must run for before the backoff
+	// is reset back to MinBackoff. If unset, this defaults to MaxBackoff.
+	ResetAfter time.Duration
+}
+
+func (p Pipeline) getMinBackoff() time.Duration {
+	if p.MinBackoff == 0 {
+		return time.Second
+	}
+	return p.MinBackoff
+}
+
+func (p Pipeline) getMaxBackoff() time.Duration {
+	if p.MaxBackoff == 0 {
+		return time.Minute
+	}
+	return p.MaxBackoff
+}
+
+func (p Pipeline) getResetAfter() time.Duration {
+	if p.ResetAfter == 0 {
+		return p.getMaxBackoff()
+	}
+	return p.ResetAfter
+}
+
+// Supervisor runs a set of Pipelines.
+type Supervisor struct {
+	lock      *sync.Mutex
+	running   bool
+	pipelines []Pipeline
+	status    map[string]*Status
+}
+
+// New will create a new Supervisor with no Pipelines.
+func New() *Supervisor {
+	return &Supervisor{
+		lock:   &sync.Mutex{},
+		status: map[string]*Status{},
+	}
+}
+
+// Add will add a Pipeline to the Supervisor. All Pipelines must be added
+// before calling Run.
+func (s *Supervisor) Add(p Pipeline) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.running {
+		return ErrRunning
+	}
+	if _, ok := s.status[p.Name]; ok {
+		return ErrDuplicatePipeline
+	}
+	s.pipelines = append(s.pipelines, p)
+	s.status[p.Name] = &Status{Name: p.Name, State: StatePending}
+	return nil
+}
+
+// Run will start every Pipeline, and supervise them until the context is
+// canceled, or every Pipeline has stopped for good. Run returns once all
+// Pipelines have returned.
+func (s *Supervisor) Run(ctx context.Context) error {
+	s.lock.Lock()
+	if s.running {
+		s.lock.Unlock()
+		return ErrRunning
+	}
+	s.running = true
+	pipelines := s.pipelines
+	s.lock.Unlock()
+
+	wg := sync.WaitGroup{}
+	for _, p := range pipelines {
+		wg.Add(1)
+		go func(p Pipeline) {
+			defer wg.Done()
+			s.supervise(ctx, p)
+		}(p)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (s *Supervisor) update(name string, fn func(*Status)) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	fn(s.status[name])
+}
+
+func (s *Supervisor) supervise(ctx context.Context, p Pipeline) {
+	var (
+		backoff  = p.getMinBackoff()
+		restarts = 0
+	)
+
+	for {
+		start := time.Now()
+		s.update(p.Name, func(st *Status) {
+			st.State = StateRunning
+			st.Since = start
+		})
+
+		err := run(ctx, p.Run)
+
+		now := time.Now()
+		if now.Sub(start) >= p.getResetAfter() {
+			backoff = p.getMinBackoff()
+		}
+
+		s.update(p.Name, func(st *Status) {
+			st.Since = now
+			st.LastError = err
+			if err != nil {
+				st.LastErrorTime = now
+			}
+		})
+
+		if ctx.Err() != nil {
+			s.update(p.Name, func(st *Status) { st.State = StateStopped })
+			return
+		}
+
+		switch {
+		case p.Restart == RestartNever,
+			p.Restart == RestartOnFailure && err == nil:
+			state := StateStopped
+			if err != nil {
+				state = StateFailed
+			}
+			s.update(p.Name, func(st *Status) { st.State = state })
+			return
+		case p.MaxRestarts > 0 && restarts >= p.MaxRestarts:
+			s.update(p.Name, func(st *Status) { st.State = StateFailed })
+			return
+		}
+
+		s.update(p.Name, func(st *Status) { st.State = StateBackoff })
+		select {
+		case <-ctx.Done():
+			s.update(p.Name, func(st *Status) { st.State = StateStopped })
+			return
+		case <-time.After(backoff):
+		}
+
+		restarts++
+		s.update(p.Name, func(st *Status) { st.Restarts = restarts })
+
+		backoff *= 2
+		if backoff > p.getMaxBackoff() {
+			backoff = p.getMaxBackoff()
+		}
+	}
+}
+
+func run(ctx context.Context, fn func(context.Context) error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = sdr.DriverPanic(v)
+		}
+	}()
+	return fn(ctx)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,130 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package supervisor_test
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/supervisor"
+)
+
+func waitFor(t *testing.T, s *supervisor.Supervisor, fn func(supervisor.Health) bool) supervisor.Health {
+	deadline := time.Now().Add(time.Second * 5)
+	for time.Now().Before(deadline) {
+		h := s.Health()
+		if fn(h) {
+			return h
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for health: %#v", s.Health())
+	return supervisor.Health{}
+}
+
+func TestRestart(t *testing.T) {
+	var calls int32
+	s := supervisor.New()
+	assert.NoError(t, s.Add(supervisor.Pipeline{
+		Name:       "flaky",
+		MinBackoff: time.Millisecond,
+		Run: func(ctx context.Context) error {
+			if atomic.AddInt32(&calls, 1) <= 2 {
+				return fmt.Errorf("oops")
+			}
+			<-ctx.Done()
+			return nil
+		},
+	}))
+	assert.Equal(t, supervisor.ErrDuplicatePipeline, s.Add(supervisor.Pipeline{Name: "flaky"}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+
+	h := waitFor(t, s, func(h supervisor.Health) bool {
+		return h.Pipelines[0].Restarts == 2 && h.Pipelines[0].State == supervisor.StateRunning
+	})
+	assert.True(t, h.Healthy)
+	assert.EqualError(t, h.Pipelines[0].LastError, "oops")
+
+	assert.Equal(t, supervisor.ErrRunning, s.Add(supervisor.Pipeline{Name: "late"}))
+
+	cancel()
+	assert.NoError(t, <-done)
+	assert.Equal(t, supervisor.StateStopped, s.Health().Pipelines[0].State)
+}
+
+func TestFailures(t *testing.T) {
+	s := supervisor.New()
+	assert.NoError(t, s.Add(supervisor.Pipeline{
+		Name:    "never",
+		Restart: supervisor.RestartNever,
+		Run: func(ctx context.Context) error {
+			return fmt.Errorf("nope")
+		},
+	}))
+	assert.NoError(t, s.Add(supervisor.Pipeline{
+		Name:        "limited",
+		MaxRestarts: 2,
+		MinBackoff:  time.Millisecond,
+		Run: func(ctx context.Context) error {
+			panic("boom")
+		},
+	}))
+	assert.NoError(t, s.Add(supervisor.Pipeline{
+		Name: "done",
+		Run: func(ctx context.Context) error {
+			return nil
+		},
+	}))
+
+	// Every pipeline stops for good, so Run returns on its own.
+	assert.NoError(t, s.Run(context.Background()))
+
+	h := s.Health()
+	assert.False(t, h.Healthy)
+
+	assert.Equal(t, supervisor.StateFailed, h.Pipelines[0].State)
+	assert.Equal(t, 0, h.Pipelines[0].Restarts)
+
+	assert.Equal(t, supervisor.StateFailed, h.Pipelines[1].State)
+	assert.Equal(t, 2, h.Pipelines[1].Restarts)
+	_, ok := h.Pipelines[1].LastError.(sdr.ErrDriverPanic)
+	assert.True(t, ok)
+
+	assert.Equal(t, supervisor.StateStopped, h.Pipelines[2].State)
+	assert.True(t, h.Pipelines[2].Healthy())
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/health", nil))
+	assert.Equal(t, 503, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"last_error":"nope"`)
+}
+
+// vim: foldmethod=marker
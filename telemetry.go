@@ -0,0 +1,60 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sdr
+
+// TelemetryReadings are readings of the health of the SDR hardware, such as
+// temperatures, voltages and the lock state of clocks. Each map is keyed by
+// a driver specific sensor name; anything the hardware doesn't report is
+// left out.
+type TelemetryReadings struct {
+	// Temperatures are in degrees Celsius.
+	Temperatures map[string]float64
+
+	// Voltages are in Volts.
+	Voltages map[string]float64
+
+	// Locked is true if the named clock or PLL (such as the reference
+	// input, or a GPSDO) is locked.
+	Locked map[string]bool
+}
+
+// Telemetry is an "extension" of the SDR Interface, for SDRs which are
+// able to report on the health of the hardware. This is mostly of
+// interest to long-running unattended stations, which may be sitting
+// outside in the sun.
+type Telemetry interface {
+	Sdr
+
+	// ReadTelemetry will take a fresh set of readings from the hardware.
+	ReadTelemetry() (TelemetryReadings, error)
+}
+
+// ReadTelemetry will read the telemetry of the provided SDR, or return an
+// ErrNotSupported if the SDR doesn't implement the Telemetry interface.
+func ReadTelemetry(dev Sdr) (TelemetryReadings, error) {
+	t, ok := dev.(Telemetry)
+	if !ok {
+		return TelemetryReadings{}, ErrNotSupported
+	}
+	return t.ReadTelemetry()
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,220 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package testutils
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+)
+
+// SampleFormats is every SampleFormat that sdr.ConvertBuffer is able to
+// convert between.
+var SampleFormats = []sdr.SampleFormat{
+	sdr.SampleFormatU8,
+	sdr.SampleFormatI8,
+	sdr.SampleFormatI16,
+	sdr.SampleFormatI12Packed,
+	sdr.SampleFormatC64,
+	sdr.SampleFormatC128,
+}
+
+// floatSweepLength is the number of points the floating point formats are
+// swept over, which is enough to land between every int16 value.
+const floatSweepLength = 1<<17 + 1
+
+// FormatResolution returns the largest error (in full scale units, where
+// 1 is full scale) that converting an I or Q value into the provided
+// SampleFormat may add.
+//
+// This is a little over one step of the format, since conversions into the
+// integer formats truncate rather than round, and some of the integer
+// formats are not scaled the same way in both directions (int8 is
+// multiplied by 127 on the way in, and divided by 128 on the way out).
+func FormatResolution(f sdr.SampleFormat) float64 {
+	switch f {
+	case sdr.SampleFormatU8:
+		return 2 / 127.5
+	case sdr.SampleFormatI8:
+		return 2 / 127.0
+	case sdr.SampleFormatI12Packed:
+		return 2 / 2047.0
+	case sdr.SampleFormatI16:
+		return 2 / 32767.0
+	case sdr.SampleFormatC64:
+		return 1e-6
+	case sdr.SampleFormatC128:
+		return 1e-12
+	default:
+		return math.Inf(1)
+	}
+}
+
+// ConversionTolerance returns the largest error (in full scale units) an I
+// or Q value may pick up being converted from one SampleFormat to another, or
+// back again.
+func ConversionTolerance(from, to sdr.SampleFormat) float64 {
+	return FormatResolution(from) + FormatResolution(to)
+}
+
+// ConformanceSamples will return a buffer sweeping over the full range of
+// the SampleFormat, from the most negative value to the most positive. The
+// integer formats are swept exhaustively. The I values increase, and the Q
+// values are the mirror image, decreasing over the same range, so that a
+// conversion that swaps I and Q is caught.
+func ConformanceSamples(f sdr.SampleFormat) (sdr.Samples, error) {
+	switch f {
+	case sdr.SampleFormatU8:
+		s := make(sdr.SamplesU8, 256)
+		for i := range s {
+			s[i] = [2]uint8{uint8(i), uint8(255 - i)}
+		}
+		return s, nil
+	case sdr.SampleFormatI8:
+		s := make(sdr.SamplesI8, 255)
+		for i := range s {
+			v := int8(i - 127)
+			s[i] = [2]int8{v, -v}
+		}
+		return s, nil
+	case sdr.SampleFormatI12Packed:
+		s := make(sdr.SamplesI12Packed, 4095)
+		for i := range s {
+			v := int16(i - 2047)
+			s.Set(i, [2]int16{v, -v})
+		}
+		return s, nil
+	case sdr.SampleFormatI16:
+		s := make(sdr.SamplesI16, 65535)
+		for i := range s {
+			v := int16(i - 32767)
+			s[i] = [2]int16{v, -v}
+		}
+		return s, nil
+	case sdr.SampleFormatC64:
+		s := make(sdr.SamplesC64, floatSweepLength)
+		for i := range s {
+			v := float32(2*float64(i)/float64(len(s)-1) - 1)
+			s[i] = complex(v, -v)
+		}
+		return s, nil
+	case sdr.SampleFormatC128:
+		s := make(sdr.SamplesC128, floatSweepLength)
+		for i := range s {
+			v := 2*float64(i)/float64(len(s)-1) - 1
+			s[i] = complex(v, -v)
+		}
+		return s, nil
+	default:
+		return nil, sdr.ErrSampleFormatUnknown
+	}
+}
+
+// maxError returns the larger of the I and Q errors between a and b.
+func maxError(a, b complex128) float64 {
+	return math.Max(math.Abs(real(a)-real(b)), math.Abs(imag(a)-imag(b)))
+}
+
+// toC128 will convert the samples to complex128, which every format can be
+// converted to without loss, to compare them with.
+func toC128(s sdr.Samples) (sdr.SamplesC128, error) {
+	out := make(sdr.SamplesC128, s.Length())
+	_, err := sdr.ConvertBuffer(out, s)
+	return out, err
+}
+
+// TestConversion will check converting the full range of one SampleFormat to
+// another:
+//
+//   - is monotonic, larger input values are never converted to smaller
+//     output values.
+//   - is symmetric, the mirror image of an input value (such as -v, or
+//     255-v for uint8) converts to the mirror image of the output value,
+//     within ConversionTolerance.
+//   - round trips, converting to the other format and back again is within
+//     ConversionTolerance of the original value.
+func TestConversion(t *testing.T, from, to sdr.SampleFormat) {
+	t.Run(fmt.Sprintf("%s to %s", from, to), func(t *testing.T) {
+		var (
+			tolerance = ConversionTolerance(from, to)
+		)
+
+		in, err := ConformanceSamples(from)
+		assert.NoError(t, err)
+
+		out, err := sdr.MakeSamples(to, in.Length())
+		assert.NoError(t, err)
+		_, err = sdr.ConvertBuffer(out, in)
+		assert.NoError(t, err)
+
+		back, err := sdr.MakeSamples(from, in.Length())
+		assert.NoError(t, err)
+		_, err = sdr.ConvertBuffer(back, out)
+		assert.NoError(t, err)
+
+		inC, err := toC128(in)
+		assert.NoError(t, err)
+		outC, err := toC128(out)
+		assert.NoError(t, err)
+		backC, err := toC128(back)
+		assert.NoError(t, err)
+
+		n := len(inC)
+		for i := 1; i < n; i++ {
+			if real(outC[i]) < real(outC[i-1]) || imag(outC[i]) > imag(outC[i-1]) {
+				t.Errorf("not monotonic at %d: %v -> %v, %v -> %v",
+					i, inC[i-1], outC[i-1], inC[i], outC[i])
+				break
+			}
+		}
+
+		for i := 0; i < n; i++ {
+			if err := maxError(outC[i], -outC[n-1-i]); err > tolerance {
+				t.Errorf("not symmetric at %d: %v -> %v, %v -> %v (error %f)",
+					i, inC[i], outC[i], inC[n-1-i], outC[n-1-i], err)
+				break
+			}
+		}
+
+		for i := 0; i < n; i++ {
+			if err := maxError(backC[i], inC[i]); err > tolerance {
+				t.Errorf("round trip error at %d: %v -> %v -> %v (error %f)",
+					i, inC[i], outC[i], backC[i], err)
+				break
+			}
+		}
+	})
+}
+
+// TestConversions will run TestConversion over every pair of SampleFormats.
+func TestConversions(t *testing.T) {
+	for _, from := range SampleFormats {
+		for _, to := range SampleFormats {
+			TestConversion(t, from, to)
+		}
+	}
+}
+
+// vim: foldmethod=marker
@@ -22,9 +22,11 @@ package testutils
 
 import (
 	"math"
+	"math/rand"
 
 	"hz.tools/rf"
 	"hz.tools/sdr"
+	"hz.tools/sdr/siggen"
 )
 
 // CW will generate a Carrier Wave at a specific frequency.
@@ -43,4 +45,101 @@ func CW(buf sdr.SamplesC64, freq rf.Hz, sampleRate int, phase float64) {
 	}
 }
 
+// Tone is a single carrier as part of a MultiTone stimulus. This is the
+// same type siggen.MultiTone takes, so one set of Tones can drive both.
+type Tone = siggen.Tone
+
+// MultiTone will fill the buffer with the sum of all the provided Tones.
+//
+// Since each Tone is a complex exponential, the total power of the buffer
+// (if each Tone is at a distinct frequency that lands on a whole number of
+// cycles within the buffer) is the sum of each Amplitude squared.
+func MultiTone(buf sdr.SamplesC64, sampleRate int, tones ...Tone) {
+	tau := math.Pi * 2
+
+	for i := range buf {
+		now := float64(i) / float64(sampleRate)
+		var sample complex128
+		for _, tone := range tones {
+			sample += complex(
+				tone.Amplitude*math.Cos(tau*float64(tone.Frequency)*now+tone.Phase),
+				tone.Amplitude*math.Sin(tau*float64(tone.Frequency)*now+tone.Phase),
+			)
+		}
+		buf[i] = complex64(sample)
+	}
+}
+
+// BandLimitedNoise will fill the buffer with noise whose energy is confined
+// to the frequencies between low and high (inclusive), and which has a total
+// power of exactly `power`.
+//
+// This is generated as the sum of a carrier at every FFT bin (of the length
+// of the buffer) that falls within the band, each with equal amplitude and a
+// random phase drawn from the provided rand.Source. As a result, an FFT of
+// the buffer will have a flat power of power/n in each in-band bin, and
+// nothing at all out of band, which makes it a good stimulus for checking
+// filter responses against a known answer.
+//
+// If no bins fall within the band, the buffer will be zeroed.
+func BandLimitedNoise(
+	buf sdr.SamplesC64,
+	sampleRate int,
+	low, high rf.Hz,
+	power float64,
+	source rand.Source,
+) {
+	var (
+		r     = rand.New(source)
+		n     = len(buf)
+		tones = []Tone{}
+	)
+
+	for i := 0; i < n; i++ {
+		// Walk the bins in the same order an FFT would lay them out,
+		// so bin n/2 and above are the negative frequencies.
+		bin := i
+		if bin >= n/2 {
+			bin -= n
+		}
+		freq := rf.Hz(float64(bin) * float64(sampleRate) / float64(n))
+		if freq < low || freq > high {
+			continue
+		}
+		tones = append(tones, Tone{
+			Frequency: freq,
+			Phase:     r.Float64() * math.Pi * 2,
+		})
+	}
+
+	if len(tones) == 0 {
+		for i := range buf {
+			buf[i] = 0
+		}
+		return
+	}
+
+	amplitude := math.Sqrt(power / float64(len(tones)))
+	for i := range tones {
+		tones[i].Amplitude = amplitude
+	}
+	MultiTone(buf, sampleRate, tones...)
+}
+
+// AM will apply an amplitude-modulated envelope to the samples already in the
+// buffer, in place. Each sample is scaled by `1 + depth*cos(2*pi*freq*t)`,
+// so a depth of 0 leaves the buffer untouched, and a depth of 1 will swing
+// the envelope between zero and twice the original amplitude.
+//
+// This is mostly useful to exercise AGC loops, since the envelope at any
+// sample is known exactly.
+func AM(buf sdr.SamplesC64, freq rf.Hz, sampleRate int, depth float64) {
+	tau := math.Pi * 2
+
+	for i := range buf {
+		now := float64(i) / float64(sampleRate)
+		buf[i] *= complex(float32(1+depth*math.Cos(tau*float64(freq)*now)), 0)
+	}
+}
+
 // vim: foldmethod=marker
@@ -0,0 +1,109 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package testutils_test
+
+import (
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/testutils"
+)
+
+func power(buf sdr.SamplesC64) float64 {
+	var total float64
+	for _, s := range buf {
+		total += float64(real(s)*real(s) + imag(s)*imag(s))
+	}
+	return total / float64(len(buf))
+}
+
+// dft returns the power in each bin, normalized so that a unit carrier
+// centered on a bin reads as 1.
+func dft(buf sdr.SamplesC64) []float64 {
+	n := len(buf)
+	ret := make([]float64, n)
+	for k := 0; k < n; k++ {
+		var acc complex128
+		for i, s := range buf {
+			acc += complex128(s) * cmplx.Exp(complex(0, -2*math.Pi*float64(k*i)/float64(n)))
+		}
+		acc /= complex(float64(n), 0)
+		ret[k] = real(acc)*real(acc) + imag(acc)*imag(acc)
+	}
+	return ret
+}
+
+func TestMultiTone(t *testing.T) {
+	buf := make(sdr.SamplesC64, 256)
+	testutils.MultiTone(buf, 256,
+		testutils.Tone{Frequency: rf.Hz(10), Amplitude: 1},
+		testutils.Tone{Frequency: rf.Hz(-20), Amplitude: 0.5, Phase: 1},
+	)
+	assert.InDelta(t, 1.25, power(buf), 1e-4)
+
+	bins := dft(buf)
+	assert.InDelta(t, 1, bins[10], 1e-4)
+	assert.InDelta(t, 0.25, bins[256-20], 1e-4)
+	assert.InDelta(t, 0, bins[11], 1e-4)
+}
+
+func TestBandLimitedNoise(t *testing.T) {
+	buf := make(sdr.SamplesC64, 256)
+	testutils.BandLimitedNoise(buf, 256, rf.Hz(-16), rf.Hz(15), 2, rand.NewSource(1))
+	assert.InDelta(t, 2, power(buf), 1e-3)
+
+	bins := dft(buf)
+	for i, p := range bins {
+		if i < 16 || i >= 256-16 {
+			assert.InDelta(t, 2.0/32, p, 1e-4)
+		} else {
+			assert.InDelta(t, 0, p, 1e-4)
+		}
+	}
+}
+
+func TestBandLimitedNoiseEmpty(t *testing.T) {
+	buf := make(sdr.SamplesC64, 16)
+	buf[0] = 1
+	testutils.BandLimitedNoise(buf, 16, rf.Hz(1.5), rf.Hz(1.6), 2, rand.NewSource(1))
+	assert.Equal(t, make(sdr.SamplesC64, 16), buf)
+}
+
+func TestAM(t *testing.T) {
+	buf := make(sdr.SamplesC64, 256)
+	testutils.CW(buf, rf.Hz(10), 256, 0)
+	testutils.AM(buf, rf.Hz(4), 256, 0.5)
+
+	assert.InDelta(t, 1.5, cmplx.Abs(complex128(buf[0])), 1e-5)
+	assert.InDelta(t, 0.5, cmplx.Abs(complex128(buf[32])), 1e-5)
+	assert.InDelta(t, 1, cmplx.Abs(complex128(buf[16])), 1e-5)
+
+	// Carrier power plus two sidebands, each at (depth/2)^2.
+	assert.InDelta(t, 1+2*0.0625, power(buf), 1e-4)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,73 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package testutils
+
+import (
+	"math"
+	"math/cmplx"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/fft"
+)
+
+type dftPlan struct {
+	iq        sdr.SamplesC64
+	frequency []complex64
+	direction fft.Direction
+}
+
+func (p dftPlan) Transform() error {
+	var (
+		n    = len(p.iq)
+		src  = p.iq
+		dst  = p.frequency
+		sign = -1.0
+	)
+	if p.direction == fft.Backward {
+		src, dst = p.frequency, p.iq
+		sign = 1.0
+	}
+
+	out := make([]complex64, n)
+	for k := range out {
+		var acc complex128
+		for i, s := range src[:n] {
+			acc += complex128(s) * cmplx.Exp(complex(0, sign*2*math.Pi*float64(k*i)/float64(n)))
+		}
+		out[k] = complex64(acc)
+	}
+	copy(dst, out)
+	return nil
+}
+
+func (p dftPlan) Close() error { return nil }
+
+// DFTPlanner is a very slow (but very obviously correct) unnormalized DFT
+// fft.Planner, to check FFT implementations against, and to test code built
+// on top of a Planner without depending on any particular FFT.
+func DFTPlanner(iq sdr.SamplesC64, frequency []complex64, direction fft.Direction) (fft.Plan, error) {
+	if len(frequency) < len(iq) {
+		return nil, sdr.ErrDstTooSmall
+	}
+	return dftPlan{iq: iq, frequency: frequency, direction: direction}, nil
+}
+
+// vim: foldmethod=marker
@@ -74,9 +74,15 @@ func TestReader(t *testing.T, name string, r sdr.Reader) {
 		t.Run("SampleFormatI16", func(t *testing.T) {
 			testReaderSampleFormat(t, sdr.SampleFormatI16, r)
 		})
+		t.Run("SampleFormatI12Packed", func(t *testing.T) {
+			testReaderSampleFormat(t, sdr.SampleFormatI12Packed, r)
+		})
 		t.Run("SampleFormatC64", func(t *testing.T) {
 			testReaderSampleFormat(t, sdr.SampleFormatC64, r)
 		})
+		t.Run("SampleFormatC128", func(t *testing.T) {
+			testReaderSampleFormat(t, sdr.SampleFormatC128, r)
+		})
 		t.Run("SampleRate", func(t *testing.T) {
 			// We're just invoking this to ensure we don't panic.
 			r.SampleRate()
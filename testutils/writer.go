@@ -41,9 +41,15 @@ func TestWriter(t *testing.T, name string, w sdr.Writer) {
 		t.Run("SampleFormatI16", func(t *testing.T) {
 			testWriterSampleFormat(t, sdr.SampleFormatI16, w)
 		})
+		t.Run("SampleFormatI12Packed", func(t *testing.T) {
+			testWriterSampleFormat(t, sdr.SampleFormatI12Packed, w)
+		})
 		t.Run("SampleFormatC64", func(t *testing.T) {
 			testWriterSampleFormat(t, sdr.SampleFormatC64, w)
 		})
+		t.Run("SampleFormatC128", func(t *testing.T) {
+			testWriterSampleFormat(t, sdr.SampleFormatC128, w)
+		})
 		t.Run("SampleRate", func(t *testing.T) {
 			// We're just invoking this to ensure we don't panic.
 			w.SampleRate()
@@ -0,0 +1,217 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sdr
+
+import (
+	"sync"
+	"time"
+)
+
+// TimedReader is a Reader which is able to report when the samples it
+// returns were received.
+type TimedReader interface {
+	Reader
+
+	// ReadTimed is the same as Read, but will also return the time at which
+	// the first sample in the buffer was received.
+	ReadTimed(Samples) (int, time.Time, error)
+}
+
+// TimedReadCloser is the interface that groups the ReadTimed and Close
+// methods.
+type TimedReadCloser interface {
+	TimedReader
+	Closer
+}
+
+// HostClock estimates when samples were received for devices which don't
+// have a hardware clock to timestamp samples with (such as the rtl-sdr or
+// HackRF), using the host's clock at the point the driver got each buffer
+// of samples, usually in the USB transfer callback.
+//
+// Buffers arrive some (varying) time after the last sample in them was
+// received. Since samples can't arrive before they were received, the
+// estimate follows the earliest arrivals: it's snapped back if a buffer
+// arrives earlier than expected, and otherwise slowly nudged towards the
+// arrival time. The drift of the device's oscillator against the host's
+// clock is tracked from the earliest arrival in each window of buffers.
+// The result is only approximate (USB and scheduling latency will be in
+// the order of milliseconds), but it's stable, and available everywhere.
+type HostClock struct {
+	lock   *sync.Mutex
+	marked *sync.Cond
+
+	nominal float64
+
+	// start is the estimated time of sample 0, and period is the estimated
+	// time between samples, in seconds. total is the number of samples that
+	// have arrived.
+	start  time.Time
+	period float64
+	total  int64
+
+	// epoch is the arrival time of the first buffer. The earliest arrival
+	// (in seconds after epoch, less the nominal time for the samples so
+	// far) in the first window, and in the current window, are used to
+	// estimate the period.
+	epoch  time.Time
+	first  *hostClockArrival
+	window hostClockArrival
+	marks  int
+}
+
+type hostClockArrival struct {
+	offset float64
+	total  int64
+}
+
+const (
+	hostClockSmoothing = 0.05
+	hostClockWindow    = 32
+)
+
+// NewHostClock will create a new HostClock for a device running at the
+// provided sample rate.
+func NewHostClock(sampleRate uint) *HostClock {
+	lock := &sync.Mutex{}
+	return &HostClock{
+		lock:    lock,
+		marked:  sync.NewCond(lock),
+		nominal: 1 / float64(sampleRate),
+		period:  1 / float64(sampleRate),
+	}
+}
+
+// Mark will account for n samples having arrived at the provided time. Only
+// samples which the reader will actually get should be Marked, so drivers
+// which may drop a buffer should Mark it once it's been handed over. Reads
+// from a HostTimedReader will wait for the samples they return to be Marked.
+func (c *HostClock) Mark(now time.Time, n int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	defer c.marked.Broadcast()
+
+	c.total += int64(n)
+	if c.start.IsZero() {
+		c.epoch = now
+		c.start = now.Add(-c.duration(c.total))
+	}
+
+	c.track(now)
+
+	err := now.Sub(c.start.Add(c.duration(c.total))).Seconds()
+	if err < 0 {
+		// The buffer arrived earlier than we thought it could have.
+		c.start = c.start.Add(secondsToDuration(err))
+	} else {
+		c.start = c.start.Add(secondsToDuration(err * hostClockSmoothing))
+	}
+}
+
+// track will keep track of the earliest arrival in each window, and update
+// the period at the end of each window.
+func (c *HostClock) track(now time.Time) {
+	arrival := hostClockArrival{
+		offset: now.Sub(c.epoch).Seconds() - float64(c.total)*c.nominal,
+		total:  c.total,
+	}
+	if c.marks == 0 || arrival.offset < c.window.offset {
+		c.window = arrival
+	}
+	c.marks++
+	if c.marks < hostClockWindow {
+		return
+	}
+	c.marks = 0
+
+	if c.first == nil {
+		first := c.window
+		c.first = &first
+		return
+	}
+
+	drift := (c.window.offset - c.first.offset) / float64(c.window.total-c.first.total)
+	if lim := c.nominal * 1e-3; drift > lim {
+		drift = lim
+	} else if drift < -lim {
+		drift = -lim
+	}
+	c.period = c.nominal + drift
+}
+
+// Time will return the estimated time at which the sample at the provided
+// offset into the stream was received.
+func (c *HostClock) Time(offset int64) time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.start.Add(c.duration(offset))
+}
+
+// timeMarked is the same as Time, but will wait until the sample at the
+// provided offset has been Marked.
+func (c *HostClock) timeMarked(offset int64) time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for offset >= c.total {
+		c.marked.Wait()
+	}
+	return c.start.Add(c.duration(offset))
+}
+
+func (c *HostClock) duration(samples int64) time.Duration {
+	return secondsToDuration(float64(samples) * c.period)
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+type hostTimedReader struct {
+	ReadCloser
+	clock  *HostClock
+	offset int64
+}
+
+// Read implements the sdr.Reader interface.
+func (r *hostTimedReader) Read(s Samples) (int, error) {
+	n, _, err := r.ReadTimed(s)
+	return n, err
+}
+
+// ReadTimed implements the sdr.TimedReader interface.
+func (r *hostTimedReader) ReadTimed(s Samples) (int, time.Time, error) {
+	n, err := r.ReadCloser.Read(s)
+	if n == 0 {
+		return 0, time.Time{}, err
+	}
+	when := r.clock.timeMarked(r.offset)
+	r.offset += int64(n)
+	return n, when, err
+}
+
+// HostTimedReader will wrap the provided ReadCloser, whose samples are
+// being Marked on the provided HostClock as they arrive, and return a
+// TimedReadCloser. Reads must not be called concurrently.
+func HostTimedReader(r ReadCloser, clock *HostClock) TimedReadCloser {
+	return &hostTimedReader{ReadCloser: r, clock: clock}
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,116 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sdr_test
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+)
+
+// markClock will feed the HostClock a minute's worth of 100 sample
+// buffers, from a 1000 sps device whose sample clock is off by ppm, arriving
+// with 1 to 5ms of latency. The returned function will return the real
+// time the sample at the provided offset was received.
+func markClock(clock *sdr.HostClock, ppm float64) func(int64) time.Time {
+	var (
+		rng   = rand.New(rand.NewSource(1))
+		start = time.Unix(1700000000, 0)
+		rate  = 1000 * (1 + ppm/1e6)
+	)
+	received := func(offset int64) time.Time {
+		return start.Add(time.Duration(float64(offset) / rate * float64(time.Second)))
+	}
+	for total := int64(100); total <= 60000; total += 100 {
+		latency := time.Millisecond + time.Duration(rng.Int63n(int64(4*time.Millisecond)))
+		clock.Mark(received(total).Add(latency), 100)
+	}
+	return received
+}
+
+func TestHostClock(t *testing.T) {
+	for _, ppm := range []float64{0, 800, -800} {
+		clock := sdr.NewHostClock(1000)
+		received := markClock(clock, ppm)
+
+		// Without tracking the drift, 800 ppm would be off by 48ms at
+		// the start of the stream.
+		for _, offset := range []int64{0, 30000, 59900} {
+			err := clock.Time(offset).Sub(received(offset))
+			assert.True(t, err > -time.Millisecond, "%f ppm, %d: %s", ppm, offset, err)
+			assert.True(t, err < time.Millisecond*3, "%f ppm, %d: %s", ppm, offset, err)
+		}
+	}
+}
+
+func TestHostTimedReader(t *testing.T) {
+	pipeReader, pipeWriter := sdr.Pipe(1000, sdr.SampleFormatC64)
+	clock := sdr.NewHostClock(1000)
+	start := time.Unix(1700000000, 0)
+
+	go func() {
+		for i := 1; i <= 2; i++ {
+			clock.Mark(start.Add(time.Duration(i)*time.Second/10), 100)
+			pipeWriter.Write(make(sdr.SamplesC64, 100))
+		}
+	}()
+
+	rx := sdr.HostTimedReader(pipeReader, clock)
+	buf := make(sdr.SamplesC64, 100)
+
+	n, when, err := rx.ReadTimed(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 100, n)
+	assert.Equal(t, start, when)
+
+	_, err = rx.Read(buf[:50])
+	assert.NoError(t, err)
+
+	_, when, err = rx.ReadTimed(buf[:50])
+	assert.NoError(t, err)
+	assert.Equal(t, start.Add(time.Millisecond*150), when)
+}
+
+func TestHostTimedReaderMarkAfterWrite(t *testing.T) {
+	pipeReader, pipeWriter := sdr.Pipe(1000, sdr.SampleFormatC64)
+	clock := sdr.NewHostClock(1000)
+	start := time.Unix(1700000000, 0)
+
+	go func() {
+		// The pipe won't return until the samples have been read, so
+		// they're always Marked after the reader has them.
+		pipeWriter.Write(make(sdr.SamplesC64, 100))
+		time.Sleep(10 * time.Millisecond)
+		clock.Mark(start.Add(time.Second/10), 100)
+	}()
+
+	rx := sdr.HostTimedReader(pipeReader, clock)
+	n, when, err := rx.ReadTimed(make(sdr.SamplesC64, 100))
+	assert.NoError(t, err)
+	assert.Equal(t, 100, n)
+	assert.Equal(t, start, when)
+}
+
+// vim: foldmethod=marker
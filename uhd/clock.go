@@ -59,8 +59,8 @@ func (s *Sdr) SetTimeNow(d time.Duration) error {
 // splitDuration will split a time.Duration into USRP's time format, which
 // is whole seconds and fractional seconds.
 func splitDuration(d time.Duration) (C.int64_t, C.double) {
-	secs := d.Seconds()
-	frac := float64(d.Nanoseconds()) / 1e+9
+	secs := d / time.Second
+	frac := (d - secs*time.Second).Seconds()
 	return C.int64_t(secs), C.double(frac)
 }
 
@@ -110,11 +110,94 @@ func (s *Sdr) GetTimeSources() ([]string, error) {
 	})
 }
 
+// GetTimeSource will return the current time source of the USRP.
+func (s *Sdr) GetTimeSource() (string, error) {
+	var (
+		buf  [64]C.char
+		blen = 64
+	)
+	if err := rvToError(C.uhd_usrp_get_time_source(
+		*s.handle,
+		0,
+		&buf[0],
+		C.size_t(blen),
+	)); err != nil {
+		return "", err
+	}
+	return C.GoString(&buf[0]), nil
+}
+
+// GetTimeLastPPS will return the USRP's Time at the last PPS pulse.
+func (s *Sdr) GetTimeLastPPS() (time.Duration, error) {
+	var (
+		secs C.int64_t
+		frac C.double
+	)
+
+	if err := rvToError(C.uhd_usrp_get_time_last_pps(
+		*s.handle,
+		0,
+		&secs,
+		&frac,
+	)); err != nil {
+		return time.Duration(0), err
+	}
+
+	return newDuration(secs, frac), nil
+}
+
+const (
+	// SourceInternal will use the USRP's onboard oscillator or time.
+	SourceInternal = "internal"
+
+	// SourceExternal will use the 10 MHz reference or PPS inputs.
+	SourceExternal = "external"
+
+	// SourceGPSDO will use the GPS disciplined oscillator, if fitted.
+	SourceGPSDO = "gpsdo"
+)
+
+// SetClockSource will set the frequency reference for the USRP, such as
+// SourceInternal, SourceExternal or SourceGPSDO. Every device in a coherent
+// deployment needs to share a reference.
+func (s *Sdr) SetClockSource(what string) error {
+	cWhat := C.CString(what)
+	defer C.free(unsafe.Pointer(cWhat))
+	return rvToError(C.uhd_usrp_set_clock_source(
+		*s.handle,
+		cWhat,
+		0,
+	))
+}
+
+// GetClockSource will return the current frequency reference of the USRP.
+func (s *Sdr) GetClockSource() (string, error) {
+	var (
+		buf  [64]C.char
+		blen = 64
+	)
+	if err := rvToError(C.uhd_usrp_get_clock_source(
+		*s.handle,
+		0,
+		&buf[0],
+		C.size_t(blen),
+	)); err != nil {
+		return "", err
+	}
+	return C.GoString(&buf[0]), nil
+}
+
+// GetClockSources will return the frequency references that can be passed
+// to SetClockSource.
+func (s *Sdr) GetClockSources() ([]string, error) {
+	return getStringVector(func(names *C.uhd_string_vector_handle) error {
+		return rvToError(C.uhd_usrp_get_clock_sources(*s.handle, 0, names))
+	})
+}
+
 // TODO:
 //
 //  - uhd_usrp_set_time_unknown_pps
-//  - uhd_usrp_get_time_last_pps
-//  - uhd_usrp_get_time_source
 //  - uhd_usrp_get_time_synchronized
 //
 
@@ -0,0 +1,87 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package uhd
+
+// #cgo pkg-config: uhd
+//
+// #include <stdlib.h>
+// #include <uhd.h>
+import "C"
+
+import (
+	"strings"
+	"unsafe"
+
+	"hz.tools/sdr"
+)
+
+// Find will return the device address of every USRP that UHD can find
+// matching the provided device arguments, which may be empty to find
+// everything. Each address is a comma separated list of key=value pairs,
+// such as "type=b200,name=MyB210,serial=31ABCDE,product=B210".
+func Find(args string) ([]string, error) {
+	cArgs := C.CString(args)
+	defer C.free(unsafe.Pointer(cArgs))
+
+	return getStringVector(func(addrs *C.uhd_string_vector_handle) error {
+		return rvToError(C.uhd_usrp_find(cArgs, addrs))
+	})
+}
+
+// parseDeviceAddress will split a UHD device address into its key=value
+// pairs.
+func parseDeviceAddress(addr string) map[string]string {
+	ret := map[string]string{}
+	for _, pair := range strings.Split(addr, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		ret[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return ret
+}
+
+// List will return the sdr.HardwareInfo for every USRP that UHD can find,
+// without opening any of them.
+func List() ([]sdr.HardwareInfo, error) {
+	addrs, err := Find("")
+	if err != nil {
+		return nil, err
+	}
+
+	ret := []sdr.HardwareInfo{}
+	for _, addr := range addrs {
+		kv := parseDeviceAddress(addr)
+		product := kv["product"]
+		if product == "" {
+			product = kv["type"]
+		}
+		ret = append(ret, sdr.HardwareInfo{
+			Manufacturer: "Ettus Research",
+			Product:      product,
+			Serial:       kv["serial"],
+		})
+	}
+	return ret, nil
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,64 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package uhd
+
+import (
+	"fmt"
+
+	"hz.tools/sdr"
+)
+
+// streamFormat is how a SampleFormat is streamed by UHD; the format of the
+// samples on the wire between the device and the host, and the format UHD
+// hands the samples to us in.
+type streamFormat struct {
+	// otw is the UHD "over the wire" format.
+	otw string
+
+	// cpu is the UHD format of the samples in host memory.
+	cpu string
+
+	// host is the sdr.SampleFormat matching the cpu format. UHD has no
+	// packed host formats, so if this isn't the SampleFormat of the Sdr,
+	// the driver will convert each buffer to or from it.
+	host sdr.SampleFormat
+}
+
+// getStreamFormat will return the streamFormat to use for the provided
+// SampleFormat.
+func getStreamFormat(sf sdr.SampleFormat) (streamFormat, error) {
+	switch sf {
+	case sdr.SampleFormatI8:
+		return streamFormat{otw: "sc8", cpu: "sc8", host: sf}, nil
+	case sdr.SampleFormatI16:
+		return streamFormat{otw: "sc16", cpu: "sc16", host: sf}, nil
+	case sdr.SampleFormatC64:
+		return streamFormat{otw: "fc32", cpu: "fc32", host: sf}, nil
+	case sdr.SampleFormatI12Packed:
+		// sc12 cuts the bandwidth on the wire by a quarter over sc16, but
+		// UHD will only hand it to us as sc16, which we pack again.
+		return streamFormat{otw: "sc12", cpu: "sc16", host: sdr.SampleFormatI16}, nil
+	default:
+		return streamFormat{}, fmt.Errorf("uhd: unsupported SampleFormat provided")
+	}
+}
+
+// vim: foldmethod=marker
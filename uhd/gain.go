@@ -139,7 +139,7 @@ func (s *Sdr) GetGainStages() (sdr.GainStages, error) {
 
 	var (
 		rxChannels = s.rxChannels
-		txChannel  = s.txChannel
+		txChannels = s.txChannels
 	)
 
 	for _, rxChannel := range rxChannels {
@@ -187,47 +187,49 @@ func (s *Sdr) GetGainStages() (sdr.GainStages, error) {
 		}
 	}
 
-	txGainStageNames, err := getTxGainStageNames(s.handle, C.size_t(txChannel))
-	if err != nil {
-		return nil, err
-	}
-
-	for _, gainStageName := range txGainStageNames {
-		gsn := C.CString(gainStageName)
-		err := rvToError(C.uhd_usrp_get_tx_gain_range(
-			*s.handle,
-			gsn,
-			C.size_t(txChannel),
-			gainRange,
-		))
-		C.free(unsafe.Pointer(gsn))
+	for _, txChannel := range txChannels {
+		txGainStageNames, err := getTxGainStageNames(s.handle, C.size_t(txChannel))
 		if err != nil {
 			return nil, err
 		}
 
-		if err := rvToError(C.uhd_meta_range_start(gainRange, &start)); err != nil {
-			return nil, err
-		}
+		for _, gainStageName := range txGainStageNames {
+			gsn := C.CString(gainStageName)
+			err := rvToError(C.uhd_usrp_get_tx_gain_range(
+				*s.handle,
+				gsn,
+				C.size_t(txChannel),
+				gainRange,
+			))
+			C.free(unsafe.Pointer(gsn))
+			if err != nil {
+				return nil, err
+			}
 
-		if err := rvToError(C.uhd_meta_range_stop(gainRange, &end)); err != nil {
-			return nil, err
-		}
+			if err := rvToError(C.uhd_meta_range_start(gainRange, &start)); err != nil {
+				return nil, err
+			}
 
-		if err := rvToError(C.uhd_meta_range_step(gainRange, &step)); err != nil {
-			return nil, err
-		}
+			if err := rvToError(C.uhd_meta_range_stop(gainRange, &end)); err != nil {
+				return nil, err
+			}
+
+			if err := rvToError(C.uhd_meta_range_step(gainRange, &step)); err != nil {
+				return nil, err
+			}
 
-		ret = append(ret, txGainStage{
-			channel: txChannel,
-			gainStage: gainStage{
-				stageType: sdr.GainStageTypeTransmit,
-				prefix:    fmt.Sprintf("TX%d", txChannel),
-				name:      gainStageName,
-				minGain:   float32(start),
-				maxGain:   float32(end),
-				step:      float32(step),
-			},
-		})
+			ret = append(ret, txGainStage{
+				channel: txChannel,
+				gainStage: gainStage{
+					stageType: sdr.GainStageTypeTransmit,
+					prefix:    fmt.Sprintf("TX%d", txChannel),
+					name:      gainStageName,
+					minGain:   float32(start),
+					maxGain:   float32(end),
+					step:      float32(step),
+				},
+			})
+		}
 	}
 
 	return ret, nil
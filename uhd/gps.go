@@ -0,0 +1,144 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package uhd
+
+// #cgo pkg-config: uhd
+//
+// #include <uhd.h>
+import "C"
+
+import (
+	"fmt"
+	"time"
+	"unsafe"
+)
+
+var (
+	// ErrNoPPS will be returned by SetTimeFromGPS if no PPS edge was seen,
+	// usually because the time source isn't set to SourceGPSDO or
+	// SourceExternal, or the GPS hasn't got a fix yet.
+	ErrNoPPS = fmt.Errorf("uhd: no pps edge seen")
+)
+
+// mboardSensor will read the named motherboard sensor, and pass the value
+// to fn.
+func (s *Sdr) mboardSensor(name string, fn func(C.uhd_sensor_value_handle) error) error {
+	var value C.uhd_sensor_value_handle
+
+	if err := rvToError(C.uhd_sensor_value_make(&value)); err != nil {
+		return err
+	}
+	defer C.uhd_sensor_value_free(&value)
+
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+
+	// TODO(paultag): Multiple Mboards?
+	if err := rvToError(C.uhd_usrp_get_mboard_sensor(*s.handle, cName, 0, &value)); err != nil {
+		return err
+	}
+	return fn(value)
+}
+
+// GPSLocked will return true if the GPSDO has a GPS fix. Devices without a
+// GPSDO will return an error.
+func (s *Sdr) GPSLocked() (bool, error) {
+	var locked C.bool
+	err := s.mboardSensor("gps_locked", func(value C.uhd_sensor_value_handle) error {
+		return rvToError(C.uhd_sensor_value_to_bool(value, &locked))
+	})
+	return bool(locked), err
+}
+
+// GPSTime will return the time according to the GPSDO, to the second.
+func (s *Sdr) GPSTime() (time.Time, error) {
+	var secs C.int
+	err := s.mboardSensor("gps_time", func(value C.uhd_sensor_value_handle) error {
+		return rvToError(C.uhd_sensor_value_to_int(value, &secs))
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(int64(secs), 0), nil
+}
+
+// GPSNMEA will return the latest GGA and RMC NMEA sentences from the
+// GPSDO, in that order.
+func (s *Sdr) GPSNMEA() ([]string, error) {
+	ret := []string{}
+	for _, name := range []string{"gps_gpgga", "gps_gprmc"} {
+		var (
+			buf  [256]C.char
+			blen = 256
+		)
+		if err := s.mboardSensor(name, func(value C.uhd_sensor_value_handle) error {
+			return rvToError(C.uhd_sensor_value_value(value, &buf[0], C.size_t(blen)))
+		}); err != nil {
+			return nil, err
+		}
+		ret = append(ret, C.GoString(&buf[0]))
+	}
+	return ret, nil
+}
+
+// SetTimeFromGPS will set the device time to the GPS time (as a Duration
+// since the Unix epoch), so that timestamps line up across every device
+// disciplined by GPS. The time source must already be set to SourceGPSDO.
+//
+// This waits for a PPS edge, so that the GPS time can be read well clear
+// of the next one, and then sets the time at the next PPS. This takes up to
+// two seconds; the time is valid once the call returns.
+func (s *Sdr) SetTimeFromGPS() error {
+	last, err := s.GetTimeLastPPS()
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(1500 * time.Millisecond)
+	for {
+		now, err := s.GetTimeLastPPS()
+		if err != nil {
+			return err
+		}
+		if now != last {
+			break
+		}
+		if time.Now().After(deadline) {
+			return ErrNoPPS
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	gpsTime, err := s.GPSTime()
+	if err != nil {
+		return err
+	}
+	next := time.Duration(gpsTime.Add(time.Second).UnixNano())
+	if err := s.SetTimeNextPPS(next); err != nil {
+		return err
+	}
+
+	// Wait out the PPS the time was set on before anything relies on it.
+	time.Sleep(time.Second)
+	return nil
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,112 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package uhd
+
+// #cgo pkg-config: uhd
+//
+// #include <uhd.h>
+import "C"
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// LibraryVersion will return the version of libuhd that's been linked
+// against, such as "4.4.0.0-0-g5fac246b". FPGA and firmware images must
+// match this version.
+func LibraryVersion() (string, error) {
+	var (
+		buf  [256]C.char
+		blen = 256
+	)
+	if err := rvToError(C.uhd_get_version_string(&buf[0], C.size_t(blen))); err != nil {
+		return "", err
+	}
+	return C.GoString(&buf[0]), nil
+}
+
+// ImagesDir will return the directory libuhd will search for FPGA and
+// firmware images, if it has been overridden with the UHD_IMAGES_DIR
+// environment variable. If it has not, an empty string is returned, and
+// libuhd will use its compiled in default.
+func ImagesDir() string {
+	return os.Getenv("UHD_IMAGES_DIR")
+}
+
+// lastError will return the message of the last exception libuhd caught,
+// which is far more useful than the error code alone.
+func lastError() string {
+	var (
+		buf  [1024]C.char
+		blen = 1024
+	)
+	if rvToError(C.uhd_get_last_error(&buf[0], C.size_t(blen))) != nil {
+		return ""
+	}
+	return C.GoString(&buf[0])
+}
+
+// ImageError is returned by Open when libuhd could not bring up the device
+// because the FPGA or firmware images could not be found, or are not
+// compatible with the version of libuhd in use.
+type ImageError struct {
+	// Err is the error code returned by libuhd.
+	Err error
+
+	// Detail is the message libuhd gave for the failure.
+	Detail string
+}
+
+// Error implements the error interface.
+func (e ImageError) Error() string {
+	version, err := LibraryVersion()
+	if err != nil {
+		version = "unknown"
+	}
+	hint := "run uhd_images_downloader(1)"
+	if dir := ImagesDir(); dir != "" {
+		hint += fmt.Sprintf(" with -i %q (from UHD_IMAGES_DIR)", dir)
+	}
+	return fmt.Sprintf(
+		"%s: FPGA/firmware image problem: %s; %s to fetch images matching libuhd %s, "+
+			"or set Options.FPGAImage / Options.FirmwareImage",
+		e.Err, e.Detail, hint, version,
+	)
+}
+
+// makeError will turn an error from uhd_usrp_make into something a bit
+// more actionable, since image mismatches otherwise only surface as a
+// generic runtime error.
+func makeError(err error) error {
+	detail := lastError()
+	if detail == "" {
+		return err
+	}
+	lower := strings.ToLower(detail)
+	if strings.Contains(lower, "image") || strings.Contains(lower, "compat") {
+		return ImageError{Err: err, Detail: detail}
+	}
+	return fmt.Errorf("%s: %s", err, detail)
+}
+
+// vim: foldmethod=marker
@@ -97,11 +97,13 @@ type readStreamer struct {
 
 	writers      pipeWriters
 	sampleFormat sdr.SampleFormat
+	hostFormat   sdr.SampleFormat
 
 	rxStreamer C.uhd_rx_streamer_handle
 	rxMetadata C.uhd_rx_metadata_handle
 
 	iqLen int
+	clock *rxClock
 
 	timing struct {
 		Set    bool
@@ -169,10 +171,14 @@ func (rc *readStreamer) run() error {
 	defer rc.writers.Close()
 	defer rc.cancel()
 	defer rc.wg.Done()
+	defer sdr.RecoverDriverPanic(rc.writers)
 
 	var channels = len(rc.writers)
-	if channels > 32 {
-		panic("UHD: too many rx channels set")
+	if channels > maxRxChannels {
+		// startRx checks this before we ever get here, but this is cheap
+		// enough to check again rather than find out the hard way.
+		rc.writers.CloseWithError(ErrTooManyChannels)
+		return ErrTooManyChannels
 	}
 
 	var (
@@ -180,9 +186,12 @@ func (rc *readStreamer) run() error {
 		i         int
 		errCode   C.uhd_rx_metadata_error_code_t
 		streamCmd C.uhd_stream_cmd_t
+		hasTime   C.bool
+		timeSecs  C.int64_t
+		timeFrac  C.double
 
 		iqLength  = rc.iqLen
-		iqSize    = iqLength * rc.sampleFormat.Size()
+		iqSize    = iqLength * rc.hostFormat.Size()
 		ciqSize   = C.size_t(iqSize)
 		ciqLength = C.size_t(iqLength)
 
@@ -193,6 +202,18 @@ func (rc *readStreamer) run() error {
 		cIQBuffers[i] = C.malloc(C.size_t(ciqSize))
 	}
 
+	// If UHD can't give us samples in our SampleFormat, we convert each
+	// buffer into this one before writing it out.
+	var converted sdr.Samples
+	if rc.hostFormat != rc.sampleFormat {
+		var err error
+		converted, err = sdr.MakeSamples(rc.sampleFormat, iqLength)
+		if err != nil {
+			rc.writers.CloseWithError(err)
+			return err
+		}
+	}
+
 	var hasTimeSpec = C.bool(rc.timing.Set)
 	secs, frac := splitDuration(rc.timing.Offset)
 
@@ -231,15 +252,34 @@ func (rc *readStreamer) run() error {
 			return err
 		}
 
+		if err := rvToError(C.uhd_rx_metadata_has_time_spec(rc.rxMetadata, &hasTime)); err != nil {
+			rc.writers.CloseWithError(err)
+			return err
+		}
+		if hasTime {
+			if err := rvToError(C.uhd_rx_metadata_time_spec(rc.rxMetadata, &timeSecs, &timeFrac)); err != nil {
+				rc.writers.CloseWithError(err)
+				return err
+			}
+		}
+		rc.clock.Mark(newDuration(timeSecs, timeFrac), bool(hasTime), int(n))
+
 		for i := 0; i < channels; i++ {
 			ciq := cIQBuffers[i]
 			writer := rc.writers[i]
-			iq, err := yikes.Samples(uintptr(ciq), iqLength, rc.sampleFormat)
+			iq, err := yikes.Samples(uintptr(ciq), iqLength, rc.hostFormat)
 			if err != nil {
 				rc.writers.CloseWithError(uhdRxMetadataError(errCode))
 				return err
 			}
 			iq = iq.Slice(0, int(n))
+			if converted != nil {
+				if _, err := sdr.ConvertBuffer(converted, iq); err != nil {
+					rc.writers.CloseWithError(err)
+					return err
+				}
+				iq = converted.Slice(0, int(n))
+			}
 			_, err = writer.Write(iq)
 			if err != nil {
 				rc.writers.CloseWithError(err)
@@ -259,6 +299,9 @@ type startRxOpts struct {
 }
 
 // StartRx implements the sdr.Sdr interface.
+//
+// The returned ReadCloser is an sdr.TimedReader, which reports the device
+// time of the first sample of each read from the UHD rx metadata.
 func (s *Sdr) StartRx() (sdr.ReadCloser, error) {
 	if len(s.rxChannels) != 1 {
 		return nil, fmt.Errorf("uhd: rx: only one channel can be provided")
@@ -315,21 +358,14 @@ func (s *Sdr) StartCoherentRxAt(d time.Duration) (sdr.ReadClosers, error) {
 func (s *Sdr) startRx(opts startRxOpts) (sdr.ReadClosers, error) {
 	// Before we get down the road of allocating anything, let's check
 	// to ensure that we have a supported SampleFormat.
-	var format string
-	switch s.sampleFormat {
-	case sdr.SampleFormatI8:
-		format = "sc8"
-	case sdr.SampleFormatI16:
-		format = "sc16"
-	case sdr.SampleFormatC64:
-		format = "fc32"
-	default:
-		return nil, fmt.Errorf("uhd: StartRx: unsupported SampleFormat provided")
+	format, err := getStreamFormat(s.sampleFormat)
+	if err != nil {
+		return nil, err
 	}
 
 	channels := len(opts.RxChannels)
-	if channels > 32 {
-		return nil, fmt.Errorf("uhd: wow, that's a lot of channels; this breaks some internals, please fix")
+	if channels > maxRxChannels {
+		return nil, ErrTooManyChannels
 	}
 
 	var (
@@ -347,7 +383,8 @@ func (s *Sdr) startRx(opts startRxOpts) (sdr.ReadClosers, error) {
 	}
 
 	rxStreamerArgsStr := C.CString("")
-	rxStreamFormat := C.CString(format)
+	rxStreamOTWFormat := C.CString(format.otw)
+	rxStreamCPUFormat := C.CString(format.cpu)
 
 	// TODO(paultag): Is it safe to free these even though they were passed
 	// into a constructor for the rx streamer?
@@ -358,7 +395,8 @@ func (s *Sdr) startRx(opts startRxOpts) (sdr.ReadClosers, error) {
 	// the error cases in the constructor here a lot easier too.
 	defer C.free(unsafe.Pointer(rxStreamerChans))
 	defer C.free(unsafe.Pointer(rxStreamerArgsStr))
-	defer C.free(unsafe.Pointer(rxStreamFormat))
+	defer C.free(unsafe.Pointer(rxStreamOTWFormat))
+	defer C.free(unsafe.Pointer(rxStreamCPUFormat))
 
 	if err := rvToError(C.uhd_rx_streamer_make(&rxStreamer)); err != nil {
 		return nil, err
@@ -369,8 +407,8 @@ func (s *Sdr) startRx(opts startRxOpts) (sdr.ReadClosers, error) {
 		return nil, err
 	}
 
-	rxStreamerArgs.otw_format = rxStreamFormat
-	rxStreamerArgs.cpu_format = rxStreamFormat
+	rxStreamerArgs.otw_format = rxStreamOTWFormat
+	rxStreamerArgs.cpu_format = rxStreamCPUFormat
 	rxStreamerArgs.args = rxStreamerArgsStr
 	rxStreamerArgs.channel_list = rxStreamerChans
 	rxStreamerArgs.n_channels = C.int(rxStreamerChanLen)
@@ -403,8 +441,11 @@ func (s *Sdr) startRx(opts startRxOpts) (sdr.ReadClosers, error) {
 	writers := make([]sdr.PipeWriter, len(opts.RxChannels))
 	readers := make(sdr.ReadClosers, len(opts.RxChannels))
 
+	clock := newRxClock(sr)
 	for i := range opts.RxChannels {
-		readers[i], writers[i] = sdr.Pipe(sr, s.sampleFormat)
+		var reader sdr.ReadCloser
+		reader, writers[i] = sdr.Pipe(sr, s.sampleFormat)
+		readers[i] = &timedReader{ReadCloser: reader, clock: clock}
 	}
 
 	rc := &readStreamer{
@@ -413,8 +454,10 @@ func (s *Sdr) startRx(opts startRxOpts) (sdr.ReadClosers, error) {
 		cancel: cancel,
 
 		iqLen: iqLength,
+		clock: clock,
 
 		sampleFormat: s.sampleFormat,
+		hostFormat:   format.host,
 		writers:      writers,
 
 		rxStreamer: rxStreamer,
@@ -0,0 +1,148 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package uhd
+
+import (
+	"sync"
+	"time"
+
+	"hz.tools/sdr"
+)
+
+// rxClockMarks is the number of buffers worth of timestamps kept around.
+// The Pipe between the streamer and readers is unbuffered, so readers are
+// never more than a buffer or two behind.
+const rxClockMarks = 64
+
+// rxClockMark is the device time of the sample at some offset into the
+// stream, as reported in the UHD rx metadata for that buffer.
+type rxClockMark struct {
+	offset int64
+	when   time.Duration
+}
+
+// rxClock keeps track of the device time of each buffer received by the
+// readStreamer, so readers can work out the device time of any sample they
+// read. One rxClock is shared between all channels of a stream, since they
+// are received together.
+type rxClock struct {
+	lock   sync.Mutex
+	period float64
+	total  int64
+	marks  []rxClockMark
+	next   int
+}
+
+func newRxClock(sampleRate uint) *rxClock {
+	return &rxClock{
+		period: 1 / float64(sampleRate),
+		marks:  make([]rxClockMark, 0, rxClockMarks),
+	}
+}
+
+// Mark will account for n samples having been received, the first of which
+// was received at the provided device time. If the buffer had no time, ok
+// is false, and the time is carried on from the last buffer. This must be
+// called before those samples are able to be read.
+func (c *rxClock) Mark(when time.Duration, ok bool, n int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if !ok {
+		when = c.time(c.total)
+	}
+	mark := rxClockMark{offset: c.total, when: when}
+	c.total += int64(n)
+
+	if len(c.marks) < rxClockMarks {
+		c.marks = append(c.marks, mark)
+		return
+	}
+	c.marks[c.next] = mark
+	c.next = (c.next + 1) % rxClockMarks
+}
+
+// Time will return the device time of the sample at the provided offset
+// into the stream.
+func (c *rxClock) Time(offset int64) time.Duration {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.time(offset)
+}
+
+func (c *rxClock) time(offset int64) time.Duration {
+	if len(c.marks) == 0 {
+		return 0
+	}
+
+	// Find the latest buffer that started at or before the offset. If the
+	// offset is older than anything we have, work back from the oldest.
+	var (
+		oldest = c.marks[c.next%len(c.marks)]
+		best   = oldest
+	)
+	for _, mark := range c.marks {
+		if mark.offset <= offset && mark.offset > best.offset {
+			best = mark
+		}
+	}
+	return best.when + time.Duration(float64(offset-best.offset)*c.period*float64(time.Second))
+}
+
+// timedReader is an sdr.TimedReadCloser for one channel of a stream,
+// timestamping samples using the rxClock.
+type timedReader struct {
+	sdr.ReadCloser
+	clock  *rxClock
+	offset int64
+}
+
+// Read implements the sdr.Reader interface.
+func (r *timedReader) Read(s sdr.Samples) (int, error) {
+	n, _, err := r.ReadTimed(s)
+	return n, err
+}
+
+// ReadTimed implements the sdr.TimedReader interface.
+//
+// The returned time is the USRP's device time of the first sample, as a
+// time.Time that far after the Unix epoch. If the device time has been set
+// to the Unix time (for instance, from a GPSDO with SetTimeNextPPS), this is
+// the absolute time the sample was received; otherwise only the difference
+// between times is meaningful. DeviceTime will turn it back into the
+// time.Duration used by the rest of this package.
+func (r *timedReader) ReadTimed(s sdr.Samples) (int, time.Time, error) {
+	n, err := r.ReadCloser.Read(s)
+	when := r.clock.Time(r.offset)
+	r.offset += int64(n)
+	return n, deviceEpoch.Add(when), err
+}
+
+// deviceEpoch is device time zero as a time.Time.
+var deviceEpoch = time.Unix(0, 0)
+
+// DeviceTime will convert a time returned by ReadTimed on a UHD reader back
+// into the USRP device time, as used by StartRxAt or SetTimeNow.
+func DeviceTime(t time.Time) time.Duration {
+	return t.Sub(deviceEpoch)
+}
+
+// vim: foldmethod=marker
@@ -27,14 +27,36 @@ import "C"
 
 import (
 	"fmt"
+	"strings"
 
 	"hz.tools/rf"
 	"hz.tools/sdr"
 	"hz.tools/sdr/debug"
 )
 
+var (
+	// ErrTooManyChannels will be returned if more RX channels are requested
+	// than this package is able to stream at once.
+	ErrTooManyChannels = fmt.Errorf("uhd: too many rx channels requested")
+
+	// ErrTooManyTxChannels will be returned if more TX channels are
+	// requested than this package is able to stream at once.
+	ErrTooManyTxChannels = fmt.Errorf("uhd: too many tx channels requested")
+)
+
+// maxRxChannels is the most channels that can be streamed at once; there
+// are a few fixed-size internals that would need fixing to go beyond this.
+const maxRxChannels = 32
+
+// maxTxChannels is the TX counterpart of maxRxChannels.
+const maxTxChannels = 32
+
 func init() {
 	debug.RegisterRadioDriver("hz.tools/sdr/uhd.Sdr")
+	sdr.RegisterSerialOpener("uhd", func(serial string) (sdr.Sdr, error) {
+		return OpenBySerial(serial, Options{})
+	})
+	sdr.RegisterEnumerator("uhd", sdr.ListEnumerator(List))
 }
 
 // Sdr is a UHD backed Software Defined Radio. This implements the sdr.Sdr
@@ -44,7 +66,7 @@ type Sdr struct {
 	sampleFormat sdr.SampleFormat
 
 	rxChannels []int
-	txChannel  int
+	txChannels []int
 
 	sampleRate   uint
 	bufferLength int
@@ -52,6 +74,15 @@ type Sdr struct {
 	hi sdr.HardwareInfo
 }
 
+// supportedSampleFormats are the SampleFormats UHD can stream, with the
+// default first.
+var supportedSampleFormats = []sdr.SampleFormat{
+	sdr.SampleFormatI16,
+	sdr.SampleFormatI8,
+	sdr.SampleFormatC64,
+	sdr.SampleFormatI12Packed,
+}
+
 // Options contains arguments used to configure the UHD Radio.
 type Options struct {
 	// Args is passed to uhd_usrp_make as device arguments.
@@ -66,18 +97,85 @@ type Options struct {
 	// TxChannel is the channel to use for TX operations.
 	TxChannel int
 
+	// TxChannels contains the channels to be used for TX operations. If
+	// more than one is set, transmit with StartCoherentTx rather than
+	// StartTx.
+	TxChannels []int
+
 	// SampleFormat to be used internally.
 	//
 	// Currently supported types:
 	//   - sdr.SampleFormatI8
 	//   - sdr.SampleFormatI16
 	//   - sdr.SampleFormatC64
+	//   - sdr.SampleFormatI12Packed (sc12 on the wire, see below)
+	//
+	// sdr.SampleFormatI12Packed streams sc12 between the radio and host,
+	// which uses a quarter less USB or Ethernet bandwidth than sc16. UHD
+	// only hands sc12 to the host as sc16, so the driver packs (and unpacks,
+	// for TX) each buffer itself. Not every USRP supports sc12.
 	//
 	SampleFormat sdr.SampleFormat
 
+	// SampleFormats is an ordered list of preferred SampleFormats, most
+	// preferred first, used to pick the SampleFormat if SampleFormat is
+	// not set. The chosen format is returned by Sdr.SampleFormat. If both
+	// are unset, sdr.SampleFormatI16 is used.
+	SampleFormats []sdr.SampleFormat
+
 	// BufferLength is used to set the capacity of the internal BufPipe
 	// to help avoid overruns. If set to 0, this will use a default value.
 	BufferLength int
+
+	// RecvFrameSize and NumRecvFrames are the size in bytes, and number,
+	// of transport frames used to receive samples from the device, passed
+	// to UHD as the recv_frame_size and num_recv_frames device arguments.
+	// Raising NumRecvFrames is usually the first thing to try when seeing
+	// overflows. If set to 0, the UHD default for the transport is used.
+	RecvFrameSize int
+	NumRecvFrames int
+
+	// SendFrameSize and NumSendFrames are the same as RecvFrameSize and
+	// NumRecvFrames, but for transmitting samples (send_frame_size and
+	// num_send_frames).
+	SendFrameSize int
+	NumSendFrames int
+
+	// FPGAImage and FirmwareImage are paths to FPGA and firmware images to
+	// load, rather than the images libuhd finds in its images directory,
+	// passed to UHD as the fpga and fw device arguments. Not all devices
+	// support loading images this way.
+	FPGAImage     string
+	FirmwareImage string
+}
+
+// args will return the device arguments to pass to uhd_usrp_make, which is
+// Args plus any transport arguments set in the Options.
+func (opts Options) args() string {
+	args := []string{}
+	if opts.Args != "" {
+		args = append(args, opts.Args)
+	}
+	for _, arg := range []struct {
+		name  string
+		value int
+	}{
+		{"recv_frame_size", opts.RecvFrameSize},
+		{"num_recv_frames", opts.NumRecvFrames},
+		{"send_frame_size", opts.SendFrameSize},
+		{"num_send_frames", opts.NumSendFrames},
+	} {
+		if arg.value != 0 {
+			args = append(args, fmt.Sprintf("%s=%d", arg.name, arg.value))
+		}
+	}
+	if opts.FPGAImage != "" {
+		args = append(args, "fpga="+opts.FPGAImage)
+	}
+	if opts.FirmwareImage != "" {
+		args = append(args, "fw="+opts.FirmwareImage)
+	}
+	return strings.Join(args, ",")
 }
 
 func (opts Options) getBufferLength() int {
@@ -87,9 +185,6 @@ func (opts Options) getBufferLength() int {
 	return opts.BufferLength
 }
 
-// TODO: add in support for uhd_usrp_find to enumerate UHD
-// devices
-
 // Open will connect to an USRP Radio.
 func Open(opts Options) (*Sdr, error) {
 	var (
@@ -99,12 +194,41 @@ func Open(opts Options) (*Sdr, error) {
 		blen = 256
 	)
 
-	if err := rvToError(C.uhd_usrp_make(&usrp, C.CString(opts.Args))); err != nil {
+	// Check the channel configuration before opening the device, so a
+	// configuration mistake doesn't leave a USRP handle hanging around.
+	var rxChannels = []int{opts.RxChannel}
+	if len(opts.RxChannels) > 0 {
+		if opts.RxChannel != 0 {
+			return nil, fmt.Errorf("uhd: both RxChannel and RxChannels are set")
+		}
+		rxChannels = opts.RxChannels
+	}
+	if len(rxChannels) > maxRxChannels {
+		return nil, ErrTooManyChannels
+	}
+
+	var txChannels = []int{opts.TxChannel}
+	if len(opts.TxChannels) > 0 {
+		if opts.TxChannel != 0 {
+			return nil, fmt.Errorf("uhd: both TxChannel and TxChannels are set")
+		}
+		txChannels = opts.TxChannels
+	}
+	if len(txChannels) > maxTxChannels {
+		return nil, ErrTooManyTxChannels
+	}
+
+	preferred := opts.SampleFormats
+	if opts.SampleFormat != 0 {
+		preferred = []sdr.SampleFormat{opts.SampleFormat}
+	}
+	sampleFormat, err := sdr.NegotiateSampleFormat(preferred, supportedSampleFormats)
+	if err != nil {
 		return nil, err
 	}
 
-	if opts.SampleFormat == 0 {
-		opts.SampleFormat = sdr.SampleFormatI16
+	if err := rvToError(C.uhd_usrp_make(&usrp, C.CString(opts.args()))); err != nil {
+		return nil, makeError(err)
 	}
 
 	if err := rvToError(C.uhd_usrp_get_mboard_name(
@@ -126,24 +250,33 @@ func Open(opts Options) (*Sdr, error) {
 		Serial:       "", // TODO(paultag): Do this
 	}
 
-	var rxChannels = []int{opts.RxChannel}
-	if len(opts.RxChannels) > 0 {
-		if opts.RxChannel != 0 {
-			return nil, fmt.Errorf("uhd: both RxChannel and RxChannels are set")
-		}
-		rxChannels = opts.RxChannels
-	}
-
 	return &Sdr{
 		handle:       &usrp,
-		sampleFormat: opts.SampleFormat,
+		sampleFormat: sampleFormat,
 		rxChannels:   rxChannels,
-		txChannel:    opts.TxChannel,
+		txChannels:   txChannels,
 		hi:           hi,
 		bufferLength: opts.getBufferLength(),
 	}, nil
 }
 
+// OpenBySerial will connect to the USRP Radio with the provided Serial,
+// by adding a "serial=" device argument to any Args in the Options.
+func OpenBySerial(serial string, opts Options) (*Sdr, error) {
+	args := "serial=" + serial
+	if opts.Args != "" {
+		args = opts.Args + "," + args
+	}
+	opts.Args = args
+
+	s, err := Open(opts)
+	if err != nil {
+		return nil, err
+	}
+	s.hi.Serial = serial
+	return s, nil
+}
+
 // Close will release all held handles.
 func (s *Sdr) Close() error {
 	return rvToError(C.uhd_usrp_free(s.handle))
@@ -195,12 +328,17 @@ func (s *Sdr) SetCenterFrequencyTX(freq rf.Hz) error {
 	tuneRequest.rf_freq_policy = C.UHD_TUNE_REQUEST_POLICY_AUTO
 	tuneRequest.dsp_freq_policy = C.UHD_TUNE_REQUEST_POLICY_AUTO
 
-	return rvToError(C.uhd_usrp_set_tx_freq(
-		*s.handle,
-		&tuneRequest,
-		C.size_t(s.txChannel),
-		&tuneResult,
-	))
+	for _, txChannel := range s.txChannels {
+		if err := rvToError(C.uhd_usrp_set_tx_freq(
+			*s.handle,
+			&tuneRequest,
+			C.size_t(txChannel),
+			&tuneResult,
+		)); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // SetCenterFrequency implements the sdr.Sdr interface.
@@ -213,8 +351,14 @@ func (s *Sdr) SetCenterFrequency(freq rf.Hz) error {
 
 // SetSampleRate implements the sdr.Sdr interface.
 func (s *Sdr) SetSampleRate(rate uint) error {
-	if err := rvToError(C.uhd_usrp_set_tx_rate(*s.handle, C.double(rate), C.size_t(s.txChannel))); err != nil {
-		return err
+	for _, txChannel := range s.txChannels {
+		if err := rvToError(C.uhd_usrp_set_tx_rate(
+			*s.handle,
+			C.double(rate),
+			C.size_t(txChannel),
+		)); err != nil {
+			return err
+		}
 	}
 	for _, rxChannel := range s.rxChannels {
 		if err := rvToError(C.uhd_usrp_set_rx_rate(
@@ -241,11 +385,87 @@ func (s *Sdr) GetSampleRate() (uint, error) {
 	return uint(rate), nil
 }
 
+// SetBandwidth implements the sdr.BandwidthSetter interface, setting the
+// analog filter bandwidth of every RX and TX channel.
+func (s *Sdr) SetBandwidth(bw rf.Hz) error {
+	for _, rxChannel := range s.rxChannels {
+		if err := rvToError(C.uhd_usrp_set_rx_bandwidth(
+			*s.handle,
+			C.double(bw),
+			C.size_t(rxChannel),
+		)); err != nil {
+			return err
+		}
+	}
+	for _, txChannel := range s.txChannels {
+		if err := rvToError(C.uhd_usrp_set_tx_bandwidth(
+			*s.handle,
+			C.double(bw),
+			C.size_t(txChannel),
+		)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetBandwidth implements the sdr.BandwidthSetter interface, returning the
+// RX analog filter bandwidth.
+func (s *Sdr) GetBandwidth() (rf.Hz, error) {
+	var bw C.double
+	for _, rxChannel := range s.rxChannels {
+		if err := rvToError(C.uhd_usrp_get_rx_bandwidth(*s.handle, C.size_t(rxChannel), &bw)); err != nil {
+			return rf.Hz(0), err
+		}
+	}
+	return rf.Hz(bw), nil
+}
+
 // SampleFormat implements the sdr.Sdr interface.
 func (s *Sdr) SampleFormat() sdr.SampleFormat {
 	return s.sampleFormat
 }
 
+// SampleRateRanges implements the sdr.SampleRateRanger interface, using the
+// rx rates of the first rx channel.
+func (s *Sdr) SampleRateRanges() ([]sdr.SampleRateRange, error) {
+	var (
+		rates C.uhd_meta_range_handle
+		size  C.size_t
+		rng   C.uhd_range_t
+	)
+
+	if err := rvToError(C.uhd_meta_range_make(&rates)); err != nil {
+		return nil, err
+	}
+	defer C.uhd_meta_range_free(&rates)
+
+	if err := rvToError(C.uhd_usrp_get_rx_rates(
+		*s.handle,
+		C.size_t(s.rxChannels[0]),
+		rates,
+	)); err != nil {
+		return nil, err
+	}
+
+	if err := rvToError(C.uhd_meta_range_size(rates, &size)); err != nil {
+		return nil, err
+	}
+
+	ret := make([]sdr.SampleRateRange, int(size))
+	for i := range ret {
+		if err := rvToError(C.uhd_meta_range_at(rates, C.size_t(i), &rng)); err != nil {
+			return nil, err
+		}
+		ret[i] = sdr.SampleRateRange{
+			Min:  uint(rng.start),
+			Max:  uint(rng.stop),
+			Step: uint(rng.step),
+		}
+	}
+	return ret, nil
+}
+
 // HardwareInfo implements the sdr.Sdr interface.
 func (s *Sdr) HardwareInfo() sdr.HardwareInfo {
 	return s.hi
@@ -0,0 +1,141 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package uhd
+
+// #cgo pkg-config: uhd
+//
+// #include <uhd.h>
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+
+	"hz.tools/sdr"
+)
+
+// readSensor will read the named sensor into the TelemetryReadings under
+// the provided key, based on its type and unit. Sensors that aren't a lock
+// state, temperature or voltage are skipped.
+func readSensor(
+	readings *sdr.TelemetryReadings,
+	key, name string,
+	fn func(*C.char, *C.uhd_sensor_value_handle) error,
+) error {
+	var (
+		value    C.uhd_sensor_value_handle
+		dataType C.uhd_sensor_value_data_type_t
+		buf      [64]C.char
+		blen     = 64
+	)
+
+	if err := rvToError(C.uhd_sensor_value_make(&value)); err != nil {
+		return err
+	}
+	defer C.uhd_sensor_value_free(&value)
+
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+
+	if err := fn(cName, &value); err != nil {
+		return err
+	}
+
+	if err := rvToError(C.uhd_sensor_value_data_type(value, &dataType)); err != nil {
+		return err
+	}
+
+	switch dataType {
+	case C.UHD_SENSOR_VALUE_BOOLEAN:
+		var locked C.bool
+		if err := rvToError(C.uhd_sensor_value_to_bool(value, &locked)); err != nil {
+			return err
+		}
+		readings.Locked[key] = bool(locked)
+	case C.UHD_SENSOR_VALUE_REALNUM, C.UHD_SENSOR_VALUE_INTEGER:
+		var v C.double
+		if err := rvToError(C.uhd_sensor_value_to_realnum(value, &v)); err != nil {
+			return err
+		}
+		if err := rvToError(C.uhd_sensor_value_unit(value, &buf[0], C.size_t(blen))); err != nil {
+			return err
+		}
+		switch C.GoString(&buf[0]) {
+		case "C":
+			readings.Temperatures[key] = float64(v)
+		case "V":
+			readings.Voltages[key] = float64(v)
+		}
+	}
+	return nil
+}
+
+// ReadTelemetry implements the sdr.Telemetry interface.
+//
+// This will read all motherboard sensors, and the sensors for each rx
+// channel (prefixed with "rxN:", where N is the channel), such as
+// "ref_locked", "gps_locked", "temp" or "lo_locked". Which sensors exist
+// depends on the device and daughterboards.
+func (s *Sdr) ReadTelemetry() (sdr.TelemetryReadings, error) {
+	readings := sdr.TelemetryReadings{
+		Temperatures: map[string]float64{},
+		Voltages:     map[string]float64{},
+		Locked:       map[string]bool{},
+	}
+
+	// TODO(paultag): Multiple Mboards?
+	names, err := getStringVector(func(names *C.uhd_string_vector_handle) error {
+		return rvToError(C.uhd_usrp_get_mboard_sensor_names(*s.handle, 0, names))
+	})
+	if err != nil {
+		return readings, err
+	}
+	for _, name := range names {
+		if err := readSensor(&readings, name, name, func(cName *C.char, value *C.uhd_sensor_value_handle) error {
+			return rvToError(C.uhd_usrp_get_mboard_sensor(*s.handle, cName, 0, value))
+		}); err != nil {
+			return readings, err
+		}
+	}
+
+	for _, rxChannel := range s.rxChannels {
+		channel := C.size_t(rxChannel)
+		names, err := getStringVector(func(names *C.uhd_string_vector_handle) error {
+			return rvToError(C.uhd_usrp_get_rx_sensor_names(*s.handle, channel, names))
+		})
+		if err != nil {
+			return readings, err
+		}
+
+		for _, name := range names {
+			key := fmt.Sprintf("rx%d:%s", rxChannel, name)
+			if err := readSensor(&readings, key, name, func(cName *C.char, value *C.uhd_sensor_value_handle) error {
+				return rvToError(C.uhd_usrp_get_rx_sensor(*s.handle, cName, channel, value))
+			}); err != nil {
+				return readings, err
+			}
+		}
+	}
+
+	return readings, nil
+}
+
+// vim: foldmethod=marker
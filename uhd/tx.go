@@ -37,24 +37,56 @@ import (
 	"hz.tools/sdr/yikes"
 )
 
-// writeCloser contains all the allocated structs to be used by the writeer
-// goroutine and close function.
+// writeStreamer contains all the allocated structs to be used by the
+// writer goroutine and close function.
 //
 // Most of this stuff isn't stuff that really belongs in here, but the
 // allocation lifecycle needs to be tied to this struct.
-type writeCloser struct {
+type writeStreamer struct {
+	lock   sync.Mutex
+	refs   int
 	closed bool
 	ctx    context.Context
 	cancel context.CancelFunc
 	wg     sync.WaitGroup
 
-	pipe         *stream.BufPipe2
+	pipes        bufPipes
 	sampleFormat sdr.SampleFormat
+	hostFormat   sdr.SampleFormat
 
 	txStreamer C.uhd_tx_streamer_handle
 	txMetadata C.uhd_tx_metadata_handle
 }
 
+type bufPipes []*stream.BufPipe2
+
+func (bp bufPipes) CloseWithError(e error) error {
+	var ret error
+	for _, el := range bp {
+		if err := el.CloseWithError(e); err != nil {
+			ret = err
+		}
+	}
+	return ret
+}
+
+func (bp bufPipes) Close() error {
+	var ret error
+	for _, el := range bp {
+		if err := el.Close(); err != nil {
+			ret = err
+		}
+	}
+	return ret
+}
+
+// writeCloser is the sdr.WriteCloser for one channel of a writeStreamer.
+type writeCloser struct {
+	streamer *writeStreamer
+	pipe     *stream.BufPipe2
+	once     sync.Once
+}
+
 // Write implements the sdr.Writer interface
 func (wc *writeCloser) Write(iq sdr.Samples) (int, error) {
 	return wc.pipe.Write(iq)
@@ -67,46 +99,70 @@ func (wc *writeCloser) SampleRate() uint {
 
 // SampleFormat implements the sdr.Writer interface
 func (wc *writeCloser) SampleFormat() sdr.SampleFormat {
-	return wc.sampleFormat
+	return wc.streamer.sampleFormat
 }
 
 // Close implements the sdr.WriteCloser interface
+//
+// For a coherent transmit, closing any one channel will end the transmit
+// on every channel once the samples already written have been sent. The
+// UHD resources are released once every channel has been closed.
 func (wc *writeCloser) Close() error {
-	if wc.closed {
+	var err error
+	wc.once.Do(func() {
+		wc.pipe.Close()
+		err = wc.streamer.release()
+	})
+	return err
+}
+
+// release will drop one reference to the writeStreamer, tearing it down
+// once the last channel is closed.
+func (ws *writeStreamer) release() error {
+	ws.lock.Lock()
+	defer ws.lock.Unlock()
+
+	ws.refs--
+	if ws.refs > 0 {
+		return nil
+	}
+
+	if ws.closed {
 		// Avoid double-free'ing or issuing a stream command if we've been
 		// called before. This is really a bug, but we wanna be fairly
 		// defensive here.
 		return nil
 	}
 
-	wc.pipe.Close()
-	wc.cancel()
+	ws.pipes.Close()
+	ws.cancel()
 
 	// Wait until pipe is read fully, and we're sure the goroutine is stopped.
 	// This means that we can free the resouwces below, otherwise we risk a
 	// SEGV.
-	wc.wg.Wait()
+	ws.wg.Wait()
 
-	C.uhd_tx_streamer_free(&wc.txStreamer)
-	C.uhd_tx_metadata_free(&wc.txMetadata)
+	C.uhd_tx_streamer_free(&ws.txStreamer)
+	C.uhd_tx_metadata_free(&ws.txMetadata)
 
 	// TODO(paultag): Literally any error checking at all :)
 
-	wc.closed = true
+	ws.closed = true
 	return nil
 }
 
-// run is a goroutine to handle copying IQ data from the UHD device
-// to the Pipe contained inside the writeCloser.
-func (wc *writeCloser) run() error {
-	defer wc.pipe.Close()
-	defer wc.cancel()
-	defer wc.wg.Done()
+// run is a goroutine to handle copying IQ data from the Pipes contained
+// inside the writeStreamer to the UHD device.
+func (ws *writeStreamer) run() error {
+	defer ws.pipes.Close()
+	defer ws.cancel()
+	defer ws.wg.Done()
+	defer sdr.RecoverDriverPanic(ws.pipes)
 
 	var ciqLen C.size_t
 
-	if err := rvToError(C.uhd_tx_streamer_max_num_samps(wc.txStreamer, &ciqLen)); err != nil {
-		wc.pipe.CloseWithError(err)
+	if err := rvToError(C.uhd_tx_streamer_max_num_samps(ws.txStreamer, &ciqLen)); err != nil {
+		ws.pipes.CloseWithError(err)
 		return err
 	}
 
@@ -115,28 +171,48 @@ func (wc *writeCloser) run() error {
 		i   int
 		err error
 
+		channels = len(ws.pipes)
 		iqLength = int(ciqLen)
-		iqSize   = iqLength * wc.sampleFormat.Size()
+		iqSize   = iqLength * ws.hostFormat.Size()
 		ciqSize  = C.size_t(iqSize)
-		ciq      = C.malloc(C.size_t(ciqSize))
+
+		ciqs  = make([]unsafe.Pointer, channels)
+		iqs   = make([]sdr.Samples, channels)
+		hosts = make([]sdr.Samples, channels)
 	)
 
-	iq, err := sdr.MakeSamples(wc.sampleFormat, iqLength)
-	if err != nil {
-		wc.pipe.CloseWithError(err)
-		return err
-	}
+	for c := 0; c < channels; c++ {
+		ciqs[c] = C.malloc(ciqSize)
+		defer C.free(ciqs[c])
 
-	// Blank out the C memory
-	copy(yikes.GoBytes(uintptr(unsafe.Pointer(ciq)), iqSize),
-		sdr.MustUnsafeSamplesAsBytes(iq))
+		iqs[c], err = sdr.MakeSamples(ws.sampleFormat, iqLength)
+		if err != nil {
+			ws.pipes.CloseWithError(err)
+			return err
+		}
+
+		// If UHD can't take samples in our SampleFormat, we convert each
+		// buffer into this one before sending it.
+		hosts[c] = iqs[c]
+		if ws.hostFormat != ws.sampleFormat {
+			hosts[c], err = sdr.MakeSamples(ws.hostFormat, iqLength)
+			if err != nil {
+				ws.pipes.CloseWithError(err)
+				return err
+			}
+		}
+
+		// Blank out the C memory
+		copy(yikes.GoBytes(uintptr(ciqs[c]), iqSize),
+			sdr.MustUnsafeSamplesAsBytes(hosts[c]))
+	}
 
 	// before we do anything, let's send a buffer to let
 	// the hardware warm up and get something to chew on
 	// while we get going here
 	for i := 0; i < 20; i++ {
 		if err := rvToError(C.uhd_tx_streamer_send(
-			wc.txStreamer, &ciq, ciqLen, &wc.txMetadata,
+			ws.txStreamer, &ciqs[0], ciqLen, &ws.txMetadata,
 			0.1, &cn,
 		)); err != nil {
 			return err
@@ -145,25 +221,45 @@ func (wc *writeCloser) run() error {
 
 	for {
 		i++
-		n, rferr := sdr.ReadFull(wc.pipe, iq)
 
-		if n != iq.Length() {
-			if rferr == nil {
+		// Every channel has to be sent in the same call to keep them
+		// aligned, so read a buffer from each one first. If any channel
+		// comes up short, only the samples every channel has are sent.
+		var (
+			n     = iqLength
+			rferr error
+		)
+		for c := 0; c < channels; c++ {
+			rn, err := sdr.ReadFull(ws.pipes[c], iqs[c])
+			if rn != iqs[c].Length() && err == nil {
 				// this is bad, something is broken
 				err := fmt.Errorf("uhd: ReadFull was short")
-				wc.pipe.CloseWithError(err)
+				ws.pipes.CloseWithError(err)
 				return err
 			}
-		}
+			if rn < n {
+				n = rn
+			}
+			if err != nil && rferr == nil {
+				rferr = err
+			}
+
+			if ws.hostFormat != ws.sampleFormat {
+				if _, err := sdr.ConvertBuffer(hosts[c], iqs[c]); err != nil {
+					ws.pipes.CloseWithError(err)
+					return err
+				}
+			}
 
-		copy(yikes.GoBytes(uintptr(unsafe.Pointer(ciq)), iqSize),
-			sdr.MustUnsafeSamplesAsBytes(iq))
+			copy(yikes.GoBytes(uintptr(ciqs[c]), iqSize),
+				sdr.MustUnsafeSamplesAsBytes(hosts[c]))
+		}
 
 		if err := rvToError(C.uhd_tx_streamer_send(
-			wc.txStreamer, &ciq, C.size_t(n), &wc.txMetadata,
+			ws.txStreamer, &ciqs[0], C.size_t(n), &ws.txMetadata,
 			0.1, &cn,
 		)); err != nil {
-			// wc.pipe.CloseWithError(err)
+			// ws.pipes.CloseWithError(err)
 			return err
 		}
 
@@ -177,58 +273,101 @@ func (wc *writeCloser) run() error {
 
 // StartTxAt will start TX at the provided Duration offset.
 func (s *Sdr) StartTxAt(d time.Duration) (sdr.WriteCloser, error) {
+	if len(s.txChannels) != 1 {
+		return nil, fmt.Errorf("uhd: tx: only one channel can be provided")
+	}
+
 	opts := startTxOpts{
 		BufferLength: s.bufferLength,
+		TxChannels:   s.txChannels,
 	}
 	opts.Timing.Set = true
 	opts.Timing.Offset = d
-	return s.startTx(opts)
+	wcs, err := s.startTx(opts)
+	if err != nil {
+		return nil, err
+	}
+	return wcs[0], nil
 }
 
 // StartTx implements the sdr.Sdr interface.
 func (s *Sdr) StartTx() (sdr.WriteCloser, error) {
+	if len(s.txChannels) != 1 {
+		return nil, fmt.Errorf("uhd: tx: only one channel can be provided")
+	}
+
 	opts := startTxOpts{
 		BufferLength: s.bufferLength,
+		TxChannels:   s.txChannels,
 	}
+	wcs, err := s.startTx(opts)
+	if err != nil {
+		return nil, err
+	}
+	return wcs[0], nil
+}
+
+// StartCoherentTx will start a coherent TX operation on every configured
+// TX channel. As a byproduct, this will reset the clock.
+func (s *Sdr) StartCoherentTx() (sdr.WriteClosers, error) {
+	if err := s.SetTimeNow(time.Duration(0)); err != nil {
+		return nil, err
+	}
+	return s.StartCoherentTxAt(time.Second)
+}
+
+// StartCoherentTxAt will start a coherent TX operation, sync'd at the
+// provided offset. The returned WriteClosers are in the same order as the
+// TxChannels, and should be written to in lockstep, since a buffer isn't
+// sent until every channel has one ready.
+func (s *Sdr) StartCoherentTxAt(d time.Duration) (sdr.WriteClosers, error) {
+	opts := startTxOpts{
+		BufferLength: s.bufferLength,
+		TxChannels:   s.txChannels,
+	}
+	opts.Timing.Set = true
+	opts.Timing.Offset = d
 	return s.startTx(opts)
 }
 
 type startTxOpts struct {
 	BufferLength int
+	TxChannels   []int
 	Timing       struct {
 		Set    bool
 		Offset time.Duration
 	}
 }
 
-func (s *Sdr) startTx(opts startTxOpts) (sdr.WriteCloser, error) {
+func (s *Sdr) startTx(opts startTxOpts) (sdr.WriteClosers, error) {
 	// Before we get down the road of allocating anything, let's check
 	// to ensure that we have a supported SampleFormat.
-	var format string
-	switch s.sampleFormat {
-	case sdr.SampleFormatI8:
-		format = "sc8"
-	case sdr.SampleFormatI16:
-		format = "sc16"
-	case sdr.SampleFormatC64:
-		format = "fc32"
-	default:
-		return nil, fmt.Errorf("uhd: StartRx: unsupported SampleFormat provided")
+	format, err := getStreamFormat(s.sampleFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	channels := len(opts.TxChannels)
+	if channels > maxTxChannels {
+		return nil, ErrTooManyTxChannels
 	}
 
 	var (
 		txStreamerArgs    C.uhd_stream_args_t
 		txStreamer        C.uhd_tx_streamer_handle
 		txMetadata        C.uhd_tx_metadata_handle
-		txStreamerChanLen = C.size_t(1)
-		txStreamerChans   = (*C.size_t)(C.malloc(C.size_t(unsafe.Sizeof(C.size_t(0) * txStreamerChanLen))))
+		txStreamerChanLen = C.size_t(channels)
+		txStreamerChans   = (*C.size_t)(C.malloc(C.size_t(unsafe.Sizeof(C.size_t(0)) * uintptr(channels))))
+		txStreamerGoChans = (*[1 << 30]C.size_t)(unsafe.Pointer(txStreamerChans))[:channels:channels]
 	)
 
 	ctx, cancel := context.WithCancel(context.Background())
-
-	*txStreamerChans = C.size_t(s.txChannel)
+	for i, c := range opts.TxChannels {
+		txStreamerGoChans[i] = C.size_t(c)
+	}
 	txStreamerArgsStr := C.CString("")
-	txStreamFormat := C.CString(format)
+	txStreamOTWFormat := C.CString(format.otw)
+	txStreamCPUFormat := C.CString(format.cpu)
 
 	// TODO(paultag): Is it safe to free these even though they were passed
 	// into a constructor for the tx streamer?
@@ -239,7 +378,8 @@ func (s *Sdr) startTx(opts startTxOpts) (sdr.WriteCloser, error) {
 	// the error cases in the constructor here a lot easier too.
 	defer C.free(unsafe.Pointer(txStreamerChans))
 	defer C.free(unsafe.Pointer(txStreamerArgsStr))
-	defer C.free(unsafe.Pointer(txStreamFormat))
+	defer C.free(unsafe.Pointer(txStreamOTWFormat))
+	defer C.free(unsafe.Pointer(txStreamCPUFormat))
 
 	if err := rvToError(C.uhd_tx_streamer_make(&txStreamer)); err != nil {
 		return nil, err
@@ -253,8 +393,8 @@ func (s *Sdr) startTx(opts startTxOpts) (sdr.WriteCloser, error) {
 		return nil, err
 	}
 
-	txStreamerArgs.otw_format = txStreamFormat
-	txStreamerArgs.cpu_format = txStreamFormat
+	txStreamerArgs.otw_format = txStreamOTWFormat
+	txStreamerArgs.cpu_format = txStreamCPUFormat
 	txStreamerArgs.args = txStreamerArgsStr
 	txStreamerArgs.channel_list = txStreamerChans
 	txStreamerArgs.n_channels = C.int(txStreamerChanLen)
@@ -278,27 +418,41 @@ func (s *Sdr) startTx(opts startTxOpts) (sdr.WriteCloser, error) {
 
 	bufferLength := opts.BufferLength
 
-	bp, err := stream.NewBufPipe2(bufferLength, sr, s.sampleFormat)
-	if err != nil {
-		C.uhd_tx_streamer_free(&txStreamer)
-		C.uhd_tx_metadata_free(&txMetadata)
-		return nil, err
+	pipes := make(bufPipes, channels)
+	for i := range pipes {
+		pipes[i], err = stream.NewBufPipe2(bufferLength, sr, s.sampleFormat)
+		if err != nil {
+			C.uhd_tx_streamer_free(&txStreamer)
+			C.uhd_tx_metadata_free(&txMetadata)
+			return nil, err
+		}
 	}
 
-	wc := &writeCloser{
+	ws := &writeStreamer{
 		wg:     sync.WaitGroup{},
 		ctx:    ctx,
 		cancel: cancel,
+		refs:   channels,
 
 		sampleFormat: s.sampleFormat,
-		pipe:         bp,
+		hostFormat:   format.host,
+		pipes:        pipes,
 
 		txStreamer: txStreamer,
 		txMetadata: txMetadata,
 	}
-	wc.wg.Add(1)
-	go wc.run()
-	return wc, nil
+
+	writers := make(sdr.WriteClosers, channels)
+	for i := range writers {
+		writers[i] = &writeCloser{
+			streamer: ws,
+			pipe:     pipes[i],
+		}
+	}
+
+	ws.wg.Add(1)
+	go ws.run()
+	return writers, nil
 }
 
 // vim: foldmethod=marker
@@ -0,0 +1,328 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package watchdog
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"hz.tools/sdr"
+)
+
+var (
+	// ErrStalled will be returned by a Guard'ed function when a stage of
+	// the pipeline stalled, and the pipeline was torn down.
+	ErrStalled = fmt.Errorf("watchdog: pipeline stalled")
+)
+
+// Event is raised when a stage of the pipeline has stalled.
+type Event struct {
+	// Stage is the name the stage was registered under.
+	Stage string
+
+	// Blocked is true if the stage is currently inside a call to Read or
+	// Write, which means it's likely the stage that's stuck, rather than
+	// a stage that's waiting on a stuck stage.
+	Blocked bool
+
+	// Idle is how long it's been since the stage last made progress.
+	Idle time.Duration
+}
+
+// Config controls the Watchdog.
+type Config struct {
+	// Timeout is how long a stage may go without making progress before
+	// it's considered stalled. If 0, this will default to 5 seconds.
+	Timeout time.Duration
+
+	// Interval is how often the stages are checked. If 0, this will
+	// default to a quarter of the Timeout.
+	Interval time.Duration
+
+	// Grace is how long Guard will wait for the function to return after
+	// tearing down a stalled pipeline, before giving up on it and
+	// returning anyway. If 0, this will default to 5 seconds.
+	Grace time.Duration
+
+	// OnStall, if set, is called for each stalled stage. Each stage is
+	// only reported once, until it makes progress again.
+	OnStall func(Event)
+}
+
+func (c Config) getTimeout() time.Duration {
+	if c.Timeout == 0 {
+		return 5 * time.Second
+	}
+	return c.Timeout
+}
+
+func (c Config) getInterval() time.Duration {
+	if c.Interval == 0 {
+		return c.getTimeout() / 4
+	}
+	return c.Interval
+}
+
+func (c Config) getGrace() time.Duration {
+	if c.Grace == 0 {
+		return 5 * time.Second
+	}
+	return c.Grace
+}
+
+// stage is the progress of a single Reader or Writer.
+type stage struct {
+	name   string
+	closer sdr.Closer
+
+	lock         sync.Mutex
+	calls        int
+	lastProgress time.Time
+	reported     bool
+}
+
+func (s *stage) enter() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.calls++
+}
+
+func (s *stage) exit(n int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.calls--
+	if n > 0 {
+		s.lastProgress = time.Now()
+		s.reported = false
+	}
+}
+
+// check will return an Event if the stage has stalled, and hasn't been
+// reported yet.
+func (s *stage) check(now time.Time, timeout time.Duration) (Event, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	idle := now.Sub(s.lastProgress)
+	if s.reported || idle < timeout {
+		return Event{}, false
+	}
+	s.reported = true
+	return Event{Stage: s.name, Blocked: s.calls > 0, Idle: idle}, true
+}
+
+// Watchdog tracks the progress of a set of pipeline stages.
+type Watchdog struct {
+	cfg Config
+
+	lock    sync.Mutex
+	stages  []*stage
+	onStall []func(Event)
+}
+
+// New will create a new Watchdog with no stages.
+func New(cfg Config) *Watchdog {
+	w := &Watchdog{cfg: cfg}
+	if cfg.OnStall != nil {
+		w.onStall = append(w.onStall, cfg.OnStall)
+	}
+	return w
+}
+
+func (w *Watchdog) add(name string, closer sdr.Closer) *stage {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	s := &stage{name: name, closer: closer, lastProgress: time.Now()}
+	w.stages = append(w.stages, s)
+	return s
+}
+
+type reader struct {
+	sdr.Reader
+	stage *stage
+}
+
+func (r reader) Read(s sdr.Samples) (int, error) {
+	r.stage.enter()
+	n, err := r.Reader.Read(s)
+	r.stage.exit(n)
+	return n, err
+}
+
+type readCloser struct {
+	reader
+	sdr.Closer
+}
+
+type writer struct {
+	sdr.Writer
+	stage *stage
+}
+
+func (w writer) Write(s sdr.Samples) (int, error) {
+	w.stage.enter()
+	n, err := w.Writer.Write(s)
+	w.stage.exit(n)
+	return n, err
+}
+
+type writeCloser struct {
+	writer
+	sdr.Closer
+}
+
+// Reader will return a Reader that tracks the progress of reads from r,
+// under the provided stage name.
+func (w *Watchdog) Reader(name string, r sdr.Reader) sdr.Reader {
+	closer, _ := r.(sdr.Closer)
+	return reader{Reader: r, stage: w.add(name, closer)}
+}
+
+// ReadCloser is the same as Reader, but for an sdr.ReadCloser. If the
+// pipeline is torn down by Guard, rc will be closed.
+func (w *Watchdog) ReadCloser(name string, rc sdr.ReadCloser) sdr.ReadCloser {
+	return readCloser{reader: reader{Reader: rc, stage: w.add(name, rc)}, Closer: rc}
+}
+
+// Writer will return a Writer that tracks the progress of writes to wr,
+// under the provided stage name.
+func (w *Watchdog) Writer(name string, wr sdr.Writer) sdr.Writer {
+	closer, _ := wr.(sdr.Closer)
+	return writer{Writer: wr, stage: w.add(name, closer)}
+}
+
+// WriteCloser is the same as Writer, but for an sdr.WriteCloser. If the
+// pipeline is torn down by Guard, wc will be closed.
+func (w *Watchdog) WriteCloser(name string, wc sdr.WriteCloser) sdr.WriteCloser {
+	return writeCloser{writer: writer{Writer: wc, stage: w.add(name, wc)}, Closer: wc}
+}
+
+// Check will check every stage once, and return an Event for each stage
+// that has newly stalled, sorted with blocked stages first. Run will call
+// this every Interval; this is exported mostly for testing.
+func (w *Watchdog) Check() []Event {
+	w.lock.Lock()
+	stages := append([]*stage{}, w.stages...)
+	w.lock.Unlock()
+
+	var (
+		now     = time.Now()
+		timeout = w.cfg.getTimeout()
+		events  = []Event{}
+	)
+	for _, s := range stages {
+		if event, ok := s.check(now, timeout); ok {
+			events = append(events, event)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Blocked && !events[j].Blocked
+	})
+	return events
+}
+
+// Run will check the stages every Interval, invoking OnStall for each
+// stalled stage, until the context is canceled.
+func (w *Watchdog) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.cfg.getInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		for _, event := range w.Check() {
+			for _, fn := range w.onStall {
+				fn(event)
+			}
+		}
+	}
+}
+
+// closeAll will close every stage with a Closer, to unblock any stuck Read
+// or Write calls.
+func (w *Watchdog) closeAll() {
+	w.lock.Lock()
+	stages := append([]*stage{}, w.stages...)
+	w.lock.Unlock()
+	for _, s := range stages {
+		if s.closer != nil {
+			s.closer.Close()
+		}
+	}
+}
+
+// Guard will wrap fn (which builds and runs a pipeline, registering its
+// stages with the provided Watchdog) into a function suitable for use as a
+// supervisor.Pipeline's Run.
+//
+// If any stage stalls, the context passed to fn is canceled, every stage
+// with a Close method is closed (to unblock stuck calls), and ErrStalled is
+// returned. If fn doesn't return within the Grace period, Guard returns
+// anyway, and the stuck goroutine is abandoned, so that the pipeline can be
+// rebuilt.
+func Guard(cfg Config, fn func(context.Context, *Watchdog) error) func(context.Context) error {
+	return func(ctx context.Context) error {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		var (
+			w       = New(cfg)
+			stalled = make(chan struct{}, 1)
+			done    = make(chan error, 1)
+		)
+		w.onStall = append(w.onStall, func(Event) {
+			select {
+			case stalled <- struct{}{}:
+			default:
+			}
+		})
+
+		go w.Run(ctx)
+		go func() {
+			defer func() {
+				if v := recover(); v != nil {
+					done <- sdr.DriverPanic(v)
+				}
+			}()
+			done <- fn(ctx, w)
+		}()
+
+		select {
+		case err := <-done:
+			return err
+		case <-stalled:
+		}
+
+		cancel()
+		w.closeAll()
+		select {
+		case <-done:
+		case <-time.After(cfg.getGrace()):
+		}
+		return ErrStalled
+	}
+}
+
+// vim: foldmethod=marker
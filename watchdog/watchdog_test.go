@@ -0,0 +1,156 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package watchdog_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/supervisor"
+	"hz.tools/sdr/watchdog"
+)
+
+// stuckReader returns samples until stuck is closed, and then blocks until
+// it's Closed.
+type stuckReader struct {
+	stuck  chan struct{}
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newStuckReader() *stuckReader {
+	return &stuckReader{stuck: make(chan struct{}), closed: make(chan struct{})}
+}
+
+func (sr *stuckReader) SampleFormat() sdr.SampleFormat { return sdr.SampleFormatC64 }
+func (sr *stuckReader) SampleRate() uint               { return 1000 }
+
+func (sr *stuckReader) Read(s sdr.Samples) (int, error) {
+	select {
+	case <-sr.stuck:
+	default:
+		time.Sleep(time.Millisecond)
+		return s.Length(), nil
+	}
+	<-sr.closed
+	return 0, sdr.ErrPipeClosed
+}
+
+func (sr *stuckReader) Close() error {
+	sr.once.Do(func() { close(sr.closed) })
+	return nil
+}
+
+func TestCheck(t *testing.T) {
+	wd := watchdog.New(watchdog.Config{Timeout: 50 * time.Millisecond})
+	sr := newStuckReader()
+	r := wd.ReadCloser("rx", sr)
+	w := wd.Writer("out", sdr.Discard(1000, sdr.SampleFormatC64))
+
+	done := make(chan error)
+	go func() {
+		_, err := sdr.Copy(w, r)
+		done <- err
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(t, wd.Check())
+
+	close(sr.stuck)
+	time.Sleep(100 * time.Millisecond)
+	events := wd.Check()
+	assert.Equal(t, 2, len(events))
+	assert.Equal(t, "rx", events[0].Stage)
+	assert.True(t, events[0].Blocked)
+	assert.Equal(t, "out", events[1].Stage)
+	assert.False(t, events[1].Blocked)
+	assert.True(t, events[0].Idle >= 50*time.Millisecond)
+
+	// Only reported once.
+	assert.Empty(t, wd.Check())
+
+	sr.Close()
+	assert.Equal(t, sdr.ErrPipeClosed, <-done)
+}
+
+func TestGuard(t *testing.T) {
+	var (
+		lock   sync.Mutex
+		events []watchdog.Event
+		runs   int
+	)
+
+	run := watchdog.Guard(watchdog.Config{
+		Timeout: 50 * time.Millisecond,
+		OnStall: func(e watchdog.Event) {
+			lock.Lock()
+			defer lock.Unlock()
+			events = append(events, e)
+		},
+	}, func(ctx context.Context, wd *watchdog.Watchdog) error {
+		runs++
+		sr := newStuckReader()
+		r := wd.ReadCloser("rx", sr)
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			close(sr.stuck)
+		}()
+		_, err := sdr.Copy(sdr.Discard(1000, sdr.SampleFormatC64), r)
+		return err
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	sup := supervisor.New()
+	assert.NoError(t, sup.Add(supervisor.Pipeline{
+		Name:        "stuck",
+		Run:         run,
+		MaxRestarts: 2,
+		MinBackoff:  time.Millisecond,
+	}))
+	assert.NoError(t, sup.Run(ctx))
+
+	status := sup.Health().Pipelines
+	assert.Equal(t, 3, runs)
+	assert.Equal(t, watchdog.ErrStalled, status[0].LastError)
+	assert.Equal(t, supervisor.StateFailed, status[0].State)
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, 3, len(events))
+	assert.Equal(t, "rx", events[0].Stage)
+}
+
+func TestGuardReturns(t *testing.T) {
+	run := watchdog.Guard(watchdog.Config{Timeout: time.Second},
+		func(ctx context.Context, wd *watchdog.Watchdog) error {
+			return nil
+		})
+	assert.NoError(t, run(context.Background()))
+}
+
+// vim: foldmethod=marker
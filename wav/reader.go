@@ -0,0 +1,175 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+)
+
+// skip will discard n bytes from the reader, along with the pad byte that
+// follows odd sized chunks.
+func skip(r io.Reader, n uint64) error {
+	n += n % 2
+	_, err := io.CopyN(ioutil.Discard, r, int64(n))
+	return err
+}
+
+// readChunk will read the chunk body into v, and skip anything left over.
+func readChunk(r io.Reader, size uint64, v interface{}) error {
+	want := uint64(binary.Size(v))
+	if size < want {
+		return fmt.Errorf("wav: chunk too short (%d bytes, need %d)", size, want)
+	}
+	if err := binary.Read(r, binary.LittleEndian, v); err != nil {
+		return err
+	}
+	return skip(r, size-want)
+}
+
+// ReadHeader will read the WAV header from the reader, up to the start of
+// the IQ data. The reader will be left at the first byte of the data chunk,
+// ready to be passed to Header.Reader.
+func ReadHeader(r io.Reader) (*Header, error) {
+	var riff struct {
+		ID   [4]byte
+		Size uint32
+		WAVE [4]byte
+	}
+	if err := binary.Read(r, binary.LittleEndian, &riff); err != nil {
+		return nil, err
+	}
+	if (string(riff.ID[:]) != "RIFF" && string(riff.ID[:]) != "RF64") ||
+		string(riff.WAVE[:]) != "WAVE" {
+		return nil, ErrNotWAV
+	}
+
+	var (
+		h      = &Header{}
+		format *fmtChunk
+		ds64   *ds64Chunk
+	)
+
+	for {
+		var chunk struct {
+			ID   [4]byte
+			Size uint32
+		}
+		if err := binary.Read(r, binary.LittleEndian, &chunk); err != nil {
+			if err == io.EOF {
+				return nil, ErrNoData
+			}
+			return nil, err
+		}
+		size := uint64(chunk.Size)
+
+		switch string(chunk.ID[:]) {
+		case "ds64":
+			ds64 = &ds64Chunk{}
+			if err := readChunk(r, size, ds64); err != nil {
+				return nil, err
+			}
+		case "fmt ":
+			format = &fmtChunk{}
+			if err := readChunk(r, size, format); err != nil {
+				return nil, err
+			}
+			if format.Channels != 2 {
+				return nil, ErrNotIQ
+			}
+			sf, err := sampleFormat(*format)
+			if err != nil {
+				return nil, err
+			}
+			h.SampleFormat = sf
+			h.SampleRate = uint(format.SampleRate)
+		case "auxi":
+			// Some tools write a shorter auxi chunk than others, so only the
+			// bits we actually use are required.
+			auxi := auxiChunk{}
+			head := uint64(binary.Size(auxi.StartTime)*2 + 4)
+			if size < head {
+				if err := skip(r, size); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			body := make([]byte, size+size%2)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return nil, err
+			}
+			if len(body) < binary.Size(auxi) {
+				body = append(body, make([]byte, binary.Size(auxi)-len(body))...)
+			}
+			if err := binary.Read(bytes.NewReader(body), binary.LittleEndian, &auxi); err != nil {
+				return nil, err
+			}
+			h.CenterFrequency = rf.Hz(auxi.CenterFreq)
+			h.StartTime = auxi.StartTime.Time()
+			h.StopTime = auxi.StopTime.Time()
+		case "data":
+			if format == nil {
+				return nil, ErrUnsupportedFormat
+			}
+			if size == sizeUnknown && ds64 != nil {
+				size = ds64.DataSize
+			}
+			if size != sizeUnknown && format.BlockAlign != 0 {
+				h.Length = size / uint64(format.BlockAlign)
+			}
+			return h, nil
+		default:
+			if err := skip(r, size); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// Reader will return an sdr.Reader for the IQ data in the provided reader,
+// which must be positioned at the start of the data chunk (as ReadHeader
+// leaves it). Reading will stop at the end of the data chunk, if the size
+// of it is known.
+func (h Header) Reader(r io.Reader) sdr.Reader {
+	if h.Length != 0 {
+		r = io.LimitReader(r, int64(h.Length)*int64(h.SampleFormat.Size()))
+	}
+	return sdr.ByteReader(r, binary.LittleEndian, h.SampleRate, h.SampleFormat)
+}
+
+// NewReader will read the WAV header from the provided reader, and return
+// an sdr.Reader of the IQ data that follows it. Use ReadHeader if the center
+// frequency or timestamps of the recording are needed.
+func NewReader(r io.Reader) (sdr.Reader, error) {
+	h, err := ReadHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	return h.Reader(r), nil
+}
+
+// vim: foldmethod=marker
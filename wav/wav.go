@@ -0,0 +1,177 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package wav
+
+import (
+	"fmt"
+	"time"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+)
+
+var (
+	// ErrNotWAV will be returned if the file isn't a RIFF (or RF64) WAVE
+	// file.
+	ErrNotWAV = fmt.Errorf("wav: not a WAVE file")
+
+	// ErrNotIQ will be returned if the WAV file isn't 2 channels, since it
+	// can't contain IQ data.
+	ErrNotIQ = fmt.Errorf("wav: file does not have 2 channels")
+
+	// ErrUnsupportedFormat will be returned if the WAV sample encoding
+	// doesn't map to an sdr.SampleFormat, or the other way around.
+	ErrUnsupportedFormat = fmt.Errorf("wav: unsupported sample format")
+
+	// ErrNoData will be returned if the file ends before the data chunk.
+	ErrNoData = fmt.Errorf("wav: no data chunk")
+)
+
+const (
+	formatPCM   = 1
+	formatFloat = 3
+
+	// sizeUnknown is written in 32 bit size fields of RF64 files, and by
+	// some tools that write WAV files as a stream.
+	sizeUnknown = 0xFFFFFFFF
+)
+
+// Header contains information about the recording, taken from the fmt and
+// auxi chunks.
+type Header struct {
+	// SampleRate is the number of IQ samples per second.
+	SampleRate uint
+
+	// SampleFormat is the format of the IQ samples; 8 bit WAV files are
+	// SampleFormatU8, 16 bit WAV files are SampleFormatI16, 32 bit float
+	// WAV files are SampleFormatC64, and 64 bit float WAV files are
+	// SampleFormatC128.
+	SampleFormat sdr.SampleFormat
+
+	// CenterFrequency is the center frequency of the recording, from the
+	// auxi chunk. This will be 0 if the file has no auxi chunk.
+	CenterFrequency rf.Hz
+
+	// StartTime and StopTime are from the auxi chunk, and will be the zero
+	// time if the file has no auxi chunk.
+	StartTime time.Time
+	StopTime  time.Time
+
+	// Length is the number of IQ samples in the data chunk. When reading a
+	// file that was written as a stream, this will be 0.
+	Length uint64
+}
+
+// fmtChunk is the contents of the WAV fmt chunk.
+type fmtChunk struct {
+	FormatTag     uint16
+	Channels      uint16
+	SampleRate    uint32
+	ByteRate      uint32
+	BlockAlign    uint16
+	BitsPerSample uint16
+}
+
+// systemTime is the Windows SYSTEMTIME struct.
+type systemTime struct {
+	Year, Month, DayOfWeek, Day, Hour, Minute, Second, Milliseconds uint16
+}
+
+func (st systemTime) Time() time.Time {
+	if st.Year == 0 {
+		return time.Time{}
+	}
+	return time.Date(
+		int(st.Year), time.Month(st.Month), int(st.Day),
+		int(st.Hour), int(st.Minute), int(st.Second),
+		int(st.Milliseconds)*int(time.Millisecond), time.UTC,
+	)
+}
+
+func newSystemTime(t time.Time) systemTime {
+	if t.IsZero() {
+		return systemTime{}
+	}
+	t = t.UTC()
+	return systemTime{
+		Year:         uint16(t.Year()),
+		Month:        uint16(t.Month()),
+		DayOfWeek:    uint16(t.Weekday()),
+		Day:          uint16(t.Day()),
+		Hour:         uint16(t.Hour()),
+		Minute:       uint16(t.Minute()),
+		Second:       uint16(t.Second()),
+		Milliseconds: uint16(t.Nanosecond() / int(time.Millisecond)),
+	}
+}
+
+// auxiChunk is the contents of the auxi chunk written by SDR# and HDSDR.
+type auxiChunk struct {
+	StartTime   systemTime
+	StopTime    systemTime
+	CenterFreq  uint32
+	ADFrequency uint32
+	IFFrequency uint32
+	Bandwidth   uint32
+	IQOffset    uint32
+	Unused      [4]uint32
+}
+
+// ds64Chunk is the start of the RF64 ds64 chunk, which contains the 64 bit
+// sizes which don't fit in the 32 bit RIFF size fields.
+type ds64Chunk struct {
+	RIFFSize    uint64
+	DataSize    uint64
+	SampleCount uint64
+	TableLength uint32
+}
+
+func encoding(sf sdr.SampleFormat) (uint16, uint16, error) {
+	switch sf {
+	case sdr.SampleFormatU8:
+		return formatPCM, 8, nil
+	case sdr.SampleFormatI16:
+		return formatPCM, 16, nil
+	case sdr.SampleFormatC64:
+		return formatFloat, 32, nil
+	case sdr.SampleFormatC128:
+		return formatFloat, 64, nil
+	default:
+		return 0, 0, ErrUnsupportedFormat
+	}
+}
+
+func sampleFormat(f fmtChunk) (sdr.SampleFormat, error) {
+	switch {
+	case f.FormatTag == formatPCM && f.BitsPerSample == 8:
+		return sdr.SampleFormatU8, nil
+	case f.FormatTag == formatPCM && f.BitsPerSample == 16:
+		return sdr.SampleFormatI16, nil
+	case f.FormatTag == formatFloat && f.BitsPerSample == 32:
+		return sdr.SampleFormatC64, nil
+	case f.FormatTag == formatFloat && f.BitsPerSample == 64:
+		return sdr.SampleFormatC128, nil
+	default:
+		return 0, ErrUnsupportedFormat
+	}
+}
+
+// vim: foldmethod=marker
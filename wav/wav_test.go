@@ -0,0 +1,168 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package wav_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/wav"
+)
+
+func roundTrip(t *testing.T, in sdr.Samples) {
+	dir, err := ioutil.TempDir("", "wav")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "iq.wav")
+
+	start := time.Date(2021, 3, 4, 5, 6, 7, 8e6, time.UTC)
+	w, err := wav.Create(path, wav.Header{
+		SampleRate:      192000,
+		SampleFormat:    in.Format(),
+		CenterFrequency: rf.MHz * 7,
+		StartTime:       start,
+	})
+	assert.NoError(t, err)
+	n, err := w.Write(in)
+	assert.NoError(t, err)
+	assert.Equal(t, in.Length(), n)
+	assert.NoError(t, w.Close())
+
+	_, err = w.Write(in)
+	assert.Equal(t, wav.ErrClosed, err)
+
+	fd, err := os.Open(path)
+	assert.NoError(t, err)
+	defer fd.Close()
+
+	h, err := wav.ReadHeader(fd)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(192000), h.SampleRate)
+	assert.Equal(t, in.Format(), h.SampleFormat)
+	assert.Equal(t, rf.MHz*7, h.CenterFrequency)
+	assert.Equal(t, start, h.StartTime)
+	assert.False(t, h.StopTime.Before(start))
+	assert.Equal(t, uint64(in.Length()), h.Length)
+
+	out, err := sdr.MakeSamples(in.Format(), in.Length()+10)
+	assert.NoError(t, err)
+	r := h.Reader(fd)
+	n, err = sdr.ReadFull(r, out.Slice(0, in.Length()))
+	assert.NoError(t, err)
+	assert.Equal(t, in.Length(), n)
+	assert.Equal(t, in, out.Slice(0, in.Length()))
+
+	_, err = r.Read(out)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestRoundTrip(t *testing.T) {
+	roundTrip(t, sdr.SamplesU8{{0, 255}, {127, 128}, {1, 2}})
+	roundTrip(t, sdr.SamplesI16{{-32768, 32767}, {0, 0}, {1, -1}})
+	roundTrip(t, sdr.SamplesC64{complex(0.5, -0.5), complex(1, 0)})
+}
+
+// chunk will build a RIFF chunk with the provided id and body.
+func chunk(id string, size uint32, body ...interface{}) []byte {
+	buf := &bytes.Buffer{}
+	buf.WriteString(id)
+	binary.Write(buf, binary.LittleEndian, size)
+	for _, v := range body {
+		binary.Write(buf, binary.LittleEndian, v)
+	}
+	return buf.Bytes()
+}
+
+func TestReadRF64(t *testing.T) {
+	samples := []int16{1, 2, 3, 4, 5, 6}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("RF64")
+	binary.Write(buf, binary.LittleEndian, uint32(0xFFFFFFFF))
+	buf.WriteString("WAVE")
+	buf.Write(chunk("ds64", 28, uint64(0), uint64(8), uint64(2), uint32(0)))
+	buf.Write(chunk("fmt ", 16, uint16(1), uint16(2), uint32(48000),
+		uint32(48000*4), uint16(4), uint16(16)))
+	buf.Write(chunk("LIST", 3, []byte("abc"), byte(0)))
+	buf.Write(chunk("data", 0xFFFFFFFF, samples))
+
+	h, err := wav.ReadHeader(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), h.Length)
+	assert.Equal(t, rf.Hz(0), h.CenterFrequency)
+	assert.True(t, h.StartTime.IsZero())
+
+	out := make(sdr.SamplesI16, 4)
+	n, err := sdr.ReadFull(h.Reader(buf), out[:2])
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, sdr.SamplesI16{{1, 2}, {3, 4}}, out[:2])
+}
+
+func TestNewReader(t *testing.T) {
+	buf := &bytes.Buffer{}
+	buf.WriteString("RIFF")
+	binary.Write(buf, binary.LittleEndian, uint32(0))
+	buf.WriteString("WAVE")
+	buf.Write(chunk("fmt ", 18, uint16(3), uint16(2), uint32(1000),
+		uint32(8000), uint16(8), uint16(32), uint16(0)))
+	buf.Write(chunk("data", 8, float32(0.25), float32(-0.25)))
+
+	r, err := wav.NewReader(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1000), r.SampleRate())
+	assert.Equal(t, sdr.SampleFormatC64, r.SampleFormat())
+
+	out := make(sdr.SamplesC64, 1)
+	_, err = sdr.ReadFull(r, out)
+	assert.NoError(t, err)
+	assert.Equal(t, complex64(complex(0.25, -0.25)), out[0])
+}
+
+func TestReadHeaderErrors(t *testing.T) {
+	_, err := wav.ReadHeader(bytes.NewReader([]byte("RIFF\x00\x00\x00\x00AVI ")))
+	assert.Equal(t, wav.ErrNotWAV, err)
+
+	mono := &bytes.Buffer{}
+	mono.WriteString("RIFF\x00\x00\x00\x00WAVE")
+	mono.Write(chunk("fmt ", 16, uint16(1), uint16(1), uint32(48000),
+		uint32(48000*2), uint16(2), uint16(16)))
+	_, err = wav.ReadHeader(mono)
+	assert.Equal(t, wav.ErrNotIQ, err)
+
+	_, err = wav.ReadHeader(bytes.NewReader([]byte("RIFF\x00\x00\x00\x00WAVE")))
+	assert.Equal(t, wav.ErrNoData, err)
+
+	_, err = wav.NewWriter(nil, wav.Header{SampleFormat: sdr.SampleFormatI8})
+	assert.Equal(t, wav.ErrUnsupportedFormat, err)
+}
+
+// vim: foldmethod=marker
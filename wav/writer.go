@@ -0,0 +1,237 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package wav
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"hz.tools/sdr"
+)
+
+var (
+	// ErrClosed will be returned when writing to a Writer that has already
+	// been closed.
+	ErrClosed = fmt.Errorf("wav: writer is closed")
+)
+
+// The header is always written with the same layout, so that the sizes can
+// be patched in place once the recording is done. A JUNK chunk is reserved
+// where the ds64 chunk goes, in case the recording grows past 4 GiB, and
+// needs to be turned into an RF64 file.
+const (
+	riffSizeOffset = 4
+	ds64Offset     = 12
+	fmtOffset      = ds64Offset + 8 + 28
+	auxiOffset     = fmtOffset + 8 + 16
+	stopTimeOffset = auxiOffset + 8 + 16
+	dataOffset     = auxiOffset + 8 + 68
+	dataSizeOffset = dataOffset + 4
+	headerSize     = dataOffset + 8
+
+	maxRIFFSize = 0xFFFFFFFF
+)
+
+// Writer will write IQ samples to a WAV file. The sizes in the header (and
+// the stop time in the auxi chunk) are only filled in when the Writer is
+// closed, so an unclosed recording will have a data chunk of length 0.
+type Writer struct {
+	lock *sync.Mutex
+
+	ws     io.WriteSeeker
+	closer io.Closer
+	buf    *bufio.Writer
+	w      sdr.Writer
+
+	header  Header
+	written uint64
+	closed  bool
+}
+
+// NewWriter will write a WAV header to the provided io.WriteSeeker, and
+// return a Writer for the IQ samples. The SampleRate and SampleFormat of
+// the Header must be set, the CenterFrequency will be written to the auxi
+// chunk, and the StartTime will default to now.
+//
+// Closing the Writer will not close the io.WriteSeeker.
+func NewWriter(ws io.WriteSeeker, h Header) (*Writer, error) {
+	formatTag, bits, err := encoding(h.SampleFormat)
+	if err != nil {
+		return nil, err
+	}
+	if h.StartTime.IsZero() {
+		h.StartTime = time.Now()
+	}
+
+	var (
+		buf        = bufio.NewWriter(ws)
+		blockAlign = uint16(h.SampleFormat.Size())
+	)
+
+	for _, v := range []interface{}{
+		[]byte("RIFF"), uint32(0), []byte("WAVE"),
+		[]byte("JUNK"), uint32(28), ds64Chunk{},
+		[]byte("fmt "), uint32(16), fmtChunk{
+			FormatTag:     formatTag,
+			Channels:      2,
+			SampleRate:    uint32(h.SampleRate),
+			ByteRate:      uint32(h.SampleRate) * uint32(blockAlign),
+			BlockAlign:    blockAlign,
+			BitsPerSample: bits,
+		},
+		[]byte("auxi"), uint32(68), auxiChunk{
+			StartTime:  newSystemTime(h.StartTime),
+			CenterFreq: uint32(h.CenterFrequency),
+		},
+		[]byte("data"), uint32(0),
+	} {
+		if err := binary.Write(buf, binary.LittleEndian, v); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Writer{
+		lock:   &sync.Mutex{},
+		ws:     ws,
+		buf:    buf,
+		w:      sdr.ByteWriter(buf, binary.LittleEndian, h.SampleRate, h.SampleFormat),
+		header: h,
+	}, nil
+}
+
+// Create will create a WAV file at the provided path (truncating it if it
+// exists), and return a Writer for the IQ samples. Unlike NewWriter,
+// closing the Writer will close the file.
+func Create(path string, h Header) (*Writer, error) {
+	fd, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w, err := NewWriter(fd, h)
+	if err != nil {
+		fd.Close()
+		return nil, err
+	}
+	w.closer = fd
+	return w, nil
+}
+
+// SampleFormat implements the sdr.Writer interface.
+func (w *Writer) SampleFormat() sdr.SampleFormat {
+	return w.header.SampleFormat
+}
+
+// SampleRate implements the sdr.Writer interface.
+func (w *Writer) SampleRate() uint {
+	return w.header.SampleRate
+}
+
+// Write implements the sdr.Writer interface.
+func (w *Writer) Write(s sdr.Samples) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.closed {
+		return 0, ErrClosed
+	}
+	n, err := w.w.Write(s)
+	w.written += uint64(n)
+	return n, err
+}
+
+// writeAt will write v at the provided offset from the start of the file.
+func (w *Writer) writeAt(offset int64, v interface{}) error {
+	if _, err := w.ws.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	return binary.Write(w.ws, binary.LittleEndian, v)
+}
+
+// finish will patch the sizes and stop time into the header. If the file
+// is too large to be described by a RIFF header, it's turned into an RF64
+// file, with the reserved JUNK chunk becoming the ds64 chunk.
+func (w *Writer) finish() error {
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+
+	var (
+		dataSize = w.written * uint64(w.header.SampleFormat.Size())
+		riffSize = headerSize + dataSize - 8
+	)
+
+	if err := w.writeAt(stopTimeOffset, newSystemTime(time.Now())); err != nil {
+		return err
+	}
+
+	if riffSize <= maxRIFFSize {
+		if err := w.writeAt(riffSizeOffset, uint32(riffSize)); err != nil {
+			return err
+		}
+		return w.writeAt(dataSizeOffset, uint32(dataSize))
+	}
+
+	for _, patch := range []struct {
+		offset int64
+		v      interface{}
+	}{
+		{0, []byte("RF64")},
+		{riffSizeOffset, uint32(sizeUnknown)},
+		{ds64Offset, []byte("ds64")},
+		{ds64Offset + 8, ds64Chunk{
+			RIFFSize:    riffSize,
+			DataSize:    dataSize,
+			SampleCount: w.written,
+		}},
+		{dataSizeOffset, uint32(sizeUnknown)},
+	} {
+		if err := w.writeAt(patch.offset, patch.v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close will flush any buffered samples, and fill in the header.
+func (w *Writer) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	err := w.finish()
+	if w.closer != nil {
+		if cerr := w.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,240 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package weak
+
+import (
+	"fmt"
+	"math"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/stream"
+)
+
+var (
+	// ErrBadFactor will be returned if a decimation factor is 0.
+	ErrBadFactor = fmt.Errorf("weak: decimation factor must be at least 1")
+)
+
+// maxStageFactor is the largest factor a single Decimator stage in a
+// Decimate chain will be asked to do. Large factors in one stage need very
+// long filters, a few smaller stages are much cheaper.
+const maxStageFactor = 8
+
+// Decimator will low-pass filter and then reduce the sample rate of complex128
+// IQ data by an integer factor, using float64 taps and accumulators.
+//
+// Filter state is kept between calls to Process, so a stream may be
+// processed in chunks of any size.
+type Decimator struct {
+	factor  int
+	taps    []float64
+	history sdr.SamplesC128
+	buf     sdr.SamplesC128
+	phase   int
+}
+
+// NewDecimator will create a new Decimator which reduces the sample rate by
+// the provided factor, with a Blackman windowed-sinc filter cutting off
+// at 80% of the new Nyquist frequency.
+func NewDecimator(factor uint) (*Decimator, error) {
+	if factor == 0 {
+		return nil, ErrBadFactor
+	}
+
+	var (
+		f      = int(factor)
+		n      = 16*f + 1
+		cutoff = 0.8 / float64(f) / 2
+		taps   = make([]float64, n)
+		sum    float64
+	)
+
+	for i := range taps {
+		x := float64(i - n/2)
+		sinc := 2 * cutoff
+		if x != 0 {
+			sinc = math.Sin(2*math.Pi*cutoff*x) / (math.Pi * x)
+		}
+		w := 0.42 -
+			0.5*math.Cos(2*math.Pi*float64(i)/float64(n-1)) +
+			0.08*math.Cos(4*math.Pi*float64(i)/float64(n-1))
+		taps[i] = sinc * w
+		sum += taps[i]
+	}
+	for i := range taps {
+		taps[i] /= sum
+	}
+
+	return &Decimator{
+		factor:  f,
+		taps:    taps,
+		history: make(sdr.SamplesC128, n-1),
+	}, nil
+}
+
+// OutputLength will return the largest number of samples Process may
+// write when given `n` input samples.
+func (d *Decimator) OutputLength(n int) int {
+	return (n + d.factor - 1) / d.factor
+}
+
+// Process will filter and decimate `src` into `dst`, returning the number
+// of samples written.
+func (d *Decimator) Process(dst, src sdr.SamplesC128) (int, error) {
+	if len(dst) < d.OutputLength(len(src)) {
+		return 0, sdr.ErrDstTooSmall
+	}
+
+	var (
+		hist = len(d.history)
+		j    int
+	)
+	if cap(d.buf) < hist+len(src) {
+		d.buf = make(sdr.SamplesC128, hist+len(src))
+	}
+	buf := d.buf[:hist+len(src)]
+	copy(buf, d.history)
+	copy(buf[hist:], src)
+
+	for i := d.phase; i < len(src); i += d.factor {
+		var (
+			window  = buf[i : i+len(d.taps)]
+			re, im  float64
+			lastTap = len(d.taps) - 1
+		)
+		for k, tap := range d.taps {
+			v := window[lastTap-k]
+			re += real(v) * tap
+			im += imag(v) * tap
+		}
+		dst[j] = complex(re, im)
+		j++
+		d.phase = i + d.factor
+	}
+	d.phase -= len(src)
+
+	copy(d.history, buf[len(src):])
+	return j, nil
+}
+
+// stageFactors will split a decimation factor into stages no larger than
+// maxStageFactor where possible, largest first. Prime factors larger than
+// maxStageFactor get a stage of their own.
+func stageFactors(factor uint) []uint {
+	var (
+		primes []uint
+		stages []uint
+	)
+
+	for p := uint(2); p*p <= factor; p++ {
+		for factor%p == 0 {
+			primes = append(primes, p)
+			factor /= p
+		}
+	}
+	if factor > 1 {
+		primes = append(primes, factor)
+	}
+
+	for i := len(primes) - 1; i >= 0; i-- {
+		p := primes[i]
+		last := len(stages) - 1
+		if last >= 0 && stages[last]*p <= maxStageFactor {
+			stages[last] *= p
+			continue
+		}
+		stages = append(stages, p)
+	}
+	return stages
+}
+
+// Decimate will reduce the sample rate of the provided Reader by `factor`,
+// in a chain of Decimator stages, returning a Reader of SamplesC128 at the
+// new rate. This is intended for getting from a normal SDR sample rate down
+// to the very low rates (a few hundred or thousand samples per second)
+// that weak signal work is done at.
+//
+// Samples in other formats are converted to complex128 on the way in.
+func Decimate(in sdr.Reader, factor uint) (sdr.Reader, error) {
+	if factor == 0 {
+		return nil, ErrBadFactor
+	}
+
+	var stages []*Decimator
+	for _, f := range stageFactors(factor) {
+		d, err := NewDecimator(f)
+		if err != nil {
+			return nil, err
+		}
+		stages = append(stages, d)
+	}
+
+	var (
+		format       = in.SampleFormat()
+		inputLength  = 32 * 1024
+		outputLength = inputLength
+		buffers      []sdr.SamplesC128
+		inC128       sdr.SamplesC128
+	)
+
+	if format != sdr.SampleFormatC128 {
+		inC128 = make(sdr.SamplesC128, inputLength)
+	}
+	for _, d := range stages {
+		outputLength = d.OutputLength(outputLength)
+		buffers = append(buffers, make(sdr.SamplesC128, outputLength))
+	}
+
+	return stream.ReadTransformer(in, stream.ReadTransformerConfig{
+		InputBufferLength:  inputLength,
+		OutputBufferLength: outputLength,
+		OutputSampleRate:   in.SampleRate() / factor,
+		OutputSampleFormat: sdr.SampleFormatC128,
+		Proc: func(inBuf sdr.Samples, outBuf sdr.Samples) (int, error) {
+			var (
+				cur sdr.SamplesC128
+				n   int
+				err error
+			)
+
+			if format == sdr.SampleFormatC128 {
+				cur = inBuf.(sdr.SamplesC128)
+			} else {
+				n, err = sdr.ConvertBuffer(inC128, inBuf)
+				if err != nil {
+					return 0, err
+				}
+				cur = inC128[:n]
+			}
+
+			for i, d := range stages {
+				n, err = d.Process(buffers[i], cur)
+				if err != nil {
+					return 0, err
+				}
+				cur = buffers[i][:n]
+			}
+			return copy(outBuf.(sdr.SamplesC128), cur), nil
+		},
+	})
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,33 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+// Package weak contains helpers for long coherent and incoherent
+// integration of very weak signals, such as meteor scatter, WSPR or EME,
+// where a signal may be tens of dB under the noise in any single FFT, and
+// only shows up after minutes of averaging.
+//
+// Integrating for that long means a few problems that don't matter for a
+// quick look at the spectrum start to matter a lot -- tiny amounts of
+// transmitter or receiver drift smear the signal across bins, and float32
+// phase and power accumulators run out of precision. Everything in here
+// works on SamplesC128, and only drops to complex64 for the FFT itself.
+package weak
+
+// vim: foldmethod=marker
@@ -0,0 +1,338 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package weak
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"hz.tools/rf"
+	"hz.tools/sdr"
+	"hz.tools/sdr/fft"
+	"hz.tools/sdr/spectrum"
+)
+
+var (
+	// ErrNoSampleRate will be returned if the Config is missing a
+	// SampleRate.
+	ErrNoSampleRate = fmt.Errorf("weak: sample rate is required")
+
+	// ErrNoFrames will be returned when asking for the result of an
+	// Integrator that has not yet seen a full FFT worth of samples.
+	ErrNoFrames = fmt.Errorf("weak: no frames have been integrated")
+
+	// ErrNoDrifts will be returned if SearchDrift is called without any
+	// drift rates to try.
+	ErrNoDrifts = fmt.Errorf("weak: no drift rates to search")
+)
+
+// Config contains the parameters used to integrate a weak signal.
+type Config struct {
+	// SampleRate is the sample rate of the IQ data. This is required, since
+	// it's needed to turn the Drift into a per-sample phase correction.
+	SampleRate uint
+
+	// Planner is used to create the FFT plan. If nil, this will default to
+	// fft.DefaultPlanner.
+	Planner fft.Planner
+
+	// Size is the number of samples in each FFT. If 0, this will default
+	// to 1024.
+	Size int
+
+	// Window is the window function applied to each FFT. If nil, this will
+	// default to spectrum.Hann.
+	Window spectrum.Window
+
+	// Drift is the rate the signal is expected to be changing frequency,
+	// in Hz per second. Before each FFT, the IQ data is mixed with a chirp
+	// that undoes this drift, so that a signal that is drifting at this
+	// rate will stay in the same bin (at the frequency it was at when the
+	// first sample was written) for the whole integration.
+	Drift float64
+}
+
+func (c Config) getPlanner() fft.Planner {
+	if c.Planner == nil {
+		return fft.DefaultPlanner
+	}
+	return c.Planner
+}
+
+func (c Config) getSize() int {
+	if c.Size == 0 {
+		return 1024
+	}
+	return c.Size
+}
+
+func (c Config) getWindow() spectrum.Window {
+	if c.Window == nil {
+		return spectrum.Hann
+	}
+	return c.Window
+}
+
+// Integrator will average the power spectrum of a stream of complex128 IQ
+// data over a long period of time, optionally correcting for a constant
+// frequency drift.
+//
+// The drift correction phase is computed from the absolute sample index
+// in float64 (rather than accumulated), and power is accumulated in
+// float64, so that integrating for tens of minutes doesn't lose precision.
+type Integrator struct {
+	sampleRate float64
+	drift      float64
+	window     []float64
+	scale      float64
+
+	plan    fft.Plan
+	segment sdr.SamplesC128
+	fill    int
+	iq      sdr.SamplesC64
+	freq    []complex64
+
+	samples uint64
+	frames  int
+	acc     []float64
+}
+
+// NewIntegrator will create a new Integrator for the provided Config.
+func NewIntegrator(cfg Config) (*Integrator, error) {
+	if cfg.SampleRate == 0 {
+		return nil, ErrNoSampleRate
+	}
+
+	var (
+		size = cfg.getSize()
+		err  error
+		sum  float64
+	)
+
+	in := &Integrator{
+		sampleRate: float64(cfg.SampleRate),
+		drift:      cfg.Drift,
+		window:     make([]float64, size),
+		segment:    make(sdr.SamplesC128, size),
+		iq:         make(sdr.SamplesC64, size),
+		freq:       make([]complex64, size),
+		acc:        make([]float64, size),
+	}
+
+	for i, w := range cfg.getWindow()(size) {
+		in.window[i] = float64(w)
+		sum += float64(w)
+	}
+	// As in the spectrum package, a full scale carrier centered on a bin
+	// reads as 1.
+	in.scale = 1 / (sum * sum)
+
+	in.plan, err = cfg.getPlanner()(in.iq, in.freq, fft.Forward)
+	if err != nil {
+		return nil, err
+	}
+	return in, nil
+}
+
+// Write will add the provided IQ samples to the integration. Samples are
+// buffered until there are enough for a full FFT.
+func (in *Integrator) Write(iq sdr.SamplesC128) (int, error) {
+	var (
+		n    = len(iq)
+		size = len(in.segment)
+	)
+
+	for len(iq) > 0 {
+		c := copy(in.segment[in.fill:], iq)
+		in.fill += c
+		iq = iq[c:]
+
+		if in.fill < size {
+			break
+		}
+		if err := in.integrate(); err != nil {
+			return n - len(iq), err
+		}
+		in.fill = 0
+	}
+	return n, nil
+}
+
+// integrate will process one full segment.
+func (in *Integrator) integrate() error {
+	var (
+		size = len(in.segment)
+		half = size / 2
+	)
+
+	for i, v := range in.segment {
+		if in.drift != 0 {
+			// A signal at f0 + drift*t has accumulated a phase of
+			// 2pi * (f0*t + drift*t^2/2), so take the second term back out.
+			t := float64(in.samples+uint64(i)) / in.sampleRate
+			phase := math.Mod(math.Pi*in.drift*t*t, 2*math.Pi)
+			sin, cos := math.Sincos(-phase)
+			v *= complex(cos, sin)
+		}
+		v *= complex(in.window[i], 0)
+		in.iq[i] = complex64(v)
+	}
+
+	if err := in.plan.Transform(); err != nil {
+		return err
+	}
+
+	for i, v := range in.freq {
+		re, im := float64(real(v)), float64(imag(v))
+		in.acc[(i+half)%size] += (re*re + im*im) * in.scale
+	}
+
+	in.samples += uint64(size)
+	in.frames++
+	return nil
+}
+
+// Frames returns the number of FFTs that have been averaged together.
+func (in *Integrator) Frames() int {
+	return in.frames
+}
+
+// Duration returns the amount of time (of IQ data) that has been
+// integrated.
+func (in *Integrator) Duration() time.Duration {
+	return time.Duration(float64(in.samples) / in.sampleRate * float64(time.Second))
+}
+
+// Power will write the average power in each bin into dst, in the
+// fft.NegativeFirst order, where a full scale carrier centered on a bin
+// reads as 1.
+func (in *Integrator) Power(dst []float64) error {
+	if len(dst) < len(in.acc) {
+		return sdr.ErrDstTooSmall
+	}
+	if in.frames == 0 {
+		return ErrNoFrames
+	}
+	for i, v := range in.acc {
+		dst[i] = v / float64(in.frames)
+	}
+	return nil
+}
+
+// Peak will return the frequency (relative to the center of the IQ data)
+// of the strongest bin, along with its power relative to the median power
+// of all bins, which is a rough estimate of the SNR in one bin.
+func (in *Integrator) Peak() (rf.Hz, float64, error) {
+	if in.frames == 0 {
+		return 0, 0, ErrNoFrames
+	}
+
+	var (
+		size   = len(in.acc)
+		sorted = make([]float64, size)
+		peak   int
+	)
+	copy(sorted, in.acc)
+	sort.Float64s(sorted)
+
+	for i, v := range in.acc {
+		if v > in.acc[peak] {
+			peak = i
+		}
+	}
+
+	median := sorted[size/2]
+	if median == 0 {
+		median = math.SmallestNonzeroFloat64
+	}
+
+	freq, err := fft.FreqByBin(size, uint(in.sampleRate), fft.NegativeFirst, peak)
+	if err != nil {
+		return 0, 0, err
+	}
+	return freq, in.acc[peak] / median, nil
+}
+
+// Reset will throw away everything integrated so far, including any
+// partially buffered FFT, and restart the drift correction from 0.
+func (in *Integrator) Reset() {
+	in.fill = 0
+	in.samples = 0
+	in.frames = 0
+	for i := range in.acc {
+		in.acc[i] = 0
+	}
+}
+
+// Close will release the FFT plan.
+func (in *Integrator) Close() error {
+	return in.plan.Close()
+}
+
+// SearchDrift will integrate the provided IQ data once for each of the
+// provided drift rates (in Hz per second), and return the drift rate, and
+// the Integrator, with the best Peak SNR. The Drift in the Config is
+// ignored.
+//
+// The returned Integrator has not been closed, so it can be queried (or
+// have more samples written into it); be sure to Close it when done.
+func SearchDrift(iq sdr.SamplesC128, cfg Config, drifts []float64) (float64, *Integrator, error) {
+	if len(drifts) == 0 {
+		return 0, nil, ErrNoDrifts
+	}
+
+	var (
+		best      *Integrator
+		bestDrift float64
+		bestSNR   float64
+	)
+
+	for _, drift := range drifts {
+		cfg.Drift = drift
+		in, err := NewIntegrator(cfg)
+		if err != nil {
+			return 0, nil, err
+		}
+		if _, err := in.Write(iq); err != nil {
+			in.Close()
+			return 0, nil, err
+		}
+		_, snr, err := in.Peak()
+		if err != nil {
+			in.Close()
+			return 0, nil, err
+		}
+		if best == nil || snr > bestSNR {
+			if best != nil {
+				best.Close()
+			}
+			best, bestDrift, bestSNR = in, drift, snr
+			continue
+		}
+		in.Close()
+	}
+
+	return bestDrift, best, nil
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,211 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package weak_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hz.tools/sdr"
+	"hz.tools/sdr/weak"
+)
+
+// chirp will generate a carrier starting at `freq` Hz, drifting `drift` Hz
+// per second, at the provided amplitude, buried in complex gaussian noise
+// with a total power of 1.
+func chirp(sampleRate, n int, freq, drift, amplitude float64) sdr.SamplesC128 {
+	var (
+		rng   = rand.New(rand.NewSource(1))
+		iq    = make(sdr.SamplesC128, n)
+		sigma = math.Sqrt(0.5)
+	)
+	for i := range iq {
+		t := float64(i) / float64(sampleRate)
+		phase := 2 * math.Pi * (freq*t + drift*t*t/2)
+		sin, cos := math.Sincos(phase)
+		iq[i] = complex(
+			amplitude*cos+rng.NormFloat64()*sigma,
+			amplitude*sin+rng.NormFloat64()*sigma,
+		)
+	}
+	return iq
+}
+
+func TestIntegratorDrift(t *testing.T) {
+	const (
+		sampleRate = 1000
+		seconds    = 120
+	)
+
+	// About -26 dB SNR in the full 1 kHz bandwidth, drifting 1 Hz over the
+	// two minutes, which would smear it across 8 bins.
+	iq := chirp(sampleRate, sampleRate*seconds, 100, 1.0/seconds, 0.05)
+
+	cfg := weak.Config{SampleRate: sampleRate, Size: 8192}
+
+	flat, err := weak.NewIntegrator(cfg)
+	assert.NoError(t, err)
+	defer flat.Close()
+	_, err = flat.Write(iq)
+	assert.NoError(t, err)
+	assert.Equal(t, sampleRate*seconds/8192, flat.Frames())
+
+	_, flatSNR, err := flat.Peak()
+	assert.NoError(t, err)
+
+	cfg.Drift = 1.0 / seconds
+	corrected, err := weak.NewIntegrator(cfg)
+	assert.NoError(t, err)
+	defer corrected.Close()
+	_, err = corrected.Write(iq)
+	assert.NoError(t, err)
+
+	freq, snr, err := corrected.Peak()
+	assert.NoError(t, err)
+	assert.InDelta(t, 100, float64(freq), 0.25)
+	assert.Greater(t, snr, 10.0)
+	assert.Greater(t, snr, flatSNR)
+}
+
+func TestIntegratorChunks(t *testing.T) {
+	iq := chirp(1000, 10000, -200, 0.5, 0.1)
+	cfg := weak.Config{SampleRate: 1000, Size: 1024, Drift: 0.5}
+
+	whole, err := weak.NewIntegrator(cfg)
+	assert.NoError(t, err)
+	defer whole.Close()
+	_, err = whole.Write(iq)
+	assert.NoError(t, err)
+
+	chunked, err := weak.NewIntegrator(cfg)
+	assert.NoError(t, err)
+	defer chunked.Close()
+	for i := 0; i < len(iq); i += 333 {
+		end := i + 333
+		if end > len(iq) {
+			end = len(iq)
+		}
+		_, err = chunked.Write(iq[i:end])
+		assert.NoError(t, err)
+	}
+
+	a := make([]float64, 1024)
+	b := make([]float64, 1024)
+	assert.NoError(t, whole.Power(a))
+	assert.NoError(t, chunked.Power(b))
+	assert.Equal(t, a, b)
+	assert.Equal(t, 9, chunked.Frames())
+
+	chunked.Reset()
+	assert.Equal(t, 0, chunked.Frames())
+	assert.Equal(t, weak.ErrNoFrames, chunked.Power(b))
+}
+
+func TestSearchDrift(t *testing.T) {
+	iq := chirp(1000, 60000, 50, -0.05, 0.03)
+
+	drift, in, err := weak.SearchDrift(
+		iq,
+		weak.Config{SampleRate: 1000, Size: 8192},
+		[]float64{-0.1, -0.05, 0, 0.05, 0.1},
+	)
+	assert.NoError(t, err)
+	defer in.Close()
+	assert.Equal(t, -0.05, drift)
+
+	_, _, err = weak.SearchDrift(iq, weak.Config{SampleRate: 1000}, nil)
+	assert.Equal(t, weak.ErrNoDrifts, err)
+}
+
+func TestDecimator(t *testing.T) {
+	const factor = 10
+
+	d, err := weak.NewDecimator(factor)
+	assert.NoError(t, err)
+
+	// A tone in band, and one that would alias onto DC.
+	for _, tc := range []struct {
+		freq float64
+		gain float64
+	}{
+		{freq: 0.01, gain: 1},
+		{freq: 0.1, gain: 0},
+	} {
+		in := make(sdr.SamplesC128, 10000)
+		for i := range in {
+			sin, cos := math.Sincos(2 * math.Pi * tc.freq * float64(i))
+			in[i] = complex(cos, sin)
+		}
+		out := make(sdr.SamplesC128, d.OutputLength(len(in))+factor)
+
+		// Process in odd sized chunks, to make sure state is carried.
+		var n int
+		for i := 0; i < len(in); i += 777 {
+			end := i + 777
+			if end > len(in) {
+				end = len(in)
+			}
+			j, err := d.Process(out[n:], in[i:end])
+			assert.NoError(t, err)
+			n += j
+		}
+		assert.Equal(t, len(in)/factor, n)
+
+		var power float64
+		for _, v := range out[100:n] {
+			power += real(v)*real(v) + imag(v)*imag(v)
+		}
+		power /= float64(n - 100)
+		assert.InDelta(t, tc.gain, power, 1e-3)
+	}
+
+	_, err = weak.NewDecimator(0)
+	assert.Equal(t, weak.ErrBadFactor, err)
+}
+
+func TestDecimateReader(t *testing.T) {
+	in := make(sdr.SamplesI16, 48000)
+	for i := range in {
+		in[i] = [2]int16{1000, -1000}
+	}
+	pr, pw := sdr.Pipe(48000, sdr.SampleFormatI16)
+	go func() {
+		pw.Write(in)
+		pw.Close()
+	}()
+
+	r, err := weak.Decimate(pr, 480)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(100), r.SampleRate())
+	assert.Equal(t, sdr.SampleFormatC128, r.SampleFormat())
+
+	out := make(sdr.SamplesC128, 50)
+	_, err = sdr.ReadFull(r, out)
+	assert.NoError(t, err)
+	last := out[len(out)-1]
+	assert.InDelta(t, 1000.0/math.MaxInt16, real(last), 1e-6)
+	assert.InDelta(t, -1000.0/math.MaxInt16, imag(last), 1e-6)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,87 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2023
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package sdr
+
+// Writers represents a collection of Writers.
+type Writers []Writer
+
+// SampleRate returns the number of samples per second in the stream, or
+// 0 if the Writers don't agree.
+func (ws Writers) SampleRate() uint {
+	if len(ws) == 0 {
+		return 0
+	}
+	ret := ws[0].SampleRate()
+	for _, w := range ws {
+		if w.SampleRate() != ret {
+			return 0
+		}
+	}
+	return ret
+}
+
+// SampleFormat returns the IQ Format of the Writers.
+func (ws Writers) SampleFormat() SampleFormat {
+	if len(ws) == 0 {
+		return SampleFormat(0)
+	}
+	ret := ws[0].SampleFormat()
+	for _, w := range ws {
+		if w.SampleFormat() != ret {
+			return SampleFormat(0)
+		}
+	}
+	return ret
+}
+
+// WriteClosers is a collection of WriteCloser objects.
+type WriteClosers []WriteCloser
+
+// SampleRate returns the number of IQ samples per second.
+func (wcs WriteClosers) SampleRate() uint {
+	return wcs.Writers().SampleRate()
+}
+
+// SampleFormat returns the IQ format of the Writers.
+func (wcs WriteClosers) SampleFormat() SampleFormat {
+	return wcs.Writers().SampleFormat()
+}
+
+// Writers will return the WriteClosers as a Writer slice.
+func (wcs WriteClosers) Writers() Writers {
+	ret := make(Writers, len(wcs))
+	for i := range wcs {
+		ret[i] = wcs[i]
+	}
+	return ret
+}
+
+// Close will close all the WriteClosers.
+func (wcs WriteClosers) Close() error {
+	for _, wc := range wcs {
+		if err := wc.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// vim: foldmethod=marker
@@ -57,6 +57,10 @@ func Samples(base uintptr, length int, sampleFormat sdr.SampleFormat) (sdr.Sampl
 	}{base, length, length}
 
 	switch sampleFormat {
+	case sdr.SampleFormatI12Packed:
+		return *(*sdr.SamplesI12Packed)(unsafe.Pointer(&b)), nil
+	case sdr.SampleFormatC128:
+		return *(*sdr.SamplesC128)(unsafe.Pointer(&b)), nil
 	case sdr.SampleFormatC64:
 		return *(*sdr.SamplesC64)(unsafe.Pointer(&b)), nil
 	case sdr.SampleFormatI16: